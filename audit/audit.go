@@ -0,0 +1,381 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+// Package audit records the state-changing operations to an append-only log file that is
+// rotated by its size.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/superkkt/cherry/clock"
+
+	"github.com/superkkt/go-logging"
+)
+
+const (
+	// Number of the entries returned by Query if the limit is not specified.
+	DefaultQueryLimit = 1000
+	// Maximum length of a line in the log file.
+	maxLineSize = 64 * 1024
+	// Number of the entries waiting to be written to the file.
+	queueSize = 4096
+)
+
+var logger = logging.MustGetLogger("audit")
+
+// Entry is a state-changing operation.
+type Entry struct {
+	Time time.Time `json:"time"`
+	// Who made the operation, e.g., the API client or the application that owns the flow.
+	Actor string `json:"actor"`
+	// Kind of the operation, e.g., "flow_mod" and "api".
+	Operation string `json:"operation"`
+	// What has been done.
+	Detail string `json:"detail"`
+	// DPID of the target device. Empty if the operation does not target a device or the
+	// DPID is not known yet.
+	DPID string `json:"dpid,omitempty"`
+	// "ok" or the reason of the failure.
+	Result string `json:"result"`
+}
+
+// Filter selects the entries returned by Query. Zero values match all the entries.
+type Filter struct {
+	Since     time.Time
+	Until     time.Time
+	Actor     string
+	Operation string
+	DPID      string
+	// Maximum number of the latest entries. DefaultQueryLimit is used if it is zero.
+	Limit int
+}
+
+func (r Filter) match(e Entry) bool {
+	if !r.Since.IsZero() && e.Time.Before(r.Since) {
+		return false
+	}
+	if !r.Until.IsZero() && e.Time.After(r.Until) {
+		return false
+	}
+	if len(r.Actor) > 0 && e.Actor != r.Actor {
+		return false
+	}
+	if len(r.Operation) > 0 && e.Operation != r.Operation {
+		return false
+	}
+	if len(r.DPID) > 0 && e.DPID != r.DPID {
+		return false
+	}
+
+	return true
+}
+
+// Log writes the entries to a file as JSON objects, one per line. The file is renamed to
+// path.1 when its size exceeds the maximum, and the older files are renamed to path.2,
+// path.3, and so on up to the maximum number of files. The entries are written by a background
+// goroutine so that Record never waits for the file.
+type Log struct {
+	clock    clock.Clock
+	path     string
+	maxSize  int64
+	maxFiles int
+	// Entries waiting to be written by the background goroutine.
+	queue chan record
+	// done is closed by Close, and stopped is closed when the background goroutine returns.
+	done      chan struct{}
+	stopped   chan struct{}
+	closeOnce sync.Once
+
+	mutex sync.Mutex
+	file  *os.File
+	size  int64
+}
+
+// record is a line to write, or a marker whose flushed is closed when all the lines queued
+// before it have been written.
+type record struct {
+	line    []byte
+	flushed chan struct{}
+}
+
+// Open opens the log file at path, which is created if it does not exist. The file is
+// rotated when its size exceeds maxSize bytes, and maxFiles rotated files are kept.
+func Open(path string, maxSize int64, maxFiles int, clk clock.Clock) (*Log, error) {
+	if len(path) == 0 {
+		return nil, errors.New("empty path")
+	}
+	if maxSize <= 0 {
+		return nil, errors.New("maxSize should be greater than zero")
+	}
+	if maxFiles < 0 {
+		return nil, errors.New("maxFiles should be equal to or greater than zero")
+	}
+	if clk == nil {
+		panic("clock is nil")
+	}
+
+	v := &Log{
+		clock:    clk,
+		path:     path,
+		maxSize:  maxSize,
+		maxFiles: maxFiles,
+		queue:    make(chan record, queueSize),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	if err := v.open(); err != nil {
+		return nil, err
+	}
+	go v.run()
+
+	return v, nil
+}
+
+func (r *Log) run() {
+	defer close(r.stopped)
+
+	for {
+		select {
+		case v := <-r.queue:
+			r.handle(v)
+		case <-r.done:
+			// Write the entries queued before Close.
+			for {
+				select {
+				case v := <-r.queue:
+					r.handle(v)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (r *Log) handle(v record) {
+	if v.flushed != nil {
+		close(v.flushed)
+		return
+	}
+	if err := r.write(v.line); err != nil {
+		logger.Errorf("failed to write the audit log: %v", err)
+	}
+}
+
+// XXX: Caller should lock the mutex
+func (r *Log) open() error {
+	file, err := os.OpenFile(r.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0640)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	r.file = file
+	r.size = info.Size()
+
+	return nil
+}
+
+func (r *Log) rotatedPath(n int) string {
+	return fmt.Sprintf("%v.%v", r.path, n)
+}
+
+// XXX: Caller should lock the mutex
+func (r *Log) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	r.file = nil
+
+	if r.maxFiles == 0 {
+		if err := os.Remove(r.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return r.open()
+	}
+	if err := os.Remove(r.rotatedPath(r.maxFiles)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for i := r.maxFiles - 1; i >= 1; i-- {
+		if err := os.Rename(r.rotatedPath(i), r.rotatedPath(i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.Rename(r.path, r.rotatedPath(1)); err != nil {
+		return err
+	}
+
+	return r.open()
+}
+
+// Record queues e to append it to the log. The current time is used if the time of e is zero.
+// It returns an error without waiting if the queue is full.
+func (r *Log) Record(e Entry) error {
+	if e.Time.IsZero() {
+		e.Time = r.clock.Now()
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	if len(line) > maxLineSize {
+		return errors.New("too long audit entry")
+	}
+
+	select {
+	case <-r.done:
+		return errors.New("closed audit log")
+	default:
+	}
+	select {
+	case r.queue <- record{line: line}:
+		return nil
+	default:
+		return errors.New("audit log queue is full")
+	}
+}
+
+func (r *Log) write(line []byte) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.file == nil {
+		// The previous rotation has failed.
+		if err := r.open(); err != nil {
+			return err
+		}
+	}
+	if r.size > 0 && r.size+int64(len(line)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			return fmt.Errorf("failed to rotate the audit log: %v", err)
+		}
+	}
+	n, err := r.file.Write(line)
+	r.size += int64(n)
+
+	return err
+}
+
+// flush waits until the entries recorded so far are written.
+func (r *Log) flush() {
+	flushed := make(chan struct{})
+	select {
+	case r.queue <- record{flushed: flushed}:
+	case <-r.stopped:
+		return
+	}
+	select {
+	case <-flushed:
+	case <-r.stopped:
+	}
+}
+
+// Query returns the latest entries selected by f in chronological order, including the ones
+// in the rotated files.
+func (r *Log) Query(f Filter) ([]Entry, error) {
+	limit := f.Limit
+	if limit <= 0 {
+		limit = DefaultQueryLimit
+	}
+	r.flush()
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	// Ring buffer of the latest entries.
+	ring := make([]Entry, 0, limit)
+	next := 0
+	for i := r.maxFiles; i >= 0; i-- {
+		path := r.path
+		if i > 0 {
+			path = r.rotatedPath(i)
+		}
+		err := readEntries(path, func(e Entry) {
+			if !f.match(e) {
+				return
+			}
+			if len(ring) < limit {
+				ring = append(ring, e)
+				return
+			}
+			ring[next] = e
+			next = (next + 1) % limit
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return append(ring[next:], ring[:next]...), nil
+}
+
+// readEntries calls f with each entry in the file at path. It does nothing if the file does
+// not exist, and skips the corrupted lines.
+func readEntries(path string, f func(Entry)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 4096), maxLineSize)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		f(e)
+	}
+
+	return scanner.Err()
+}
+
+// Close writes the entries already recorded, and closes the file.
+func (r *Log) Close() error {
+	r.closeOnce.Do(func() { close(r.done) })
+	<-r.stopped
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+
+	return err
+}
@@ -0,0 +1,175 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package audit
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/superkkt/cherry/testutil"
+)
+
+func newTestLog(t *testing.T, maxSize int64, maxFiles int) (*Log, *testutil.FakeClock, string) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	clock := testutil.NewFakeClock(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))
+	path := filepath.Join(dir, "audit.log")
+	log, err := Open(path, maxSize, maxFiles, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { log.Close() })
+
+	return log, clock, path
+}
+
+func TestQuery(t *testing.T) {
+	log, clock, _ := newTestLog(t, 1<<20, 2)
+
+	for i := 0; i < 10; i++ {
+		e := Entry{Actor: "app", Operation: "flow_mod", Detail: strconv.Itoa(i), DPID: strconv.Itoa(i % 2), Result: "ok"}
+		if err := log.Record(e); err != nil {
+			t.Fatal(err)
+		}
+		clock.Advance(time.Second)
+	}
+
+	entries, err := log.Query(Filter{DPID: "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 5 || entries[0].Detail != "1" || entries[4].Detail != "9" {
+		t.Fatalf("unexpected entries: %+v", entries)
+	}
+	if !entries[0].Time.Equal(time.Date(2018, 1, 1, 0, 0, 1, 0, time.UTC)) {
+		t.Fatalf("unexpected time: %v", entries[0].Time)
+	}
+
+	// The latest ones in chronological order.
+	entries, err = log.Query(Filter{Since: time.Date(2018, 1, 1, 0, 0, 2, 0, time.UTC), Limit: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 || entries[0].Detail != "7" || entries[2].Detail != "9" {
+		t.Fatalf("unexpected entries: %+v", entries)
+	}
+
+	entries, err = log.Query(Filter{Operation: "api"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("unexpected entries: %+v", entries)
+	}
+}
+
+func TestRotation(t *testing.T) {
+	// Each entry is about 130 bytes, so a file keeps three entries.
+	log, _, path := newTestLog(t, 400, 2)
+
+	for i := 0; i < 20; i++ {
+		if err := log.Record(Entry{Actor: "10.0.0.1:1000", Operation: "api", Detail: "PUT /api/v1/capture", Result: strconv.Itoa(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Wait for the background writer.
+	log.flush()
+
+	for _, p := range []string{path, path + ".1", path + ".2"} {
+		info, err := os.Stat(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Size() > 400 {
+			t.Fatalf("too large file %v: %v bytes", p, info.Size())
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Fatalf("unexpected rotated file: %v", err)
+	}
+
+	// The entries in the rotated files are also queried.
+	entries, err := log.Query(Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) < 4 || entries[len(entries)-1].Result != "19" {
+		t.Fatalf("unexpected entries: %+v", entries)
+	}
+	for i := 1; i < len(entries); i++ {
+		prev, _ := strconv.Atoi(entries[i-1].Result)
+		cur, _ := strconv.Atoi(entries[i].Result)
+		if cur != prev+1 {
+			t.Fatalf("entries are not in order: %+v", entries)
+		}
+	}
+
+	// Closed log rejects new entries.
+	log.Close()
+	if err := log.Record(Entry{}); err == nil {
+		t.Fatal("expected an error after close")
+	}
+}
+
+func TestRecordAsync(t *testing.T) {
+	log, _, path := newTestLog(t, 1<<20, 1)
+
+	// Hold the file so that the background writer cannot write.
+	log.mutex.Lock()
+	for i := 0; i < 10; i++ {
+		if err := log.Record(Entry{Operation: "api", Result: strconv.Itoa(i)}); err != nil {
+			log.mutex.Unlock()
+			t.Fatal(err)
+		}
+	}
+	log.mutex.Unlock()
+
+	// Query sees all the recorded entries.
+	entries, err := log.Query(Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 10 || entries[9].Result != "9" {
+		t.Fatalf("unexpected entries: %+v", entries)
+	}
+
+	// Close writes the remaining entries.
+	if err := log.Record(Entry{Operation: "api", Result: "10"}); err != nil {
+		t.Fatal(err)
+	}
+	log.Close()
+	n := 0
+	if err := readEntries(path, func(Entry) { n++ }); err != nil {
+		t.Fatal(err)
+	}
+	if n != 11 {
+		t.Fatalf("unexpected number of entries: %v", n)
+	}
+}
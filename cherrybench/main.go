@@ -0,0 +1,367 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+// cherrybench measures the performance of an OpenFlow controller in the way of cbench. It
+// emulates OpenFlow 1.3 switches that send PACKET_INs of the hosts behind them to the
+// controller, and counts the FLOW_MODs and PACKET_OUTs sent by the controller in response.
+//
+// In the latency mode, each switch has only one outstanding PACKET_IN, and sends the next one
+// when a response arrives, so the reciprocal of the rate of a switch is the round-trip latency
+// from a PACKET_IN to its FLOW_MOD or PACKET_OUT. In the throughput mode, each switch keeps
+// sending PACKET_INs as long as it has less than -window outstanding ones.
+//
+// Note that cherryd limits the rate of the PACKET_INs and blocks the table-miss packets of a
+// switch that floods it with PACKET_INs, so default.packet_in.rate_limit and
+// default.packet_in.flood_threshold should be 0 while benchmarking.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/superkkt/cherry/openflow/of13"
+	"github.com/superkkt/cherry/protocol"
+	"github.com/superkkt/cherry/testutil/ofswitch"
+)
+
+const usage = `Usage: cherrybench [options]
+
+Options:
+`
+
+var (
+	addr     = flag.String("addr", "localhost:6633", "OpenFlow address of the controller")
+	switches = flag.Int("switches", 16, "Number of the emulated switches")
+	macs     = flag.Int("macs", 1000, "Number of the hosts behind each switch")
+	loops    = flag.Int("loops", 10, "Number of the test runs")
+	duration = flag.Duration("duration", time.Second, "Duration of each test run")
+	warmup   = flag.Int("warmup", 1, "Number of the first test runs whose results are ignored")
+	delay    = flag.Duration("delay", 3*time.Second, "Time to wait for the handshakes before the first test run")
+	mode     = flag.String("mode", "latency", `Benchmark mode: "latency" or "throughput"`)
+	window   = flag.Int("window", 100, "Maximum outstanding PACKET_INs of each switch in the throughput mode")
+	timeout  = flag.Duration("timeout", 100*time.Millisecond, "Time after which the outstanding PACKET_INs are regarded as lost")
+)
+
+// Port of the emulated switches that the hosts are connected to.
+const hostPort = 1
+
+// emulator is an emulated switch that sends PACKET_INs to the controller.
+type emulator struct {
+	sw *ofswitch.Switch
+	// Index of the switch, which is used to make the host addresses unique among the switches.
+	index int
+	// Tokens of the outstanding PACKET_INs.
+	outstanding chan struct{}
+	// Number of the FLOW_MODs and PACKET_OUTs from the controller.
+	responses uint64
+	// Sequence number of the PACKET_INs, which selects the source and destination hosts.
+	seq int
+}
+
+func newEmulator(index, window int) *emulator {
+	r := &emulator{
+		index:       index,
+		outstanding: make(chan struct{}, window),
+	}
+	r.sw = ofswitch.New(ofswitch.Config{
+		DPID: uint64(index + 1),
+		Ports: []ofswitch.Port{
+			{Number: hostPort, Name: "eth1", MAC: net.HardwareAddr{0x02, 0, 0, byte(index >> 8), byte(index), 1}},
+		},
+		Manufacturer: "Cherry",
+		Hardware:     "cherrybench",
+		Description:  fmt.Sprintf("emulated switch %v", index+1),
+		DiscardFlows: true,
+		OnMessage:    r.onMessage,
+	})
+
+	return r
+}
+
+func (r *emulator) onMessage(msgType uint8) {
+	if msgType != of13.OFPT_FLOW_MOD && msgType != of13.OFPT_PACKET_OUT {
+		return
+	}
+	atomic.AddUint64(&r.responses, 1)
+	// Consider a PACKET_IN as answered. A controller that sends both FLOW_MOD and PACKET_OUT
+	// for a PACKET_IN frees two tokens, as cbench does.
+	select {
+	case <-r.outstanding:
+	default:
+	}
+}
+
+func (r *emulator) Responses() uint64 {
+	return atomic.LoadUint64(&r.responses)
+}
+
+// hostMAC returns the MAC address of the i-th host behind the switch.
+func (r *emulator) hostMAC(i int) net.HardwareAddr {
+	return net.HardwareAddr{0x06, byte(r.index >> 8), byte(r.index), byte(i >> 16), byte(i >> 8), byte(i)}
+}
+
+func (r *emulator) hostIP(i int) net.IP {
+	return net.IPv4(10, byte(r.index), byte(i>>8), byte(i)).To4()
+}
+
+// newPacket returns the next UDP packet from a host to another behind the switch.
+func (r *emulator) newPacket(numHosts int) ([]byte, error) {
+	src := r.seq % numHosts
+	dst := (r.seq + 1) % numHosts
+	r.seq++
+
+	udp := protocol.UDP{SrcPort: 1024, DstPort: 9, Payload: make([]byte, 18)}
+	udp.SetPseudoHeader(r.hostIP(src), r.hostIP(dst))
+	payload, err := udp.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	ip, err := protocol.NewIPv4(r.hostIP(src), r.hostIP(dst), 17, payload).MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	eth := protocol.Ethernet{
+		SrcMAC:  r.hostMAC(src),
+		DstMAC:  r.hostMAC(dst),
+		Type:    0x0800,
+		Payload: ip,
+	}
+
+	return eth.MarshalBinary()
+}
+
+// Run sends PACKET_INs until done is closed.
+func (r *emulator) Run(numHosts int, done <-chan struct{}) error {
+	for {
+		select {
+		case <-done:
+			return nil
+		case r.outstanding <- struct{}{}:
+		case <-time.After(*timeout):
+			// The controller has dropped the outstanding PACKET_INs.
+			r.reset()
+			continue
+		}
+
+		packet, err := r.newPacket(numHosts)
+		if err != nil {
+			return err
+		}
+		if err := r.sw.SendPacketIn(ofswitch.PacketIn{InPort: hostPort, Data: packet}); err != nil {
+			return err
+		}
+	}
+}
+
+func (r *emulator) reset() {
+	for {
+		select {
+		case <-r.outstanding:
+		default:
+			return
+		}
+	}
+}
+
+// result is the result of a test run.
+type result struct {
+	elapsed time.Duration
+	// Number of the responses of each switch.
+	responses []uint64
+}
+
+// Rate returns the number of the responses per second of all the switches.
+func (r result) Rate() float64 {
+	total := uint64(0)
+	for _, v := range r.responses {
+		total += v
+	}
+
+	return float64(total) / r.elapsed.Seconds()
+}
+
+// Latency returns the mean of the round-trip latencies of the switches, which is meaningful
+// only in the latency mode. It returns zero if a switch has no response.
+func (r result) Latency() time.Duration {
+	sum := time.Duration(0)
+	for _, v := range r.responses {
+		if v == 0 {
+			return 0
+		}
+		sum += r.elapsed / time.Duration(v)
+	}
+
+	return sum / time.Duration(len(r.responses))
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "cherrybench: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func validateFlags() error {
+	if *switches < 1 || *switches > math.MaxUint16 {
+		return fmt.Errorf("invalid number of switches: %v", *switches)
+	}
+	if *macs < 2 || *macs > 1<<24 {
+		return fmt.Errorf("invalid number of hosts: %v", *macs)
+	}
+	if *loops < 1 || *warmup < 0 || *warmup >= *loops {
+		return fmt.Errorf("invalid number of loops (%v) or warmup runs (%v)", *loops, *warmup)
+	}
+	if *duration <= 0 || *timeout <= 0 {
+		return errors.New("duration and timeout should be positive")
+	}
+	switch *mode {
+	case "latency":
+		*window = 1
+	case "throughput":
+		if *window < 1 {
+			return fmt.Errorf("invalid window: %v", *window)
+		}
+	default:
+		return fmt.Errorf("invalid mode: %v", *mode)
+	}
+
+	return nil
+}
+
+func run() error {
+	if err := validateFlags(); err != nil {
+		return err
+	}
+
+	emulators := make([]*emulator, *switches)
+	errc := make(chan error, *switches*2)
+	for i := range emulators {
+		emulators[i] = newEmulator(i, *window)
+		go func(sw *ofswitch.Switch) {
+			if err := sw.Dial(*addr); err != nil {
+				errc <- err
+				return
+			}
+			errc <- errors.New("connection closed by the controller")
+		}(emulators[i].sw)
+	}
+	fmt.Printf("cherrybench: connecting %v switches with %v hosts each to %v in the %v mode\n", *switches, *macs, *addr, *mode)
+
+	select {
+	case err := <-errc:
+		return err
+	case <-time.After(*delay):
+	}
+
+	done := make(chan struct{})
+	wg := new(sync.WaitGroup)
+	for _, e := range emulators {
+		wg.Add(1)
+		go func(e *emulator) {
+			defer wg.Done()
+			if err := e.Run(*macs, done); err != nil {
+				errc <- err
+			}
+		}(e)
+	}
+	defer func() {
+		close(done)
+		wg.Wait()
+	}()
+
+	results := make([]result, 0, *loops)
+	for i := 0; i < *loops; i++ {
+		v, err := measure(emulators, errc)
+		if err != nil {
+			return err
+		}
+		note := ""
+		if i < *warmup {
+			note = " (warmup)"
+		} else {
+			results = append(results, v)
+		}
+		fmt.Printf("run %v: %.2f responses/s", i+1, v.Rate())
+		if *mode == "latency" {
+			fmt.Printf(", mean latency %v", v.Latency())
+		}
+		fmt.Printf("%v\n", note)
+	}
+	printSummary(results)
+
+	return nil
+}
+
+// measure counts the responses of the switches for the duration of a test run.
+func measure(emulators []*emulator, errc <-chan error) (result, error) {
+	start := time.Now()
+	before := make([]uint64, len(emulators))
+	for i, e := range emulators {
+		before[i] = e.Responses()
+	}
+
+	select {
+	case err := <-errc:
+		return result{}, err
+	case <-time.After(*duration):
+	}
+
+	v := result{
+		elapsed:   time.Since(start),
+		responses: make([]uint64, len(emulators)),
+	}
+	for i, e := range emulators {
+		v.responses[i] = e.Responses() - before[i]
+	}
+
+	return v, nil
+}
+
+func printSummary(results []result) {
+	min, max, sum := math.MaxFloat64, 0.0, 0.0
+	for _, v := range results {
+		rate := v.Rate()
+		min = math.Min(min, rate)
+		max = math.Max(max, rate)
+		sum += rate
+	}
+	avg := sum / float64(len(results))
+	variance := 0.0
+	for _, v := range results {
+		variance += (v.Rate() - avg) * (v.Rate() - avg)
+	}
+	stdev := math.Sqrt(variance / float64(len(results)))
+
+	fmt.Printf("RESULT: %v switches %v tests min/max/avg/stdev = %.2f/%.2f/%.2f/%.2f responses/s\n", *switches, len(results), min, max, avg, stdev)
+}
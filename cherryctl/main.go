@@ -0,0 +1,748 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+// cherryctl is a command-line client of the REST API of cherryd.
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const usage = `Usage: cherryctl [options] <command> [arguments]
+
+Commands:
+  devices list                  List the connected devices.
+  devices inventory             List the hardware and software of the connected devices.
+  flows dump <dpid>             Dump the flows of a device.
+  flows add <dpid> [options]    Install a flow. See "cherryctl flows add <dpid> -h".
+  flows del <dpid> [options]    Remove the flows matched with the match fields.
+  selftest <dpid>               Run the self-test of the basic OpenFlow behavior on a device.
+  links                         List the links among the devices.
+  hosts                         List the registered hosts.
+  snapshot export [file]        Dump the state of the network in JSON to file, or stdout if omitted.
+  snapshot import <file>        Import the host locations of a snapshot. Add -force to import
+                                the locations on the devices that are not connected yet.
+  diff <before> <after>         Show the changes from a snapshot file to another. Add -live to
+                                compare a snapshot file with the live state instead of <after>,
+                                and -json to print the result in JSON.
+
+Options:
+`
+
+var (
+	addr     = flag.String("addr", "https://localhost:7070", "Base URL of the cherryd REST API")
+	insecure = flag.Bool("insecure", false, "Skip verifying the TLS certificate of the server")
+	timeout  = flag.Duration("timeout", 10*time.Second, "Timeout of each request")
+	user     = flag.String("user", "", "User name of the basic authentication. The password is read from $CHERRY_PASSWORD")
+	token    = flag.Bool("token", false, "Authenticate with the bearer token read from $CHERRY_TOKEN")
+)
+
+type client struct {
+	base string
+	http *http.Client
+}
+
+func newClient() *client {
+	return &client{
+		base: strings.TrimRight(*addr, "/"),
+		http: &http.Client{
+			Timeout: *timeout,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: *insecure},
+			},
+		},
+	}
+}
+
+// do sends a request of method to path with body encoded in JSON if it is not nil, and then
+// decodes the response into result if it is not nil.
+func (r *client) do(method, path string, body, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		v, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(v)
+	}
+
+	req, err := http.NewRequest(method, r.base+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if len(*user) > 0 {
+		req.SetBasicAuth(*user, os.Getenv("CHERRY_PASSWORD"))
+	} else if *token {
+		req.Header.Set("Authorization", "Bearer "+os.Getenv("CHERRY_TOKEN"))
+	}
+	resp, err := r.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		e := struct {
+			Error string `json:"error"`
+		}{}
+		if err := json.NewDecoder(resp.Body).Decode(&e); err != nil || len(e.Error) == 0 {
+			return fmt.Errorf("%v %v: %v", method, path, resp.Status)
+		}
+		return fmt.Errorf("%v %v: %v: %v", method, path, resp.Status, e.Error)
+	}
+	if result == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if err := run(newClient(), flag.Args()); err != nil {
+		fmt.Fprintf(os.Stderr, "cherryctl: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+var errUsage = errors.New(`invalid command (see "cherryctl -h")`)
+
+func run(c *client, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	switch args[0] {
+	case "devices":
+		if len(args) != 2 {
+			return errUsage
+		}
+		switch args[1] {
+		case "list":
+			return listDevices(c)
+		case "inventory":
+			return listInventory(c)
+		default:
+			return errUsage
+		}
+	case "flows":
+		if len(args) < 3 {
+			return errUsage
+		}
+		switch args[1] {
+		case "dump":
+			return dumpFlows(c, args[2])
+		case "add":
+			return addFlow(c, args[2], args[3:])
+		case "del":
+			return removeFlows(c, args[2], args[3:])
+		default:
+			return errUsage
+		}
+	case "selftest":
+		if len(args) != 2 {
+			return errUsage
+		}
+		return selfTest(c, args[1])
+	case "links":
+		return listLinks(c)
+	case "hosts":
+		return listHosts(c)
+	case "snapshot":
+		if len(args) < 2 {
+			return errUsage
+		}
+		switch args[1] {
+		case "export":
+			return exportSnapshot(c, args[2:])
+		case "import":
+			return importSnapshot(c, args[2:])
+		default:
+			return errUsage
+		}
+	case "diff":
+		return diffSnapshots(c, args[1:])
+	default:
+		return errUsage
+	}
+}
+
+func newTabWriter() *tabwriter.Writer {
+	return tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+}
+
+func listDevices(c *client) error {
+	resp := struct {
+		Devices []struct {
+			DPID         string `json:"dpid"`
+			Version      uint8  `json:"version"`
+			Manufacturer string `json:"manufacturer"`
+			Hardware     string `json:"hardware"`
+			NumPorts     int    `json:"n_ports"`
+			Drained      bool   `json:"drained"`
+			Role         string `json:"role"`
+			RTT          int64  `json:"rtt_usec"`
+		} `json:"devices"`
+	}{}
+	if err := c.do("GET", "/api/v1/devices", nil, &resp); err != nil {
+		return err
+	}
+
+	w := newTabWriter()
+	fmt.Fprintln(w, "DPID\tVERSION\tMANUFACTURER\tHARDWARE\tPORTS\tROLE\tDRAINED\tRTT")
+	for _, v := range resp.Devices {
+		fmt.Fprintf(w, "%v\t0x%02x\t%v\t%v\t%v\t%v\t%v\t%v\n", v.DPID, v.Version, v.Manufacturer, v.Hardware, v.NumPorts, v.Role, v.Drained, time.Duration(v.RTT)*time.Microsecond)
+	}
+
+	return w.Flush()
+}
+
+func listInventory(c *client) error {
+	resp := struct {
+		Devices []struct {
+			DPID         string `json:"dpid"`
+			Manufacturer string `json:"manufacturer"`
+			Hardware     string `json:"hardware"`
+			Software     string `json:"software"`
+			Serial       string `json:"serial"`
+			Description  string `json:"description"`
+		} `json:"devices"`
+	}{}
+	if err := c.do("GET", "/api/v1/devices", nil, &resp); err != nil {
+		return err
+	}
+
+	w := newTabWriter()
+	fmt.Fprintln(w, "DPID\tMANUFACTURER\tHARDWARE\tSOFTWARE\tSERIAL\tDESCRIPTION")
+	for _, v := range resp.Devices {
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\n", v.DPID, v.Manufacturer, v.Hardware, v.Software, v.Serial, v.Description)
+	}
+
+	return w.Flush()
+}
+
+// matchParam is the match fields of the REST API. Zero values are wildcards.
+type matchParam struct {
+	InPort     uint32 `json:"in_port"`
+	SrcMAC     string `json:"src_mac"`
+	DstMAC     string `json:"dst_mac"`
+	EtherType  uint16 `json:"ether_type"`
+	IPProtocol uint8  `json:"ip_protocol"`
+	SrcIP      string `json:"src_ip"`
+	DstIP      string `json:"dst_ip"`
+	SrcPort    uint16 `json:"src_port"`
+	DstPort    uint16 `json:"dst_port"`
+}
+
+func (r matchParam) String() string {
+	fields := make([]string, 0)
+	add := func(name string, value interface{}, wildcard bool) {
+		if !wildcard {
+			fields = append(fields, fmt.Sprintf("%v=%v", name, value))
+		}
+	}
+	add("in_port", r.InPort, r.InPort == 0)
+	add("src_mac", r.SrcMAC, r.SrcMAC == "")
+	add("dst_mac", r.DstMAC, r.DstMAC == "")
+	add("ether_type", fmt.Sprintf("0x%04x", r.EtherType), r.EtherType == 0)
+	add("ip_protocol", r.IPProtocol, r.IPProtocol == 0)
+	add("src_ip", r.SrcIP, r.SrcIP == "")
+	add("dst_ip", r.DstIP, r.DstIP == "")
+	add("src_port", r.SrcPort, r.SrcPort == 0)
+	add("dst_port", r.DstPort, r.DstPort == 0)
+	if len(fields) == 0 {
+		return "*"
+	}
+
+	return strings.Join(fields, ",")
+}
+
+// addMatchFlags registers the flags of the match fields into fs. The returned function should be
+// called after parsing fs to copy the integer flags into m.
+func addMatchFlags(fs *flag.FlagSet, m *matchParam) func() {
+	var inPort, etherType, ipProtocol, srcPort, dstPort uint
+	fs.UintVar(&inPort, "in-port", 0, "Ingress port number")
+	fs.StringVar(&m.SrcMAC, "src-mac", "", "Source MAC address")
+	fs.StringVar(&m.DstMAC, "dst-mac", "", "Destination MAC address")
+	fs.UintVar(&etherType, "ether-type", 0, "Ethernet type, e.g., 0x0800")
+	fs.UintVar(&ipProtocol, "ip-protocol", 0, "IP protocol number, e.g., 6 for TCP")
+	fs.StringVar(&m.SrcIP, "src-ip", "", "Source IP address in CIDR notation")
+	fs.StringVar(&m.DstIP, "dst-ip", "", "Destination IP address in CIDR notation")
+	fs.UintVar(&srcPort, "src-port", 0, "TCP or UDP source port number")
+	fs.UintVar(&dstPort, "dst-port", 0, "TCP or UDP destination port number")
+
+	return func() {
+		m.InPort = uint32(inPort)
+		m.EtherType = uint16(etherType)
+		m.IPProtocol = uint8(ipProtocol)
+		m.SrcPort = uint16(srcPort)
+		m.DstPort = uint16(dstPort)
+	}
+}
+
+func parseFlags(fs *flag.FlagSet, args []string, apply func()) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %v", fs.Args())
+	}
+	apply()
+
+	return nil
+}
+
+func selfTest(c *client, dpid string) error {
+	resp := struct {
+		Passed bool `json:"passed"`
+		Steps  []struct {
+			Name    string `json:"name"`
+			Result  string `json:"result"`
+			Elapsed int64  `json:"elapsed_usec"`
+			Error   string `json:"error"`
+		} `json:"steps"`
+	}{}
+	if err := c.do("POST", "/api/v1/devices/"+dpid+"/selftest", nil, &resp); err != nil {
+		return err
+	}
+
+	w := newTabWriter()
+	fmt.Fprintln(w, "STEP\tRESULT\tTIME\tERROR")
+	for _, v := range resp.Steps {
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\n", v.Name, v.Result, time.Duration(v.Elapsed)*time.Microsecond, v.Error)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if !resp.Passed {
+		return errors.New("self-test failed")
+	}
+
+	return nil
+}
+
+func dumpFlows(c *client, dpid string) error {
+	resp := struct {
+		Flows []struct {
+			TableID     uint8      `json:"table_id"`
+			Priority    uint16     `json:"priority"`
+			Cookie      uint64     `json:"cookie"`
+			DurationSec uint32     `json:"duration_sec"`
+			IdleTimeout uint16     `json:"idle_timeout"`
+			HardTimeout uint16     `json:"hard_timeout"`
+			PacketCount uint64     `json:"packet_count"`
+			ByteCount   uint64     `json:"byte_count"`
+			Match       matchParam `json:"match"`
+		} `json:"flows"`
+		Collected *time.Time `json:"collected"`
+	}{}
+	if err := c.do("GET", "/api/v1/devices/"+dpid+"/flows", nil, &resp); err != nil {
+		return err
+	}
+	if resp.Collected == nil {
+		fmt.Println("flows are not collected yet")
+		return nil
+	}
+
+	fmt.Printf("collected at %v\n", resp.Collected.Local())
+	w := newTabWriter()
+	fmt.Fprintln(w, "TABLE\tPRIORITY\tCOOKIE\tDURATION\tIDLE\tHARD\tPACKETS\tBYTES\tMATCH")
+	for _, v := range resp.Flows {
+		fmt.Fprintf(w, "%v\t%v\t0x%x\t%vs\t%v\t%v\t%v\t%v\t%v\n", v.TableID, v.Priority, v.Cookie, v.DurationSec, v.IdleTimeout, v.HardTimeout, v.PacketCount, v.ByteCount, v.Match)
+	}
+
+	return w.Flush()
+}
+
+func addFlow(c *client, dpid string, args []string) error {
+	param := struct {
+		Match       matchParam `json:"match"`
+		Priority    uint16     `json:"priority"`
+		IdleTimeout uint16     `json:"idle_timeout"`
+		HardTimeout uint16     `json:"hard_timeout"`
+		Cookie      uint64     `json:"cookie"`
+		Output      string     `json:"output"`
+	}{}
+
+	fs := flag.NewFlagSet("flows add", flag.ContinueOnError)
+	apply := addMatchFlags(fs, &param.Match)
+	var priority, idle, hard uint
+	fs.UintVar(&priority, "priority", 100, "Priority of the flow")
+	fs.UintVar(&idle, "idle-timeout", 0, "Idle timeout in seconds. 0 means no timeout")
+	fs.UintVar(&hard, "hard-timeout", 0, "Hard timeout in seconds. 0 means no timeout")
+	fs.Uint64Var(&param.Cookie, "cookie", 0, "Cookie of the flow, up to 48 bits")
+	fs.StringVar(&param.Output, "output", "", `Port number, "flood", "all", "controller" or "in_port". Empty output drops the packets`)
+	if err := parseFlags(fs, args, apply); err != nil {
+		return err
+	}
+	if priority > 0xFFFF || idle > 0xFFFF || hard > 0xFFFF {
+		return errors.New("too large priority or timeout")
+	}
+	param.Priority = uint16(priority)
+	param.IdleTimeout = uint16(idle)
+	param.HardTimeout = uint16(hard)
+
+	return c.do("POST", "/api/v1/devices/"+dpid+"/flows", &param, nil)
+}
+
+func removeFlows(c *client, dpid string, args []string) error {
+	param := matchParam{}
+	fs := flag.NewFlagSet("flows del", flag.ContinueOnError)
+	apply := addMatchFlags(fs, &param)
+	if err := parseFlags(fs, args, apply); err != nil {
+		return err
+	}
+
+	return c.do("DELETE", "/api/v1/devices/"+dpid+"/flows", &param, nil)
+}
+
+func listLinks(c *client) error {
+	resp := struct {
+		Links []struct {
+			DPID1 string `json:"dpid1"`
+			Port1 uint32 `json:"port1"`
+			DPID2 string `json:"dpid2"`
+			Port2 uint32 `json:"port2"`
+		} `json:"links"`
+	}{}
+	if err := c.do("GET", "/api/v1/links", nil, &resp); err != nil {
+		return err
+	}
+
+	w := newTabWriter()
+	fmt.Fprintln(w, "DPID\tPORT\tDPID\tPORT")
+	for _, v := range resp.Links {
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\n", v.DPID1, v.Port1, v.DPID2, v.Port2)
+	}
+
+	return w.Flush()
+}
+
+func listHosts(c *client) error {
+	resp := struct {
+		Hosts []struct {
+			ID          string `json:"id"`
+			IP          string `json:"ip"`
+			Port        string `json:"port"`
+			MAC         string `json:"mac"`
+			Description string `json:"description"`
+			Stale       bool   `json:"stale"`
+		} `json:"hosts"`
+	}{}
+	if err := c.do("GET", "/api/v1/host", nil, &resp); err != nil {
+		return err
+	}
+
+	w := newTabWriter()
+	fmt.Fprintln(w, "ID\tIP\tMAC\tPORT\tSTALE\tDESCRIPTION")
+	for _, v := range resp.Hosts {
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\n", v.ID, v.IP, v.MAC, v.Port, v.Stale, v.Description)
+	}
+
+	return w.Flush()
+}
+
+func exportSnapshot(c *client, args []string) error {
+	if len(args) > 1 {
+		return errUsage
+	}
+	snapshot := json.RawMessage{}
+	if err := c.do("GET", "/api/v1/snapshot", nil, &snapshot); err != nil {
+		return err
+	}
+	v := new(bytes.Buffer)
+	if err := json.Indent(v, snapshot, "", "  "); err != nil {
+		return err
+	}
+	v.WriteByte('\n')
+
+	if len(args) == 0 {
+		_, err := v.WriteTo(os.Stdout)
+		return err
+	}
+
+	return ioutil.WriteFile(args[0], v.Bytes(), 0644)
+}
+
+func importSnapshot(c *client, args []string) error {
+	fs := flag.NewFlagSet("snapshot import", flag.ContinueOnError)
+	force := fs.Bool("force", false, "Import the locations on the devices that are not connected yet")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errUsage
+	}
+
+	data, err := ioutil.ReadFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	param := struct {
+		Hosts json.RawMessage `json:"hosts"`
+		Force bool            `json:"force"`
+	}{}
+	if err := json.Unmarshal(data, &param); err != nil {
+		return fmt.Errorf("invalid snapshot: %v", err)
+	}
+	param.Force = *force
+
+	resp := struct {
+		Updated   int      `json:"updated"`
+		Unchanged int      `json:"unchanged"`
+		Failed    []string `json:"failed"`
+	}{}
+	if err := c.do("POST", "/api/v1/import", &param, &resp); err != nil {
+		return err
+	}
+
+	fmt.Printf("updated=%v, unchanged=%v, failed=%v\n", resp.Updated, resp.Unchanged, len(resp.Failed))
+	for _, v := range resp.Failed {
+		fmt.Printf("  %v\n", v)
+	}
+
+	return nil
+}
+
+// snapshotDiff is the result of the snapshot diff API. Only the fields that are displayed are decoded.
+type snapshotDiff struct {
+	Before         time.Time `json:"before"`
+	After          time.Time `json:"after"`
+	AddedDevices   []string  `json:"added_devices"`
+	RemovedDevices []string  `json:"removed_devices"`
+	Ports          []struct {
+		DPID   string    `json:"dpid"`
+		Number uint32    `json:"number"`
+		Before *portInfo `json:"before"`
+		After  *portInfo `json:"after"`
+	} `json:"ports"`
+	AddedLinks   []linkInfo `json:"added_links"`
+	RemovedLinks []linkInfo `json:"removed_links"`
+	Hosts        []struct {
+		MAC    string        `json:"mac"`
+		IP     string        `json:"ip"`
+		Before *hostLocation `json:"before"`
+		After  *hostLocation `json:"after"`
+	} `json:"hosts"`
+	Flows []struct {
+		DPID     string    `json:"dpid"`
+		TableID  uint8     `json:"table_id"`
+		Priority uint16    `json:"priority"`
+		Match    string    `json:"match"`
+		Before   *flowInfo `json:"before"`
+		After    *flowInfo `json:"after"`
+	} `json:"flows"`
+}
+
+type portInfo struct {
+	Name    string `json:"name"`
+	AdminUp bool   `json:"admin_up"`
+	LinkUp  bool   `json:"link_up"`
+	Speed   uint64 `json:"speed"`
+}
+
+func (r *portInfo) String() string {
+	if r == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%v admin=%v link=%v speed=%vMbps", r.Name, upDown(r.AdminUp), upDown(r.LinkUp), r.Speed)
+}
+
+func upDown(up bool) string {
+	if up {
+		return "up"
+	}
+	return "down"
+}
+
+type linkInfo struct {
+	DPID1    string `json:"dpid1"`
+	Port1    uint32 `json:"port1"`
+	DPID2    string `json:"dpid2"`
+	Port2    uint32 `json:"port2"`
+	Indirect bool   `json:"indirect"`
+}
+
+type hostLocation struct {
+	DPID string `json:"dpid"`
+	Port uint32 `json:"port"`
+}
+
+func (r *hostLocation) String() string {
+	if r == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%v/%v", r.DPID, r.Port)
+}
+
+type flowInfo struct {
+	Cookie      uint64 `json:"cookie"`
+	IdleTimeout uint16 `json:"idle_timeout"`
+	HardTimeout uint16 `json:"hard_timeout"`
+	Owner       string `json:"owner"`
+}
+
+func (r *flowInfo) String() string {
+	if r == nil {
+		return "-"
+	}
+	owner := r.Owner
+	if owner == "" {
+		owner = "-"
+	}
+	return fmt.Sprintf("cookie=0x%x idle=%v hard=%v owner=%v", r.Cookie, r.IdleTimeout, r.HardTimeout, owner)
+}
+
+func diffSnapshots(c *client, args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	live := fs.Bool("live", false, "Compare the snapshot with the live state of the network")
+	raw := fs.Bool("json", false, "Print the result in JSON")
+	// The flags may come after the file names, e.g., "cherryctl diff snapshot.json -live".
+	files := []string{}
+	for {
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		files = append(files, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	if (*live && len(files) != 1) || (!*live && len(files) != 2) {
+		return errUsage
+	}
+
+	param := struct {
+		Before json.RawMessage `json:"before"`
+		// Omitted to compare with the live state.
+		After json.RawMessage `json:"after,omitempty"`
+	}{}
+	var err error
+	if param.Before, err = ioutil.ReadFile(files[0]); err != nil {
+		return err
+	}
+	if !*live {
+		if param.After, err = ioutil.ReadFile(files[1]); err != nil {
+			return err
+		}
+	}
+
+	resp := json.RawMessage{}
+	if err := c.do("POST", "/api/v1/snapshot/diff", &param, &resp); err != nil {
+		return err
+	}
+	if *raw {
+		v := new(bytes.Buffer)
+		if err := json.Indent(v, resp, "", "  "); err != nil {
+			return err
+		}
+		v.WriteByte('\n')
+		_, err := v.WriteTo(os.Stdout)
+		return err
+	}
+
+	diff := snapshotDiff{}
+	if err := json.Unmarshal(resp, &diff); err != nil {
+		return err
+	}
+	printSnapshotDiff(diff)
+
+	return nil
+}
+
+func printSnapshotDiff(diff snapshotDiff) {
+	fmt.Printf("changes from %v to %v\n", diff.Before.Local(), diff.After.Local())
+	if len(diff.AddedDevices)+len(diff.RemovedDevices)+len(diff.Ports)+len(diff.AddedLinks)+len(diff.RemovedLinks)+len(diff.Hosts)+len(diff.Flows) == 0 {
+		fmt.Println("no changes")
+		return
+	}
+
+	if len(diff.AddedDevices)+len(diff.RemovedDevices) > 0 {
+		fmt.Println("\nDEVICES")
+		for _, v := range diff.AddedDevices {
+			fmt.Printf("+ %v\n", v)
+		}
+		for _, v := range diff.RemovedDevices {
+			fmt.Printf("- %v\n", v)
+		}
+	}
+
+	if len(diff.Ports) > 0 {
+		fmt.Println("\nPORTS")
+		w := newTabWriter()
+		fmt.Fprintln(w, "DPID\tPORT\tBEFORE\tAFTER")
+		for _, v := range diff.Ports {
+			fmt.Fprintf(w, "%v\t%v\t%v\t%v\n", v.DPID, v.Number, v.Before, v.After)
+		}
+		w.Flush()
+	}
+
+	if len(diff.AddedLinks)+len(diff.RemovedLinks) > 0 {
+		fmt.Println("\nLINKS")
+		for _, v := range diff.AddedLinks {
+			fmt.Printf("+ %v/%v <-> %v/%v\n", v.DPID1, v.Port1, v.DPID2, v.Port2)
+		}
+		for _, v := range diff.RemovedLinks {
+			fmt.Printf("- %v/%v <-> %v/%v\n", v.DPID1, v.Port1, v.DPID2, v.Port2)
+		}
+	}
+
+	if len(diff.Hosts) > 0 {
+		fmt.Println("\nHOSTS")
+		w := newTabWriter()
+		fmt.Fprintln(w, "MAC\tIP\tBEFORE\tAFTER")
+		for _, v := range diff.Hosts {
+			fmt.Fprintf(w, "%v\t%v\t%v\t%v\n", v.MAC, v.IP, v.Before, v.After)
+		}
+		w.Flush()
+	}
+
+	if len(diff.Flows) > 0 {
+		fmt.Println("\nFLOWS")
+		w := newTabWriter()
+		fmt.Fprintln(w, "DPID\tTABLE\tPRIORITY\tMATCH\tBEFORE\tAFTER")
+		for _, v := range diff.Flows {
+			fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\n", v.DPID, v.TableID, v.Priority, v.Match, v.Before, v.After)
+		}
+		w.Flush()
+	}
+}
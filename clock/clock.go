@@ -0,0 +1,97 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+// Package clock abstracts the time source so that time-driven behavior can be
+// tested deterministically with a fake clock.
+package clock
+
+import (
+	"time"
+)
+
+// Clock provides the current time and timers. Components that depend on time
+// should take a Clock instead of calling the functions of the time package.
+type Clock interface {
+	Now() time.Time
+	Since(t time.Time) time.Duration
+	// After waits for the duration to elapse and then sends the current time on
+	// the returned channel. Use NewTimer instead on hot paths to reuse the timer.
+	After(d time.Duration) <-chan time.Time
+	NewTimer(d time.Duration) Timer
+	NewTicker(d time.Duration) Ticker
+}
+
+// Timer is a single event timer that can be reused by calling Reset.
+type Timer interface {
+	C() <-chan time.Time
+	// Reset changes the timer to expire after duration d. It returns true if the
+	// timer had been active.
+	Reset(d time.Duration) bool
+	// Stop prevents the timer from firing. It returns true if the timer had been active.
+	Stop() bool
+}
+
+// Ticker delivers ticks at intervals.
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// Real is the clock of the operating system.
+var Real Clock = realClock{}
+
+type realClock struct{}
+
+func (r realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (r realClock) Since(t time.Time) time.Duration {
+	return time.Since(t)
+}
+
+func (r realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (r realClock) NewTimer(d time.Duration) Timer {
+	return &realTimer{time.NewTimer(d)}
+}
+
+func (r realClock) NewTicker(d time.Duration) Ticker {
+	return &realTicker{time.NewTicker(d)}
+}
+
+type realTimer struct {
+	*time.Timer
+}
+
+func (r *realTimer) C() <-chan time.Time {
+	return r.Timer.C
+}
+
+type realTicker struct {
+	*time.Ticker
+}
+
+func (r *realTicker) C() <-chan time.Time {
+	return r.Ticker.C
+}
@@ -0,0 +1,102 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package clock
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Packages that should get the time only from a Clock.
+var convertedPackages = []string{
+	"../network",
+	"../northbound/app/blacklist",
+	"../northbound/app/dhcp",
+	"../northbound/app/firewall",
+	"../northbound/app/hosttracker",
+	"../northbound/app/intent",
+	"../northbound/app/l2switch",
+	"../northbound/app/qos",
+	"../northbound/app/router",
+	"../openflow/transceiver",
+	"../ratelog",
+}
+
+// Files that are allowed to use the time package directly.
+var exemptFiles = map[string]string{
+	"../openflow/transceiver/stream.go": "socket deadlines are based on the wall clock",
+}
+
+// Functions of the time package that should be called through a Clock.
+var forbiddenFuncs = map[string]bool{
+	"After":     true,
+	"AfterFunc": true,
+	"NewTicker": true,
+	"NewTimer":  true,
+	"Now":       true,
+	"Since":     true,
+	"Sleep":     true,
+	"Tick":      true,
+	"Until":     true,
+}
+
+func TestNoDirectTimeUsage(t *testing.T) {
+	for _, dir := range convertedPackages {
+		files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(files) == 0 {
+			t.Fatalf("no source file in %v", dir)
+		}
+
+		for _, file := range files {
+			if strings.HasSuffix(file, "_test.go") {
+				continue
+			}
+			if _, ok := exemptFiles[filepath.ToSlash(file)]; ok {
+				continue
+			}
+
+			fset := token.NewFileSet()
+			f, err := parser.ParseFile(fset, file, nil, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ast.Inspect(f, func(n ast.Node) bool {
+				sel, ok := n.(*ast.SelectorExpr)
+				if !ok {
+					return true
+				}
+				pkg, ok := sel.X.(*ast.Ident)
+				if ok && pkg.Name == "time" && forbiddenFuncs[sel.Sel.Name] {
+					t.Errorf("%v: time.%v should be called through a Clock", fset.Position(sel.Pos()), sel.Sel.Name)
+				}
+				return true
+			})
+		}
+	}
+}
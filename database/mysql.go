@@ -34,7 +34,10 @@ import (
 	"time"
 
 	"github.com/superkkt/cherry/network"
+	"github.com/superkkt/cherry/northbound/app/blacklist"
 	"github.com/superkkt/cherry/northbound/app/discovery"
+	"github.com/superkkt/cherry/northbound/app/firewall"
+	"github.com/superkkt/cherry/northbound/app/intent"
 	"github.com/superkkt/cherry/northbound/app/proxyarp"
 	"github.com/superkkt/cherry/northbound/app/virtualip"
 
@@ -296,7 +299,7 @@ func (r *MySQL) Location(mac net.HardwareAddr) (dpid string, port uint32, status
 
 func (r *MySQL) Switches() (sw []network.Switch, err error) {
 	f := func(tx *sql.Tx) error {
-		rows, err := tx.Query("SELECT id, dpid, n_ports, first_port, first_printed_port, description FROM switch ORDER BY id DESC")
+		rows, err := tx.Query("SELECT id, dpid, n_ports, first_port, first_printed_port, description, drained FROM switch ORDER BY id DESC")
 		if err != nil {
 			return err
 		}
@@ -304,7 +307,7 @@ func (r *MySQL) Switches() (sw []network.Switch, err error) {
 
 		for rows.Next() {
 			v := network.Switch{}
-			if err := rows.Scan(&v.ID, &v.DPID, &v.NumPorts, &v.FirstPort, &v.FirstPrintedPort, &v.Description); err != nil {
+			if err := rows.Scan(&v.ID, &v.DPID, &v.NumPorts, &v.FirstPort, &v.FirstPrintedPort, &v.Description, &v.Drained); err != nil {
 				return err
 			}
 			sw = append(sw, v)
@@ -370,7 +373,7 @@ func (r *MySQL) addPorts(tx *sql.Tx, swID uint64, firstPort, n_ports uint16) err
 
 func (r *MySQL) Switch(dpid uint64) (sw network.Switch, ok bool, err error) {
 	f := func(tx *sql.Tx) error {
-		row, err := tx.Query("SELECT id, dpid, n_ports, first_port, first_printed_port, description FROM switch WHERE dpid = ?", dpid)
+		row, err := tx.Query("SELECT id, dpid, n_ports, first_port, first_printed_port, description, drained FROM switch WHERE dpid = ?", dpid)
 		if err != nil {
 			return err
 		}
@@ -380,7 +383,7 @@ func (r *MySQL) Switch(dpid uint64) (sw network.Switch, ok bool, err error) {
 		if !row.Next() {
 			return nil
 		}
-		if err := row.Scan(&sw.ID, &sw.DPID, &sw.NumPorts, &sw.FirstPort, &sw.FirstPrintedPort, &sw.Description); err != nil {
+		if err := row.Scan(&sw.ID, &sw.DPID, &sw.NumPorts, &sw.FirstPort, &sw.FirstPrintedPort, &sw.Description, &sw.Drained); err != nil {
 			return err
 		}
 		ok = true
@@ -420,6 +423,39 @@ func (r *MySQL) RemoveSwitch(id uint64) (ok bool, err error) {
 	return ok, nil
 }
 
+func (r *MySQL) SetSwitchDrained(dpid uint64, drained bool) (ok bool, err error) {
+	f := func(tx *sql.Tx) error {
+		// Lock the row to distinguish an unknown switch from an unchanged one.
+		row, err := tx.Query("SELECT id FROM switch WHERE dpid = ? FOR UPDATE", dpid)
+		if err != nil {
+			return err
+		}
+		defer row.Close()
+
+		// Unknown switch?
+		if !row.Next() {
+			return nil
+		}
+		var id uint64
+		if err := row.Scan(&id); err != nil {
+			return err
+		}
+		row.Close()
+
+		if _, err := tx.Exec("UPDATE switch SET drained = ? WHERE id = ?", drained, id); err != nil {
+			return err
+		}
+		ok = true
+
+		return nil
+	}
+	if err = r.query(f); err != nil {
+		return false, err
+	}
+
+	return ok, nil
+}
+
 func (r *MySQL) SwitchPorts(swID uint64) (ports []network.SwitchPort, err error) {
 	f := func(tx *sql.Tx) error {
 		qry := `SELECT A.id, A.number, B.first_port
@@ -1251,6 +1287,495 @@ func (r *MySQL) RemoveVIP(id uint64) (ok bool, err error) {
 	return ok, nil
 }
 
+func (r *MySQL) FirewallRules() (result []network.FirewallRule, err error) {
+	f := func(tx *sql.Tx) error {
+		qry := `SELECT id, priority, allow, INET_NTOA(src_address), src_mask, INET_NTOA(dst_address), dst_mask, 
+			protocol, src_port, dst_port, description 
+			FROM firewall 
+			ORDER BY priority DESC, id ASC`
+		rows, err := tx.Query(qry)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var v network.FirewallRule
+			var allow bool
+			var src, dst string
+			var srcMask, dstMask uint8
+			if err := rows.Scan(&v.ID, &v.Priority, &allow, &src, &srcMask, &dst, &dstMask, &v.Protocol, &v.SrcPort, &v.DstPort, &v.Description); err != nil {
+				return err
+			}
+			v.Action = "deny"
+			if allow {
+				v.Action = "allow"
+			}
+			// Zero mask means any address.
+			if srcMask > 0 {
+				v.Src = fmt.Sprintf("%v/%v", src, srcMask)
+			}
+			if dstMask > 0 {
+				v.Dst = fmt.Sprintf("%v/%v", dst, dstMask)
+			}
+			result = append(result, v)
+		}
+
+		return rows.Err()
+	}
+	if err = r.query(f); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *MySQL) GetFirewallRules() (result []firewall.Rule, err error) {
+	rules, err := r.FirewallRules()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range rules {
+		rule := firewall.Rule{
+			ID:       v.ID,
+			Priority: v.Priority,
+			Allow:    v.Action == "allow",
+			Protocol: v.Protocol,
+			SrcPort:  v.SrcPort,
+			DstPort:  v.DstPort,
+		}
+		if len(v.Src) > 0 {
+			if _, rule.Src, err = net.ParseCIDR(v.Src); err != nil {
+				return nil, fmt.Errorf("invalid source network: %v", v.Src)
+			}
+		}
+		if len(v.Dst) > 0 {
+			if _, rule.Dst, err = net.ParseCIDR(v.Dst); err != nil {
+				return nil, fmt.Errorf("invalid destination network: %v", v.Dst)
+			}
+		}
+		result = append(result, rule)
+	}
+
+	return result, nil
+}
+
+// splitCIDR returns the address and mask length of cidr, or zeros if cidr is empty.
+func splitCIDR(cidr string) (address string, mask int, err error) {
+	if len(cidr) == 0 {
+		return "0.0.0.0", 0, nil
+	}
+	_, n, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return "", 0, err
+	}
+	mask, _ = n.Mask.Size()
+
+	return n.IP.String(), mask, nil
+}
+
+func (r *MySQL) AddFirewallRule(rule network.FirewallRuleParam) (id uint64, err error) {
+	src, srcMask, err := splitCIDR(rule.Src)
+	if err != nil {
+		return 0, err
+	}
+	dst, dstMask, err := splitCIDR(rule.Dst)
+	if err != nil {
+		return 0, err
+	}
+
+	f := func(tx *sql.Tx) error {
+		qry := `INSERT INTO firewall (priority, allow, src_address, src_mask, dst_address, dst_mask, protocol, src_port, dst_port, description) 
+			VALUES (?, ?, INET_ATON(?), ?, INET_ATON(?), ?, ?, ?, ?, ?)`
+		result, err := tx.Exec(qry, rule.Priority, rule.Action == "allow", src, srcMask, dst, dstMask, rule.Protocol, rule.SrcPort, rule.DstPort, rule.Description)
+		if err != nil {
+			return err
+		}
+		v, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		id = uint64(v)
+
+		return nil
+	}
+	if err = r.query(f); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (r *MySQL) RemoveFirewallRule(id uint64) (ok bool, err error) {
+	f := func(tx *sql.Tx) error {
+		result, err := tx.Exec("DELETE FROM firewall WHERE id = ?", id)
+		if err != nil {
+			return err
+		}
+		nRows, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if nRows > 0 {
+			ok = true
+		}
+
+		return nil
+	}
+	if err = r.query(f); err != nil {
+		return false, err
+	}
+
+	return ok, nil
+}
+
+func (r *MySQL) Intents() (result []network.Intent, err error) {
+	intents, err := r.getIntents()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range intents {
+		src, ok, err := r.Host(v.src)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("unknown source host (ID=%v)", v.src)
+		}
+
+		dst, ok, err := r.Host(v.dst)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("unknown destination host (ID=%v)", v.dst)
+		}
+
+		result = append(result, network.Intent{
+			ID:          v.id,
+			SrcHost:     src,
+			DstHost:     dst,
+			Bandwidth:   v.bandwidth,
+			Description: v.description,
+		})
+	}
+
+	return result, nil
+}
+
+type registeredIntent struct {
+	id          uint64
+	src         uint64
+	dst         uint64
+	bandwidth   uint32
+	description string
+}
+
+func (r *MySQL) getIntents() (result []registeredIntent, err error) {
+	f := func(tx *sql.Tx) error {
+		qry := "SELECT id, src_host_id, dst_host_id, bandwidth, description FROM intent ORDER BY id ASC"
+		rows, err := tx.Query(qry)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var v registeredIntent
+			if err := rows.Scan(&v.id, &v.src, &v.dst, &v.bandwidth, &v.description); err != nil {
+				return err
+			}
+			result = append(result, v)
+		}
+
+		return rows.Err()
+	}
+	if err = r.query(f); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *MySQL) GetIntents() (result []intent.Intent, err error) {
+	intents, err := r.Intents()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range intents {
+		src, err := net.ParseMAC(v.SrcHost.MAC)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MAC address: %v", v.SrcHost.MAC)
+		}
+		dst, err := net.ParseMAC(v.DstHost.MAC)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MAC address: %v", v.DstHost.MAC)
+		}
+
+		result = append(result, intent.Intent{
+			ID:        v.ID,
+			SrcMAC:    src,
+			DstMAC:    dst,
+			Bandwidth: v.Bandwidth,
+		})
+	}
+
+	return result, nil
+}
+
+func (r *MySQL) AddIntent(param network.IntentParam) (id uint64, err error) {
+	f := func(tx *sql.Tx) error {
+		qry := "INSERT INTO intent (src_host_id, dst_host_id, bandwidth, description) VALUES (?, ?, ?, ?)"
+		result, err := tx.Exec(qry, param.SrcHostID, param.DstHostID, param.Bandwidth, param.Description)
+		if err != nil {
+			return err
+		}
+		v, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		id = uint64(v)
+
+		return nil
+	}
+	if err = r.query(f); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (r *MySQL) RemoveIntent(id uint64) (ok bool, err error) {
+	f := func(tx *sql.Tx) error {
+		result, err := tx.Exec("DELETE FROM intent WHERE id = ?", id)
+		if err != nil {
+			return err
+		}
+		nRows, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if nRows > 0 {
+			ok = true
+		}
+
+		return nil
+	}
+	if err = r.query(f); err != nil {
+		return false, err
+	}
+
+	return ok, nil
+}
+
+func (r *MySQL) Blacklist() (result []network.Blacklist, err error) {
+	f := func(tx *sql.Tx) error {
+		qry := "SELECT `id`, `mac`, IFNULL(INET_NTOA(`ip`), ''), `description` FROM `blacklist` ORDER BY `id` ASC"
+		rows, err := tx.Query(qry)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var v network.Blacklist
+			var mac []byte
+			if err := rows.Scan(&v.ID, &mac, &v.IP, &v.Description); err != nil {
+				return err
+			}
+			// NULL MAC address?
+			if mac != nil {
+				v.MAC = net.HardwareAddr(mac).String()
+			}
+			result = append(result, v)
+		}
+
+		return rows.Err()
+	}
+	if err = r.query(f); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *MySQL) GetBlacklist() (result []blacklist.Entry, err error) {
+	entries, err := r.Blacklist()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range entries {
+		entry := blacklist.Entry{ID: v.ID}
+		if len(v.MAC) > 0 {
+			if entry.MAC, err = net.ParseMAC(v.MAC); err != nil {
+				return nil, fmt.Errorf("invalid blacklisted MAC address: %v", v.MAC)
+			}
+		} else {
+			if entry.IP = net.ParseIP(v.IP); entry.IP == nil {
+				return nil, fmt.Errorf("invalid blacklisted IP address: %v", v.IP)
+			}
+		}
+		result = append(result, entry)
+	}
+
+	return result, nil
+}
+
+// AddBlacklist adds the address of param to the blacklist. duplicated will be true if
+// the address has been already blacklisted.
+func (r *MySQL) AddBlacklist(param network.BlacklistParam) (id uint64, duplicated bool, err error) {
+	var mac, ip interface{}
+	if len(param.MAC) > 0 {
+		v, err := net.ParseMAC(param.MAC)
+		if err != nil {
+			return 0, false, err
+		}
+		mac = []byte(v)
+	} else {
+		ip = param.IP
+	}
+
+	f := func(tx *sql.Tx) error {
+		var n int
+		qry := "SELECT COUNT(*) FROM `blacklist` WHERE `mac` = ? OR `ip` = INET_ATON(?) FOR UPDATE"
+		if err := tx.QueryRow(qry, mac, ip).Scan(&n); err != nil {
+			return err
+		}
+		if n > 0 {
+			duplicated = true
+			return nil
+		}
+
+		qry = "INSERT INTO `blacklist` (`mac`, `ip`, `description`) VALUES (?, INET_ATON(?), ?)"
+		result, err := tx.Exec(qry, mac, ip, param.Description)
+		if err != nil {
+			return err
+		}
+		v, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		id = uint64(v)
+
+		return nil
+	}
+	if err = r.query(f); err != nil {
+		return 0, false, err
+	}
+
+	return id, duplicated, nil
+}
+
+func (r *MySQL) RemoveBlacklist(id uint64) (ok bool, err error) {
+	f := func(tx *sql.Tx) error {
+		result, err := tx.Exec("DELETE FROM `blacklist` WHERE `id` = ?", id)
+		if err != nil {
+			return err
+		}
+		nRows, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if nRows > 0 {
+			ok = true
+		}
+
+		return nil
+	}
+	if err = r.query(f); err != nil {
+		return false, err
+	}
+
+	return ok, nil
+}
+
+// BindSwitchCertificate binds the client certificate whose SHA-256 fingerprint is fingerprint
+// to the switch whose DPID is dpid if the switch has no bound certificate yet. It returns the
+// fingerprint of the certificate bound to the switch.
+func (r *MySQL) BindSwitchCertificate(dpid uint64, fingerprint, subject string) (bound string, err error) {
+	f := func(tx *sql.Tx) error {
+		// Lock the row so that the concurrent connections of the same DPID cannot bind different certificates.
+		err := tx.QueryRow("SELECT `fingerprint` FROM `switch_certificate` WHERE `dpid` = ? FOR UPDATE", dpid).Scan(&bound)
+		if err == nil {
+			return nil
+		}
+		if err != sql.ErrNoRows {
+			return err
+		}
+
+		qry := "INSERT INTO `switch_certificate` (`dpid`, `fingerprint`, `subject`, `timestamp`) VALUES (?, ?, ?, NOW())"
+		if _, err := tx.Exec(qry, dpid, fingerprint, subject); err != nil {
+			return err
+		}
+		bound = fingerprint
+
+		return nil
+	}
+	if err = r.query(f); err != nil {
+		return "", err
+	}
+
+	return bound, nil
+}
+
+// UnbindSwitchCertificate removes the certificate bound to the switch whose DPID is dpid, so
+// that the next connection of the switch binds its certificate. ok will be false if the switch
+// has no bound certificate.
+func (r *MySQL) UnbindSwitchCertificate(dpid uint64) (ok bool, err error) {
+	f := func(tx *sql.Tx) error {
+		result, err := tx.Exec("DELETE FROM `switch_certificate` WHERE `dpid` = ?", dpid)
+		if err != nil {
+			return err
+		}
+		nRows, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if nRows > 0 {
+			ok = true
+		}
+
+		return nil
+	}
+	if err = r.query(f); err != nil {
+		return false, err
+	}
+
+	return ok, nil
+}
+
+// ResetHostLocations sets NULL to the locations of the hosts whose MAC address is mac or
+// IP address is ip. mac or ip can be nil.
+func (r *MySQL) ResetHostLocations(mac net.HardwareAddr, ip net.IP) error {
+	var macArg, ipArg interface{}
+	if mac != nil {
+		macArg = []byte(mac)
+	}
+	if ip != nil {
+		ipArg = ip.String()
+	}
+
+	f := func(tx *sql.Tx) error {
+		qry := "UPDATE `host` A "
+		qry += "JOIN `ip` B ON A.`ip_id` = B.`id` "
+		qry += "SET A.`port_id` = NULL "
+		qry += "WHERE A.`mac` = ? OR B.`address` = INET_ATON(?)"
+
+		_, err := tx.Exec(qry, macArg, ipArg)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	}
+
+	return r.query(f)
+}
+
 // GetUndiscoveredHosts returns IP addresses whose physical location is still
 // undiscovered or staled more than expiration. result can be nil on empty result.
 func (r *MySQL) GetUndiscoveredHosts(expiration time.Duration) (result []net.IP, err error) {
@@ -1417,16 +1942,17 @@ func (r *MySQL) ResetHostLocationsByDevice(swDPID uint64) error {
 
 // Elect selects a new master as uid if there is a no existing master that has
 // been updated within expiration. elected will be true if this uid has been
-// elected as the new master or was already elected.
-func (r *MySQL) Elect(uid string, expiration time.Duration) (elected bool, err error) {
+// elected as the new master or was already elected. generation is the number
+// of the current master, which is increased whenever the master changes.
+func (r *MySQL) Elect(uid string, expiration time.Duration) (elected bool, generation uint64, err error) {
 	f := func(tx *sql.Tx) error {
 		var name string
 		var timestamp time.Time
-		qry := "SELECT `name`, `timestamp` "
+		qry := "SELECT `name`, `timestamp`, `generation` "
 		qry += "FROM `election` "
 		qry += "WHERE `type` = 'MASTER' "
 		qry += "FOR UPDATE" // Lock the selected row even if there is a no exsiting one.
-		err = tx.QueryRow(qry).Scan(&name, &timestamp)
+		err = tx.QueryRow(qry).Scan(&name, &timestamp, &generation)
 		// Real error?
 		if err != nil && err != sql.ErrNoRows {
 			return err
@@ -1435,17 +1961,22 @@ func (r *MySQL) Elect(uid string, expiration time.Duration) (elected bool, err e
 		// No existing master?
 		if err == sql.ErrNoRows {
 			// I am the newly elected master!
-			qry = "INSERT INTO `election` (`name`, `type`, `timestamp`) "
-			qry += "VALUES (?, 'MASTER', NOW())"
+			qry = "INSERT INTO `election` (`name`, `type`, `timestamp`, `generation`) "
+			qry += "VALUES (?, 'MASTER', NOW(), 1)"
 			if _, err := tx.Exec(qry, uid); err != nil {
 				return err
 			}
 			elected = true
+			generation = 1
 		} else {
 			// Already elected or another stale master?
 			if name == uid || time.Now().Sub(timestamp) > expiration {
-				qry = "UPDATE `election` SET `name` = ?, `timestamp` = NOW() WHERE `type` = 'MASTER'"
-				if _, err := tx.Exec(qry, uid); err != nil {
+				// New master has a new generation.
+				if name != uid {
+					generation++
+				}
+				qry = "UPDATE `election` SET `name` = ?, `timestamp` = NOW(), `generation` = ? WHERE `type` = 'MASTER'"
+				if _, err := tx.Exec(qry, uid, generation); err != nil {
 					return err
 				}
 				elected = true
@@ -1456,10 +1987,10 @@ func (r *MySQL) Elect(uid string, expiration time.Duration) (elected bool, err e
 	}
 
 	if err := r.query(f); err != nil {
-		return false, err
+		return false, 0, err
 	}
 
-	return elected, nil
+	return elected, generation, nil
 }
 
 func (r *MySQL) GetActivatedHosts() (hosts []proxyarp.Host, err error) {
@@ -1535,3 +2066,115 @@ func (r *MySQL) MACAddrs() (result []net.HardwareAddr, err error) {
 
 	return result, nil
 }
+
+// FlowIntents returns all the flow intents saved by SaveFlowIntent. The records are returned in
+// the schema versions they have been saved in, and the network package decodes them.
+func (r *MySQL) FlowIntents() (intents []network.FlowIntentRecord, err error) {
+	f := func(tx *sql.Tx) error {
+		rows, err := tx.Query("SELECT `dpid`, `key`, `version`, `data` FROM `flow_intent` ORDER BY `dpid`, `key`")
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		intents = nil
+		for rows.Next() {
+			v := network.FlowIntentRecord{}
+			if err := rows.Scan(&v.DPID, &v.Key, &v.Version, &v.Data); err != nil {
+				return err
+			}
+			intents = append(intents, v)
+		}
+
+		return rows.Err()
+	}
+	if err = r.query(f); err != nil {
+		return nil, err
+	}
+
+	return intents, nil
+}
+
+// SaveFlowIntent saves the flow intent, replacing the one that has the same DPID and key.
+func (r *MySQL) SaveFlowIntent(intent network.FlowIntentRecord) error {
+	f := func(tx *sql.Tx) error {
+		qry := "INSERT INTO `flow_intent` (`dpid`, `key`, `version`, `data`, `timestamp`) VALUES (?, ?, ?, ?, NOW()) "
+		qry += "ON DUPLICATE KEY UPDATE `version` = VALUES(`version`), `data` = VALUES(`data`), `timestamp` = NOW()"
+		_, err := tx.Exec(qry, intent.DPID, intent.Key, intent.Version, intent.Data)
+		return err
+	}
+
+	return r.query(f)
+}
+
+// RemoveFlowIntent removes the flow intent whose DPID and key are dpid and key. It is not an
+// error if there is no such intent.
+func (r *MySQL) RemoveFlowIntent(dpid uint64, key string) error {
+	f := func(tx *sql.Tx) error {
+		_, err := tx.Exec("DELETE FROM `flow_intent` WHERE `dpid` = ? AND `key` = ?", dpid, key)
+		return err
+	}
+
+	return r.query(f)
+}
+
+// RemoveFlowIntents removes all the flow intents of the switch whose DPID is dpid.
+func (r *MySQL) RemoveFlowIntents(dpid uint64) error {
+	f := func(tx *sql.Tx) error {
+		_, err := tx.Exec("DELETE FROM `flow_intent` WHERE `dpid` = ?", dpid)
+		return err
+	}
+
+	return r.query(f)
+}
+
+// Links returns all the links saved by SaveLink.
+func (r *MySQL) Links() (links []network.LinkRecord, err error) {
+	f := func(tx *sql.Tx) error {
+		rows, err := tx.Query("SELECT `src_dpid`, `src_port`, `dst_dpid`, `dst_port`, `indirect` FROM `link`")
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		links = nil
+		for rows.Next() {
+			v := network.LinkRecord{}
+			if err := rows.Scan(&v.SrcDPID, &v.SrcPort, &v.DstDPID, &v.DstPort, &v.Indirect); err != nil {
+				return err
+			}
+			links = append(links, v)
+		}
+
+		return rows.Err()
+	}
+	if err = r.query(f); err != nil {
+		return nil, err
+	}
+
+	return links, nil
+}
+
+// SaveLink saves the link, replacing the one that has the same ports.
+func (r *MySQL) SaveLink(link network.LinkRecord) error {
+	f := func(tx *sql.Tx) error {
+		qry := "INSERT INTO `link` (`src_dpid`, `src_port`, `dst_dpid`, `dst_port`, `indirect`, `timestamp`) VALUES (?, ?, ?, ?, ?, NOW()) "
+		qry += "ON DUPLICATE KEY UPDATE `indirect` = VALUES(`indirect`), `timestamp` = NOW()"
+		_, err := tx.Exec(qry, link.SrcDPID, link.SrcPort, link.DstDPID, link.DstPort, link.Indirect)
+		return err
+	}
+
+	return r.query(f)
+}
+
+// RemoveLink removes the link that has the same ports as link. It is not an error if there is
+// no such link.
+func (r *MySQL) RemoveLink(link network.LinkRecord) error {
+	f := func(tx *sql.Tx) error {
+		qry := "DELETE FROM `link` WHERE `src_dpid` = ? AND `src_port` = ? AND `dst_dpid` = ? AND `dst_port` = ?"
+		_, err := tx.Exec(qry, link.SrcDPID, link.SrcPort, link.DstDPID, link.DstPort)
+		return err
+	}
+
+	return r.query(f)
+}
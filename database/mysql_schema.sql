@@ -26,6 +26,7 @@ CREATE TABLE IF NOT EXISTS `election` (
   `name` varchar(255) NOT NULL,
   `type` enum('MASTER') NOT NULL,
   `timestamp` datetime NOT NULL,
+  `generation` bigint(20) unsigned NOT NULL DEFAULT '0',
   PRIMARY KEY (`id`),
   UNIQUE KEY `type` (`type`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8;
@@ -46,6 +47,29 @@ CREATE TABLE IF NOT EXISTS `acl` (
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8;
 /*!40101 SET character_set_client = @saved_cs_client */;
 
+--
+-- Table structure for table `firewall`
+--
+
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE IF NOT EXISTS `firewall` (
+  `id` bigint(20) unsigned NOT NULL AUTO_INCREMENT,
+  `priority` smallint(5) unsigned NOT NULL,
+  `allow` tinyint(1) NOT NULL,
+  `src_address` int(10) unsigned NOT NULL,
+  `src_mask` tinyint(3) unsigned NOT NULL,
+  `dst_address` int(10) unsigned NOT NULL,
+  `dst_mask` tinyint(3) unsigned NOT NULL,
+  `protocol` tinyint(3) unsigned NOT NULL,
+  `src_port` smallint(5) unsigned NOT NULL,
+  `dst_port` smallint(5) unsigned NOT NULL,
+  `description` varchar(255) NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `priority` (`priority`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
 --
 -- Table structure for table `network`
 --
@@ -74,6 +98,7 @@ CREATE TABLE IF NOT EXISTS `switch` (
   `first_port` smallint(5) unsigned NOT NULL DEFAULT '1',
   `first_printed_port` smallint(5) unsigned NOT NULL DEFAULT '0',
   `description` varchar(255) NOT NULL,
+  `drained` tinyint(1) NOT NULL DEFAULT '0',
   PRIMARY KEY (`id`),
   UNIQUE KEY `dpid` (`dpid`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8;
@@ -255,6 +280,89 @@ DELIMITER ;
 /*!50003 SET character_set_results = @saved_cs_results */ ;
 /*!50003 SET collation_connection  = @saved_col_connection */ ;
 
+--
+-- Table structure for table `intent`
+--
+
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE IF NOT EXISTS `intent` (
+  `id` bigint(20) unsigned NOT NULL AUTO_INCREMENT,
+  `src_host_id` bigint(20) unsigned NOT NULL,
+  `dst_host_id` bigint(20) unsigned NOT NULL,
+  `bandwidth` int(10) unsigned NOT NULL,
+  `description` varchar(255) NOT NULL,
+  PRIMARY KEY (`id`),
+  CONSTRAINT `intent_ibfk_1` FOREIGN KEY (`src_host_id`) REFERENCES `host` (`id`) ON DELETE RESTRICT ON UPDATE CASCADE,
+  CONSTRAINT `intent_ibfk_2` FOREIGN KEY (`dst_host_id`) REFERENCES `host` (`id`) ON DELETE RESTRICT ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Table structure for table `blacklist`
+--
+
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE IF NOT EXISTS `blacklist` (
+  `id` bigint(20) unsigned NOT NULL AUTO_INCREMENT,
+  `mac` binary(6) default NULL,
+  `ip` int(10) unsigned default NULL,
+  `description` varchar(255) NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `mac` (`mac`),
+  UNIQUE KEY `ip` (`ip`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Table structure for table `switch_certificate`
+--
+
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE IF NOT EXISTS `switch_certificate` (
+  `dpid` bigint(20) unsigned NOT NULL,
+  `fingerprint` char(64) NOT NULL,
+  `subject` varchar(255) NOT NULL,
+  `timestamp` datetime NOT NULL,
+  PRIMARY KEY (`dpid`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Table structure for table `flow_intent`
+--
+
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE IF NOT EXISTS `flow_intent` (
+  `dpid` bigint(20) unsigned NOT NULL,
+  `key` char(64) NOT NULL,
+  `version` int(10) unsigned NOT NULL,
+  `data` blob NOT NULL,
+  `timestamp` datetime NOT NULL,
+  PRIMARY KEY (`dpid`,`key`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Table structure for table `link`
+--
+
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE IF NOT EXISTS `link` (
+  `src_dpid` bigint(20) unsigned NOT NULL,
+  `src_port` int(10) unsigned NOT NULL,
+  `dst_dpid` bigint(20) unsigned NOT NULL,
+  `dst_port` int(10) unsigned NOT NULL,
+  `indirect` tinyint(1) NOT NULL DEFAULT '0',
+  `timestamp` datetime NOT NULL,
+  PRIMARY KEY (`src_dpid`,`src_port`,`dst_dpid`,`dst_port`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
 --
 -- Dumping routines for database 'cherry'
 --
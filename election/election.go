@@ -45,17 +45,24 @@ type Observer struct {
 	uid string
 	db  Database
 
-	mutex  sync.Mutex
-	master bool
+	mutex      sync.Mutex
+	master     bool
+	generation uint64
+	listeners  []Listener
 }
 
 type Database interface {
 	// Elect selects a new master as uid if there is a no existing master that has
 	// been updated within expiration. elected will be true if this uid has been
-	// elected as the new master or was already elected.
-	Elect(uid string, expiration time.Duration) (elected bool, err error)
+	// elected as the new master or was already elected. generation is the number
+	// of the current master, which is increased whenever the master changes.
+	Elect(uid string, expiration time.Duration) (elected bool, generation uint64, err error)
 }
 
+// Listener is called whenever the mastership of this controller or the generation
+// of the master changes.
+type Listener func(master bool, generation uint64)
+
 func New(db Database) *Observer {
 	return &Observer{
 		uid: generateRandomUID(),
@@ -81,29 +88,62 @@ func (r *Observer) Run(ctx context.Context) error {
 			logger.Debug("terminating the election observer...")
 			return nil
 		case <-ticker:
-			prev := r.getMaster()
-			elected, err := r.db.Elect(r.uid, interval*5)
+			prev, prevGeneration := r.getMaster(), r.Generation()
+			elected, generation, err := r.db.Elect(r.uid, interval*5)
 			if err != nil {
 				return err
 			}
-			r.setMaster(elected)
+			r.setMaster(elected, generation)
 
 			if prev != elected {
-				logger.Warningf("master controller has been changed: prev=%v, new=%v", prev, elected)
+				logger.Warningf("master controller has been changed: prev=%v, new=%v, generation=%v", prev, elected, generation)
+			}
+			if prev != elected || prevGeneration != generation {
+				r.notify(elected, generation)
 			}
 		}
 	}
 }
 
+// Subscribe registers f that will be called whenever the mastership of this controller
+// or the generation of the master changes.
+func (r *Observer) Subscribe(f Listener) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.listeners = append(r.listeners, f)
+}
+
+func (r *Observer) notify(master bool, generation uint64) {
+	r.mutex.Lock()
+	listeners := make([]Listener, len(r.listeners))
+	copy(listeners, r.listeners)
+	r.mutex.Unlock()
+
+	for _, f := range listeners {
+		f(master, generation)
+	}
+}
+
 func (r *Observer) IsMaster() bool {
 	return r.getMaster()
 }
 
-func (r *Observer) setMaster(value bool) {
+// Generation returns the generation of the current master, which may be another controller.
+// It is zero until the first election.
+func (r *Observer) Generation() uint64 {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.generation
+}
+
+func (r *Observer) setMaster(value bool, generation uint64) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
 	r.master = value
+	r.generation = generation
 }
 
 func (r *Observer) getMaster() bool {
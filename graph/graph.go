@@ -59,6 +59,8 @@ type edge struct {
 	value     Edge
 	enabled   bool
 	timestamp time.Time
+	// True if this edge is connected to a drained vertex.
+	drained bool
 }
 
 type vertex struct {
@@ -71,6 +73,8 @@ type Graph struct {
 	vertexies map[string]vertex
 	edges     map[string]*edge
 	points    map[string]*edge
+	// Key is the vertex ID. Drained vertexies are avoided by MST whenever possible.
+	drained map[string]bool
 }
 
 func New() *Graph {
@@ -78,6 +82,7 @@ func New() *Graph {
 		vertexies: make(map[string]vertex),
 		edges:     make(map[string]*edge),
 		points:    make(map[string]*edge),
+		drained:   make(map[string]bool),
 	}
 }
 
@@ -187,6 +192,20 @@ func (r *Graph) AddEdge(e Edge) (added bool, err error) {
 	return true, nil
 }
 
+// Edges returns all the edges, including the ones disabled by MST.
+func (r *Graph) Edges() []Edge {
+	// Read lock
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	v := make([]Edge, 0, len(r.edges))
+	for _, e := range r.edges {
+		v = append(v, e.value)
+	}
+
+	return v
+}
+
 func (r *Graph) RemoveEdge(p Point) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
@@ -200,6 +219,69 @@ func (r *Graph) RemoveEdge(p Point) {
 	logger.Debugf("removed an edge: id=%v", e.value.ID())
 }
 
+// SetDrained marks v as drained, or normal if drained is false. Edges connected to a drained
+// vertex are used by MST only if there is no other way to reach a vertex, so that a drained
+// vertex becomes a leaf of the tree and does not carry transit traffic. v does not need to be
+// added yet. changed will be true if the state of v has been changed.
+func (r *Graph) SetDrained(v Vertex, drained bool) (changed bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if v == nil {
+		panic("nil vertex")
+	}
+	if r.drained[v.ID()] == drained {
+		return false
+	}
+
+	if drained {
+		r.drained[v.ID()] = true
+	} else {
+		delete(r.drained, v.ID())
+	}
+	r.calculateMST()
+
+	return true
+}
+
+// IsDrained returns whether v is marked as drained.
+func (r *Graph) IsDrained(v Vertex) bool {
+	// Read lock
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if v == nil {
+		panic("nil vertex")
+	}
+
+	return r.drained[v.ID()]
+}
+
+// IsTransit returns whether v has two or more edges that belong to MST, which means
+// a path between other vertexies may traverse v.
+func (r *Graph) IsTransit(v Vertex) bool {
+	// Read lock
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if v == nil {
+		panic("nil vertex")
+	}
+
+	vertex, ok := r.vertexies[v.ID()]
+	if !ok {
+		return false
+	}
+	count := 0
+	for _, e := range vertex.edges {
+		if e.enabled {
+			count++
+		}
+	}
+
+	return count > 1
+}
+
 // IsEdge returns whether p is on an edge between two vertexeis.
 func (r *Graph) IsEdge(p Point) bool {
 	// Read lock
@@ -239,6 +321,10 @@ func (r sortedEdge) Len() int {
 }
 
 func (r sortedEdge) Less(i, j int) bool {
+	// Edges connected to a drained vertex always come after the normal ones.
+	if r[i].drained != r[j].drained {
+		return r[j].drained
+	}
 	if r[i].value.Weight() < r[j].value.Weight() {
 		return true
 	}
@@ -267,6 +353,8 @@ func (r *Graph) resetEdges() *list.List {
 	for _, v := range r.edges {
 		// Disable all edges
 		v.enabled = false
+		points := v.value.Points()
+		v.drained = r.drained[points[0].Vertex().ID()] || r.drained[points[1].Vertex().ID()]
 		edges = append(edges, v)
 	}
 	sort.Sort(edges)
@@ -445,6 +533,75 @@ func (r *Graph) FindPath(src, dst Vertex) []Path {
 	return reverse(result)
 }
 
+// FindNextHops returns the edges of src that belong to one of the shortest paths, in the
+// number of hops, from src to dst. Unlike FindPath, it also uses the edges disabled by MST
+// so that the traffic toward dst can be balanced over the equal-cost paths. Drained vertexies
+// are not used as a transit, and the edges are sorted by their IDs.
+func (r *Graph) FindNextHops(src, dst Vertex) []Edge {
+	// Read lock
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if src.ID() == dst.ID() {
+		return []Edge{}
+	}
+	start, ok := r.vertexies[dst.ID()]
+	if !ok {
+		return []Edge{}
+	}
+
+	// Number of hops from each vertex to dst.
+	distance := map[string]int{dst.ID(): 0}
+	queue := newQueue()
+	queue.enqueue(start)
+
+	// Implementation of BFS algorithm from the destination
+	for queue.length() > 0 {
+		vertex := queue.dequeue().(vertex)
+		// We don't have to go further than the source.
+		if vertex.value.ID() == src.ID() {
+			continue
+		}
+		for _, w := range vertex.edges {
+			next := opposite(w.value, vertex.value).Vertex()
+			if _, ok := distance[next.ID()]; ok {
+				continue
+			}
+			// Drained vertexies cannot be a transit.
+			if r.drained[next.ID()] && next.ID() != src.ID() {
+				continue
+			}
+			distance[next.ID()] = distance[vertex.value.ID()] + 1
+			queue.enqueue(r.vertexies[next.ID()])
+		}
+	}
+
+	d, ok := distance[src.ID()]
+	if !ok {
+		return []Edge{}
+	}
+	result := make([]Edge, 0)
+	for _, w := range r.vertexies[src.ID()].edges {
+		next := opposite(w.value, src)
+		if v, ok := distance[next.Vertex().ID()]; ok && v == d-1 {
+			result = append(result, w.value)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID() < result[j].ID() })
+
+	return result
+}
+
+// opposite returns the point of e that is not on v.
+func opposite(e Edge, v Vertex) Point {
+	points := e.Points()
+	if points[0].Vertex().ID() == v.ID() {
+		return points[1]
+	}
+
+	return points[0]
+}
+
 func reverse(data []Path) []Path {
 	length := len(data)
 	if length == 0 {
@@ -92,7 +92,7 @@ func TestInvalidMST(t *testing.T) {
 		points: [2]point{point{"a", 1}, point{"b", 1}},
 		weight: 2,
 	}
-	if err := graph.AddEdge(e); err == nil {
+	if _, err := graph.AddEdge(e); err == nil {
 		t.Fatal("Expected error, but not occurred!")
 	}
 }
@@ -105,7 +105,7 @@ func TestRemoveVertex(t *testing.T) {
 		points: [2]point{point{"a", 1}, point{"b", 1}},
 		weight: 2,
 	}
-	if err := graph.AddEdge(e); err != nil {
+	if _, err := graph.AddEdge(e); err != nil {
 		t.Fatal(err)
 	}
 	graph.RemoveVertex(node{"a"})
@@ -132,7 +132,7 @@ func TestRemoveEdges(t *testing.T) {
 		points: [2]point{point{"a", 1}, point{"b", 1}},
 		weight: 2,
 	}
-	if err := graph.AddEdge(e); err != nil {
+	if _, err := graph.AddEdge(e); err != nil {
 		t.Fatal(err)
 	}
 	graph.RemoveEdge(point{"a", 1})
@@ -148,10 +148,10 @@ func TestRemoveEdges(t *testing.T) {
 		t.Fatalf("Expected # of edges is 0/0, got=%v/%v\n", len(a.edges), len(b.edges))
 	}
 
-	if err := graph.AddEdge(e); err != nil {
+	if _, err := graph.AddEdge(e); err != nil {
 		t.Fatal(err)
 	}
-	if err := graph.AddEdge(e); err != nil {
+	if _, err := graph.AddEdge(e); err != nil {
 		t.Fatal(err)
 	}
 	if len(a.edges) != 1 || len(b.edges) != 1 {
@@ -177,10 +177,10 @@ func TestDuplicatedEdges(t *testing.T) {
 		points: [2]point{point{"a", 1}, point{"b", 1}},
 		weight: 2,
 	}
-	if err := graph.AddEdge(e); err != nil {
+	if _, err := graph.AddEdge(e); err != nil {
 		t.Fatal(err)
 	}
-	if err := graph.AddEdge(e); err != nil {
+	if _, err := graph.AddEdge(e); err != nil {
 		t.Fatal(err)
 	}
 
@@ -209,7 +209,7 @@ func TestMST0(t *testing.T) {
 	})
 
 	for _, v := range edges {
-		if err := graph.AddEdge(v); err != nil {
+		if _, err := graph.AddEdge(v); err != nil {
 			t.Fatal(err)
 		}
 	}
@@ -279,7 +279,7 @@ func TestMST1(t *testing.T) {
 	})
 
 	for _, v := range edges {
-		if err := graph.AddEdge(v); err != nil {
+		if _, err := graph.AddEdge(v); err != nil {
 			t.Fatal(err)
 		}
 	}
@@ -364,7 +364,7 @@ func TestMST2(t *testing.T) {
 	})
 
 	for _, v := range edges {
-		if err := graph.AddEdge(v); err != nil {
+		if _, err := graph.AddEdge(v); err != nil {
 			t.Fatal(err)
 		}
 	}
@@ -459,7 +459,7 @@ func TestMST3(t *testing.T) {
 	})
 
 	for _, v := range edges {
-		if err := graph.AddEdge(v); err != nil {
+		if _, err := graph.AddEdge(v); err != nil {
 			t.Fatal(err)
 		}
 	}
@@ -535,7 +535,7 @@ func TestMST4(t *testing.T) {
 	})
 
 	for _, v := range edges {
-		if err := graph.AddEdge(v); err != nil {
+		if _, err := graph.AddEdge(v); err != nil {
 			t.Fatal(err)
 		}
 	}
@@ -558,3 +558,63 @@ func TestMST4(t *testing.T) {
 		}
 	}
 }
+
+func TestFindNextHops(t *testing.T) {
+	graph := New()
+	graph.AddVertex(node{"a"})
+	graph.AddVertex(node{"b"})
+	graph.AddVertex(node{"c"})
+	graph.AddVertex(node{"d"})
+
+	// Diamond topology whose a and b are connected by two links.
+	edges := make([]link, 0)
+	edges = append(edges, link{
+		points: [2]point{point{"a", 1}, point{"b", 1}},
+	})
+	edges = append(edges, link{
+		points: [2]point{point{"a", 2}, point{"b", 2}},
+	})
+	edges = append(edges, link{
+		points: [2]point{point{"a", 3}, point{"c", 1}},
+	})
+	edges = append(edges, link{
+		points: [2]point{point{"b", 3}, point{"d", 1}},
+	})
+	edges = append(edges, link{
+		points: [2]point{point{"c", 2}, point{"d", 2}},
+	})
+
+	for _, v := range edges {
+		if _, err := graph.AddEdge(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	hops := graph.FindNextHops(node{"a"}, node{"d"})
+	if len(hops) != 3 {
+		t.Fatalf("Unexpected next hops: expected=3, got=%v", len(hops))
+	}
+	for i, v := range []string{"a:1/b:1", "a:2/b:2", "a:3/c:1"} {
+		if hops[i].ID() != v {
+			t.Fatalf("Unexpected next hop: expected=%v, got=%v", v, hops[i].ID())
+		}
+	}
+	hops = graph.FindNextHops(node{"b"}, node{"d"})
+	if len(hops) != 1 || hops[0].ID() != "b:3/d:1" {
+		t.Fatalf("Unexpected next hops: expected=[b:3/d:1], got=%v", hops)
+	}
+	if hops := graph.FindNextHops(node{"d"}, node{"d"}); len(hops) != 0 {
+		t.Fatalf("Unexpected next hops: expected=[], got=%v", hops)
+	}
+
+	// The drained vertex should not be a transit.
+	graph.SetDrained(node{"c"}, true)
+	hops = graph.FindNextHops(node{"a"}, node{"d"})
+	if len(hops) != 2 || hops[0].ID() != "a:1/b:1" || hops[1].ID() != "a:2/b:2" {
+		t.Fatalf("Unexpected next hops: expected=[a:1/b:1 a:2/b:2], got=%v", hops)
+	}
+	hops = graph.FindNextHops(node{"c"}, node{"d"})
+	if len(hops) != 1 || hops[0].ID() != "c:2/d:2" {
+		t.Fatalf("Unexpected next hops: expected=[c:2/d:2], got=%v", hops)
+	}
+}
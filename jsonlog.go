@@ -0,0 +1,101 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/superkkt/go-logging"
+)
+
+var (
+	// Most log messages contain key=value pairs, e.g., "DPID=1, PortNum=2". A key should
+	// start at a word boundary so that a pair is not found in the middle of a word.
+	logFieldRegexp = regexp.MustCompile(`\b([A-Za-z_][A-Za-z0-9_]*)=([^\s,()]+)`)
+)
+
+// jsonLog is a logging backend that writes each record as a JSON object per line,
+// which is easy to be collected by log shippers.
+type jsonLog struct {
+	mutex  sync.Mutex
+	writer io.Writer
+}
+
+type jsonRecord struct {
+	Time    string `json:"time"`
+	Level   string `json:"level"`
+	Module  string `json:"module"`
+	Caller  string `json:"caller"`
+	TID     string `json:"tid"`
+	Message string `json:"message"`
+	// Key-value pairs found in the message. Keys are lower cased.
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+func newJSONLog(w io.Writer) logging.Backend {
+	backend := &jsonLog{writer: w}
+	// The formatted record is only used as the caller.
+	return logging.NewBackendFormatter(backend, logging.MustStringFormatter(`%{shortpkg}.%{shortfunc}`))
+}
+
+func (r *jsonLog) Log(level logging.Level, calldepth int, record *logging.Record) error {
+	v := jsonRecord{
+		Time:    record.Time.Format(time.RFC3339Nano),
+		Level:   strings.ToLower(level.String()),
+		Module:  record.Module,
+		Caller:  record.Formatted(calldepth + 1),
+		TID:     getGoRoutineID(),
+		Message: record.Message(),
+		Fields:  parseLogFields(record.Message()),
+	}
+	line, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	_, err = r.writer.Write(line)
+	return err
+}
+
+// parseLogFields returns the key=value pairs in msg, or nil if there is no pair.
+func parseLogFields(msg string) map[string]string {
+	matches := logFieldRegexp.FindAllStringSubmatch(msg, -1)
+	if len(matches) == 0 {
+		return nil
+	}
+
+	fields := make(map[string]string)
+	for _, m := range matches {
+		fields[strings.ToLower(m[1])] = m[2]
+	}
+
+	return fields
+}
@@ -0,0 +1,52 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseLogFields(t *testing.T) {
+	tests := []struct {
+		msg      string
+		expected map[string]string
+	}{
+		{"no key-value pair in this message", nil},
+		{"", nil},
+		{"Device=1, PortNum=2, AdminUp=true", map[string]string{"device": "1", "portnum": "2", "adminup": "true"}},
+		// Parentheses and commas do not belong to the values.
+		{"FLOW_REMOVED is received (device=1, cookie=0x10, reason=idle_timeout)", map[string]string{"device": "1", "cookie": "0x10", "reason": "idle_timeout"}},
+		// Colons and slashes are parts of the values.
+		{"learned host mac=00:11:22:33:44:55 ip=10.0.0.1/24", map[string]string{"mac": "00:11:22:33:44:55", "ip": "10.0.0.1/24"}},
+		// The last value wins if a key appears more than once.
+		{"xid=1, XID=2", map[string]string{"xid": "2"}},
+		// Neither an empty value, a spaced pair nor a key starting with a digit is a field.
+		{"a= b, c =d, 1e=f", nil},
+	}
+
+	for _, test := range tests {
+		if v := parseLogFields(test.msg); !reflect.DeepEqual(v, test.expected) {
+			t.Errorf("%q: expected=%v, got=%v", test.msg, test.expected, v)
+		}
+	}
+}
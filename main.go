@@ -23,18 +23,24 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"flag"
 	"fmt"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"regexp"
 	"runtime"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
 	"github.com/superkkt/cherry/database"
 	"github.com/superkkt/cherry/election"
+	"github.com/superkkt/cherry/metrics"
 	"github.com/superkkt/cherry/network"
 	"github.com/superkkt/cherry/northbound"
 
@@ -54,7 +60,10 @@ var (
 	logger            = logging.MustGetLogger("main")
 	loggerLeveled     logging.LeveledBackend
 	showVersion       = flag.Bool("version", false, "Show program version and exit")
+	checkConfig       = flag.Bool("check-config", false, "Validate the configuration file and exit")
 	defaultConfigFile = flag.String("config", fmt.Sprintf("/usr/local/etc/%v.yaml", programName), "absolute path of the configuration file")
+	// deliver, drop, or sample:N (N > 0)
+	packetInPolicyRegexp = regexp.MustCompile(`(?i)^\s*(deliver|drop|sample:[1-9][0-9]*)?\s*$`)
 )
 
 func main() {
@@ -66,6 +75,10 @@ func main() {
 	}
 
 	initConfig()
+	if *checkConfig {
+		fmt.Printf("%v: configuration is valid\n", *defaultConfigFile)
+		os.Exit(0)
+	}
 	if err := initLog(getLogLevel(viper.GetString("default.log_level"))); err != nil {
 		logger.Fatalf("failed to init log: %v", err)
 	}
@@ -86,8 +99,16 @@ func main() {
 	manager.AddEventSender(controller)
 
 	initSignalHandler(controller, manager, cancel)
+	if port := viper.GetInt("metrics.port"); port > 0 {
+		go serveMetrics(viper.GetString("metrics.listen_addr"), port, manager)
+	}
+
+	tlsConfig, err := newTLSConfig()
+	if err != nil {
+		logger.Fatalf("failed to init TLS: %v", err)
+	}
 
-	listen(ctx, viper.GetInt("default.port"), controller, observer)
+	listen(ctx, viper.GetString("default.listen_addr"), viper.GetInt("default.port"), tlsConfig, controller)
 }
 
 func initConfig() {
@@ -118,23 +139,212 @@ func validateConfig() error {
 	if port := viper.GetInt("default.port"); port <= 0 || port > 0xFFFF {
 		return errors.New("invalid default.port")
 	}
-	if len(viper.GetString("default.log_level")) == 0 {
+	if addr := viper.GetString("default.listen_addr"); len(addr) > 0 && net.ParseIP(addr) == nil {
+		return errors.New("invalid default.listen_addr")
+	}
+	if _, err := logging.LogLevel(viper.GetString("default.log_level")); err != nil {
 		return errors.New("invalid default.log_level")
 	}
+	switch strings.ToLower(viper.GetString("default.log_backend")) {
+	case "", "syslog", "stderr", "json":
+	default:
+		return errors.New("invalid default.log_backend")
+	}
 	if len(viper.GetString("default.applications")) == 0 {
 		return errors.New("invalid default.applications")
 	}
 	if len(viper.GetString("default.admin_email")) == 0 {
 		return errors.New("invalid default.admin_email")
 	}
+	for _, reason := range []string{"no_match", "action", "invalid_ttl"} {
+		key := "default.packet_in." + reason
+		if !packetInPolicyRegexp.MatchString(viper.GetString(key)) {
+			return fmt.Errorf("invalid %v", key)
+		}
+	}
+	if viper.GetInt("default.packet_in.rate_limit") < 0 {
+		return errors.New("invalid default.packet_in.rate_limit")
+	}
+	if viper.IsSet("default.packet_in.miss_send_len") {
+		if v := viper.GetInt("default.packet_in.miss_send_len"); v < 0 || v > 0xFFFF {
+			return errors.New("invalid default.packet_in.miss_send_len")
+		}
+	}
+	if viper.GetInt("default.packet_in.flood_threshold") < 0 {
+		return errors.New("invalid default.packet_in.flood_threshold")
+	}
+	// The block time is the hard timeout of a flow.
+	if v := viper.GetInt("default.packet_in.flood_block_time"); v < 0 || v > 0xFFFF {
+		return errors.New("invalid default.packet_in.flood_block_time")
+	}
+	if v := viper.GetInt("default.packet_in.workers"); v < 0 || v > 1024 {
+		return errors.New("invalid default.packet_in.workers")
+	}
+	if viper.GetInt("default.packet_in.worker_queue_size") < 0 {
+		return errors.New("invalid default.packet_in.worker_queue_size")
+	}
+	if rate := viper.GetFloat64("default.trace.sample_rate"); rate < 0 || rate > 1 {
+		return errors.New("invalid default.trace.sample_rate")
+	}
+	if port := viper.GetInt("default.trace.port"); port < 0 {
+		return errors.New("invalid default.trace.port")
+	}
+	if viper.GetBool("default.tls.enable") {
+		if len(viper.GetString("default.tls.cert_file")) == 0 || len(viper.GetString("default.tls.key_file")) == 0 {
+			return errors.New("invalid default.tls.cert_file or default.tls.key_file")
+		}
+		if _, err := parseCipherSuites(viper.GetString("default.tls.cipher_suites")); err != nil {
+			return errors.Wrap(err, "invalid default.tls.cipher_suites")
+		}
+		// Binding the certificates that are not verified by a CA allows anyone to bind a DPID.
+		if viper.GetBool("default.tls.bind_dpid") && len(viper.GetString("default.tls.ca_file")) == 0 {
+			return errors.New("default.tls.bind_dpid requires default.tls.ca_file")
+		}
+	}
+	switch strings.ToLower(strings.TrimSpace(viper.GetString("default.aux_channel"))) {
+	case "", "main", "packet":
+	default:
+		return errors.New("invalid default.aux_channel")
+	}
+	switch strings.ToLower(strings.TrimSpace(viper.GetString("default.flow_conflict"))) {
+	case "", "ignore", "warn", "reject", "adjust":
+	default:
+		return errors.New("invalid default.flow_conflict")
+	}
+	switch strings.ToLower(strings.TrimSpace(viper.GetString("default.table_miss"))) {
+	case "", "none", "controller", "drop":
+	default:
+		return errors.New("invalid default.table_miss")
+	}
+	if viper.GetInt("default.port_stats_interval") < 0 {
+		return errors.New("invalid default.port_stats_interval")
+	}
+	if viper.GetInt("default.shutdown.timeout") <= 0 {
+		return errors.New("invalid default.shutdown.timeout")
+	}
+	if viper.GetInt("default.max_handshakes") < 0 {
+		return errors.New("invalid default.max_handshakes")
+	}
+	if viper.GetInt("default.handshake_timeout") <= 0 {
+		return errors.New("invalid default.handshake_timeout")
+	}
+	if viper.GetInt("default.handshake_retry.attempts") < 0 {
+		return errors.New("invalid default.handshake_retry.attempts")
+	}
+	if viper.GetInt("default.handshake_retry.attempts") > 0 {
+		backoff := viper.GetInt("default.handshake_retry.backoff")
+		if backoff <= 0 {
+			return errors.New("invalid default.handshake_retry.backoff")
+		}
+		if viper.GetInt("default.handshake_retry.max_backoff") < backoff {
+			return errors.New("invalid default.handshake_retry.max_backoff")
+		}
+	}
+	if viper.GetInt("default.handshake_retry.failure_threshold") < 0 {
+		return errors.New("invalid default.handshake_retry.failure_threshold")
+	}
+	if viper.GetInt("default.conn_rate_limit") < 0 {
+		return errors.New("invalid default.conn_rate_limit")
+	}
+	if viper.GetInt("default.flow_mod_rate_limit") < 0 {
+		return errors.New("invalid default.flow_mod_rate_limit")
+	}
+	if viper.GetInt("default.packet_out_rate_limit") < 0 {
+		return errors.New("invalid default.packet_out_rate_limit")
+	}
 	vlanID := viper.GetInt("default.vlan_id")
 	if vlanID < 0 || vlanID > 4095 {
 		return errors.New("invalid default.vlan_id in the config file")
 	}
+	if _, err := network.LoadAdmissionConfig(); err != nil {
+		return errors.Wrap(err, "invalid admission")
+	}
+	if _, err := network.LoadAPIUsers(); err != nil {
+		return errors.Wrap(err, "invalid rest.auth")
+	}
+	if _, err := network.LoadSlices(); err != nil {
+		return errors.Wrap(err, "invalid slice")
+	}
+	if _, err := network.LoadPriorityBands(); err != nil {
+		return errors.Wrap(err, "invalid priority_band")
+	}
+	if len(viper.GetString("mysql.addr")) == 0 {
+		return errors.New("invalid mysql.addr")
+	}
+	if len(viper.GetString("mysql.username")) == 0 {
+		return errors.New("invalid mysql.username")
+	}
+	if len(viper.GetString("mysql.name")) == 0 {
+		return errors.New("invalid mysql.name")
+	}
+	if addr := viper.GetString("rest.listen_addr"); len(addr) > 0 && net.ParseIP(addr) == nil {
+		return errors.New("invalid rest.listen_addr")
+	}
+	if addr := viper.GetString("metrics.listen_addr"); len(addr) > 0 && net.ParseIP(addr) == nil {
+		return errors.New("invalid metrics.listen_addr")
+	}
+	if port := viper.GetInt("metrics.port"); port < 0 || port > 0xFFFF {
+		return errors.New("invalid metrics.port")
+	}
+	if addr := viper.GetString("sflow.listen_addr"); len(addr) > 0 && net.ParseIP(addr) == nil {
+		return errors.New("invalid sflow.listen_addr")
+	}
+	if port := viper.GetInt("sflow.port"); port < 0 || port > 0xFFFF {
+		return errors.New("invalid sflow.port")
+	}
+	if addr := viper.GetString("grpc.listen_addr"); len(addr) > 0 && net.ParseIP(addr) == nil {
+		return errors.New("invalid grpc.listen_addr")
+	}
+	if port := viper.GetInt("grpc.port"); port < 0 || port > 0xFFFF {
+		return errors.New("invalid grpc.port")
+	}
+	if viper.GetInt("grpc.port") > 0 && viper.GetBool("grpc.tls") {
+		if len(viper.GetString("grpc.cert_file")) == 0 || len(viper.GetString("grpc.key_file")) == 0 {
+			return errors.New("invalid grpc.cert_file or grpc.key_file")
+		}
+	}
+	if dir := viper.GetString("capture.dir"); len(dir) > 0 && !filepath.IsAbs(dir) {
+		return errors.New("invalid capture.dir: not an absolute path")
+	}
+	if file := viper.GetString("audit.file"); len(file) > 0 {
+		if !filepath.IsAbs(file) {
+			return errors.New("invalid audit.file: not an absolute path")
+		}
+		if viper.GetInt("audit.max_size") <= 0 {
+			return errors.New("invalid audit.max_size")
+		}
+		if viper.GetInt("audit.max_files") <= 0 {
+			return errors.New("invalid audit.max_files")
+		}
+		f, err := os.OpenFile(file, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0640)
+		if err != nil {
+			return fmt.Errorf("invalid audit.file: %v", err)
+		}
+		f.Close()
+	}
+	if port := viper.GetInt("rest.port"); port <= 0 || port > 0xFFFF {
+		return errors.New("invalid rest.port")
+	}
+	if viper.GetBool("rest.tls") {
+		if len(viper.GetString("rest.cert_file")) == 0 || len(viper.GetString("rest.key_file")) == 0 {
+			return errors.New("invalid rest.cert_file or rest.key_file")
+		}
+	}
 
 	return nil
 }
 
+// serveMetrics exposes the metrics on /metrics for Prometheus scraping, and the event
+// statistics of the applications on /debug/apps.
+func serveMetrics(addr string, port int, manager *northbound.Manager) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	mux.Handle("/debug/apps", manager.DebugHandler())
+	if err := http.ListenAndServe(net.JoinHostPort(addr, strconv.Itoa(port)), mux); err != nil {
+		logger.Errorf("failed to serve the metrics: %v", err)
+	}
+}
+
 func initElectionObserver(ctx context.Context, db *database.MySQL) *election.Observer {
 	observer := election.New(db)
 	go func() {
@@ -159,9 +369,7 @@ func initSignalHandler(controller *network.Controller, manager *northbound.Manag
 			if s == syscall.SIGTERM || s == syscall.SIGINT {
 				// Graceful shutdown
 				logger.Warning("Shutting down...")
-				cancel()
-				// Timeout for cancelation
-				time.Sleep(5 * time.Second)
+				shutdown(controller, cancel)
 				os.Exit(0)
 			} else if s == syscall.SIGHUP {
 				fmt.Println("* Controller status:")
@@ -173,12 +381,27 @@ func initSignalHandler(controller *network.Controller, manager *northbound.Manag
 	}()
 }
 
+// shutdown optionally removes the flows installed by us from the devices, and then closes
+// all the connections within default.shutdown.timeout.
+func shutdown(controller *network.Controller, cancel context.CancelFunc) {
+	ctx, cancelTimeout := context.WithTimeout(context.Background(), time.Duration(viper.GetInt("default.shutdown.timeout"))*time.Second)
+	defer cancelTimeout()
+
+	controller.Shutdown(ctx, viper.GetBool("default.shutdown.remove_flows"))
+	// Close the listener and all the connections.
+	cancel()
+	if err := controller.Wait(ctx); err != nil {
+		logger.Errorf("failed to close the connections: %v", err)
+		return
+	}
+	logger.Warning("all the connections have been closed")
+}
+
 func initLog(level logging.Level) error {
-	backend, err := newSyslog(programName)
+	backend, err := newLogBackend(viper.GetString("default.log_backend"), viper.GetString("default.log_file"))
 	if err != nil {
 		return err
 	}
-	backend = logging.NewBackendFormatter(backend, logging.MustStringFormatter(`%{level}: %{shortpkg}.%{shortfunc}: %{message}`))
 
 	loggerLeveled = logging.AddModuleLevel(backend)
 	// Set log level for all modules
@@ -188,6 +411,34 @@ func initLog(level logging.Level) error {
 	return nil
 }
 
+func newLogBackend(name, file string) (logging.Backend, error) {
+	const format = `%{level}: %{shortpkg}.%{shortfunc}: %{message}`
+
+	switch strings.ToLower(name) {
+	case "", "syslog":
+		backend, err := newSyslog(programName)
+		if err != nil {
+			return nil, err
+		}
+		return logging.NewBackendFormatter(backend, logging.MustStringFormatter(format)), nil
+	case "stderr":
+		backend := logging.NewLogBackend(os.Stderr, "", 0)
+		// Syslog adds the timestamp by itself, but stderr does not.
+		return logging.NewBackendFormatter(backend, logging.MustStringFormatter(`%{time:2006-01-02T15:04:05.000} `+format)), nil
+	case "json":
+		if len(file) == 0 {
+			return newJSONLog(os.Stderr), nil
+		}
+		f, err := os.OpenFile(file, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			return nil, err
+		}
+		return newJSONLog(f), nil
+	default:
+		return nil, fmt.Errorf("unknown log backend: %v", name)
+	}
+}
+
 func getLogLevel(level string) logging.Level {
 	level = strings.ToUpper(level)
 	ret, err := logging.LogLevel(level)
@@ -199,13 +450,15 @@ func getLogLevel(level string) logging.Level {
 	return ret
 }
 
-func listen(ctx context.Context, port int, controller *network.Controller, observer *election.Observer) {
+// listen accepts the connections from switches on addr, or all addresses if addr is empty.
+// The connections are secured by TLS if tlsConfig is not nil.
+func listen(ctx context.Context, addr string, port int, tlsConfig *tls.Config, controller *network.Controller) {
 	type KeepAliver interface {
 		SetKeepAlive(keepalive bool) error
 		SetKeepAlivePeriod(d time.Duration) error
 	}
 
-	listener, err := net.Listen("tcp", fmt.Sprintf(":%v", port))
+	listener, err := net.Listen("tcp", net.JoinHostPort(addr, strconv.Itoa(port)))
 	if err != nil {
 		logger.Errorf("failed to listen on %v port: %v", port, err)
 		return
@@ -221,13 +474,9 @@ func listen(ctx context.Context, port int, controller *network.Controller, obser
 				continue
 			}
 			logger.Infof("new device is connected from %v", conn.RemoteAddr())
-
-			// Only the master controller can serve the connections!
-			if observer.IsMaster() == false {
-				logger.Warningf("disconnecting the newly connected device (%v) because we are not the master controller!", conn.RemoteAddr())
-				conn.Close()
-				continue
-			}
+			// NOTE: The backup controllers also serve the connections from OpenFlow 1.3 devices
+			// in the SLAVE role, so that they can take over the devices as soon as they are elected.
+			// OpenFlow 1.0 devices are disconnected by the controller if we are not the master.
 
 			// Pass the new connection into the backlog queue.
 			c <- conn
@@ -255,6 +504,10 @@ func listen(ctx context.Context, port int, controller *network.Controller, obser
 					logger.Errorf("failed to enable socket keepalive: %v", err)
 				}
 			}
+			if tlsConfig != nil {
+				// The TLS handshake will be done by the first read or write on the connection.
+				conn = tls.Server(conn, tlsConfig)
+			}
 			controller.AddConnection(ctx, conn)
 		}
 	}
@@ -0,0 +1,413 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+// Package metrics provides counters and histograms that are exposed in the Prometheus
+// text format (https://prometheus.io/docs/instrumenting/exposition_formats/).
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// metric is a family of the time series that have the same name.
+type metric interface {
+	name() string
+	write(w io.Writer)
+}
+
+var (
+	mutex    sync.Mutex
+	registry = make(map[string]metric)
+)
+
+func register(m metric) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if _, ok := registry[m.name()]; ok {
+		panic(fmt.Sprintf("duplicated metric name: %v", m.name()))
+	}
+	registry[m.name()] = m
+}
+
+// WriteTo writes all the registered metrics in the Prometheus text format.
+func WriteTo(w io.Writer) {
+	mutex.Lock()
+	metrics := make([]metric, 0, len(registry))
+	for _, m := range registry {
+		metrics = append(metrics, m)
+	}
+	mutex.Unlock()
+
+	sort.Slice(metrics, func(i, j int) bool { return metrics[i].name() < metrics[j].name() })
+	for _, m := range metrics {
+		m.write(w)
+	}
+}
+
+// Handler returns a HTTP handler that serves the registered metrics for Prometheus scraping.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var buf bytes.Buffer
+		WriteTo(&buf)
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		w.Write(buf.Bytes())
+	})
+}
+
+// vec keeps the values of a metric for each combination of the label values.
+type vec struct {
+	metricName string
+	help       string
+	labels     []string
+
+	mutex sync.Mutex
+	// Key is the label values joined by '\xff'.
+	values map[string][]string
+}
+
+func newVec(name, help string, labels []string) vec {
+	return vec{
+		metricName: name,
+		help:       help,
+		labels:     labels,
+		values:     make(map[string][]string),
+	}
+}
+
+func (r *vec) name() string {
+	return r.metricName
+}
+
+// key returns the map key of the label values.
+// XXX: Caller should lock the mutex
+func (r *vec) key(values []string) string {
+	if len(values) != len(r.labels) {
+		panic(fmt.Sprintf("%v: expected %v label values, got %v", r.metricName, len(r.labels), len(values)))
+	}
+
+	k := strings.Join(values, "\xff")
+	if _, ok := r.values[k]; !ok {
+		r.values[k] = append([]string(nil), values...)
+	}
+
+	return k
+}
+
+// sortedKeys returns the map keys in the order of the label values.
+// XXX: Caller should lock the mutex
+func (r *vec) sortedKeys() []string {
+	keys := make([]string, 0, len(r.values))
+	for k := range r.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// labelPairs formats the label pairs of key and extra pairs, e.g., {dpid="1",le="0.1"}.
+// XXX: Caller should lock the mutex
+func (r *vec) labelPairs(key string, extra ...string) string {
+	pairs := make([]string, 0, len(r.labels)+len(extra)/2)
+	for i, v := range r.values[key] {
+		pairs = append(pairs, fmt.Sprintf("%v=%v", r.labels[i], strconv.Quote(v)))
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, fmt.Sprintf("%v=%v", extra[i], strconv.Quote(extra[i+1])))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func (r *vec) writeHeader(w io.Writer, typ string) {
+	fmt.Fprintf(w, "# HELP %v %v\n", r.metricName, r.help)
+	fmt.Fprintf(w, "# TYPE %v %v\n", r.metricName, typ)
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, +1) {
+		return "+Inf"
+	}
+
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// CounterVec is a monotonically increasing value for each combination of the label values.
+type CounterVec struct {
+	vec
+	counters map[string]float64
+}
+
+// NewCounterVec registers and returns a new counter. It panics if the name is already registered.
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	v := &CounterVec{
+		vec:      newVec(name, help, labels),
+		counters: make(map[string]float64),
+	}
+	register(v)
+
+	return v
+}
+
+// Inc increases the counter of the label values by 1.
+func (r *CounterVec) Inc(values ...string) {
+	r.Add(1, values...)
+}
+
+// Add increases the counter of the label values by delta, which should not be negative.
+func (r *CounterVec) Add(delta float64, values ...string) {
+	if delta < 0 {
+		panic("counter cannot decrease")
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.counters[r.key(values)] += delta
+}
+
+func (r *CounterVec) write(w io.Writer) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.writeHeader(w, "counter")
+	for _, k := range r.sortedKeys() {
+		fmt.Fprintf(w, "%v%v %v\n", r.metricName, r.labelPairs(k), formatFloat(r.counters[k]))
+	}
+}
+
+// CounterFunc is a counter whose value is read by a function when the metrics are written. It
+// is for the values counted elsewhere, e.g., atomically on a hot path where the mutex of
+// CounterVec costs too much.
+type CounterFunc struct {
+	metricName string
+	help       string
+	f          func() float64
+}
+
+// NewCounterFunc registers and returns a new counter whose value is f(). It panics if the name
+// is already registered.
+func NewCounterFunc(name, help string, f func() float64) *CounterFunc {
+	v := &CounterFunc{
+		metricName: name,
+		help:       help,
+		f:          f,
+	}
+	register(v)
+
+	return v
+}
+
+func (r *CounterFunc) name() string {
+	return r.metricName
+}
+
+func (r *CounterFunc) write(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %v %v\n", r.metricName, r.help)
+	fmt.Fprintf(w, "# TYPE %v counter\n", r.metricName)
+	fmt.Fprintf(w, "%v %v\n", r.metricName, formatFloat(r.f()))
+}
+
+type histogram struct {
+	// Cumulative counts of each bucket except +Inf.
+	buckets []uint64
+	count   uint64
+	sum     float64
+}
+
+// HistogramVec counts the observed values in the configurable buckets for each combination of the label values.
+type HistogramVec struct {
+	vec
+	// Upper bounds of the buckets in increasing order. +Inf bucket is implicit.
+	bounds     []float64
+	histograms map[string]*histogram
+}
+
+// NewHistogramVec registers and returns a new histogram whose bucket upper bounds are bounds
+// in increasing order. It panics if the name is already registered.
+func NewHistogramVec(name, help string, bounds []float64, labels ...string) *HistogramVec {
+	if !sort.Float64sAreSorted(bounds) {
+		panic("histogram bounds should be in increasing order")
+	}
+
+	v := &HistogramVec{
+		vec:        newVec(name, help, labels),
+		bounds:     bounds,
+		histograms: make(map[string]*histogram),
+	}
+	register(v)
+
+	return v
+}
+
+// Observe adds v to the histogram of the label values.
+func (r *HistogramVec) Observe(v float64, values ...string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	k := r.key(values)
+	h, ok := r.histograms[k]
+	if !ok {
+		h = &histogram{buckets: make([]uint64, len(r.bounds))}
+		r.histograms[k] = h
+	}
+	for i, bound := range r.bounds {
+		if v <= bound {
+			h.buckets[i]++
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+func (r *HistogramVec) write(w io.Writer) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.writeHeader(w, "histogram")
+	for _, k := range r.sortedKeys() {
+		h := r.histograms[k]
+		for i, bound := range r.bounds {
+			fmt.Fprintf(w, "%v_bucket%v %v\n", r.metricName, r.labelPairs(k, "le", formatFloat(bound)), h.buckets[i])
+		}
+		fmt.Fprintf(w, "%v_bucket%v %v\n", r.metricName, r.labelPairs(k, "le", "+Inf"), h.count)
+		fmt.Fprintf(w, "%v_sum%v %v\n", r.metricName, r.labelPairs(k), formatFloat(h.sum))
+		fmt.Fprintf(w, "%v_count%v %v\n", r.metricName, r.labelPairs(k), h.count)
+	}
+}
+
+// Sample is the value of a time series whose label values are Values.
+type Sample struct {
+	Values []string
+	Value  float64
+}
+
+// CounterVecFunc is a CounterFunc for each combination of the label values. The samples are
+// read by a function when the metrics are written.
+type CounterVecFunc struct {
+	vec
+	f func() []Sample
+}
+
+// NewCounterVecFunc registers and returns a new counter whose samples are f(). It panics if
+// the name is already registered.
+func NewCounterVecFunc(name, help string, f func() []Sample, labels ...string) *CounterVecFunc {
+	v := &CounterVecFunc{
+		vec: newVec(name, help, labels),
+		f:   f,
+	}
+	register(v)
+
+	return v
+}
+
+func (r *CounterVecFunc) write(w io.Writer) {
+	samples := r.f()
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	// The label values of the previous samples may have gone.
+	r.values = make(map[string][]string)
+	counters := make(map[string]float64)
+	for _, s := range samples {
+		counters[r.key(s.Values)] = s.Value
+	}
+
+	r.writeHeader(w, "counter")
+	for _, k := range r.sortedKeys() {
+		fmt.Fprintf(w, "%v%v %v\n", r.metricName, r.labelPairs(k), formatFloat(counters[k]))
+	}
+}
+
+// HistogramSample is the value of a histogram whose label values are Values.
+type HistogramSample struct {
+	Values []string
+	// Number of the observed values that are less than or equal to each bucket upper bound.
+	Buckets []uint64
+	Count   uint64
+	Sum     float64
+}
+
+// HistogramVecFunc is a HistogramVec whose samples are read by a function when the metrics
+// are written. It is for the histograms counted elsewhere, e.g., atomically on a hot path.
+type HistogramVecFunc struct {
+	vec
+	bounds []float64
+	f      func() []HistogramSample
+}
+
+// NewHistogramVecFunc registers and returns a new histogram whose bucket upper bounds are
+// bounds in increasing order and whose samples are f(). It panics if the name is already registered.
+func NewHistogramVecFunc(name, help string, bounds []float64, f func() []HistogramSample, labels ...string) *HistogramVecFunc {
+	if !sort.Float64sAreSorted(bounds) {
+		panic("histogram bounds should be in increasing order")
+	}
+
+	v := &HistogramVecFunc{
+		vec:    newVec(name, help, labels),
+		bounds: bounds,
+		f:      f,
+	}
+	register(v)
+
+	return v
+}
+
+func (r *HistogramVecFunc) write(w io.Writer) {
+	samples := r.f()
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	// The label values of the previous samples may have gone.
+	r.values = make(map[string][]string)
+	histograms := make(map[string]HistogramSample)
+	for _, s := range samples {
+		if len(s.Buckets) != len(r.bounds) {
+			panic(fmt.Sprintf("%v: expected %v buckets, got %v", r.metricName, len(r.bounds), len(s.Buckets)))
+		}
+		histograms[r.key(s.Values)] = s
+	}
+
+	r.writeHeader(w, "histogram")
+	for _, k := range r.sortedKeys() {
+		h := histograms[k]
+		for i, bound := range r.bounds {
+			fmt.Fprintf(w, "%v_bucket%v %v\n", r.metricName, r.labelPairs(k, "le", formatFloat(bound)), h.Buckets[i])
+		}
+		fmt.Fprintf(w, "%v_bucket%v %v\n", r.metricName, r.labelPairs(k, "le", "+Inf"), h.Count)
+		fmt.Fprintf(w, "%v_sum%v %v\n", r.metricName, r.labelPairs(k), formatFloat(h.Sum))
+		fmt.Fprintf(w, "%v_count%v %v\n", r.metricName, r.labelPairs(k), h.Count)
+	}
+}
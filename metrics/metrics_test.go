@@ -0,0 +1,131 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package metrics
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCounterVec(t *testing.T) {
+	counter := NewCounterVec("test_messages_total", "Number of messages.", "version", "type")
+	counter.Inc("4", "10")
+	counter.Add(2, "4", "10")
+	counter.Inc("1", "14")
+
+	var buf bytes.Buffer
+	counter.write(&buf)
+	expected := `# HELP test_messages_total Number of messages.
+# TYPE test_messages_total counter
+test_messages_total{version="1",type="14"} 1
+test_messages_total{version="4",type="10"} 3
+`
+	if buf.String() != expected {
+		t.Fatalf("unexpected output:\n%v", buf.String())
+	}
+}
+
+func TestCounterFunc(t *testing.T) {
+	n := 0
+	counter := NewCounterFunc("test_reads_total", "Number of reads.", func() float64 { return float64(n) })
+	n = 7
+
+	var buf bytes.Buffer
+	counter.write(&buf)
+	expected := `# HELP test_reads_total Number of reads.
+# TYPE test_reads_total counter
+test_reads_total 7
+`
+	if buf.String() != expected {
+		t.Fatalf("unexpected output:\n%v", buf.String())
+	}
+}
+
+func TestHistogramVec(t *testing.T) {
+	histogram := NewHistogramVec("test_rtt_seconds", "Round-trip time.", []float64{0.01, 0.1})
+	histogram.Observe(0.005)
+	histogram.Observe(0.05)
+	histogram.Observe(1)
+
+	var buf bytes.Buffer
+	histogram.write(&buf)
+	expected := `# HELP test_rtt_seconds Round-trip time.
+# TYPE test_rtt_seconds histogram
+test_rtt_seconds_bucket{le="0.01"} 1
+test_rtt_seconds_bucket{le="0.1"} 2
+test_rtt_seconds_bucket{le="+Inf"} 3
+test_rtt_seconds_sum 1.055
+test_rtt_seconds_count 3
+`
+	if buf.String() != expected {
+		t.Fatalf("unexpected output:\n%v", buf.String())
+	}
+}
+
+func TestCounterVecFunc(t *testing.T) {
+	samples := []Sample{{Values: []string{"b"}, Value: 2}, {Values: []string{"a"}, Value: 1}}
+	counter := NewCounterVecFunc("test_calls_total", "Number of calls.", func() []Sample { return samples }, "app")
+
+	var buf bytes.Buffer
+	counter.write(&buf)
+	expected := `# HELP test_calls_total Number of calls.
+# TYPE test_calls_total counter
+test_calls_total{app="a"} 1
+test_calls_total{app="b"} 2
+`
+	if buf.String() != expected {
+		t.Fatalf("unexpected output:\n%v", buf.String())
+	}
+
+	// The series that are no longer sampled disappear.
+	samples = samples[:1]
+	buf.Reset()
+	counter.write(&buf)
+	expected = `# HELP test_calls_total Number of calls.
+# TYPE test_calls_total counter
+test_calls_total{app="b"} 2
+`
+	if buf.String() != expected {
+		t.Fatalf("unexpected output:\n%v", buf.String())
+	}
+}
+
+func TestHistogramVecFunc(t *testing.T) {
+	f := func() []HistogramSample {
+		return []HistogramSample{{Values: []string{"a"}, Buckets: []uint64{1, 2}, Count: 3, Sum: 1.055}}
+	}
+	histogram := NewHistogramVecFunc("test_latency_seconds", "Latency.", []float64{0.01, 0.1}, f, "app")
+
+	var buf bytes.Buffer
+	histogram.write(&buf)
+	expected := `# HELP test_latency_seconds Latency.
+# TYPE test_latency_seconds histogram
+test_latency_seconds_bucket{app="a",le="0.01"} 1
+test_latency_seconds_bucket{app="a",le="0.1"} 2
+test_latency_seconds_bucket{app="a",le="+Inf"} 3
+test_latency_seconds_sum{app="a"} 1.055
+test_latency_seconds_count{app="a"} 3
+`
+	if buf.String() != expected {
+		t.Fatalf("unexpected output:\n%v", buf.String())
+	}
+}
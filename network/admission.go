@@ -0,0 +1,392 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/superkkt/cherry/clock"
+
+	"github.com/ant0ine/go-json-rest/rest"
+	"github.com/superkkt/viper"
+)
+
+const (
+	// Admit all the switches except the blacklisted ones.
+	AdmissionOpen = "open"
+	// Admit only the whitelisted switches that are not blacklisted.
+	AdmissionWhitelist = "whitelist"
+)
+
+type admissionDecision int
+
+const (
+	admissionAccepted admissionDecision = iota
+	admissionRejected
+	// The switch is kept connected until it is approved.
+	admissionPending
+)
+
+// AdmissionConfig is the admission control of the switches defined in the config file.
+type AdmissionConfig struct {
+	Mode      string
+	Whitelist []string
+	Blacklist []string
+	// Hold the unknown switches in the pending state instead of disconnecting them in the
+	// whitelist mode.
+	HoldPending bool
+}
+
+// parseDPIDs parses s that is a comma separated list of DPIDs in decimal.
+func parseDPIDs(s string) ([]string, error) {
+	result := []string{}
+	for _, v := range splitList(s) {
+		dpid, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DPID: %v", v)
+		}
+		result = append(result, strconv.FormatUint(dpid, 10))
+	}
+
+	return result, nil
+}
+
+// LoadAdmissionConfig returns the admission control defined in the admission section of the
+// config file.
+func LoadAdmissionConfig() (AdmissionConfig, error) {
+	mode := strings.ToLower(strings.TrimSpace(viper.GetString("admission.mode")))
+	switch mode {
+	case "":
+		mode = AdmissionOpen
+	case AdmissionOpen, AdmissionWhitelist:
+	default:
+		return AdmissionConfig{}, fmt.Errorf("invalid admission mode: %v", mode)
+	}
+	whitelist, err := parseDPIDs(viper.GetString("admission.whitelist"))
+	if err != nil {
+		return AdmissionConfig{}, err
+	}
+	blacklist, err := parseDPIDs(viper.GetString("admission.blacklist"))
+	if err != nil {
+		return AdmissionConfig{}, err
+	}
+
+	return AdmissionConfig{
+		Mode:        mode,
+		Whitelist:   whitelist,
+		Blacklist:   blacklist,
+		HoldPending: viper.GetBool("admission.hold_pending"),
+	}, nil
+}
+
+type PendingSwitch struct {
+	DPID    string    `json:"dpid"`
+	Address string    `json:"address"`
+	Since   time.Time `json:"since"`
+}
+
+type AdmissionStatus struct {
+	Mode      string          `json:"mode"`
+	Whitelist []string        `json:"whitelist"`
+	Blacklist []string        `json:"blacklist"`
+	Pending   []PendingSwitch `json:"pending"`
+}
+
+type pendingSession struct {
+	session *session
+	since   time.Time
+}
+
+// admissionControl decides whether a switch is admitted into the device pool by its DPID when
+// it sends the first FEATURES_REPLY. The lists can be changed by the REST API, but the changes
+// are not written to the config file, so they are lost when the controller restarts.
+type admissionControl struct {
+	clock clock.Clock
+
+	mutex       sync.Mutex
+	mode        string
+	holdPending bool
+	whitelist   map[string]bool
+	blacklist   map[string]bool
+	// Sessions waiting for the approval. Key is the DPID.
+	pending map[string]pendingSession
+}
+
+func newAdmissionControl(conf AdmissionConfig, clk clock.Clock) *admissionControl {
+	if conf.Mode != AdmissionOpen && conf.Mode != AdmissionWhitelist {
+		panic(fmt.Sprintf("invalid admission mode: %v", conf.Mode))
+	}
+	if clk == nil {
+		panic("clock is nil")
+	}
+
+	v := &admissionControl{
+		clock:       clk,
+		mode:        conf.Mode,
+		holdPending: conf.HoldPending,
+		whitelist:   make(map[string]bool),
+		blacklist:   make(map[string]bool),
+		pending:     make(map[string]pendingSession),
+	}
+	for _, dpid := range conf.Whitelist {
+		v.whitelist[dpid] = true
+	}
+	for _, dpid := range conf.Blacklist {
+		v.blacklist[dpid] = true
+	}
+
+	return v
+}
+
+// XXX: Caller should lock the mutex
+func (r *admissionControl) decide(dpid string) admissionDecision {
+	if r.blacklist[dpid] {
+		return admissionRejected
+	}
+	if r.mode == AdmissionOpen || r.whitelist[dpid] {
+		return admissionAccepted
+	}
+	if r.holdPending {
+		return admissionPending
+	}
+
+	return admissionRejected
+}
+
+// check decides whether the switch of session s, whose DPID is dpid, is admitted. The session
+// is held until it is approved if the decision is admissionPending.
+func (r *admissionControl) check(dpid string, s *session) admissionDecision {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	d := r.decide(dpid)
+	switch d {
+	case admissionRejected:
+		rateLogger.Warningf("admission "+dpid, "rejected the switch: DPID=%v, address=%v", dpid, s.remoteAddr)
+	case admissionPending:
+		logger.Warningf("unknown switch is waiting for the approval: DPID=%v, address=%v", dpid, s.remoteAddr)
+		r.pending[dpid] = pendingSession{session: s, since: r.clock.Now()}
+	}
+
+	return d
+}
+
+// isPending returns whether session s is waiting for the approval.
+func (r *admissionControl) isPending(s *session) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, v := range r.pending {
+		if v.session == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+// remove forgets session s if it is waiting for the approval.
+func (r *admissionControl) remove(s *session) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for dpid, v := range r.pending {
+		if v.session == s {
+			delete(r.pending, dpid)
+		}
+	}
+}
+
+// Approve whitelists dpid, and returns the session of the switch if it has been waiting for
+// the approval.
+func (r *admissionControl) Approve(dpid string) *session {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.whitelist[dpid] = true
+	v, ok := r.pending[dpid]
+	if !ok || r.decide(dpid) != admissionAccepted {
+		return nil
+	}
+	delete(r.pending, dpid)
+
+	return v.session
+}
+
+// Unwhitelist removes dpid from the whitelist, and returns whether the switch is still admitted.
+func (r *admissionControl) Unwhitelist(dpid string) (admitted bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.whitelist, dpid)
+
+	return r.decide(dpid) == admissionAccepted
+}
+
+// Block blacklists dpid, and returns the session of the switch if it has been waiting for
+// the approval.
+func (r *admissionControl) Block(dpid string) *session {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.blacklist[dpid] = true
+	v, ok := r.pending[dpid]
+	if !ok {
+		return nil
+	}
+	delete(r.pending, dpid)
+
+	return v.session
+}
+
+func (r *admissionControl) Unblock(dpid string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.blacklist, dpid)
+}
+
+func sortedKeys(m map[string]bool) []string {
+	result := make([]string, 0, len(m))
+	for k := range m {
+		result = append(result, k)
+	}
+	sort.Strings(result)
+
+	return result
+}
+
+func (r *admissionControl) Status() AdmissionStatus {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	pending := make([]PendingSwitch, 0, len(r.pending))
+	for dpid, v := range r.pending {
+		pending = append(pending, PendingSwitch{DPID: dpid, Address: v.session.remoteAddr.String(), Since: v.since})
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].DPID < pending[j].DPID })
+
+	return AdmissionStatus{
+		Mode:      r.mode,
+		Whitelist: sortedKeys(r.whitelist),
+		Blacklist: sortedKeys(r.blacklist),
+		Pending:   pending,
+	}
+}
+
+// admissionDPID returns the dpid path parameter normalized.
+func admissionDPID(w rest.ResponseWriter, req *rest.Request) (dpid string, ok bool) {
+	v, err := strconv.ParseUint(req.PathParam("dpid"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errors.New("invalid DPID"))
+		return "", false
+	}
+
+	return strconv.FormatUint(v, 10), true
+}
+
+func (r *Controller) getAdmission(w rest.ResponseWriter, req *rest.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.WriteJson(r.admission.Status())
+}
+
+// approveSwitch whitelists a switch. The switch waiting for the approval resumes its handshake.
+func (r *Controller) approveSwitch(w rest.ResponseWriter, req *rest.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	dpid, ok := admissionDPID(w, req)
+	if !ok {
+		return
+	}
+	logger.Infof("whitelisted the switch: DPID=%v", dpid)
+	if s := r.admission.Approve(dpid); s != nil {
+		logger.Infof("resuming the handshake of the approved switch: DPID=%v", dpid)
+		// The next FEATURES_REPLY will initialize the device.
+		if err := sendFeaturesRequest(s.device.Factory(), s); err != nil {
+			logger.Errorf("failed to send FEATURES_REQUEST to the approved switch %v: %v", dpid, err)
+			s.device.disconnect()
+		}
+	}
+
+	w.WriteJson(r.admission.Status())
+}
+
+func (r *Controller) unwhitelistSwitch(w rest.ResponseWriter, req *rest.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	dpid, ok := admissionDPID(w, req)
+	if !ok {
+		return
+	}
+	logger.Infof("removed the switch from the whitelist: DPID=%v", dpid)
+	if !r.admission.Unwhitelist(dpid) {
+		r.disconnectDevice(dpid)
+	}
+
+	w.WriteJson(r.admission.Status())
+}
+
+func (r *Controller) blockSwitch(w rest.ResponseWriter, req *rest.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	dpid, ok := admissionDPID(w, req)
+	if !ok {
+		return
+	}
+	logger.Infof("blacklisted the switch: DPID=%v", dpid)
+	if s := r.admission.Block(dpid); s != nil {
+		s.device.disconnect()
+	}
+	r.disconnectDevice(dpid)
+
+	w.WriteJson(r.admission.Status())
+}
+
+func (r *Controller) unblockSwitch(w rest.ResponseWriter, req *rest.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	dpid, ok := admissionDPID(w, req)
+	if !ok {
+		return
+	}
+	logger.Infof("removed the switch from the blacklist: DPID=%v", dpid)
+	r.admission.Unblock(dpid)
+
+	w.WriteJson(r.admission.Status())
+}
+
+// disconnectDevice closes the connection of the device whose DPID is dpid, if it is connected.
+func (r *Controller) disconnectDevice(dpid string) {
+	device := r.topo.Device(dpid)
+	if device == nil {
+		return
+	}
+	logger.Warningf("disconnecting the switch that is no longer admitted: DPID=%v", dpid)
+	device.disconnect()
+}
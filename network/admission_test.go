@@ -0,0 +1,126 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/superkkt/cherry/testutil"
+
+	"github.com/superkkt/viper"
+)
+
+func TestLoadAdmissionConfig(t *testing.T) {
+	defer viper.Reset()
+
+	viper.Set("admission.mode", "Whitelist")
+	viper.Set("admission.whitelist", "1, 0002")
+	viper.Set("admission.hold_pending", true)
+	conf, err := LoadAdmissionConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Mode != AdmissionWhitelist || !conf.HoldPending || len(conf.Whitelist) != 2 || conf.Whitelist[1] != "2" || len(conf.Blacklist) != 0 {
+		t.Fatalf("unexpected config: %+v", conf)
+	}
+
+	viper.Set("admission.blacklist", "1,x")
+	if _, err := LoadAdmissionConfig(); err == nil {
+		t.Fatal("expected an error for the invalid DPID")
+	}
+	viper.Set("admission.blacklist", "")
+	viper.Set("admission.mode", "closed")
+	if _, err := LoadAdmissionConfig(); err == nil {
+		t.Fatal("expected an error for the invalid mode")
+	}
+}
+
+func TestAdmissionControl(t *testing.T) {
+	clock := testutil.NewFakeClock(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))
+	s := &session{remoteAddr: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1000}}
+
+	open := newAdmissionControl(AdmissionConfig{Mode: AdmissionOpen, Blacklist: []string{"2"}}, clock)
+	if open.check("1", s) != admissionAccepted {
+		t.Fatal("unknown switch should be accepted in the open mode")
+	}
+	if open.check("2", s) != admissionRejected {
+		t.Fatal("blacklisted switch should be rejected")
+	}
+
+	whitelist := newAdmissionControl(AdmissionConfig{Mode: AdmissionWhitelist, Whitelist: []string{"1", "2"}, Blacklist: []string{"2"}}, clock)
+	if whitelist.check("1", s) != admissionAccepted {
+		t.Fatal("whitelisted switch should be accepted")
+	}
+	if whitelist.check("2", s) != admissionRejected {
+		t.Fatal("blacklist should precede the whitelist")
+	}
+	if whitelist.check("3", s) != admissionRejected {
+		t.Fatal("unknown switch should be rejected without hold_pending")
+	}
+	if whitelist.Unwhitelist("1") {
+		t.Fatal("unwhitelisted switch should not be admitted")
+	}
+}
+
+func TestAdmissionPending(t *testing.T) {
+	clock := testutil.NewFakeClock(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))
+	admission := newAdmissionControl(AdmissionConfig{Mode: AdmissionWhitelist, HoldPending: true}, clock)
+	s1 := &session{remoteAddr: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1000}}
+	s2 := &session{remoteAddr: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 2), Port: 1000}}
+
+	if admission.check("1", s1) != admissionPending || admission.check("2", s2) != admissionPending {
+		t.Fatal("unknown switch should be pending")
+	}
+	if !admission.isPending(s1) || !admission.isPending(s2) {
+		t.Fatal("session should be pending")
+	}
+	status := admission.Status()
+	if len(status.Pending) != 2 || status.Pending[0].DPID != "1" || status.Pending[0].Address != "10.0.0.1:1000" || !status.Pending[0].Since.Equal(clock.Now()) {
+		t.Fatalf("unexpected status: %+v", status)
+	}
+
+	if admission.Approve("1") != s1 {
+		t.Fatal("approval should return the pending session")
+	}
+	if admission.isPending(s1) || admission.check("1", s1) != admissionAccepted {
+		t.Fatal("approved switch should be accepted")
+	}
+	if admission.Block("2") != s2 || admission.isPending(s2) {
+		t.Fatal("blocked switch should not be pending")
+	}
+	if admission.Approve("2") != nil || admission.check("2", s2) != admissionRejected {
+		t.Fatal("blacklisted switch should be rejected even if it is whitelisted")
+	}
+
+	admission.Unblock("2")
+	if admission.check("2", s2) != admissionAccepted {
+		t.Fatal("unblocked whitelisted switch should be accepted")
+	}
+	admission.Unwhitelist("2")
+	admission.check("2", s2)
+	admission.remove(s2)
+	if len(admission.Status().Pending) != 0 {
+		t.Fatal("closed session should not be pending")
+	}
+}
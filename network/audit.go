@@ -0,0 +1,345 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/superkkt/cherry/audit"
+	"github.com/superkkt/cherry/clock"
+	"github.com/superkkt/cherry/openflow"
+	"github.com/superkkt/cherry/openflow/of10"
+	"github.com/superkkt/cherry/openflow/of13"
+
+	"github.com/ant0ine/go-json-rest/rest"
+	"github.com/superkkt/viper"
+)
+
+// openAuditLog opens the audit log file of the audit section of the config file. It returns
+// nil if the audit log is disabled.
+func openAuditLog() (*audit.Log, error) {
+	path := viper.GetString("audit.file")
+	if len(path) == 0 {
+		return nil, nil
+	}
+
+	return audit.Open(path, int64(viper.GetInt("audit.max_size"))*1024*1024, viper.GetInt("audit.max_files"), clock.Real)
+}
+
+func auditResult(err error) string {
+	if err != nil {
+		return err.Error()
+	}
+
+	return "ok"
+}
+
+// commandName returns the name of the command of a FLOW_MOD, GROUP_MOD or METER_MOD, which
+// share the values of ADD, MODIFY and DELETE.
+func commandName(cmd int) string {
+	names := []string{"add", "modify", "delete", "modify_strict", "delete_strict"}
+	if cmd < 0 || cmd >= len(names) {
+		return fmt.Sprintf("unknown(%v)", cmd)
+	}
+
+	return names[cmd]
+}
+
+// Maximum number of the entries waiting for the outcomes, and of the replies received before
+// their requests are audited, in a session.
+const maxPendingAudits = 1024
+
+// sessionAudit records the FLOW_MOD, GROUP_MOD and METER_MOD messages written to the device
+// of a session with their outcomes: the error replied by the device, or "ok" once a barrier
+// sent after the message is replied without an error.
+type sessionAudit struct {
+	log     *audit.Log
+	session *session
+
+	mutex sync.Mutex
+	// Messages and barriers written to the device in order, waiting for the replies.
+	pending []pendingAudit
+	// Replies received before their requests are audited. Key is the transaction ID, and the
+	// value is the error, or nil for a barrier reply.
+	early map[uint32]error
+}
+
+type pendingAudit struct {
+	xid uint32
+	// True if this is a barrier request, which has no entry.
+	barrier bool
+	entry   audit.Entry
+}
+
+func newSessionAudit(log *audit.Log, s *session) *sessionAudit {
+	return &sessionAudit{
+		log:     log,
+		session: s,
+		early:   make(map[uint32]error),
+	}
+}
+
+func (r *sessionAudit) Audit(msg encoding.BinaryMarshaler, err error) {
+	switch v := msg.(type) {
+	case *of10.BarrierRequest, *of13.BarrierRequest:
+		if err == nil {
+			r.barrier(v.(openflow.Header).TransactionID())
+		}
+		return
+	}
+
+	e := audit.Entry{
+		Time:  r.session.clock.Now(),
+		Actor: "controller",
+		DPID:  r.session.eventDevice().ID(),
+	}
+	var xid uint32
+	switch v := msg.(type) {
+	case openflow.FlowMod:
+		xid = v.TransactionID()
+		e.Operation = "flow_mod"
+		e.Detail = fmt.Sprintf("command=%v, table=%v, priority=%v, cookie=0x%x, match=%v", commandName(int(v.Command())), v.TableID(), v.Priority(), v.Cookie(), auditMatch(v.FlowMatch()))
+		if owner := cookieOwnerName(v.Cookie()); owner != "" {
+			e.Actor = fmt.Sprintf("app/%v", owner)
+		}
+	case openflow.GroupMod:
+		xid = v.TransactionID()
+		e.Operation = "group_mod"
+		e.Detail = fmt.Sprintf("command=%v, group=%v, buckets=%v", commandName(int(v.Command())), v.GroupID(), len(v.Buckets()))
+	case openflow.MeterMod:
+		xid = v.TransactionID()
+		e.Operation = "meter_mod"
+		e.Detail = fmt.Sprintf("command=%v, meter=%v, bands=%v", commandName(int(v.Command())), v.MeterID(), len(v.Bands()))
+	default:
+		return
+	}
+	if len(e.DPID) == 0 {
+		// The device has not completed the handshake yet.
+		e.Detail += fmt.Sprintf(", address=%v", r.session.remoteAddr)
+	}
+	if err != nil {
+		// Not sent to the device at all.
+		e.Result = err.Error()
+		r.record(e)
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if reply, ok := r.early[xid]; ok {
+		delete(r.early, xid)
+		e.Result = auditResult(reply)
+		r.record(e)
+		return
+	}
+	if len(r.pending) >= maxPendingAudits {
+		r.unconfirm(1)
+	}
+	r.pending = append(r.pending, pendingAudit{xid: xid, entry: e})
+}
+
+func (r *sessionAudit) barrier(xid uint32) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.early[xid]; ok {
+		delete(r.early, xid)
+		r.confirm(len(r.pending))
+		return
+	}
+	if len(r.pending) >= maxPendingAudits {
+		r.unconfirm(1)
+	}
+	r.pending = append(r.pending, pendingAudit{xid: xid, barrier: true})
+}
+
+// Replied is called with the reply of the device whose transaction ID is xid: err is the error
+// replied, or nil for a barrier reply.
+func (r *sessionAudit) Replied(xid uint32, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for i, v := range r.pending {
+		if v.xid != xid {
+			continue
+		}
+		if v.barrier {
+			// The device has processed all the messages before the barrier without an error.
+			r.confirm(i + 1)
+		} else {
+			v.entry.Result = auditResult(err)
+			r.record(v.entry)
+			r.pending = append(r.pending[:i], r.pending[i+1:]...)
+		}
+		return
+	}
+
+	// The request may not have been audited yet.
+	if len(r.early) >= maxPendingAudits {
+		r.early = make(map[uint32]error)
+	}
+	r.early[xid] = err
+}
+
+// Close records the messages whose outcomes are unknown because the session has been closed.
+func (r *sessionAudit) Close() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.unconfirm(len(r.pending))
+}
+
+// confirm records the first n pending messages as succeeded.
+// XXX: Caller should lock the mutex
+func (r *sessionAudit) confirm(n int) {
+	for _, v := range r.pending[:n] {
+		if v.barrier {
+			continue
+		}
+		v.entry.Result = "ok"
+		r.record(v.entry)
+	}
+	r.pending = r.pending[n:]
+}
+
+// unconfirm records the first n pending messages as unconfirmed.
+// XXX: Caller should lock the mutex
+func (r *sessionAudit) unconfirm(n int) {
+	for _, v := range r.pending[:n] {
+		if v.barrier {
+			continue
+		}
+		v.entry.Result = "unconfirmed"
+		r.record(v.entry)
+	}
+	r.pending = r.pending[n:]
+}
+
+func (r *sessionAudit) record(e audit.Entry) {
+	if err := r.log.Record(e); err != nil {
+		rateLogger.Errorf("audit", "failed to record the audit log: %v", err)
+	}
+}
+
+// auditMatch returns the fields of match in the canonical form.
+func auditMatch(match openflow.Match) string {
+	if match == nil {
+		return ""
+	}
+	v, err := newCanonicalMatch(match)
+	if err != nil {
+		return fmt.Sprintf("invalid(%v)", err)
+	}
+
+	return v.String()
+}
+
+// auditMiddleware records the REST API calls that change the state, i.e., other than GET,
+// HEAD and OPTIONS. It should wrap rest.RecorderMiddleware that records the status code.
+func (r *Controller) auditMiddleware(handler rest.HandlerFunc) rest.HandlerFunc {
+	return func(w rest.ResponseWriter, req *rest.Request) {
+		handler(w, req)
+
+		switch req.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			return
+		}
+		status, _ := req.Env["STATUS_CODE"].(int)
+		result := "ok"
+		if status >= 400 {
+			result = fmt.Sprintf("HTTP %v", status)
+		}
+		actor := req.RemoteAddr
+		if user, ok := req.Env["REMOTE_USER"].(string); ok {
+			actor = user + "@" + req.RemoteAddr
+		}
+		e := audit.Entry{
+			Actor:     actor,
+			Operation: "api",
+			Detail:    fmt.Sprintf("%v %v", req.Method, req.URL.Path),
+			DPID:      req.PathParam("dpid"),
+			Result:    result,
+		}
+		if err := r.auditLog.Record(e); err != nil {
+			rateLogger.Errorf("audit", "failed to record the audit log: %v", err)
+		}
+	}
+}
+
+// queryAudit returns the audit log entries selected by the query parameters: since and until
+// in RFC 3339, actor, operation, dpid, and limit.
+func (r *Controller) queryAudit(w rest.ResponseWriter, req *rest.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	if r.auditLog == nil {
+		writeError(w, http.StatusNotFound, errors.New("audit log is disabled"))
+		return
+	}
+
+	query := req.URL.Query()
+	filter := audit.Filter{
+		Actor:     query.Get("actor"),
+		Operation: query.Get("operation"),
+		DPID:      query.Get("dpid"),
+	}
+	for _, v := range []struct {
+		name string
+		t    *time.Time
+	}{{"since", &filter.Since}, {"until", &filter.Until}} {
+		s := query.Get(v.name)
+		if len(s) == 0 {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid %v: %v", v.name, s))
+			return
+		}
+		*v.t = t
+	}
+	if s := query.Get("limit"); len(s) > 0 {
+		limit, err := strconv.Atoi(s)
+		if err != nil || limit <= 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid limit: %v", s))
+			return
+		}
+		filter.Limit = limit
+	}
+
+	entries, err := r.auditLog.Query(filter)
+	if err != nil {
+		logger.Errorf("failed to query the audit log: %v", err)
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.WriteJson(&struct {
+		Entries []audit.Entry `json:"entries"`
+	}{entries})
+}
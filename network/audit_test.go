@@ -0,0 +1,125 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/superkkt/cherry/audit"
+	"github.com/superkkt/cherry/openflow"
+	"github.com/superkkt/cherry/openflow/of13"
+	"github.com/superkkt/cherry/testutil"
+)
+
+func TestSessionAudit(t *testing.T) {
+	clock := testutil.NewFakeClock(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))
+	log, err := audit.Open(filepath.Join(t.TempDir(), "audit.log"), 1024*1024, 1, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer log.Close()
+
+	s := &session{
+		remoteAddr:   &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1000},
+		clock:        clock,
+		flowConflict: newConflictPolicy(),
+	}
+	s.device = newDevice(s)
+	a := newSessionAudit(log, s)
+
+	f := of13.NewFactory()
+	flow := newTestFlow(t, f, openflow.FlowDeleteStrict, net.HardwareAddr{0x0a, 0, 0, 0, 0, 1}, 0)
+	flow.SetCookie(NewCookie("Test", 1))
+	// Sent before the handshake completes.
+	a.Audit(flow, nil)
+	barrier, err := f.NewBarrierRequest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.Audit(barrier, nil)
+
+	s.device.setID("1")
+	group, err := f.NewGroupMod(openflow.GroupAdd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	group.SetGroupID(7)
+	// Not queued, so recorded at once.
+	a.Audit(group, errors.New("queue is full"))
+	// Other messages are not recorded.
+	hello, err := f.NewHello()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.Audit(hello, nil)
+	if entries, err := log.Query(audit.Filter{}); err != nil || len(entries) != 1 {
+		t.Fatalf("unexpected entries before the replies: %+v, %v", entries, err)
+	}
+	// The barrier reply confirms the flow.
+	a.Replied(barrier.TransactionID(), nil)
+
+	// Rejected by the device.
+	rejected := newTestFlow(t, f, openflow.FlowAdd, nil, 0)
+	a.Audit(rejected, nil)
+	barrier, err = f.NewBarrierRequest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.Audit(barrier, nil)
+	a.Replied(rejected.TransactionID(), errors.New("table is full"))
+	a.Replied(barrier.TransactionID(), nil)
+
+	// The error is received before the flow is audited.
+	early := newTestFlow(t, f, openflow.FlowAdd, nil, 0)
+	a.Replied(early.TransactionID(), errors.New("bad match"))
+	a.Audit(early, nil)
+
+	// No reply before the session is closed.
+	a.Audit(newTestFlow(t, f, openflow.FlowAdd, nil, 0), nil)
+	a.Close()
+
+	entries, err := log.Query(audit.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 5 {
+		t.Fatalf("expected 5 entries, got %+v", entries)
+	}
+	if v := entries[0]; v.Operation != "group_mod" || v.Actor != "controller" || v.DPID != "1" || v.Result != "queue is full" ||
+		v.Detail != "command=add, group=7, buckets=0" {
+		t.Fatalf("unexpected group_mod entry: %+v", v)
+	}
+	if v := entries[1]; v.Operation != "flow_mod" || v.Actor != fmt.Sprintf("app/%v", CookieOwnerID("Test")) || v.DPID != "" || v.Result != "ok" ||
+		v.Detail != fmt.Sprintf("command=delete_strict, table=0, priority=100, cookie=0x%x, match=8000:03=0a0000000001/ffffffffffff, address=10.0.0.1:1000", NewCookie("Test", 1)) {
+		t.Fatalf("unexpected flow_mod entry: %+v", v)
+	}
+	for i, result := range []string{"table is full", "bad match", "unconfirmed"} {
+		if v := entries[i+2]; v.Operation != "flow_mod" || v.DPID != "1" || v.Result != result {
+			t.Fatalf("unexpected flow_mod entry: %+v", v)
+		}
+	}
+}
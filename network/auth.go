@@ -0,0 +1,291 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/ant0ine/go-json-rest/rest"
+	"github.com/superkkt/viper"
+)
+
+// APIRole is the permission of a user of the REST API.
+type APIRole int
+
+const (
+	// Read the state of the network and the controller.
+	RoleReadOnly APIRole = iota
+	// RoleReadOnly plus changing the state of the network, e.g., installing flows.
+	RoleOperator
+	// RoleOperator plus administering the controller, e.g., admitting switches.
+	RoleAdmin
+)
+
+func (r APIRole) String() string {
+	switch r {
+	case RoleReadOnly:
+		return "read-only"
+	case RoleOperator:
+		return "operator"
+	case RoleAdmin:
+		return "admin"
+	default:
+		return fmt.Sprintf("unknown(%v)", int(r))
+	}
+}
+
+func parseAPIRole(s string) (APIRole, error) {
+	for _, v := range []APIRole{RoleReadOnly, RoleOperator, RoleAdmin} {
+		if strings.ToLower(strings.TrimSpace(s)) == v.String() {
+			return v, nil
+		}
+	}
+
+	return 0, fmt.Errorf("invalid role: %v", s)
+}
+
+// adminPaths are the API paths that only RoleAdmin can access, including the reads, because
+// they administer the controller or expose the captured packets and the operation history.
+var adminPaths = []string{
+	"/api/v1/admission",
+	"/api/v1/audit",
+	"/api/v1/capture",
+	"/api/v1/certificate",
+	"/api/v1/import",
+	"/api/v1/ovsdb",
+	"/api/v1/switch",
+}
+
+// permitted returns whether role is allowed to call method on path.
+func (r APIRole) permitted(method, path string) bool {
+	if r >= RoleAdmin {
+		return true
+	}
+	for _, v := range adminPaths {
+		if path == v || strings.HasPrefix(path, v+"/") {
+			return false
+		}
+	}
+	if r >= RoleOperator {
+		return true
+	}
+
+	return method == http.MethodGet || method == http.MethodHead
+}
+
+// tenantPermitted returns whether a tenant user is allowed to call method on path, which is
+// already permitted by the role of the user. A tenant can only read and add the flows
+// confined to its slice, so it cannot change the network shared with other tenants.
+func tenantPermitted(method, path string) bool {
+	if method == http.MethodGet || method == http.MethodHead {
+		return true
+	}
+	t := strings.Split(strings.Trim(path, "/"), "/")
+	// POST /api/v1/devices/:dpid/flows
+	return method == http.MethodPost && len(t) == 5 && t[0] == "api" && t[1] == "v1" && t[2] == "devices" && t[4] == "flows"
+}
+
+// APIUser is a user of the REST API defined in the config file. The user is authenticated by
+// the basic authentication with its name and password, or by the bearer token. Only the
+// SHA-256 digests of the password and the token are kept.
+type APIUser struct {
+	Name           string
+	Role           APIRole
+	PasswordSHA256 []byte
+	TokenSHA256    []byte
+	// Name of the slice that the flows of a tenant user are confined to. Empty means the
+	// user is not a tenant.
+	Slice string
+}
+
+func parseDigest(s string) ([]byte, error) {
+	if len(s) == 0 {
+		return nil, nil
+	}
+	v, err := hex.DecodeString(s)
+	if err != nil || len(v) != sha256.Size {
+		return nil, errors.New("invalid SHA-256 digest")
+	}
+
+	return v, nil
+}
+
+// LoadAPIUsers returns the users defined in rest.auth.users of the config file. It returns
+// nil if the authentication is disabled.
+func LoadAPIUsers() ([]APIUser, error) {
+	if !viper.GetBool("rest.auth.enable") {
+		return nil, nil
+	}
+
+	names := []string{}
+	for name := range viper.GetStringMap("rest.auth.users") {
+		names = append(names, name)
+	}
+	// Keep the order regardless of the map iteration.
+	sort.Strings(names)
+
+	slices, err := LoadSlices()
+	if err != nil {
+		return nil, err
+	}
+
+	result := []APIUser{}
+	tokens := make(map[string]string)
+	for _, name := range names {
+		key := "rest.auth.users." + name
+		role, err := parseAPIRole(viper.GetString(key + ".role"))
+		if err != nil {
+			return nil, fmt.Errorf("user %v: %v", name, err)
+		}
+		password, err := parseDigest(viper.GetString(key + ".password_sha256"))
+		if err != nil {
+			return nil, fmt.Errorf("user %v: password: %v", name, err)
+		}
+		token, err := parseDigest(viper.GetString(key + ".token_sha256"))
+		if err != nil {
+			return nil, fmt.Errorf("user %v: token: %v", name, err)
+		}
+		if password == nil && token == nil {
+			return nil, fmt.Errorf("user %v: neither password nor token", name)
+		}
+		if token != nil {
+			if other, ok := tokens[string(token)]; ok {
+				return nil, fmt.Errorf("user %v and %v have the same token", other, name)
+			}
+			tokens[string(token)] = name
+		}
+		slice := viper.GetString(key + ".slice")
+		if len(slice) > 0 && findSlice(slices, slice) == nil {
+			return nil, fmt.Errorf("user %v: unknown slice: %v", name, slice)
+		}
+		result = append(result, APIUser{Name: name, Role: role, PasswordSHA256: password, TokenSHA256: token, Slice: slice})
+	}
+	if len(result) == 0 {
+		return nil, errors.New("authentication is enabled without any user")
+	}
+
+	return result, nil
+}
+
+// apiAuth authenticates the clients of the REST API and authorizes their requests by the
+// roles of the users.
+type apiAuth struct {
+	users []APIUser
+}
+
+func newAPIAuth(users []APIUser) *apiAuth {
+	if len(users) == 0 {
+		panic("empty API users")
+	}
+
+	return &apiAuth{users: users}
+}
+
+func digestEqual(digest []byte, secret string) bool {
+	if digest == nil {
+		return false
+	}
+	v := sha256.Sum256([]byte(secret))
+
+	return subtle.ConstantTimeCompare(digest, v[:]) == 1
+}
+
+// authenticate returns the user of the credentials in the Authorization header, or nil if
+// the credentials are missing or invalid.
+func (r *apiAuth) authenticate(req *http.Request) *APIUser {
+	if name, password, ok := req.BasicAuth(); ok {
+		for i, v := range r.users {
+			if v.Name == name && digestEqual(v.PasswordSHA256, password) {
+				return &r.users[i]
+			}
+		}
+		return nil
+	}
+
+	const prefix = "Bearer "
+	header := req.Header.Get("Authorization")
+	if len(header) <= len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return nil
+	}
+	token := strings.TrimSpace(header[len(prefix):])
+	for i, v := range r.users {
+		if digestEqual(v.TokenSHA256, token) {
+			return &r.users[i]
+		}
+	}
+
+	return nil
+}
+
+// Request environment key of the slice of a tenant user.
+const tenantSliceEnv = "TENANT_SLICE"
+
+// tenantSlice returns the name of the slice that the user of req is confined to. ok is false
+// if the user is not a tenant.
+func tenantSlice(req *rest.Request) (name string, ok bool) {
+	name, ok = req.Env[tenantSliceEnv].(string)
+	return name, ok
+}
+
+// middleware denies the requests of the unauthenticated clients and the users whose role is
+// not permitted. The name of the authenticated user is stored in REMOTE_USER of the request
+// environment. The CORS preflight requests are allowed without the credentials because the
+// browsers do not send them.
+func (r *apiAuth) middleware(handler rest.HandlerFunc) rest.HandlerFunc {
+	return func(w rest.ResponseWriter, req *rest.Request) {
+		if req.Method == http.MethodOptions {
+			handler(w, req)
+			return
+		}
+
+		user := r.authenticate(req.Request)
+		if user == nil {
+			rateLogger.Warningf("auth "+req.RemoteAddr, "unauthenticated REST API request from %v: %v %v", req.RemoteAddr, req.Method, req.URL.Path)
+			w.Header().Set("WWW-Authenticate", `Basic realm="cherry"`)
+			writeError(w, http.StatusUnauthorized, errors.New("authentication required"))
+			return
+		}
+		if !user.Role.permitted(req.Method, req.URL.Path) {
+			logger.Warningf("denied the REST API request of %v (%v): %v %v", user.Name, user.Role, req.Method, req.URL.Path)
+			writeError(w, http.StatusForbidden, fmt.Errorf("%v role is not permitted to %v %v", user.Role, req.Method, req.URL.Path))
+			return
+		}
+		if len(user.Slice) > 0 {
+			if !tenantPermitted(req.Method, req.URL.Path) {
+				logger.Warningf("denied the REST API request of tenant %v (slice %v): %v %v", user.Name, user.Slice, req.Method, req.URL.Path)
+				writeError(w, http.StatusForbidden, fmt.Errorf("tenant of slice %v is not permitted to %v %v", user.Slice, req.Method, req.URL.Path))
+				return
+			}
+			req.Env[tenantSliceEnv] = user.Slice
+		}
+		req.Env["REMOTE_USER"] = user.Name
+
+		handler(w, req)
+	}
+}
@@ -0,0 +1,185 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ant0ine/go-json-rest/rest"
+	"github.com/superkkt/viper"
+)
+
+func digest(s string) string {
+	v := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(v[:])
+}
+
+func TestLoadAPIUsers(t *testing.T) {
+	defer viper.Reset()
+
+	// Disabled.
+	users, err := LoadAPIUsers()
+	if err != nil || users != nil {
+		t.Fatalf("expected no user, got %v (%v)", users, err)
+	}
+
+	viper.Set("rest.auth.enable", true)
+	if _, err := LoadAPIUsers(); err == nil {
+		t.Fatal("expected an error for no user")
+	}
+	viper.Set("rest.auth.users", map[string]interface{}{
+		"monitoring": map[string]interface{}{"role": "Read-Only", "token_sha256": digest("token")},
+		"alice":      map[string]interface{}{"role": "admin", "password_sha256": digest("password")},
+	})
+	users, err = LoadAPIUsers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 || users[0].Name != "alice" || users[0].Role != RoleAdmin || users[1].Role != RoleReadOnly || users[1].PasswordSHA256 != nil {
+		t.Fatalf("unexpected users: %+v", users)
+	}
+
+	viper.Set("rest.auth.users.monitoring.role", "guest")
+	if _, err := LoadAPIUsers(); err == nil {
+		t.Fatal("expected an error for the invalid role")
+	}
+	viper.Set("rest.auth.users.monitoring.role", "read-only")
+	viper.Set("rest.auth.users.monitoring.token_sha256", "1234")
+	if _, err := LoadAPIUsers(); err == nil {
+		t.Fatal("expected an error for the invalid digest")
+	}
+	viper.Set("rest.auth.users.monitoring.token_sha256", "")
+	if _, err := LoadAPIUsers(); err == nil {
+		t.Fatal("expected an error for the user without the credentials")
+	}
+	viper.Set("rest.auth.users.monitoring.token_sha256", digest("token"))
+	viper.Set("rest.auth.users.monitoring.slice", "tenant1")
+	if _, err := LoadAPIUsers(); err == nil {
+		t.Fatal("expected an error for the unknown slice")
+	}
+	viper.Set("slice.tenant1.vlans", "10")
+	users, err = LoadAPIUsers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if users[1].Slice != "tenant1" {
+		t.Fatalf("unexpected slice of the tenant: %+v", users[1])
+	}
+}
+
+func TestTenantPermitted(t *testing.T) {
+	tests := []struct {
+		method, path string
+		expected     bool
+	}{
+		{"GET", "/api/v1/devices", true},
+		{"POST", "/api/v1/devices/1/flows", true},
+		{"DELETE", "/api/v1/devices/1/flows", false},
+		{"DELETE", "/api/v1/devices/1/flows/owner/L2Switch", false},
+		{"POST", "/api/v1/devices/1/drain", false},
+		{"PUT", "/api/v1/host", false},
+	}
+	for _, v := range tests {
+		if got := tenantPermitted(v.method, v.path); got != v.expected {
+			t.Fatalf("%v %v: expected=%v, got=%v", v.method, v.path, v.expected, got)
+		}
+	}
+}
+
+func TestAPIAuth(t *testing.T) {
+	auth := newAPIAuth([]APIUser{
+		{Name: "monitoring", Role: RoleReadOnly, TokenSHA256: mustDigest(t, "token1")},
+		{Name: "bob", Role: RoleOperator, PasswordSHA256: mustDigest(t, "password")},
+		{Name: "alice", Role: RoleAdmin, TokenSHA256: mustDigest(t, "token2")},
+	})
+	api := rest.NewApi()
+	api.Use(rest.MiddlewareSimple(auth.middleware))
+	router, err := rest.MakeRouter(
+		rest.Get("/api/v1/host", func(w rest.ResponseWriter, req *rest.Request) {
+			w.WriteJson(req.Env["REMOTE_USER"])
+		}),
+		rest.Post("/api/v1/host", func(w rest.ResponseWriter, req *rest.Request) {}),
+		rest.Options("/api/v1/host/:id", func(w rest.ResponseWriter, req *rest.Request) {}),
+		rest.Get("/api/v1/audit", func(w rest.ResponseWriter, req *rest.Request) {}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	api.SetApp(router)
+	handler := api.MakeHandler()
+
+	tests := []struct {
+		method, path string
+		set          func(*http.Request)
+		status       int
+	}{
+		{"GET", "/api/v1/host", func(*http.Request) {}, http.StatusUnauthorized},
+		{"OPTIONS", "/api/v1/host/1", func(*http.Request) {}, http.StatusOK},
+		{"GET", "/api/v1/host", bearer("invalid"), http.StatusUnauthorized},
+		{"GET", "/api/v1/host", bearer("token1"), http.StatusOK},
+		{"POST", "/api/v1/host", bearer("token1"), http.StatusForbidden},
+		{"GET", "/api/v1/audit", bearer("token1"), http.StatusForbidden},
+		{"POST", "/api/v1/host", basic("bob", "invalid"), http.StatusUnauthorized},
+		{"POST", "/api/v1/host", basic("alice", "token2"), http.StatusUnauthorized},
+		{"POST", "/api/v1/host", basic("bob", "password"), http.StatusOK},
+		{"GET", "/api/v1/audit", basic("bob", "password"), http.StatusForbidden},
+		{"GET", "/api/v1/audit", bearer("token2"), http.StatusOK},
+	}
+	for _, v := range tests {
+		req := httptest.NewRequest(v.method, v.path, nil)
+		v.set(req)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != v.status {
+			t.Fatalf("%v %v: expected status %v, got %v", v.method, v.path, v.status, w.Code)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/api/v1/host", nil)
+	basic("bob", "password")(req)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Body.String() != `"bob"` {
+		t.Fatalf("unexpected remote user: %v", w.Body.String())
+	}
+}
+
+func mustDigest(t *testing.T, s string) []byte {
+	v, err := parseDigest(digest(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return v
+}
+
+func bearer(token string) func(*http.Request) {
+	return func(req *http.Request) { req.Header.Set("Authorization", "Bearer "+token) }
+}
+
+func basic(user, password string) func(*http.Request) {
+	return func(req *http.Request) { req.SetBasicAuth(user, password) }
+}
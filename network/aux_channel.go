@@ -0,0 +1,75 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/superkkt/viper"
+)
+
+// auxPolicy decides which messages are sent over the auxiliary connections of a device.
+// The messages sent by the device, such as PACKET_IN, are always received from any
+// connection that the device chooses.
+type auxPolicy int
+
+const (
+	// Send all the messages over the main connection.
+	auxPolicyMain auxPolicy = iota
+	// Send PACKET_OUT messages over the auxiliary connections in round-robin, and
+	// the other messages over the main connection.
+	auxPolicyPacket
+)
+
+func (r auxPolicy) String() string {
+	switch r {
+	case auxPolicyMain:
+		return "main"
+	case auxPolicyPacket:
+		return "packet"
+	default:
+		panic(fmt.Sprintf("unexpected auxiliary channel policy: %v", int(r)))
+	}
+}
+
+// parseAuxPolicy parses s that should be one of "main" and "packet". Empty s means "main".
+func parseAuxPolicy(s string) (auxPolicy, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "main":
+		return auxPolicyMain, nil
+	case "packet":
+		return auxPolicyPacket, nil
+	default:
+		return auxPolicyMain, fmt.Errorf("unknown auxiliary channel policy: %v", s)
+	}
+}
+
+func newAuxPolicy() auxPolicy {
+	policy, err := parseAuxPolicy(viper.GetString("default.aux_channel"))
+	if err != nil {
+		logger.Errorf("invalid default.aux_channel: %v (use the main connection only)", err)
+		return auxPolicyMain
+	}
+
+	return policy
+}
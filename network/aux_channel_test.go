@@ -0,0 +1,49 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"testing"
+)
+
+func TestParseAuxPolicy(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected auxPolicy
+		valid    bool
+	}{
+		{"", auxPolicyMain, true},
+		{"main", auxPolicyMain, true},
+		{" Packet ", auxPolicyPacket, true},
+		{"udp", auxPolicyMain, false},
+	}
+
+	for _, test := range tests {
+		policy, err := parseAuxPolicy(test.input)
+		if (err == nil) != test.valid {
+			t.Fatalf("%q: unexpected error: %v", test.input, err)
+		}
+		if policy != test.expected {
+			t.Fatalf("%q: expected=%v, got=%v", test.input, test.expected, policy)
+		}
+	}
+}
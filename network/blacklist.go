@@ -0,0 +1,136 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/ant0ine/go-json-rest/rest"
+)
+
+type BlacklistParam struct {
+	// Either MAC or IPv4 address of the blacklisted hosts.
+	MAC         string `json:"mac"`
+	IP          string `json:"ip"`
+	Description string `json:"description"`
+}
+
+func (r *BlacklistParam) validate() error {
+	if (len(r.MAC) == 0) == (len(r.IP) == 0) {
+		return errors.New("either MAC or IP address should be specified")
+	}
+	if len(r.MAC) > 0 {
+		mac, err := net.ParseMAC(r.MAC)
+		if err != nil || len(mac) != 6 {
+			return fmt.Errorf("invalid MAC address: %v", r.MAC)
+		}
+		// Normalize the address, e.g., 00-00-5E-00-53-01 to 00:00:5e:00:53:01.
+		r.MAC = mac.String()
+	}
+	if len(r.IP) > 0 {
+		ip := net.ParseIP(r.IP)
+		if ip == nil || ip.To4() == nil {
+			return fmt.Errorf("invalid IPv4 address: %v", r.IP)
+		}
+		r.IP = ip.String()
+	}
+
+	return nil
+}
+
+type Blacklist struct {
+	ID uint64 `json:"id"`
+	BlacklistParam
+}
+
+func (r *Controller) listBlacklist(w rest.ResponseWriter, req *rest.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	blacklist, err := r.db.Blacklist()
+	if err != nil {
+		logger.Errorf("failed to query database: %v", err)
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.WriteJson(&struct {
+		Blacklist []Blacklist `json:"blacklist"`
+	}{blacklist})
+}
+
+func (r *Controller) addBlacklist(w rest.ResponseWriter, req *rest.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	param := BlacklistParam{}
+	if err := req.DecodeJsonPayload(&param); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := param.validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	id, duplicated, err := r.db.AddBlacklist(param)
+	if err != nil {
+		logger.Errorf("failed to query database: %v", err)
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if duplicated {
+		writeError(w, http.StatusConflict, errors.New("already blacklisted address"))
+		return
+	}
+	logger.Infof("blacklisted a new address (ID=%v, %+v)", id, param)
+
+	w.WriteJson(&struct {
+		ID uint64 `json:"blacklist_id"`
+	}{id})
+}
+
+func (r *Controller) removeBlacklist(w rest.ResponseWriter, req *rest.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	id, err := strconv.ParseUint(req.PathParam("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	ok, err := r.db.RemoveBlacklist(id)
+	if err != nil {
+		logger.Errorf("failed to query database: %v", err)
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if !ok {
+		writeError(w, http.StatusNotFound, errors.New("unknown blacklist ID"))
+		return
+	}
+	logger.Infof("removed the blacklisted address (ID=%v)", id)
+
+	w.WriteJson(&struct{}{})
+}
@@ -0,0 +1,340 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/superkkt/cherry/clock"
+	"github.com/superkkt/cherry/pcap"
+
+	"github.com/ant0ine/go-json-rest/rest"
+)
+
+const (
+	// Number of the latest packets kept in memory if the ring size is not specified.
+	defaultCaptureRingSize = 10000
+	maxCaptureRingSize     = 1000000
+)
+
+var (
+	ErrCaptureRunning    = errors.New("capture is already running")
+	ErrCaptureFileExists = errors.New("capture file already exists")
+)
+
+// CaptureParam starts recording the OpenFlow messages exchanged with the devices.
+type CaptureParam struct {
+	// DPID of the device to capture. All the devices, including the ones in the handshake,
+	// are captured if it is empty.
+	DPID string `json:"dpid"`
+	// Name of the pcap file created in capture.dir, which should not exist. The latest
+	// messages are kept in memory instead if it is empty.
+	File string `json:"file"`
+	// Number of the packets kept in memory. Default is 10000.
+	RingSize int `json:"ring_size"`
+}
+
+func (r *CaptureParam) validate() error {
+	if len(r.DPID) > 0 {
+		if _, err := strconv.ParseUint(r.DPID, 10, 64); err != nil {
+			return fmt.Errorf("invalid DPID: %v", r.DPID)
+		}
+	}
+	if len(r.File) > 0 {
+		if r.File != filepath.Base(r.File) || r.File == "." || r.File == ".." || strings.ContainsAny(r.File, `/\`) {
+			return fmt.Errorf("invalid file name: %v", r.File)
+		}
+		return nil
+	}
+	if r.RingSize == 0 {
+		r.RingSize = defaultCaptureRingSize
+	}
+	if r.RingSize < 0 || r.RingSize > maxCaptureRingSize {
+		return fmt.Errorf("invalid ring size: %v", r.RingSize)
+	}
+
+	return nil
+}
+
+type CaptureStatus struct {
+	Running bool   `json:"running"`
+	DPID    string `json:"dpid"`
+	// Path of the pcap file, or empty if the packets are kept in memory.
+	File     string `json:"file"`
+	RingSize int    `json:"ring_size"`
+	// Number of the captured packets.
+	Packets uint64     `json:"packets"`
+	Started *time.Time `json:"started"`
+	Stopped *time.Time `json:"stopped"`
+}
+
+// captureManager records the OpenFlow messages exchanged with the devices in the pcap format,
+// either to a file or to a ring buffer in memory. Each message is recorded as an IPv4 packet
+// of the TCP connection of its session, so that Wireshark can dissect it as OpenFlow.
+type captureManager struct {
+	clock clock.Clock
+	// Directory where the pcap files are created.
+	dir string
+	// 1 if the capture is running. Accessed atomically so that the sessions can skip the
+	// capture without locking the mutex.
+	running int32
+
+	mutex  sync.Mutex
+	status CaptureStatus
+	file   *os.File
+	writer *pcap.Writer
+	// Ring buffer of the last capture in memory, which is kept after the capture stops.
+	ring *pcap.Ring
+}
+
+func newCaptureManager(dir string, clk clock.Clock) *captureManager {
+	if clk == nil {
+		panic("clock is nil")
+	}
+
+	return &captureManager{
+		clock: clk,
+		dir:   dir,
+	}
+}
+
+// Start starts a new capture. It returns ErrCaptureRunning if a capture is already running, and
+// ErrCaptureFileExists if the file to create already exists.
+func (r *captureManager) Start(param CaptureParam) (CaptureStatus, error) {
+	if err := param.validate(); err != nil {
+		return CaptureStatus{}, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.status.Running {
+		return CaptureStatus{}, ErrCaptureRunning
+	}
+
+	now := r.clock.Now()
+	status := CaptureStatus{Running: true, DPID: param.DPID, Started: &now}
+	if len(param.File) > 0 {
+		if len(r.dir) == 0 {
+			return CaptureStatus{}, errors.New("capture.dir is not configured")
+		}
+		// A missing directory is created as a private one.
+		if err := os.MkdirAll(r.dir, 0700); err != nil {
+			return CaptureStatus{}, err
+		}
+		status.File = filepath.Join(r.dir, param.File)
+		// O_EXCL never follows a symbolic link planted at the path, nor truncates an existing file.
+		f, err := os.OpenFile(status.File, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err != nil {
+			if os.IsExist(err) {
+				return CaptureStatus{}, ErrCaptureFileExists
+			}
+			return CaptureStatus{}, err
+		}
+		r.file = f
+		r.writer = pcap.NewWriter(f, pcap.LinkTypeRaw)
+		r.ring = nil
+	} else {
+		status.RingSize = param.RingSize
+		r.ring = pcap.NewRing(param.RingSize, pcap.LinkTypeRaw)
+	}
+	r.status = status
+	atomic.StoreInt32(&r.running, 1)
+
+	return r.status, nil
+}
+
+// Stop stops the running capture, if any, and returns its status.
+func (r *captureManager) Stop() CaptureStatus {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.stop()
+
+	return r.status
+}
+
+// XXX: Caller should lock the mutex
+func (r *captureManager) stop() {
+	if !r.status.Running {
+		return
+	}
+	atomic.StoreInt32(&r.running, 0)
+	if r.file != nil {
+		if err := r.file.Close(); err != nil {
+			logger.Errorf("failed to close the capture file: %v", err)
+		}
+		r.file = nil
+		r.writer = nil
+	}
+	now := r.clock.Now()
+	r.status.Running = false
+	r.status.Stopped = &now
+}
+
+func (r *captureManager) Status() CaptureStatus {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.status
+}
+
+// Ring returns the ring buffer of the last capture in memory, or nil if there is no such capture.
+func (r *captureManager) Ring() *pcap.Ring {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.ring
+}
+
+func (r *captureManager) record(s *sessionCapture, sent bool, packet []byte) {
+	if atomic.LoadInt32(&r.running) == 0 {
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if !r.status.Running {
+		return
+	}
+	if len(r.status.DPID) > 0 && r.status.DPID != s.DPID() {
+		return
+	}
+	packets, err := s.stream.Packets(sent, packet)
+	if err != nil {
+		logger.Errorf("failed to encapsulate the captured message: %v", err)
+		return
+	}
+
+	now := r.clock.Now()
+	for _, v := range packets {
+		if r.writer == nil {
+			r.ring.Add(now, v)
+		} else if err := r.writer.WritePacket(now, v); err != nil {
+			logger.Errorf("stopping the capture: failed to write the capture file: %v", err)
+			r.stop()
+			return
+		}
+		r.status.Packets++
+	}
+}
+
+// sessionCapture records the messages of a session by the capture manager.
+type sessionCapture struct {
+	manager *captureManager
+	// DPID of the device, which is a string, or nil if it is unknown yet.
+	dpid atomic.Value
+	// Guarded by the mutex of the manager.
+	stream *pcap.TCPStream
+}
+
+func newSessionCapture(manager *captureManager, local, remote net.Addr) *sessionCapture {
+	return &sessionCapture{
+		manager: manager,
+		stream:  pcap.NewTCPStream(local, remote),
+	}
+}
+
+// setDPID sets the DPID reported by the device. The device mutex is not used because a
+// capture by the writer goroutine should not wait for the device.
+func (r *sessionCapture) setDPID(dpid string) {
+	r.dpid.Store(dpid)
+}
+
+func (r *sessionCapture) DPID() string {
+	v, _ := r.dpid.Load().(string)
+	return v
+}
+
+func (r *sessionCapture) Capture(sent bool, packet []byte) {
+	r.manager.record(r, sent, packet)
+}
+
+func (r *Controller) getCapture(w rest.ResponseWriter, req *rest.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.WriteJson(r.capture.Status())
+}
+
+func (r *Controller) startCapture(w rest.ResponseWriter, req *rest.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	param := CaptureParam{}
+	if err := req.DecodeJsonPayload(&param); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := param.validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	status, err := r.capture.Start(param)
+	if err != nil {
+		if err == ErrCaptureRunning || err == ErrCaptureFileExists {
+			writeError(w, http.StatusConflict, err)
+		} else {
+			logger.Errorf("failed to start the capture: %v", err)
+			writeError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+	logger.Infof("started capturing the OpenFlow messages: %+v", param)
+
+	w.WriteJson(status)
+}
+
+func (r *Controller) stopCapture(w rest.ResponseWriter, req *rest.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	status := r.capture.Stop()
+	logger.Infof("stopped capturing the OpenFlow messages: packets=%v", status.Packets)
+
+	w.WriteJson(status)
+}
+
+// downloadCapture writes the packets of the last capture in memory as a pcap file.
+func (r *Controller) downloadCapture(w rest.ResponseWriter, req *rest.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	ring := r.capture.Ring()
+	if ring == nil {
+		writeError(w, http.StatusNotFound, errors.New("no capture in memory"))
+		return
+	}
+
+	writer := w.(http.ResponseWriter)
+	writer.Header().Set("Content-Type", "application/vnd.tcpdump.pcap")
+	writer.Header().Set("Content-Disposition", `attachment; filename="cherry.pcap"`)
+	if err := ring.Dump(writer); err != nil {
+		logger.Errorf("failed to write the captured packets: %v", err)
+	}
+}
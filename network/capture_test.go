@@ -0,0 +1,108 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/superkkt/cherry/testutil"
+)
+
+func TestCaptureParam(t *testing.T) {
+	for _, v := range []CaptureParam{{DPID: "x"}, {File: "../cherry.pcap"}, {File: ".."}, {RingSize: -1}, {RingSize: maxCaptureRingSize + 1}} {
+		if err := v.validate(); err == nil {
+			t.Fatalf("expected an error for %+v", v)
+		}
+	}
+	v := CaptureParam{DPID: "1"}
+	if err := v.validate(); err != nil {
+		t.Fatal(err)
+	}
+	if v.RingSize != defaultCaptureRingSize {
+		t.Fatalf("unexpected default ring size: %v", v.RingSize)
+	}
+}
+
+func TestCaptureManager(t *testing.T) {
+	clock := testutil.NewFakeClock(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))
+	dir, err := ioutil.TempDir("", "capture")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	manager := newCaptureManager(dir, clock)
+	local := &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 6633}
+	s1 := newSessionCapture(manager, local, &net.TCPAddr{IP: net.IPv4(10, 0, 0, 2), Port: 40000})
+	s1.setDPID("1")
+	s2 := newSessionCapture(manager, local, &net.TCPAddr{IP: net.IPv4(10, 0, 0, 3), Port: 40000})
+	s2.setDPID("2")
+	hello := []byte{0x04, 0x00, 0x00, 0x08, 0x00, 0x00, 0x00, 0x01}
+
+	// Not running.
+	s1.Capture(true, hello)
+	if _, err := manager.Start(CaptureParam{DPID: "1", RingSize: 10}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := manager.Start(CaptureParam{}); err != ErrCaptureRunning {
+		t.Fatalf("expected ErrCaptureRunning, got=%v", err)
+	}
+	s1.Capture(true, hello)
+	s1.Capture(false, hello)
+	// Another device.
+	s2.Capture(false, hello)
+	status := manager.Stop()
+	if status.Running || status.Packets != 2 || manager.Ring().Len() != 2 {
+		t.Fatalf("unexpected status: %+v", status)
+	}
+
+	// All the devices to a file.
+	status, err = manager.Start(CaptureParam{File: "test.pcap"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.File != filepath.Join(dir, "test.pcap") || manager.Ring() != nil {
+		t.Fatalf("unexpected status: %+v", status)
+	}
+	s1.Capture(true, hello)
+	s2.Capture(true, hello)
+	if status := manager.Stop(); status.Packets != 2 {
+		t.Fatalf("unexpected status: %+v", status)
+	}
+	// An existing file is not overwritten.
+	if _, err := manager.Start(CaptureParam{File: "test.pcap"}); err != ErrCaptureFileExists {
+		t.Fatalf("expected ErrCaptureFileExists, got=%v", err)
+	}
+	info, err := os.Stat(status.File)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// File header, and two IPv4 packets with their record headers.
+	if expected := int64(24 + 2*(16+20+20+len(hello))); info.Size() != expected {
+		t.Fatalf("unexpected file size: expected=%v, got=%v", expected, info.Size())
+	}
+}
@@ -0,0 +1,122 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/superkkt/cherry/metrics"
+
+	"github.com/ant0ine/go-json-rest/rest"
+)
+
+var (
+	certMismatches = metrics.NewCounterVec("cherry_certificate_mismatches_total", "Number of connections rejected because the client certificate is not the one bound to the DPID.", "dpid")
+)
+
+type certDatabase interface {
+	BindSwitchCertificate(dpid uint64, fingerprint, subject string) (bound string, err error)
+	UnbindSwitchCertificate(dpid uint64) (ok bool, err error)
+}
+
+// certBinder binds the client certificate of a switch to its DPID when the switch connects
+// for the first time, and rejects the later connections that present the same DPID with a
+// different certificate, so that a switch holding a valid certificate cannot impersonate
+// another switch. A renewed certificate is rejected until its DPID is unbound.
+type certBinder struct {
+	db certDatabase
+}
+
+func newCertBinder(db certDatabase) *certBinder {
+	if db == nil {
+		panic("database is nil")
+	}
+
+	return &certBinder{db: db}
+}
+
+// fingerprint returns the SHA-256 fingerprint in hex and the subject of the client
+// certificate of conn. ok is false if the client has not presented a certificate.
+func fingerprint(conn *tls.Conn) (v, subject string, ok bool) {
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return "", "", false
+	}
+	sum := sha256.Sum256(certs[0].Raw)
+
+	return hex.EncodeToString(sum[:]), certs[0].Subject.String(), true
+}
+
+// check binds the client certificate of conn to dpid, or verifies that the certificate is the
+// one bound to dpid. A switch that has not presented a certificate is rejected.
+func (r *certBinder) check(dpid uint64, conn *tls.Conn) error {
+	v, subject, ok := fingerprint(conn)
+	if !ok {
+		certMismatches.Inc(strconv.FormatUint(dpid, 10))
+		logger.Errorf("rejecting the switch that presents no client certificate: DPID=%v, address=%v", dpid, conn.RemoteAddr())
+		return fmt.Errorf("no client certificate for DPID %v", dpid)
+	}
+
+	bound, err := r.db.BindSwitchCertificate(dpid, v, subject)
+	if err != nil {
+		return fmt.Errorf("failed to bind the client certificate: %v", err)
+	}
+	if bound != v {
+		certMismatches.Inc(strconv.FormatUint(dpid, 10))
+		logger.Errorf("rejecting the switch that presents a certificate different from the bound one: DPID=%v, address=%v, subject=%v, fingerprint=%v, bound=%v", dpid, conn.RemoteAddr(), subject, v, bound)
+		return fmt.Errorf("client certificate is not bound to DPID %v", dpid)
+	}
+
+	return nil
+}
+
+// unbindCertificate removes the certificate bound to a switch, e.g., after its certificate is
+// renewed, so that the next connection of the switch binds its new certificate.
+func (r *Controller) unbindCertificate(w rest.ResponseWriter, req *rest.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	dpid, err := strconv.ParseUint(req.PathParam("dpid"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errors.New("invalid DPID"))
+		return
+	}
+
+	ok, err := r.db.UnbindSwitchCertificate(dpid)
+	if err != nil {
+		logger.Errorf("failed to query database: %v", err)
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if !ok {
+		writeError(w, http.StatusNotFound, errors.New("no certificate bound to the DPID"))
+		return
+	}
+	logger.Infof("unbound the client certificate of the switch: DPID=%v", dpid)
+
+	w.WriteJson(&struct{}{})
+}
@@ -0,0 +1,144 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+)
+
+// certDB keeps the bound fingerprints in memory.
+type certDB struct {
+	database
+	bound map[uint64]string
+}
+
+func (r *certDB) BindSwitchCertificate(dpid uint64, fingerprint, subject string) (bound string, err error) {
+	if v, ok := r.bound[dpid]; ok {
+		return v, nil
+	}
+	r.bound[dpid] = fingerprint
+
+	return fingerprint, nil
+}
+
+func (r *certDB) UnbindSwitchCertificate(dpid uint64) (ok bool, err error) {
+	_, ok = r.bound[dpid]
+	delete(r.bound, dpid)
+
+	return ok, nil
+}
+
+func newTestCertificate(t *testing.T, name string) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// newTLSConn returns the server side of a TLS connection whose client presents cert, if any.
+func newTLSConn(t *testing.T, server tls.Certificate, cert *tls.Certificate) *tls.Conn {
+	s, c := net.Pipe()
+	conn := tls.Server(s, &tls.Config{Certificates: []tls.Certificate{server}, ClientAuth: tls.RequestClientCert})
+	config := &tls.Config{InsecureSkipVerify: true}
+	if cert != nil {
+		config.Certificates = []tls.Certificate{*cert}
+	}
+	client := tls.Client(c, config)
+
+	done := make(chan error, 1)
+	go func() { done <- client.Handshake() }()
+	if err := conn.Handshake(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	// Close the pipe instead of the TLS connections that block sending close_notify.
+	t.Cleanup(func() {
+		s.Close()
+		c.Close()
+	})
+
+	return conn
+}
+
+func TestCertBinder(t *testing.T) {
+	db := &certDB{bound: make(map[uint64]string)}
+	binder := newCertBinder(db)
+	server := newTestCertificate(t, "controller")
+	switch1 := newTestCertificate(t, "switch1")
+	switch2 := newTestCertificate(t, "switch2")
+
+	// First connection binds the certificate.
+	if err := binder.check(1, newTLSConn(t, server, &switch1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := binder.check(1, newTLSConn(t, server, &switch1)); err != nil {
+		t.Fatalf("same certificate should be accepted: %v", err)
+	}
+	if err := binder.check(1, newTLSConn(t, server, &switch2)); err == nil {
+		t.Fatal("different certificate should be rejected")
+	}
+	if err := binder.check(2, newTLSConn(t, server, &switch2)); err != nil {
+		t.Fatalf("certificate of another DPID should be bound: %v", err)
+	}
+	// No client certificate.
+	if err := binder.check(1, newTLSConn(t, server, nil)); err == nil {
+		t.Fatal("connection without a certificate should be rejected")
+	}
+	if err := binder.check(3, newTLSConn(t, server, nil)); err == nil {
+		t.Fatal("connection without a certificate should be rejected before binding")
+	}
+	if _, ok := db.bound[3]; ok {
+		t.Fatal("DPID is bound without a certificate")
+	}
+
+	// Unbound DPID binds the new certificate.
+	if ok, _ := db.UnbindSwitchCertificate(1); !ok {
+		t.Fatal("failed to unbind")
+	}
+	if err := binder.check(1, newTLSConn(t, server, &switch2)); err != nil {
+		t.Fatalf("renewed certificate should be bound: %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/superkkt/cherry/clock"
+)
+
+const (
+	connRateWindow = 1 * time.Minute
+)
+
+type connWindow struct {
+	start time.Time
+	count int
+}
+
+// connLimiter limits the number of new connections from each source IP address per
+// minute, so that a misbehaving host cannot exhaust the controller by repeatedly
+// opening connections.
+type connLimiter struct {
+	// Zero max means unlimited.
+	max   int
+	clock clock.Clock
+
+	mutex sync.Mutex
+	// Key is the source IP address.
+	windows   map[string]*connWindow
+	lastPurge time.Time
+}
+
+// newConnLimiter returns a new limiter that allows max connections per minute from
+// each source IP address. Zero max disables the limiting.
+func newConnLimiter(max int, clk clock.Clock) *connLimiter {
+	if max < 0 {
+		panic("max should be equal to or greater than zero")
+	}
+	if clk == nil {
+		panic("clock is nil")
+	}
+
+	return &connLimiter{
+		max:       max,
+		clock:     clk,
+		windows:   make(map[string]*connWindow),
+		lastPurge: clk.Now(),
+	}
+}
+
+// allow returns whether a new connection from addr is allowed.
+func (r *connLimiter) allow(addr net.Addr) bool {
+	if r.max == 0 {
+		return true
+	}
+
+	ip := addressIP(addr)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := r.clock.Now()
+	r.purge(now)
+
+	w, ok := r.windows[ip]
+	if !ok || now.Sub(w.start) >= connRateWindow {
+		w = &connWindow{start: now}
+		r.windows[ip] = w
+	}
+	w.count++
+
+	return w.count <= r.max
+}
+
+// XXX: Caller should lock the mutex
+func (r *connLimiter) purge(now time.Time) {
+	if now.Sub(r.lastPurge) < connRateWindow {
+		return
+	}
+	r.lastPurge = now
+
+	for ip, w := range r.windows {
+		if now.Sub(w.start) >= connRateWindow {
+			delete(r.windows, ip)
+		}
+	}
+}
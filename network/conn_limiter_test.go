@@ -0,0 +1,65 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/superkkt/cherry/testutil"
+)
+
+func TestConnLimiter(t *testing.T) {
+	clock := testutil.NewFakeClock(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))
+	limiter := newConnLimiter(2, clock)
+	host1 := &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1000}
+	host1OtherPort := &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 2000}
+	host2 := &net.TCPAddr{IP: net.IPv4(10, 0, 0, 2), Port: 1000}
+
+	if !limiter.allow(host1) || !limiter.allow(host1OtherPort) {
+		t.Fatal("connections within the limit should be allowed")
+	}
+	if limiter.allow(host1) {
+		t.Fatal("connection exceeding the limit should not be allowed")
+	}
+	if !limiter.allow(host2) {
+		t.Fatal("connection from another host should be allowed")
+	}
+
+	clock.Advance(connRateWindow)
+	if !limiter.allow(host1) {
+		t.Fatal("connection should be allowed in the next window")
+	}
+}
+
+func TestConnLimiterUnlimited(t *testing.T) {
+	clock := testutil.NewFakeClock(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))
+	limiter := newConnLimiter(0, clock)
+	host := &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1000}
+
+	for i := 0; i < 1000; i++ {
+		if !limiter.allow(host) {
+			t.Fatal("unlimited limiter should allow all connections")
+		}
+	}
+}
@@ -23,14 +23,28 @@ package network
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"math"
 	"net"
 	"net/http"
+	"path/filepath"
+	"sort"
 	"strconv"
-
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/superkkt/cherry/audit"
+	"github.com/superkkt/cherry/clock"
+	"github.com/superkkt/cherry/election"
+	"github.com/superkkt/cherry/metrics"
 	"github.com/superkkt/cherry/openflow"
+	"github.com/superkkt/cherry/ovsdb"
 	"github.com/superkkt/cherry/protocol"
+	"github.com/superkkt/cherry/ratelog"
 
 	"github.com/ant0ine/go-json-rest/rest"
 	"github.com/superkkt/go-logging"
@@ -39,6 +53,10 @@ import (
 
 var (
 	logger = logging.MustGetLogger("network")
+	// Logger for the messages that may be repeated on every packet.
+	rateLogger = ratelog.New("network", 10*time.Second)
+
+	connsRejected = metrics.NewCounterVec("cherry_connections_rejected_total", "Number of connections closed by the connection rate limit.")
 )
 
 type database interface {
@@ -46,8 +64,16 @@ type database interface {
 	AddNetwork(net.IP, net.IPMask) (netID uint64, err error)
 	AddSwitch(SwitchParam) (swID uint64, err error)
 	AddVIP(VIPParam) (id uint64, cidr string, err error)
+	AddFirewallRule(FirewallRuleParam) (id uint64, err error)
+	AddIntent(IntentParam) (id uint64, err error)
+	// AddBlacklist adds the address of the parameter to the blacklist. duplicated will be
+	// true if the address has been already blacklisted.
+	AddBlacklist(BlacklistParam) (id uint64, duplicated bool, err error)
+	Blacklist() ([]Blacklist, error)
+	FirewallRules() ([]FirewallRule, error)
 	Host(hostID uint64) (host Host, ok bool, err error)
 	Hosts() ([]Host, error)
+	Intents() ([]Intent, error)
 	IPAddrs(networkID uint64) ([]IP, error)
 	Location(mac net.HardwareAddr) (dpid string, port uint32, status LocationStatus, err error)
 	Network(net.IP) (n Network, ok bool, err error)
@@ -56,15 +82,49 @@ type database interface {
 	RemoveNetwork(id uint64) (ok bool, err error)
 	RemoveSwitch(id uint64) (ok bool, err error)
 	RemoveVIP(id uint64) (ok bool, err error)
+	RemoveFirewallRule(id uint64) (ok bool, err error)
+	RemoveIntent(id uint64) (ok bool, err error)
+	RemoveBlacklist(id uint64) (ok bool, err error)
+	// BindSwitchCertificate binds the client certificate whose SHA-256 fingerprint is
+	// fingerprint to the switch whose DPID is dpid if the switch has no bound certificate
+	// yet. It returns the fingerprint of the certificate bound to the switch.
+	BindSwitchCertificate(dpid uint64, fingerprint, subject string) (bound string, err error)
+	// UnbindSwitchCertificate removes the certificate bound to the switch whose DPID is dpid.
+	// ok will be false if the switch has no bound certificate.
+	UnbindSwitchCertificate(dpid uint64) (ok bool, err error)
+	// FlowIntents returns all the saved flow intents.
+	FlowIntents() ([]FlowIntentRecord, error)
+	// SaveFlowIntent saves the flow intent, replacing the one that has the same DPID and key.
+	SaveFlowIntent(FlowIntentRecord) error
+	// RemoveFlowIntent removes the flow intent whose DPID and key are dpid and key.
+	RemoveFlowIntent(dpid uint64, key string) error
+	// RemoveFlowIntents removes all the flow intents of the switch whose DPID is dpid.
+	RemoveFlowIntents(dpid uint64) error
+	// Links returns all the links saved by SaveLink.
+	Links() ([]LinkRecord, error)
+	// SaveLink saves the link, replacing the one that has the same ports.
+	SaveLink(LinkRecord) error
+	// RemoveLink removes the link that has the same ports as the parameter.
+	RemoveLink(LinkRecord) error
+	// SetSwitchDrained persists the drained state of the switch whose DPID is dpid.
+	// ok will be false if the switch is not registered.
+	SetSwitchDrained(dpid uint64, drained bool) (ok bool, err error)
 	Switch(dpid uint64) (sw Switch, ok bool, err error)
 	Switches() ([]Switch, error)
 	SwitchPorts(switchID uint64) ([]SwitchPort, error)
 	ToggleVIP(id uint64) (net.IP, net.HardwareAddr, error)
+	// UpdateHostLocation updates the physical location of a host, whose MAC and IP
+	// addresses are matched with mac and ip, to the port identified by swDPID and
+	// portNum. updated will be true if the location has been changed.
+	UpdateHostLocation(mac net.HardwareAddr, ip net.IP, swDPID uint64, portNum uint16) (updated bool, err error)
 	VIPs() ([]VIP, error)
 }
 
 type observer interface {
 	IsMaster() bool
+	// Subscribe registers f that will be called whenever the mastership of this controller
+	// or the generation of the master changes.
+	Subscribe(f election.Listener)
 }
 
 type LocationStatus int
@@ -87,13 +147,24 @@ type ControllerEventListener interface {
 	OnPacketIn(Finder, *Port, *protocol.Ethernet) error
 	OnPortUp(Finder, *Port) error
 	OnPortDown(Finder, *Port) error
+	// OnPortsRenumbered is called when the port numbers of a reconnected device have been
+	// changed since its last connection. mapping contains the old-to-new port numbers,
+	// and deleted contains the old port numbers that have disappeared or are ambiguous.
+	OnPortsRenumbered(finder Finder, device *Device, mapping map[uint32]uint32, deleted []uint32) error
 	OnDeviceUp(Finder, *Device) error
 	OnDeviceDown(Finder, *Device) error
 	OnFlowRemoved(Finder, openflow.FlowRemoved) error
+	// OnPortStatsUpdated is called whenever the port statistics of device are refreshed.
+	OnPortStatsUpdated(finder Finder, device *Device) error
 }
 
 type TopologyEventListener interface {
 	OnTopologyChange(Finder) error
+	// OnLinkUp is called when a new link between two switches is discovered by LLDP.
+	OnLinkUp(finder Finder, ports [2]*Port) error
+	// OnLinkDown is called when a link between two switches has disappeared because
+	// its port or device went down, or LLDP has not been received for a long time.
+	OnLinkDown(finder Finder, ports [2]*Port) error
 }
 
 type Controller struct {
@@ -101,21 +172,162 @@ type Controller struct {
 	listener EventListener
 	db       database
 	observer observer
+	pacer    *handshakePacer
+	limiter  *connLimiter
+	packetIn *packetInPolicy
+	// Workers that deliver the PACKET_INs to the applications. nil means each session delivers them.
+	packetInWorkers *packetInWorkers
+	clock           clock.Clock
+	// Interval of the port statistics polling. 0 disables the polling.
+	portStatsInterval time.Duration
+	// Maximum time from the start of a handshake to the first FEATURES_REPLY.
+	handshakeTimeout time.Duration
+	// Retry of the handshake messages that have failed transiently.
+	handshakeRetry handshakeRetry
+	// Consecutive negotiation failures of the switches.
+	negotiation *negotiationTracker
+	// Admission control of the switches by their DPIDs.
+	admission *admissionControl
+	// Binding of the client certificates to the DPIDs. nil disables it.
+	certBinder *certBinder
+	// Reconcile the flows of the devices with their shadow copies.
+	reconcileFlows bool
+	// Remove the saved flows owned by the applications that are not loaded.
+	removeStaleFlows bool
+	// Running sessions.
+	sessions sync.WaitGroup
+	// Our role among the controllers that share the switches.
+	mastership *mastership
+	// Messages sent over the auxiliary connections of the devices.
+	auxPolicy auxPolicy
+	// Maximum number of FLOW_MODs and PACKET_OUTs per second sent to each device. 0 means unlimited.
+	flowModRate, packetOutRate int
+	// What to do with the flows that conflict with the flows of other applications.
+	flowConflict conflictPolicy
+	// Flow priority bands reserved for the applications.
+	priorities *priorityAllocator
+	// Virtual partitions of the network that confine the tenant flows.
+	slices []*Slice
+	// Slices keyed by the cookie owner IDs of their applications.
+	sliceIndex *sliceIndex
+	// Traffic among the hosts collected by sFlow.
+	traffic *trafficMatrix
+	// Recorder of the OpenFlow messages exchanged with the devices.
+	capture *captureManager
+	// Log of the state-changing operations. nil disables it.
+	auditLog *audit.Log
+	// Authentication of the REST API clients. nil disables it.
+	auth *apiAuth
+	// Unix domain sockets of the OVSDB servers that can be bootstrapped by the REST API.
+	ovsdbSockets []string
+	// Last self-test results of the devices.
+	selfTests *selfTestResults
 }
 
 func NewController(db database, observer observer) *Controller {
+	slices, err := LoadSlices()
+	if err != nil {
+		// The slices should be already checked in the main code.
+		panic(fmt.Sprintf("invalid slice in the config file: %v", err))
+	}
+	admission, err := LoadAdmissionConfig()
+	if err != nil {
+		// The admission control should be already checked in the main code.
+		panic(fmt.Sprintf("invalid admission control in the config file: %v", err))
+	}
+	auditLog, err := openAuditLog()
+	if err != nil {
+		// The audit log should be already checked in the main code.
+		panic(fmt.Sprintf("failed to open the audit log: %v", err))
+	}
+	users, err := LoadAPIUsers()
+	if err != nil {
+		// The users should be already checked in the main code.
+		panic(fmt.Sprintf("invalid REST API user in the config file: %v", err))
+	}
+	bands, err := LoadPriorityBands()
+	if err != nil {
+		// The priority bands should be already checked in the main code.
+		panic(fmt.Sprintf("invalid priority band in the config file: %v", err))
+	}
+
+	topo := newTopology(db, clock.Real)
+	retry := newHandshakeRetry(
+		viper.GetInt("default.handshake_retry.attempts"),
+		time.Duration(viper.GetInt("default.handshake_retry.backoff"))*time.Millisecond,
+		time.Duration(viper.GetInt("default.handshake_retry.max_backoff"))*time.Millisecond,
+		clock.Real,
+	)
 	v := &Controller{
-		topo:     newTopology(db),
-		db:       db,
-		observer: observer,
+		topo:              topo,
+		db:                db,
+		observer:          observer,
+		pacer:             newHandshakePacer(viper.GetInt("default.max_handshakes"), clock.Real),
+		limiter:           newConnLimiter(viper.GetInt("default.conn_rate_limit"), clock.Real),
+		packetIn:          newPacketInPolicy(),
+		packetInWorkers:   newPacketInWorkers(viper.GetInt("default.packet_in.workers"), viper.GetInt("default.packet_in.worker_queue_size")),
+		clock:             clock.Real,
+		portStatsInterval: time.Duration(viper.GetInt("default.port_stats_interval")) * time.Second,
+		handshakeTimeout:  time.Duration(viper.GetInt("default.handshake_timeout")) * time.Second,
+		handshakeRetry:    retry,
+		negotiation:       newNegotiationTracker(viper.GetInt("default.handshake_retry.failure_threshold"), topo.events, clock.Real),
+		admission:         newAdmissionControl(admission, clock.Real),
+		reconcileFlows:    viper.GetBool("default.flow_reconciliation"),
+		removeStaleFlows:  viper.GetBool("default.flow_intent.remove_stale"),
+		mastership:        new(mastership),
+		auxPolicy:         newAuxPolicy(),
+		flowModRate:       viper.GetInt("default.flow_mod_rate_limit"),
+		packetOutRate:     viper.GetInt("default.packet_out_rate_limit"),
+		flowConflict:      newConflictPolicy(),
+		priorities:        newPriorityAllocator(bands),
+		slices:            slices,
+		sliceIndex:        newSliceIndex(slices),
+		traffic:           newTrafficMatrix(clock.Real),
+		capture:           newCaptureManager(viper.GetString("capture.dir"), clock.Real),
+		auditLog:          auditLog,
+		ovsdbSockets:      splitList(viper.GetString("ovsdb.unix_sockets")),
+		selfTests:         newSelfTestResults(),
+	}
+	if viper.GetBool("default.tls.enable") && viper.GetBool("default.tls.bind_dpid") {
+		v.certBinder = newCertBinder(db)
+	}
+	if users != nil {
+		v.auth = newAPIAuth(users)
+	}
+	if v.reconcileFlows && viper.GetBool("default.flow_intent.persist") {
+		store := newFlowIntentStore(db)
+		if err := topo.setFlowIntentStore(store); err != nil {
+			// The devices are reconciled with the intents declared by the applications only.
+			logger.Errorf("failed to load the saved flow intents: %v", err)
+		}
+		go store.run()
+	}
+	if viper.GetBool("default.persist_links") {
+		if store, err := newLinkStore(db); err != nil {
+			// The links are discovered again by LLDP.
+			logger.Errorf("failed to load the saved links: %v", err)
+		} else {
+			topo.setLinkStore(store)
+		}
 	}
+	observer.Subscribe(v.setMastership)
 	go v.serveREST()
+	if viper.GetInt("sflow.port") > 0 {
+		go v.serveSFlow()
+	}
+	if viper.GetInt("grpc.port") > 0 {
+		go v.serveGRPC()
+	}
 
 	return v
 }
 
 func (r *Controller) serveREST() {
 	api := rest.NewApi()
+	if r.auth != nil {
+		// Authenticate the clients before anything else.
+		api.Use(rest.MiddlewareSimple(r.auth.middleware))
+	}
 	// Middleware to deny the client requests if we are not the master controller.
 	api.Use(rest.MiddlewareSimple(func(handler rest.HandlerFunc) rest.HandlerFunc {
 		return func(writer rest.ResponseWriter, request *rest.Request) {
@@ -127,11 +339,30 @@ func (r *Controller) serveREST() {
 			handler(writer, request)
 		}
 	}))
+	if r.auditLog != nil {
+		// The recorder should be inside the audit middleware that reads the recorded status code.
+		api.Use(rest.MiddlewareSimple(r.auditMiddleware), &rest.RecorderMiddleware{})
+	}
 	router, err := rest.MakeRouter(
 		rest.Get("/api/v1/switch", r.listSwitch),
 		rest.Post("/api/v1/switch", r.addSwitch),
 		rest.Delete("/api/v1/switch/:id", r.removeSwitch),
 		rest.Options("/api/v1/switch/:id", r.allowOrigin),
+		rest.Get("/api/v1/switch/:id/drain", r.drainStatus),
+		rest.Put("/api/v1/switch/:id/drain", r.drainSwitch),
+		rest.Options("/api/v1/switch/:id/drain", r.allowOrigin),
+		rest.Put("/api/v1/switch/:id/undrain", r.undrainSwitch),
+		rest.Options("/api/v1/switch/:id/undrain", r.allowOrigin),
+		rest.Get("/api/v1/admission", r.getAdmission),
+		rest.Put("/api/v1/admission/whitelist/:dpid", r.approveSwitch),
+		rest.Delete("/api/v1/admission/whitelist/:dpid", r.unwhitelistSwitch),
+		rest.Options("/api/v1/admission/whitelist/:dpid", r.allowOrigin),
+		rest.Put("/api/v1/admission/blacklist/:dpid", r.blockSwitch),
+		rest.Delete("/api/v1/admission/blacklist/:dpid", r.unblockSwitch),
+		rest.Options("/api/v1/admission/blacklist/:dpid", r.allowOrigin),
+		rest.Delete("/api/v1/certificate/:dpid", r.unbindCertificate),
+		rest.Options("/api/v1/certificate/:dpid", r.allowOrigin),
+		rest.Get("/api/v1/audit", r.queryAudit),
 		rest.Get("/api/v1/port/:switchID", r.listPort),
 		rest.Get("/api/v1/network", r.listNetwork),
 		rest.Post("/api/v1/network", r.addNetwork),
@@ -142,11 +373,56 @@ func (r *Controller) serveREST() {
 		rest.Post("/api/v1/host", r.addHost),
 		rest.Delete("/api/v1/host/:id", r.removeHost),
 		rest.Options("/api/v1/host/:id", r.allowOrigin),
+		rest.Get("/api/v1/flow_intents", r.exportFlowIntents),
+		rest.Post("/api/v1/flow_intents", r.importFlowIntents),
+		rest.Delete("/api/v1/flow_intents/stale", r.removeStaleFlowIntents),
+		rest.Options("/api/v1/flow_intents/stale", r.allowOrigin),
+		rest.Get("/api/v1/snapshot", r.exportSnapshot),
+		rest.Post("/api/v1/snapshot/diff", r.diffSnapshot),
+		rest.Post("/api/v1/import", r.importState),
 		rest.Get("/api/v1/vip", r.listVIP),
 		rest.Post("/api/v1/vip", r.addVIP),
 		rest.Delete("/api/v1/vip/:id", r.removeVIP),
 		rest.Options("/api/v1/vip/:id", r.allowOrigin),
 		rest.Put("/api/v1/vip/:id", r.toggleVIP),
+		rest.Get("/api/v1/firewall", r.listFirewallRules),
+		rest.Post("/api/v1/firewall", r.addFirewallRule),
+		rest.Delete("/api/v1/firewall/:id", r.removeFirewallRule),
+		rest.Options("/api/v1/firewall/:id", r.allowOrigin),
+		rest.Get("/api/v1/intent", r.listIntents),
+		rest.Post("/api/v1/intent", r.addIntent),
+		rest.Delete("/api/v1/intent/:id", r.removeIntent),
+		rest.Options("/api/v1/intent/:id", r.allowOrigin),
+		rest.Get("/api/v1/blacklist", r.listBlacklist),
+		rest.Post("/api/v1/blacklist", r.addBlacklist),
+		rest.Delete("/api/v1/blacklist/:id", r.removeBlacklist),
+		rest.Options("/api/v1/blacklist/:id", r.allowOrigin),
+		rest.Get("/api/v1/traffic", r.listTraffic),
+		rest.Delete("/api/v1/traffic", r.resetTraffic),
+		rest.Options("/api/v1/traffic", r.allowOrigin),
+		rest.Get("/api/v1/devices", r.listDevices),
+		rest.Get("/api/v1/devices/:dpid", r.getDevice),
+		rest.Put("/api/v1/devices/:dpid/miss_send_len", r.setDeviceMissSendLen),
+		rest.Options("/api/v1/devices/:dpid/miss_send_len", r.allowOrigin),
+		rest.Get("/api/v1/devices/:dpid/ports", r.listDevicePorts),
+		rest.Post("/api/v1/devices/:dpid/selftest", r.selfTestDevice),
+		rest.Get("/api/v1/devices/:dpid/flows", r.listDeviceFlows),
+		rest.Post("/api/v1/devices/:dpid/flows", r.addDeviceFlow),
+		rest.Delete("/api/v1/devices/:dpid/flows", r.removeDeviceFlows),
+		rest.Options("/api/v1/devices/:dpid/flows", r.allowOrigin),
+		rest.Delete("/api/v1/devices/:dpid/flows/owner/:name", r.removeOwnedFlows),
+		rest.Options("/api/v1/devices/:dpid/flows/owner/:name", r.allowOrigin),
+		rest.Get("/api/v1/devices/:dpid/queues", r.listDeviceQueues),
+		rest.Get("/api/v1/devices/:dpid/ports/:port/queues", r.getDeviceQueueConfig),
+		rest.Get("/api/v1/links", r.listLinks),
+		rest.Get("/api/v1/slices", r.listSlices),
+		rest.Get("/api/v1/events", r.streamEvents),
+		rest.Get("/api/v1/capture", r.getCapture),
+		rest.Put("/api/v1/capture", r.startCapture),
+		rest.Delete("/api/v1/capture", r.stopCapture),
+		rest.Options("/api/v1/capture", r.allowOrigin),
+		rest.Get("/api/v1/capture/pcap", r.downloadCapture),
+		rest.Post("/api/v1/ovsdb/bootstrap", r.bootstrapOVS),
 	)
 	if err != nil {
 		logger.Errorf("failed to make a REST router: %v", err)
@@ -154,7 +430,7 @@ func (r *Controller) serveREST() {
 	}
 	api.SetApp(router)
 
-	addr := fmt.Sprintf(":%v", viper.GetInt("rest.port"))
+	addr := net.JoinHostPort(viper.GetString("rest.listen_addr"), strconv.Itoa(viper.GetInt("rest.port")))
 	if viper.GetBool("rest.tls") {
 		err = http.ListenAndServeTLS(addr, viper.GetString("rest.cert_file"), viper.GetString("rest.key_file"), api.MakeHandler())
 	} else {
@@ -170,6 +446,7 @@ func (r *Controller) serveREST() {
 func (r *Controller) allowOrigin(w rest.ResponseWriter, req *rest.Request) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Access-Control-Allow-Methods", "DELETE, PUT")
+	w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
 }
 
 type SwitchParam struct {
@@ -194,6 +471,8 @@ func (r *SwitchParam) validate() error {
 type Switch struct {
 	ID uint64 `json:"id"`
 	SwitchParam
+	// Drained is true if the switch is being drained for maintenance.
+	Drained bool `json:"drained"`
 }
 
 func (r *Controller) listSwitch(w rest.ResponseWriter, req *rest.Request) {
@@ -283,6 +562,110 @@ func (r *Controller) removeSwitch(w rest.ResponseWriter, req *rest.Request) {
 	w.WriteJson(&struct{}{})
 }
 
+// drainTimeout is the maximum time that the drain API waits for the paths to be moved.
+const drainTimeout = 10 * time.Second
+
+// switchDPID returns the DPID of the registered switch whose ID is the id path parameter.
+func (r *Controller) switchDPID(w rest.ResponseWriter, req *rest.Request) (dpid string, ok bool) {
+	id, err := strconv.ParseUint(req.PathParam("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return "", false
+	}
+
+	switches, err := r.db.Switches()
+	if err != nil {
+		logger.Errorf("failed to query database: %v", err)
+		writeError(w, http.StatusInternalServerError, err)
+		return "", false
+	}
+	for _, sw := range switches {
+		if sw.ID == id {
+			return strconv.FormatUint(sw.DPID, 10), true
+		}
+	}
+	writeError(w, http.StatusNotFound, errors.New("unknown switch ID"))
+
+	return "", false
+}
+
+type DrainStatus struct {
+	Drained bool `json:"drained"`
+	// Safe is true if no path managed by the controller traverses the switch,
+	// which means the switch can be rebooted.
+	Safe bool `json:"safe"`
+}
+
+func (r *Controller) drainStatus(w rest.ResponseWriter, req *rest.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	dpid, ok := r.switchDPID(w, req)
+	if !ok {
+		return
+	}
+	drained := r.topo.IsDrained(dpid)
+
+	w.WriteJson(&DrainStatus{
+		Drained: drained,
+		Safe:    drained && r.topo.IsTransit(dpid) == false,
+	})
+}
+
+func (r *Controller) drainSwitch(w rest.ResponseWriter, req *rest.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	dpid, ok := r.switchDPID(w, req)
+	if !ok {
+		return
+	}
+
+	logger.Infof("draining the switch whose DPID is %v", dpid)
+	device := r.topo.Device(dpid)
+	// Disconnected switch? No path traverses it, so just remember the state.
+	if device == nil {
+		if err := r.topo.SetDrained(dpid, true); err != nil {
+			logger.Errorf("failed to drain the switch %v: %v", dpid, err)
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		w.WriteJson(&DrainStatus{Drained: true, Safe: true})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+	err := device.Drain(ctx)
+	if err != nil && err != context.DeadlineExceeded {
+		logger.Errorf("failed to drain the switch %v: %v", dpid, err)
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if err == context.DeadlineExceeded {
+		// The switch remains drained. The client may poll the drain status.
+		logger.Warningf("paths still traverse the draining switch %v", dpid)
+	}
+
+	w.WriteJson(&DrainStatus{Drained: true, Safe: err == nil})
+}
+
+func (r *Controller) undrainSwitch(w rest.ResponseWriter, req *rest.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	dpid, ok := r.switchDPID(w, req)
+	if !ok {
+		return
+	}
+
+	logger.Infof("undraining the switch whose DPID is %v", dpid)
+	if err := r.topo.SetDrained(dpid, false); err != nil {
+		logger.Errorf("failed to undrain the switch %v: %v", dpid, err)
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.WriteJson(&DrainStatus{Drained: false, Safe: false})
+}
+
 type SwitchPort struct {
 	ID     uint64 `json:"id"`
 	Number uint   `json:"number"`
@@ -536,6 +919,114 @@ func (r *Controller) addHost(w rest.ResponseWriter, req *rest.Request) {
 	}
 }
 
+type ImportParam struct {
+	Hosts []HostLocationParam `json:"hosts"`
+	// Force allows the host locations on the devices that are not connected yet.
+	Force bool `json:"force"`
+}
+
+type HostLocationParam struct {
+	MAC  string `json:"mac"`
+	IP   string `json:"ip"`
+	DPID string `json:"dpid"`
+	Port uint32 `json:"port"`
+}
+
+type hostLocation struct {
+	mac  net.HardwareAddr
+	ip   net.IP
+	dpid uint64
+	port uint32
+}
+
+func (r *HostLocationParam) parse() (hostLocation, error) {
+	mac, err := net.ParseMAC(r.MAC)
+	if err != nil {
+		return hostLocation{}, err
+	}
+	ip := net.ParseIP(r.IP)
+	if ip == nil {
+		return hostLocation{}, fmt.Errorf("invalid IP address: %v", r.IP)
+	}
+	dpid, err := strconv.ParseUint(r.DPID, 10, 64)
+	if err != nil {
+		return hostLocation{}, fmt.Errorf("invalid DPID: %v", r.DPID)
+	}
+	if r.Port == 0 {
+		return hostLocation{}, errors.New("invalid port number: 0")
+	}
+	// The database stores the port numbers in a 16-bit column, so reject the larger ones here
+	// instead of silently truncating them into a different port.
+	if r.Port > math.MaxUint16 {
+		return hostLocation{}, fmt.Errorf("unsupported port number: %v", r.Port)
+	}
+
+	return hostLocation{mac: mac, ip: ip, dpid: dpid, port: r.Port}, nil
+}
+
+// importState pre-populates the locations of the registered hosts so that we do
+// not have to wait until they are discovered. The imported locations will be
+// verified by the discovery application as usual. Importing the same document
+// again has no effect. A snapshot exported by exportSnapshot is also accepted.
+func (r *Controller) importState(w rest.ResponseWriter, req *rest.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	param := ImportParam{}
+	if err := req.DecodeJsonPayload(&param); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// Validate the whole document before importing anything.
+	locations := make([]hostLocation, len(param.Hosts))
+	for i, h := range param.Hosts {
+		loc, err := h.parse()
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("hosts[%v]: %v", i, err))
+			return
+		}
+		if !param.Force && r.topo.Device(h.DPID) == nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("hosts[%v]: unknown device: %v", i, h.DPID))
+			return
+		}
+		locations[i] = loc
+	}
+
+	result := struct {
+		Updated   int      `json:"updated"`
+		Unchanged int      `json:"unchanged"`
+		Failed    []string `json:"failed"`
+	}{
+		Failed: make([]string, 0),
+	}
+	for _, loc := range locations {
+		updated, err := r.db.UpdateHostLocation(loc.mac, loc.ip, loc.dpid, uint16(loc.port))
+		if err != nil {
+			logger.Errorf("failed to import the host location (MAC=%v, IP=%v): %v", loc.mac, loc.ip, err)
+			result.Failed = append(result.Failed, fmt.Sprintf("%v: %v", loc.mac, err))
+			continue
+		}
+		if !updated {
+			// Unknown host or no location change.
+			result.Unchanged++
+			continue
+		}
+		result.Updated++
+		logger.Infof("imported host location: IP=%v, MAC=%v, deviceID=%v, portNum=%v", loc.ip, loc.mac, loc.dpid, loc.port)
+		r.topo.events.publishHostMoved(loc.mac, loc.ip, strconv.FormatUint(loc.dpid, 10), loc.port)
+
+		// Remove the flows installed for the previous location of this host.
+		for _, d := range r.topo.Devices() {
+			if err := d.RemoveFlowByMAC(loc.mac); err != nil {
+				logger.Errorf("failed to remove flows from %v: %v", d.ID(), err)
+				continue
+			}
+		}
+	}
+
+	w.WriteJson(&result)
+}
+
 func (r *Controller) sendARPAnnouncement(cidr string, mac string) error {
 	ip, _, err := net.ParseCIDR(cidr)
 	if err != nil {
@@ -744,6 +1235,918 @@ func (r *Controller) toggleVIP(w rest.ResponseWriter, req *rest.Request) {
 	w.WriteJson(&struct{}{})
 }
 
+type DeviceInfo struct {
+	DPID         string `json:"dpid"`
+	Version      uint8  `json:"version"`
+	Manufacturer string `json:"manufacturer"`
+	Hardware     string `json:"hardware"`
+	Software     string `json:"software"`
+	Serial       string `json:"serial"`
+	Description  string `json:"description"`
+	NumBuffers   uint32 `json:"n_buffers"`
+	NumTables    uint8  `json:"n_tables"`
+	NumPorts     int    `json:"n_ports"`
+	Drained      bool   `json:"drained"`
+	Role         string `json:"role"`
+	// Round-trip time of the control channel in microseconds. 0 if it is not measured yet.
+	RTT int64 `json:"rtt_usec"`
+	// Max length of the table-miss packets sent in PACKET_INs.
+	MissSendLen uint16 `json:"miss_send_len"`
+}
+
+func (r *Controller) listDevices(w rest.ResponseWriter, req *rest.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	devices := []DeviceInfo{}
+	for _, d := range r.topo.Devices() {
+		if d.isReady() == false {
+			continue
+		}
+		devices = append(devices, newDeviceInfo(d))
+	}
+
+	w.WriteJson(&struct {
+		Devices []DeviceInfo `json:"devices"`
+	}{devices})
+}
+
+func (r *Controller) getDevice(w rest.ResponseWriter, req *rest.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	device, ok := r.connectedDevice(w, req)
+	if !ok {
+		return
+	}
+	w.WriteJson(&struct {
+		DeviceInfo
+		// Result of the last self-test, or null if the device has never been tested.
+		SelfTest *SelfTestResult `json:"self_test"`
+	}{newDeviceInfo(device), r.selfTests.get(device.ID())})
+}
+
+// newDeviceInfo returns the inventory of d. The descriptions are empty if the device has
+// not replied to our DESC request yet.
+func newDeviceInfo(d *Device) DeviceInfo {
+	desc := d.Descriptions()
+	features := d.Features()
+	role, _ := d.Role()
+
+	return DeviceInfo{
+		DPID:         d.ID(),
+		Version:      d.Factory().ProtocolVersion(),
+		Manufacturer: desc.Manufacturer,
+		Hardware:     desc.Hardware,
+		Software:     desc.Software,
+		Serial:       desc.Serial,
+		Description:  desc.Description,
+		NumBuffers:   features.NumBuffers,
+		NumTables:    features.NumTables,
+		NumPorts:     len(d.Ports()),
+		Drained:      d.IsDrained(),
+		Role:         role.String(),
+		RTT:          int64(d.RTT() / time.Microsecond),
+		MissSendLen:  d.MissSendLength(),
+	}
+}
+
+type MissSendLenParam struct {
+	// 0xFFFF means the whole packet.
+	MissSendLen *uint16 `json:"miss_send_len"`
+}
+
+func (r *Controller) setDeviceMissSendLen(w rest.ResponseWriter, req *rest.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	param := MissSendLenParam{}
+	if err := req.DecodeJsonPayload(&param); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if param.MissSendLen == nil {
+		writeError(w, http.StatusBadRequest, errors.New("missing miss_send_len"))
+		return
+	}
+	device, ok := r.connectedDevice(w, req)
+	if !ok {
+		return
+	}
+
+	logger.Infof("changing miss_send_len of %v to %v", device.ID(), *param.MissSendLen)
+	if err := device.SetMissSendLength(*param.MissSendLen); err != nil {
+		logger.Errorf("failed to change miss_send_len of %v: %v", device.ID(), err)
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.WriteJson(newDeviceInfo(device))
+}
+
+// connectedDevice returns the connected device whose DPID is the dpid path parameter.
+func (r *Controller) connectedDevice(w rest.ResponseWriter, req *rest.Request) (device *Device, ok bool) {
+	if _, err := strconv.ParseUint(req.PathParam("dpid"), 10, 64); err != nil {
+		writeError(w, http.StatusBadRequest, errors.New("invalid DPID"))
+		return nil, false
+	}
+	device = r.topo.Device(req.PathParam("dpid"))
+	if device == nil || device.isReady() == false {
+		writeError(w, http.StatusNotFound, errors.New("unknown or disconnected device"))
+		return nil, false
+	}
+
+	return device, true
+}
+
+type DevicePort struct {
+	Number  uint32 `json:"number"`
+	Name    string `json:"name"`
+	MAC     string `json:"mac"`
+	AdminUp bool   `json:"admin_up"`
+	LinkUp  bool   `json:"link_up"`
+	// Config and State are the raw OFPPC_* and OFPPS_* bitmaps of the negotiated OpenFlow version.
+	Config uint32 `json:"config"`
+	State  uint32 `json:"state"`
+	// Speed in Mbps
+	Speed uint64 `json:"speed"`
+	// Stats is nil if the port statistics have never been polled.
+	Stats *openflow.PortStats `json:"stats"`
+}
+
+func (r *Controller) listDevicePorts(w rest.ResponseWriter, req *rest.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	device, ok := r.connectedDevice(w, req)
+	if !ok {
+		return
+	}
+
+	w.WriteJson(&struct {
+		Ports []DevicePort `json:"ports"`
+	}{newDevicePorts(device)})
+}
+
+func newDevicePorts(device *Device) []DevicePort {
+	ports := []DevicePort{}
+	for _, p := range device.Ports() {
+		v := p.Value()
+		if v == nil {
+			continue
+		}
+		port := DevicePort{
+			Number:  p.Number(),
+			Name:    v.Name(),
+			MAC:     v.MAC().String(),
+			AdminUp: !v.IsPortDown(),
+			LinkUp:  !v.IsLinkDown(),
+			Config:  v.Config(),
+			State:   v.State(),
+			Speed:   v.Speed(),
+		}
+		if stats, updated := p.Stats(); updated.IsZero() == false {
+			port.Stats = &stats
+		}
+		ports = append(ports, port)
+	}
+
+	return ports
+}
+
+// FlowMatchParam is the match fields of a flow. Zero values (or empty strings) are wildcards.
+type FlowMatchParam struct {
+	InPort     uint32 `json:"in_port"`
+	SrcMAC     string `json:"src_mac"`
+	DstMAC     string `json:"dst_mac"`
+	EtherType  uint16 `json:"ether_type"`
+	IPProtocol uint8  `json:"ip_protocol"`
+	// CIDR notation, e.g., 10.0.0.0/24
+	SrcIP   string `json:"src_ip"`
+	DstIP   string `json:"dst_ip"`
+	SrcPort uint16 `json:"src_port"`
+	DstPort uint16 `json:"dst_port"`
+}
+
+func (r *FlowMatchParam) match(f openflow.Factory) (openflow.Match, error) {
+	match, err := f.NewMatch()
+	if err != nil {
+		return nil, err
+	}
+
+	if r.InPort != 0 {
+		port := openflow.NewInPort()
+		port.SetValue(r.InPort)
+		match.SetInPort(port)
+	}
+	if len(r.SrcMAC) > 0 {
+		mac, err := net.ParseMAC(r.SrcMAC)
+		if err != nil {
+			return nil, err
+		}
+		match.SetSrcMAC(mac)
+	}
+	if len(r.DstMAC) > 0 {
+		mac, err := net.ParseMAC(r.DstMAC)
+		if err != nil {
+			return nil, err
+		}
+		match.SetDstMAC(mac)
+	}
+	if r.EtherType != 0 {
+		match.SetEtherType(r.EtherType)
+	}
+	if r.IPProtocol != 0 {
+		match.SetIPProtocol(r.IPProtocol)
+	}
+	if len(r.SrcIP) > 0 {
+		_, ip, err := net.ParseCIDR(r.SrcIP)
+		if err != nil {
+			return nil, err
+		}
+		match.SetSrcIP(ip)
+	}
+	if len(r.DstIP) > 0 {
+		_, ip, err := net.ParseCIDR(r.DstIP)
+		if err != nil {
+			return nil, err
+		}
+		match.SetDstIP(ip)
+	}
+	if r.SrcPort != 0 {
+		match.SetSrcPort(r.SrcPort)
+	}
+	if r.DstPort != 0 {
+		match.SetDstPort(r.DstPort)
+	}
+	if err := match.Error(); err != nil {
+		return nil, err
+	}
+
+	return match, nil
+}
+
+func newFlowMatchParam(m openflow.Match) FlowMatchParam {
+	v := FlowMatchParam{}
+	if m == nil {
+		return v
+	}
+
+	if wildcard, port := m.InPort(); !wildcard {
+		v.InPort = port.Value()
+	}
+	if wildcard, mac := m.SrcMAC(); !wildcard {
+		v.SrcMAC = mac.String()
+	}
+	if wildcard, mac := m.DstMAC(); !wildcard {
+		v.DstMAC = mac.String()
+	}
+	if wildcard, t := m.EtherType(); !wildcard {
+		v.EtherType = t
+	}
+	if wildcard, p := m.IPProtocol(); !wildcard {
+		v.IPProtocol = p
+	}
+	if ip := m.SrcIP(); ip != nil && ip.IP != nil {
+		if ones, _ := ip.Mask.Size(); ones > 0 {
+			v.SrcIP = ip.String()
+		}
+	}
+	if ip := m.DstIP(); ip != nil && ip.IP != nil {
+		if ones, _ := ip.Mask.Size(); ones > 0 {
+			v.DstIP = ip.String()
+		}
+	}
+	if wildcard, p := m.SrcPort(); !wildcard {
+		v.SrcPort = p
+	}
+	if wildcard, p := m.DstPort(); !wildcard {
+		v.DstPort = p
+	}
+
+	return v
+}
+
+// String returns the non-wildcard fields in a fixed order, or "*" if all the fields are wildcards.
+func (r FlowMatchParam) String() string {
+	fields := make([]string, 0)
+	add := func(name string, value interface{}, wildcard bool) {
+		if !wildcard {
+			fields = append(fields, fmt.Sprintf("%v=%v", name, value))
+		}
+	}
+	add("in_port", r.InPort, r.InPort == 0)
+	add("src_mac", r.SrcMAC, r.SrcMAC == "")
+	add("dst_mac", r.DstMAC, r.DstMAC == "")
+	add("ether_type", fmt.Sprintf("0x%04x", r.EtherType), r.EtherType == 0)
+	add("ip_protocol", r.IPProtocol, r.IPProtocol == 0)
+	add("src_ip", r.SrcIP, r.SrcIP == "")
+	add("dst_ip", r.DstIP, r.DstIP == "")
+	add("src_port", r.SrcPort, r.SrcPort == 0)
+	add("dst_port", r.DstPort, r.DstPort == 0)
+	if len(fields) == 0 {
+		return "*"
+	}
+
+	return strings.Join(fields, ",")
+}
+
+type DeviceFlow struct {
+	TableID     uint8          `json:"table_id"`
+	Priority    uint16         `json:"priority"`
+	Cookie      uint64         `json:"cookie"`
+	DurationSec uint32         `json:"duration_sec"`
+	IdleTimeout uint16         `json:"idle_timeout"`
+	HardTimeout uint16         `json:"hard_timeout"`
+	PacketCount uint64         `json:"packet_count"`
+	ByteCount   uint64         `json:"byte_count"`
+	Match       FlowMatchParam `json:"match"`
+	// Owner is the name of the application that owns the flow, or empty if no application owns it.
+	Owner string `json:"owner,omitempty"`
+}
+
+func (r *Controller) listDeviceFlows(w rest.ResponseWriter, req *rest.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	device, ok := r.connectedDevice(w, req)
+	if !ok {
+		return
+	}
+
+	flows, collected := newDeviceFlows(device)
+	// Only the flows owned by the application if the owner is specified.
+	if owner := req.URL.Query().Get("owner"); owner != "" {
+		owned := []DeviceFlow{}
+		for _, v := range flows {
+			if strings.EqualFold(v.Owner, owner) {
+				owned = append(owned, v)
+			}
+		}
+		flows = owned
+	}
+	w.WriteJson(&struct {
+		Flows []DeviceFlow `json:"flows"`
+		// Collected is the time when the flows were collected from the device, or null if not yet collected.
+		Collected *time.Time `json:"collected"`
+	}{flows, collected})
+}
+
+// newDeviceFlows returns the flows last collected from device, and the time when they were
+// collected. The time is nil if the flows are not collected yet.
+func newDeviceFlows(device *Device) (flows []DeviceFlow, collected *time.Time) {
+	stats, updated := device.FlowStats()
+	flows = make([]DeviceFlow, len(stats))
+	for i, v := range stats {
+		flows[i] = DeviceFlow{
+			TableID:     v.TableID,
+			Priority:    v.Priority,
+			Cookie:      v.Cookie,
+			DurationSec: v.DurationSec,
+			IdleTimeout: v.IdleTimeout,
+			HardTimeout: v.HardTimeout,
+			PacketCount: v.PacketCount,
+			ByteCount:   v.ByteCount,
+			Match:       newFlowMatchParam(v.Match),
+			Owner:       cookieOwnerName(v.Cookie),
+		}
+	}
+
+	if updated.IsZero() == false {
+		collected = &updated
+	}
+
+	return flows, collected
+}
+
+type DeviceQueue struct {
+	Port      uint32 `json:"port"`
+	QueueID   uint32 `json:"queue_id"`
+	TxBytes   uint64 `json:"tx_bytes"`
+	TxPackets uint64 `json:"tx_packets"`
+	TxErrors  uint64 `json:"tx_errors"`
+}
+
+func (r *Controller) listDeviceQueues(w rest.ResponseWriter, req *rest.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	device, ok := r.connectedDevice(w, req)
+	if !ok {
+		return
+	}
+
+	stats, updated := device.QueueStats()
+	queues := make([]DeviceQueue, len(stats))
+	for i, v := range stats {
+		queues[i] = DeviceQueue{
+			Port:      v.PortNumber,
+			QueueID:   v.QueueID,
+			TxBytes:   v.TxBytes,
+			TxPackets: v.TxPackets,
+			TxErrors:  v.TxErrors,
+		}
+	}
+
+	var collected *time.Time
+	if updated.IsZero() == false {
+		collected = &updated
+	}
+	w.WriteJson(&struct {
+		Queues []DeviceQueue `json:"queues"`
+		// Collected is the time when the statistics were collected from the device, or null if not yet collected.
+		Collected *time.Time `json:"collected"`
+	}{queues, collected})
+}
+
+type QueueConfig struct {
+	QueueID uint32 `json:"queue_id"`
+	// MinRate and MaxRate are in 1/10 of a percent of the port speed. null means not configured.
+	MinRate *uint16 `json:"min_rate"`
+	MaxRate *uint16 `json:"max_rate"`
+}
+
+func (r *Controller) getDeviceQueueConfig(w rest.ResponseWriter, req *rest.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	port, err := strconv.ParseUint(req.PathParam("port"), 10, 32)
+	if err != nil || port == 0 {
+		writeError(w, http.StatusBadRequest, errors.New("invalid port number"))
+		return
+	}
+	device, ok := r.connectedDevice(w, req)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	queues, err := device.QueueConfig(ctx, uint32(port))
+	if err != nil {
+		logger.Errorf("failed to query the queue configuration of %v:%v: %v", device.ID(), port, err)
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	result := make([]QueueConfig, 0, len(queues))
+	for _, q := range queues {
+		c := QueueConfig{QueueID: q.ID()}
+		for _, p := range q.Property() {
+			rate, err := p.Rate()
+			if err != nil {
+				continue
+			}
+			switch p.Type() {
+			case openflow.OFPQT_MIN_RATE:
+				c.MinRate = &rate
+			case openflow.OFPQT_MAX_RATE:
+				c.MaxRate = &rate
+			}
+		}
+		result = append(result, c)
+	}
+
+	w.WriteJson(&struct {
+		Queues []QueueConfig `json:"queues"`
+	}{result})
+}
+
+type FlowParam struct {
+	Match       FlowMatchParam `json:"match"`
+	Priority    uint16         `json:"priority"`
+	IdleTimeout uint16         `json:"idle_timeout"`
+	HardTimeout uint16         `json:"hard_timeout"`
+	// Cookie should fit in 48 bits because the upper bits are reserved for the special
+	// flows and the owner applications.
+	Cookie uint64 `json:"cookie"`
+	// Output is a port number, "flood", "all", "controller" or "in_port". Empty
+	// output drops the matched packets.
+	Output string `json:"output"`
+	// Queue is the ID of the egress queue on the output port, or null to use the default queue.
+	Queue *uint32 `json:"queue"`
+	// Slice is the name of the slice that the flow should be confined to, or empty if the
+	// flow is not programmed on behalf of a slice.
+	Slice string `json:"slice"`
+}
+
+func (r *FlowParam) validate() error {
+	if r.Cookie&^cookieValueMask != 0 {
+		return errors.New("reserved cookie value")
+	}
+	if r.Queue != nil && r.Output == "" {
+		return errors.New("queue without output")
+	}
+
+	return nil
+}
+
+func (r *FlowParam) outPort() (port openflow.OutPort, drop bool, err error) {
+	port = openflow.NewOutPort()
+	switch r.Output {
+	case "":
+		return port, true, nil
+	case "flood":
+		port.SetFlood()
+	case "all":
+		port.SetAll()
+	case "controller":
+		port.SetController()
+	case "in_port":
+		port.SetInPort()
+	default:
+		num, err := strconv.ParseUint(r.Output, 10, 32)
+		if err != nil || num == 0 {
+			return port, false, errors.New("invalid output")
+		}
+		port.SetValue(uint32(num))
+	}
+
+	return port, false, nil
+}
+
+// slice returns the slice whose name is name, or nil if it does not exist.
+func (r *Controller) slice(name string) *Slice {
+	return findSlice(r.slices, name)
+}
+
+type SliceInfo struct {
+	Name  string   `json:"name"`
+	VLANs []uint16 `json:"vlans"`
+	// DPID:port pairs of the slice.
+	Ports []string `json:"ports"`
+	Apps  []string `json:"applications"`
+}
+
+func (r *Controller) listSlices(w rest.ResponseWriter, req *rest.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	slices := []SliceInfo{}
+	for _, s := range r.slices {
+		v := SliceInfo{Name: s.Name, VLANs: []uint16{}, Ports: []string{}, Apps: s.Apps}
+		for id := range s.VLANs {
+			v.VLANs = append(v.VLANs, id)
+		}
+		sort.Slice(v.VLANs, func(i, j int) bool { return v.VLANs[i] < v.VLANs[j] })
+		for dpid, ports := range s.Ports {
+			for num := range ports {
+				v.Ports = append(v.Ports, fmt.Sprintf("%v:%v", dpid, num))
+			}
+		}
+		sort.Strings(v.Ports)
+		slices = append(slices, v)
+	}
+	w.WriteJson(slices)
+}
+
+func (r *Controller) addDeviceFlow(w rest.ResponseWriter, req *rest.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	param := FlowParam{}
+	if err := req.DecodeJsonPayload(&param); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := param.validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	device, ok := r.connectedDevice(w, req)
+	if !ok {
+		return
+	}
+
+	f := device.Factory()
+	match, err := param.Match.match(f)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	port, drop, err := param.outPort()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	flow, err := f.NewFlowMod(openflow.FlowAdd)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	flow.SetTableID(device.FlowTableID())
+	flow.SetCookie(param.Cookie)
+	flow.SetPriority(param.Priority)
+	flow.SetIdleTimeout(param.IdleTimeout)
+	flow.SetHardTimeout(param.HardTimeout)
+	flow.SetFlowMatch(match)
+	// A flow without any instruction drops the packets.
+	if !drop {
+		action, err := f.NewAction()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		action.SetOutPort(port)
+		if param.Queue != nil {
+			action.SetQueue(*param.Queue)
+		}
+		inst, err := f.NewInstruction()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		inst.ApplyAction(action)
+		flow.SetFlowInstruction(inst)
+	}
+
+	if err := device.ValidateFlow(flow); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	// The flows of a tenant are always confined to its slice.
+	if tenant, ok := tenantSlice(req); ok {
+		if len(param.Slice) > 0 && !strings.EqualFold(param.Slice, tenant) {
+			writeError(w, http.StatusForbidden, fmt.Errorf("user is confined to slice %v", tenant))
+			return
+		}
+		param.Slice = tenant
+	}
+	if len(param.Slice) > 0 {
+		slice := r.slice(param.Slice)
+		if slice == nil {
+			writeError(w, http.StatusBadRequest, errors.New("unknown slice"))
+			return
+		}
+		out := &port
+		if drop {
+			out = nil
+		}
+		if err := slice.ValidateFlow(device.ID(), match, out); err != nil {
+			writeError(w, http.StatusForbidden, err)
+			return
+		}
+	}
+
+	logger.Infof("installing a flow on %v by the REST API: %+v", device.ID(), param)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := device.InstallFlowAndWait(ctx, flow); err != nil {
+		logger.Errorf("failed to install a flow on %v: %v", device.ID(), err)
+		status := http.StatusInternalServerError
+		var e *openflow.DeviceError
+		// The device rejected the flow.
+		if errors.As(err, &e) {
+			status = http.StatusUnprocessableEntity
+		}
+		writeError(w, status, err)
+		return
+	}
+
+	w.WriteJson(&struct{}{})
+}
+
+func (r *Controller) removeDeviceFlows(w rest.ResponseWriter, req *rest.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	param := FlowMatchParam{}
+	if err := req.DecodeJsonPayload(&param); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	device, ok := r.connectedDevice(w, req)
+	if !ok {
+		return
+	}
+
+	f := device.Factory()
+	match, err := param.match(f)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	flow, err := f.NewFlowMod(openflow.FlowDelete)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	// Only the normal flows, except the special flows whose cookie MSB is 1.
+	flow.SetCookieMask(0x1 << 63)
+	flow.SetTableID(0xFF) // ALL
+	flow.SetFlowMatch(match)
+
+	logger.Infof("removing the flows on %v by the REST API: %+v", device.ID(), param)
+	if err := device.InstallFlow(flow); err != nil {
+		logger.Errorf("failed to remove the flows on %v: %v", device.ID(), err)
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.WriteJson(&struct{}{})
+}
+
+func (r *Controller) removeOwnedFlows(w rest.ResponseWriter, req *rest.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	device, ok := r.connectedDevice(w, req)
+	if !ok {
+		return
+	}
+	owner := req.PathParam("name")
+
+	logger.Infof("removing the flows owned by %v on %v by the REST API", owner, device.ID())
+	if err := device.RemoveOwnedFlows(owner); err != nil {
+		logger.Errorf("failed to remove the flows owned by %v on %v: %v", owner, device.ID(), err)
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.WriteJson(&struct{}{})
+}
+
+// LinkInfo is a link between two switches discovered by LLDP, or by BDDP if it goes
+// through non-OpenFlow switches.
+type LinkInfo struct {
+	DPID1 string `json:"dpid1"`
+	Port1 uint32 `json:"port1"`
+	DPID2 string `json:"dpid2"`
+	Port2 uint32 `json:"port2"`
+	// True if the link has been discovered by BDDP.
+	Indirect bool `json:"indirect"`
+}
+
+func (r *Controller) listLinks(w rest.ResponseWriter, req *rest.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	w.WriteJson(&struct {
+		Links []LinkInfo `json:"links"`
+	}{r.linkInfos()})
+}
+
+func (r *Controller) linkInfos() []LinkInfo {
+	links := []LinkInfo{}
+	for _, v := range r.topo.Links() {
+		links = append(links, LinkInfo{
+			DPID1:    v[0].Device().ID(),
+			Port1:    v[0].Number(),
+			DPID2:    v[1].Device().ID(),
+			Port2:    v[1].Number(),
+			Indirect: r.topo.isIndirectLink(v),
+		})
+	}
+
+	return links
+}
+
+// streamEvents upgrades the request to a WebSocket connection and pushes the network events
+// to the client as JSON text messages until either side closes the connection. The optional
+// types query parameter is a comma-separated list of the event types to receive. A client that
+// cannot keep up with the events is disconnected with the policy violation status.
+func (r *Controller) streamEvents(w rest.ResponseWriter, req *rest.Request) {
+	var types []EventType
+	if v := req.URL.Query().Get("types"); v != "" {
+		for _, t := range strings.Split(v, ",") {
+			types = append(types, EventType(strings.TrimSpace(t)))
+		}
+	}
+	// Subscribe before the upgrade so that no event is lost after the client is notified.
+	events, cancel := r.Subscribe(types...)
+	defer cancel()
+
+	conn, err := upgradeWebSocket(w.(http.ResponseWriter), req.Request)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	defer conn.Close()
+	logger.Infof("new event subscriber: %v", conn)
+
+	closed := make(chan struct{})
+	go func() {
+		if err := conn.readLoop(); err != nil && err != io.EOF {
+			logger.Debugf("failed to read from the event subscriber %v: %v", conn, err)
+		}
+		close(closed)
+	}()
+
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				conn.writeClose(wsClosePolicyViolation, "too slow to receive the events")
+				return
+			}
+			v, err := json.Marshal(e)
+			if err != nil {
+				logger.Errorf("failed to marshal the event: %v", err)
+				continue
+			}
+			if err := conn.writeText(v); err != nil {
+				logger.Infof("failed to send the event to %v: %v", conn, err)
+				return
+			}
+		case <-closed:
+			logger.Infof("event subscriber is closed: %v", conn)
+			return
+		}
+	}
+}
+
+type OVSTunnelParam struct {
+	Name string `json:"name"`
+	// vxlan, gre or geneve
+	Type     string `json:"type"`
+	RemoteIP string `json:"remote_ip"`
+}
+
+// OVSBootstrapParam is the configuration of an Open vSwitch bridge that will be made through OVSDB.
+type OVSBootstrapParam struct {
+	// OVSDB server, e.g., "tcp:10.0.0.1:6640" or "unix:/var/run/openvswitch/db.sock". A Unix
+	// domain socket should be one of the sockets in the config file.
+	Server string `json:"server"`
+	Bridge string `json:"bridge"`
+	// OpenFlow controllers of the bridge, e.g., "tcp:10.0.0.100:6633".
+	Controllers []string `json:"controllers"`
+	// secure or standalone. Empty string keeps the current fail mode.
+	FailMode string           `json:"fail_mode"`
+	Tunnels  []OVSTunnelParam `json:"tunnels"`
+}
+
+// validate checks the parameters. sockets are the Unix domain sockets that are allowed.
+func (r *OVSBootstrapParam) validate(sockets []string) error {
+	switch {
+	case strings.HasPrefix(r.Server, "tcp:"):
+	case strings.HasPrefix(r.Server, "unix:"):
+		if !isAllowedSocket(strings.TrimPrefix(r.Server, "unix:"), sockets) {
+			return errors.New("OVSDB socket is not allowed")
+		}
+	default:
+		return errors.New("invalid OVSDB server")
+	}
+	if len(r.Bridge) == 0 {
+		return errors.New("empty bridge name")
+	}
+	if len(r.Controllers) == 0 {
+		return errors.New("empty controllers")
+	}
+	for _, v := range r.Tunnels {
+		if len(v.Name) == 0 || net.ParseIP(v.RemoteIP) == nil {
+			return fmt.Errorf("invalid tunnel: %+v", v)
+		}
+	}
+
+	return nil
+}
+
+// isAllowedSocket returns whether path is one of sockets, so that the REST API cannot be used to
+// connect to an arbitrary Unix domain socket of the controller host.
+func isAllowedSocket(path string, sockets []string) bool {
+	if !filepath.IsAbs(path) {
+		return false
+	}
+	path = filepath.Clean(path)
+	for _, v := range sockets {
+		if filepath.Clean(v) == path {
+			return true
+		}
+	}
+
+	return false
+}
+
+// bootstrapOVS configures an Open vSwitch bridge, its controllers and tunnel ports through OVSDB,
+// so that the switch connects to us without ovs-vsctl. The bridge is created if it does not exist.
+func (r *Controller) bootstrapOVS(w rest.ResponseWriter, req *rest.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	param := OVSBootstrapParam{}
+	if err := req.DecodeJsonPayload(&param); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := param.validate(r.ovsdbSockets); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	logger.Infof("bootstrapping an Open vSwitch bridge by the REST API: %+v", param)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	s := strings.SplitN(param.Server, ":", 2)
+	client, err := ovsdb.Dial(ctx, s[0], s[1])
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	defer client.Close()
+
+	tunnels := make([]ovsdb.Tunnel, 0, len(param.Tunnels))
+	for _, v := range param.Tunnels {
+		tunnels = append(tunnels, ovsdb.Tunnel{Name: v.Name, Type: v.Type, RemoteIP: net.ParseIP(v.RemoteIP)})
+	}
+	// All the changes are made in a single transaction, so a failure leaves the switch untouched.
+	err = client.Bootstrap(ctx, param.Bridge, param.Controllers, param.FailMode, tunnels)
+	if err != nil {
+		logger.Errorf("failed to bootstrap the Open vSwitch bridge %v on %v: %v", param.Bridge, param.Server, err)
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	w.WriteJson(&struct{}{})
+}
+
 func writeError(w rest.ResponseWriter, status int, err error) {
 	w.WriteHeader(status)
 	w.WriteJson(&struct {
@@ -751,22 +2154,122 @@ func writeError(w rest.ResponseWriter, status int, err error) {
 	}{err.Error()})
 }
 
+// AddConnection starts a new session for the connection c. It blocks until a
+// handshake slot becomes available if there are too many connections that are
+// still in the handshake phase.
 func (r *Controller) AddConnection(ctx context.Context, c net.Conn) {
+	if !r.limiter.allow(c.RemoteAddr()) {
+		key := fmt.Sprintf("conn rate limit %v", c.RemoteAddr())
+		rateLogger.Warningf(key, "closing the connection from %v: too many connections from the same address", c.RemoteAddr())
+		c.Close()
+		connsRejected.Inc()
+		return
+	}
+
+	release, err := r.pacer.acquire(ctx)
+	if err != nil {
+		logger.Infof("closing the pending connection from %v: %v", c.RemoteAddr(), err)
+		c.Close()
+		return
+	}
+
 	conf := sessionConfig{
-		conn:     c,
-		watcher:  r.topo,
-		finder:   r.topo,
-		listener: r.listener,
+		conn:              c,
+		watcher:           r.topo,
+		finder:            r.topo,
+		listener:          r.listener,
+		events:            r.topo.events,
+		handshakeDone:     release,
+		packetIn:          r.packetIn,
+		packetInWorkers:   r.packetInWorkers,
+		clock:             r.clock,
+		portStatsInterval: r.portStatsInterval,
+		handshakeTimeout:  r.handshakeTimeout,
+		handshakeRetry:    r.handshakeRetry,
+		negotiation:       r.negotiation,
+		admission:         r.admission,
+		certBinder:        r.certBinder,
+		reconcileFlows:    r.reconcileFlows,
+		removeStaleFlows:  r.removeStaleFlows,
+		mastership:        r.mastership,
+		auxPolicy:         r.auxPolicy,
+		flowModRate:       r.flowModRate,
+		packetOutRate:     r.packetOutRate,
+		flowConflict:      r.flowConflict,
+		priorities:        r.priorities,
+		slices:            r.sliceIndex,
+		capture:           r.capture,
+		auditLog:          r.auditLog,
 	}
 	session := newSession(conf)
-	go session.Run(ctx)
+	r.sessions.Add(1)
+	go func() {
+		defer r.sessions.Done()
+		session.Run(ctx)
+	}()
+}
+
+// Shutdown prepares the controller to exit. If removeFlows is true, it removes the normal
+// flows installed by the controller from all the devices. It then sends a final barrier to
+// each device, and waits until the device has applied all the messages sent before the
+// shutdown. All the devices are then closed so that no more flow is installed. The
+// connections will be closed when the context passed to AddConnection is done, and Wait
+// can be used to wait for them.
+func (r *Controller) Shutdown(ctx context.Context, removeFlows bool) {
+	for _, d := range r.topo.Devices() {
+		if d.isReady() {
+			removed := false
+			if removeFlows {
+				if err := d.RemoveFlows(); err != nil {
+					logger.Errorf("failed to remove flows from %v: %v", d.ID(), err)
+				} else {
+					removed = true
+				}
+			}
+			// Send the final barrier even if the flows are kept so that the flows installed
+			// just before the shutdown are not lost with the connection.
+			if err := d.Barrier(ctx); err != nil {
+				logger.Errorf("failed to send the final barrier to %v: %v", d.ID(), err)
+			} else if removed {
+				logger.Infof("removed flows from %v", d.ID())
+			}
+		}
+		d.Close()
+	}
+}
+
+// Wait blocks until all the sessions are closed or ctx is done.
+func (r *Controller) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		r.sessions.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 func (r *Controller) SetEventListener(l EventListener) {
 	r.listener = l
-	r.topo.setEventListener(l)
+	// Publish the link events on the bus before passing them to l. The sessions do the same
+	// thing for the device, port and flow events.
+	r.topo.setEventListener(&topologyPublisher{TopologyEventListener: l, bus: r.topo.events})
+}
+
+// Subscribe returns a channel that receives the network events of types, or all the events if
+// types is empty. The events are delivered asynchronously, and the channel is closed if the
+// subscriber falls behind by more than subscriptionBufferSize events. cancel should be called
+// when the subscriber is no longer interested in the events.
+func (r *Controller) Subscribe(types ...EventType) (events <-chan Event, cancel func()) {
+	c := r.topo.events.subscribe(types...)
+	return c, func() { r.topo.events.unsubscribe(c) }
 }
 
 func (r *Controller) String() string {
-	return r.topo.String()
+	return fmt.Sprintf("%v\n%v\n%v", r.pacer, r.packetIn, r.topo)
 }
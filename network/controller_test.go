@@ -0,0 +1,96 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/superkkt/cherry/clock"
+	"github.com/superkkt/cherry/openflow"
+	"github.com/superkkt/cherry/openflow/of13"
+)
+
+func TestOVSBootstrapParamServer(t *testing.T) {
+	sockets := []string{"/var/run/openvswitch/db.sock"}
+	valid := []string{
+		"tcp:10.0.0.1:6640",
+		"unix:/var/run/openvswitch/db.sock",
+		"unix:/var/run/openvswitch/../openvswitch/db.sock",
+	}
+	for _, v := range valid {
+		param := OVSBootstrapParam{Server: v, Bridge: "br0", Controllers: []string{"tcp:10.0.0.100:6633"}}
+		if err := param.validate(sockets); err != nil {
+			t.Fatalf("unexpected error for %v: %v", v, err)
+		}
+	}
+
+	invalid := []string{
+		"unix:/var/run/docker.sock",
+		"unix:var/run/openvswitch/db.sock",
+		"unix:",
+		"udp:10.0.0.1:6640",
+	}
+	for _, v := range invalid {
+		param := OVSBootstrapParam{Server: v, Bridge: "br0", Controllers: []string{"tcp:10.0.0.100:6633"}}
+		if err := param.validate(sockets); err == nil {
+			t.Fatalf("expected an error for %v", v)
+		}
+	}
+}
+
+func TestShutdownFinalBarrier(t *testing.T) {
+	d, remote, stream, cleanup := newBarrierDevice(t)
+	defer cleanup()
+	d.id = "1"
+	topo := newTopology(&drainDB{switches: map[uint64]bool{}}, clock.Real)
+	d.session.watcher = topo
+	topo.DeviceAdded(d)
+	controller := &Controller{topo: topo, clock: clock.Real}
+
+	// The final barrier is sent even if the flows are kept.
+	done := make(chan struct{})
+	go func() {
+		controller.Shutdown(context.Background(), false)
+		close(done)
+	}()
+	xid := readBarrier(t, stream)
+	select {
+	case <-done:
+		t.Fatal("Shutdown returned before the barrier reply")
+	case <-time.After(100 * time.Millisecond):
+	}
+	reply := []byte{openflow.OF13_VERSION, of13.OFPT_BARRIER_REPLY, 0, 8, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(reply[4:8], xid)
+	writeSwitch(t, remote, reply)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Shutdown did not return")
+	}
+
+	if !d.IsClosed() {
+		t.Fatal("device is not closed")
+	}
+}
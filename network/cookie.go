@@ -0,0 +1,157 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// The cookie of a flow consists of the following fields:
+//
+//	bit 63:      reserved for the special flows such as the table-miss flows.
+//	bits 48-62:  ID of the application that owns the flow, or zero if no application owns it.
+//	bits 0-47:   value defined by the owner.
+//
+// FLOW_REMOVED messages of the flows owned by an application are delivered only to that
+// application. The others are delivered to all the applications.
+const (
+	cookieOwnerShift = 48
+	cookieOwnerMask  = 0x7FFF << cookieOwnerShift
+	cookieValueMask  = 1<<cookieOwnerShift - 1
+)
+
+// cookieOwners is the names of the applications registered by RegisterCookieOwner.
+var cookieOwners = struct {
+	sync.RWMutex
+	// Key is the cookie owner ID.
+	names map[uint16]string
+	// Key is the cookie owner ID of the loaded applications.
+	loaded map[uint16]bool
+}{names: make(map[uint16]string), loaded: make(map[uint16]bool)}
+
+// CookieOwnerID returns the non-zero ID of the application whose name is owner. The ID is
+// derived from the case-insensitive name so that it does not change after the controller restarts.
+func CookieOwnerID(owner string) uint16 {
+	h := fnv.New32a()
+	h.Write([]byte(strings.ToUpper(owner)))
+
+	return uint16(h.Sum32()%0x7FFF) + 1
+}
+
+// NewCookie returns the cookie of a flow owned by the application whose name is owner.
+// Only the lower 48 bits of value are used.
+func NewCookie(owner string, value uint64) uint64 {
+	return uint64(CookieOwnerID(owner))<<cookieOwnerShift | value&cookieValueMask
+}
+
+// CookieOwner returns the ID of the application that owns the flow whose cookie is cookie.
+// It returns false if no application owns the flow.
+func CookieOwner(cookie uint64) (id uint16, ok bool) {
+	id = uint16((cookie & cookieOwnerMask) >> cookieOwnerShift)
+	if id == 0 || cookie&(0x1<<63) != 0 {
+		return 0, false
+	}
+
+	return id, true
+}
+
+// CookieValue returns the value defined by the owner of the flow whose cookie is cookie, e.g.,
+// the ID of the rule that the flow is made from.
+func CookieValue(cookie uint64) uint64 {
+	return cookie & cookieValueMask
+}
+
+// OwnerCookie returns the cookie and the cookie mask of a FLOW_MOD that selects all the flows
+// owned by the application whose name is owner, e.g., to remove them at once.
+func OwnerCookie(owner string) (cookie, mask uint64) {
+	return NewCookie(owner, 0), 0x1<<63 | cookieOwnerMask
+}
+
+// RegisterCookieOwner registers the application whose name is owner so that its flows are
+// attributed to it by name, e.g., in the flow statistics. It returns an error if another
+// application has been registered with the same cookie owner ID. Registering the same
+// application again has no effect.
+func RegisterCookieOwner(owner string) error {
+	id := CookieOwnerID(owner)
+
+	cookieOwners.Lock()
+	defer cookieOwners.Unlock()
+
+	if v, ok := cookieOwners.names[id]; ok && !strings.EqualFold(v, owner) {
+		return fmt.Errorf("duplicated cookie owner ID: %v and %v", v, owner)
+	}
+	cookieOwners.names[id] = owner
+
+	return nil
+}
+
+// LoadCookieOwner marks the application whose name is owner as loaded, i.e., it is running and
+// declares its flows. The saved flows owned by the applications that are not loaded are stale.
+func LoadCookieOwner(owner string) {
+	cookieOwners.Lock()
+	defer cookieOwners.Unlock()
+
+	cookieOwners.loaded[CookieOwnerID(owner)] = true
+}
+
+func isLoadedCookieOwner(id uint16) bool {
+	cookieOwners.RLock()
+	defer cookieOwners.RUnlock()
+
+	return cookieOwners.loaded[id]
+}
+
+// CookieOwnerName returns the name of the application that has been registered with the cookie
+// owner ID id. It returns false if no application has been registered with the ID.
+func CookieOwnerName(id uint16) (name string, ok bool) {
+	cookieOwners.RLock()
+	defer cookieOwners.RUnlock()
+
+	name, ok = cookieOwners.names[id]
+
+	return name, ok
+}
+
+// cookieOwnerName returns the name of the application that owns the flow whose cookie is
+// cookie, or its ID if the application has not been registered. It returns an empty string
+// if no application owns the flow.
+func cookieOwnerName(cookie uint64) string {
+	id, ok := CookieOwner(cookie)
+	if !ok {
+		return ""
+	}
+	if name, ok := CookieOwnerName(id); ok {
+		return name
+	}
+
+	return strconv.Itoa(int(id))
+}
+
+// matchCookie returns whether cookie is selected by a FLOW_MOD whose cookie is filter and
+// cookie mask is mask, i.e., they have the same bits set in mask.
+func matchCookie(cookie, filter, mask uint64) bool {
+	return cookie&mask == filter&mask
+}
@@ -0,0 +1,95 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCookieOwner(t *testing.T) {
+	cookie := NewCookie("L2Switch", 0xFFFF123456789ABC)
+	if cookie&cookieValueMask != 0x123456789ABC {
+		t.Fatalf("unexpected cookie value: 0x%x", cookie)
+	}
+	if cookie&(0x1<<63) != 0 {
+		t.Fatalf("reserved bit is set: 0x%x", cookie)
+	}
+	// The owner name is case-insensitive.
+	id, ok := CookieOwner(cookie)
+	if !ok || id != CookieOwnerID("L2SWITCH") {
+		t.Fatalf("unexpected owner: ok=%v, id=%v", ok, id)
+	}
+
+	if _, ok := CookieOwner(0x1234); ok {
+		t.Fatal("flow without any owner bit should not have the owner")
+	}
+	// Table-miss flows
+	if _, ok := CookieOwner(0x1<<63 | cookie); ok {
+		t.Fatal("special flow should not have the owner")
+	}
+}
+
+func TestOwnerCookie(t *testing.T) {
+	cookie, mask := OwnerCookie("Firewall")
+	if !matchCookie(NewCookie("FIREWALL", 7), cookie, mask) {
+		t.Fatal("flow of the owner should be selected")
+	}
+	if matchCookie(NewCookie("Router", 7), cookie, mask) {
+		t.Fatal("flow of another owner should not be selected")
+	}
+	if matchCookie(0x1<<63|NewCookie("Firewall", 7), cookie, mask) {
+		t.Fatal("special flow should not be selected")
+	}
+	if v := CookieValue(NewCookie("Firewall", 7)); v != 7 {
+		t.Fatalf("unexpected cookie value: %v", v)
+	}
+}
+
+func TestRegisterCookieOwner(t *testing.T) {
+	if err := RegisterCookieOwner("CookieTestApp"); err != nil {
+		t.Fatal(err)
+	}
+	// Registering again, even in a different case, is allowed.
+	if err := RegisterCookieOwner("COOKIETESTAPP"); err != nil {
+		t.Fatal(err)
+	}
+	if v := cookieOwnerName(NewCookie("CookieTestApp", 1)); v != "COOKIETESTAPP" {
+		t.Fatalf("unexpected owner name: %v", v)
+	}
+	if v := cookieOwnerName(0x1234); v != "" {
+		t.Fatalf("unexpected owner name of a flow without any owner: %v", v)
+	}
+
+	// Find another name that has the same ID.
+	id := CookieOwnerID("CookieTestApp")
+	for i := 0; ; i++ {
+		name := fmt.Sprintf("CookieTestApp%v", i)
+		if CookieOwnerID(name) != id {
+			continue
+		}
+		if err := RegisterCookieOwner(name); err == nil {
+			t.Fatalf("expected an error for the duplicated ID of %v", name)
+		}
+		break
+	}
+}
@@ -22,6 +22,7 @@
 package network
 
 import (
+	"context"
 	"encoding"
 	"errors"
 	"fmt"
@@ -29,7 +30,9 @@ import (
 	"sync"
 	"time"
 
+	"github.com/superkkt/cherry/clock"
 	"github.com/superkkt/cherry/openflow"
+	"github.com/superkkt/cherry/openflow/of13"
 	"github.com/superkkt/cherry/openflow/transceiver"
 	"github.com/superkkt/cherry/protocol"
 
@@ -62,6 +65,40 @@ type Device struct {
 	closed       bool
 	flowCache    *flowCache
 	vlanID       uint16
+	clock        clock.Clock
+	flowStats    []openflow.FlowStats
+	// Time when the flow statistics were last refreshed.
+	flowStatsTime time.Time
+	// Shadow copy of the permanent flows installed on this device. nil until the device is ready.
+	shadowFlows *flowTable
+	// Our role confirmed by the device and its generation ID.
+	role         openflow.ControllerRole
+	generationID uint64
+	// True if the device has been initialized while we were a SLAVE, so that the special
+	// flows, e.g., the table-miss flows, have not been installed by us.
+	readOnly bool
+	// Capabilities of the flow tables reported by the device. nil if they are unknown.
+	tableFeatures []openflow.TableFeatures
+	// Table that classifies the packets before the flow table. -1 if there is no such table.
+	classifierTableID int16
+	queueStats        []openflow.QueueStats
+	// Time when the queue statistics were last refreshed.
+	queueStatsTime time.Time
+	// Auxiliary connections of this device, and the index of the next one for PACKET_OUT.
+	auxSessions []*session
+	auxNext     int
+	// Flows installed by the applications, which are checked for the conflicts among them.
+	conflicts *flowRegistry
+	// Flows installed with their lifetimes by InstallFlowWithLifetime.
+	lifetimes *flowLifetimes
+	// Groups created on this device, which are referenced by the flows and other groups.
+	groups *groupTable
+	// Flow priority bands reserved for the applications.
+	priorities *priorityAllocator
+	// Slices that confine the flows of their applications.
+	slices *sliceIndex
+	// Probe packets of the running self-test. nil if no self-test is running.
+	selfTestProbe chan []byte
 }
 
 var (
@@ -82,8 +119,17 @@ func newDevice(s *session) *Device {
 	return &Device{
 		session:   s,
 		ports:     make(map[uint32]*Port),
-		flowCache: newFlowCache(5 * time.Second),
+		flowCache: newFlowCache(5*time.Second, s.clock),
 		vlanID:    uint16(vlanID),
+		clock:     s.clock,
+		role:      openflow.RoleEqual,
+		// No classifier table until the table-miss flows are installed.
+		classifierTableID: -1,
+		conflicts:         newFlowRegistry(s.flowConflict, s.clock),
+		lifetimes:         newFlowLifetimes(s.clock),
+		groups:            newGroupTable(),
+		priorities:        s.priorities,
+		slices:            s.slices,
 	}
 }
 
@@ -116,6 +162,14 @@ func (r *Device) setID(id string) {
 	r.id = id
 }
 
+func (r *Device) setShadowFlows(t *flowTable) {
+	// Write lock
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.shadowFlows = t
+}
+
 func (r *Device) isReady() bool {
 	// Read lock
 	r.mutex.RLock()
@@ -143,6 +197,24 @@ func (r *Device) setFactory(f openflow.Factory) {
 	r.factory = f
 }
 
+// RTT returns the round-trip time of the control channel to this device, or 0 if it has not been measured yet.
+func (r *Device) RTT() time.Duration {
+	// Read lock
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return r.session.RTT()
+}
+
+// RemoteAddr returns the address of the device's main connection.
+func (r *Device) RemoteAddr() net.Addr {
+	// Read lock
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return r.session.remoteAddr
+}
+
 func (r *Device) Writer() transceiver.Writer {
 	// Read lock
 	r.mutex.RLock()
@@ -156,116 +228,1068 @@ func (r *Device) Descriptions() Descriptions {
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
 
-	return r.descriptions
+	return r.descriptions
+}
+
+func (r *Device) setDescriptions(d Descriptions) {
+	// Write lock
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.descriptions = d
+}
+
+func (r *Device) Features() Features {
+	// Read lock
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return r.features
+}
+
+func (r *Device) setFeatures(f Features) {
+	// Write lock
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.features = f
+}
+
+// Port may return nil if there is no port whose number is num
+func (r *Device) Port(num uint32) *Port {
+	// Read lock
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return r.ports[num]
+}
+
+func (r *Device) Ports() []*Port {
+	// Read lock
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	p := make([]*Port, 0)
+	for _, v := range r.ports {
+		p = append(p, v)
+	}
+
+	return p
+}
+
+func (r *Device) setPort(num uint32, p openflow.Port) {
+	// Write lock
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if p == nil {
+		panic("Port is nil")
+	}
+	logger.Debugf("Device=%v, PortNum=%v, AdminUp=%v, LinkUp=%v", r.id, p.Number(), !p.IsPortDown(), !p.IsLinkDown())
+
+	port, ok := r.ports[num]
+	if ok {
+		port.SetValue(p)
+	} else {
+		v := NewPort(r, num)
+		v.SetValue(p)
+		r.ports[num] = v
+	}
+}
+
+// removePort removes the port whose number is num from the port list, and returns it.
+// It returns nil if there is no such port.
+func (r *Device) removePort(num uint32) *Port {
+	// Write lock
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	port, ok := r.ports[num]
+	if !ok {
+		return nil
+	}
+	delete(r.ports, num)
+
+	return port
+}
+
+func (r *Device) FlowTableID() uint8 {
+	// Read lock
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return r.flowTableID
+}
+
+// PriorityBand returns the lowest and highest priorities of the flows that the application
+// whose name is owner can install in the table whose ID is tableID. It returns false if no
+// band is reserved for the application, where its flows can have any priority that is not
+// reserved for other applications.
+func (r *Device) PriorityBand(owner string, tableID uint8) (min, max uint16, ok bool) {
+	return r.priorities.Band(owner, tableID)
+}
+
+// FlowPriority returns the priority of a flow that the application whose name is owner installs
+// in the table whose ID is tableID. offset is the rank of the flow among the flows of the
+// application, where 0 is the lowest. The rank is counted from the lowest priority of the band of
+// the application, or from base if no band is reserved for the application. It returns
+// ErrPriorityOutOfBand if the priority is beyond the band.
+func (r *Device) FlowPriority(owner string, tableID uint8, base, offset uint16) (uint16, error) {
+	return r.priorities.Priority(owner, tableID, base, offset)
+}
+
+func (r *Device) setFlowTableID(id uint8) {
+	// Write lock
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.flowTableID = id
+}
+
+// ClassifierTableID returns the ID of the table whose flows classify the packets, e.g., remark
+// their DSCP, before they are forwarded by the flows of FlowTableID. A flow of the classifier table
+// should continue processing the packets on the flow table. ok is false if the device has no
+// classifier table.
+func (r *Device) ClassifierTableID() (ok bool, id uint8) {
+	// Read lock
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if r.classifierTableID == -1 {
+		return false, 0
+	}
+
+	return true, uint8(r.classifierTableID)
+}
+
+func (r *Device) setClassifierTableID(id uint8) {
+	// Write lock
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.classifierTableID = int16(id)
+}
+
+// TableFeatures returns the capabilities of the flow tables reported by the device. It returns
+// nil if the device does not support the table features, e.g., OpenFlow 1.0 devices.
+func (r *Device) TableFeatures() []openflow.TableFeatures {
+	// Read lock
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return r.tableFeatures
+}
+
+func (r *Device) setTableFeatures(tables []openflow.TableFeatures) {
+	// Write lock
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.tableFeatures = tables
+}
+
+// ValidateFlow returns an error if flow cannot be installed on the device according to its table
+// features. It always returns nil if the table features of the device are unknown.
+func (r *Device) ValidateFlow(flow openflow.FlowMod) error {
+	tables := r.TableFeatures()
+	if tables == nil {
+		return nil
+	}
+
+	return of13.ValidateFlowMod(tables, flow)
+}
+
+// FlowStats returns the flow statistics most recently collected from the
+// device and the time when they were collected. The statistics are refreshed
+// every flowStatsInterval.
+func (r *Device) FlowStats() (stats []openflow.FlowStats, updated time.Time) {
+	// Read lock
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	stats = make([]openflow.FlowStats, len(r.flowStats))
+	copy(stats, r.flowStats)
+
+	return stats, r.flowStatsTime
+}
+
+func (r *Device) setFlowStats(stats []openflow.FlowStats) {
+	// Write lock
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.flowStats = stats
+	r.flowStatsTime = r.clock.Now()
+}
+
+// QueueStats returns the statistics of the egress queues most recently collected from the
+// device and the time when they were collected. The statistics are refreshed together with
+// the port statistics.
+func (r *Device) QueueStats() (stats []openflow.QueueStats, updated time.Time) {
+	// Read lock
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	stats = make([]openflow.QueueStats, len(r.queueStats))
+	copy(stats, r.queueStats)
+
+	return stats, r.queueStatsTime
+}
+
+func (r *Device) setQueueStats(stats []openflow.QueueStats) {
+	// Write lock
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.queueStats = stats
+	r.queueStatsTime = r.clock.Now()
+}
+
+// QueueConfig queries the device for the egress queues configured on the port whose number is port.
+func (r *Device) QueueConfig(ctx context.Context, port uint32) ([]openflow.Queue, error) {
+	// NOTE: Do not hold the lock while waiting for the reply. Otherwise, other goroutines
+	// cannot send any message to this device until the reply arrives.
+	session, req, err := func() (*session, openflow.QueueGetConfigRequest, error) {
+		// Read lock
+		r.mutex.RLock()
+		defer r.mutex.RUnlock()
+
+		if r.closed {
+			return nil, nil, ErrClosedDevice
+		}
+		req, err := r.factory.NewQueueGetConfigRequest()
+		if err != nil {
+			return nil, nil, err
+		}
+		p := openflow.NewOutPort()
+		p.SetValue(port)
+		req.SetPort(p)
+
+		return r.session, req, nil
+	}()
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := session.SendAndWait(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query the queue configuration: %v", err)
+	}
+	config, ok := reply.(openflow.QueueGetConfigReply)
+	if !ok {
+		return nil, fmt.Errorf("unexpected reply for the queue configuration: %T", reply)
+	}
+
+	return config.Queue(), nil
+}
+
+func (r *Device) setPortStats(stats []openflow.PortStats) {
+	now := r.clock.Now()
+	for _, v := range stats {
+		// Skip the stats of the ports that we don't know yet, e.g., OFPP_LOCAL.
+		p := r.Port(v.PortNumber)
+		if p == nil {
+			continue
+		}
+		p.setStats(v, now)
+	}
+}
+
+// Role returns our role confirmed by the device and its generation ID. The
+// default role is EQUAL.
+func (r *Device) Role() (role openflow.ControllerRole, generationID uint64) {
+	// Read lock
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return r.role, r.generationID
+}
+
+func (r *Device) isReadOnly() bool {
+	// Read lock
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return r.readOnly
+}
+
+func (r *Device) setReadOnly(readOnly bool) {
+	// Write lock
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.readOnly = readOnly
+}
+
+func (r *Device) setRole(role openflow.ControllerRole, generationID uint64) {
+	// Write lock
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.role = role
+	if role == openflow.RoleMaster || role == openflow.RoleSlave {
+		r.generationID = generationID
+	}
+}
+
+// SetRole requests the device to change our role. generationID is only used
+// by the MASTER and SLAVE roles, and it should be increased whenever the master
+// controller changes. Role() will return the new role after the device replies.
+func (r *Device) SetRole(role openflow.ControllerRole, generationID uint64) error {
+	// Write lock
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.closed {
+		return ErrClosedDevice
+	}
+	if (role == openflow.RoleMaster || role == openflow.RoleSlave) && openflow.IsStaleGenerationID(generationID, r.generationID) {
+		return fmt.Errorf("stale generation ID: last=%v, requested=%v", r.generationID, generationID)
+	}
+
+	msg, err := r.factory.NewRoleRequest()
+	if err != nil {
+		return err
+	}
+	msg.SetRole(role)
+	msg.SetGenerationID(generationID)
+	if err := msg.Error(); err != nil {
+		return err
+	}
+
+	return r.session.Write(msg)
+}
+
+// Barrier sends a BARRIER_REQUEST and blocks until its reply arrives, so that the caller
+// can make sure the device has processed all the messages sent before the barrier.
+//
+// Barrier should not be called while handling an event raised by this device, e.g.,
+// OnPacketIn, because the reply is dispatched by the goroutine that raises the events.
+func (r *Device) Barrier(ctx context.Context) error {
+	// NOTE: Do not hold the lock while waiting for the reply. Otherwise, other goroutines
+	// cannot send any message to this device until the reply arrives.
+	session, barrier, err := func() (*session, openflow.BarrierRequest, error) {
+		// Read lock
+		r.mutex.RLock()
+		defer r.mutex.RUnlock()
+
+		if r.closed {
+			return nil, nil, ErrClosedDevice
+		}
+		barrier, err := r.factory.NewBarrierRequest()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return r.session, barrier, nil
+	}()
+	if err != nil {
+		return err
+	}
+
+	if _, err := session.SendAndWait(ctx, barrier); err != nil {
+		return fmt.Errorf("failed to wait for the barrier reply: %v", err)
+	}
+
+	return nil
+}
+
+// InstallFlowAndWait is same as InstallFlow except that it waits until the device finishes
+// processing flow. The returned error wraps one of the openflow.Err* causes, such as
+// openflow.ErrTableFull, if the device rejects flow.
+func (r *Device) InstallFlowAndWait(ctx context.Context, flow openflow.FlowMod) error {
+	// NOTE: Do not hold the lock while waiting for the reply. Otherwise, other goroutines
+	// cannot send any message to this device until the reply arrives.
+	session, err := func() (*session, error) {
+		// Read lock
+		r.mutex.RLock()
+		defer r.mutex.RUnlock()
+
+		if r.closed {
+			return nil, ErrClosedDevice
+		}
+		if err := r.validateFlow(flow); err != nil {
+			return nil, err
+		}
+
+		return r.session, nil
+	}()
+	if err != nil {
+		return err
+	}
+
+	if err := session.SendAndConfirm(ctx, flow); err != nil {
+		r.conflicts.Forget(flow)
+		return err
+	}
+
+	// Write lock
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.shadowFlows != nil {
+		if err := r.shadowFlows.Update(flow); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// InstallFlows installs all the flows in flows and waits until the device finishes processing
+// them. The flows are committed atomically in a bundle if the device supports OpenFlow 1.4.
+// Otherwise, they are sent as a barrier-delimited sequence and the flows accepted by the device
+// remain installed even if others are rejected. The returned error is same as the one of
+// InstallFlowAndWait.
+func (r *Device) InstallFlows(ctx context.Context, flows []openflow.FlowMod) error {
+	// NOTE: This is an anonymous function (NOT a goroutine!) that has a critical section.
+	session, err := func() (*session, error) {
+		// Read lock
+		r.mutex.RLock()
+		defer r.mutex.RUnlock()
+
+		if r.closed {
+			return nil, ErrClosedDevice
+		}
+		for i, flow := range flows {
+			if err := r.validateFlow(flow); err != nil {
+				// None of the flows is sent.
+				for _, v := range flows[:i] {
+					r.conflicts.Forget(v)
+				}
+				return nil, err
+			}
+		}
+
+		return r.session, nil
+	}()
+	if err != nil {
+		return err
+	}
+
+	reqs := make([]transceiver.Request, len(flows))
+	for i, flow := range flows {
+		reqs[i] = flow
+	}
+	// NOTE: The shadow flows are not updated on error because we do not know which flows
+	// have been installed by the barrier-delimited sequence.
+	if err := session.SendBundle(ctx, reqs); err != nil {
+		return err
+	}
+
+	// Write lock
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.shadowFlows != nil {
+		for _, flow := range flows {
+			if err := r.shadowFlows.Update(flow); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (r *Device) SendMessage(msg encoding.BinaryMarshaler) error {
+	// Write lock
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if msg == nil {
+		panic("Message is nil")
+	}
+	if r.closed {
+		return ErrClosedDevice
+	}
+
+	if _, ok := msg.(openflow.PacketOut); ok {
+		return r.packetSession().Write(msg)
+	}
+
+	return r.session.Write(msg)
+}
+
+// packetSession returns the session that PACKET_OUT messages are sent over according to
+// the auxiliary channel policy.
+// XXX: Caller should lock the mutex before they call this function.
+func (r *Device) packetSession() *session {
+	if r.session.auxPolicy != auxPolicyPacket || len(r.auxSessions) == 0 {
+		return r.session
+	}
+	r.auxNext = (r.auxNext + 1) % len(r.auxSessions)
+
+	return r.auxSessions[r.auxNext]
+}
+
+func (r *Device) addAuxSession(s *session) {
+	// Write lock
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.auxSessions = append(r.auxSessions, s)
+}
+
+func (r *Device) removeAuxSession(s *session) {
+	// Write lock
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for i, v := range r.auxSessions {
+		if v == s {
+			r.auxSessions = append(r.auxSessions[:i], r.auxSessions[i+1:]...)
+			return
+		}
+	}
+}
+
+// disconnectAux closes all the auxiliary connections of this device.
+func (r *Device) disconnectAux() {
+	// NOTE: This is an anonymous function (NOT a goroutine!) that has a critical section.
+	sessions := func() []*session {
+		// Read lock
+		r.mutex.RLock()
+		defer r.mutex.RUnlock()
+
+		v := make([]*session, len(r.auxSessions))
+		copy(v, r.auxSessions)
+
+		return v
+	}()
+
+	for _, s := range sessions {
+		if err := s.transceiver.Close(); err != nil {
+			logger.Errorf("failed to close the auxiliary connection of %v: %v", r.ID(), err)
+		}
+	}
+}
+
+// SendPacketOut sends a PACKET_OUT message that applies action to a packet. The packet
+// is the one buffered in the switch if bufferID, which should be delivered by PACKET_IN,
+// is not openflow.NoBuffer. Otherwise, the packet is data. inPort is the ingress port
+// of the packet, or the controller port if the controller originates the packet.
+func (r *Device) SendPacketOut(inPort openflow.InPort, action openflow.Action, bufferID uint32, data []byte) error {
+	// Write lock
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if action == nil {
+		panic("Action is nil")
+	}
+	if r.closed {
+		return ErrClosedDevice
+	}
+	if bufferID == openflow.NoBuffer && len(data) == 0 {
+		return errors.New("empty packet data without a buffered packet")
+	}
+
+	out, err := r.factory.NewPacketOut()
+	if err != nil {
+		return err
+	}
+	out.SetInPort(inPort)
+	out.SetAction(action)
+	out.SetBufferID(bufferID)
+	if bufferID == openflow.NoBuffer {
+		out.SetData(data)
+	}
+
+	return r.packetSession().Write(out)
+}
+
+func (r *Device) IsClosed() bool {
+	// Read lock
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return r.closed
+}
+
+// SetFlow installs a normal flow entry for packet switching and routing into the switch device.
+// owner is the name of the application that owns the flow.
+func (r *Device) SetFlow(owner string, match openflow.Match, port openflow.OutPort) error {
+	// Write lock
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.closed {
+		return ErrClosedDevice
+	}
+
+	// For valid (non-overlapping) ADD requests, or those with no overlap checking,
+	// the switch must insert the flow entry at the lowest numbered table for which
+	// the switch supports all wildcards set in the flow_match struct, and for which
+	// the priority would be observed during the matching process. If a flow entry
+	// with identical header fields and priority already resides in any table, then
+	// that entry, including its counters, must be removed, and the new flow entry added.
+	flow, err := r.newFlowMod(openflow.FlowAdd, match, port)
+	if err != nil {
+		return err
+	}
+	flow.SetCookie(NewCookie(owner, 0))
+	if err := r.setOwnerPriority(owner, flow); err != nil {
+		return err
+	}
+	if err := r.slices.Check(r.id, flow); err != nil {
+		return err
+	}
+
+	ok, err := r.flowCache.InProgress(match, port)
+	if err != nil {
+		return err
+	}
+	if ok {
+		logger.Debugf("skip to install a new flow: already installed one: deviceID=%v", r.id)
+		return nil
+	}
+	// Install the new flow.
+	if err := r.session.Write(flow); err != nil {
+		return err
+	}
+	if err := r.flowCache.Add(match, port); err != nil {
+		return err
+	}
+
+	barrier, err := r.factory.NewBarrierRequest()
+	if err != nil {
+		return err
+	}
+
+	return r.session.Write(barrier)
+}
+
+// SetGroupFlow installs a normal flow entry like SetFlow, but the packets matched with match
+// are forwarded to the group whose ID is group instead of a port.
+func (r *Device) SetGroupFlow(owner string, match openflow.Match, group uint32) error {
+	// Write lock
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.closed {
+		return ErrClosedDevice
+	}
+
+	action, err := r.factory.NewAction()
+	if err != nil {
+		return err
+	}
+	action.SetGroup(group)
+	flow, err := r.newNormalFlowMod(openflow.FlowAdd, match, action)
+	if err != nil {
+		return err
+	}
+	flow.SetCookie(NewCookie(owner, 0))
+	if err := r.setOwnerPriority(owner, flow); err != nil {
+		return err
+	}
+	if err := r.groups.CheckFlow(flow); err != nil {
+		return err
+	}
+	if err := r.slices.Check(r.id, flow); err != nil {
+		return err
+	}
+
+	target := fmt.Sprintf("group:%v", group)
+	ok, err := r.flowCache.InProgress(match, target)
+	if err != nil {
+		return err
+	}
+	if ok {
+		logger.Debugf("skip to install a new flow: already installed one: deviceID=%v", r.id)
+		return nil
+	}
+	if err := r.session.Write(flow); err != nil {
+		return err
+	}
+	if err := r.flowCache.Add(match, target); err != nil {
+		return err
+	}
+
+	barrier, err := r.factory.NewBarrierRequest()
+	if err != nil {
+		return err
+	}
+
+	return r.session.Write(barrier)
+}
+
+// SupportsGroups returns whether the device can forward packets to groups. OpenFlow 1.0
+// devices and the devices whose flow table does not support the group action cannot.
+func (r *Device) SupportsGroups() bool {
+	// Read lock
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if r.factory == nil || r.factory.ProtocolVersion() < openflow.OF13_VERSION {
+		return false
+	}
+	for _, t := range r.tableFeatures {
+		if t.TableID != r.flowTableID {
+			continue
+		}
+		// The switch has not reported the actions?
+		if t.ApplyActions == nil {
+			return true
+		}
+		for _, v := range t.ApplyActions {
+			if v == of13.OFPAT_GROUP {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+// Priority of the normal flow entries made by SetFlow and SetGroupFlow for the applications
+// that have no priority band.
+const normalFlowPriority = 10
+
+// setOwnerPriority sets the priority of the normal flow entry owned by the application whose
+// name is owner according to the priority band of the application.
+// XXX: Caller should lock the mutex
+func (r *Device) setOwnerPriority(owner string, flow openflow.FlowMod) error {
+	priority, err := r.priorities.Priority(owner, flow.TableID(), normalFlowPriority, 0)
+	if err != nil {
+		return err
+	}
+	flow.SetPriority(priority)
+
+	return nil
+}
+
+// newFlowMod returns a FLOW_MOD message of cmd for a normal flow entry that sends
+// the packets matched with match to port. Caller should lock the mutex before they
+// call this function.
+func (r *Device) newFlowMod(cmd openflow.FlowModCmd, match openflow.Match, port openflow.OutPort) (openflow.FlowMod, error) {
+	action, err := r.factory.NewAction()
+	if err != nil {
+		return nil, err
+	}
+	action.SetOutPort(port)
+
+	return r.newNormalFlowMod(cmd, match, action)
+}
+
+// newNormalFlowMod returns a FLOW_MOD message of cmd for a normal flow entry that applies
+// action to the packets matched with match. Caller should lock the mutex before they call
+// this function.
+func (r *Device) newNormalFlowMod(cmd openflow.FlowModCmd, match openflow.Match, action openflow.Action) (openflow.FlowMod, error) {
+	// Set the default VLAN ID. It is necessary to use the L2 MAC flow table of Dell SXXX switches.
+	match.SetVLANID(r.vlanID)
+
+	inst, err := r.factory.NewInstruction()
+	if err != nil {
+		return nil, err
+	}
+	inst.ApplyAction(action)
+
+	flow, err := r.factory.NewFlowMod(cmd)
+	if err != nil {
+		return nil, err
+	}
+	flow.SetTableID(r.flowTableID)
+	// This idle timeout is actually useless because we update the installed flows
+	// more frequently than this timeout.
+	flow.SetIdleTimeout(90)
+	flow.SetPriority(normalFlowPriority)
+	flow.SetFlowMatch(match)
+	flow.SetFlowInstruction(inst)
+
+	return flow, nil
 }
 
-func (r *Device) setDescriptions(d Descriptions) {
+// TouchFlow modifies the normal flow entry, which has been installed by SetFlow for owner with
+// the same match and port, without changing its actions. Unlike SetFlow, the packet and byte
+// counters of the flow are not reset. Note that an OpenFlow 1.3 switch does nothing if there is
+// no such flow, and whether the timeouts of the flow are restarted depends on the switch
+// implementation: the OpenFlow specification leaves the timeouts of a modified flow unchanged,
+// but many switches restart them. VerifyTouchFlow tells which one the switch does.
+func (r *Device) TouchFlow(owner string, match openflow.Match, port openflow.OutPort) error {
 	// Write lock
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
-	r.descriptions = d
-}
+	if r.closed {
+		return ErrClosedDevice
+	}
 
-func (r *Device) Features() Features {
-	// Read lock
-	r.mutex.RLock()
-	defer r.mutex.RUnlock()
+	flow, err := r.newTouchFlowMod(owner, match, port)
+	if err != nil {
+		return err
+	}
 
-	return r.features
+	return r.session.Write(flow)
 }
 
-func (r *Device) setFeatures(f Features) {
-	// Write lock
-	r.mutex.Lock()
-	defer r.mutex.Unlock()
+// newTouchFlowMod returns the FLOW_MOD message of TouchFlow. Caller should lock the mutex
+// before they call this function.
+func (r *Device) newTouchFlowMod(owner string, match openflow.Match, port openflow.OutPort) (openflow.FlowMod, error) {
+	// MODIFY_STRICT without the OFPFF_RESET_COUNTS flag keeps the counters.
+	flow, err := r.newFlowMod(openflow.FlowModifyStrict, match, port)
+	if err != nil {
+		return nil, err
+	}
+	// The cookie and priority should be the same as SetFlow's to modify its flow.
+	flow.SetCookie(NewCookie(owner, 0))
+	if err := r.setOwnerPriority(owner, flow); err != nil {
+		return nil, err
+	}
 
-	r.features = f
+	return flow, nil
 }
 
-// Port may return nil if there is no port whose number is num
-func (r *Device) Port(num uint32) *Port {
-	// Read lock
-	r.mutex.RLock()
-	defer r.mutex.RUnlock()
+// TouchReport is the result of VerifyTouchFlow.
+type TouchReport struct {
+	// Durations of the flow in seconds before and after it is touched.
+	Before, After uint32
+	// True if the switch has restarted the duration of the flow, which means that it has
+	// replaced the flow instead of modifying it, so the timeouts are restarted too.
+	DurationReset bool
+	// True if the switch has reset the packet and byte counters of the flow.
+	CountersReset bool
+}
 
-	return r.ports[num]
+// compareTouchStats returns the report of a flow whose statistics were before and after
+// before and after it is touched.
+func compareTouchStats(before, after openflow.FlowStats) TouchReport {
+	return TouchReport{
+		Before:        before.DurationSec,
+		After:         after.DurationSec,
+		DurationReset: after.DurationSec < before.DurationSec,
+		CountersReset: after.PacketCount < before.PacketCount || after.ByteCount < before.ByteCount,
+	}
 }
 
-func (r *Device) Ports() []*Port {
-	// Read lock
-	r.mutex.RLock()
-	defer r.mutex.RUnlock()
+// VerifyTouchFlow touches the flow like TouchFlow, and reports how the switch has handled it by
+// comparing the flow statistics queried before and after the flow is touched. The flow should
+// be older than a second because the duration is compared in seconds. It waits for the replies
+// of the device, so it should not be called by the goroutine that handles the messages of the
+// device, e.g., in OnPacketIn when the PACKET_IN workers are disabled.
+func (r *Device) VerifyTouchFlow(ctx context.Context, owner string, match openflow.Match, port openflow.OutPort) (TouchReport, error) {
+	// NOTE: Do not hold the lock while waiting for the replies.
+	session, flow, req, err := func() (*session, openflow.FlowMod, openflow.FlowStatsRequest, error) {
+		// Write lock
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+
+		if r.closed {
+			return nil, nil, nil, ErrClosedDevice
+		}
+		flow, err := r.newTouchFlowMod(owner, match, port)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		req, err := r.factory.NewFlowStatsRequest()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if r.factory.ProtocolVersion() == openflow.OF10_VERSION {
+			// The device has chosen the table.
+			req.SetTableID(0xFF)
+		} else {
+			req.SetTableID(flow.TableID())
+		}
+		req.SetCookie(flow.Cookie())
+		req.SetCookieMask(0xFFFFFFFFFFFFFFFF)
+		req.SetMatch(flow.FlowMatch())
+		if err := req.Error(); err != nil {
+			return nil, nil, nil, err
+		}
+
+		return r.session, flow, req, nil
+	}()
+	if err != nil {
+		return TouchReport{}, err
+	}
 
-	p := make([]*Port, 0)
-	for _, v := range r.ports {
-		p = append(p, v)
+	before, err := queryTouchedFlow(ctx, session, req, flow)
+	if err != nil {
+		return TouchReport{}, err
+	}
+	if before.DurationSec == 0 {
+		return TouchReport{}, errors.New("the flow is too young to verify")
+	}
+	if err := session.SendAndConfirm(ctx, flow); err != nil {
+		return TouchReport{}, fmt.Errorf("failed to touch the flow: %v", err)
+	}
+	after, err := queryTouchedFlow(ctx, session, req, flow)
+	if err != nil {
+		return TouchReport{}, err
 	}
 
-	return p
+	return compareTouchStats(before, after), nil
 }
 
-func (r *Device) setPort(num uint32, p openflow.Port) {
+// queryTouchedFlow returns the statistics of flow queried by req.
+func queryTouchedFlow(ctx context.Context, session *session, req openflow.FlowStatsRequest, flow openflow.FlowMod) (openflow.FlowStats, error) {
+	reply, err := session.SendAndWait(ctx, req)
+	if err != nil {
+		return openflow.FlowStats{}, fmt.Errorf("failed to query the flow statistics: %v", err)
+	}
+	v, ok := reply.(openflow.FlowStatsReply)
+	if !ok {
+		return openflow.FlowStats{}, fmt.Errorf("unexpected reply for the flow statistics: %T", reply)
+	}
+	for _, s := range v.FlowStats() {
+		// OpenFlow 1.0 devices do not filter the flows by the cookie.
+		if s.Cookie == flow.Cookie() && s.Priority == flow.Priority() {
+			return s, nil
+		}
+	}
+
+	return openflow.FlowStats{}, errors.New("the flow is not found in the flow statistics")
+}
+
+// InstallFlow sends flow, which should be made by the factory of this device, to
+// the switch followed by a barrier request. Unlike SetFlow, the caller decides all
+// the fields of the flow, such as the priority, timeouts, cookie and buffer ID.
+// It returns ErrUnknownGroup if flow forwards the packets to a group that has not
+// been created by AddGroup.
+func (r *Device) InstallFlow(flow openflow.FlowMod) error {
 	// Write lock
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
-	if p == nil {
-		panic("Port is nil")
+	if r.closed {
+		return ErrClosedDevice
 	}
-	logger.Debugf("Device=%v, PortNum=%v, AdminUp=%v, LinkUp=%v", r.id, p.Number(), !p.IsPortDown(), !p.IsLinkDown())
-
-	port, ok := r.ports[num]
-	if ok {
-		port.SetValue(p)
-	} else {
-		v := NewPort(r, num)
-		v.SetValue(p)
-		r.ports[num] = v
+	if err := r.validateFlow(flow); err != nil {
+		return err
 	}
-}
 
-func (r *Device) FlowTableID() uint8 {
-	// Read lock
-	r.mutex.RLock()
-	defer r.mutex.RUnlock()
+	if err := r.session.Write(flow); err != nil {
+		r.conflicts.Forget(flow)
+		return err
+	}
+	if r.shadowFlows != nil {
+		if err := r.shadowFlows.Update(flow); err != nil {
+			return err
+		}
+	}
+	barrier, err := r.factory.NewBarrierRequest()
+	if err != nil {
+		return err
+	}
 
-	return r.flowTableID
+	return r.session.Write(barrier)
 }
 
-func (r *Device) setFlowTableID(id uint8) {
-	// Write lock
-	r.mutex.Lock()
-	defer r.mutex.Unlock()
+// validateFlow returns an error if flow cannot be sent to the device. The conflict policy may
+// adjust the priority of flow and register it, so the caller should call r.conflicts.Forget if
+// flow is not sent after validateFlow succeeds.
+// XXX: Caller should lock the mutex
+func (r *Device) validateFlow(flow openflow.FlowMod) error {
+	if flow == nil {
+		return errors.New("nil flow")
+	}
+	if flow.Version() != r.factory.ProtocolVersion() {
+		return fmt.Errorf("mis-matched flow version: device=%v, flow=%v", r.factory.ProtocolVersion(), flow.Version())
+	}
+	if err := r.groups.CheckFlow(flow); err != nil {
+		return err
+	}
+	if err := r.slices.Check(r.id, flow); err != nil {
+		return err
+	}
+	if err := r.conflicts.Check(flow); err != nil {
+		return err
+	}
+	// Checked after the priority has been adjusted by the conflict policy.
+	if err := r.priorities.Check(flow); err != nil {
+		r.conflicts.Forget(flow)
+		return err
+	}
 
-	r.flowTableID = id
+	return nil
 }
 
-func (r *Device) SendMessage(msg encoding.BinaryMarshaler) error {
+// reconcileFlows compares the flows in stats, which have been collected from the device, with the
+// shadow copy. The flows lost by the device are reinstalled, and the unknown permanent flows are removed.
+func (r *Device) reconcileFlows(stats []openflow.FlowStats) error {
 	// Write lock
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
-	if msg == nil {
-		panic("Message is nil")
-	}
 	if r.closed {
 		return ErrClosedDevice
 	}
+	// The flows of a SLAVE are managed by the master.
+	if r.shadowFlows == nil || r.session.mastership.isSlave() {
+		return nil
+	}
 
-	return r.session.Write(msg)
+	missing, unknown, err := r.shadowFlows.Diff(stats)
+	if err != nil {
+		return err
+	}
+	if len(missing) == 0 && len(unknown) == 0 {
+		return nil
+	}
+
+	for _, flow := range missing {
+		// The device may have reconnected with another protocol version.
+		if flow.Version() != r.factory.ProtocolVersion() {
+			logger.Warningf("dropping the lost flows of %v: mis-matched flow version: device=%v, flow=%v", r.id, r.factory.ProtocolVersion(), flow.Version())
+			r.shadowFlows.RemoveAll()
+			return nil
+		}
+		logger.Warningf("reinstalling a flow lost by %v: table=%v, priority=%v, match=%+v", r.id, flow.TableID(), flow.Priority(), newFlowMatchParam(flow.FlowMatch()))
+		// The packet buffered when the flow was installed is no longer valid.
+		flow.SetBufferID(openflow.NoBuffer)
+		if err := r.session.Write(flow); err != nil {
+			return err
+		}
+	}
+	for _, v := range unknown {
+		logger.Warningf("removing an unknown flow on %v: table=%v, priority=%v, cookie=%v, match=%+v", r.id, v.TableID, v.Priority, v.Cookie, newFlowMatchParam(v.Match))
+		flow, err := r.factory.NewFlowMod(openflow.FlowDeleteStrict)
+		if err != nil {
+			return err
+		}
+		// Only the normal flows, except the special flows whose cookie MSB is 1.
+		flow.SetCookieMask(0x1 << 63)
+		flow.SetTableID(v.TableID)
+		flow.SetPriority(v.Priority)
+		flow.SetFlowMatch(v.Match)
+		if err := r.session.Write(flow); err != nil {
+			return err
+		}
+	}
+
+	barrier, err := r.factory.NewBarrierRequest()
+	if err != nil {
+		return err
+	}
+
+	return r.session.Write(barrier)
 }
 
-func (r *Device) IsClosed() bool {
-	// Read lock
-	r.mutex.RLock()
-	defer r.mutex.RUnlock()
+// AddGroup creates a group whose ID is id on the device. Groups are only
+// supported by OpenFlow 1.3 devices. It returns ErrGroupExists if the group
+// already exists, and ErrUnknownGroup or ErrGroupLoop if the buckets reference
+// a group that does not exist or that references the group.
+func (r *Device) AddGroup(t openflow.GroupType, id uint32, buckets []openflow.Bucket) error {
+	return r.sendGroupMod(openflow.GroupAdd, t, id, buckets)
+}
 
-	return r.closed
+// ModifyGroup replaces the type and buckets of the group whose ID is id. The
+// returned errors are same as the ones of AddGroup except that ErrUnknownGroup
+// is also returned if the group does not exist.
+func (r *Device) ModifyGroup(t openflow.GroupType, id uint32, buckets []openflow.Bucket) error {
+	return r.sendGroupMod(openflow.GroupModify, t, id, buckets)
 }
 
-// SetFlow installs a normal flow entry for packet switching and routing into the switch device.
-func (r *Device) SetFlow(match openflow.Match, port openflow.OutPort) error {
+// DeleteGroup removes the group whose ID is id, and also the flows that forward
+// packets to the group. openflow.AllGroups removes all the groups. It returns
+// ErrChainedGroup if another group references the group.
+func (r *Device) DeleteGroup(id uint32) error {
+	return r.sendGroupMod(openflow.GroupDelete, openflow.GroupAll, id, nil)
+}
+
+func (r *Device) sendGroupMod(cmd openflow.GroupModCmd, t openflow.GroupType, id uint32, buckets []openflow.Bucket) error {
 	// Write lock
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
@@ -273,56 +1297,80 @@ func (r *Device) SetFlow(match openflow.Match, port openflow.OutPort) error {
 	if r.closed {
 		return ErrClosedDevice
 	}
-
-	// Set the default VLAN ID. It is necessary to use the L2 MAC flow table of Dell SXXX switches.
-	match.SetVLANID(r.vlanID)
-
-	action, err := r.factory.NewAction()
-	if err != nil {
+	if err := r.groups.Check(cmd, id, buckets); err != nil {
 		return err
 	}
-	action.SetOutPort(port)
 
-	inst, err := r.factory.NewInstruction()
+	group, err := r.factory.NewGroupMod(cmd)
 	if err != nil {
 		return err
 	}
-	inst.ApplyAction(action)
-
-	// For valid (non-overlapping) ADD requests, or those with no overlap checking,
-	// the switch must insert the flow entry at the lowest numbered table for which
-	// the switch supports all wildcards set in the flow_match struct, and for which
-	// the priority would be observed during the matching process. If a flow entry
-	// with identical header fields and priority already resides in any table, then
-	// that entry, including its counters, must be removed, and the new flow entry added.
-	flow, err := r.factory.NewFlowMod(openflow.FlowAdd)
+	group.SetGroupType(t)
+	group.SetGroupID(id)
+	for _, b := range buckets {
+		group.AddBucket(b)
+	}
+	if err := group.Error(); err != nil {
+		return err
+	}
+	if err := r.session.Write(group); err != nil {
+		return err
+	}
+	r.groups.Update(cmd, id, buckets)
+	barrier, err := r.factory.NewBarrierRequest()
 	if err != nil {
 		return err
 	}
-	flow.SetTableID(r.flowTableID)
-	// This idle timeout is actually useless because we update the installed flows
-	// more frequently than this timeout.
-	flow.SetIdleTimeout(90)
-	flow.SetPriority(10)
-	flow.SetFlowMatch(match)
-	flow.SetFlowInstruction(inst)
 
-	ok, err := r.flowCache.InProgress(match, port)
+	return r.session.Write(barrier)
+}
+
+// AddMeter creates a meter whose ID is id on the device. The rates of the
+// bands are in packets per second if pktps is true, otherwise in kilobits per
+// second. Meters are only supported by OpenFlow 1.3 devices, and they can be
+// attached to flows by Instruction.SetMeter.
+func (r *Device) AddMeter(id uint32, pktps bool, bands []openflow.MeterBand) error {
+	return r.sendMeterMod(openflow.MeterAdd, id, pktps, bands)
+}
+
+// ModifyMeter replaces the bands of the meter whose ID is id.
+func (r *Device) ModifyMeter(id uint32, pktps bool, bands []openflow.MeterBand) error {
+	return r.sendMeterMod(openflow.MeterModify, id, pktps, bands)
+}
+
+// DeleteMeter removes the meter whose ID is id, and also the flows that use the meter.
+func (r *Device) DeleteMeter(id uint32) error {
+	return r.sendMeterMod(openflow.MeterDelete, id, false, nil)
+}
+
+func (r *Device) sendMeterMod(cmd openflow.MeterModCmd, id uint32, pktps bool, bands []openflow.MeterBand) error {
+	// Write lock
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.closed {
+		return ErrClosedDevice
+	}
+
+	meter, err := r.factory.NewMeterMod(cmd)
 	if err != nil {
 		return err
 	}
-	if ok {
-		logger.Debugf("skip to install a new flow: already installed one: deviceID=%v", r.id)
-		return nil
+	meter.SetMeterID(id)
+	meter.SetPacketRate(pktps)
+	for _, b := range bands {
+		// Apply the burst sizes if any band has it.
+		if b.BurstSize > 0 {
+			meter.SetBurst(true)
+		}
+		meter.AddBand(b)
 	}
-	// Install the new flow.
-	if err := r.session.Write(flow); err != nil {
+	if err := meter.Error(); err != nil {
 		return err
 	}
-	if err := r.flowCache.Add(match, port); err != nil {
+	if err := r.session.Write(meter); err != nil {
 		return err
 	}
-
 	barrier, err := r.factory.NewBarrierRequest()
 	if err != nil {
 		return err
@@ -365,6 +1413,11 @@ func (r *Device) RemoveFlows() error {
 		return err
 	}
 	r.flowCache.RemoveAll()
+	if r.shadowFlows != nil {
+		r.shadowFlows.RemoveAll()
+	}
+	r.conflicts.RemoveAll()
+	r.lifetimes.RemoveAll()
 
 	return nil
 }
@@ -393,8 +1446,14 @@ func (r *Device) RemoveFlow(match openflow.Match, port openflow.OutPort) error {
 	flowmod.SetTableID(0xFF) // ALL
 	flowmod.SetFlowMatch(match)
 	flowmod.SetOutPort(port)
+	if err := r.session.Write(flowmod); err != nil {
+		return err
+	}
+	if r.shadowFlows != nil {
+		r.shadowFlows.Remove(0xFF, match, port)
+	}
 
-	return r.session.Write(flowmod)
+	return nil
 }
 
 // TODO:
@@ -429,8 +1488,35 @@ func (r *Device) RemoveFlowByMAC(mac net.HardwareAddr) error {
 	flowmod.SetTableID(0xFF) // ALL
 	flowmod.SetFlowMatch(match)
 	flowmod.SetOutPort(port)
+	if err := r.session.Write(flowmod); err != nil {
+		return err
+	}
+	if r.shadowFlows != nil {
+		r.shadowFlows.Remove(0xFF, match, port)
+	}
+
+	return nil
+}
+
+// RemoveOwnedFlows removes all the flows owned by the application whose name is owner, i.e.,
+// the flows whose cookies are made by NewCookie with owner, from all the tables.
+func (r *Device) RemoveOwnedFlows(owner string) error {
+	f := r.Factory()
+	match, err := f.NewMatch()
+	if err != nil {
+		return err
+	}
+	flow, err := f.NewFlowMod(openflow.FlowDelete)
+	if err != nil {
+		return err
+	}
+	cookie, mask := OwnerCookie(owner)
+	flow.SetCookie(cookie)
+	flow.SetCookieMask(mask)
+	flow.SetTableID(0xFF) // ALL
+	flow.SetFlowMatch(match)
 
-	return r.session.Write(flowmod)
+	return r.InstallFlow(flow)
 }
 
 func makeARPAnnouncement(ip net.IP, mac net.HardwareAddr) ([]byte, error) {
@@ -556,3 +1642,106 @@ func (r *Device) Close() {
 
 	r.closed = true
 }
+
+// disconnect closes the connection of this device. The device will be closed when its session terminates.
+func (r *Device) disconnect() {
+	if err := r.session.transceiver.Close(); err != nil {
+		logger.Errorf("failed to close the transceiver of %v: %v", r.ID(), err)
+	}
+}
+
+const drainCheckInterval = 1 * time.Second
+
+// Drain moves the paths managed by the controller off this device before maintenance.
+// It marks the device as drained so that the spanning tree, and thus the paths
+// computed from it, avoid this device whenever possible, and then blocks until no
+// path traverses this device or ctx is done. The drained state is stored in the
+// database, and it remains until Undrain is called even if the controller restarts.
+func (r *Device) Drain(ctx context.Context) error {
+	id := r.ID()
+	if err := r.session.watcher.SetDrained(id, true); err != nil {
+		return err
+	}
+
+	ticker := r.clock.NewTicker(drainCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		// The device can be rebooted safely if it is not a transit node anymore.
+		if r.session.watcher.IsTransit(id) == false {
+			logger.Infof("device %v has been drained: no path traverses it", id)
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C():
+		}
+	}
+}
+
+// Undrain restores the normal path computation for this device.
+func (r *Device) Undrain() error {
+	return r.session.watcher.SetDrained(r.ID(), false)
+}
+
+func (r *Device) IsDrained() bool {
+	return r.session.watcher.IsDrained(r.ID())
+}
+
+// MissSendLength returns the max_len of the PACKET_INs configured on this device.
+func (r *Device) MissSendLength() uint16 {
+	return r.session.packetInGate.policy.missSendLength(r.ID())
+}
+
+// SetMissSendLength changes the max_len of the PACKET_INs that the device sends for the
+// table-miss packets. 0xFFFF means the whole packet. The value is kept for the device
+// even if it reconnects.
+func (r *Device) SetMissSendLength(length uint16) error {
+	if !r.isReady() {
+		return errors.New("device is not ready")
+	}
+
+	msg, err := r.Factory().NewSetConfig()
+	if err != nil {
+		return err
+	}
+	msg.SetFlags(openflow.FragNormal)
+	msg.SetMissSendLength(length)
+	if err := r.SendMessage(msg); err != nil {
+		return err
+	}
+	r.session.packetInGate.policy.setMissSendLength(r.ID(), length)
+
+	return nil
+}
+
+// blockTableMiss installs a temporary flow that drops the packets that would be sent
+// to the controller by the table-miss flow for d. The flows whose priorities are higher,
+// e.g., the ARP and LLDP senders and the flows for the known hosts, are not affected.
+// The flow is installed through InstallFlow so that it is validated and tracked by the
+// shadow flow table like the other flows.
+func (r *Device) blockTableMiss(d time.Duration) error {
+	f := r.Factory()
+	match, err := f.NewMatch() // Wildcard
+	if err != nil {
+		return err
+	}
+
+	flow, err := f.NewFlowMod(openflow.FlowAdd)
+	if err != nil {
+		return err
+	}
+	// We use MSB to represent the special flows that are not managed by the applications.
+	flow.SetCookie(0x1 << 63)
+	flow.SetTableID(r.FlowTableID())
+	flow.SetIdleTimeout(0)
+	flow.SetHardTimeout(uint16(d / time.Second))
+	// Right above the table-miss flow whose priority is zero.
+	flow.SetPriority(1)
+	// No actions means dropping the matched packets.
+	flow.SetFlowMatch(match)
+
+	return r.InstallFlow(flow)
+}
@@ -0,0 +1,324 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/superkkt/cherry/clock"
+	"github.com/superkkt/cherry/openflow"
+	"github.com/superkkt/cherry/openflow/of10"
+	"github.com/superkkt/cherry/openflow/of13"
+	"github.com/superkkt/cherry/openflow/transceiver"
+)
+
+func TestTouchFlowMessage(t *testing.T) {
+	tests := []struct {
+		factory openflow.Factory
+		// Offsets of the command and flags fields in the encoded FLOW_MOD message
+		command, flags int
+		// Size of the command field
+		commandSize int
+	}{
+		// ofp_header (8) + ofp_match (40)
+		{of10.NewFactory(), 8 + 40 + 8, 8 + 40 + 22, 2},
+		{of13.NewFactory(), 8 + 17, 8 + 36, 1},
+	}
+
+	for _, test := range tests {
+		d := &Device{factory: test.factory, vlanID: 1000}
+		match, err := test.factory.NewMatch()
+		if err != nil {
+			t.Fatal(err)
+		}
+		match.SetDstMAC(net.HardwareAddr{0x0a, 0, 0, 0, 0, 1})
+		port := openflow.NewOutPort()
+		port.SetValue(3)
+
+		flow, err := d.newFlowMod(openflow.FlowModifyStrict, match, port)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, err := flow.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var command uint16
+		if test.commandSize == 1 {
+			command = uint16(v[test.command])
+		} else {
+			command = binary.BigEndian.Uint16(v[test.command:])
+		}
+		// OFPFC_MODIFY_STRICT is 2 in both versions.
+		if command != 2 {
+			t.Fatalf("version %v: unexpected command: expected=2, got=%v", test.factory.ProtocolVersion(), command)
+		}
+		// Only OFPFF_SEND_FLOW_REM. OFPFF_RESET_COUNTS should not be set.
+		if flags := binary.BigEndian.Uint16(v[test.flags:]); flags != 1 {
+			t.Fatalf("version %v: unexpected flags: expected=1, got=%v", test.factory.ProtocolVersion(), flags)
+		}
+		if flow.Priority() != 10 || flow.IdleTimeout() != 90 {
+			t.Fatalf("version %v: unexpected priority or idle timeout: %v, %v", test.factory.ProtocolVersion(), flow.Priority(), flow.IdleTimeout())
+		}
+	}
+}
+
+func TestCompareTouchStats(t *testing.T) {
+	before := openflow.FlowStats{DurationSec: 30, PacketCount: 100, ByteCount: 6400}
+	tests := []struct {
+		after    openflow.FlowStats
+		expected TouchReport
+	}{
+		// Modified as the specification says.
+		{openflow.FlowStats{DurationSec: 30, PacketCount: 100, ByteCount: 6400}, TouchReport{Before: 30, After: 30}},
+		{openflow.FlowStats{DurationSec: 31, PacketCount: 102, ByteCount: 6528}, TouchReport{Before: 30, After: 31}},
+		// The timeouts are restarted, but the counters are kept.
+		{openflow.FlowStats{DurationSec: 0, PacketCount: 100, ByteCount: 6400}, TouchReport{Before: 30, After: 0, DurationReset: true}},
+		// Replaced by a new flow.
+		{openflow.FlowStats{DurationSec: 0}, TouchReport{Before: 30, After: 0, DurationReset: true, CountersReset: true}},
+	}
+
+	for _, test := range tests {
+		if report := compareTouchStats(before, test.after); report != test.expected {
+			t.Fatalf("unexpected report: expected=%+v, got=%+v", test.expected, report)
+		}
+	}
+}
+
+func TestVerifyTouchFlow(t *testing.T) {
+	tests := []struct {
+		// True if the switch replaces the touched flow.
+		replace  bool
+		expected TouchReport
+	}{
+		{false, TouchReport{Before: 60, After: 60}},
+		{true, TouchReport{Before: 60, After: 0, DurationReset: true, CountersReset: true}},
+	}
+
+	for _, test := range tests {
+		sw := &selfTestSwitch{}
+		d, cleanup := newSelfTestDevice(t, sw)
+		replace := test.replace
+		sw.mutex.Lock()
+		sw.flows[NewCookie("TouchTest", 0)] = true
+		sw.stats = func(cookie uint64, entry []byte, modified int) {
+			binary.BigEndian.PutUint16(entry[12:14], normalFlowPriority)
+			if modified > 0 && replace {
+				return
+			}
+			binary.BigEndian.PutUint32(entry[4:8], 60)
+			binary.BigEndian.PutUint64(entry[32:40], 10)
+		}
+		sw.mutex.Unlock()
+
+		match, err := d.Factory().NewMatch()
+		if err != nil {
+			t.Fatal(err)
+		}
+		match.SetDstMAC(net.HardwareAddr{0x0a, 0, 0, 0, 0, 1})
+		port := openflow.NewOutPort()
+		port.SetValue(3)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		report, err := d.VerifyTouchFlow(ctx, "TouchTest", match, port)
+		cancel()
+		cleanup()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report != test.expected {
+			t.Fatalf("unexpected report: expected=%+v, got=%+v", test.expected, report)
+		}
+		sw.mutex.Lock()
+		modified := sw.modified
+		sw.mutex.Unlock()
+		if modified != 1 {
+			t.Fatalf("unexpected number of touches: %v", modified)
+		}
+	}
+}
+
+func TestFlowModBufferID(t *testing.T) {
+	tests := []struct {
+		factory openflow.Factory
+		// Offset of the buffer_id field in the encoded FLOW_MOD message
+		offset int
+	}{
+		// ofp_header (8) + ofp_match (40)
+		{of10.NewFactory(), 8 + 40 + 16},
+		{of13.NewFactory(), 8 + 24},
+	}
+
+	for _, test := range tests {
+		d := &Device{factory: test.factory, vlanID: 1000}
+		match, err := test.factory.NewMatch()
+		if err != nil {
+			t.Fatal(err)
+		}
+		port := openflow.NewOutPort()
+		port.SetValue(3)
+
+		flow, err := d.newFlowMod(openflow.FlowAdd, match, port)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, id := range []uint32{openflow.NoBuffer, 0x1234} {
+			if id != openflow.NoBuffer {
+				flow.SetBufferID(id)
+			}
+			v, err := flow.MarshalBinary()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := binary.BigEndian.Uint32(v[test.offset:]); got != id {
+				t.Fatalf("version %v: unexpected buffer ID: expected=%#x, got=%#x", test.factory.ProtocolVersion(), id, got)
+			}
+		}
+	}
+}
+
+// barrierHandler accepts the HELLO and the replies of the barrier requests.
+type barrierHandler struct {
+	transceiver.Handler
+	hello chan struct{}
+}
+
+func (r *barrierHandler) OnHello(openflow.Factory, transceiver.Writer, openflow.Hello) error {
+	close(r.hello)
+	return nil
+}
+
+func (r *barrierHandler) OnError(openflow.Factory, transceiver.Writer, openflow.Error) error {
+	return nil
+}
+
+func (r *barrierHandler) OnBarrierReply(openflow.Factory, transceiver.Writer, openflow.BarrierReply) error {
+	return nil
+}
+
+// newBarrierDevice returns an OF1.3 device whose switch is the other end of remote.
+func newBarrierDevice(t *testing.T) (d *Device, remote net.Conn, stream *transceiver.Stream, cleanup func()) {
+	local, remote := net.Pipe()
+	handler := &barrierHandler{hello: make(chan struct{})}
+	s := &session{clock: clock.Real, flowConflict: newConflictPolicy()}
+	s.transceiver = transceiver.NewTransceiver(transceiver.NewStream(local), handler, clock.Real)
+	s.device = newDevice(s)
+	s.device.setFactory(of13.NewFactory())
+	ctx, cancel := context.WithCancel(context.Background())
+	go s.transceiver.Run(ctx)
+
+	stream = transceiver.NewStream(remote)
+	writeSwitch(t, remote, []byte{openflow.OF13_VERSION, of13.OFPT_HELLO, 0, 8, 0, 0, 0, 1})
+	select {
+	case <-handler.hello:
+	case <-time.After(5 * time.Second):
+		t.Fatal("HELLO is not dispatched")
+	}
+
+	return s.device, remote, stream, func() {
+		cancel()
+		s.transceiver.Close()
+		stream.Close()
+		remote.Close()
+	}
+}
+
+func writeSwitch(t *testing.T, conn net.Conn, packet []byte) {
+	conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Write(packet); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readBarrier returns the transaction ID of the next barrier request that the switch receives.
+func readBarrier(t *testing.T, stream *transceiver.Stream) uint32 {
+	for {
+		packet, err := stream.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if packet[1] == of13.OFPT_BARRIER_REQUEST {
+			return binary.BigEndian.Uint32(packet[4:8])
+		}
+	}
+}
+
+func TestDeviceBarrier(t *testing.T) {
+	d, remote, stream, cleanup := newBarrierDevice(t)
+	defer cleanup()
+
+	barrier := func(ctx context.Context) <-chan error {
+		c := make(chan error, 1)
+		go func() { c <- d.Barrier(ctx) }()
+		return c
+	}
+	wait := func(c <-chan error) error {
+		select {
+		case err := <-c:
+			return err
+		case <-time.After(5 * time.Second):
+			t.Fatal("Barrier did not return")
+			return nil
+		}
+	}
+
+	// Barrier returns once the reply arrives.
+	result := barrier(context.Background())
+	xid := readBarrier(t, stream)
+	reply := []byte{openflow.OF13_VERSION, of13.OFPT_BARRIER_REPLY, 0, 8, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(reply[4:8], xid)
+	writeSwitch(t, remote, reply)
+	if err := wait(result); err != nil {
+		t.Fatal(err)
+	}
+
+	// The error message replied by the device.
+	result = barrier(context.Background())
+	xid = readBarrier(t, stream)
+	// OFPET_BAD_REQUEST/OFPBRC_BAD_TYPE
+	reply = []byte{openflow.OF13_VERSION, of13.OFPT_ERROR, 0, 12, 0, 0, 0, 0, 0, 1, 0, 1}
+	binary.BigEndian.PutUint32(reply[4:8], xid)
+	writeSwitch(t, remote, reply)
+	if err := wait(result); err == nil || !strings.Contains(err.Error(), "device replied an error") {
+		t.Fatalf("expected the device error, got %v", err)
+	}
+
+	// No reply until ctx is done.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	result = barrier(ctx)
+	readBarrier(t, stream)
+	if err := wait(result); err == nil || !strings.Contains(err.Error(), context.DeadlineExceeded.Error()) {
+		t.Fatalf("expected the deadline error, got %v", err)
+	}
+
+	// Nothing is sent to the closed device.
+	d.Close()
+	if err := d.Barrier(context.Background()); err != ErrClosedDevice {
+		t.Fatalf("expected ErrClosedDevice, got %v", err)
+	}
+}
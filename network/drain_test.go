@@ -0,0 +1,200 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/superkkt/cherry/testutil"
+)
+
+// drainDB is a database that only keeps the drained state of the registered switches.
+type drainDB struct {
+	database
+	// Key is the DPID, and value is the drained state.
+	switches map[uint64]bool
+}
+
+func (r *drainDB) Switch(dpid uint64) (sw Switch, ok bool, err error) {
+	drained, ok := r.switches[dpid]
+	if !ok {
+		return Switch{}, false, nil
+	}
+	sw.DPID = dpid
+	sw.Drained = drained
+
+	return sw, true, nil
+}
+
+func (r *drainDB) SetSwitchDrained(dpid uint64, drained bool) (ok bool, err error) {
+	if _, ok := r.switches[dpid]; !ok {
+		return false, nil
+	}
+	r.switches[dpid] = drained
+
+	return true, nil
+}
+
+// newTestTopology makes a topology whose devices are linked to each other as described
+// by links, and the port number of a link is the ID of the peer device.
+func newTestTopology(db database, ids []string, links [][2]string) *topology {
+	clock := testutil.NewFakeClock(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))
+	topo := newTopology(db, clock)
+	devices := make(map[string]*Device)
+	for _, id := range ids {
+		d := &Device{id: id, ports: make(map[uint32]*Port), clock: clock}
+		d.session = &session{watcher: topo, device: d}
+		devices[id] = d
+		topo.DeviceAdded(d)
+	}
+	for _, l := range links {
+		src, dst := devices[l[0]], devices[l[1]]
+		topo.DeviceLinked([2]*Port{NewPort(src, uint32(testDPID(dst.ID()))), NewPort(dst, uint32(testDPID(src.ID())))})
+	}
+
+	return topo
+}
+
+func testDPID(id string) uint64 {
+	v, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		panic(err)
+	}
+
+	return v
+}
+
+func TestDrainTriangle(t *testing.T) {
+	db := &drainDB{switches: map[uint64]bool{1: false, 2: false, 3: false}}
+	ids := []string{"1", "2", "3"}
+	links := [][2]string{{"1", "2"}, {"2", "3"}, {"1", "3"}}
+	topo := newTestTopology(db, ids, links)
+
+	// Drain every device in turn, and the paths between the others should avoid it.
+	for _, drained := range ids {
+		if err := topo.Device(drained).Drain(context.Background()); err != nil {
+			t.Fatalf("failed to drain device %v: %v", drained, err)
+		}
+		if !db.switches[testDPID(drained)] {
+			t.Fatalf("drained state of device %v is not persisted", drained)
+		}
+		if topo.IsTransit(drained) {
+			t.Fatalf("device %v is still a transit node", drained)
+		}
+
+		for _, src := range ids {
+			for _, dst := range ids {
+				if src == dst {
+					continue
+				}
+				path := topo.Path(src, dst)
+				if len(path) == 0 {
+					t.Fatalf("no path from %v to %v while %v is drained", src, dst, drained)
+				}
+				if src == drained || dst == drained {
+					continue
+				}
+				// The path should be the direct link between src and dst.
+				if len(path) != 1 {
+					t.Fatalf("path from %v to %v traverses the drained device %v", src, dst, drained)
+				}
+			}
+		}
+
+		if err := topo.Device(drained).Undrain(); err != nil {
+			t.Fatalf("failed to undrain device %v: %v", drained, err)
+		}
+		if topo.IsDrained(drained) || db.switches[testDPID(drained)] {
+			t.Fatalf("device %v is still drained after undrain", drained)
+		}
+	}
+}
+
+func TestDrainCutVertex(t *testing.T) {
+	db := &drainDB{switches: map[uint64]bool{1: false, 2: false, 3: false}}
+	// Device 2 is the only way between 1 and 3.
+	topo := newTestTopology(db, []string{"1", "2", "3"}, [][2]string{{"1", "2"}, {"2", "3"}})
+
+	// Drain keeps waiting while the device is a transit node.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := topo.Device("2").Drain(ctx); err != context.Canceled {
+		t.Fatalf("expected the canceled error, but got %v", err)
+	}
+	if len(topo.Path("1", "3")) != 2 {
+		t.Fatalf("draining a cut vertex should not disconnect the network")
+	}
+}
+
+func TestDrainPersistence(t *testing.T) {
+	db := &drainDB{switches: map[uint64]bool{1: false, 2: true, 3: false}}
+	// Device 2 was drained before the controller restart.
+	topo := newTestTopology(db, []string{"1", "2", "3"}, [][2]string{{"1", "2"}, {"2", "3"}, {"1", "3"}})
+
+	if !topo.IsDrained("2") {
+		t.Fatalf("drained state is not restored")
+	}
+	if topo.IsTransit("2") {
+		t.Fatalf("restored drained device is a transit node")
+	}
+
+	// Unregistered switches cannot be drained.
+	if err := topo.SetDrained("4", true); err == nil {
+		t.Fatalf("expected an error for the unregistered switch")
+	}
+}
+
+// lockCheckDB records whether the topology is locked while its drained state is queried.
+type lockCheckDB struct {
+	drainDB
+	topo   *topology
+	locked bool
+}
+
+func (r *lockCheckDB) Switch(dpid uint64) (sw Switch, ok bool, err error) {
+	if r.topo.mutex.TryLock() {
+		r.topo.mutex.Unlock()
+	} else {
+		r.locked = true
+	}
+
+	return r.drainDB.Switch(dpid)
+}
+
+func TestDrainQueryWithoutLock(t *testing.T) {
+	db := &lockCheckDB{drainDB: drainDB{switches: map[uint64]bool{1: true}}}
+	clock := testutil.NewFakeClock(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))
+	db.topo = newTopology(db, clock)
+	d := &Device{id: "1", ports: make(map[uint32]*Port), clock: clock}
+	d.session = &session{watcher: db.topo, device: d}
+	db.topo.DeviceAdded(d)
+
+	if db.locked {
+		t.Fatalf("database is queried while the topology is locked")
+	}
+	if !db.topo.IsDrained("1") {
+		t.Fatalf("drained state is not restored")
+	}
+}
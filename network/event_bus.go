@@ -0,0 +1,232 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/superkkt/cherry/clock"
+	"github.com/superkkt/cherry/openflow"
+)
+
+type EventType string
+
+const (
+	EventDeviceUp    EventType = "device_up"
+	EventDeviceDown  EventType = "device_down"
+	EventPortUp      EventType = "port_up"
+	EventPortDown    EventType = "port_down"
+	EventLinkUp      EventType = "link_up"
+	EventLinkDown    EventType = "link_down"
+	EventHostMoved   EventType = "host_moved"
+	EventFlowRemoved EventType = "flow_removed"
+	// A switch has repeatedly failed to complete the handshake.
+	EventNegotiationFailed EventType = "negotiation_failed"
+)
+
+// Event is a network event published on the event bus.
+type Event struct {
+	Type EventType `json:"type"`
+	Time time.Time `json:"time"`
+	// DPID of the device for the device, port, host and flow events.
+	DPID string `json:"dpid,omitempty"`
+	// Port number for the port and host events.
+	Port uint32 `json:"port,omitempty"`
+	// Two ends of the link for the link events.
+	Link []EventPort `json:"link,omitempty"`
+	// Addresses of the moved host for the host events.
+	MAC string `json:"mac,omitempty"`
+	IP  string `json:"ip,omitempty"`
+	// Cookie and removal reason of the removed flow for the flow events.
+	Cookie uint64 `json:"cookie,omitempty"`
+	Reason string `json:"reason,omitempty"`
+	// Source IP address of the switch and the number of its consecutive failures for the
+	// negotiation events.
+	Address  string `json:"address,omitempty"`
+	Failures int    `json:"failures,omitempty"`
+}
+
+type EventPort struct {
+	DPID string `json:"dpid"`
+	Port uint32 `json:"port"`
+}
+
+// Number of the events buffered for a subscriber. A subscriber that falls behind by more
+// than this number of events is dropped.
+const subscriptionBufferSize = 256
+
+// eventBus delivers the network events to its subscribers. Unlike the event listeners, the
+// subscribers receive the events asynchronously and cannot affect the event processing, so
+// they can be added without touching the device and session code.
+type eventBus struct {
+	mutex sync.Mutex
+	// Value is the event types that the subscriber wants. nil means all types.
+	subscribers map[chan Event]map[EventType]bool
+	clock       clock.Clock
+}
+
+func newEventBus(clk clock.Clock) *eventBus {
+	if clk == nil {
+		panic("clock is nil")
+	}
+
+	return &eventBus{
+		subscribers: make(map[chan Event]map[EventType]bool),
+		clock:       clk,
+	}
+}
+
+// subscribe returns a channel that receives the events of types published after this call, or
+// all the events if types is empty. The channel is closed by unsubscribe, or by publish if the
+// subscriber does not receive the events fast enough.
+func (r *eventBus) subscribe(types ...EventType) <-chan Event {
+	var filter map[EventType]bool
+	if len(types) > 0 {
+		filter = make(map[EventType]bool)
+		for _, t := range types {
+			filter[t] = true
+		}
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	c := make(chan Event, subscriptionBufferSize)
+	r.subscribers[c] = filter
+
+	return c
+}
+
+func (r *eventBus) unsubscribe(c <-chan Event) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for v := range r.subscribers {
+		if v == c {
+			delete(r.subscribers, v)
+			close(v)
+			return
+		}
+	}
+}
+
+// publish sends e to all the subscribers of its type without blocking.
+func (r *eventBus) publish(e Event) {
+	e.Time = r.clock.Now()
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for c, filter := range r.subscribers {
+		if filter != nil && !filter[e.Type] {
+			continue
+		}
+		select {
+		case c <- e:
+		default:
+			// The subscriber is too slow. Drop it instead of losing the events silently.
+			logger.Warningf("dropping a slow event subscriber: %v buffered events", len(c))
+			delete(r.subscribers, c)
+			close(c)
+		}
+	}
+}
+
+func (r *eventBus) publishHostMoved(mac net.HardwareAddr, ip net.IP, dpid string, port uint32) {
+	r.publish(Event{Type: EventHostMoved, DPID: dpid, Port: port, MAC: mac.String(), IP: ip.String()})
+}
+
+// sessionPublisher is a controller event listener that publishes the events of a session on
+// the bus before passing them to the next listener.
+type sessionPublisher struct {
+	ControllerEventListener
+	bus     *eventBus
+	session *session
+}
+
+func (r *sessionPublisher) OnDeviceUp(finder Finder, device *Device) error {
+	r.bus.publish(Event{Type: EventDeviceUp, DPID: device.ID()})
+	return r.ControllerEventListener.OnDeviceUp(finder, device)
+}
+
+func (r *sessionPublisher) OnDeviceDown(finder Finder, device *Device) error {
+	r.bus.publish(Event{Type: EventDeviceDown, DPID: device.ID()})
+	return r.ControllerEventListener.OnDeviceDown(finder, device)
+}
+
+func (r *sessionPublisher) OnPortUp(finder Finder, port *Port) error {
+	r.bus.publish(Event{Type: EventPortUp, DPID: port.Device().ID(), Port: port.Number()})
+	return r.ControllerEventListener.OnPortUp(finder, port)
+}
+
+func (r *sessionPublisher) OnPortDown(finder Finder, port *Port) error {
+	r.bus.publish(Event{Type: EventPortDown, DPID: port.Device().ID(), Port: port.Number()})
+	return r.ControllerEventListener.OnPortDown(finder, port)
+}
+
+func (r *sessionPublisher) OnFlowRemoved(finder Finder, flow openflow.FlowRemoved) error {
+	r.bus.publish(Event{Type: EventFlowRemoved, DPID: r.session.eventDevice().ID(), Cookie: flow.Cookie(), Reason: flowRemovedReason(flow.Reason())})
+	return r.ControllerEventListener.OnFlowRemoved(finder, flow)
+}
+
+func flowRemovedReason(reason uint8) string {
+	switch reason {
+	case openflow.FlowRemovedIdleTimeout:
+		return "idle_timeout"
+	case openflow.FlowRemovedHardTimeout:
+		return "hard_timeout"
+	case openflow.FlowRemovedDelete:
+		return "delete"
+	case openflow.FlowRemovedGroupDelete:
+		return "group_delete"
+	case openflow.FlowRemovedMeterDelete:
+		return "meter_delete"
+	default:
+		return "unknown"
+	}
+}
+
+// topologyPublisher is a topology event listener that publishes the link events on the bus
+// before passing them to the next listener.
+type topologyPublisher struct {
+	TopologyEventListener
+	bus *eventBus
+}
+
+func (r *topologyPublisher) OnLinkUp(finder Finder, ports [2]*Port) error {
+	r.bus.publish(Event{Type: EventLinkUp, Link: newEventLink(ports)})
+	return r.TopologyEventListener.OnLinkUp(finder, ports)
+}
+
+func (r *topologyPublisher) OnLinkDown(finder Finder, ports [2]*Port) error {
+	r.bus.publish(Event{Type: EventLinkDown, Link: newEventLink(ports)})
+	return r.TopologyEventListener.OnLinkDown(finder, ports)
+}
+
+func newEventLink(ports [2]*Port) []EventPort {
+	return []EventPort{
+		{DPID: ports[0].Device().ID(), Port: ports[0].Number()},
+		{DPID: ports[1].Device().ID(), Port: ports[1].Number()},
+	}
+}
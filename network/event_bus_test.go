@@ -0,0 +1,74 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"testing"
+	"time"
+
+	"github.com/superkkt/cherry/testutil"
+)
+
+func TestEventBusSlowSubscriber(t *testing.T) {
+	bus := newEventBus(testutil.NewFakeClock(time.Unix(0, 0)))
+	fast := bus.subscribe()
+	slow := bus.subscribe()
+
+	for i := 0; i <= subscriptionBufferSize; i++ {
+		bus.publish(Event{Type: EventPortUp, Port: uint32(i)})
+		<-fast
+	}
+
+	// The slow subscriber is closed after receiving the buffered events.
+	n := 0
+	for range slow {
+		n++
+	}
+	if n != subscriptionBufferSize {
+		t.Fatalf("unexpected number of buffered events: %v", n)
+	}
+	bus.publish(Event{Type: EventPortDown})
+	if e := <-fast; e.Type != EventPortDown {
+		t.Fatalf("unexpected event: %+v", e)
+	}
+	bus.unsubscribe(fast)
+	bus.unsubscribe(slow)
+}
+
+func TestEventBusFilter(t *testing.T) {
+	bus := newEventBus(testutil.NewFakeClock(time.Unix(0, 0)))
+	links := bus.subscribe(EventLinkUp, EventLinkDown)
+	all := bus.subscribe()
+
+	bus.publish(Event{Type: EventDeviceUp})
+	bus.publish(Event{Type: EventLinkUp})
+
+	if e := <-links; e.Type != EventLinkUp {
+		t.Fatalf("unexpected event: %+v", e)
+	}
+	if len(links) != 0 {
+		t.Fatalf("unexpected number of filtered events: %v", len(links))
+	}
+	if len(all) != 2 {
+		t.Fatalf("unexpected number of events: %v", len(all))
+	}
+}
@@ -0,0 +1,170 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/ant0ine/go-json-rest/rest"
+)
+
+// Name of the firewall application that owns the flows of the firewall rules.
+const firewallApp = "Firewall"
+
+// Maximum priority of a firewall rule.
+const maxFirewallPriority = 9999
+
+type FirewallRuleParam struct {
+	// Rules are evaluated in the descending order of the priority (0 ~ 9999).
+	Priority uint16 `json:"priority"`
+	// "allow" or "deny".
+	Action string `json:"action"`
+	// Source and destination IPv4 networks in CIDR notation. Empty means any address.
+	Src string `json:"src"`
+	Dst string `json:"dst"`
+	// IP protocol number, e.g., 6 for TCP and 17 for UDP. 0 means any protocol.
+	Protocol uint8 `json:"protocol"`
+	// TCP or UDP port numbers. 0 means any port.
+	SrcPort     uint16 `json:"src_port"`
+	DstPort     uint16 `json:"dst_port"`
+	Description string `json:"description"`
+}
+
+func (r *FirewallRuleParam) validate() error {
+	if r.Priority > maxFirewallPriority {
+		return fmt.Errorf("priority should be less than or equal to %v", maxFirewallPriority)
+	}
+	if r.Action != "allow" && r.Action != "deny" {
+		return errors.New("action should be allow or deny")
+	}
+	for _, v := range []*string{&r.Src, &r.Dst} {
+		if len(*v) == 0 {
+			continue
+		}
+		_, n, err := net.ParseCIDR(*v)
+		if err != nil || n.IP.To4() == nil {
+			return fmt.Errorf("invalid IPv4 network: %v", *v)
+		}
+		// Normalize the address, e.g., 10.0.0.1/8 to 10.0.0.0/8.
+		*v = n.String()
+	}
+	if (r.SrcPort != 0 || r.DstPort != 0) && r.Protocol != 6 && r.Protocol != 17 {
+		return errors.New("port number without TCP or UDP protocol")
+	}
+
+	return nil
+}
+
+type FirewallRule struct {
+	ID uint64 `json:"id"`
+	FirewallRuleParam
+	// Number of the packets and bytes matched by the flows of the rule on all the switches,
+	// which are gathered from the flow statistics.
+	Packets uint64 `json:"packets"`
+	Bytes   uint64 `json:"bytes"`
+}
+
+func (r *Controller) listFirewallRules(w rest.ResponseWriter, req *rest.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	rules, err := r.db.FirewallRules()
+	if err != nil {
+		logger.Errorf("failed to query database: %v", err)
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// Key is the cookie of the flows of a rule.
+	index := make(map[uint64]int)
+	for i, v := range rules {
+		index[NewCookie(firewallApp, v.ID)] = i
+	}
+	for _, d := range r.topo.Devices() {
+		stats, _ := d.FlowStats()
+		for _, s := range stats {
+			i, ok := index[s.Cookie]
+			if !ok {
+				continue
+			}
+			rules[i].Packets += s.PacketCount
+			rules[i].Bytes += s.ByteCount
+		}
+	}
+
+	w.WriteJson(&struct {
+		Rules []FirewallRule `json:"rules"`
+	}{rules})
+}
+
+func (r *Controller) addFirewallRule(w rest.ResponseWriter, req *rest.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	rule := FirewallRuleParam{}
+	if err := req.DecodeJsonPayload(&rule); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := rule.validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	id, err := r.db.AddFirewallRule(rule)
+	if err != nil {
+		logger.Errorf("failed to query database: %v", err)
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	logger.Infof("added a new firewall rule (ID=%v, %+v)", id, rule)
+
+	w.WriteJson(&struct {
+		ID uint64 `json:"rule_id"`
+	}{id})
+}
+
+func (r *Controller) removeFirewallRule(w rest.ResponseWriter, req *rest.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	id, err := strconv.ParseUint(req.PathParam("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	ok, err := r.db.RemoveFirewallRule(id)
+	if err != nil {
+		logger.Errorf("failed to query database: %v", err)
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if !ok {
+		writeError(w, http.StatusNotFound, errors.New("unknown firewall rule ID"))
+		return
+	}
+	logger.Infof("removed the firewall rule (ID=%v)", id)
+
+	w.WriteJson(&struct{}{})
+}
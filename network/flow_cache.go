@@ -25,6 +25,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/superkkt/cherry/clock"
 	"github.com/superkkt/cherry/openflow"
 
 	lru "github.com/hashicorp/golang-lru"
@@ -33,9 +34,14 @@ import (
 type flowCache struct {
 	cache      *lru.Cache
 	expiration time.Duration
+	clock      clock.Clock
 }
 
-func newFlowCache(expiration time.Duration) *flowCache {
+func newFlowCache(expiration time.Duration, clk clock.Clock) *flowCache {
+	if clk == nil {
+		panic("clock is nil")
+	}
+
 	c, err := lru.New(8192)
 	if err != nil {
 		panic(fmt.Sprintf("failed to init a LRU flow cache: %v", err))
@@ -44,16 +50,17 @@ func newFlowCache(expiration time.Duration) *flowCache {
 	return &flowCache{
 		cache:      c,
 		expiration: expiration,
+		clock:      clk,
 	}
 }
 
-func (r *flowCache) Add(match openflow.Match, port openflow.OutPort) error {
-	key, err := r.key(match, port)
+func (r *flowCache) Add(match openflow.Match, out interface{}) error {
+	key, err := r.key(match, out)
 	if err != nil {
 		return err
 	}
 
-	t := time.Now()
+	t := r.clock.Now()
 	// Update if the key already exists.
 	r.cache.Add(key, t)
 	logger.Debugf("added a new flow cache: key=%v, timestamp=%v", key, t)
@@ -61,17 +68,18 @@ func (r *flowCache) Add(match openflow.Match, port openflow.OutPort) error {
 	return nil
 }
 
-func (r *flowCache) key(match openflow.Match, port openflow.OutPort) (string, error) {
+// key identifies a flow by its match and out, which is either the output port or the group of the flow.
+func (r *flowCache) key(match openflow.Match, out interface{}) (string, error) {
 	m, err := match.MarshalBinary()
 	if err != nil {
 		return "", err
 	}
 
-	return fmt.Sprintf("%v/%v", m, port), nil
+	return fmt.Sprintf("%v/%v", m, out), nil
 }
 
-func (r *flowCache) InProgress(match openflow.Match, port openflow.OutPort) (ok bool, err error) {
-	key, err := r.key(match, port)
+func (r *flowCache) InProgress(match openflow.Match, out interface{}) (ok bool, err error) {
+	key, err := r.key(match, out)
 	if err != nil {
 		return false, err
 	}
@@ -83,7 +91,7 @@ func (r *flowCache) InProgress(match openflow.Match, port openflow.OutPort) (ok
 	timestamp := v.(time.Time)
 
 	// Timeout?
-	if time.Since(timestamp) > r.expiration {
+	if r.clock.Since(timestamp) > r.expiration {
 		r.cache.Remove(key)
 		logger.Debugf("removed the timed-out flow cache: key=%v", key)
 		return false, nil
@@ -0,0 +1,437 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/superkkt/cherry/clock"
+	"github.com/superkkt/cherry/openflow"
+
+	"github.com/superkkt/viper"
+)
+
+// conflictPolicy decides what to do with a flow that conflicts with a flow of another application,
+// i.e., they have the same table and priority, and a packet can match both of them. The switch
+// processes such a packet with one of the flows, which one is undefined.
+type conflictPolicy int
+
+const (
+	// Send the flows without checking the conflicts.
+	conflictIgnore conflictPolicy = iota
+	// Send the conflicting flow after logging a warning.
+	conflictWarn
+	// Do not send the conflicting flow, and return ErrFlowConflict.
+	conflictReject
+	// Raise the priority of the conflicting flow until it does not conflict.
+	conflictAdjust
+)
+
+var (
+	ErrFlowConflict = errors.New("flow conflicts with a flow of another application")
+)
+
+func (r conflictPolicy) String() string {
+	switch r {
+	case conflictIgnore:
+		return "ignore"
+	case conflictWarn:
+		return "warn"
+	case conflictReject:
+		return "reject"
+	case conflictAdjust:
+		return "adjust"
+	default:
+		panic(fmt.Sprintf("unexpected flow conflict policy: %v", int(r)))
+	}
+}
+
+// parseConflictPolicy parses s that should be one of "ignore", "warn", "reject" and "adjust".
+// Empty s means "ignore".
+func parseConflictPolicy(s string) (conflictPolicy, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "ignore":
+		return conflictIgnore, nil
+	case "warn":
+		return conflictWarn, nil
+	case "reject":
+		return conflictReject, nil
+	case "adjust":
+		return conflictAdjust, nil
+	default:
+		return conflictIgnore, fmt.Errorf("unknown flow conflict policy: %v", s)
+	}
+}
+
+func newConflictPolicy() conflictPolicy {
+	policy, err := parseConflictPolicy(viper.GetString("default.flow_conflict"))
+	if err != nil {
+		logger.Errorf("invalid default.flow_conflict: %v (ignore the conflicts)", err)
+		return conflictIgnore
+	}
+
+	return policy
+}
+
+// ownedFlow is a flow installed on behalf of an application, which is the owner of its cookie.
+type ownedFlow struct {
+	owner    uint16
+	cookie   uint64
+	tableID  uint8
+	priority uint16
+	match    FlowMatchParam
+	// Time when the flow will be expired by its timeouts. Zero if it is a permanent flow.
+	expiration time.Time
+}
+
+// overlaps returns whether a packet can match both r and other.
+func (r FlowMatchParam) overlaps(other FlowMatchParam) bool {
+	overlapString := func(a, b string) bool { return a == "" || b == "" || a == b }
+	overlapValue := func(a, b uint32) bool { return a == 0 || b == 0 || a == b }
+	overlapNet := func(a, b string) bool {
+		if a == "" || b == "" {
+			return true
+		}
+		_, x, err1 := net.ParseCIDR(a)
+		_, y, err2 := net.ParseCIDR(b)
+		if err1 != nil || err2 != nil {
+			return a == b
+		}
+		return x.Contains(y.IP) || y.Contains(x.IP)
+	}
+
+	return overlapValue(r.InPort, other.InPort) &&
+		overlapString(r.SrcMAC, other.SrcMAC) && overlapString(r.DstMAC, other.DstMAC) &&
+		overlapValue(uint32(r.EtherType), uint32(other.EtherType)) &&
+		overlapValue(uint32(r.IPProtocol), uint32(other.IPProtocol)) &&
+		overlapNet(r.SrcIP, other.SrcIP) && overlapNet(r.DstIP, other.DstIP) &&
+		overlapValue(uint32(r.SrcPort), uint32(other.SrcPort)) && overlapValue(uint32(r.DstPort), uint32(other.DstPort))
+}
+
+// flowRegistry keeps the flows that the applications have installed on a device, and checks
+// a new flow against the flows of the other applications according to the conflict policy.
+// The flows without the owner in their cookies, such as the normal flows, are not checked.
+// Only the match fields of FlowMatchParam are compared, so the flows that differ in the other
+// fields are also regarded as overlapped.
+type flowRegistry struct {
+	mutex  sync.Mutex
+	policy conflictPolicy
+	clock  clock.Clock
+	// Key is made by flowKey.
+	flows map[string]ownedFlow
+	// Priorities raised by the adjust policy. Key is made by adjustedKey with the priority
+	// requested by the owner, and value is the raised one.
+	adjusted map[string]uint16
+}
+
+func newFlowRegistry(policy conflictPolicy, clk clock.Clock) *flowRegistry {
+	if clk == nil {
+		panic("clock is nil")
+	}
+
+	return &flowRegistry{
+		policy:   policy,
+		clock:    clk,
+		flows:    make(map[string]ownedFlow),
+		adjusted: make(map[string]uint16),
+	}
+}
+
+func adjustedKey(owner uint16, tableID uint8, priority uint16, match openflow.Match) (string, error) {
+	key, err := flowKey(tableID, priority, match)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%v/%v", owner, key), nil
+}
+
+// Check examines flow before it is sent to the device. It returns ErrFlowConflict if flow
+// should not be sent. The priority of flow is changed if it has been raised by the adjust
+// policy, so that the owner can modify and remove the flow with the requested priority.
+func (r *flowRegistry) Check(flow openflow.FlowMod) error {
+	if r.policy == conflictIgnore {
+		return nil
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.expire()
+	switch flow.Command() {
+	case openflow.FlowAdd:
+		return r.add(flow)
+	case openflow.FlowModifyStrict:
+		_, err := r.translate(flow)
+		return err
+	case openflow.FlowDeleteStrict:
+		adjusted, err := r.translate(flow)
+		if err != nil {
+			return err
+		}
+		delete(r.adjusted, adjusted)
+		key, err := flowKey(flow.TableID(), flow.Priority(), flow.FlowMatch())
+		if err != nil {
+			return err
+		}
+		delete(r.flows, key)
+	case openflow.FlowDelete:
+		r.remove(flow.TableID(), flow.FlowMatch(), flow.Cookie(), flow.CookieMask())
+	}
+
+	return nil
+}
+
+// translate replaces the priority of flow with the adjusted one, if any, and returns the key
+// of the adjusted priority.
+// XXX: Caller should lock the mutex
+func (r *flowRegistry) translate(flow openflow.FlowMod) (string, error) {
+	owner, ok := CookieOwner(flow.Cookie())
+	if !ok {
+		return "", nil
+	}
+	key, err := adjustedKey(owner, flow.TableID(), flow.Priority(), flow.FlowMatch())
+	if err != nil {
+		return "", err
+	}
+	if priority, ok := r.adjusted[key]; ok {
+		flow.SetPriority(priority)
+	}
+
+	return key, nil
+}
+
+// XXX: Caller should lock the mutex
+func (r *flowRegistry) add(flow openflow.FlowMod) error {
+	owner, ok := CookieOwner(flow.Cookie())
+	if !ok {
+		return nil
+	}
+	// A flow added again keeps its adjusted priority.
+	adjusted, err := r.translate(flow)
+	if err != nil {
+		return err
+	}
+
+	v := ownedFlow{
+		owner:    owner,
+		cookie:   flow.Cookie(),
+		tableID:  flow.TableID(),
+		priority: flow.Priority(),
+		match:    newFlowMatchParam(flow.FlowMatch()),
+	}
+	if t := expiration(flow.IdleTimeout(), flow.HardTimeout()); t > 0 {
+		v.expiration = r.clock.Now().Add(t)
+	}
+
+	if conflict, ok := r.conflict(v); ok {
+		switch r.policy {
+		case conflictWarn:
+			logger.Warningf("flow conflicts with a flow of another application: owner=%v, table=%v, priority=%v, match=%+v, conflict=%+v",
+				owner, v.tableID, v.priority, v.match, conflict)
+		case conflictReject:
+			logger.Warningf("rejected the flow that conflicts with a flow of another application: owner=%v, table=%v, priority=%v, match=%+v, conflict=%+v",
+				owner, v.tableID, v.priority, v.match, conflict)
+			return ErrFlowConflict
+		case conflictAdjust:
+			if !r.raise(&v, flow.FlowMatch()) {
+				logger.Warningf("rejected the flow that conflicts with the flows of other applications at all the higher priorities: owner=%v, table=%v, priority=%v, match=%+v",
+					owner, v.tableID, v.priority, v.match)
+				return ErrFlowConflict
+			}
+			logger.Infof("raised the priority of the flow that conflicts with a flow of another application: owner=%v, table=%v, priority=%v->%v, match=%+v, conflict=%+v",
+				owner, v.tableID, flow.Priority(), v.priority, v.match, conflict)
+			r.adjusted[adjusted] = v.priority
+			flow.SetPriority(v.priority)
+		default:
+			panic(fmt.Sprintf("unexpected flow conflict policy: %v", r.policy))
+		}
+	}
+
+	key, err := flowKey(v.tableID, v.priority, flow.FlowMatch())
+	if err != nil {
+		return err
+	}
+	r.flows[key] = v
+
+	return nil
+}
+
+// expiration returns the time after which a flow with the timeouts is expired. The idle timeout is
+// regarded as the hard timeout because the controller does not know when the last packet matched.
+func expiration(idleTimeout, hardTimeout uint16) time.Duration {
+	t := hardTimeout
+	if t == 0 || (idleTimeout > 0 && idleTimeout < t) {
+		t = idleTimeout
+	}
+
+	return time.Duration(t) * time.Second
+}
+
+// conflict returns a flow of another owner that conflicts with flow.
+// XXX: Caller should lock the mutex
+func (r *flowRegistry) conflict(flow ownedFlow) (conflict ownedFlow, ok bool) {
+	for _, v := range r.flows {
+		if v.owner == flow.owner || v.tableID != flow.tableID || v.priority != flow.priority {
+			continue
+		}
+		if v.match.overlaps(flow.match) {
+			return v, true
+		}
+	}
+
+	return ownedFlow{}, false
+}
+
+// raise increases the priority of flow until it does not conflict with the flows of the other
+// owners. It returns false if there is no such priority.
+// XXX: Caller should lock the mutex
+func (r *flowRegistry) raise(flow *ownedFlow, match openflow.Match) bool {
+	for p := uint32(flow.priority) + 1; p <= 0xFFFF; p++ {
+		v := *flow
+		v.priority = uint16(p)
+		if _, ok := r.conflict(v); ok {
+			continue
+		}
+		// Do not replace another flow of the same owner.
+		key, err := flowKey(v.tableID, v.priority, match)
+		if err != nil {
+			return false
+		}
+		if _, ok := r.flows[key]; ok {
+			continue
+		}
+		flow.priority = v.priority
+		return true
+	}
+
+	return false
+}
+
+// XXX: Caller should lock the mutex
+func (r *flowRegistry) expire() {
+	now := r.clock.Now()
+	for key, v := range r.flows {
+		if !v.expiration.IsZero() && now.After(v.expiration) {
+			delete(r.flows, key)
+		}
+	}
+}
+
+// XXX: Caller should lock the mutex
+func (r *flowRegistry) remove(tableID uint8, match openflow.Match, cookie, mask uint64) {
+	filter := newFlowMatchParam(match)
+	for key, v := range r.flows {
+		// 0xFF means all tables.
+		if tableID != 0xFF && tableID != v.tableID {
+			continue
+		}
+		if !matchCookie(v.cookie, cookie, mask) {
+			continue
+		}
+		if v.match.covered(filter) {
+			delete(r.flows, key)
+		}
+	}
+}
+
+// covered returns whether all the fields that are not wildcards in filter have the same values in r.
+func (r FlowMatchParam) covered(filter FlowMatchParam) bool {
+	if filter.InPort != 0 && filter.InPort != r.InPort {
+		return false
+	}
+	if len(filter.SrcMAC) > 0 && filter.SrcMAC != r.SrcMAC {
+		return false
+	}
+	if len(filter.DstMAC) > 0 && filter.DstMAC != r.DstMAC {
+		return false
+	}
+	if filter.EtherType != 0 && filter.EtherType != r.EtherType {
+		return false
+	}
+	if filter.IPProtocol != 0 && filter.IPProtocol != r.IPProtocol {
+		return false
+	}
+	if len(filter.SrcIP) > 0 && filter.SrcIP != r.SrcIP {
+		return false
+	}
+	if len(filter.DstIP) > 0 && filter.DstIP != r.DstIP {
+		return false
+	}
+	if filter.SrcPort != 0 && filter.SrcPort != r.SrcPort {
+		return false
+	}
+	if filter.DstPort != 0 && filter.DstPort != r.DstPort {
+		return false
+	}
+
+	return true
+}
+
+// Removed forgets the flow that the device has removed, e.g., by its timeouts.
+func (r *flowRegistry) Removed(flow openflow.FlowRemoved) error {
+	if r.policy == conflictIgnore {
+		return nil
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	key, err := flowKey(flow.TableID(), flow.Priority(), flow.Match())
+	if err != nil {
+		return err
+	}
+	delete(r.flows, key)
+
+	return nil
+}
+
+// Forget forgets flow whose ADD command has not been applied by the device.
+func (r *flowRegistry) Forget(flow openflow.FlowMod) error {
+	if r.policy == conflictIgnore || flow.Command() != openflow.FlowAdd {
+		return nil
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	key, err := flowKey(flow.TableID(), flow.Priority(), flow.FlowMatch())
+	if err != nil {
+		return err
+	}
+	delete(r.flows, key)
+
+	return nil
+}
+
+func (r *flowRegistry) RemoveAll() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.flows = make(map[string]ownedFlow)
+	r.adjusted = make(map[string]uint16)
+}
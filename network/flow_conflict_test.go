@@ -0,0 +1,172 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/superkkt/cherry/openflow"
+	"github.com/superkkt/cherry/openflow/of13"
+	"github.com/superkkt/cherry/testutil"
+)
+
+func newOwnedFlow(t *testing.T, owner string, cmd openflow.FlowModCmd, dstMAC net.HardwareAddr, idleTimeout uint16) openflow.FlowMod {
+	flow := newTestFlow(t, of13.NewFactory(), cmd, dstMAC, idleTimeout)
+	flow.SetCookie(NewCookie(owner, 1))
+
+	return flow
+}
+
+func TestParseConflictPolicy(t *testing.T) {
+	for s, expected := range map[string]conflictPolicy{"": conflictIgnore, "ignore": conflictIgnore, "Warn": conflictWarn, "reject": conflictReject, " adjust ": conflictAdjust} {
+		policy, err := parseConflictPolicy(s)
+		if err != nil {
+			t.Fatalf("%q: %v", s, err)
+		}
+		if policy != expected {
+			t.Fatalf("%q: expected=%v, got=%v", s, expected, policy)
+		}
+	}
+	if _, err := parseConflictPolicy("drop"); err == nil {
+		t.Fatal("expected an error for an unknown policy")
+	}
+}
+
+func TestFlowConflictReject(t *testing.T) {
+	clock := testutil.NewFakeClock(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))
+	registry := newFlowRegistry(conflictReject, clock)
+	mac1 := net.HardwareAddr{0x0a, 0, 0, 0, 0, 1}
+	mac2 := net.HardwareAddr{0x0a, 0, 0, 0, 0, 2}
+
+	if err := registry.Check(newOwnedFlow(t, "L2Switch", openflow.FlowAdd, mac1, 0)); err != nil {
+		t.Fatal(err)
+	}
+	// The same application can replace its own flow.
+	if err := registry.Check(newOwnedFlow(t, "L2Switch", openflow.FlowAdd, mac1, 0)); err != nil {
+		t.Fatal(err)
+	}
+	// A wildcard overlaps the destination MAC.
+	if err := registry.Check(newOwnedFlow(t, "Firewall", openflow.FlowAdd, nil, 0)); err != ErrFlowConflict {
+		t.Fatalf("expected ErrFlowConflict, got=%v", err)
+	}
+	if err := registry.Check(newOwnedFlow(t, "Firewall", openflow.FlowAdd, mac2, 0)); err != nil {
+		t.Fatal(err)
+	}
+	// Flows without the owner are not checked.
+	if err := registry.Check(newTestFlow(t, of13.NewFactory(), openflow.FlowAdd, mac1, 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	// The flow of L2Switch is removed.
+	if err := registry.Check(newOwnedFlow(t, "L2Switch", openflow.FlowDeleteStrict, mac1, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.Check(newOwnedFlow(t, "Firewall", openflow.FlowAdd, mac1, 0)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFlowConflictExpiration(t *testing.T) {
+	clock := testutil.NewFakeClock(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))
+	registry := newFlowRegistry(conflictReject, clock)
+	mac := net.HardwareAddr{0x0a, 0, 0, 0, 0, 1}
+
+	if err := registry.Check(newOwnedFlow(t, "L2Switch", openflow.FlowAdd, mac, 30)); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.Check(newOwnedFlow(t, "Firewall", openflow.FlowAdd, mac, 0)); err != ErrFlowConflict {
+		t.Fatalf("expected ErrFlowConflict, got=%v", err)
+	}
+	clock.Advance(31 * time.Second)
+	if err := registry.Check(newOwnedFlow(t, "Firewall", openflow.FlowAdd, mac, 0)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFlowConflictWarn(t *testing.T) {
+	clock := testutil.NewFakeClock(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))
+	registry := newFlowRegistry(conflictWarn, clock)
+	mac := net.HardwareAddr{0x0a, 0, 0, 0, 0, 1}
+
+	if err := registry.Check(newOwnedFlow(t, "L2Switch", openflow.FlowAdd, mac, 0)); err != nil {
+		t.Fatal(err)
+	}
+	flow := newOwnedFlow(t, "Firewall", openflow.FlowAdd, mac, 0)
+	if err := registry.Check(flow); err != nil {
+		t.Fatal(err)
+	}
+	if flow.Priority() != 100 {
+		t.Fatalf("unexpected priority: %v", flow.Priority())
+	}
+}
+
+func TestFlowConflictAdjust(t *testing.T) {
+	clock := testutil.NewFakeClock(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))
+	registry := newFlowRegistry(conflictAdjust, clock)
+	mac := net.HardwareAddr{0x0a, 0, 0, 0, 0, 1}
+
+	if err := registry.Check(newOwnedFlow(t, "L2Switch", openflow.FlowAdd, mac, 0)); err != nil {
+		t.Fatal(err)
+	}
+	flow := newOwnedFlow(t, "Firewall", openflow.FlowAdd, mac, 0)
+	if err := registry.Check(flow); err != nil {
+		t.Fatal(err)
+	}
+	if flow.Priority() != 101 {
+		t.Fatalf("expected priority=101, got=%v", flow.Priority())
+	}
+	// Another application is raised above the both flows.
+	flow = newOwnedFlow(t, "Router", openflow.FlowAdd, nil, 0)
+	if err := registry.Check(flow); err != nil {
+		t.Fatal(err)
+	}
+	if flow.Priority() != 102 {
+		t.Fatalf("expected priority=102, got=%v", flow.Priority())
+	}
+
+	// The owner modifies and removes the flow with the requested priority.
+	flow = newOwnedFlow(t, "Firewall", openflow.FlowModifyStrict, mac, 0)
+	if err := registry.Check(flow); err != nil {
+		t.Fatal(err)
+	}
+	if flow.Priority() != 101 {
+		t.Fatalf("expected priority=101, got=%v", flow.Priority())
+	}
+	flow = newOwnedFlow(t, "Firewall", openflow.FlowDeleteStrict, mac, 0)
+	if err := registry.Check(flow); err != nil {
+		t.Fatal(err)
+	}
+	if flow.Priority() != 101 {
+		t.Fatalf("expected priority=101, got=%v", flow.Priority())
+	}
+
+	// The adjustment is forgotten after the removal.
+	flow = newOwnedFlow(t, "Firewall", openflow.FlowAdd, mac, 0)
+	if err := registry.Check(flow); err != nil {
+		t.Fatal(err)
+	}
+	if flow.Priority() != 101 {
+		t.Fatalf("expected priority=101, got=%v", flow.Priority())
+	}
+}
@@ -0,0 +1,426 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/superkkt/cherry/openflow"
+	"github.com/superkkt/cherry/openflow/of10"
+	"github.com/superkkt/cherry/openflow/of13"
+
+	"github.com/ant0ine/go-json-rest/rest"
+)
+
+// flowIntentVersion is the schema version of the flow intents that we save. Increase it, and
+// upgrade the older versions in decodeFlowIntent, whenever FlowIntent is changed incompatibly.
+const flowIntentVersion = 1
+
+// FlowIntent is a permanent flow that the controller wants to keep on a device. The intents are
+// saved in the database so that a restarted controller can still tell its flows from the
+// leftovers on the devices.
+type FlowIntent struct {
+	DPID     string `json:"dpid"`
+	TableID  uint8  `json:"table_id"`
+	Priority uint16 `json:"priority"`
+	Cookie   uint64 `json:"cookie"`
+	// Name of the application that owns the flow, or its cookie owner ID if the application
+	// has not been registered. Empty if no application owns the flow.
+	Owner string `json:"owner"`
+	// FLOW_MOD message encoded in the protocol version of the device.
+	FlowMod []byte `json:"flow_mod"`
+	// Stale is true if the owner application is not loaded. It is not saved.
+	Stale bool `json:"stale"`
+}
+
+// FlowIntentRecord is a flow intent saved in the database. Data is the intent encoded in the
+// schema whose version is Version.
+type FlowIntentRecord struct {
+	DPID uint64
+	// Hash of the key that identifies the flow in the device.
+	Key     string
+	Version int
+	Data    []byte
+}
+
+// FlowIntentParam is the exported flow intents, which can be imported again.
+type FlowIntentParam struct {
+	Version int          `json:"version"`
+	Intents []FlowIntent `json:"intents"`
+}
+
+func newFlowIntent(id string, flow openflow.FlowMod) (FlowIntent, error) {
+	data, err := flow.MarshalBinary()
+	if err != nil {
+		return FlowIntent{}, err
+	}
+
+	return FlowIntent{
+		DPID:     id,
+		TableID:  flow.TableID(),
+		Priority: flow.Priority(),
+		Cookie:   flow.Cookie(),
+		Owner:    cookieOwnerName(flow.Cookie()),
+		FlowMod:  data,
+	}, nil
+}
+
+// isStaleFlow returns whether the flow whose cookie is cookie is owned by an application that
+// is not loaded, e.g., because it has been disabled in the config file since the flow was saved.
+func isStaleFlow(cookie uint64) bool {
+	id, ok := CookieOwner(cookie)
+	if !ok {
+		// The flows that have no owner are installed by the REST API.
+		return false
+	}
+
+	return !isLoadedCookieOwner(id)
+}
+
+// decodeFlowIntent decodes the record, which may have been saved in an older schema.
+func decodeFlowIntent(record FlowIntentRecord) (FlowIntent, error) {
+	var v FlowIntent
+	switch record.Version {
+	case 1:
+		if err := json.Unmarshal(record.Data, &v); err != nil {
+			return FlowIntent{}, err
+		}
+	default:
+		return FlowIntent{}, fmt.Errorf("unsupported flow intent version: %v", record.Version)
+	}
+	// The record does not tell whether the owner is loaded now.
+	v.Stale = false
+
+	return v, nil
+}
+
+// flowMod decodes the FLOW_MOD of the intent, which should be a permanent flow to add.
+func (r FlowIntent) flowMod() (openflow.FlowMod, error) {
+	if len(r.FlowMod) == 0 {
+		return nil, errors.New("empty FLOW_MOD")
+	}
+
+	var f openflow.Factory
+	switch r.FlowMod[0] {
+	case openflow.OF10_VERSION:
+		f = of10.NewFactory()
+	// OpenFlow 1.4 has the same FLOW_MOD as 1.3.
+	case openflow.OF13_VERSION, openflow.OF14_VERSION:
+		f = of13.NewFactory()
+	default:
+		return nil, fmt.Errorf("unsupported FLOW_MOD version: %v", r.FlowMod[0])
+	}
+	flow, err := f.NewFlowMod(openflow.FlowAdd)
+	if err != nil {
+		return nil, err
+	}
+	if err := flow.UnmarshalBinary(r.FlowMod); err != nil {
+		return nil, err
+	}
+	if flow.Command() != openflow.FlowAdd || !isPermanent(flow.Cookie(), flow.IdleTimeout(), flow.HardTimeout()) {
+		return nil, errors.New("not a permanent flow to add")
+	}
+
+	return flow, nil
+}
+
+func hashFlowKey(key string) string {
+	v := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(v[:])
+}
+
+type flowIntentKey struct {
+	dpid uint64
+	key  string
+}
+
+// flowIntentStore saves the changes of the shadow flow tables in the database. The changes are
+// written by a goroutine so that the callers, which hold the locks of the devices, do not wait
+// for the database. Only the last change of each flow is written.
+type flowIntentStore struct {
+	db    database
+	mutex sync.Mutex
+	// nil value means that the intent has been removed.
+	pending map[flowIntentKey]*FlowIntentRecord
+	// DPIDs of the devices whose intents have been removed all before the pending changes.
+	cleared map[uint64]bool
+	signal  chan struct{}
+	// Only one goroutine writes the changes at a time so that they are written in order.
+	writer sync.Mutex
+}
+
+func newFlowIntentStore(db database) *flowIntentStore {
+	return &flowIntentStore{
+		db:      db,
+		pending: make(map[flowIntentKey]*FlowIntentRecord),
+		cleared: make(map[uint64]bool),
+		signal:  make(chan struct{}, 1),
+	}
+}
+
+func (r *flowIntentStore) notify() {
+	select {
+	case r.signal <- struct{}{}:
+	default:
+		// The writer has been already notified.
+	}
+}
+
+// run writes the changes whenever they are made. It never returns.
+func (r *flowIntentStore) run() {
+	for range r.signal {
+		if err := r.flush(); err != nil {
+			logger.Errorf("failed to save the flow intents: %v", err)
+		}
+	}
+}
+
+// flush writes the pending changes. The changes that have failed are discarded as the shadow
+// flow tables are saved again by their next changes.
+func (r *flowIntentStore) flush() error {
+	r.writer.Lock()
+	defer r.writer.Unlock()
+
+	r.mutex.Lock()
+	pending, cleared := r.pending, r.cleared
+	r.pending = make(map[flowIntentKey]*FlowIntentRecord)
+	r.cleared = make(map[uint64]bool)
+	r.mutex.Unlock()
+
+	var lastErr error
+	for dpid := range cleared {
+		if err := r.db.RemoveFlowIntents(dpid); err != nil {
+			lastErr = err
+		}
+	}
+	for k, v := range pending {
+		var err error
+		if v == nil {
+			err = r.db.RemoveFlowIntent(k.dpid, k.key)
+		} else {
+			err = r.db.SaveFlowIntent(*v)
+		}
+		if err != nil {
+			lastErr = err
+		}
+	}
+
+	return lastErr
+}
+
+func parseDPID(id string) (uint64, error) {
+	dpid, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid DPID: %v", id)
+	}
+
+	return dpid, nil
+}
+
+// put saves flow, whose key is key, as an intent of the device whose ID is id.
+func (r *flowIntentStore) put(id, key string, flow openflow.FlowMod) error {
+	dpid, err := parseDPID(id)
+	if err != nil {
+		return err
+	}
+	intent, err := newFlowIntent(id, flow)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(intent)
+	if err != nil {
+		return err
+	}
+
+	k := flowIntentKey{dpid: dpid, key: hashFlowKey(key)}
+	r.mutex.Lock()
+	r.pending[k] = &FlowIntentRecord{DPID: dpid, Key: k.key, Version: flowIntentVersion, Data: data}
+	r.mutex.Unlock()
+	r.notify()
+
+	return nil
+}
+
+// remove removes the intent, whose key is key, of the device whose ID is id.
+func (r *flowIntentStore) remove(id, key string) error {
+	dpid, err := parseDPID(id)
+	if err != nil {
+		return err
+	}
+
+	k := flowIntentKey{dpid: dpid, key: hashFlowKey(key)}
+	r.mutex.Lock()
+	r.pending[k] = nil
+	r.mutex.Unlock()
+	r.notify()
+
+	return nil
+}
+
+// removeAll removes all the intents of the device whose ID is id.
+func (r *flowIntentStore) removeAll(id string) error {
+	dpid, err := parseDPID(id)
+	if err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	for k := range r.pending {
+		if k.dpid == dpid {
+			delete(r.pending, k)
+		}
+	}
+	r.cleared[dpid] = true
+	r.mutex.Unlock()
+	r.notify()
+
+	return nil
+}
+
+// load returns the shadow flow tables restored from the saved intents. Key is the device ID.
+// The intents that cannot be decoded, e.g., saved by a newer controller, are skipped.
+func (r *flowIntentStore) load() (map[string]*flowTable, error) {
+	records, err := r.db.FlowIntents()
+	if err != nil {
+		return nil, err
+	}
+
+	tables := make(map[string]*flowTable)
+	for _, v := range records {
+		intent, err := decodeFlowIntent(v)
+		if err == nil {
+			err = r.restore(tables, intent)
+		}
+		if err != nil {
+			logger.Warningf("skipping a saved flow intent of %v: %v", v.DPID, err)
+			continue
+		}
+	}
+
+	return tables, nil
+}
+
+// restore adds intent to the shadow flow table, which is made if necessary, of its device in tables.
+func (r *flowIntentStore) restore(tables map[string]*flowTable, intent FlowIntent) error {
+	dpid, err := parseDPID(intent.DPID)
+	if err != nil {
+		return err
+	}
+	flow, err := intent.flowMod()
+	if err != nil {
+		return err
+	}
+	key, err := flowKey(flow.TableID(), flow.Priority(), flow.FlowMatch())
+	if err != nil {
+		return err
+	}
+
+	id := strconv.FormatUint(dpid, 10)
+	t, ok := tables[id]
+	if !ok {
+		t = newFlowTable()
+		t.persist(id, r)
+		tables[id] = t
+	}
+	t.restore(key, flow)
+
+	return nil
+}
+
+func (r *Controller) exportFlowIntents(w rest.ResponseWriter, req *rest.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	intents, err := r.topo.flowIntents()
+	if err != nil {
+		logger.Errorf("failed to export the flow intents: %v", err)
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if intents == nil {
+		intents = []FlowIntent{}
+	}
+
+	w.WriteJson(&FlowIntentParam{Version: flowIntentVersion, Intents: intents})
+}
+
+// importFlowIntents adds the flow intents exported by exportFlowIntents to the shadow flow tables.
+// The flows are installed on the devices by the next reconciliation.
+func (r *Controller) importFlowIntents(w rest.ResponseWriter, req *rest.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	if !r.reconcileFlows {
+		writeError(w, http.StatusConflict, errors.New("flow reconciliation is disabled"))
+		return
+	}
+	p := FlowIntentParam{}
+	if err := req.DecodeJsonPayload(&p); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if p.Version <= 0 || p.Version > flowIntentVersion {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("unsupported flow intent version: %v", p.Version))
+		return
+	}
+
+	// Check all the intents before any of them is imported.
+	flows := make([]openflow.FlowMod, len(p.Intents))
+	for i, v := range p.Intents {
+		if _, err := parseDPID(v.DPID); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		flow, err := v.flowMod()
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid flow intent of %v: %v", v.DPID, err))
+			return
+		}
+		flows[i] = flow
+	}
+	for i, v := range p.Intents {
+		if err := r.topo.FlowTable(v.DPID).Update(flows[i]); err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	w.WriteJson(&struct {
+		Imported int `json:"imported"`
+	}{len(p.Intents)})
+}
+
+func (r *Controller) removeStaleFlowIntents(w rest.ResponseWriter, req *rest.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	n := r.topo.removeStaleFlowIntents()
+	logger.Infof("removed %v stale flow intents", n)
+
+	w.WriteJson(&struct {
+		Removed int `json:"removed"`
+	}{n})
+}
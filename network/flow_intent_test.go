@@ -0,0 +1,422 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/superkkt/cherry/clock"
+	"github.com/superkkt/cherry/openflow"
+	"github.com/superkkt/cherry/openflow/of13"
+	"github.com/superkkt/cherry/openflow/transceiver"
+
+	"github.com/ant0ine/go-json-rest/rest"
+)
+
+const (
+	intentTestOwner      = "FlowIntentTest"
+	intentTestStaleOwner = "FlowIntentStale"
+)
+
+func init() {
+	for _, v := range []string{intentTestOwner, intentTestStaleOwner} {
+		if err := RegisterCookieOwner(v); err != nil {
+			panic(err)
+		}
+	}
+	// The stale owner is registered, but not loaded.
+	LoadCookieOwner(intentTestOwner)
+}
+
+// intentDB is a database that keeps the flow intents in memory.
+type intentDB struct {
+	database
+	mutex   sync.Mutex
+	intents map[flowIntentKey]FlowIntentRecord
+}
+
+func newIntentDB() *intentDB {
+	return &intentDB{intents: make(map[flowIntentKey]FlowIntentRecord)}
+}
+
+func (r *intentDB) FlowIntents() ([]FlowIntentRecord, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var result []FlowIntentRecord
+	for _, v := range r.intents {
+		result = append(result, v)
+	}
+
+	return result, nil
+}
+
+func (r *intentDB) SaveFlowIntent(v FlowIntentRecord) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.intents[flowIntentKey{v.DPID, v.Key}] = v
+	return nil
+}
+
+func (r *intentDB) RemoveFlowIntent(dpid uint64, key string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.intents, flowIntentKey{dpid, key})
+	return nil
+}
+
+func (r *intentDB) RemoveFlowIntents(dpid uint64) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for k := range r.intents {
+		if k.dpid == dpid {
+			delete(r.intents, k)
+		}
+	}
+	return nil
+}
+
+func (r *intentDB) len() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return len(r.intents)
+}
+
+func newIntentFlow(t *testing.T, owner string, dstMAC net.HardwareAddr, port uint32) openflow.FlowMod {
+	flow := newOutputFlow(t, of13.NewFactory(), dstMAC, port)
+	if len(owner) > 0 {
+		flow.SetCookie(NewCookie(owner, uint64(port)))
+	}
+
+	return flow
+}
+
+// readFlowMods returns the FLOW_MODs that the switch receives before the next barrier request.
+func readFlowMods(t *testing.T, stream *transceiver.Stream) []openflow.FlowMod {
+	var result []openflow.FlowMod
+	for {
+		packet, err := stream.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch packet[1] {
+		case of13.OFPT_BARRIER_REQUEST:
+			return result
+		case of13.OFPT_FLOW_MOD:
+			flow, err := of13.NewFactory().NewFlowMod(openflow.FlowAdd)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := flow.UnmarshalBinary(packet); err != nil {
+				t.Fatal(err)
+			}
+			result = append(result, flow)
+		}
+	}
+}
+
+func dstMACOf(flow openflow.FlowMod) string {
+	_, mac := flow.FlowMatch().DstMAC()
+	return mac.String()
+}
+
+func TestFlowIntentRestart(t *testing.T) {
+	matched := net.HardwareAddr{0x0a, 0, 0, 0, 0, 1}
+	lost := net.HardwareAddr{0x0a, 0, 0, 0, 0, 2}
+	stale := net.HardwareAddr{0x0a, 0, 0, 0, 0, 3}
+	unknown := net.HardwareAddr{0x0a, 0, 0, 0, 0, 4}
+	removed := net.HardwareAddr{0x0a, 0, 0, 0, 0, 5}
+	db := newIntentDB()
+
+	// The previous run of the controller has installed the flows.
+	store := newFlowIntentStore(db)
+	table := newFlowTable()
+	table.persist("1", store)
+	for _, v := range []openflow.FlowMod{
+		newIntentFlow(t, intentTestOwner, matched, 1),
+		newIntentFlow(t, "", lost, 2),
+		newIntentFlow(t, intentTestStaleOwner, stale, 3),
+		newIntentFlow(t, intentTestOwner, removed, 5),
+		// Flows with a timeout are not saved.
+		newTestFlow(t, of13.NewFactory(), openflow.FlowAdd, unknown, 30),
+	} {
+		if err := table.Update(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := table.Update(newTestFlow(t, of13.NewFactory(), openflow.FlowDeleteStrict, removed, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.flush(); err != nil {
+		t.Fatal(err)
+	}
+	if db.len() != 3 {
+		t.Fatalf("unexpected number of saved intents: expected=3, got=%v", db.len())
+	}
+
+	// The controller restarts.
+	store = newFlowIntentStore(db)
+	tables, err := store.load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	table, ok := tables["1"]
+	if !ok || table.Len() != 3 {
+		t.Fatalf("unexpected restored flow tables: %+v", tables)
+	}
+	if table.Stale() != 1 {
+		t.Fatalf("unexpected number of stale intents: expected=1, got=%v", table.Stale())
+	}
+	intents, err := table.Intents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range intents {
+		if v.Stale != (v.Owner == intentTestStaleOwner) {
+			t.Fatalf("unexpected stale flag: %+v", v)
+		}
+	}
+	if n := len(table.RemoveStale()); n != 1 {
+		t.Fatalf("unexpected number of removed stale intents: expected=1, got=%v", n)
+	}
+
+	// The switch has the matched flow, the flow of the stale intent and an unknown one.
+	d, _, stream, cleanup := newBarrierDevice(t)
+	defer cleanup()
+	d.id = "1"
+	d.session.limiter = newSendLimiter(0, 0, clock.Real)
+	d.setShadowFlows(table)
+	var stats []openflow.FlowStats
+	for _, v := range []openflow.FlowMod{
+		newIntentFlow(t, intentTestOwner, matched, 1),
+		newIntentFlow(t, intentTestStaleOwner, stale, 3),
+		newIntentFlow(t, "", unknown, 4),
+	} {
+		stats = append(stats, openflow.FlowStats{TableID: v.TableID(), Priority: v.Priority(), Cookie: v.Cookie(), Match: v.FlowMatch()})
+	}
+	done := make(chan error, 1)
+	go func() { done <- d.reconcileFlows(stats) }()
+
+	result := make(map[string]openflow.FlowModCmd)
+	for _, v := range readFlowMods(t, stream) {
+		result[dstMACOf(v)] = v.Command()
+		if v.Command() == openflow.FlowAdd && (v.FlowInstruction() == nil || len(v.FlowInstruction().Actions()) != 1) {
+			t.Fatalf("reinstalled flow without the action: %v", dstMACOf(v))
+		}
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]openflow.FlowModCmd{
+		lost.String():    openflow.FlowAdd,
+		stale.String():   openflow.FlowDeleteStrict,
+		unknown.String(): openflow.FlowDeleteStrict,
+	}
+	if len(result) != len(expected) {
+		t.Fatalf("unexpected FLOW_MODs: %v", result)
+	}
+	for mac, cmd := range expected {
+		if result[mac] != cmd {
+			t.Fatalf("unexpected FLOW_MODs: %v", result)
+		}
+	}
+
+	// The application declares the matched flow again with another action, which wins.
+	if err := table.Update(newIntentFlow(t, intentTestOwner, matched, 7)); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.flush(); err != nil {
+		t.Fatal(err)
+	}
+	tables, err = newFlowIntentStore(db).load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	intents, err = tables["1"].Intents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(intents) != 2 {
+		t.Fatalf("unexpected intents: %+v", intents)
+	}
+	for _, v := range intents {
+		flow, err := v.flowMod()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if dstMACOf(flow) == matched.String() && CookieValue(flow.Cookie()) != 7 {
+			t.Fatalf("the declared flow does not replace the saved one: cookie=%#x", flow.Cookie())
+		}
+	}
+}
+
+func TestFlowIntentVersion(t *testing.T) {
+	db := newIntentDB()
+	store := newFlowIntentStore(db)
+	table := newFlowTable()
+	table.persist("1", store)
+	if err := table.Update(newIntentFlow(t, intentTestOwner, net.HardwareAddr{0x0a, 0, 0, 0, 0, 1}, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.flush(); err != nil {
+		t.Fatal(err)
+	}
+	// A newer controller has saved an intent in the schema that we do not know.
+	db.SaveFlowIntent(FlowIntentRecord{DPID: 2, Key: "future", Version: flowIntentVersion + 1, Data: []byte(`{}`)})
+
+	tables, err := newFlowIntentStore(db).load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tables) != 1 || tables["1"].Len() != 1 {
+		t.Fatalf("unexpected restored flow tables: %+v", tables)
+	}
+}
+
+func TestFlowIntentRemoveAll(t *testing.T) {
+	db := newIntentDB()
+	store := newFlowIntentStore(db)
+	table := newFlowTable()
+	table.persist("1", store)
+	add := func(mac net.HardwareAddr) {
+		if err := table.Update(newIntentFlow(t, intentTestOwner, mac, 1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	add(net.HardwareAddr{0x0a, 0, 0, 0, 0, 1})
+	if err := store.flush(); err != nil {
+		t.Fatal(err)
+	}
+	// The flows added after removing all are kept.
+	table.RemoveAll()
+	add(net.HardwareAddr{0x0a, 0, 0, 0, 0, 2})
+	if err := store.flush(); err != nil {
+		t.Fatal(err)
+	}
+	if db.len() != 1 {
+		t.Fatalf("unexpected number of saved intents: expected=1, got=%v", db.len())
+	}
+}
+
+func newFlowIntentAPI(t *testing.T, c *Controller) http.Handler {
+	api := rest.NewApi()
+	router, err := rest.MakeRouter(
+		rest.Get("/api/v1/flow_intents", c.exportFlowIntents),
+		rest.Post("/api/v1/flow_intents", c.importFlowIntents),
+		rest.Delete("/api/v1/flow_intents/stale", c.removeStaleFlowIntents),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	api.SetApp(router)
+
+	return api.MakeHandler()
+}
+
+func newFlowIntentController(t *testing.T, db *intentDB) (*Controller, *flowIntentStore) {
+	topo := newTopology(db, clock.Real)
+	store := newFlowIntentStore(db)
+	if err := topo.setFlowIntentStore(store); err != nil {
+		t.Fatal(err)
+	}
+
+	return &Controller{topo: topo, db: db, clock: clock.Real, reconcileFlows: true}, store
+}
+
+func TestFlowIntentExportImport(t *testing.T) {
+	src, store := newFlowIntentController(t, newIntentDB())
+	for i, owner := range []string{intentTestOwner, intentTestStaleOwner, ""} {
+		flow := newIntentFlow(t, owner, net.HardwareAddr{0x0a, 0, 0, 0, 0, byte(i)}, uint32(i+1))
+		if err := src.topo.FlowTable("1").Update(flow); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	newFlowIntentAPI(t, src).ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/flow_intents", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("failed to export: %v", w.Body.String())
+	}
+	exported := w.Body.Bytes()
+	p := FlowIntentParam{}
+	if err := json.Unmarshal(exported, &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Version != flowIntentVersion || len(p.Intents) != 3 {
+		t.Fatalf("unexpected exported intents: %+v", p)
+	}
+
+	// Restore the backup on another controller.
+	db := newIntentDB()
+	dst, store := newFlowIntentController(t, db)
+	w = httptest.NewRecorder()
+	newFlowIntentAPI(t, dst).ServeHTTP(w, httptest.NewRequest("POST", "/api/v1/flow_intents", bytes.NewReader(exported)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("failed to import: %v", w.Body.String())
+	}
+	if err := store.flush(); err != nil {
+		t.Fatal(err)
+	}
+	if db.len() != 3 {
+		t.Fatalf("unexpected number of saved intents: expected=3, got=%v", db.len())
+	}
+
+	w = httptest.NewRecorder()
+	newFlowIntentAPI(t, dst).ServeHTTP(w, httptest.NewRequest("DELETE", "/api/v1/flow_intents/stale", nil))
+	removed := struct {
+		Removed int `json:"removed"`
+	}{}
+	if err := json.Unmarshal(w.Body.Bytes(), &removed); err != nil || removed.Removed != 1 {
+		t.Fatalf("failed to remove the stale intents: %v", w.Body.String())
+	}
+	if err := store.flush(); err != nil {
+		t.Fatal(err)
+	}
+	if db.len() != 2 {
+		t.Fatalf("unexpected number of saved intents: expected=2, got=%v", db.len())
+	}
+
+	// Unknown schema version.
+	p.Version = flowIntentVersion + 1
+	body, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w = httptest.NewRecorder()
+	newFlowIntentAPI(t, dst).ServeHTTP(w, httptest.NewRequest("POST", "/api/v1/flow_intents", bytes.NewReader(body)))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status: expected=%v, got=%v", http.StatusBadRequest, w.Code)
+	}
+}
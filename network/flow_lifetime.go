@@ -0,0 +1,261 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/superkkt/cherry/clock"
+	"github.com/superkkt/cherry/openflow"
+)
+
+type FlowLifetimeClass int
+
+const (
+	// The flow has no timeouts. It is reinstalled if the device removes it by a timeout,
+	// e.g., the switch imposes its own timeouts on the flows.
+	FlowPermanent FlowLifetimeClass = iota
+	// The flow is removed when no packet has matched it for FlowLifetime.Timeout.
+	FlowIdleExpire
+	// The flow is removed at FlowLifetime.Until. The flow is refreshed with the remaining
+	// time if the schedule is longer than the maximum hard timeout of OpenFlow.
+	FlowScheduled
+)
+
+func (r FlowLifetimeClass) String() string {
+	switch r {
+	case FlowPermanent:
+		return "permanent"
+	case FlowIdleExpire:
+		return "idle-expire"
+	case FlowScheduled:
+		return "scheduled"
+	default:
+		return fmt.Sprintf("unknown(%v)", int(r))
+	}
+}
+
+// FlowLifetime declares how long a flow lives on the device.
+type FlowLifetime struct {
+	Class FlowLifetimeClass
+	// Idle timeout of FlowIdleExpire, which should be between 1 and 65535 seconds.
+	Timeout time.Duration
+	// Time when the flow of FlowScheduled is removed.
+	Until time.Time
+	// Expired is called, if it is not nil, when the flow is removed by its declared lifetime.
+	// It is not called when the flow is deleted explicitly. Expired is called in the goroutine
+	// reading the messages of the device, so it should not block.
+	Expired func(openflow.FlowRemoved)
+}
+
+// Maximum timeout of a FLOW_MOD in seconds.
+const maxFlowTimeout = math.MaxUint16
+
+var (
+	ErrPastSchedule = errors.New("flow schedule has already passed")
+)
+
+// setTimeouts sets the idle and hard timeouts of flow according to the lifetime.
+func (r FlowLifetime) setTimeouts(flow openflow.FlowMod, now time.Time) error {
+	switch r.Class {
+	case FlowPermanent:
+		flow.SetIdleTimeout(0)
+		flow.SetHardTimeout(0)
+	case FlowIdleExpire:
+		timeout := r.Timeout / time.Second
+		if timeout < 1 || timeout > maxFlowTimeout {
+			return fmt.Errorf("invalid idle timeout: %v", r.Timeout)
+		}
+		flow.SetIdleTimeout(uint16(timeout))
+		flow.SetHardTimeout(0)
+	case FlowScheduled:
+		// Rounded down so that the flow is not removed after the schedule.
+		remaining := r.Until.Sub(now) / time.Second
+		if remaining < 1 {
+			return ErrPastSchedule
+		}
+		if remaining > maxFlowTimeout {
+			remaining = maxFlowTimeout
+		}
+		flow.SetIdleTimeout(0)
+		flow.SetHardTimeout(uint16(remaining))
+	default:
+		return fmt.Errorf("unknown flow lifetime class: %v", r.Class)
+	}
+
+	return nil
+}
+
+type lifetimeFlow struct {
+	flow     openflow.FlowMod
+	lifetime FlowLifetime
+}
+
+// flowLifetimes keeps the flows installed with their lifetimes on a device, and decides what
+// to do with them when the device removes them.
+type flowLifetimes struct {
+	mutex sync.Mutex
+	clock clock.Clock
+	// Key is made by flowKey.
+	flows map[string]lifetimeFlow
+}
+
+func newFlowLifetimes(clk clock.Clock) *flowLifetimes {
+	if clk == nil {
+		panic("clock is nil")
+	}
+
+	return &flowLifetimes{
+		clock: clk,
+		flows: make(map[string]lifetimeFlow),
+	}
+}
+
+// Prepare sets the timeouts of flow, which is an ADD command, according to the lifetime.
+func (r *flowLifetimes) Prepare(flow openflow.FlowMod, lifetime FlowLifetime) error {
+	if flow.Command() != openflow.FlowAdd {
+		return errors.New("flow lifetime can be declared only for the ADD command")
+	}
+
+	return lifetime.setTimeouts(flow, r.clock.Now())
+}
+
+// Add keeps flow that has been sent to the device.
+func (r *flowLifetimes) Add(flow openflow.FlowMod, lifetime FlowLifetime) error {
+	key, err := flowKey(flow.TableID(), flow.Priority(), flow.FlowMatch())
+	if err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.flows[key] = lifetimeFlow{flow: flow, lifetime: lifetime}
+
+	return nil
+}
+
+// Removed forgets the flow that the device has removed. It returns the forgotten flow, if
+// any, and whether the flow should be reinstalled. expired is true if the flow has been
+// removed by its declared lifetime.
+func (r *flowLifetimes) Removed(flow openflow.FlowRemoved) (v lifetimeFlow, reinstall, expired bool, err error) {
+	key, err := flowKey(flow.TableID(), flow.Priority(), flow.Match())
+	if err != nil {
+		return lifetimeFlow{}, false, false, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	v, ok := r.flows[key]
+	if !ok {
+		return lifetimeFlow{}, false, false, nil
+	}
+	delete(r.flows, key)
+	reinstall, expired = r.decide(v.lifetime, flow.Reason())
+
+	return v, reinstall, expired, nil
+}
+
+// XXX: Caller should lock the mutex
+func (r *flowLifetimes) decide(lifetime FlowLifetime, reason uint8) (reinstall, expired bool) {
+	if reason != openflow.FlowRemovedIdleTimeout && reason != openflow.FlowRemovedHardTimeout {
+		// Deleted explicitly, or by the removal of its group or meter.
+		return false, false
+	}
+
+	switch lifetime.Class {
+	case FlowIdleExpire:
+		if reason == openflow.FlowRemovedIdleTimeout {
+			return false, true
+		}
+	case FlowScheduled:
+		if r.clock.Now().Add(time.Second).After(lifetime.Until) {
+			return false, true
+		}
+	}
+	// Removed by the timeout imposed by the switch, or earlier than the schedule.
+	return true, false
+}
+
+func (r *flowLifetimes) RemoveAll() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.flows = make(map[string]lifetimeFlow)
+}
+
+// InstallFlowWithLifetime is same as InstallFlow except that the timeouts of flow, which should
+// be an ADD command, are set according to the lifetime, and the flow is managed by the lifetime
+// when the device removes it: the flow is reinstalled if it has been removed earlier than the
+// lifetime, and lifetime.Expired is called if it has been expired by the lifetime. Applications
+// install their flows with FlowPermanent, instead of InstallFlow, so that the flows survive the
+// switches that impose their own timeouts on the flows without timeouts.
+func (r *Device) InstallFlowWithLifetime(flow openflow.FlowMod, lifetime FlowLifetime) error {
+	if flow == nil {
+		return errors.New("nil flow")
+	}
+	if err := r.lifetimes.Prepare(flow, lifetime); err != nil {
+		return err
+	}
+	if err := r.InstallFlow(flow); err != nil {
+		return err
+	}
+
+	return r.lifetimes.Add(flow, lifetime)
+}
+
+// flowRemoved applies the lifetime of the flow that the device has removed.
+func (r *Device) flowRemoved(removed openflow.FlowRemoved) error {
+	v, reinstall, expired, err := r.lifetimes.Removed(removed)
+	if err != nil {
+		return err
+	}
+	if expired {
+		logger.Debugf("flow has been expired by its lifetime (%v) on %v: table=%v, priority=%v", v.lifetime.Class, r.ID(), removed.TableID(), removed.Priority())
+		if v.lifetime.Expired != nil {
+			v.lifetime.Expired(removed)
+		}
+		return nil
+	}
+	if !reinstall {
+		return nil
+	}
+
+	logger.Infof("reinstalling a flow removed earlier than its lifetime (%v) on %v: table=%v, priority=%v, reason=%v", v.lifetime.Class, r.ID(), removed.TableID(), removed.Priority(), removed.Reason())
+	// The packet buffered when the flow was installed is no longer valid.
+	v.flow.SetBufferID(openflow.NoBuffer)
+	err = r.InstallFlowWithLifetime(v.flow, v.lifetime)
+	if err == ErrPastSchedule {
+		// The schedule has passed while the flow was being removed.
+		if v.lifetime.Expired != nil {
+			v.lifetime.Expired(removed)
+		}
+		return nil
+	}
+
+	return err
+}
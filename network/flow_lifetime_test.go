@@ -0,0 +1,122 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/superkkt/cherry/openflow"
+	"github.com/superkkt/cherry/openflow/of13"
+	"github.com/superkkt/cherry/testutil"
+)
+
+// removedFlow is a FLOW_REMOVED of flow with reason.
+type removedFlow struct {
+	openflow.FlowRemoved
+	flow   openflow.FlowMod
+	reason uint8
+}
+
+func (r removedFlow) TableID() uint8        { return r.flow.TableID() }
+func (r removedFlow) Priority() uint16      { return r.flow.Priority() }
+func (r removedFlow) Match() openflow.Match { return r.flow.FlowMatch() }
+func (r removedFlow) Reason() uint8         { return r.reason }
+
+func TestFlowLifetimeTimeouts(t *testing.T) {
+	now := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	flow := newTestFlow(t, of13.NewFactory(), openflow.FlowAdd, nil, 30)
+
+	if err := (FlowLifetime{Class: FlowPermanent}).setTimeouts(flow, now); err != nil {
+		t.Fatal(err)
+	}
+	if flow.IdleTimeout() != 0 || flow.HardTimeout() != 0 {
+		t.Fatalf("unexpected timeouts of a permanent flow: idle=%v, hard=%v", flow.IdleTimeout(), flow.HardTimeout())
+	}
+	if err := (FlowLifetime{Class: FlowIdleExpire, Timeout: 10 * time.Second}).setTimeouts(flow, now); err != nil {
+		t.Fatal(err)
+	}
+	if flow.IdleTimeout() != 10 || flow.HardTimeout() != 0 {
+		t.Fatalf("unexpected timeouts of an idle-expire flow: idle=%v, hard=%v", flow.IdleTimeout(), flow.HardTimeout())
+	}
+	if err := (FlowLifetime{Class: FlowIdleExpire, Timeout: 100 * time.Millisecond}).setTimeouts(flow, now); err == nil {
+		t.Fatal("expected an error for an idle timeout shorter than a second")
+	}
+	// Longer than the maximum hard timeout.
+	if err := (FlowLifetime{Class: FlowScheduled, Until: now.Add(24 * time.Hour)}).setTimeouts(flow, now); err != nil {
+		t.Fatal(err)
+	}
+	if flow.IdleTimeout() != 0 || flow.HardTimeout() != maxFlowTimeout {
+		t.Fatalf("unexpected timeouts of a scheduled flow: idle=%v, hard=%v", flow.IdleTimeout(), flow.HardTimeout())
+	}
+	if err := (FlowLifetime{Class: FlowScheduled, Until: now}).setTimeouts(flow, now); err != ErrPastSchedule {
+		t.Fatalf("expected ErrPastSchedule, got=%v", err)
+	}
+}
+
+func TestFlowLifetimeRemoved(t *testing.T) {
+	clock := testutil.NewFakeClock(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))
+	lifetimes := newFlowLifetimes(clock)
+	mac1 := net.HardwareAddr{0x0a, 0, 0, 0, 0, 1}
+	mac2 := net.HardwareAddr{0x0a, 0, 0, 0, 0, 2}
+	mac3 := net.HardwareAddr{0x0a, 0, 0, 0, 0, 3}
+
+	add := func(mac net.HardwareAddr, lifetime FlowLifetime) openflow.FlowMod {
+		flow := newTestFlow(t, of13.NewFactory(), openflow.FlowAdd, mac, 0)
+		if err := lifetimes.Prepare(flow, lifetime); err != nil {
+			t.Fatal(err)
+		}
+		if err := lifetimes.Add(flow, lifetime); err != nil {
+			t.Fatal(err)
+		}
+		return flow
+	}
+	check := func(flow openflow.FlowMod, reason uint8, reinstall, expired bool) {
+		_, r, e, err := lifetimes.Removed(removedFlow{flow: flow, reason: reason})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r != reinstall || e != expired {
+			t.Fatalf("unexpected decision: reason=%v, reinstall=%v, expired=%v", reason, r, e)
+		}
+	}
+
+	permanent := add(mac1, FlowLifetime{Class: FlowPermanent})
+	check(permanent, openflow.FlowRemovedHardTimeout, true, false)
+	// Already forgotten.
+	check(permanent, openflow.FlowRemovedHardTimeout, false, false)
+	add(mac1, FlowLifetime{Class: FlowPermanent})
+	check(permanent, openflow.FlowRemovedDelete, false, false)
+
+	idle := add(mac2, FlowLifetime{Class: FlowIdleExpire, Timeout: 30 * time.Second})
+	check(idle, openflow.FlowRemovedIdleTimeout, false, true)
+
+	until := clock.Now().Add(24 * time.Hour)
+	scheduled := add(mac3, FlowLifetime{Class: FlowScheduled, Until: until})
+	// Refreshed until the schedule.
+	clock.Advance(maxFlowTimeout * time.Second)
+	check(scheduled, openflow.FlowRemovedHardTimeout, true, false)
+	add(mac3, FlowLifetime{Class: FlowScheduled, Until: until})
+	clock.Advance(until.Sub(clock.Now()) - 500*time.Millisecond)
+	check(scheduled, openflow.FlowRemovedHardTimeout, false, true)
+}
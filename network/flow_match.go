@@ -0,0 +1,206 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/superkkt/cherry/openflow"
+	"github.com/superkkt/cherry/openflow/of10"
+)
+
+// matchField is a match field in the canonical form: value is masked by mask, and an exact
+// field has a mask whose bits are all 1.
+type matchField struct {
+	value, mask []byte
+}
+
+// canonicalMatch is the match fields keyed by their IDs. It does not depend on the order of the
+// fields in the wire format, so two matches that are the same to a switch have the same form.
+type canonicalMatch map[string]matchField
+
+func newCanonicalMatch(match openflow.Match) (canonicalMatch, error) {
+	if match == nil {
+		return nil, fmt.Errorf("nil match")
+	}
+	data, err := match.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := match.(*of10.Match); ok {
+		return parseOF10Match(data)
+	}
+	return parseOXMMatch(data)
+}
+
+func (r canonicalMatch) add(id string, value, mask []byte) {
+	if mask == nil {
+		mask = bytes.Repeat([]byte{0xFF}, len(value))
+	}
+	if len(mask) != len(value) {
+		// Malformed field. Keep it as it is so that it never equals to a valid one.
+		r[id] = matchField{value: value, mask: mask}
+		return
+	}
+	v := make([]byte, len(value))
+	wildcard := true
+	for i := range value {
+		v[i] = value[i] & mask[i]
+		if mask[i] != 0 {
+			wildcard = false
+		}
+	}
+	// A field whose mask is all 0 matches everything.
+	if wildcard {
+		return
+	}
+	r[id] = matchField{value: v, mask: append([]byte(nil), mask...)}
+}
+
+func parseOF10Match(data []byte) (canonicalMatch, error) {
+	if len(data) < 40 {
+		return nil, openflow.ErrInvalidPacketLength
+	}
+
+	result := make(canonicalMatch)
+	w := binary.BigEndian.Uint32(data[0:4])
+	fields := []struct {
+		id       string
+		wildcard uint32
+		value    []byte
+	}{
+		{"in_port", of10.OFPFW_IN_PORT, data[4:6]},
+		{"dl_src", of10.OFPFW_DL_SRC, data[6:12]},
+		{"dl_dst", of10.OFPFW_DL_DST, data[12:18]},
+		{"dl_vlan", of10.OFPFW_DL_VLAN, data[18:20]},
+		{"dl_vlan_pcp", of10.OFPFW_DL_VLAN_PCP, data[20:21]},
+		{"dl_type", of10.OFPFW_DL_TYPE, data[22:24]},
+		{"nw_tos", of10.OFPFW_NW_TOS, data[24:25]},
+		{"nw_proto", of10.OFPFW_NW_PROTO, data[25:26]},
+		{"tp_src", of10.OFPFW_TP_SRC, data[36:38]},
+		{"tp_dst", of10.OFPFW_TP_DST, data[38:40]},
+	}
+	for _, v := range fields {
+		if w&v.wildcard == 0 {
+			result.add(v.id, v.value, nil)
+		}
+	}
+	// The IP addresses are wildcarded by the number of their low-order bits.
+	result.add("nw_src", data[28:32], ipv4Mask((w>>8)&0x3F))
+	result.add("nw_dst", data[32:36], ipv4Mask((w>>14)&0x3F))
+
+	return result, nil
+}
+
+func ipv4Mask(wildcardBits uint32) []byte {
+	mask := make([]byte, 4)
+	if wildcardBits < 32 {
+		binary.BigEndian.PutUint32(mask, ^uint32(0)<<wildcardBits)
+	}
+
+	return mask
+}
+
+func parseOXMMatch(data []byte) (canonicalMatch, error) {
+	if len(data) < 4 {
+		return nil, openflow.ErrInvalidPacketLength
+	}
+	length := int(binary.BigEndian.Uint16(data[2:4]))
+	if length < 4 || len(data) < length {
+		return nil, openflow.ErrInvalidPacketLength
+	}
+
+	result := make(canonicalMatch)
+	buf := data[4:length]
+	for len(buf) >= 4 {
+		header := binary.BigEndian.Uint32(buf[0:4])
+		class := uint16(header >> 16)
+		field := uint8((header >> 9) & 0x7F)
+		hasmask := (header>>8)&0x1 == 1
+		n := int(header & 0xFF)
+		if len(buf) < 4+n {
+			return nil, openflow.ErrInvalidPacketLength
+		}
+		payload := buf[4 : 4+n]
+		buf = buf[4+n:]
+
+		id := fmt.Sprintf("%04x:%02x", class, field)
+		// The experimenter class has a 4-byte experimenter ID before the value and mask.
+		if class == 0xFFFF {
+			if len(payload) < 4 {
+				return nil, openflow.ErrInvalidPacketLength
+			}
+			id = fmt.Sprintf("%v:%x", id, payload[0:4])
+			payload = payload[4:]
+		}
+		if hasmask {
+			half := len(payload) / 2
+			result.add(id, payload[:half], payload[half:])
+		} else {
+			result.add(id, payload, nil)
+		}
+	}
+
+	return result, nil
+}
+
+// String returns the fields sorted by their IDs.
+func (r canonicalMatch) String() string {
+	ids := make([]string, 0, len(r))
+	for id := range r {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	fields := make([]string, len(ids))
+	for i, id := range ids {
+		fields[i] = fmt.Sprintf("%v=%x/%x", id, r[id].value, r[id].mask)
+	}
+
+	return strings.Join(fields, ",")
+}
+
+// coveredBy returns whether all the packets that match r also match filter, i.e., every field of
+// filter exists in r with a mask that includes the filter's one and the same masked value.
+func (r canonicalMatch) coveredBy(filter canonicalMatch) bool {
+	for id, f := range filter {
+		m, ok := r[id]
+		if !ok || len(m.mask) != len(f.mask) {
+			return false
+		}
+		for i := range f.mask {
+			if m.mask[i]&f.mask[i] != f.mask[i] {
+				return false
+			}
+			if m.value[i]&f.mask[i] != f.value[i] {
+				return false
+			}
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,316 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/superkkt/cherry/openflow"
+)
+
+// flowTable is a shadow copy of the permanent flows, which have neither idle nor hard timeout,
+// that the controller has installed on a device. It outlives the connections of the device so
+// that the flows lost by the switch, e.g., by a reboot, can be restored when it reconnects.
+type flowTable struct {
+	mutex sync.RWMutex
+	// Key is made by flowKey.
+	flows map[string]openflow.FlowMod
+	// ID of the device, and the store that saves the changes of the flows. nil store disables it.
+	id    string
+	store *flowIntentStore
+}
+
+func newFlowTable() *flowTable {
+	return &flowTable{
+		flows: make(map[string]openflow.FlowMod),
+	}
+}
+
+// flowKey returns the key that identifies a flow in a switch, which consists of the table ID,
+// priority and match fields. The match fields are in the canonical form so that a switch that
+// reorders them still reports the same key.
+func flowKey(tableID uint8, priority uint16, match openflow.Match) (string, error) {
+	m, err := newCanonicalMatch(match)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%v/%v/%v", tableID, priority, m), nil
+}
+
+// persist saves the flows in store whenever they are changed. id is the ID of the device.
+func (r *flowTable) persist(id string, store *flowIntentStore) {
+	// Write lock
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.id = id
+	r.store = store
+}
+
+// restore adds flow, whose key is key, that has been loaded from the store.
+func (r *flowTable) restore(key string, flow openflow.FlowMod) {
+	// Write lock
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.flows[key] = flow
+}
+
+// XXX: Caller should lock the mutex
+func (r *flowTable) save(key string, flow openflow.FlowMod) {
+	if r.store == nil {
+		return
+	}
+	if err := r.store.put(r.id, key, flow); err != nil {
+		logger.Errorf("failed to save the flow intent of %v: %v", r.id, err)
+	}
+}
+
+// XXX: Caller should lock the mutex
+func (r *flowTable) forget(key string) {
+	if r.store == nil {
+		return
+	}
+	if err := r.store.remove(r.id, key); err != nil {
+		logger.Errorf("failed to remove the flow intent of %v: %v", r.id, err)
+	}
+}
+
+// isPermanent returns whether a flow of the parameters is a permanent normal flow. The special
+// flows whose cookie MSB is 1 are excluded because they are installed whenever a device connects.
+func isPermanent(cookie uint64, idleTimeout, hardTimeout uint16) bool {
+	return cookie&(0x1<<63) == 0 && idleTimeout == 0 && hardTimeout == 0
+}
+
+// covers returns whether match is covered by filter, i.e., all the fields that are not wildcards in
+// filter have the same values, under the filter's masks, in match. A nil filter covers everything.
+func covers(filter, match openflow.Match) bool {
+	if filter == nil {
+		return true
+	}
+	f, err := newCanonicalMatch(filter)
+	if err != nil {
+		return false
+	}
+	m, err := newCanonicalMatch(match)
+	if err != nil {
+		return false
+	}
+
+	return m.coveredBy(f)
+}
+
+// outputsTo returns whether flow has an output action to port. Any flow matches the NONE port.
+func outputsTo(flow openflow.FlowMod, port openflow.OutPort) bool {
+	if port.IsNone() {
+		return true
+	}
+	inst := flow.FlowInstruction()
+	if inst == nil {
+		return false
+	}
+	for _, v := range inst.Actions() {
+		if v.OutPort() == port {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Update applies flow, which has been sent to the device, to the shadow copy. Only the ADD and
+// DELETE commands are tracked.
+func (r *flowTable) Update(flow openflow.FlowMod) error {
+	// Write lock
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	switch flow.Command() {
+	case openflow.FlowAdd:
+		key, err := flowKey(flow.TableID(), flow.Priority(), flow.FlowMatch())
+		if err != nil {
+			return err
+		}
+		// ADD replaces the existing flow that has the same key.
+		if isPermanent(flow.Cookie(), flow.IdleTimeout(), flow.HardTimeout()) {
+			r.flows[key] = flow
+			r.save(key, flow)
+		} else if _, ok := r.flows[key]; ok {
+			delete(r.flows, key)
+			r.forget(key)
+		}
+	case openflow.FlowDelete:
+		r.remove(flow.TableID(), flow.FlowMatch(), flow.OutPort(), flow.Cookie(), flow.CookieMask())
+	case openflow.FlowDeleteStrict:
+		key, err := flowKey(flow.TableID(), flow.Priority(), flow.FlowMatch())
+		if err != nil {
+			return err
+		}
+		if _, ok := r.flows[key]; ok {
+			delete(r.flows, key)
+			r.forget(key)
+		}
+	}
+
+	return nil
+}
+
+// XXX: Caller should lock the mutex
+func (r *flowTable) remove(tableID uint8, match openflow.Match, port openflow.OutPort, cookie, mask uint64) {
+	for key, flow := range r.flows {
+		// 0xFF means all tables.
+		if tableID != 0xFF && tableID != flow.TableID() {
+			continue
+		}
+		if !matchCookie(flow.Cookie(), cookie, mask) {
+			continue
+		}
+		if !outputsTo(flow, port) {
+			continue
+		}
+		if covers(match, flow.FlowMatch()) {
+			delete(r.flows, key)
+			r.forget(key)
+		}
+	}
+}
+
+// Remove removes the flows whose match fields are covered by match in the table whose ID is tableID,
+// like the non-strict DELETE command does. 0xFF means all tables. If port is not NONE, only the
+// flows that have an output action to port are removed.
+func (r *flowTable) Remove(tableID uint8, match openflow.Match, port openflow.OutPort) {
+	// Write lock
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.remove(tableID, match, port, 0, 0)
+}
+
+func (r *flowTable) RemoveAll() {
+	// Write lock
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.flows = make(map[string]openflow.FlowMod)
+	if r.store == nil {
+		return
+	}
+	if err := r.store.removeAll(r.id); err != nil {
+		logger.Errorf("failed to remove the flow intents of %v: %v", r.id, err)
+	}
+}
+
+// Intents returns the flows as the intents of the device.
+func (r *flowTable) Intents() ([]FlowIntent, error) {
+	// Read lock
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	result := make([]FlowIntent, 0, len(r.flows))
+	for _, flow := range r.flows {
+		v, err := newFlowIntent(r.id, flow)
+		if err != nil {
+			return nil, err
+		}
+		v.Stale = isStaleFlow(v.Cookie)
+		result = append(result, v)
+	}
+
+	return result, nil
+}
+
+// Stale returns the number of the flows owned by the applications that are not loaded.
+func (r *flowTable) Stale() int {
+	// Read lock
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	n := 0
+	for _, flow := range r.flows {
+		if isStaleFlow(flow.Cookie()) {
+			n++
+		}
+	}
+
+	return n
+}
+
+// RemoveStale removes the flows owned by the applications that are not loaded. It returns the
+// removed flows.
+func (r *flowTable) RemoveStale() []openflow.FlowMod {
+	// Write lock
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var stale []openflow.FlowMod
+	for key, flow := range r.flows {
+		if !isStaleFlow(flow.Cookie()) {
+			continue
+		}
+		delete(r.flows, key)
+		r.forget(key)
+		stale = append(stale, flow)
+	}
+
+	return stale
+}
+
+func (r *flowTable) Len() int {
+	// Read lock
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return len(r.flows)
+}
+
+// Diff compares the shadow copy with stats collected from the device. missing are the flows that
+// the device has lost, and unknown are the permanent normal flows that we have not installed.
+func (r *flowTable) Diff(stats []openflow.FlowStats) (missing []openflow.FlowMod, unknown []openflow.FlowStats, err error) {
+	// Read lock
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	found := make(map[string]bool)
+	for _, v := range stats {
+		key, err := flowKey(v.TableID, v.Priority, v.Match)
+		if err != nil {
+			return nil, nil, err
+		}
+		found[key] = true
+
+		if _, ok := r.flows[key]; ok {
+			continue
+		}
+		if isPermanent(v.Cookie, v.IdleTimeout, v.HardTimeout) {
+			unknown = append(unknown, v)
+		}
+	}
+	for key, flow := range r.flows {
+		if !found[key] {
+			missing = append(missing, flow)
+		}
+	}
+
+	return missing, unknown, nil
+}
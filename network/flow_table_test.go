@@ -0,0 +1,255 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/superkkt/cherry/openflow"
+	"github.com/superkkt/cherry/openflow/of13"
+)
+
+func newTestFlow(t *testing.T, f openflow.Factory, cmd openflow.FlowModCmd, dstMAC net.HardwareAddr, idleTimeout uint16) openflow.FlowMod {
+	match, err := f.NewMatch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dstMAC != nil {
+		match.SetDstMAC(dstMAC)
+	}
+	flow, err := f.NewFlowMod(cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flow.SetTableID(0)
+	flow.SetPriority(100)
+	flow.SetIdleTimeout(idleTimeout)
+	flow.SetFlowMatch(match)
+
+	return flow
+}
+
+func TestFlowTable(t *testing.T) {
+	f := of13.NewFactory()
+	mac1 := net.HardwareAddr{0x0a, 0, 0, 0, 0, 1}
+	mac2 := net.HardwareAddr{0x0a, 0, 0, 0, 0, 2}
+	table := newFlowTable()
+
+	// Flows with a timeout are not permanent.
+	if err := table.Update(newTestFlow(t, f, openflow.FlowAdd, mac1, 90)); err != nil {
+		t.Fatal(err)
+	}
+	if table.Len() != 0 {
+		t.Fatalf("unexpected number of flows: expected=0, got=%v", table.Len())
+	}
+	for _, mac := range []net.HardwareAddr{mac1, mac2} {
+		if err := table.Update(newTestFlow(t, f, openflow.FlowAdd, mac, 0)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if table.Len() != 2 {
+		t.Fatalf("unexpected number of flows: expected=2, got=%v", table.Len())
+	}
+
+	// The device has lost the flow for mac2, and has an unknown permanent flow and a learned one.
+	stats := []openflow.FlowStats{
+		{TableID: 0, Priority: 100, Match: newTestFlow(t, f, openflow.FlowAdd, mac1, 0).FlowMatch()},
+		{TableID: 0, Priority: 200, Match: newTestFlow(t, f, openflow.FlowAdd, mac1, 0).FlowMatch()},
+		{TableID: 0, Priority: 10, IdleTimeout: 90, Match: newTestFlow(t, f, openflow.FlowAdd, mac2, 0).FlowMatch()},
+	}
+	missing, unknown, err := table.Diff(stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(missing) != 1 {
+		t.Fatalf("unexpected number of missing flows: expected=1, got=%v", len(missing))
+	}
+	if _, mac := missing[0].FlowMatch().DstMAC(); mac.String() != mac2.String() {
+		t.Fatalf("unexpected missing flow: %v", mac)
+	}
+	if len(unknown) != 1 || unknown[0].Priority != 200 {
+		t.Fatalf("unexpected unknown flows: %+v", unknown)
+	}
+
+	// Non-strict DELETE removes the covered flows.
+	if err := table.Update(newTestFlow(t, f, openflow.FlowDelete, mac2, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if table.Len() != 1 {
+		t.Fatalf("unexpected number of flows: expected=1, got=%v", table.Len())
+	}
+	if err := table.Update(newTestFlow(t, f, openflow.FlowDelete, nil, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if table.Len() != 0 {
+		t.Fatalf("unexpected number of flows: expected=0, got=%v", table.Len())
+	}
+}
+
+func TestFlowTableDeleteByCookie(t *testing.T) {
+	f := of13.NewFactory()
+	table := newFlowTable()
+
+	for i, owner := range []string{"Firewall", "Router"} {
+		flow := newTestFlow(t, f, openflow.FlowAdd, net.HardwareAddr{0x0a, 0, 0, 0, 0, byte(i)}, 0)
+		flow.SetCookie(NewCookie(owner, uint64(i)))
+		if err := table.Update(flow); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Only the flows of the owner are removed.
+	flow := newTestFlow(t, f, openflow.FlowDelete, nil, 0)
+	cookie, mask := OwnerCookie("Firewall")
+	flow.SetCookie(cookie)
+	flow.SetCookieMask(mask)
+	if err := table.Update(flow); err != nil {
+		t.Fatal(err)
+	}
+	if table.Len() != 1 {
+		t.Fatalf("unexpected number of flows: expected=1, got=%v", table.Len())
+	}
+}
+
+func newOutputFlow(t *testing.T, f openflow.Factory, dstMAC net.HardwareAddr, port uint32) openflow.FlowMod {
+	flow := newTestFlow(t, f, openflow.FlowAdd, dstMAC, 0)
+	out := openflow.NewOutPort()
+	out.SetValue(port)
+	action, err := f.NewAction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	action.SetOutPort(out)
+	inst, err := f.NewInstruction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	inst.ApplyAction(action)
+	flow.SetFlowInstruction(inst)
+
+	return flow
+}
+
+func TestFlowTableRemoveByOutPort(t *testing.T) {
+	f := of13.NewFactory()
+	table := newFlowTable()
+	for i := 1; i <= 2; i++ {
+		if err := table.Update(newOutputFlow(t, f, net.HardwareAddr{0x0a, 0, 0, 0, 0, byte(i)}, uint32(i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A port down removes only the flows that output to the port even if the match is a wildcard.
+	match, err := f.NewMatch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := openflow.NewOutPort()
+	port.SetValue(1)
+	table.Remove(0xFF, match, port)
+	if table.Len() != 1 {
+		t.Fatalf("unexpected number of flows: expected=1, got=%v", table.Len())
+	}
+	port.SetValue(3)
+	table.Remove(0xFF, match, port)
+	if table.Len() != 1 {
+		t.Fatalf("unexpected number of flows: expected=1, got=%v", table.Len())
+	}
+	port.SetNone()
+	table.Remove(0xFF, match, port)
+	if table.Len() != 0 {
+		t.Fatalf("unexpected number of flows: expected=0, got=%v", table.Len())
+	}
+}
+
+func TestCoversAllFields(t *testing.T) {
+	f := of13.NewFactory()
+	mac := net.HardwareAddr{0x0a, 0, 0, 0, 0, 1}
+
+	filter := newTestFlow(t, f, openflow.FlowDelete, mac, 0).FlowMatch()
+	filter.SetEtherType(0x0800)
+	filter.SetIPProtocol(6)
+	filter.SetIPDSCP(46)
+
+	match := newTestFlow(t, f, openflow.FlowAdd, mac, 0).FlowMatch()
+	match.SetEtherType(0x0800)
+	match.SetIPProtocol(6)
+	// The DSCP is not compared by the match parameters of the REST API.
+	if covers(filter, match) {
+		t.Fatal("a match without the DSCP field is covered by a filter with it")
+	}
+	match.SetIPDSCP(46)
+	if !covers(filter, match) {
+		t.Fatal("a match with all the fields of the filter is not covered by it")
+	}
+
+	// A narrower prefix is covered by a wider one, but not vice versa.
+	_, wide, _ := net.ParseCIDR("10.0.0.0/8")
+	_, narrow, _ := net.ParseCIDR("10.1.0.0/16")
+	filter.SetDstIP(wide)
+	match.SetDstIP(narrow)
+	if !covers(filter, match) {
+		t.Fatal("10.1.0.0/16 is not covered by 10.0.0.0/8")
+	}
+	if covers(match, filter) {
+		t.Fatal("10.0.0.0/8 is covered by 10.1.0.0/16")
+	}
+}
+
+func TestCanonicalMatch(t *testing.T) {
+	ethType := []byte{0x80, 0x00, 0x0a, 0x02, 0x08, 0x00}
+	ipDst := []byte{0x80, 0x00, 0x18, 0x04, 10, 0, 0, 1}
+	ipDstMasked := []byte{0x80, 0x00, 0x19, 0x08, 10, 0, 0, 1, 0xff, 0xff, 0xff, 0xff}
+	oxm := func(fields ...[]byte) []byte {
+		data := []byte{0x00, 0x01, 0x00, 0x00}
+		for _, v := range fields {
+			data = append(data, v...)
+		}
+		data[3] = byte(len(data))
+		return data
+	}
+
+	m1, err := parseOXMMatch(oxm(ethType, ipDst))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Reordered fields, and an exact value reported with a full mask.
+	m2, err := parseOXMMatch(oxm(ipDstMasked, ethType))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m1.String() != m2.String() {
+		t.Fatalf("different canonical forms of the same match: %v, %v", m1, m2)
+	}
+
+	// The experimenter ID is not a part of the masked value.
+	exp := []byte{0xff, 0xff, 0x01, 0x08, 0x00, 0x00, 0x23, 0x20, 0x12, 0x34, 0xff, 0x00}
+	m3, err := parseOXMMatch(oxm(exp))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := m3["ffff:00:00002320"]; !ok || !bytes.Equal(v.value, []byte{0x12, 0x00}) || !bytes.Equal(v.mask, []byte{0xff, 0x00}) {
+		t.Fatalf("unexpected experimenter field: %v", m3)
+	}
+}
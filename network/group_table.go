@@ -0,0 +1,194 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"sync"
+
+	"github.com/superkkt/cherry/openflow"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrUnknownGroup = errors.New("unknown group")
+	ErrGroupExists  = errors.New("group already exists")
+	ErrGroupLoop    = errors.New("group references itself")
+	ErrChainedGroup = errors.New("group is referenced by another group")
+)
+
+// groupTable keeps the groups installed on a device with the groups referenced by their buckets,
+// so that a flow or a group that references a nonexistent group is rejected by the controller
+// instead of an opaque OFPT_ERROR from the device. The table starts empty: the groups created
+// before the connection are unknown, and they are removed by the applications, e.g., L2Switch
+// deletes all the groups when the device is up.
+type groupTable struct {
+	mutex sync.Mutex
+	// Key is the group ID, and value is the IDs of the groups referenced by its buckets.
+	groups map[uint32][]uint32
+}
+
+func newGroupTable() *groupTable {
+	return &groupTable{
+		groups: make(map[uint32][]uint32),
+	}
+}
+
+// bucketGroups returns the IDs of the groups referenced by buckets, which are the groups of
+// their actions and the watched groups.
+func bucketGroups(buckets []openflow.Bucket) []uint32 {
+	result := []uint32{}
+	for _, b := range buckets {
+		if b.Action != nil {
+			if ok, id := b.Action.Group(); ok {
+				result = append(result, id)
+			}
+		}
+		if b.WatchGroup != openflow.NoWatch {
+			result = append(result, b.WatchGroup)
+		}
+	}
+
+	return result
+}
+
+// Check returns an error if the group command cmd for the group whose ID is id cannot be
+// processed by the device because of the groups that do or do not exist.
+func (r *groupTable) Check(cmd openflow.GroupModCmd, id uint32, buckets []openflow.Bucket) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	switch cmd {
+	case openflow.GroupAdd:
+		if _, ok := r.groups[id]; ok {
+			return errors.Wrapf(ErrGroupExists, "adding group %v", id)
+		}
+	case openflow.GroupModify:
+		if _, ok := r.groups[id]; !ok {
+			return errors.Wrapf(ErrUnknownGroup, "modifying group %v", id)
+		}
+	case openflow.GroupDelete:
+		if id == openflow.AllGroups {
+			return nil
+		}
+		// The device does not complain about deleting an unknown group.
+		for k, v := range r.groups {
+			if k != id && containsGroup(v, id) {
+				return errors.Wrapf(ErrChainedGroup, "deleting group %v referenced by group %v", id, k)
+			}
+		}
+		return nil
+	default:
+		return errors.Errorf("unknown group command: %v", cmd)
+	}
+
+	for _, v := range bucketGroups(buckets) {
+		if v == id || r.reaches(v, id) {
+			return errors.Wrapf(ErrGroupLoop, "group %v references group %v", id, v)
+		}
+		if _, ok := r.groups[v]; !ok {
+			return errors.Wrapf(ErrUnknownGroup, "group %v references group %v", id, v)
+		}
+	}
+
+	return nil
+}
+
+// reaches returns whether the group whose ID is to is referenced by the group whose ID is
+// from, directly or through other groups.
+// XXX: Caller should lock the mutex
+func (r *groupTable) reaches(from, to uint32) bool {
+	visited := make(map[uint32]bool)
+	queue := []uint32{from}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if visited[id] {
+			continue
+		}
+		visited[id] = true
+		for _, v := range r.groups[id] {
+			if v == to {
+				return true
+			}
+			queue = append(queue, v)
+		}
+	}
+
+	return false
+}
+
+func containsGroup(groups []uint32, id uint32) bool {
+	for _, v := range groups {
+		if v == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Update applies the group command that has been sent to the device.
+func (r *groupTable) Update(cmd openflow.GroupModCmd, id uint32, buckets []openflow.Bucket) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	switch cmd {
+	case openflow.GroupAdd, openflow.GroupModify:
+		r.groups[id] = bucketGroups(buckets)
+	case openflow.GroupDelete:
+		if id == openflow.AllGroups {
+			r.groups = make(map[uint32][]uint32)
+			return
+		}
+		delete(r.groups, id)
+	}
+}
+
+// CheckFlow returns ErrUnknownGroup if flow, which adds or modifies flows, forwards the packets
+// to a group that does not exist.
+func (r *groupTable) CheckFlow(flow openflow.FlowMod) error {
+	switch flow.Command() {
+	case openflow.FlowAdd, openflow.FlowModify, openflow.FlowModifyStrict:
+	default:
+		return nil
+	}
+	inst := flow.FlowInstruction()
+	if inst == nil {
+		return nil
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, act := range inst.Actions() {
+		ok, id := act.Group()
+		if !ok {
+			continue
+		}
+		if _, ok := r.groups[id]; !ok {
+			return errors.Wrapf(ErrUnknownGroup, "flow forwards packets to group %v", id)
+		}
+	}
+
+	return nil
+}
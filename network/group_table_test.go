@@ -0,0 +1,114 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"testing"
+
+	"github.com/superkkt/cherry/openflow"
+	"github.com/superkkt/cherry/openflow/of13"
+
+	"github.com/pkg/errors"
+)
+
+func newGroupBucket(t *testing.T, f openflow.Factory, group uint32) openflow.Bucket {
+	action, err := f.NewAction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if group == openflow.NoWatch {
+		action.SetOutPort(openflow.NewOutPort())
+	} else {
+		action.SetGroup(group)
+	}
+
+	return openflow.NewBucket(action)
+}
+
+func newGroupFlow(t *testing.T, f openflow.Factory, cmd openflow.FlowModCmd, group uint32) openflow.FlowMod {
+	flow := newTestFlow(t, f, cmd, nil, 0)
+	action, err := f.NewAction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	action.SetGroup(group)
+	inst, err := f.NewInstruction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	inst.ApplyAction(action)
+	flow.SetFlowInstruction(inst)
+
+	return flow
+}
+
+func TestGroupTable(t *testing.T) {
+	f := of13.NewFactory()
+	table := newGroupTable()
+	leaf := []openflow.Bucket{newGroupBucket(t, f, openflow.NoWatch)}
+
+	check := func(cmd openflow.GroupModCmd, id uint32, buckets []openflow.Bucket, expected error) {
+		err := table.Check(cmd, id, buckets)
+		if errors.Cause(err) != expected {
+			t.Fatalf("unexpected result of group command %v for group %v: expected=%v, got=%v", cmd, id, expected, err)
+		}
+		if err == nil {
+			table.Update(cmd, id, buckets)
+		}
+	}
+
+	check(openflow.GroupModify, 1, leaf, ErrUnknownGroup)
+	check(openflow.GroupAdd, 1, leaf, nil)
+	check(openflow.GroupAdd, 1, leaf, ErrGroupExists)
+	// Group 2 does not exist yet.
+	check(openflow.GroupAdd, 3, []openflow.Bucket{newGroupBucket(t, f, 2)}, ErrUnknownGroup)
+	check(openflow.GroupAdd, 2, []openflow.Bucket{newGroupBucket(t, f, 1)}, nil)
+	check(openflow.GroupAdd, 3, []openflow.Bucket{newGroupBucket(t, f, 2)}, nil)
+	// Watched group should also exist.
+	watch := openflow.NewBucket(leaf[0].Action)
+	watch.WatchGroup = 4
+	check(openflow.GroupAdd, 5, []openflow.Bucket{watch}, ErrUnknownGroup)
+
+	// 1 -> 3 -> 2 -> 1
+	check(openflow.GroupModify, 1, []openflow.Bucket{newGroupBucket(t, f, 3)}, ErrGroupLoop)
+	check(openflow.GroupModify, 1, []openflow.Bucket{newGroupBucket(t, f, 1)}, ErrGroupLoop)
+	// Group 2 is referenced by group 3.
+	check(openflow.GroupDelete, 2, nil, ErrChainedGroup)
+	check(openflow.GroupDelete, 3, nil, nil)
+	check(openflow.GroupDelete, 2, nil, nil)
+
+	if err := table.CheckFlow(newGroupFlow(t, f, openflow.FlowAdd, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := table.CheckFlow(newGroupFlow(t, f, openflow.FlowModify, 2)); errors.Cause(err) != ErrUnknownGroup {
+		t.Fatalf("expected ErrUnknownGroup, got=%v", err)
+	}
+	// Deleting flows is not checked.
+	if err := table.CheckFlow(newGroupFlow(t, f, openflow.FlowDelete, 2)); err != nil {
+		t.Fatal(err)
+	}
+
+	check(openflow.GroupDelete, openflow.AllGroups, nil, nil)
+	if err := table.CheckFlow(newGroupFlow(t, f, openflow.FlowAdd, 1)); errors.Cause(err) != ErrUnknownGroup {
+		t.Fatalf("expected ErrUnknownGroup after removing all the groups, got=%v", err)
+	}
+}
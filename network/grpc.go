@@ -0,0 +1,348 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/superkkt/cherry/network/pb"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/superkkt/viper"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// serveGRPC serves the gRPC API defined in pb/cherry.proto on grpc.listen_addr and grpc.port.
+func (r *Controller) serveGRPC() {
+	var opts []grpc.ServerOption
+	if viper.GetBool("grpc.tls") {
+		creds, err := credentials.NewServerTLSFromFile(viper.GetString("grpc.cert_file"), viper.GetString("grpc.key_file"))
+		if err != nil {
+			logger.Errorf("failed to load the TLS certificate of the gRPC API: %v", err)
+			return
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+	server := r.newGRPCServer(opts...)
+
+	addr := net.JoinHostPort(viper.GetString("grpc.listen_addr"), strconv.Itoa(viper.GetInt("grpc.port")))
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		logger.Errorf("failed to listen on the gRPC port: %v", err)
+		return
+	}
+	logger.Infof("serving the gRPC API on %v", addr)
+
+	err = server.Serve(l)
+	logger.Errorf("gRPC server terminated: %v", err)
+}
+
+// newGRPCServer returns a gRPC server that has the API registered and authorizes its calls.
+func (r *Controller) newGRPCServer(opts ...grpc.ServerOption) *grpc.Server {
+	opts = append(opts,
+		grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			if err := r.authorizeGRPC(ctx, info.FullMethod); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.StreamInterceptor(func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := r.authorizeGRPC(stream.Context(), info.FullMethod); err != nil {
+				return err
+			}
+			return handler(srv, stream)
+		}),
+	)
+	server := grpc.NewServer(opts...)
+	pb.RegisterCherryServer(server, &grpcServer{controller: r})
+
+	return server
+}
+
+// authorizeGRPC returns the status error if the client of ctx is not allowed to call method. The
+// clients are authenticated by the users of the REST API with the authorization metadata, which
+// has the same credentials as the Authorization header. Every role is permitted because the gRPC
+// API only reads the state of the network.
+func (r *Controller) authorizeGRPC(ctx context.Context, method string) error {
+	if r.auth != nil {
+		md, _ := metadata.FromIncomingContext(ctx)
+		req := &http.Request{Header: http.Header{"Authorization": md.Get("authorization")}}
+		if r.auth.authenticate(req) == nil {
+			addr := "unknown"
+			if p, ok := peer.FromContext(ctx); ok {
+				addr = p.Addr.String()
+			}
+			rateLogger.Warningf("auth "+addr, "unauthenticated gRPC API request from %v: %v", addr, method)
+			return status.Error(codes.Unauthenticated, "authentication required")
+		}
+	}
+	if r.observer.IsMaster() == false {
+		return status.Error(codes.Unavailable, "use the master controller server")
+	}
+
+	return nil
+}
+
+// grpcServer implements pb.CherryServer with the same state served by the REST API.
+type grpcServer struct {
+	controller *Controller
+}
+
+func (r *grpcServer) ListDevices(ctx context.Context, req *pb.ListDevicesRequest) (*pb.ListDevicesResponse, error) {
+	resp := &pb.ListDevicesResponse{}
+	for _, d := range r.controller.topo.Devices() {
+		if d.isReady() == false {
+			continue
+		}
+		resp.Devices = append(resp.Devices, newPBDevice(newDeviceInfo(d)))
+	}
+
+	return resp, nil
+}
+
+// device returns the connected device whose DPID is dpid.
+func (r *grpcServer) device(dpid string) (*Device, error) {
+	if _, err := strconv.ParseUint(dpid, 10, 64); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid DPID")
+	}
+	device := r.controller.topo.Device(dpid)
+	if device == nil || device.isReady() == false {
+		return nil, status.Error(codes.NotFound, "unknown or disconnected device")
+	}
+
+	return device, nil
+}
+
+func (r *grpcServer) GetDevice(ctx context.Context, req *pb.GetDeviceRequest) (*pb.Device, error) {
+	device, err := r.device(req.Dpid)
+	if err != nil {
+		return nil, err
+	}
+
+	return newPBDevice(newDeviceInfo(device)), nil
+}
+
+func newPBDevice(v DeviceInfo) *pb.Device {
+	return &pb.Device{
+		Dpid:         v.DPID,
+		Version:      uint32(v.Version),
+		Manufacturer: v.Manufacturer,
+		Hardware:     v.Hardware,
+		Software:     v.Software,
+		Serial:       v.Serial,
+		Description:  v.Description,
+		NumBuffers:   v.NumBuffers,
+		NumTables:    uint32(v.NumTables),
+		NumPorts:     uint32(v.NumPorts),
+		Drained:      v.Drained,
+		Role:         v.Role,
+		Rtt:          v.RTT,
+		MissSendLen:  uint32(v.MissSendLen),
+	}
+}
+
+func (r *grpcServer) ListPorts(ctx context.Context, req *pb.ListPortsRequest) (*pb.ListPortsResponse, error) {
+	device, err := r.device(req.Dpid)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &pb.ListPortsResponse{}
+	for _, v := range newDevicePorts(device) {
+		port := &pb.Port{
+			Number:  v.Number,
+			Name:    v.Name,
+			Mac:     v.MAC,
+			AdminUp: v.AdminUp,
+			LinkUp:  v.LinkUp,
+			Config:  v.Config,
+			State:   v.State,
+			Speed:   v.Speed,
+		}
+		if s := v.Stats; s != nil {
+			port.Stats = &pb.PortStats{
+				RxPackets:  s.RxPackets,
+				TxPackets:  s.TxPackets,
+				RxBytes:    s.RxBytes,
+				TxBytes:    s.TxBytes,
+				RxDropped:  s.RxDropped,
+				TxDropped:  s.TxDropped,
+				RxErrors:   s.RxErrors,
+				TxErrors:   s.TxErrors,
+				RxFrameErr: s.RxFrameErr,
+				RxOverErr:  s.RxOverErr,
+				RxCrcErr:   s.RxCRCErr,
+				Collisions: s.Collisions,
+			}
+		}
+		resp.Ports = append(resp.Ports, port)
+	}
+
+	return resp, nil
+}
+
+func (r *grpcServer) ListFlows(ctx context.Context, req *pb.ListFlowsRequest) (*pb.ListFlowsResponse, error) {
+	device, err := r.device(req.Dpid)
+	if err != nil {
+		return nil, err
+	}
+
+	flows, collected := newDeviceFlows(device)
+	resp := &pb.ListFlowsResponse{}
+	for _, v := range flows {
+		// Only the flows owned by the application if the owner is specified.
+		if req.Owner != "" && !strings.EqualFold(v.Owner, req.Owner) {
+			continue
+		}
+		m := v.Match
+		resp.Flows = append(resp.Flows, &pb.Flow{
+			TableId:     uint32(v.TableID),
+			Priority:    uint32(v.Priority),
+			Cookie:      v.Cookie,
+			DurationSec: v.DurationSec,
+			IdleTimeout: uint32(v.IdleTimeout),
+			HardTimeout: uint32(v.HardTimeout),
+			PacketCount: v.PacketCount,
+			ByteCount:   v.ByteCount,
+			Match: &pb.FlowMatch{
+				InPort:     m.InPort,
+				SrcMac:     m.SrcMAC,
+				DstMac:     m.DstMAC,
+				EtherType:  uint32(m.EtherType),
+				IpProtocol: uint32(m.IPProtocol),
+				SrcIp:      m.SrcIP,
+				DstIp:      m.DstIP,
+				SrcPort:    uint32(m.SrcPort),
+				DstPort:    uint32(m.DstPort),
+			},
+			Owner: v.Owner,
+		})
+	}
+	if collected != nil {
+		if resp.Collected, err = ptypes.TimestampProto(*collected); err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+	}
+
+	return resp, nil
+}
+
+func (r *grpcServer) ListLinks(ctx context.Context, req *pb.ListLinksRequest) (*pb.ListLinksResponse, error) {
+	resp := &pb.ListLinksResponse{}
+	for _, v := range r.controller.linkInfos() {
+		resp.Links = append(resp.Links, &pb.Link{
+			Dpid1:    v.DPID1,
+			Port1:    v.Port1,
+			Dpid2:    v.DPID2,
+			Port2:    v.Port2,
+			Indirect: v.Indirect,
+		})
+	}
+
+	return resp, nil
+}
+
+func (r *grpcServer) ListHosts(ctx context.Context, req *pb.ListHostsRequest) (*pb.ListHostsResponse, error) {
+	hosts, err := r.controller.db.Hosts()
+	if err != nil {
+		logger.Errorf("failed to query database: %v", err)
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	resp := &pb.ListHostsResponse{}
+	for _, v := range hosts {
+		resp.Hosts = append(resp.Hosts, &pb.Host{
+			Id:          v.ID,
+			Ip:          v.IP,
+			Port:        v.Port,
+			Mac:         v.MAC,
+			Description: v.Description,
+			Stale:       v.Stale,
+		})
+	}
+
+	return resp, nil
+}
+
+// Subscribe pushes the network events to the client until it cancels the call, as the WebSocket
+// of the REST API does.
+func (r *grpcServer) Subscribe(req *pb.SubscribeRequest, stream pb.Cherry_SubscribeServer) error {
+	var types []EventType
+	for _, t := range req.Types {
+		types = append(types, EventType(strings.TrimSpace(t)))
+	}
+	events, cancel := r.controller.Subscribe(types...)
+	defer cancel()
+
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "too slow to receive the events")
+			}
+			v, err := newPBEvent(e)
+			if err != nil {
+				logger.Errorf("failed to convert the event: %v", err)
+				continue
+			}
+			if err := stream.Send(v); err != nil {
+				logger.Infof("failed to send the event to the gRPC subscriber: %v", err)
+				return err
+			}
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
+
+func newPBEvent(e Event) (*pb.Event, error) {
+	t, err := ptypes.TimestampProto(e.Time)
+	if err != nil {
+		return nil, err
+	}
+	v := &pb.Event{
+		Type:     string(e.Type),
+		Time:     t,
+		Dpid:     e.DPID,
+		Port:     e.Port,
+		Mac:      e.MAC,
+		Ip:       e.IP,
+		Cookie:   e.Cookie,
+		Reason:   e.Reason,
+		Address:  e.Address,
+		Failures: int32(e.Failures),
+	}
+	for _, p := range e.Link {
+		v.Link = append(v.Link, &pb.EventPort{Dpid: p.DPID, Port: p.Port})
+	}
+
+	return v, nil
+}
@@ -0,0 +1,149 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/superkkt/cherry/clock"
+	"github.com/superkkt/cherry/election"
+	"github.com/superkkt/cherry/network/pb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type fixedObserver struct {
+	master bool
+}
+
+func (r *fixedObserver) IsMaster() bool {
+	return r.master
+}
+
+func (r *fixedObserver) Subscribe(f election.Listener) {}
+
+// startGRPC serves the gRPC API of controller and returns a client connected to it.
+func startGRPC(t *testing.T, controller *Controller) (client pb.CherryClient, cleanup func()) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := controller.newGRPCServer()
+	go server.Serve(l)
+
+	conn, err := grpc.Dial(l.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		server.Stop()
+		t.Fatal(err)
+	}
+
+	return pb.NewCherryClient(conn), func() {
+		conn.Close()
+		server.Stop()
+	}
+}
+
+func (r *eventBus) numSubscribers() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return len(r.subscribers)
+}
+
+func TestGRPCSubscribe(t *testing.T) {
+	topo := newTopology(&drainDB{switches: map[uint64]bool{}}, clock.Real)
+	controller := &Controller{
+		topo:     topo,
+		observer: &fixedObserver{master: true},
+		auth:     newAPIAuth([]APIUser{{Name: "monitoring", Role: RoleReadOnly, TokenSHA256: mustDigest(t, "token1")}}),
+	}
+	client, cleanup := startGRPC(t, controller)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// The credentials are required as the REST API.
+	stream, err := client.Subscribe(ctx, &pb.SubscribeRequest{})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected the unauthenticated error, got %v", err)
+	}
+
+	authCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer token1")
+	stream, err = client.Subscribe(authCtx, &pb.SubscribeRequest{Types: []string{string(EventLinkUp)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for topo.events.numSubscribers() == 0 {
+		if ctx.Err() != nil {
+			t.Fatal("no subscriber")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// The events of the other types are filtered out.
+	topo.events.publish(Event{Type: EventDeviceUp, DPID: "1"})
+	topo.events.publish(Event{Type: EventLinkUp, Link: []EventPort{{DPID: "1", Port: 2}, {DPID: "3", Port: 4}}})
+	e, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Type != string(EventLinkUp) || len(e.Link) != 2 || e.Link[1].Dpid != "3" || e.Link[1].Port != 4 || e.Time == nil {
+		t.Fatalf("unexpected event: %+v", e)
+	}
+
+	// The subscription is removed when the client cancels the call.
+	cancel()
+	for topo.events.numSubscribers() != 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestGRPCSlave(t *testing.T) {
+	controller := &Controller{
+		topo:     newTopology(&drainDB{switches: map[uint64]bool{}}, clock.Real),
+		observer: &fixedObserver{master: false},
+	}
+	client, cleanup := startGRPC(t, controller)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := client.ListLinks(ctx, &pb.ListLinksRequest{}); status.Code(err) != codes.Unavailable {
+		t.Fatalf("expected the unavailable error, got %v", err)
+	}
+	controller.observer = &fixedObserver{master: true}
+	if _, err := client.GetDevice(ctx, &pb.GetDeviceRequest{Dpid: "x"}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected the invalid argument error, got %v", err)
+	}
+	if _, err := client.GetDevice(ctx, &pb.GetDeviceRequest{Dpid: "1"}); status.Code(err) != codes.NotFound {
+		t.Fatalf("expected the not found error, got %v", err)
+	}
+}
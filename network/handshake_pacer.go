@@ -0,0 +1,157 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/superkkt/cherry/clock"
+)
+
+const (
+	// Number of the recent handshake durations used to calculate the percentiles.
+	handshakeSamples = 1024
+)
+
+// handshakePacer limits the number of connections that are concurrently in the
+// handshake phase. When a core switch reboots, hundreds of access switches may
+// reconnect within a few seconds, and the resulting handshake burst can overwhelm
+// the controller. Excess connections wait for a free slot before starting their
+// handshake, and already connected devices are not affected at all.
+type handshakePacer struct {
+	// nil slots means unlimited concurrent handshakes.
+	slots chan struct{}
+
+	mutex     sync.Mutex
+	waiting   int // Current queue depth.
+	peak      int // Maximum queue depth ever observed.
+	samples   []time.Duration
+	nextIndex int
+	clock     clock.Clock
+}
+
+// newHandshakePacer returns a new pacer that allows max concurrent handshakes.
+// Zero max disables the pacing.
+func newHandshakePacer(max int, clk clock.Clock) *handshakePacer {
+	if max < 0 {
+		panic("max should be equal to or greater than zero")
+	}
+	if clk == nil {
+		panic("clock is nil")
+	}
+
+	v := &handshakePacer{
+		samples: make([]time.Duration, 0, handshakeSamples),
+		clock:   clk,
+	}
+	if max > 0 {
+		v.slots = make(chan struct{}, max)
+	}
+
+	return v
+}
+
+// acquire blocks until a handshake slot becomes available or ctx is canceled.
+// The caller should call the returned release function exactly once when the
+// handshake is completed or the connection is closed.
+func (r *handshakePacer) acquire(ctx context.Context) (release func(), err error) {
+	r.setWaiting(1)
+	defer r.setWaiting(-1)
+
+	if r.slots != nil {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case r.slots <- struct{}{}:
+		}
+	}
+
+	var once sync.Once
+	start := r.clock.Now()
+	release = func() {
+		once.Do(func() {
+			r.record(r.clock.Since(start))
+			if r.slots != nil {
+				<-r.slots
+			}
+		})
+	}
+
+	return release, nil
+}
+
+func (r *handshakePacer) setWaiting(delta int) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.waiting += delta
+	if r.waiting > r.peak {
+		r.peak = r.waiting
+	}
+}
+
+func (r *handshakePacer) record(d time.Duration) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if len(r.samples) < handshakeSamples {
+		r.samples = append(r.samples, d)
+		return
+	}
+	// Overwrite the oldest one.
+	r.samples[r.nextIndex] = d
+	r.nextIndex = (r.nextIndex + 1) % handshakeSamples
+}
+
+// percentile returns the p-th percentile of the sorted durations.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	return sorted[(len(sorted)-1)*p/100]
+}
+
+func (r *handshakePacer) String() string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	sorted := make([]time.Duration, len(r.samples))
+	copy(sorted, r.samples)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	limit := "unlimited"
+	if r.slots != nil {
+		limit = fmt.Sprintf("%v", cap(r.slots))
+	}
+	inProgress := 0
+	if r.slots != nil {
+		inProgress = len(r.slots)
+	}
+
+	return fmt.Sprintf("Handshake limit=%v, InProgress=%v, QueueDepth=%v, PeakQueueDepth=%v, TimeToHandshake(p50/p90/p99)=%v/%v/%v",
+		limit, inProgress, r.waiting, r.peak, percentile(sorted, 50), percentile(sorted, 90), percentile(sorted, 99))
+}
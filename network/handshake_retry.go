@@ -0,0 +1,197 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/superkkt/cherry/clock"
+	"github.com/superkkt/cherry/metrics"
+	"github.com/superkkt/cherry/openflow/transceiver"
+)
+
+var (
+	handshakeRetries = metrics.NewCounterVec("cherry_handshake_retries_total", "Number of handshake messages sent again after a transient failure.")
+)
+
+const (
+	// Consecutive negotiation failures from an address are forgotten after this time.
+	negotiationFailureWindow = 10 * time.Minute
+)
+
+// handshakeRetry sends a handshake message, such as HELLO and FEATURES_REQUEST, again after
+// a transient failure, i.e., the outbound queue is full, with exponential backoff. The zero
+// value sends the message only once.
+type handshakeRetry struct {
+	// Maximum number of the retries.
+	attempts int
+	// Delay before the first retry, which is doubled for each retry up to maxBackoff.
+	backoff    time.Duration
+	maxBackoff time.Duration
+	clock      clock.Clock
+}
+
+func newHandshakeRetry(attempts int, backoff, maxBackoff time.Duration, clk clock.Clock) handshakeRetry {
+	if attempts < 0 {
+		panic("attempts should be equal to or greater than zero")
+	}
+	if attempts > 0 && (backoff <= 0 || maxBackoff < backoff) {
+		panic("invalid backoff")
+	}
+	if clk == nil {
+		panic("clock is nil")
+	}
+
+	return handshakeRetry{
+		attempts:   attempts,
+		backoff:    backoff,
+		maxBackoff: maxBackoff,
+		clock:      clk,
+	}
+}
+
+// isTransient returns whether err is a failure that may succeed if we try again.
+func isTransient(err error) bool {
+	if err == transceiver.ErrQueueFull {
+		return true
+	}
+	if e, ok := err.(net.Error); ok && e.Timeout() {
+		return true
+	}
+
+	return false
+}
+
+// do calls send, and calls it again while it fails transiently up to the maximum attempts.
+// name is the message sent by send, which is used for logging.
+func (r handshakeRetry) do(name string, send func() error) error {
+	backoff := r.backoff
+	for i := 0; ; i++ {
+		err := send()
+		if err == nil || !isTransient(err) || i >= r.attempts {
+			return err
+		}
+
+		logger.Warningf("retrying to send %v in %v (%v/%v): %v", name, backoff, i+1, r.attempts, err)
+		handshakeRetries.Inc()
+		timer := r.clock.NewTimer(backoff)
+		<-timer.C()
+		backoff *= 2
+		if backoff > r.maxBackoff {
+			backoff = r.maxBackoff
+		}
+	}
+}
+
+type negotiationFailure struct {
+	count int
+	last  time.Time
+}
+
+// negotiationTracker counts the consecutive negotiation failures of the connections from
+// each source IP address, because we do not know the DPID of a switch until it completes
+// the handshake, and publishes EventNegotiationFailed whenever a switch fails negotiation
+// threshold times or more in a row.
+type negotiationTracker struct {
+	// Zero threshold disables the events.
+	threshold int
+	bus       *eventBus
+	clock     clock.Clock
+
+	mutex sync.Mutex
+	// Key is the source IP address.
+	failures map[string]*negotiationFailure
+}
+
+func newNegotiationTracker(threshold int, bus *eventBus, clk clock.Clock) *negotiationTracker {
+	if threshold < 0 {
+		panic("threshold should be equal to or greater than zero")
+	}
+	if bus == nil {
+		panic("bus is nil")
+	}
+	if clk == nil {
+		panic("clock is nil")
+	}
+
+	return &negotiationTracker{
+		threshold: threshold,
+		bus:       bus,
+		clock:     clk,
+		failures:  make(map[string]*negotiationFailure),
+	}
+}
+
+func addressIP(addr net.Addr) string {
+	ip := addr.String()
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+
+	return ip
+}
+
+// failed records that the connection from addr has been closed before completing the
+// handshake, and returns the number of the consecutive failures.
+func (r *negotiationTracker) failed(addr net.Addr) int {
+	ip := addressIP(addr)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := r.clock.Now()
+	r.purge(now)
+
+	v, ok := r.failures[ip]
+	if !ok {
+		v = new(negotiationFailure)
+		r.failures[ip] = v
+	}
+	v.count++
+	v.last = now
+
+	if r.threshold > 0 && v.count >= r.threshold {
+		logger.Warningf("switch at %v has repeatedly failed negotiation: %v consecutive failures", ip, v.count)
+		r.bus.publish(Event{Type: EventNegotiationFailed, Address: ip, Failures: v.count})
+	}
+
+	return v.count
+}
+
+// succeeded forgets the failures of addr.
+func (r *negotiationTracker) succeeded(addr net.Addr) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.failures, addressIP(addr))
+}
+
+// XXX: Caller should lock the mutex
+func (r *negotiationTracker) purge(now time.Time) {
+	for ip, v := range r.failures {
+		if now.Sub(v.last) >= negotiationFailureWindow {
+			delete(r.failures, ip)
+		}
+	}
+}
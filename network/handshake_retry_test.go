@@ -0,0 +1,136 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/superkkt/cherry/openflow/transceiver"
+	"github.com/superkkt/cherry/testutil"
+)
+
+func TestHandshakeRetry(t *testing.T) {
+	clock := testutil.NewFakeClock(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))
+	retry := newHandshakeRetry(3, 100*time.Millisecond, 150*time.Millisecond, clock)
+
+	sent := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- retry.do("HELLO", func() error {
+			sent <- struct{}{}
+			return transceiver.ErrQueueFull
+		})
+	}()
+
+	<-sent
+	// Backoff is doubled up to the maximum.
+	for _, backoff := range []time.Duration{100 * time.Millisecond, 150 * time.Millisecond, 150 * time.Millisecond} {
+		clock.WaitTimers(1)
+		clock.Advance(backoff - time.Millisecond)
+		select {
+		case <-sent:
+			t.Fatalf("retried before the backoff: %v", backoff)
+		case <-time.After(10 * time.Millisecond):
+		}
+		clock.Advance(time.Millisecond)
+		select {
+		case <-sent:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("not retried after the backoff: %v", backoff)
+		}
+	}
+
+	select {
+	case err := <-done:
+		if err != transceiver.ErrQueueFull {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("retry did not give up")
+	}
+}
+
+func TestHandshakeRetryPermanentError(t *testing.T) {
+	clock := testutil.NewFakeClock(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))
+	retry := newHandshakeRetry(3, 100*time.Millisecond, time.Second, clock)
+
+	count := 0
+	permanent := errors.New("closed")
+	err := retry.do("HELLO", func() error {
+		count++
+		return permanent
+	})
+	if err != permanent || count != 1 {
+		t.Fatalf("unexpected result: err=%v, count=%v", err, count)
+	}
+
+	// Zero value sends only once.
+	count = 0
+	if err := (handshakeRetry{}).do("HELLO", func() error {
+		count++
+		return transceiver.ErrQueueFull
+	}); err != transceiver.ErrQueueFull || count != 1 {
+		t.Fatalf("unexpected result: err=%v, count=%v", err, count)
+	}
+}
+
+func TestNegotiationTracker(t *testing.T) {
+	clock := testutil.NewFakeClock(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))
+	bus := newEventBus(clock)
+	events := bus.subscribe(EventNegotiationFailed)
+	tracker := newNegotiationTracker(2, bus, clock)
+	host1 := &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1000}
+	host1OtherPort := &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 2000}
+	host2 := &net.TCPAddr{IP: net.IPv4(10, 0, 0, 2), Port: 1000}
+
+	if n := tracker.failed(host1); n != 1 {
+		t.Fatalf("unexpected failures: %v", n)
+	}
+	tracker.failed(host2)
+	if len(events) != 0 {
+		t.Fatal("event published below the threshold")
+	}
+	if n := tracker.failed(host1OtherPort); n != 2 {
+		t.Fatalf("unexpected failures: %v", n)
+	}
+	e := <-events
+	if e.Address != "10.0.0.1" || e.Failures != 2 {
+		t.Fatalf("unexpected event: %+v", e)
+	}
+
+	// Success resets the failures.
+	tracker.succeeded(host1)
+	if n := tracker.failed(host1); n != 1 {
+		t.Fatalf("unexpected failures after success: %v", n)
+	}
+	// Old failures are forgotten.
+	clock.Advance(negotiationFailureWindow)
+	if n := tracker.failed(host2); n != 1 {
+		t.Fatalf("unexpected failures after the window: %v", n)
+	}
+	if len(events) != 0 {
+		t.Fatalf("unexpected events: %v", len(events))
+	}
+}
@@ -0,0 +1,135 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/ant0ine/go-json-rest/rest"
+)
+
+type IntentParam struct {
+	// Hosts that can reach each other.
+	SrcHostID uint64 `json:"src_host_id"`
+	DstHostID uint64 `json:"dst_host_id"`
+	// Bandwidth limit of each direction in kilobits per second. 0 means unlimited.
+	Bandwidth   uint32 `json:"bandwidth"`
+	Description string `json:"description"`
+}
+
+func (r *IntentParam) validate() error {
+	if r.SrcHostID == 0 || r.DstHostID == 0 {
+		return errors.New("invalid host ID")
+	}
+	if r.SrcHostID == r.DstHostID {
+		return errors.New("same host for the source and destination")
+	}
+
+	return nil
+}
+
+type Intent struct {
+	ID          uint64 `json:"id"`
+	SrcHost     Host   `json:"src_host"`
+	DstHost     Host   `json:"dst_host"`
+	Bandwidth   uint32 `json:"bandwidth"`
+	Description string `json:"description"`
+}
+
+func (r *Controller) listIntents(w rest.ResponseWriter, req *rest.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	intents, err := r.db.Intents()
+	if err != nil {
+		logger.Errorf("failed to query database: %v", err)
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.WriteJson(&struct {
+		Intents []Intent `json:"intents"`
+	}{intents})
+}
+
+func (r *Controller) addIntent(w rest.ResponseWriter, req *rest.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	intent := IntentParam{}
+	if err := req.DecodeJsonPayload(&intent); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := intent.validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	for _, id := range []uint64{intent.SrcHostID, intent.DstHostID} {
+		_, ok, err := r.db.Host(id)
+		if err != nil {
+			logger.Errorf("failed to query database: %v", err)
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if !ok {
+			writeError(w, http.StatusNotFound, errors.New("unknown host ID"))
+			return
+		}
+	}
+
+	id, err := r.db.AddIntent(intent)
+	if err != nil {
+		logger.Errorf("failed to query database: %v", err)
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	logger.Infof("added a new intent (ID=%v, %+v)", id, intent)
+
+	w.WriteJson(&struct {
+		ID uint64 `json:"intent_id"`
+	}{id})
+}
+
+func (r *Controller) removeIntent(w rest.ResponseWriter, req *rest.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	id, err := strconv.ParseUint(req.PathParam("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	ok, err := r.db.RemoveIntent(id)
+	if err != nil {
+		logger.Errorf("failed to query database: %v", err)
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if !ok {
+		writeError(w, http.StatusNotFound, errors.New("unknown intent ID"))
+		return
+	}
+	logger.Infof("removed the intent (ID=%v)", id)
+
+	w.WriteJson(&struct{}{})
+}
@@ -0,0 +1,161 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/superkkt/cherry/clock"
+	"github.com/superkkt/cherry/protocol"
+)
+
+// lacpTimeout is the long timeout of LACP. A port leaves its aggregation if it does not
+// receive a LACPDU for this duration, which is three times of the slow periodic interval.
+const lacpTimeout = 90 * time.Second
+
+// lacpStateAggregation is the actor state bit that means the link can be aggregated.
+const lacpStateAggregation = 0x04
+
+func isLACP(e *protocol.Ethernet) bool {
+	return e.Type == protocol.SlowProtocolsType && len(e.Payload) > 0 && e.Payload[0] == protocol.LACPSubtype
+}
+
+func getLACP(packet []byte) (*protocol.LACP, error) {
+	lacp := new(protocol.LACP)
+	if err := lacp.UnmarshalBinary(packet); err != nil {
+		return nil, err
+	}
+
+	return lacp, nil
+}
+
+// aggregationKey identifies the link aggregation (port-channel) of actor. The ports that receive
+// the LACPDUs of the same actor system and key are the members of an aggregation.
+func aggregationKey(actor protocol.LACPInfo) string {
+	return fmt.Sprintf("%v/%v/%v", actor.SystemPriority, actor.System, actor.Key)
+}
+
+type lagMember struct {
+	port      *Port
+	key       string
+	timestamp time.Time
+}
+
+// lagTable keeps the member ports of the link aggregations whose partners send LACPDUs to
+// the switches. We never answer the LACPDUs; the partners aggregate the ports with the
+// switches running LACP by themselves, and we only have to treat the members as a single
+// logical port. The members of an aggregation may reside on several devices if the partner
+// is connected by a multi-chassis link aggregation.
+type lagTable struct {
+	mutex sync.Mutex
+	// Key is the port ID.
+	members map[string]lagMember
+	clock   clock.Clock
+}
+
+func newLAGTable(clk clock.Clock) *lagTable {
+	if clk == nil {
+		panic("clock is nil")
+	}
+
+	return &lagTable{
+		members: make(map[string]lagMember),
+		clock:   clk,
+	}
+}
+
+func (r *lagTable) isAlive(m lagMember) bool {
+	return r.clock.Since(m.timestamp) <= lacpTimeout
+}
+
+// add refreshes p as a member of the aggregation identified by key. joined will be true if p
+// was not a member of the aggregation.
+func (r *lagTable) add(p *Port, key string) (joined bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	m, ok := r.members[p.ID()]
+	joined = !ok || m.key != key || !r.isAlive(m)
+	r.members[p.ID()] = lagMember{port: p, key: key, timestamp: r.clock.Now()}
+
+	return joined
+}
+
+// remove removes p from its aggregation.
+func (r *lagTable) remove(p *Port) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.members, p.ID())
+}
+
+// removeDevice removes all the ports of the device whose ID is id from their aggregations.
+func (r *lagTable) removeDevice(id string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for k, m := range r.members {
+		if m.port.Device().ID() == id {
+			delete(r.members, k)
+		}
+	}
+}
+
+// aggregation returns the active member ports, sorted by their IDs, of the aggregation that
+// p belongs to. It returns nil if p is not a member of any aggregation.
+func (r *lagTable) aggregation(p *Port) []*Port {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	m, ok := r.members[p.ID()]
+	if !ok || !r.isAlive(m) {
+		return nil
+	}
+
+	result := make([]*Port, 0)
+	for _, v := range r.members {
+		if v.key != m.key || !r.isAlive(v) || !isPortUp(v.port.Value()) {
+			continue
+		}
+		result = append(result, v.port)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID() < result[j].ID() })
+
+	return result
+}
+
+// sameAggregation returns whether p1 and p2 are the members of an aggregation.
+func (r *lagTable) sameAggregation(p1, p2 *Port) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	m1, ok1 := r.members[p1.ID()]
+	m2, ok2 := r.members[p2.ID()]
+	if !ok1 || !ok2 || !r.isAlive(m1) || !r.isAlive(m2) {
+		return false
+	}
+
+	return m1.key == m2.key
+}
@@ -0,0 +1,82 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"testing"
+	"time"
+
+	"github.com/superkkt/cherry/openflow/of13"
+	"github.com/superkkt/cherry/testutil"
+)
+
+func newTestAggregatedPort(d *Device, num uint32) *Port {
+	p := NewPort(d, num)
+	// Zero value is an active port.
+	p.SetValue(new(of13.Port))
+
+	return p
+}
+
+func TestLinkAggregation(t *testing.T) {
+	db := &drainDB{switches: map[uint64]bool{1: false, 2: false}}
+	topo := newTestTopology(db, []string{"1", "2"}, nil)
+	recorder := &linkRecorder{}
+	topo.setEventListener(recorder)
+	one, two := topo.Device("1"), topo.Device("2")
+
+	// Multi-chassis aggregation whose partner is connected to both devices.
+	mc := []*Port{newTestAggregatedPort(one, 1), newTestAggregatedPort(two, 1)}
+	for _, p := range mc {
+		topo.PortAggregated(p, "partner")
+	}
+	members := topo.Aggregation(mc[1])
+	if len(members) != 2 || members[0] != mc[0] || members[1] != mc[1] {
+		t.Fatalf("unexpected members: %v", members)
+	}
+	// Our LLDP packet that came back through the partner is not a link.
+	topo.DeviceLinked([2]*Port{mc[0], mc[1]})
+	if n := len(topo.Links()); n != 0 {
+		t.Fatalf("unexpected number of links: expected=0, got=%v", n)
+	}
+
+	// The link discovered before the aggregation should be removed.
+	link := [2]*Port{newTestAggregatedPort(one, 2), newTestAggregatedPort(two, 2)}
+	topo.DeviceLinked(link)
+	if n := len(topo.Links()); n != 1 {
+		t.Fatalf("unexpected number of links: expected=1, got=%v", n)
+	}
+	topo.PortAggregated(link[0], "another")
+	if n := len(topo.Links()); n != 1 {
+		t.Fatalf("unexpected number of links: expected=1, got=%v", n)
+	}
+	topo.PortAggregated(link[1], "another")
+	if len(recorder.down) != 1 || recorder.down[0] != newLink(link).ID() {
+		t.Fatalf("unexpected link down events: %v", recorder.down)
+	}
+
+	// The ports leave the aggregation if they do not receive LACPDUs.
+	topo.lags.clock.(*testutil.FakeClock).Advance(lacpTimeout + time.Second)
+	if members := topo.Aggregation(mc[0]); members != nil {
+		t.Fatalf("unexpected members: %v", members)
+	}
+}
@@ -30,6 +30,8 @@ import (
 
 type link struct {
 	ports [2]*Port
+	// True if the link is discovered by BDDP through the non-OpenFlow switches between the ports.
+	indirect bool
 }
 
 func newLink(ports [2]*Port) *link {
@@ -45,6 +47,17 @@ func (r *link) ID() string {
 	return fmt.Sprintf("%v/%v", s[0], s[1])
 }
 
+// hasPort returns whether the link has any of the ports.
+func (r *link) hasPort(ports ...*Port) bool {
+	for _, p := range ports {
+		if r.ports[0].ID() == p.ID() || r.ports[1].ID() == p.ID() {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (r *link) Points() [2]graph.Point {
 	return [2]graph.Point{r.ports[0], r.ports[1]}
 }
@@ -0,0 +1,149 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+// LinkRecord is a link between two switches saved in the database so that a restarted
+// controller knows the topology before the links are discovered again.
+type LinkRecord struct {
+	SrcDPID uint64
+	SrcPort uint32
+	DstDPID uint64
+	DstPort uint32
+	// True if the link goes through non-OpenFlow switches.
+	Indirect bool
+}
+
+func newLinkRecord(l *link) (LinkRecord, error) {
+	v := LinkRecord{Indirect: l.indirect}
+	var err error
+	if v.SrcDPID, err = strconv.ParseUint(l.ports[0].Device().ID(), 10, 64); err != nil {
+		return LinkRecord{}, fmt.Errorf("invalid DPID: %v", l.ports[0].Device().ID())
+	}
+	if v.DstDPID, err = strconv.ParseUint(l.ports[1].Device().ID(), 10, 64); err != nil {
+		return LinkRecord{}, fmt.Errorf("invalid DPID: %v", l.ports[1].Device().ID())
+	}
+	v.SrcPort = l.ports[0].Number()
+	v.DstPort = l.ports[1].Number()
+	// The smaller end is always the source so that a link has only one record.
+	if v.SrcDPID > v.DstDPID || (v.SrcDPID == v.DstDPID && v.SrcPort > v.DstPort) {
+		v.SrcDPID, v.SrcPort, v.DstDPID, v.DstPort = v.DstDPID, v.DstPort, v.SrcDPID, v.SrcPort
+	}
+
+	return v, nil
+}
+
+func (r LinkRecord) id() string {
+	return fmt.Sprintf("%v:%v/%v:%v", r.SrcDPID, r.SrcPort, r.DstDPID, r.DstPort)
+}
+
+// linkStore saves the links discovered by LLDP and BDDP in the database. A link is removed when
+// it goes down, but not when its switch is disconnected because the switch will probably come
+// back with the same link, e.g., after the controller restarts.
+type linkStore struct {
+	db    database
+	mutex sync.Mutex
+	// Key is made by LinkRecord.id.
+	links map[string]LinkRecord
+}
+
+// newLinkStore returns the store that has the links loaded from db.
+func newLinkStore(db database) (*linkStore, error) {
+	links, err := db.Links()
+	if err != nil {
+		return nil, err
+	}
+
+	v := &linkStore{
+		db:    db,
+		links: make(map[string]LinkRecord),
+	}
+	for _, l := range links {
+		v.links[l.id()] = l
+	}
+
+	return v, nil
+}
+
+// save saves up, and removes down, from the database. It is called without holding the
+// topology lock as it waits for the database.
+func (r *linkStore) save(up *link, down []*link) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, l := range down {
+		v, err := newLinkRecord(l)
+		if err != nil {
+			logger.Errorf("failed to remove a saved link: %v", err)
+			continue
+		}
+		if _, ok := r.links[v.id()]; !ok {
+			continue
+		}
+		if err := r.db.RemoveLink(v); err != nil {
+			logger.Errorf("failed to remove a saved link: %v", err)
+			continue
+		}
+		delete(r.links, v.id())
+	}
+	if up == nil {
+		return
+	}
+	v, err := newLinkRecord(up)
+	if err != nil {
+		logger.Errorf("failed to save a link: %v", err)
+		return
+	}
+	if prev, ok := r.links[v.id()]; ok && prev == v {
+		return
+	}
+	if err := r.db.SaveLink(v); err != nil {
+		logger.Errorf("failed to save a link: %v", err)
+		return
+	}
+	r.links[v.id()] = v
+}
+
+// linksOf returns the saved links of the device whose ID is id.
+func (r *linkStore) linksOf(id string) []LinkRecord {
+	dpid, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return nil
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var result []LinkRecord
+	for _, v := range r.links {
+		if v.SrcDPID == dpid || v.DstDPID == dpid {
+			result = append(result, v)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,142 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"testing"
+
+	"github.com/superkkt/cherry/openflow/of13"
+)
+
+// linkDB is a database that keeps the links in memory.
+type linkDB struct {
+	drainDB
+	// Key is made by LinkRecord.id.
+	links map[string]LinkRecord
+}
+
+func newLinkDB() *linkDB {
+	return &linkDB{
+		drainDB: drainDB{switches: map[uint64]bool{1: false, 2: false, 3: false}},
+		links:   make(map[string]LinkRecord),
+	}
+}
+
+func (r *linkDB) Links() ([]LinkRecord, error) {
+	var result []LinkRecord
+	for _, v := range r.links {
+		result = append(result, v)
+	}
+
+	return result, nil
+}
+
+func (r *linkDB) SaveLink(v LinkRecord) error {
+	r.links[v.id()] = v
+	return nil
+}
+
+func (r *linkDB) RemoveLink(v LinkRecord) error {
+	delete(r.links, v.id())
+	return nil
+}
+
+// newLinkStoreTopology returns a topology, which saves the links in db, that has the devices
+// whose IDs are ids. Each device has the ports that are up.
+func newLinkStoreTopology(t *testing.T, db *linkDB, ids []string, ports []uint32) *topology {
+	topo := newTestTopology(db, ids, nil)
+	store, err := newLinkStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	topo.setLinkStore(store)
+	for _, id := range ids {
+		d := topo.Device(id)
+		for _, num := range ports {
+			p := NewPort(d, num)
+			p.SetValue(new(of13.Port))
+			d.ports[num] = p
+		}
+	}
+
+	return topo
+}
+
+func TestLinkStoreRestart(t *testing.T) {
+	db := newLinkDB()
+	topo := newLinkStoreTopology(t, db, []string{"1", "2", "3"}, []uint32{1, 2, 3})
+	one, two, three := topo.Device("1"), topo.Device("2"), topo.Device("3")
+	topo.DeviceLinked([2]*Port{two.Port(1), one.Port(2)})
+	topo.DeviceLinkedIndirectly([2]*Port{two.Port(3), three.Port(2)})
+	if len(db.links) != 2 {
+		t.Fatalf("unexpected saved links: %v", db.links)
+	}
+	// The links of a disconnected device are kept.
+	topo.DeviceRemoved(three)
+	if len(db.links) != 2 {
+		t.Fatalf("unexpected saved links: %v", db.links)
+	}
+	if _, ok := db.links["1:2/2:1"]; !ok {
+		t.Fatalf("the link is not saved from the smaller end: %v", db.links)
+	}
+
+	// The controller restarts, and the devices connect again.
+	topo = newLinkStoreTopology(t, db, []string{"1", "2", "3"}, []uint32{1, 2, 3})
+	one, two, three = topo.Device("1"), topo.Device("2"), topo.Device("3")
+	recorder := &linkRecorder{}
+	topo.setEventListener(recorder)
+	topo.RestoreLinks(one)
+	if len(recorder.up) != 1 || recorder.up[0] != newLink([2]*Port{one.Port(2), two.Port(1)}).ID() {
+		t.Fatalf("unexpected link up events: %v", recorder.up)
+	}
+	topo.RestoreLinks(three)
+	if n := len(topo.Links()); n != 2 {
+		t.Fatalf("unexpected number of links: expected=2, got=%v", n)
+	}
+	if !topo.isIndirectLink([2]*Port{two.Port(3), three.Port(2)}) {
+		t.Fatal("the indirect link is restored as a direct one")
+	}
+
+	// A link that has gone down is removed.
+	topo.PortRemoved(one.Port(2))
+	if len(db.links) != 1 {
+		t.Fatalf("unexpected saved links: %v", db.links)
+	}
+}
+
+func TestLinkStoreMissingPeer(t *testing.T) {
+	db := newLinkDB()
+	db.SaveLink(LinkRecord{SrcDPID: 1, SrcPort: 2, DstDPID: 2, DstPort: 1})
+	db.SaveLink(LinkRecord{SrcDPID: 1, SrcPort: 3, DstDPID: 3, DstPort: 1})
+	// Device 3 is not connected yet.
+	topo := newLinkStoreTopology(t, db, []string{"1", "2"}, []uint32{2, 3})
+
+	// The port of the other end is not reported by device 2.
+	topo.RestoreLinks(topo.Device("1"))
+	if n := len(topo.Links()); n != 0 {
+		t.Fatalf("unexpected number of links: expected=0, got=%v", n)
+	}
+	// The saved links are kept until they go down.
+	if len(db.links) != 2 {
+		t.Fatalf("unexpected saved links: %v", db.links)
+	}
+}
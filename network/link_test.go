@@ -0,0 +1,125 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"testing"
+)
+
+// linkRecorder records the link events.
+type linkRecorder struct {
+	up, down []string
+}
+
+func (r *linkRecorder) OnTopologyChange(Finder) error {
+	return nil
+}
+
+func (r *linkRecorder) OnLinkUp(finder Finder, ports [2]*Port) error {
+	r.up = append(r.up, newLink(ports).ID())
+	return nil
+}
+
+func (r *linkRecorder) OnLinkDown(finder Finder, ports [2]*Port) error {
+	r.down = append(r.down, newLink(ports).ID())
+	return nil
+}
+
+func TestLinkEvents(t *testing.T) {
+	db := &drainDB{switches: map[uint64]bool{1: false, 2: false, 3: false}}
+	topo := newTestTopology(db, []string{"1", "2", "3"}, nil)
+	recorder := &linkRecorder{}
+	topo.setEventListener(recorder)
+
+	one, two, three := topo.Device("1"), topo.Device("2"), topo.Device("3")
+	link12 := [2]*Port{NewPort(one, 2), NewPort(two, 1)}
+	link23 := [2]*Port{NewPort(two, 3), NewPort(three, 2)}
+	topo.DeviceLinked(link12)
+	topo.DeviceLinked(link23)
+	// Rediscovering an existing link should not raise any event.
+	topo.DeviceLinked([2]*Port{link12[1], link12[0]})
+
+	if len(recorder.up) != 2 || len(recorder.down) != 0 {
+		t.Fatalf("unexpected link events: up=%v, down=%v", recorder.up, recorder.down)
+	}
+	if n := len(topo.Links()); n != 2 {
+		t.Fatalf("unexpected number of links: expected=2, got=%v", n)
+	}
+
+	topo.PortRemoved(link12[0])
+	if len(recorder.down) != 1 || recorder.down[0] != newLink(link12).ID() {
+		t.Fatalf("unexpected link down events: %v", recorder.down)
+	}
+
+	topo.DeviceRemoved(three)
+	if len(recorder.down) != 2 || recorder.down[1] != newLink(link23).ID() {
+		t.Fatalf("unexpected link down events: %v", recorder.down)
+	}
+	if n := len(topo.Links()); n != 0 {
+		t.Fatalf("unexpected number of links: expected=0, got=%v", n)
+	}
+}
+
+func TestIndirectLink(t *testing.T) {
+	db := &drainDB{switches: map[uint64]bool{1: false, 2: false, 3: false}}
+	topo := newTestTopology(db, []string{"1", "2", "3"}, nil)
+	recorder := &linkRecorder{}
+	topo.setEventListener(recorder)
+
+	one, two, three := topo.Device("1"), topo.Device("2"), topo.Device("3")
+	link12 := [2]*Port{NewPort(one, 2), NewPort(two, 1)}
+	topo.DeviceLinkedIndirectly(link12)
+	if len(recorder.up) != 1 || !topo.isIndirectLink(link12) {
+		t.Fatalf("expected an indirect link: up=%v", recorder.up)
+	}
+	// Another switch in the same broadcast domain is ignored.
+	topo.DeviceLinkedIndirectly([2]*Port{link12[0], NewPort(three, 1)})
+	if len(recorder.up) != 1 || len(topo.Links()) != 1 {
+		t.Fatalf("unexpected links: up=%v, links=%v", recorder.up, len(topo.Links()))
+	}
+
+	// LLDP shows that the link is a direct one.
+	topo.DeviceLinked(link12)
+	if topo.isIndirectLink(link12) {
+		t.Fatal("expected a direct link")
+	}
+	if len(recorder.up) != 1 || len(recorder.down) != 0 {
+		t.Fatalf("unexpected link events: up=%v, down=%v", recorder.up, recorder.down)
+	}
+	// BDDP does not demote the direct link.
+	topo.DeviceLinkedIndirectly(link12)
+	if topo.isIndirectLink(link12) {
+		t.Fatal("expected a direct link")
+	}
+
+	// A direct link replaces the indirect link on the same port.
+	link23 := [2]*Port{NewPort(two, 3), NewPort(three, 2)}
+	topo.DeviceLinkedIndirectly(link23)
+	link13 := [2]*Port{NewPort(one, 3), link23[1]}
+	topo.DeviceLinked(link13)
+	if len(recorder.down) != 1 || recorder.down[0] != newLink(link23).ID() {
+		t.Fatalf("unexpected link down events: %v", recorder.down)
+	}
+	if n := len(topo.Links()); n != 2 {
+		t.Fatalf("unexpected number of links: expected=2, got=%v", n)
+	}
+}
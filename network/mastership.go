@@ -0,0 +1,151 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/superkkt/cherry/openflow"
+	"github.com/superkkt/cherry/openflow/transceiver"
+)
+
+var (
+	errNotMaster = errors.New("OpenFlow 1.0 device is only served by the master controller")
+	// ErrSlave is returned when we try to change the state of a device, e.g., install a flow,
+	// while we are not the master.
+	ErrSlave = errors.New("controller is a SLAVE of the device")
+)
+
+// mastership is the role of this controller among the controllers that share the switches.
+type mastership struct {
+	mutex  sync.RWMutex
+	master bool
+	// Generation of the current master, which may be another controller. 0 means that
+	// the election has not been done yet.
+	generation uint64
+}
+
+func (r *mastership) get() (master bool, generation uint64) {
+	// Read lock
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return r.master, r.generation
+}
+
+// isSlave returns whether we are a SLAVE of the devices, i.e., we are not the master or the
+// master has not been elected yet. A nil mastership is always the master.
+func (r *mastership) isSlave() bool {
+	if r == nil {
+		return false
+	}
+	master, _ := r.get()
+
+	return !master
+}
+
+func (r *mastership) set(master bool, generation uint64) {
+	// Write lock
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.master = master
+	r.generation = generation
+}
+
+// applyRole requests the device to change our role according to our mastership. We are a SLAVE
+// until we are elected as the master, i.e., generation is 0. OpenFlow 1.0 devices do not support
+// the controller roles, so errNotMaster is returned if we are not the master.
+func applyRole(d *Device, master bool, generation uint64) error {
+	switch d.Factory().ProtocolVersion() {
+	case openflow.OF10_VERSION:
+		if !master {
+			return errNotMaster
+		}
+		return nil
+	case openflow.OF13_VERSION, openflow.OF14_VERSION:
+		// The master makes the device change the other controllers to SLAVE.
+		role := openflow.RoleSlave
+		if master {
+			role = openflow.RoleMaster
+		}
+		return d.SetRole(role, generation)
+	default:
+		panic(fmt.Sprintf("unexpected OpenFlow protocol version: %v", d.Factory().ProtocolVersion()))
+	}
+}
+
+// setMastership is called whenever our mastership or the generation of the master changes.
+func (r *Controller) setMastership(master bool, generation uint64) {
+	r.mastership.set(master, generation)
+	logger.Infof("changing the role on all the devices: master=%v, generation=%v", master, generation)
+
+	for _, d := range r.topo.Devices() {
+		if !d.isReady() {
+			continue
+		}
+		// The devices initialized while we were a SLAVE do not have our special flows, e.g.,
+		// the table-miss flows. They are initialized again by reconnecting.
+		if master && d.isReadOnly() {
+			logger.Infof("disconnecting %v to initialize it as the master", d.ID())
+			d.disconnect()
+			continue
+		}
+		err := applyRole(d, master, generation)
+		if err == errNotMaster {
+			logger.Warningf("disconnecting %v: %v", d.ID(), err)
+			d.disconnect()
+			continue
+		}
+		if err != nil {
+			logger.Errorf("failed to change the role on %v: %v", d.ID(), err)
+		}
+	}
+}
+
+// isStateChanging returns whether msg changes the state of a device, which the device denies to
+// its SLAVE controllers.
+func isStateChanging(msg encoding.BinaryMarshaler) bool {
+	switch msg.(type) {
+	case openflow.FlowMod, openflow.GroupMod, openflow.MeterMod, openflow.PacketOut:
+		return true
+	default:
+		return false
+	}
+}
+
+// readOnlyWriter discards the messages that change the state of the device. The handshake of a
+// SLAVE uses it so that the flows installed by the master are not touched.
+type readOnlyWriter struct {
+	transceiver.Writer
+}
+
+func (r readOnlyWriter) Write(msg encoding.BinaryMarshaler) error {
+	if isStateChanging(msg) {
+		return nil
+	}
+
+	return r.Writer.Write(msg)
+}
@@ -0,0 +1,123 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"encoding"
+	"testing"
+
+	"github.com/superkkt/cherry/openflow"
+	"github.com/superkkt/cherry/openflow/of10"
+	"github.com/superkkt/cherry/openflow/of13"
+)
+
+type recordingWriter struct {
+	messages []encoding.BinaryMarshaler
+}
+
+func (r *recordingWriter) Write(msg encoding.BinaryMarshaler) error {
+	r.messages = append(r.messages, msg)
+	return nil
+}
+
+func TestMastershipTransition(t *testing.T) {
+	var m *mastership
+	if m.isSlave() {
+		t.Fatal("nil mastership should be the master")
+	}
+
+	m = new(mastership)
+	if !m.isSlave() {
+		t.Fatal("expected a SLAVE before the election")
+	}
+	m.set(true, 1)
+	if m.isSlave() {
+		t.Fatal("expected the master after the election")
+	}
+	m.set(false, 2)
+	if !m.isSlave() {
+		t.Fatal("expected a SLAVE after another controller is elected")
+	}
+	if master, generation := m.get(); master || generation != 2 {
+		t.Fatalf("unexpected mastership: master=%v, generation=%v", master, generation)
+	}
+}
+
+func TestSessionWriteAsSlave(t *testing.T) {
+	f := of13.NewFactory()
+	flow, err := f.NewFlowMod(openflow.FlowAdd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	group, err := f.NewGroupMod(openflow.GroupAdd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	packet, err := f.NewPacketOut()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &session{mastership: new(mastership)}
+	for _, msg := range []encoding.BinaryMarshaler{flow, group, packet} {
+		if err := s.Write(msg); err != ErrSlave {
+			t.Fatalf("expected ErrSlave for %T, got %v", msg, err)
+		}
+	}
+}
+
+func TestReadOnlyWriter(t *testing.T) {
+	f := of13.NewFactory()
+	flow, err := f.NewFlowMod(openflow.FlowAdd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	barrier, err := f.NewBarrierRequest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := new(recordingWriter)
+	r := readOnlyWriter{w}
+	if err := r.Write(flow); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Write(barrier); err != nil {
+		t.Fatal(err)
+	}
+	if len(w.messages) != 1 || w.messages[0] != barrier {
+		t.Fatalf("unexpected written messages: %v", w.messages)
+	}
+}
+
+func TestApplyRoleOF10(t *testing.T) {
+	d := &Device{factory: of10.NewFactory()}
+	if err := applyRole(d, false, 0); err != errNotMaster {
+		t.Fatalf("expected errNotMaster before the election, got %v", err)
+	}
+	if err := applyRole(d, false, 3); err != errNotMaster {
+		t.Fatalf("expected errNotMaster as a SLAVE, got %v", err)
+	}
+	if err := applyRole(d, true, 3); err != nil {
+		t.Fatalf("unexpected error as the master: %v", err)
+	}
+}
@@ -27,27 +27,34 @@ import (
 	"github.com/superkkt/cherry/openflow/transceiver"
 
 	"github.com/pkg/errors"
+	"github.com/superkkt/viper"
 )
 
 type of10Session struct {
 	device *Device
+	retry  handshakeRetry
 	// True after we get the first barrier reply that means all the previously
 	// installed flows on the device have been removed, and then the ACL flow for
 	// ARP packes has been installed.
 	checkpoint bool
 }
 
-func newOF10Session(d *Device) *of10Session {
+func newOF10Session(d *Device, retry handshakeRetry) *of10Session {
 	return &of10Session{
 		device: d,
+		retry:  retry,
 	}
 }
 
 func (r *of10Session) OnHello(f openflow.Factory, w transceiver.Writer, v openflow.Hello) error {
-	if err := sendHello(f, w); err != nil {
+	// Do not touch the flows installed by the master.
+	if r.device.session.mastership.isSlave() {
+		return errNotMaster
+	}
+	if err := r.retry.do("HELLO", func() error { return sendHello(f, w) }); err != nil {
 		return errors.Wrap(err, "failed to send HELLO")
 	}
-	if err := sendSetConfig(f, w); err != nil {
+	if err := sendSetConfig(f, w, r.device.MissSendLength()); err != nil {
 		return errors.Wrap(err, "failed to send SET_CONFIG")
 	}
 	if err := sendRemoveAllFlows(f, w); err != nil {
@@ -62,6 +69,11 @@ func (r *of10Session) OnHello(f openflow.Factory, w transceiver.Writer, v openfl
 	if err := setLLDPSender(f, w); err != nil {
 		return errors.Wrap(err, "failed to set the LLDP sender")
 	}
+	if viper.GetBool("default.bddp") {
+		if err := setBDDPSender(f, w); err != nil {
+			return errors.Wrap(err, "failed to set the BDDP sender")
+		}
+	}
 	if err := sendBarrierRequest(f, w); err != nil {
 		return errors.Wrap(err, "failed to send BARRIER_REQUEST")
 	}
@@ -79,7 +91,7 @@ func (r *of10Session) OnBarrierReply(f openflow.Factory, w transceiver.Writer, v
 	if err := sendDescriptionRequest(f, w); err != nil {
 		return errors.Wrap(err, "failed to send DESCRIPTION_REQUEST")
 	}
-	if err := sendFeaturesRequest(f, w); err != nil {
+	if err := r.retry.do("FEATURES_REQUEST", func() error { return sendFeaturesRequest(f, w) }); err != nil {
 		return errors.Wrap(err, "failed to send FEATURE_REQUEST")
 	}
 	r.checkpoint = true
@@ -124,6 +136,34 @@ func (r *of10Session) OnDescReply(f openflow.Factory, w transceiver.Writer, v op
 	return nil
 }
 
+func (r *of10Session) OnFlowStatsReply(f openflow.Factory, w transceiver.Writer, v openflow.FlowStatsReply) error {
+	return nil
+}
+
+func (r *of10Session) OnPortStatsReply(f openflow.Factory, w transceiver.Writer, v openflow.PortStatsReply) error {
+	return nil
+}
+
+func (r *of10Session) OnRoleReply(f openflow.Factory, w transceiver.Writer, v openflow.RoleReply) error {
+	return nil
+}
+
+func (r *of10Session) OnExperimenter(f openflow.Factory, w transceiver.Writer, v openflow.Experimenter) error {
+	return nil
+}
+
+func (r *of10Session) OnTableFeaturesReply(f openflow.Factory, w transceiver.Writer, v openflow.TableFeaturesReply) error {
+	return nil
+}
+
+func (r *of10Session) OnQueueGetConfigReply(f openflow.Factory, w transceiver.Writer, v openflow.QueueGetConfigReply) error {
+	return nil
+}
+
+func (r *of10Session) OnQueueStatsReply(f openflow.Factory, w transceiver.Writer, v openflow.QueueStatsReply) error {
+	return nil
+}
+
 func (r *of10Session) OnPortDescReply(f openflow.Factory, w transceiver.Writer, v openflow.PortDescReply) error {
 	// Do nothing because OpenFlow 1.0 uses FeaturesReply instead of PortDescReply.
 	return nil
@@ -29,27 +29,39 @@ import (
 	"github.com/superkkt/cherry/openflow/transceiver"
 
 	"github.com/pkg/errors"
+	"github.com/superkkt/viper"
 )
 
 type of13Session struct {
 	device *Device
+	retry  handshakeRetry
 	// True after we get the first barrier reply that means all the previously
 	// installed flows on the device have been removed, and then the ACL flow for
 	// ARP packes has been installed.
 	checkpoint bool
+	// Table-miss entries of the tables that the controller does not use.
+	tableMiss tableMissPolicy
 }
 
-func newOF13Session(d *Device) *of13Session {
+func newOF13Session(d *Device, retry handshakeRetry) *of13Session {
 	return &of13Session{
-		device: d,
+		device:    d,
+		retry:     retry,
+		tableMiss: newTableMissPolicy(),
 	}
 }
 
+// OnHello is called after the session has confirmed that the connection is not an auxiliary
+// one. The session has already sent HELLO.
 func (r *of13Session) OnHello(f openflow.Factory, w transceiver.Writer, v openflow.Hello) error {
-	if err := sendHello(f, w); err != nil {
-		return errors.Wrap(err, "failed to send HELLO")
+	// A SLAVE does not touch the flows installed by the master. The device is initialized
+	// again when we become the master.
+	if r.device.session.mastership.isSlave() {
+		logger.Infof("initializing the device as a SLAVE: %v", r.device.session.remoteAddr)
+		r.device.setReadOnly(true)
+		w = readOnlyWriter{w}
 	}
-	if err := sendSetConfig(f, w); err != nil {
+	if err := sendSetConfig(f, w, r.device.MissSendLength()); err != nil {
 		return errors.Wrap(err, "failed to send SET_CONFIG")
 	}
 	if err := sendRemoveAllFlows(f, w); err != nil {
@@ -64,6 +76,11 @@ func (r *of13Session) OnHello(f openflow.Factory, w transceiver.Writer, v openfl
 	if err := setLLDPSender(f, w); err != nil {
 		return errors.Wrap(err, "failed to set the LLDP sender")
 	}
+	if viper.GetBool("default.bddp") {
+		if err := setBDDPSender(f, w); err != nil {
+			return errors.Wrap(err, "failed to set the BDDP sender")
+		}
+	}
 	if err := sendBarrierRequest(f, w); err != nil {
 		return errors.Wrap(err, "failed to send BARRIER_REQUEST")
 	}
@@ -78,7 +95,7 @@ func (r *of13Session) OnBarrierReply(f openflow.Factory, w transceiver.Writer, v
 		return nil
 	}
 
-	if err := sendFeaturesRequest(f, w); err != nil {
+	if err := r.retry.do("FEATURES_REQUEST", func() error { return sendFeaturesRequest(f, w) }); err != nil {
 		return errors.Wrap(err, "failed to send FEATURE_REQUEST")
 	}
 	r.checkpoint = true
@@ -129,7 +146,10 @@ func (r *of13Session) setTableMiss(f openflow.Factory, w transceiver.Writer, tab
 	// Table-miss entry should have zero priority
 	msg.SetPriority(0)
 	msg.SetFlowMatch(match)
-	msg.SetFlowInstruction(inst)
+	// No instruction drops the packets.
+	if inst != nil {
+		msg.SetFlowInstruction(inst)
+	}
 
 	return w.Write(msg)
 }
@@ -186,7 +206,18 @@ func (r *of13Session) setDefaultTableMiss(f openflow.Factory, w transceiver.Writ
 		return err
 	}
 
-	// 0 -> Controller
+	// The flow table is 0, or 1 if table 0 is used as the classifier table.
+	var flowTableID uint8
+	if viper.GetBool("qos.classifier_table") {
+		// 0 -> 1
+		inst.GotoTable(1)
+		if err := r.setTableMiss(f, w, 0, inst); err != nil {
+			return errors.Wrap(err, "failed to set table_miss flow entry")
+		}
+		flowTableID = 1
+	}
+
+	// Flow table -> Controller
 	outPort := openflow.NewOutPort()
 	outPort.SetController()
 	action, err := f.NewAction()
@@ -196,10 +227,33 @@ func (r *of13Session) setDefaultTableMiss(f openflow.Factory, w transceiver.Writ
 	action.SetOutPort(outPort)
 
 	inst.ApplyAction(action)
-	if err := r.setTableMiss(f, w, 0, inst); err != nil {
+	if err := r.setTableMiss(f, w, flowTableID, inst); err != nil {
 		return errors.Wrap(err, "failed to set table_miss flow entry")
 	}
-	r.device.setFlowTableID(0)
+	r.device.setFlowTableID(flowTableID)
+	if flowTableID > 0 {
+		r.device.setClassifierTableID(0)
+	}
+
+	return r.setOtherTableMiss(f, w, 0, flowTableID)
+}
+
+// setOtherTableMiss installs the table-miss entries of the tables other than used according
+// to the table-miss policy.
+func (r *of13Session) setOtherTableMiss(f openflow.Factory, w transceiver.Writer, used ...uint8) error {
+	inst, err := r.tableMiss.instruction(f)
+	if err != nil {
+		return err
+	}
+	tables := r.tableMiss.tables(r.device.Features().NumTables, used...)
+	for _, id := range tables {
+		if err := r.setTableMiss(f, w, id, inst); err != nil {
+			return errors.Wrap(err, "failed to set table_miss flow entry")
+		}
+	}
+	if len(tables) > 0 {
+		logger.Debugf("installed the table-miss entries (%v) on %v tables of %v", r.tableMiss, len(tables), r.device.ID())
+	}
 
 	return nil
 }
@@ -207,6 +261,11 @@ func (r *of13Session) setDefaultTableMiss(f openflow.Factory, w transceiver.Writ
 func (r *of13Session) OnDescReply(f openflow.Factory, w transceiver.Writer, v openflow.DescReply) error {
 	var err error
 
+	if r.device.isReadOnly() {
+		// The table IDs are still learned from the table-miss flows.
+		w = readOnlyWriter{w}
+	}
+
 	// FIXME:
 	// Implement general routines for various table structures of OF1.3 switches
 	// based on table features reply
@@ -225,7 +284,38 @@ func (r *of13Session) OnDescReply(f openflow.Factory, w transceiver.Writer, v op
 	if err := sendPortDescriptionRequest(f, w); err != nil {
 		return errors.Wrap(err, "failed to send DESCRIPTION_REQUEST")
 	}
+	if err := sendTableFeaturesRequest(f, w); err != nil {
+		return errors.Wrap(err, "failed to send TABLE_FEATURES_REQUEST")
+	}
+
+	return nil
+}
+
+func (r *of13Session) OnFlowStatsReply(f openflow.Factory, w transceiver.Writer, v openflow.FlowStatsReply) error {
+	return nil
+}
+
+func (r *of13Session) OnPortStatsReply(f openflow.Factory, w transceiver.Writer, v openflow.PortStatsReply) error {
+	return nil
+}
+
+func (r *of13Session) OnRoleReply(f openflow.Factory, w transceiver.Writer, v openflow.RoleReply) error {
+	return nil
+}
+
+func (r *of13Session) OnExperimenter(f openflow.Factory, w transceiver.Writer, v openflow.Experimenter) error {
+	return nil
+}
+
+func (r *of13Session) OnTableFeaturesReply(f openflow.Factory, w transceiver.Writer, v openflow.TableFeaturesReply) error {
+	return nil
+}
+
+func (r *of13Session) OnQueueGetConfigReply(f openflow.Factory, w transceiver.Writer, v openflow.QueueGetConfigReply) error {
+	return nil
+}
 
+func (r *of13Session) OnQueueStatsReply(f openflow.Factory, w transceiver.Writer, v openflow.QueueStatsReply) error {
 	return nil
 }
 
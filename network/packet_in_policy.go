@@ -0,0 +1,293 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/superkkt/cherry/clock"
+	"github.com/superkkt/cherry/openflow"
+
+	"github.com/superkkt/viper"
+)
+
+type packetInAction int
+
+const (
+	// Deliver the packet to the applications.
+	deliverPacketIn packetInAction = iota
+	// Count and drop the packet.
+	dropPacketIn
+	// Deliver only one of every N packets.
+	samplePacketIn
+)
+
+type packetInRule struct {
+	action   packetInAction
+	interval uint64 // N of the sample action
+}
+
+func (r packetInRule) String() string {
+	switch r.action {
+	case deliverPacketIn:
+		return "deliver"
+	case dropPacketIn:
+		return "drop"
+	case samplePacketIn:
+		return fmt.Sprintf("sample:%v", r.interval)
+	default:
+		panic(fmt.Sprintf("unexpected packet-in action: %v", r.action))
+	}
+}
+
+// parsePacketInRule parses s that should be one of "deliver", "drop", and "sample:N".
+// Empty s means "deliver".
+func parsePacketInRule(s string) (packetInRule, error) {
+	switch s = strings.ToLower(strings.TrimSpace(s)); {
+	case s == "" || s == "deliver":
+		return packetInRule{action: deliverPacketIn}, nil
+	case s == "drop":
+		return packetInRule{action: dropPacketIn}, nil
+	case strings.HasPrefix(s, "sample:"):
+		n, err := strconv.ParseUint(s[len("sample:"):], 10, 64)
+		if err != nil || n == 0 {
+			return packetInRule{}, fmt.Errorf("invalid sample interval: %v", s)
+		}
+		return packetInRule{action: samplePacketIn, interval: n}, nil
+	default:
+		return packetInRule{}, fmt.Errorf("unknown packet-in policy: %v", s)
+	}
+}
+
+// Reasons that have their own policy. Other reasons are always delivered.
+var packetInReasons = []openflow.PacketInReason{
+	openflow.PacketInNoMatch,
+	openflow.PacketInAction,
+	openflow.PacketInInvalidTTL,
+}
+
+type packetInCounter struct {
+	received  uint64
+	delivered uint64
+	dropped   uint64 // Dropped by the drop or sample action
+	limited   uint64 // Dropped by the rate limiter
+}
+
+// packetInPolicy decides whether a PACKET_IN should be delivered to the applications
+// based on its reason. It is shared by all devices.
+type packetInPolicy struct {
+	rules [3]packetInRule
+	// Maximum number of PACKET_INs delivered per second for each reason on each device.
+	// 0 means unlimited.
+	rateLimit uint64
+	counters  [3]packetInCounter
+	// Number of PACKET_INs per second from a device, regardless of their reasons, over
+	// which we block the table-miss packets of the device for floodBlockTime. 0 disables it.
+	floodThreshold uint64
+	floodBlockTime time.Duration
+	// Default max_len of the PACKET_INs and its overrides keyed by the DPID of each device.
+	mutex        sync.Mutex
+	missSendLen  uint16
+	missSendLens map[string]uint16
+}
+
+func newPacketInPolicy() *packetInPolicy {
+	missSendLen := 0xFFFF
+	if viper.IsSet("default.packet_in.miss_send_len") {
+		missSendLen = viper.GetInt("default.packet_in.miss_send_len")
+	}
+	if missSendLen < 0 || missSendLen > 0xFFFF {
+		// missSendLen should be already checked in the main code.
+		panic("invalid default.packet_in.miss_send_len in the config file")
+	}
+
+	v := &packetInPolicy{
+		rateLimit:      uint64(viper.GetInt("default.packet_in.rate_limit")),
+		floodThreshold: uint64(viper.GetInt("default.packet_in.flood_threshold")),
+		floodBlockTime: time.Duration(viper.GetInt("default.packet_in.flood_block_time")) * time.Second,
+		missSendLen:    uint16(missSendLen),
+		missSendLens:   make(map[string]uint16),
+	}
+	if v.floodBlockTime <= 0 {
+		v.floodBlockTime = defaultFloodBlockTime
+	}
+	for _, reason := range packetInReasons {
+		key := fmt.Sprintf("default.packet_in.%v", reason)
+		rule, err := parsePacketInRule(viper.GetString(key))
+		if err != nil {
+			logger.Errorf("invalid %v: %v (deliver all packets)", key, err)
+			continue
+		}
+		v.rules[reason] = rule
+	}
+
+	return v
+}
+
+func (r *packetInPolicy) String() string {
+	var buf bytes.Buffer
+	buf.WriteString(fmt.Sprintf("PACKET_IN policy (rate_limit=%v, flood_threshold=%v, miss_send_len=%v):\n", r.rateLimit, r.floodThreshold, r.missSendLength("")))
+	for _, reason := range packetInReasons {
+		c := &r.counters[reason]
+		buf.WriteString(fmt.Sprintf("\t%v: policy=%v, received=%v, delivered=%v, dropped=%v, limited=%v\n",
+			reason, r.rules[reason], atomic.LoadUint64(&c.received), atomic.LoadUint64(&c.delivered),
+			atomic.LoadUint64(&c.dropped), atomic.LoadUint64(&c.limited)))
+	}
+
+	return buf.String()
+}
+
+// missSendLength returns the max_len of the PACKET_INs that should be configured on
+// the device whose DPID is dpid. Empty dpid means the default value.
+func (r *packetInPolicy) missSendLength(dpid string) uint16 {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if v, ok := r.missSendLens[dpid]; ok {
+		return v
+	}
+
+	return r.missSendLen
+}
+
+// setMissSendLength overrides the max_len of the PACKET_INs for the device whose DPID
+// is dpid. The override survives reconnections of the device.
+func (r *packetInPolicy) setMissSendLength(dpid string, length uint16) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.missSendLens[dpid] = length
+}
+
+const defaultFloodBlockTime = 10 * time.Second
+
+// packetInGate applies the packet-in policy to the PACKET_INs from a device. A
+// device has its own sample counters and rate limiters for each reason so that a
+// storm of one reason cannot crowd out the others. It is goroutine-safe because the
+// auxiliary connections of a device share the gate of its main connection.
+type packetInGate struct {
+	mutex   sync.Mutex
+	policy  *packetInPolicy
+	clock   clock.Clock
+	samples [3]uint64
+	windows [3]struct {
+		start time.Time
+		count uint64
+	}
+	// Window of all the PACKET_INs to detect a flood, and the time until which the
+	// table-miss packets are blocked.
+	flood struct {
+		start time.Time
+		count uint64
+	}
+	blockedUntil time.Time
+}
+
+func newPacketInGate(policy *packetInPolicy, clk clock.Clock) *packetInGate {
+	if policy == nil {
+		panic("policy is nil")
+	}
+	if clk == nil {
+		panic("clock is nil")
+	}
+
+	return &packetInGate{policy: policy, clock: clk}
+}
+
+// admit returns whether a PACKET_IN whose reason is reason should be delivered to the applications.
+func (r *packetInGate) admit(reason openflow.PacketInReason) bool {
+	if int(reason) >= len(r.policy.rules) {
+		return true
+	}
+	counter := &r.policy.counters[reason]
+	atomic.AddUint64(&counter.received, 1)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	rule := r.policy.rules[reason]
+	switch rule.action {
+	case dropPacketIn:
+		atomic.AddUint64(&counter.dropped, 1)
+		return false
+	case samplePacketIn:
+		r.samples[reason]++
+		// Deliver the first packet of every interval.
+		if (r.samples[reason]-1)%rule.interval != 0 {
+			atomic.AddUint64(&counter.dropped, 1)
+			return false
+		}
+	}
+
+	if r.policy.rateLimit > 0 {
+		w := &r.windows[reason]
+		now := r.clock.Now()
+		if now.Sub(w.start) >= time.Second {
+			w.start = now
+			w.count = 0
+		}
+		if w.count >= r.policy.rateLimit {
+			atomic.AddUint64(&counter.limited, 1)
+			return false
+		}
+		w.count++
+	}
+	atomic.AddUint64(&counter.delivered, 1)
+
+	return true
+}
+
+// overloaded counts a PACKET_IN and returns true if the device has just exceeded the
+// flood threshold. It returns true only once for each block time so that the caller
+// installs a single blocking flow.
+func (r *packetInGate) overloaded() bool {
+	if r.policy.floodThreshold == 0 {
+		return false
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := r.clock.Now()
+	if now.Before(r.blockedUntil) {
+		return false
+	}
+	w := &r.flood
+	if now.Sub(w.start) >= time.Second {
+		w.start = now
+		w.count = 0
+	}
+	w.count++
+	if w.count <= r.policy.floodThreshold {
+		return false
+	}
+	r.blockedUntil = now.Add(r.policy.floodBlockTime)
+	w.count = 0
+
+	return true
+}
@@ -0,0 +1,155 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/superkkt/cherry/openflow"
+	"github.com/superkkt/cherry/testutil"
+)
+
+func TestPacketInGate(t *testing.T) {
+	policy := &packetInPolicy{rateLimit: 5}
+	policy.rules[openflow.PacketInNoMatch] = packetInRule{action: deliverPacketIn}
+	policy.rules[openflow.PacketInAction] = packetInRule{action: dropPacketIn}
+	policy.rules[openflow.PacketInInvalidTTL] = packetInRule{action: samplePacketIn, interval: 3}
+	clock := testutil.NewFakeClock(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))
+	gate := newPacketInGate(policy, clock)
+
+	delivered := make(map[openflow.PacketInReason]int)
+	for i := 0; i < 9; i++ {
+		for _, reason := range packetInReasons {
+			if gate.admit(reason) {
+				delivered[reason]++
+			}
+		}
+	}
+	// Rate limited to 5 packets per second.
+	if delivered[openflow.PacketInNoMatch] != 5 {
+		t.Fatalf("unexpected delivered no_match packets: expected=5, got=%v", delivered[openflow.PacketInNoMatch])
+	}
+	if delivered[openflow.PacketInAction] != 0 {
+		t.Fatalf("unexpected delivered action packets: expected=0, got=%v", delivered[openflow.PacketInAction])
+	}
+	// A sample of every 3 packets, which does not consume the rate limit of the other reasons.
+	if delivered[openflow.PacketInInvalidTTL] != 3 {
+		t.Fatalf("unexpected delivered invalid_ttl packets: expected=3, got=%v", delivered[openflow.PacketInInvalidTTL])
+	}
+	if c := policy.counters[openflow.PacketInNoMatch]; c.received != 9 || c.limited != 4 {
+		t.Fatalf("unexpected no_match counter: %+v", c)
+	}
+	if c := policy.counters[openflow.PacketInInvalidTTL]; c.received != 9 || c.dropped != 6 {
+		t.Fatalf("unexpected invalid_ttl counter: %+v", c)
+	}
+
+	// The rate limit is reset every second.
+	clock.Advance(time.Second)
+	if !gate.admit(openflow.PacketInNoMatch) {
+		t.Fatalf("no_match packet is not delivered after the rate limit window")
+	}
+
+	// Unknown reasons are always delivered.
+	if !gate.admit(openflow.PacketInReason(100)) {
+		t.Fatalf("unknown reason is not delivered")
+	}
+}
+
+// The auxiliary connections of a device share the gate from their own goroutines.
+func TestPacketInGateConcurrent(t *testing.T) {
+	policy := &packetInPolicy{rateLimit: 50}
+	policy.rules[openflow.PacketInNoMatch] = packetInRule{action: deliverPacketIn}
+	clock := testutil.NewFakeClock(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))
+	gate := newPacketInGate(policy, clock)
+
+	var delivered uint64
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if gate.admit(openflow.PacketInNoMatch) {
+					atomic.AddUint64(&delivered, 1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if delivered != 50 {
+		t.Fatalf("unexpected delivered packets: expected=50, got=%v", delivered)
+	}
+}
+
+func TestPacketInGateFlood(t *testing.T) {
+	policy := &packetInPolicy{floodThreshold: 3, floodBlockTime: 10 * time.Second}
+	clock := testutil.NewFakeClock(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))
+	gate := newPacketInGate(policy, clock)
+
+	for i := 0; i < 3; i++ {
+		if gate.overloaded() {
+			t.Fatalf("overloaded under the threshold: packet=%v", i+1)
+		}
+	}
+	if !gate.overloaded() {
+		t.Fatalf("not overloaded over the threshold")
+	}
+	// Only once while the table-miss packets are blocked.
+	for i := 0; i < 10; i++ {
+		if gate.overloaded() {
+			t.Fatalf("overloaded again during the block time")
+		}
+	}
+
+	// The flood is detected again after the block time.
+	clock.Advance(10 * time.Second)
+	for i := 0; i < 3; i++ {
+		if gate.overloaded() {
+			t.Fatalf("overloaded under the threshold after the block time: packet=%v", i+1)
+		}
+	}
+	if !gate.overloaded() {
+		t.Fatalf("not overloaded over the threshold after the block time")
+	}
+}
+
+func TestParsePacketInRule(t *testing.T) {
+	valid := map[string]string{"": "deliver", "Deliver": "deliver", "drop": "drop", " sample:10 ": "sample:10"}
+	for s, expected := range valid {
+		rule, err := parsePacketInRule(s)
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %v", s, err)
+		}
+		if rule.String() != expected {
+			t.Fatalf("unexpected rule for %q: expected=%v, got=%v", s, expected, rule)
+		}
+	}
+	for _, s := range []string{"sample:0", "sample:", "sample:x", "forward"} {
+		if _, err := parsePacketInRule(s); err == nil {
+			t.Fatalf("expected an error for %q", s)
+		}
+	}
+}
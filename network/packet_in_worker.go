@@ -0,0 +1,105 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"github.com/superkkt/cherry/metrics"
+)
+
+var (
+	packetInsQueueFull = metrics.NewCounterVec("cherry_packet_ins_queue_full_total", "Number of PACKET_IN messages dropped due to the full queue of the PACKET_IN workers.", "dpid")
+)
+
+const defaultPacketInQueueSize = 1024
+
+// packetInWorkers delivers the PACKET_INs to the applications on a fixed number of goroutines
+// shared by all the devices, so that a slow application does not stall the transceiver of a
+// device, which also has to handle the echoes and the replies of the device. The PACKET_INs
+// from an ingress port are always processed by the same worker in the order of their arrival,
+// because the applications, e.g., the host tracker, rely on the order of the packets from a
+// host. The PACKET_INs from different ports may be processed concurrently.
+type packetInWorkers struct {
+	// Bounded queue of each worker.
+	queues []chan func()
+}
+
+// newPacketInWorkers starts workers goroutines, each of which has a queue of queueSize
+// PACKET_INs. It returns nil if workers is zero, which means the PACKET_INs are processed by
+// the transceiver goroutine of each device.
+func newPacketInWorkers(workers, queueSize int) *packetInWorkers {
+	if workers < 0 || queueSize < 0 {
+		panic("negative number of the PACKET_IN workers or queue size")
+	}
+	if workers == 0 {
+		return nil
+	}
+	if queueSize == 0 {
+		queueSize = defaultPacketInQueueSize
+	}
+
+	v := &packetInWorkers{
+		queues: make([]chan func(), workers),
+	}
+	for i := range v.queues {
+		v.queues[i] = make(chan func(), queueSize)
+		go v.run(v.queues[i])
+	}
+
+	return v
+}
+
+func (r *packetInWorkers) run(queue <-chan func()) {
+	for job := range queue {
+		job()
+	}
+}
+
+// worker returns the index of the worker that processes the PACKET_INs from ingress.
+func (r *packetInWorkers) worker(ingress *Port) int {
+	// FNV-1a hash of the DPID and the port number.
+	h := uint32(2166136261)
+	id := ingress.Device().ID()
+	for i := 0; i < len(id); i++ {
+		h = (h ^ uint32(id[i])) * 16777619
+	}
+	n := ingress.Number()
+	for i := 0; i < 4; i++ {
+		h = (h ^ (n & 0xFF)) * 16777619
+		n >>= 8
+	}
+
+	return int(h % uint32(len(r.queues)))
+}
+
+// dispatch queues job that processes a PACKET_IN from ingress. The PACKET_IN is dropped if the
+// queue of its worker is full. Errors of job should be handled by job itself.
+func (r *packetInWorkers) dispatch(ingress *Port, job func()) bool {
+	select {
+	case r.queues[r.worker(ingress)] <- job:
+		return true
+	default:
+		dpid := ingress.Device().ID()
+		packetInsQueueFull.Inc(dpid)
+		rateLogger.Warningf("packet-in queue full "+dpid, "PACKET_IN worker queue is full: dropping a PACKET_IN from %v", ingress.ID())
+		return false
+	}
+}
@@ -0,0 +1,105 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPacketInWorkersOrder(t *testing.T) {
+	workers := newPacketInWorkers(4, 1000)
+	d := &Device{id: "1", ports: make(map[uint32]*Port)}
+	ports := []*Port{NewPort(d, 1), NewPort(d, 2), NewPort(d, 3)}
+
+	var mutex sync.Mutex
+	received := make(map[*Port][]int)
+	wg := new(sync.WaitGroup)
+	for i := 0; i < 100; i++ {
+		for _, p := range ports {
+			p, seq := p, i
+			wg.Add(1)
+			ok := workers.dispatch(p, func() {
+				defer wg.Done()
+				mutex.Lock()
+				received[p] = append(received[p], seq)
+				mutex.Unlock()
+			})
+			if !ok {
+				t.Fatal("unexpected full queue")
+			}
+		}
+	}
+	wg.Wait()
+
+	// The PACKET_INs from each port are processed in order.
+	for _, p := range ports {
+		if len(received[p]) != 100 {
+			t.Fatalf("%v: expected 100 PACKET_INs, got %v", p.ID(), len(received[p]))
+		}
+		for i, seq := range received[p] {
+			if seq != i {
+				t.Fatalf("%v: out of order PACKET_IN: expected=%v, got=%v", p.ID(), i, seq)
+			}
+		}
+	}
+}
+
+func TestPacketInWorkersQueueFull(t *testing.T) {
+	workers := newPacketInWorkers(1, 1)
+	d := &Device{id: "1", ports: make(map[uint32]*Port)}
+	p := NewPort(d, 1)
+
+	// Block the worker with the first job.
+	started, release := make(chan struct{}), make(chan struct{})
+	if !workers.dispatch(p, func() { close(started); <-release }) {
+		t.Fatal("unexpected full queue")
+	}
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the worker did not start the job")
+	}
+
+	// The second one fills the queue, and the third one is dropped.
+	done := make(chan struct{})
+	if !workers.dispatch(p, func() { close(done) }) {
+		t.Fatal("unexpected full queue")
+	}
+	if workers.dispatch(p, func() { t.Error("dropped job is executed") }) {
+		t.Fatal("expected the full queue")
+	}
+
+	close(release)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the queued job is not executed")
+	}
+}
+
+func TestNoPacketInWorkers(t *testing.T) {
+	if newPacketInWorkers(0, 100) != nil {
+		t.Fatal("expected no workers")
+	}
+}
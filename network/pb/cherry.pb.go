@@ -0,0 +1,1858 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: cherry.proto
+
+package pb
+
+import (
+	context "context"
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	timestamp "github.com/golang/protobuf/ptypes/timestamp"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+type Device struct {
+	Dpid string `protobuf:"bytes,1,opt,name=dpid,proto3" json:"dpid,omitempty"`
+	// Negotiated OpenFlow version, e.g., 0x04 for OpenFlow 1.3.
+	Version      uint32 `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
+	Manufacturer string `protobuf:"bytes,3,opt,name=manufacturer,proto3" json:"manufacturer,omitempty"`
+	Hardware     string `protobuf:"bytes,4,opt,name=hardware,proto3" json:"hardware,omitempty"`
+	Software     string `protobuf:"bytes,5,opt,name=software,proto3" json:"software,omitempty"`
+	Serial       string `protobuf:"bytes,6,opt,name=serial,proto3" json:"serial,omitempty"`
+	Description  string `protobuf:"bytes,7,opt,name=description,proto3" json:"description,omitempty"`
+	NumBuffers   uint32 `protobuf:"varint,8,opt,name=num_buffers,json=numBuffers,proto3" json:"num_buffers,omitempty"`
+	NumTables    uint32 `protobuf:"varint,9,opt,name=num_tables,json=numTables,proto3" json:"num_tables,omitempty"`
+	NumPorts     uint32 `protobuf:"varint,10,opt,name=num_ports,json=numPorts,proto3" json:"num_ports,omitempty"`
+	Drained      bool   `protobuf:"varint,11,opt,name=drained,proto3" json:"drained,omitempty"`
+	// Role of this controller confirmed by the device.
+	Role string `protobuf:"bytes,12,opt,name=role,proto3" json:"role,omitempty"`
+	// Round-trip time of the control channel in microseconds.
+	Rtt                  int64    `protobuf:"varint,13,opt,name=rtt,proto3" json:"rtt,omitempty"`
+	MissSendLen          uint32   `protobuf:"varint,14,opt,name=miss_send_len,json=missSendLen,proto3" json:"miss_send_len,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Device) Reset()         { *m = Device{} }
+func (m *Device) String() string { return proto.CompactTextString(m) }
+func (*Device) ProtoMessage()    {}
+func (*Device) Descriptor() ([]byte, []int) {
+	return fileDescriptor_d8e43d6f1454fb27, []int{0}
+}
+
+func (m *Device) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Device.Unmarshal(m, b)
+}
+func (m *Device) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Device.Marshal(b, m, deterministic)
+}
+func (m *Device) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Device.Merge(m, src)
+}
+func (m *Device) XXX_Size() int {
+	return xxx_messageInfo_Device.Size(m)
+}
+func (m *Device) XXX_DiscardUnknown() {
+	xxx_messageInfo_Device.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Device proto.InternalMessageInfo
+
+func (m *Device) GetDpid() string {
+	if m != nil {
+		return m.Dpid
+	}
+	return ""
+}
+
+func (m *Device) GetVersion() uint32 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *Device) GetManufacturer() string {
+	if m != nil {
+		return m.Manufacturer
+	}
+	return ""
+}
+
+func (m *Device) GetHardware() string {
+	if m != nil {
+		return m.Hardware
+	}
+	return ""
+}
+
+func (m *Device) GetSoftware() string {
+	if m != nil {
+		return m.Software
+	}
+	return ""
+}
+
+func (m *Device) GetSerial() string {
+	if m != nil {
+		return m.Serial
+	}
+	return ""
+}
+
+func (m *Device) GetDescription() string {
+	if m != nil {
+		return m.Description
+	}
+	return ""
+}
+
+func (m *Device) GetNumBuffers() uint32 {
+	if m != nil {
+		return m.NumBuffers
+	}
+	return 0
+}
+
+func (m *Device) GetNumTables() uint32 {
+	if m != nil {
+		return m.NumTables
+	}
+	return 0
+}
+
+func (m *Device) GetNumPorts() uint32 {
+	if m != nil {
+		return m.NumPorts
+	}
+	return 0
+}
+
+func (m *Device) GetDrained() bool {
+	if m != nil {
+		return m.Drained
+	}
+	return false
+}
+
+func (m *Device) GetRole() string {
+	if m != nil {
+		return m.Role
+	}
+	return ""
+}
+
+func (m *Device) GetRtt() int64 {
+	if m != nil {
+		return m.Rtt
+	}
+	return 0
+}
+
+func (m *Device) GetMissSendLen() uint32 {
+	if m != nil {
+		return m.MissSendLen
+	}
+	return 0
+}
+
+type PortStats struct {
+	RxPackets            uint64   `protobuf:"varint,1,opt,name=rx_packets,json=rxPackets,proto3" json:"rx_packets,omitempty"`
+	TxPackets            uint64   `protobuf:"varint,2,opt,name=tx_packets,json=txPackets,proto3" json:"tx_packets,omitempty"`
+	RxBytes              uint64   `protobuf:"varint,3,opt,name=rx_bytes,json=rxBytes,proto3" json:"rx_bytes,omitempty"`
+	TxBytes              uint64   `protobuf:"varint,4,opt,name=tx_bytes,json=txBytes,proto3" json:"tx_bytes,omitempty"`
+	RxDropped            uint64   `protobuf:"varint,5,opt,name=rx_dropped,json=rxDropped,proto3" json:"rx_dropped,omitempty"`
+	TxDropped            uint64   `protobuf:"varint,6,opt,name=tx_dropped,json=txDropped,proto3" json:"tx_dropped,omitempty"`
+	RxErrors             uint64   `protobuf:"varint,7,opt,name=rx_errors,json=rxErrors,proto3" json:"rx_errors,omitempty"`
+	TxErrors             uint64   `protobuf:"varint,8,opt,name=tx_errors,json=txErrors,proto3" json:"tx_errors,omitempty"`
+	RxFrameErr           uint64   `protobuf:"varint,9,opt,name=rx_frame_err,json=rxFrameErr,proto3" json:"rx_frame_err,omitempty"`
+	RxOverErr            uint64   `protobuf:"varint,10,opt,name=rx_over_err,json=rxOverErr,proto3" json:"rx_over_err,omitempty"`
+	RxCrcErr             uint64   `protobuf:"varint,11,opt,name=rx_crc_err,json=rxCrcErr,proto3" json:"rx_crc_err,omitempty"`
+	Collisions           uint64   `protobuf:"varint,12,opt,name=collisions,proto3" json:"collisions,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PortStats) Reset()         { *m = PortStats{} }
+func (m *PortStats) String() string { return proto.CompactTextString(m) }
+func (*PortStats) ProtoMessage()    {}
+func (*PortStats) Descriptor() ([]byte, []int) {
+	return fileDescriptor_d8e43d6f1454fb27, []int{1}
+}
+
+func (m *PortStats) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PortStats.Unmarshal(m, b)
+}
+func (m *PortStats) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PortStats.Marshal(b, m, deterministic)
+}
+func (m *PortStats) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PortStats.Merge(m, src)
+}
+func (m *PortStats) XXX_Size() int {
+	return xxx_messageInfo_PortStats.Size(m)
+}
+func (m *PortStats) XXX_DiscardUnknown() {
+	xxx_messageInfo_PortStats.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PortStats proto.InternalMessageInfo
+
+func (m *PortStats) GetRxPackets() uint64 {
+	if m != nil {
+		return m.RxPackets
+	}
+	return 0
+}
+
+func (m *PortStats) GetTxPackets() uint64 {
+	if m != nil {
+		return m.TxPackets
+	}
+	return 0
+}
+
+func (m *PortStats) GetRxBytes() uint64 {
+	if m != nil {
+		return m.RxBytes
+	}
+	return 0
+}
+
+func (m *PortStats) GetTxBytes() uint64 {
+	if m != nil {
+		return m.TxBytes
+	}
+	return 0
+}
+
+func (m *PortStats) GetRxDropped() uint64 {
+	if m != nil {
+		return m.RxDropped
+	}
+	return 0
+}
+
+func (m *PortStats) GetTxDropped() uint64 {
+	if m != nil {
+		return m.TxDropped
+	}
+	return 0
+}
+
+func (m *PortStats) GetRxErrors() uint64 {
+	if m != nil {
+		return m.RxErrors
+	}
+	return 0
+}
+
+func (m *PortStats) GetTxErrors() uint64 {
+	if m != nil {
+		return m.TxErrors
+	}
+	return 0
+}
+
+func (m *PortStats) GetRxFrameErr() uint64 {
+	if m != nil {
+		return m.RxFrameErr
+	}
+	return 0
+}
+
+func (m *PortStats) GetRxOverErr() uint64 {
+	if m != nil {
+		return m.RxOverErr
+	}
+	return 0
+}
+
+func (m *PortStats) GetRxCrcErr() uint64 {
+	if m != nil {
+		return m.RxCrcErr
+	}
+	return 0
+}
+
+func (m *PortStats) GetCollisions() uint64 {
+	if m != nil {
+		return m.Collisions
+	}
+	return 0
+}
+
+type Port struct {
+	Number  uint32 `protobuf:"varint,1,opt,name=number,proto3" json:"number,omitempty"`
+	Name    string `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Mac     string `protobuf:"bytes,3,opt,name=mac,proto3" json:"mac,omitempty"`
+	AdminUp bool   `protobuf:"varint,4,opt,name=admin_up,json=adminUp,proto3" json:"admin_up,omitempty"`
+	LinkUp  bool   `protobuf:"varint,5,opt,name=link_up,json=linkUp,proto3" json:"link_up,omitempty"`
+	// Raw OFPPC_* and OFPPS_* bitmaps of the negotiated OpenFlow version.
+	Config uint32 `protobuf:"varint,6,opt,name=config,proto3" json:"config,omitempty"`
+	State  uint32 `protobuf:"varint,7,opt,name=state,proto3" json:"state,omitempty"`
+	// Speed in Mbps.
+	Speed uint64 `protobuf:"varint,8,opt,name=speed,proto3" json:"speed,omitempty"`
+	// Not set if the port statistics have never been polled.
+	Stats                *PortStats `protobuf:"bytes,9,opt,name=stats,proto3" json:"stats,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *Port) Reset()         { *m = Port{} }
+func (m *Port) String() string { return proto.CompactTextString(m) }
+func (*Port) ProtoMessage()    {}
+func (*Port) Descriptor() ([]byte, []int) {
+	return fileDescriptor_d8e43d6f1454fb27, []int{2}
+}
+
+func (m *Port) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Port.Unmarshal(m, b)
+}
+func (m *Port) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Port.Marshal(b, m, deterministic)
+}
+func (m *Port) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Port.Merge(m, src)
+}
+func (m *Port) XXX_Size() int {
+	return xxx_messageInfo_Port.Size(m)
+}
+func (m *Port) XXX_DiscardUnknown() {
+	xxx_messageInfo_Port.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Port proto.InternalMessageInfo
+
+func (m *Port) GetNumber() uint32 {
+	if m != nil {
+		return m.Number
+	}
+	return 0
+}
+
+func (m *Port) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Port) GetMac() string {
+	if m != nil {
+		return m.Mac
+	}
+	return ""
+}
+
+func (m *Port) GetAdminUp() bool {
+	if m != nil {
+		return m.AdminUp
+	}
+	return false
+}
+
+func (m *Port) GetLinkUp() bool {
+	if m != nil {
+		return m.LinkUp
+	}
+	return false
+}
+
+func (m *Port) GetConfig() uint32 {
+	if m != nil {
+		return m.Config
+	}
+	return 0
+}
+
+func (m *Port) GetState() uint32 {
+	if m != nil {
+		return m.State
+	}
+	return 0
+}
+
+func (m *Port) GetSpeed() uint64 {
+	if m != nil {
+		return m.Speed
+	}
+	return 0
+}
+
+func (m *Port) GetStats() *PortStats {
+	if m != nil {
+		return m.Stats
+	}
+	return nil
+}
+
+// FlowMatch is the match fields of a flow. Zero values (or empty strings) are wildcards.
+type FlowMatch struct {
+	InPort     uint32 `protobuf:"varint,1,opt,name=in_port,json=inPort,proto3" json:"in_port,omitempty"`
+	SrcMac     string `protobuf:"bytes,2,opt,name=src_mac,json=srcMac,proto3" json:"src_mac,omitempty"`
+	DstMac     string `protobuf:"bytes,3,opt,name=dst_mac,json=dstMac,proto3" json:"dst_mac,omitempty"`
+	EtherType  uint32 `protobuf:"varint,4,opt,name=ether_type,json=etherType,proto3" json:"ether_type,omitempty"`
+	IpProtocol uint32 `protobuf:"varint,5,opt,name=ip_protocol,json=ipProtocol,proto3" json:"ip_protocol,omitempty"`
+	// CIDR notation, e.g., 10.0.0.0/24.
+	SrcIp                string   `protobuf:"bytes,6,opt,name=src_ip,json=srcIp,proto3" json:"src_ip,omitempty"`
+	DstIp                string   `protobuf:"bytes,7,opt,name=dst_ip,json=dstIp,proto3" json:"dst_ip,omitempty"`
+	SrcPort              uint32   `protobuf:"varint,8,opt,name=src_port,json=srcPort,proto3" json:"src_port,omitempty"`
+	DstPort              uint32   `protobuf:"varint,9,opt,name=dst_port,json=dstPort,proto3" json:"dst_port,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *FlowMatch) Reset()         { *m = FlowMatch{} }
+func (m *FlowMatch) String() string { return proto.CompactTextString(m) }
+func (*FlowMatch) ProtoMessage()    {}
+func (*FlowMatch) Descriptor() ([]byte, []int) {
+	return fileDescriptor_d8e43d6f1454fb27, []int{3}
+}
+
+func (m *FlowMatch) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_FlowMatch.Unmarshal(m, b)
+}
+func (m *FlowMatch) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_FlowMatch.Marshal(b, m, deterministic)
+}
+func (m *FlowMatch) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FlowMatch.Merge(m, src)
+}
+func (m *FlowMatch) XXX_Size() int {
+	return xxx_messageInfo_FlowMatch.Size(m)
+}
+func (m *FlowMatch) XXX_DiscardUnknown() {
+	xxx_messageInfo_FlowMatch.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_FlowMatch proto.InternalMessageInfo
+
+func (m *FlowMatch) GetInPort() uint32 {
+	if m != nil {
+		return m.InPort
+	}
+	return 0
+}
+
+func (m *FlowMatch) GetSrcMac() string {
+	if m != nil {
+		return m.SrcMac
+	}
+	return ""
+}
+
+func (m *FlowMatch) GetDstMac() string {
+	if m != nil {
+		return m.DstMac
+	}
+	return ""
+}
+
+func (m *FlowMatch) GetEtherType() uint32 {
+	if m != nil {
+		return m.EtherType
+	}
+	return 0
+}
+
+func (m *FlowMatch) GetIpProtocol() uint32 {
+	if m != nil {
+		return m.IpProtocol
+	}
+	return 0
+}
+
+func (m *FlowMatch) GetSrcIp() string {
+	if m != nil {
+		return m.SrcIp
+	}
+	return ""
+}
+
+func (m *FlowMatch) GetDstIp() string {
+	if m != nil {
+		return m.DstIp
+	}
+	return ""
+}
+
+func (m *FlowMatch) GetSrcPort() uint32 {
+	if m != nil {
+		return m.SrcPort
+	}
+	return 0
+}
+
+func (m *FlowMatch) GetDstPort() uint32 {
+	if m != nil {
+		return m.DstPort
+	}
+	return 0
+}
+
+type Flow struct {
+	TableId     uint32     `protobuf:"varint,1,opt,name=table_id,json=tableId,proto3" json:"table_id,omitempty"`
+	Priority    uint32     `protobuf:"varint,2,opt,name=priority,proto3" json:"priority,omitempty"`
+	Cookie      uint64     `protobuf:"varint,3,opt,name=cookie,proto3" json:"cookie,omitempty"`
+	DurationSec uint32     `protobuf:"varint,4,opt,name=duration_sec,json=durationSec,proto3" json:"duration_sec,omitempty"`
+	IdleTimeout uint32     `protobuf:"varint,5,opt,name=idle_timeout,json=idleTimeout,proto3" json:"idle_timeout,omitempty"`
+	HardTimeout uint32     `protobuf:"varint,6,opt,name=hard_timeout,json=hardTimeout,proto3" json:"hard_timeout,omitempty"`
+	PacketCount uint64     `protobuf:"varint,7,opt,name=packet_count,json=packetCount,proto3" json:"packet_count,omitempty"`
+	ByteCount   uint64     `protobuf:"varint,8,opt,name=byte_count,json=byteCount,proto3" json:"byte_count,omitempty"`
+	Match       *FlowMatch `protobuf:"bytes,9,opt,name=match,proto3" json:"match,omitempty"`
+	// Name of the application that owns the flow, or empty if no application owns it.
+	Owner                string   `protobuf:"bytes,10,opt,name=owner,proto3" json:"owner,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Flow) Reset()         { *m = Flow{} }
+func (m *Flow) String() string { return proto.CompactTextString(m) }
+func (*Flow) ProtoMessage()    {}
+func (*Flow) Descriptor() ([]byte, []int) {
+	return fileDescriptor_d8e43d6f1454fb27, []int{4}
+}
+
+func (m *Flow) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Flow.Unmarshal(m, b)
+}
+func (m *Flow) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Flow.Marshal(b, m, deterministic)
+}
+func (m *Flow) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Flow.Merge(m, src)
+}
+func (m *Flow) XXX_Size() int {
+	return xxx_messageInfo_Flow.Size(m)
+}
+func (m *Flow) XXX_DiscardUnknown() {
+	xxx_messageInfo_Flow.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Flow proto.InternalMessageInfo
+
+func (m *Flow) GetTableId() uint32 {
+	if m != nil {
+		return m.TableId
+	}
+	return 0
+}
+
+func (m *Flow) GetPriority() uint32 {
+	if m != nil {
+		return m.Priority
+	}
+	return 0
+}
+
+func (m *Flow) GetCookie() uint64 {
+	if m != nil {
+		return m.Cookie
+	}
+	return 0
+}
+
+func (m *Flow) GetDurationSec() uint32 {
+	if m != nil {
+		return m.DurationSec
+	}
+	return 0
+}
+
+func (m *Flow) GetIdleTimeout() uint32 {
+	if m != nil {
+		return m.IdleTimeout
+	}
+	return 0
+}
+
+func (m *Flow) GetHardTimeout() uint32 {
+	if m != nil {
+		return m.HardTimeout
+	}
+	return 0
+}
+
+func (m *Flow) GetPacketCount() uint64 {
+	if m != nil {
+		return m.PacketCount
+	}
+	return 0
+}
+
+func (m *Flow) GetByteCount() uint64 {
+	if m != nil {
+		return m.ByteCount
+	}
+	return 0
+}
+
+func (m *Flow) GetMatch() *FlowMatch {
+	if m != nil {
+		return m.Match
+	}
+	return nil
+}
+
+func (m *Flow) GetOwner() string {
+	if m != nil {
+		return m.Owner
+	}
+	return ""
+}
+
+type Link struct {
+	Dpid1 string `protobuf:"bytes,1,opt,name=dpid1,proto3" json:"dpid1,omitempty"`
+	Port1 uint32 `protobuf:"varint,2,opt,name=port1,proto3" json:"port1,omitempty"`
+	Dpid2 string `protobuf:"bytes,3,opt,name=dpid2,proto3" json:"dpid2,omitempty"`
+	Port2 uint32 `protobuf:"varint,4,opt,name=port2,proto3" json:"port2,omitempty"`
+	// True if the link has been discovered by BDDP.
+	Indirect             bool     `protobuf:"varint,5,opt,name=indirect,proto3" json:"indirect,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Link) Reset()         { *m = Link{} }
+func (m *Link) String() string { return proto.CompactTextString(m) }
+func (*Link) ProtoMessage()    {}
+func (*Link) Descriptor() ([]byte, []int) {
+	return fileDescriptor_d8e43d6f1454fb27, []int{5}
+}
+
+func (m *Link) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Link.Unmarshal(m, b)
+}
+func (m *Link) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Link.Marshal(b, m, deterministic)
+}
+func (m *Link) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Link.Merge(m, src)
+}
+func (m *Link) XXX_Size() int {
+	return xxx_messageInfo_Link.Size(m)
+}
+func (m *Link) XXX_DiscardUnknown() {
+	xxx_messageInfo_Link.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Link proto.InternalMessageInfo
+
+func (m *Link) GetDpid1() string {
+	if m != nil {
+		return m.Dpid1
+	}
+	return ""
+}
+
+func (m *Link) GetPort1() uint32 {
+	if m != nil {
+		return m.Port1
+	}
+	return 0
+}
+
+func (m *Link) GetDpid2() string {
+	if m != nil {
+		return m.Dpid2
+	}
+	return ""
+}
+
+func (m *Link) GetPort2() uint32 {
+	if m != nil {
+		return m.Port2
+	}
+	return 0
+}
+
+func (m *Link) GetIndirect() bool {
+	if m != nil {
+		return m.Indirect
+	}
+	return false
+}
+
+type Host struct {
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Ip string `protobuf:"bytes,2,opt,name=ip,proto3" json:"ip,omitempty"`
+	// Switch port that the host is attached to.
+	Port                 string   `protobuf:"bytes,3,opt,name=port,proto3" json:"port,omitempty"`
+	Mac                  string   `protobuf:"bytes,4,opt,name=mac,proto3" json:"mac,omitempty"`
+	Description          string   `protobuf:"bytes,5,opt,name=description,proto3" json:"description,omitempty"`
+	Stale                bool     `protobuf:"varint,6,opt,name=stale,proto3" json:"stale,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Host) Reset()         { *m = Host{} }
+func (m *Host) String() string { return proto.CompactTextString(m) }
+func (*Host) ProtoMessage()    {}
+func (*Host) Descriptor() ([]byte, []int) {
+	return fileDescriptor_d8e43d6f1454fb27, []int{6}
+}
+
+func (m *Host) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Host.Unmarshal(m, b)
+}
+func (m *Host) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Host.Marshal(b, m, deterministic)
+}
+func (m *Host) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Host.Merge(m, src)
+}
+func (m *Host) XXX_Size() int {
+	return xxx_messageInfo_Host.Size(m)
+}
+func (m *Host) XXX_DiscardUnknown() {
+	xxx_messageInfo_Host.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Host proto.InternalMessageInfo
+
+func (m *Host) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *Host) GetIp() string {
+	if m != nil {
+		return m.Ip
+	}
+	return ""
+}
+
+func (m *Host) GetPort() string {
+	if m != nil {
+		return m.Port
+	}
+	return ""
+}
+
+func (m *Host) GetMac() string {
+	if m != nil {
+		return m.Mac
+	}
+	return ""
+}
+
+func (m *Host) GetDescription() string {
+	if m != nil {
+		return m.Description
+	}
+	return ""
+}
+
+func (m *Host) GetStale() bool {
+	if m != nil {
+		return m.Stale
+	}
+	return false
+}
+
+type EventPort struct {
+	Dpid                 string   `protobuf:"bytes,1,opt,name=dpid,proto3" json:"dpid,omitempty"`
+	Port                 uint32   `protobuf:"varint,2,opt,name=port,proto3" json:"port,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *EventPort) Reset()         { *m = EventPort{} }
+func (m *EventPort) String() string { return proto.CompactTextString(m) }
+func (*EventPort) ProtoMessage()    {}
+func (*EventPort) Descriptor() ([]byte, []int) {
+	return fileDescriptor_d8e43d6f1454fb27, []int{7}
+}
+
+func (m *EventPort) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_EventPort.Unmarshal(m, b)
+}
+func (m *EventPort) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_EventPort.Marshal(b, m, deterministic)
+}
+func (m *EventPort) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_EventPort.Merge(m, src)
+}
+func (m *EventPort) XXX_Size() int {
+	return xxx_messageInfo_EventPort.Size(m)
+}
+func (m *EventPort) XXX_DiscardUnknown() {
+	xxx_messageInfo_EventPort.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_EventPort proto.InternalMessageInfo
+
+func (m *EventPort) GetDpid() string {
+	if m != nil {
+		return m.Dpid
+	}
+	return ""
+}
+
+func (m *EventPort) GetPort() uint32 {
+	if m != nil {
+		return m.Port
+	}
+	return 0
+}
+
+// Event is a network event. The fields other than type and time are set according to the
+// type, as the events of the REST API.
+type Event struct {
+	// device_up, device_down, port_up, port_down, link_up, link_down, host_moved,
+	// flow_removed or negotiation_failed.
+	Type                 string               `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Time                 *timestamp.Timestamp `protobuf:"bytes,2,opt,name=time,proto3" json:"time,omitempty"`
+	Dpid                 string               `protobuf:"bytes,3,opt,name=dpid,proto3" json:"dpid,omitempty"`
+	Port                 uint32               `protobuf:"varint,4,opt,name=port,proto3" json:"port,omitempty"`
+	Link                 []*EventPort         `protobuf:"bytes,5,rep,name=link,proto3" json:"link,omitempty"`
+	Mac                  string               `protobuf:"bytes,6,opt,name=mac,proto3" json:"mac,omitempty"`
+	Ip                   string               `protobuf:"bytes,7,opt,name=ip,proto3" json:"ip,omitempty"`
+	Cookie               uint64               `protobuf:"varint,8,opt,name=cookie,proto3" json:"cookie,omitempty"`
+	Reason               string               `protobuf:"bytes,9,opt,name=reason,proto3" json:"reason,omitempty"`
+	Address              string               `protobuf:"bytes,10,opt,name=address,proto3" json:"address,omitempty"`
+	Failures             int32                `protobuf:"varint,11,opt,name=failures,proto3" json:"failures,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *Event) Reset()         { *m = Event{} }
+func (m *Event) String() string { return proto.CompactTextString(m) }
+func (*Event) ProtoMessage()    {}
+func (*Event) Descriptor() ([]byte, []int) {
+	return fileDescriptor_d8e43d6f1454fb27, []int{8}
+}
+
+func (m *Event) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Event.Unmarshal(m, b)
+}
+func (m *Event) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Event.Marshal(b, m, deterministic)
+}
+func (m *Event) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Event.Merge(m, src)
+}
+func (m *Event) XXX_Size() int {
+	return xxx_messageInfo_Event.Size(m)
+}
+func (m *Event) XXX_DiscardUnknown() {
+	xxx_messageInfo_Event.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Event proto.InternalMessageInfo
+
+func (m *Event) GetType() string {
+	if m != nil {
+		return m.Type
+	}
+	return ""
+}
+
+func (m *Event) GetTime() *timestamp.Timestamp {
+	if m != nil {
+		return m.Time
+	}
+	return nil
+}
+
+func (m *Event) GetDpid() string {
+	if m != nil {
+		return m.Dpid
+	}
+	return ""
+}
+
+func (m *Event) GetPort() uint32 {
+	if m != nil {
+		return m.Port
+	}
+	return 0
+}
+
+func (m *Event) GetLink() []*EventPort {
+	if m != nil {
+		return m.Link
+	}
+	return nil
+}
+
+func (m *Event) GetMac() string {
+	if m != nil {
+		return m.Mac
+	}
+	return ""
+}
+
+func (m *Event) GetIp() string {
+	if m != nil {
+		return m.Ip
+	}
+	return ""
+}
+
+func (m *Event) GetCookie() uint64 {
+	if m != nil {
+		return m.Cookie
+	}
+	return 0
+}
+
+func (m *Event) GetReason() string {
+	if m != nil {
+		return m.Reason
+	}
+	return ""
+}
+
+func (m *Event) GetAddress() string {
+	if m != nil {
+		return m.Address
+	}
+	return ""
+}
+
+func (m *Event) GetFailures() int32 {
+	if m != nil {
+		return m.Failures
+	}
+	return 0
+}
+
+type ListDevicesRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListDevicesRequest) Reset()         { *m = ListDevicesRequest{} }
+func (m *ListDevicesRequest) String() string { return proto.CompactTextString(m) }
+func (*ListDevicesRequest) ProtoMessage()    {}
+func (*ListDevicesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_d8e43d6f1454fb27, []int{9}
+}
+
+func (m *ListDevicesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListDevicesRequest.Unmarshal(m, b)
+}
+func (m *ListDevicesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListDevicesRequest.Marshal(b, m, deterministic)
+}
+func (m *ListDevicesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListDevicesRequest.Merge(m, src)
+}
+func (m *ListDevicesRequest) XXX_Size() int {
+	return xxx_messageInfo_ListDevicesRequest.Size(m)
+}
+func (m *ListDevicesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListDevicesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListDevicesRequest proto.InternalMessageInfo
+
+type ListDevicesResponse struct {
+	Devices              []*Device `protobuf:"bytes,1,rep,name=devices,proto3" json:"devices,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
+	XXX_unrecognized     []byte    `json:"-"`
+	XXX_sizecache        int32     `json:"-"`
+}
+
+func (m *ListDevicesResponse) Reset()         { *m = ListDevicesResponse{} }
+func (m *ListDevicesResponse) String() string { return proto.CompactTextString(m) }
+func (*ListDevicesResponse) ProtoMessage()    {}
+func (*ListDevicesResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_d8e43d6f1454fb27, []int{10}
+}
+
+func (m *ListDevicesResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListDevicesResponse.Unmarshal(m, b)
+}
+func (m *ListDevicesResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListDevicesResponse.Marshal(b, m, deterministic)
+}
+func (m *ListDevicesResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListDevicesResponse.Merge(m, src)
+}
+func (m *ListDevicesResponse) XXX_Size() int {
+	return xxx_messageInfo_ListDevicesResponse.Size(m)
+}
+func (m *ListDevicesResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListDevicesResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListDevicesResponse proto.InternalMessageInfo
+
+func (m *ListDevicesResponse) GetDevices() []*Device {
+	if m != nil {
+		return m.Devices
+	}
+	return nil
+}
+
+type GetDeviceRequest struct {
+	Dpid                 string   `protobuf:"bytes,1,opt,name=dpid,proto3" json:"dpid,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetDeviceRequest) Reset()         { *m = GetDeviceRequest{} }
+func (m *GetDeviceRequest) String() string { return proto.CompactTextString(m) }
+func (*GetDeviceRequest) ProtoMessage()    {}
+func (*GetDeviceRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_d8e43d6f1454fb27, []int{11}
+}
+
+func (m *GetDeviceRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetDeviceRequest.Unmarshal(m, b)
+}
+func (m *GetDeviceRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetDeviceRequest.Marshal(b, m, deterministic)
+}
+func (m *GetDeviceRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetDeviceRequest.Merge(m, src)
+}
+func (m *GetDeviceRequest) XXX_Size() int {
+	return xxx_messageInfo_GetDeviceRequest.Size(m)
+}
+func (m *GetDeviceRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetDeviceRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetDeviceRequest proto.InternalMessageInfo
+
+func (m *GetDeviceRequest) GetDpid() string {
+	if m != nil {
+		return m.Dpid
+	}
+	return ""
+}
+
+type ListPortsRequest struct {
+	Dpid                 string   `protobuf:"bytes,1,opt,name=dpid,proto3" json:"dpid,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListPortsRequest) Reset()         { *m = ListPortsRequest{} }
+func (m *ListPortsRequest) String() string { return proto.CompactTextString(m) }
+func (*ListPortsRequest) ProtoMessage()    {}
+func (*ListPortsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_d8e43d6f1454fb27, []int{12}
+}
+
+func (m *ListPortsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListPortsRequest.Unmarshal(m, b)
+}
+func (m *ListPortsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListPortsRequest.Marshal(b, m, deterministic)
+}
+func (m *ListPortsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListPortsRequest.Merge(m, src)
+}
+func (m *ListPortsRequest) XXX_Size() int {
+	return xxx_messageInfo_ListPortsRequest.Size(m)
+}
+func (m *ListPortsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListPortsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListPortsRequest proto.InternalMessageInfo
+
+func (m *ListPortsRequest) GetDpid() string {
+	if m != nil {
+		return m.Dpid
+	}
+	return ""
+}
+
+type ListPortsResponse struct {
+	Ports                []*Port  `protobuf:"bytes,1,rep,name=ports,proto3" json:"ports,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListPortsResponse) Reset()         { *m = ListPortsResponse{} }
+func (m *ListPortsResponse) String() string { return proto.CompactTextString(m) }
+func (*ListPortsResponse) ProtoMessage()    {}
+func (*ListPortsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_d8e43d6f1454fb27, []int{13}
+}
+
+func (m *ListPortsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListPortsResponse.Unmarshal(m, b)
+}
+func (m *ListPortsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListPortsResponse.Marshal(b, m, deterministic)
+}
+func (m *ListPortsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListPortsResponse.Merge(m, src)
+}
+func (m *ListPortsResponse) XXX_Size() int {
+	return xxx_messageInfo_ListPortsResponse.Size(m)
+}
+func (m *ListPortsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListPortsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListPortsResponse proto.InternalMessageInfo
+
+func (m *ListPortsResponse) GetPorts() []*Port {
+	if m != nil {
+		return m.Ports
+	}
+	return nil
+}
+
+type ListFlowsRequest struct {
+	Dpid string `protobuf:"bytes,1,opt,name=dpid,proto3" json:"dpid,omitempty"`
+	// Only the flows owned by this application if it is not empty.
+	Owner                string   `protobuf:"bytes,2,opt,name=owner,proto3" json:"owner,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListFlowsRequest) Reset()         { *m = ListFlowsRequest{} }
+func (m *ListFlowsRequest) String() string { return proto.CompactTextString(m) }
+func (*ListFlowsRequest) ProtoMessage()    {}
+func (*ListFlowsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_d8e43d6f1454fb27, []int{14}
+}
+
+func (m *ListFlowsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListFlowsRequest.Unmarshal(m, b)
+}
+func (m *ListFlowsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListFlowsRequest.Marshal(b, m, deterministic)
+}
+func (m *ListFlowsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListFlowsRequest.Merge(m, src)
+}
+func (m *ListFlowsRequest) XXX_Size() int {
+	return xxx_messageInfo_ListFlowsRequest.Size(m)
+}
+func (m *ListFlowsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListFlowsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListFlowsRequest proto.InternalMessageInfo
+
+func (m *ListFlowsRequest) GetDpid() string {
+	if m != nil {
+		return m.Dpid
+	}
+	return ""
+}
+
+func (m *ListFlowsRequest) GetOwner() string {
+	if m != nil {
+		return m.Owner
+	}
+	return ""
+}
+
+type ListFlowsResponse struct {
+	Flows []*Flow `protobuf:"bytes,1,rep,name=flows,proto3" json:"flows,omitempty"`
+	// Time when the flows were collected from the device. Not set if they are not collected yet.
+	Collected            *timestamp.Timestamp `protobuf:"bytes,2,opt,name=collected,proto3" json:"collected,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *ListFlowsResponse) Reset()         { *m = ListFlowsResponse{} }
+func (m *ListFlowsResponse) String() string { return proto.CompactTextString(m) }
+func (*ListFlowsResponse) ProtoMessage()    {}
+func (*ListFlowsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_d8e43d6f1454fb27, []int{15}
+}
+
+func (m *ListFlowsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListFlowsResponse.Unmarshal(m, b)
+}
+func (m *ListFlowsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListFlowsResponse.Marshal(b, m, deterministic)
+}
+func (m *ListFlowsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListFlowsResponse.Merge(m, src)
+}
+func (m *ListFlowsResponse) XXX_Size() int {
+	return xxx_messageInfo_ListFlowsResponse.Size(m)
+}
+func (m *ListFlowsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListFlowsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListFlowsResponse proto.InternalMessageInfo
+
+func (m *ListFlowsResponse) GetFlows() []*Flow {
+	if m != nil {
+		return m.Flows
+	}
+	return nil
+}
+
+func (m *ListFlowsResponse) GetCollected() *timestamp.Timestamp {
+	if m != nil {
+		return m.Collected
+	}
+	return nil
+}
+
+type ListLinksRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListLinksRequest) Reset()         { *m = ListLinksRequest{} }
+func (m *ListLinksRequest) String() string { return proto.CompactTextString(m) }
+func (*ListLinksRequest) ProtoMessage()    {}
+func (*ListLinksRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_d8e43d6f1454fb27, []int{16}
+}
+
+func (m *ListLinksRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListLinksRequest.Unmarshal(m, b)
+}
+func (m *ListLinksRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListLinksRequest.Marshal(b, m, deterministic)
+}
+func (m *ListLinksRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListLinksRequest.Merge(m, src)
+}
+func (m *ListLinksRequest) XXX_Size() int {
+	return xxx_messageInfo_ListLinksRequest.Size(m)
+}
+func (m *ListLinksRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListLinksRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListLinksRequest proto.InternalMessageInfo
+
+type ListLinksResponse struct {
+	Links                []*Link  `protobuf:"bytes,1,rep,name=links,proto3" json:"links,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListLinksResponse) Reset()         { *m = ListLinksResponse{} }
+func (m *ListLinksResponse) String() string { return proto.CompactTextString(m) }
+func (*ListLinksResponse) ProtoMessage()    {}
+func (*ListLinksResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_d8e43d6f1454fb27, []int{17}
+}
+
+func (m *ListLinksResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListLinksResponse.Unmarshal(m, b)
+}
+func (m *ListLinksResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListLinksResponse.Marshal(b, m, deterministic)
+}
+func (m *ListLinksResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListLinksResponse.Merge(m, src)
+}
+func (m *ListLinksResponse) XXX_Size() int {
+	return xxx_messageInfo_ListLinksResponse.Size(m)
+}
+func (m *ListLinksResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListLinksResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListLinksResponse proto.InternalMessageInfo
+
+func (m *ListLinksResponse) GetLinks() []*Link {
+	if m != nil {
+		return m.Links
+	}
+	return nil
+}
+
+type ListHostsRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListHostsRequest) Reset()         { *m = ListHostsRequest{} }
+func (m *ListHostsRequest) String() string { return proto.CompactTextString(m) }
+func (*ListHostsRequest) ProtoMessage()    {}
+func (*ListHostsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_d8e43d6f1454fb27, []int{18}
+}
+
+func (m *ListHostsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListHostsRequest.Unmarshal(m, b)
+}
+func (m *ListHostsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListHostsRequest.Marshal(b, m, deterministic)
+}
+func (m *ListHostsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListHostsRequest.Merge(m, src)
+}
+func (m *ListHostsRequest) XXX_Size() int {
+	return xxx_messageInfo_ListHostsRequest.Size(m)
+}
+func (m *ListHostsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListHostsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListHostsRequest proto.InternalMessageInfo
+
+type ListHostsResponse struct {
+	Hosts                []*Host  `protobuf:"bytes,1,rep,name=hosts,proto3" json:"hosts,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListHostsResponse) Reset()         { *m = ListHostsResponse{} }
+func (m *ListHostsResponse) String() string { return proto.CompactTextString(m) }
+func (*ListHostsResponse) ProtoMessage()    {}
+func (*ListHostsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_d8e43d6f1454fb27, []int{19}
+}
+
+func (m *ListHostsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListHostsResponse.Unmarshal(m, b)
+}
+func (m *ListHostsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListHostsResponse.Marshal(b, m, deterministic)
+}
+func (m *ListHostsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListHostsResponse.Merge(m, src)
+}
+func (m *ListHostsResponse) XXX_Size() int {
+	return xxx_messageInfo_ListHostsResponse.Size(m)
+}
+func (m *ListHostsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListHostsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListHostsResponse proto.InternalMessageInfo
+
+func (m *ListHostsResponse) GetHosts() []*Host {
+	if m != nil {
+		return m.Hosts
+	}
+	return nil
+}
+
+type SubscribeRequest struct {
+	// Event types to receive. Empty means all the types.
+	Types                []string `protobuf:"bytes,1,rep,name=types,proto3" json:"types,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SubscribeRequest) Reset()         { *m = SubscribeRequest{} }
+func (m *SubscribeRequest) String() string { return proto.CompactTextString(m) }
+func (*SubscribeRequest) ProtoMessage()    {}
+func (*SubscribeRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_d8e43d6f1454fb27, []int{20}
+}
+
+func (m *SubscribeRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SubscribeRequest.Unmarshal(m, b)
+}
+func (m *SubscribeRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SubscribeRequest.Marshal(b, m, deterministic)
+}
+func (m *SubscribeRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SubscribeRequest.Merge(m, src)
+}
+func (m *SubscribeRequest) XXX_Size() int {
+	return xxx_messageInfo_SubscribeRequest.Size(m)
+}
+func (m *SubscribeRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SubscribeRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SubscribeRequest proto.InternalMessageInfo
+
+func (m *SubscribeRequest) GetTypes() []string {
+	if m != nil {
+		return m.Types
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*Device)(nil), "cherry.Device")
+	proto.RegisterType((*PortStats)(nil), "cherry.PortStats")
+	proto.RegisterType((*Port)(nil), "cherry.Port")
+	proto.RegisterType((*FlowMatch)(nil), "cherry.FlowMatch")
+	proto.RegisterType((*Flow)(nil), "cherry.Flow")
+	proto.RegisterType((*Link)(nil), "cherry.Link")
+	proto.RegisterType((*Host)(nil), "cherry.Host")
+	proto.RegisterType((*EventPort)(nil), "cherry.EventPort")
+	proto.RegisterType((*Event)(nil), "cherry.Event")
+	proto.RegisterType((*ListDevicesRequest)(nil), "cherry.ListDevicesRequest")
+	proto.RegisterType((*ListDevicesResponse)(nil), "cherry.ListDevicesResponse")
+	proto.RegisterType((*GetDeviceRequest)(nil), "cherry.GetDeviceRequest")
+	proto.RegisterType((*ListPortsRequest)(nil), "cherry.ListPortsRequest")
+	proto.RegisterType((*ListPortsResponse)(nil), "cherry.ListPortsResponse")
+	proto.RegisterType((*ListFlowsRequest)(nil), "cherry.ListFlowsRequest")
+	proto.RegisterType((*ListFlowsResponse)(nil), "cherry.ListFlowsResponse")
+	proto.RegisterType((*ListLinksRequest)(nil), "cherry.ListLinksRequest")
+	proto.RegisterType((*ListLinksResponse)(nil), "cherry.ListLinksResponse")
+	proto.RegisterType((*ListHostsRequest)(nil), "cherry.ListHostsRequest")
+	proto.RegisterType((*ListHostsResponse)(nil), "cherry.ListHostsResponse")
+	proto.RegisterType((*SubscribeRequest)(nil), "cherry.SubscribeRequest")
+}
+
+func init() { proto.RegisterFile("cherry.proto", fileDescriptor_d8e43d6f1454fb27) }
+
+var fileDescriptor_d8e43d6f1454fb27 = []byte{
+	// 1424 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x56, 0xcb, 0x8e, 0xdb, 0xc6,
+	0x12, 0x85, 0x34, 0xd4, 0x83, 0xa5, 0x91, 0x61, 0xf7, 0xf5, 0xb5, 0x39, 0xf2, 0xf5, 0xf5, 0x84,
+	0x40, 0xe2, 0x41, 0x16, 0x9a, 0x78, 0x8c, 0x3c, 0x80, 0x04, 0x48, 0xe0, 0x57, 0x62, 0xc0, 0x46,
+	0x8c, 0x9e, 0xf1, 0x26, 0x1b, 0x82, 0x22, 0x5b, 0x33, 0x0d, 0x89, 0x0f, 0x77, 0x37, 0x67, 0x34,
+	0x59, 0x67, 0x9b, 0x9f, 0xc8, 0x8f, 0x64, 0x99, 0x2f, 0xc9, 0x37, 0x04, 0xc8, 0x2a, 0xa8, 0xea,
+	0x26, 0x29, 0xc9, 0x82, 0x91, 0x1d, 0x4f, 0x9d, 0xea, 0xae, 0xea, 0xaa, 0x53, 0xdd, 0x84, 0xfd,
+	0xe4, 0x42, 0x28, 0x75, 0x3d, 0x2d, 0x55, 0x61, 0x0a, 0xd6, 0xb7, 0x68, 0xf2, 0xe0, 0xbc, 0x28,
+	0xce, 0x97, 0xe2, 0x98, 0xac, 0xb3, 0x6a, 0x7e, 0x6c, 0x64, 0x26, 0xb4, 0x89, 0xb3, 0xd2, 0x3a,
+	0x86, 0xbf, 0xee, 0x41, 0xff, 0x99, 0xb8, 0x94, 0x89, 0x60, 0x0c, 0xbc, 0xb4, 0x94, 0x69, 0xd0,
+	0x39, 0xec, 0x1c, 0xf9, 0x9c, 0xbe, 0x59, 0x00, 0x83, 0x4b, 0xa1, 0xb4, 0x2c, 0xf2, 0xa0, 0x7b,
+	0xd8, 0x39, 0x1a, 0xf3, 0x1a, 0xb2, 0x10, 0xf6, 0xb3, 0x38, 0xaf, 0xe6, 0x71, 0x62, 0x2a, 0x25,
+	0x54, 0xb0, 0x47, 0xab, 0x36, 0x6c, 0x6c, 0x02, 0xc3, 0x8b, 0x58, 0xa5, 0x57, 0xb1, 0x12, 0x81,
+	0x47, 0x7c, 0x83, 0x91, 0xd3, 0xc5, 0xdc, 0x10, 0xd7, 0xb3, 0x5c, 0x8d, 0xd9, 0x1d, 0xe8, 0x6b,
+	0xa1, 0x64, 0xbc, 0x0c, 0xfa, 0xc4, 0x38, 0xc4, 0x0e, 0x61, 0x94, 0x0a, 0x9d, 0x28, 0x59, 0x1a,
+	0xcc, 0x68, 0x40, 0xe4, 0xba, 0x89, 0x3d, 0x80, 0x51, 0x5e, 0x65, 0xd1, 0xac, 0x9a, 0xcf, 0x85,
+	0xd2, 0xc1, 0x90, 0x72, 0x86, 0xbc, 0xca, 0x9e, 0x58, 0x0b, 0xbb, 0x0f, 0x88, 0x22, 0x13, 0xcf,
+	0x96, 0x42, 0x07, 0x3e, 0xf1, 0x7e, 0x5e, 0x65, 0x67, 0x64, 0x60, 0xf7, 0x00, 0x41, 0x54, 0x16,
+	0xca, 0xe8, 0x00, 0x88, 0x1d, 0xe6, 0x55, 0xf6, 0x06, 0x31, 0x16, 0x23, 0x55, 0xb1, 0xcc, 0x45,
+	0x1a, 0x8c, 0x0e, 0x3b, 0x47, 0x43, 0x5e, 0x43, 0x2c, 0x9d, 0x2a, 0x96, 0x22, 0xd8, 0xb7, 0xa5,
+	0xc3, 0x6f, 0x76, 0x13, 0xf6, 0x94, 0x31, 0xc1, 0xf8, 0xb0, 0x73, 0xb4, 0xc7, 0xf1, 0x93, 0x85,
+	0x30, 0xce, 0xa4, 0xd6, 0x91, 0x16, 0x79, 0x1a, 0x2d, 0x45, 0x1e, 0xdc, 0xa0, 0x00, 0x23, 0x34,
+	0x9e, 0x8a, 0x3c, 0x7d, 0x25, 0xf2, 0xf0, 0xaf, 0x2e, 0xf8, 0x18, 0xed, 0xd4, 0xc4, 0x86, 0xb2,
+	0x55, 0xab, 0xa8, 0x8c, 0x93, 0x85, 0x30, 0x9a, 0x1a, 0xe3, 0x71, 0x5f, 0xad, 0xde, 0x58, 0x03,
+	0xd2, 0xa6, 0xa5, 0xbb, 0x96, 0x36, 0x0d, 0x7d, 0x00, 0x43, 0xb5, 0x8a, 0x66, 0xd7, 0x46, 0x68,
+	0x6a, 0x8f, 0xc7, 0x07, 0x6a, 0xf5, 0x04, 0x21, 0x52, 0xa6, 0xa6, 0x3c, 0x4b, 0x19, 0x47, 0xd9,
+	0x98, 0xa9, 0x2a, 0xca, 0x52, 0xa4, 0x41, 0xaf, 0x8e, 0xf9, 0xcc, 0x1a, 0x5c, 0xcc, 0x9a, 0xee,
+	0xd7, 0x31, 0x6b, 0xfa, 0x1e, 0xf8, 0x6a, 0x15, 0x09, 0xa5, 0x0a, 0xa5, 0xa9, 0x41, 0x1e, 0x1f,
+	0xaa, 0xd5, 0x73, 0xc2, 0x48, 0x9a, 0x86, 0x1c, 0x5a, 0xd2, 0xd4, 0xe4, 0x21, 0xec, 0xab, 0x55,
+	0x34, 0x57, 0x71, 0x26, 0xd0, 0x85, 0x7a, 0xe3, 0x71, 0x50, 0xab, 0x17, 0x68, 0x7a, 0xae, 0x14,
+	0xfb, 0x3f, 0x8c, 0xd4, 0x2a, 0x2a, 0x2e, 0x85, 0x22, 0x07, 0xa8, 0x53, 0xfb, 0xf1, 0x52, 0x28,
+	0xe4, 0xff, 0x47, 0x99, 0x27, 0x2a, 0x21, 0x7a, 0x54, 0x07, 0x7f, 0xaa, 0x12, 0xbb, 0x1a, 0x92,
+	0x62, 0xb9, 0x94, 0xa8, 0x5e, 0x4d, 0x9d, 0xf2, 0xf8, 0x9a, 0x25, 0xfc, 0xb3, 0x03, 0x1e, 0x56,
+	0x1e, 0xd5, 0x97, 0x57, 0xd9, 0x4c, 0x28, 0x2a, 0xf8, 0x98, 0x3b, 0x84, 0x4d, 0xce, 0xe3, 0x4c,
+	0x50, 0x9d, 0x7d, 0x4e, 0xdf, 0xd8, 0xe4, 0x2c, 0x4e, 0x9c, 0xf8, 0xf1, 0x13, 0x2b, 0x1b, 0xa7,
+	0x99, 0xcc, 0xa3, 0xaa, 0xa4, 0xca, 0x0e, 0xf9, 0x80, 0xf0, 0xdb, 0x92, 0xdd, 0x85, 0xc1, 0x52,
+	0xe6, 0x0b, 0x64, 0x7a, 0xc4, 0xf4, 0x11, 0xbe, 0x2d, 0x31, 0x62, 0x52, 0xe4, 0x73, 0x79, 0x4e,
+	0xf5, 0x1c, 0x73, 0x87, 0xd8, 0x6d, 0xe8, 0x69, 0x13, 0x1b, 0x41, 0x85, 0x1c, 0x73, 0x0b, 0xc8,
+	0x5a, 0x0a, 0x91, 0xba, 0x0a, 0x5a, 0xc0, 0x1e, 0x5a, 0x5f, 0xab, 0xe9, 0xd1, 0xc9, 0xad, 0xa9,
+	0xbb, 0x0f, 0x1a, 0x31, 0xd9, 0xe5, 0x3a, 0xfc, 0xbb, 0x03, 0xfe, 0x8b, 0x65, 0x71, 0xf5, 0x3a,
+	0x36, 0xc9, 0x05, 0xe6, 0x24, 0x73, 0xd2, 0x7b, 0x7d, 0x5a, 0x99, 0x53, 0x15, 0xee, 0xc2, 0x40,
+	0xab, 0x24, 0xc2, 0xd3, 0x75, 0xdd, 0x10, 0xaa, 0xe4, 0x75, 0x9c, 0x20, 0x91, 0x6a, 0x13, 0xb5,
+	0xc7, 0xee, 0xa7, 0xda, 0x20, 0x71, 0x1f, 0x40, 0x98, 0x0b, 0xa1, 0x22, 0x73, 0x5d, 0xda, 0x79,
+	0x1f, 0x73, 0x9f, 0x2c, 0x67, 0xd7, 0xa5, 0xc0, 0xd1, 0x94, 0x65, 0x44, 0xb7, 0x4e, 0x52, 0x2c,
+	0xa9, 0x02, 0x63, 0x0e, 0xb2, 0x7c, 0xe3, 0x2c, 0xec, 0xbf, 0x80, 0x21, 0x22, 0x59, 0xba, 0xa9,
+	0xef, 0x69, 0x95, 0xbc, 0x2c, 0xd1, 0x8c, 0xf1, 0x64, 0xe9, 0xe6, 0xbd, 0x97, 0x6a, 0xf3, 0xb2,
+	0xc4, 0x3a, 0xa3, 0x37, 0x65, 0x6e, 0xc7, 0x1c, 0xf3, 0xa5, 0xd4, 0x0f, 0x60, 0x88, 0x2b, 0x88,
+	0xb2, 0x13, 0x8e, 0x19, 0x23, 0x15, 0xfe, 0xde, 0x05, 0x0f, 0x0f, 0x8f, 0x3e, 0x74, 0x07, 0x44,
+	0xee, 0xc2, 0x1b, 0xf3, 0x01, 0xe1, 0x97, 0x29, 0xde, 0x4c, 0xa5, 0x92, 0x85, 0x92, 0xe6, 0xda,
+	0x5d, 0x7a, 0x0d, 0xb6, 0x9d, 0x2a, 0x16, 0x52, 0xb8, 0x81, 0x72, 0x88, 0x7d, 0x04, 0xfb, 0x69,
+	0xa5, 0x62, 0xbc, 0x83, 0x22, 0x2d, 0x12, 0x77, 0xfa, 0x51, 0x6d, 0x3b, 0x15, 0x09, 0xba, 0xc8,
+	0x74, 0x29, 0x22, 0xbc, 0x81, 0x8b, 0xca, 0xb8, 0x02, 0x8c, 0xd0, 0x76, 0x66, 0x4d, 0xe8, 0x82,
+	0xf7, 0x63, 0xe3, 0x62, 0xd5, 0x30, 0x42, 0xdb, 0x9a, 0x8b, 0x9d, 0xf7, 0x28, 0x29, 0xaa, 0xdc,
+	0xb8, 0x11, 0x1b, 0x59, 0xdb, 0x53, 0x34, 0x61, 0x1f, 0x70, 0xb0, 0x9d, 0x83, 0x15, 0x89, 0x8f,
+	0x16, 0x4b, 0x3f, 0x84, 0x5e, 0x86, 0xad, 0xdf, 0x16, 0x4a, 0xa3, 0x09, 0x6e, 0x79, 0xd4, 0x59,
+	0x71, 0x95, 0x0b, 0x3b, 0x68, 0x3e, 0xb7, 0x20, 0xfc, 0x19, 0xbc, 0x57, 0x32, 0x5f, 0x20, 0x8b,
+	0x2f, 0xc4, 0x23, 0xf7, 0x5c, 0x58, 0x80, 0x56, 0x2c, 0xfb, 0x23, 0x57, 0x38, 0x0b, 0x6a, 0xdf,
+	0x13, 0x27, 0x18, 0x0b, 0x6a, 0xdf, 0x13, 0x57, 0x2c, 0x0b, 0xb0, 0xfa, 0x32, 0x4f, 0xa5, 0x12,
+	0x89, 0x71, 0x53, 0xd2, 0xe0, 0xf0, 0x97, 0x0e, 0x78, 0x3f, 0x14, 0xda, 0xb0, 0x1b, 0xd0, 0x6d,
+	0x1e, 0xaa, 0xae, 0x4c, 0x09, 0x97, 0x4e, 0xa7, 0x5d, 0x59, 0xe2, 0xa8, 0x52, 0xf7, 0x6d, 0x3c,
+	0xfa, 0xae, 0x47, 0xd5, 0x6b, 0x47, 0x75, 0xeb, 0x39, 0xe9, 0xbd, 0xff, 0x9c, 0xd8, 0x01, 0x5c,
+	0x0a, 0xea, 0xc4, 0x90, 0x5b, 0x10, 0x3e, 0x06, 0xff, 0xf9, 0xa5, 0xc8, 0x49, 0x51, 0x3b, 0x5f,
+	0xcd, 0x3a, 0xbc, 0x2d, 0x02, 0x7d, 0x87, 0xbf, 0x75, 0xa1, 0x47, 0xab, 0x90, 0xa5, 0x09, 0x71,
+	0x2b, 0xf0, 0x9b, 0x4d, 0xc1, 0xc3, 0xa6, 0xd3, 0x8a, 0xd1, 0xc9, 0x64, 0x6a, 0x9f, 0xed, 0x69,
+	0xfd, 0x6c, 0x4f, 0xcf, 0xea, 0x67, 0x9b, 0x93, 0x5f, 0x13, 0x75, 0x6f, 0x47, 0x54, 0xaf, 0x8d,
+	0xca, 0x3e, 0x06, 0x0f, 0xef, 0x98, 0xa0, 0x77, 0xb8, 0xb7, 0xde, 0xeb, 0x26, 0x7d, 0x4e, 0x74,
+	0x5d, 0x9b, 0x7e, 0x5b, 0x1b, 0x5b, 0xd1, 0x41, 0x53, 0xd1, 0x56, 0xf8, 0xc3, 0x0d, 0xe1, 0xdf,
+	0x81, 0xbe, 0x12, 0xb1, 0x2e, 0x72, 0x92, 0x93, 0xcf, 0x1d, 0xc2, 0xb7, 0x32, 0x4e, 0x53, 0x25,
+	0xb4, 0x76, 0xf2, 0xa9, 0x21, 0x36, 0x78, 0x1e, 0xcb, 0x65, 0xa5, 0x84, 0xa6, 0x3b, 0xba, 0xc7,
+	0x1b, 0x1c, 0xde, 0x06, 0xf6, 0x4a, 0x6a, 0x63, 0x7f, 0x48, 0x34, 0x17, 0xef, 0x2a, 0xa1, 0x4d,
+	0xf8, 0x2d, 0xfc, 0x67, 0xc3, 0xaa, 0xcb, 0x22, 0xd7, 0x82, 0x1d, 0xc1, 0x20, 0xb5, 0xa6, 0xa0,
+	0x43, 0xc7, 0xbb, 0x51, 0x1f, 0xcf, 0x7a, 0xf2, 0x9a, 0x0e, 0x3f, 0x81, 0x9b, 0xdf, 0x0b, 0xb7,
+	0xde, 0x6d, 0xba, 0xab, 0x6f, 0xe8, 0x87, 0x81, 0xe8, 0xb5, 0xff, 0x90, 0xdf, 0x97, 0x70, 0x6b,
+	0xcd, 0xcf, 0xa5, 0x13, 0x5a, 0x39, 0xd7, 0xc9, 0xec, 0xaf, 0x5f, 0xc0, 0x56, 0xdc, 0x3a, 0xfc,
+	0xc6, 0x06, 0xc0, 0x51, 0xfb, 0x50, 0x80, 0x76, 0xf4, 0xba, 0xeb, 0xa3, 0xf7, 0x0e, 0x6e, 0xad,
+	0xad, 0x6e, 0xc3, 0xce, 0xd1, 0xb0, 0x1d, 0x16, 0xbd, 0xb8, 0xa5, 0xd8, 0x57, 0xe0, 0xe3, 0x43,
+	0x27, 0x12, 0x23, 0xd2, 0x7f, 0x21, 0xb1, 0xd6, 0x39, 0x64, 0x36, 0x61, 0x9c, 0xf8, 0xa6, 0x1d,
+	0xee, 0xf4, 0xce, 0xd6, 0xa6, 0x81, 0x4a, 0x7a, 0x2f, 0x0d, 0xf4, 0xe2, 0x96, 0xaa, 0x37, 0xc3,
+	0x09, 0xde, 0xde, 0xcc, 0xd9, 0xda, 0xcd, 0x2e, 0xd0, 0xb0, 0xbd, 0x19, 0x7a, 0x71, 0x4b, 0x85,
+	0x47, 0x70, 0xf3, 0xb4, 0x9a, 0xe1, 0xa8, 0xce, 0x9a, 0x9e, 0xde, 0x86, 0x1e, 0x4e, 0x93, 0x5d,
+	0xe7, 0x73, 0x0b, 0x4e, 0xfe, 0xd8, 0x83, 0xfe, 0x53, 0xda, 0x80, 0xbd, 0x80, 0xd1, 0x9a, 0x92,
+	0xd8, 0xa4, 0xcd, 0x72, 0x5b, 0x74, 0x93, 0x7b, 0x3b, 0x39, 0x97, 0xe0, 0xe7, 0xe0, 0x37, 0x82,
+	0x62, 0x41, 0xed, 0xb9, 0xad, 0xb1, 0xc9, 0x96, 0x20, 0xd9, 0x77, 0xe0, 0x37, 0xba, 0x69, 0x97,
+	0x6d, 0x4b, 0x6e, 0x72, 0xb0, 0x83, 0x71, 0x81, 0xdd, 0x0e, 0x24, 0x81, 0xcd, 0x1d, 0xd6, 0x35,
+	0x35, 0x39, 0xd8, 0xc1, 0x6c, 0xee, 0x40, 0xdd, 0xdb, 0xdc, 0x61, 0xbd, 0xc9, 0x93, 0x83, 0x1d,
+	0xcc, 0xe6, 0x0e, 0xd4, 0xb2, 0xcd, 0x1d, 0xd6, 0x3b, 0x3b, 0x39, 0xd8, 0xc1, 0xb8, 0x1d, 0xbe,
+	0x00, 0xbf, 0xe9, 0x5d, 0xbb, 0xc3, 0x76, 0x3b, 0x27, 0xe3, 0x8d, 0xeb, 0xea, 0xb3, 0xce, 0x93,
+	0x4f, 0x7f, 0x3a, 0x3a, 0x97, 0xe6, 0xa2, 0x9a, 0x4d, 0x93, 0x22, 0x3b, 0xd6, 0x55, 0x29, 0xd4,
+	0x62, 0x61, 0x8e, 0xad, 0xd7, 0x71, 0x2e, 0xcc, 0x55, 0xa1, 0x16, 0xc7, 0xe5, 0xec, 0xeb, 0x72,
+	0x36, 0xeb, 0x93, 0xb0, 0x1f, 0xff, 0x33, 0x00, 0x4d, 0x5c, 0xb5, 0x5f, 0x18, 0x0d, 0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// CherryClient is the client API for Cherry service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type CherryClient interface {
+	ListDevices(ctx context.Context, in *ListDevicesRequest, opts ...grpc.CallOption) (*ListDevicesResponse, error)
+	// NOT_FOUND if the device is unknown or disconnected.
+	GetDevice(ctx context.Context, in *GetDeviceRequest, opts ...grpc.CallOption) (*Device, error)
+	ListPorts(ctx context.Context, in *ListPortsRequest, opts ...grpc.CallOption) (*ListPortsResponse, error)
+	// Flows last collected from the device by the flow statistics polling.
+	ListFlows(ctx context.Context, in *ListFlowsRequest, opts ...grpc.CallOption) (*ListFlowsResponse, error)
+	ListLinks(ctx context.Context, in *ListLinksRequest, opts ...grpc.CallOption) (*ListLinksResponse, error)
+	ListHosts(ctx context.Context, in *ListHostsRequest, opts ...grpc.CallOption) (*ListHostsResponse, error)
+	// Subscribe streams the network events published after the call until the client cancels
+	// it. A client that cannot keep up with the events is aborted with RESOURCE_EXHAUSTED.
+	Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (Cherry_SubscribeClient, error)
+}
+
+type cherryClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewCherryClient(cc *grpc.ClientConn) CherryClient {
+	return &cherryClient{cc}
+}
+
+func (c *cherryClient) ListDevices(ctx context.Context, in *ListDevicesRequest, opts ...grpc.CallOption) (*ListDevicesResponse, error) {
+	out := new(ListDevicesResponse)
+	err := c.cc.Invoke(ctx, "/cherry.Cherry/ListDevices", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cherryClient) GetDevice(ctx context.Context, in *GetDeviceRequest, opts ...grpc.CallOption) (*Device, error) {
+	out := new(Device)
+	err := c.cc.Invoke(ctx, "/cherry.Cherry/GetDevice", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cherryClient) ListPorts(ctx context.Context, in *ListPortsRequest, opts ...grpc.CallOption) (*ListPortsResponse, error) {
+	out := new(ListPortsResponse)
+	err := c.cc.Invoke(ctx, "/cherry.Cherry/ListPorts", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cherryClient) ListFlows(ctx context.Context, in *ListFlowsRequest, opts ...grpc.CallOption) (*ListFlowsResponse, error) {
+	out := new(ListFlowsResponse)
+	err := c.cc.Invoke(ctx, "/cherry.Cherry/ListFlows", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cherryClient) ListLinks(ctx context.Context, in *ListLinksRequest, opts ...grpc.CallOption) (*ListLinksResponse, error) {
+	out := new(ListLinksResponse)
+	err := c.cc.Invoke(ctx, "/cherry.Cherry/ListLinks", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cherryClient) ListHosts(ctx context.Context, in *ListHostsRequest, opts ...grpc.CallOption) (*ListHostsResponse, error) {
+	out := new(ListHostsResponse)
+	err := c.cc.Invoke(ctx, "/cherry.Cherry/ListHosts", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cherryClient) Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (Cherry_SubscribeClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Cherry_serviceDesc.Streams[0], "/cherry.Cherry/Subscribe", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &cherrySubscribeClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Cherry_SubscribeClient interface {
+	Recv() (*Event, error)
+	grpc.ClientStream
+}
+
+type cherrySubscribeClient struct {
+	grpc.ClientStream
+}
+
+func (x *cherrySubscribeClient) Recv() (*Event, error) {
+	m := new(Event)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// CherryServer is the server API for Cherry service.
+type CherryServer interface {
+	ListDevices(context.Context, *ListDevicesRequest) (*ListDevicesResponse, error)
+	// NOT_FOUND if the device is unknown or disconnected.
+	GetDevice(context.Context, *GetDeviceRequest) (*Device, error)
+	ListPorts(context.Context, *ListPortsRequest) (*ListPortsResponse, error)
+	// Flows last collected from the device by the flow statistics polling.
+	ListFlows(context.Context, *ListFlowsRequest) (*ListFlowsResponse, error)
+	ListLinks(context.Context, *ListLinksRequest) (*ListLinksResponse, error)
+	ListHosts(context.Context, *ListHostsRequest) (*ListHostsResponse, error)
+	// Subscribe streams the network events published after the call until the client cancels
+	// it. A client that cannot keep up with the events is aborted with RESOURCE_EXHAUSTED.
+	Subscribe(*SubscribeRequest, Cherry_SubscribeServer) error
+}
+
+// UnimplementedCherryServer can be embedded to have forward compatible implementations.
+type UnimplementedCherryServer struct {
+}
+
+func (*UnimplementedCherryServer) ListDevices(ctx context.Context, req *ListDevicesRequest) (*ListDevicesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListDevices not implemented")
+}
+func (*UnimplementedCherryServer) GetDevice(ctx context.Context, req *GetDeviceRequest) (*Device, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDevice not implemented")
+}
+func (*UnimplementedCherryServer) ListPorts(ctx context.Context, req *ListPortsRequest) (*ListPortsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPorts not implemented")
+}
+func (*UnimplementedCherryServer) ListFlows(ctx context.Context, req *ListFlowsRequest) (*ListFlowsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListFlows not implemented")
+}
+func (*UnimplementedCherryServer) ListLinks(ctx context.Context, req *ListLinksRequest) (*ListLinksResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListLinks not implemented")
+}
+func (*UnimplementedCherryServer) ListHosts(ctx context.Context, req *ListHostsRequest) (*ListHostsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListHosts not implemented")
+}
+func (*UnimplementedCherryServer) Subscribe(req *SubscribeRequest, srv Cherry_SubscribeServer) error {
+	return status.Errorf(codes.Unimplemented, "method Subscribe not implemented")
+}
+
+func RegisterCherryServer(s *grpc.Server, srv CherryServer) {
+	s.RegisterService(&_Cherry_serviceDesc, srv)
+}
+
+func _Cherry_ListDevices_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListDevicesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CherryServer).ListDevices(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cherry.Cherry/ListDevices",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CherryServer).ListDevices(ctx, req.(*ListDevicesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cherry_GetDevice_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDeviceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CherryServer).GetDevice(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cherry.Cherry/GetDevice",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CherryServer).GetDevice(ctx, req.(*GetDeviceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cherry_ListPorts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPortsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CherryServer).ListPorts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cherry.Cherry/ListPorts",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CherryServer).ListPorts(ctx, req.(*ListPortsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cherry_ListFlows_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListFlowsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CherryServer).ListFlows(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cherry.Cherry/ListFlows",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CherryServer).ListFlows(ctx, req.(*ListFlowsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cherry_ListLinks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListLinksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CherryServer).ListLinks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cherry.Cherry/ListLinks",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CherryServer).ListLinks(ctx, req.(*ListLinksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cherry_ListHosts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListHostsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CherryServer).ListHosts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cherry.Cherry/ListHosts",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CherryServer).ListHosts(ctx, req.(*ListHostsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cherry_Subscribe_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CherryServer).Subscribe(m, &cherrySubscribeServer{stream})
+}
+
+type Cherry_SubscribeServer interface {
+	Send(*Event) error
+	grpc.ServerStream
+}
+
+type cherrySubscribeServer struct {
+	grpc.ServerStream
+}
+
+func (x *cherrySubscribeServer) Send(m *Event) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+var _Cherry_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "cherry.Cherry",
+	HandlerType: (*CherryServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListDevices",
+			Handler:    _Cherry_ListDevices_Handler,
+		},
+		{
+			MethodName: "GetDevice",
+			Handler:    _Cherry_GetDevice_Handler,
+		},
+		{
+			MethodName: "ListPorts",
+			Handler:    _Cherry_ListPorts_Handler,
+		},
+		{
+			MethodName: "ListFlows",
+			Handler:    _Cherry_ListFlows_Handler,
+		},
+		{
+			MethodName: "ListLinks",
+			Handler:    _Cherry_ListLinks_Handler,
+		},
+		{
+			MethodName: "ListHosts",
+			Handler:    _Cherry_ListHosts_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Subscribe",
+			Handler:       _Cherry_Subscribe_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "cherry.proto",
+}
@@ -24,6 +24,7 @@ package network
 import (
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/superkkt/cherry/graph"
 	"github.com/superkkt/cherry/openflow"
@@ -35,6 +36,9 @@ type Port struct {
 	device *Device
 	number uint32
 	value  openflow.Port
+	stats  openflow.PortStats
+	// Time when the statistics were last refreshed.
+	statsTime time.Time
 }
 
 func NewPort(d *Device, num uint32) *Port {
@@ -83,3 +87,22 @@ func (r *Port) SetValue(p openflow.Port) {
 
 	r.value = p
 }
+
+// Stats returns the statistics most recently polled from the device and the
+// time when they were polled. The time is zero if they have never been polled.
+func (r *Port) Stats() (stats openflow.PortStats, updated time.Time) {
+	// Read lock
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return r.stats, r.statsTime
+}
+
+func (r *Port) setStats(stats openflow.PortStats, updated time.Time) {
+	// Write lock
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.stats = stats
+	r.statsTime = updated
+}
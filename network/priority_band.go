@@ -0,0 +1,233 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/superkkt/cherry/openflow"
+
+	"github.com/pkg/errors"
+	"github.com/superkkt/viper"
+)
+
+// PriorityBand is a range of the flow priorities reserved for an application, so that the
+// application cannot shadow the flows of another one, and vice versa, by mistake.
+type PriorityBand struct {
+	// Name of the application.
+	Owner string
+	// IDs of the tables where the band is reserved. Empty means all the tables.
+	Tables map[uint8]bool
+	// Lowest and highest priorities of the band.
+	Min, Max uint16
+}
+
+var (
+	ErrPriorityOutOfBand = errors.New("flow priority is out of the band of its application")
+)
+
+// ParsePriorityBand returns the band of the application whose name is owner. tables is a comma
+// separated list of table IDs, and priorities is the lowest and highest priorities separated by
+// a hyphen, e.g., "1000-9999".
+func ParsePriorityBand(owner, tables, priorities string) (*PriorityBand, error) {
+	v := &PriorityBand{
+		Owner:  owner,
+		Tables: make(map[uint8]bool),
+	}
+
+	for _, s := range splitList(tables) {
+		id, err := strconv.ParseUint(s, 10, 8)
+		// 0xFF means all the tables in a FLOW_MOD.
+		if err != nil || id == 0xFF {
+			return nil, fmt.Errorf("invalid table ID: %v", s)
+		}
+		v.Tables[uint8(id)] = true
+	}
+	t := strings.Split(priorities, "-")
+	if len(t) != 2 {
+		return nil, fmt.Errorf("invalid priority band: %v", priorities)
+	}
+	min, err1 := strconv.ParseUint(strings.TrimSpace(t[0]), 10, 16)
+	max, err2 := strconv.ParseUint(strings.TrimSpace(t[1]), 10, 16)
+	if err1 != nil || err2 != nil || min > max {
+		return nil, fmt.Errorf("invalid priority band: %v", priorities)
+	}
+	v.Min, v.Max = uint16(min), uint16(max)
+
+	return v, nil
+}
+
+// LoadPriorityBands returns the bands defined in the priority_band section of the config file.
+// The bands of different applications cannot overlap in the same table.
+func LoadPriorityBands() ([]*PriorityBand, error) {
+	owners := []string{}
+	for name := range viper.GetStringMap("priority_band") {
+		owners = append(owners, name)
+	}
+	// Keep the order regardless of the map iteration.
+	sort.Strings(owners)
+
+	result := []*PriorityBand{}
+	for _, name := range owners {
+		key := "priority_band." + name
+		b, err := ParsePriorityBand(name, viper.GetString(key+".tables"), viper.GetString(key+".priorities"))
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range result {
+			if v.overlaps(b) {
+				return nil, fmt.Errorf("priority band of %v overlaps with the one of %v", name, v.Owner)
+			}
+		}
+		result = append(result, b)
+	}
+
+	return result, nil
+}
+
+func (r *PriorityBand) String() string {
+	tables := []string{}
+	for id := range r.Tables {
+		tables = append(tables, strconv.Itoa(int(id)))
+	}
+	sort.Strings(tables)
+
+	return fmt.Sprintf("PriorityBand %v: Tables=%v, Priorities=%v-%v", r.Owner, tables, r.Min, r.Max)
+}
+
+// HasTable returns whether the band is reserved in the table whose ID is id.
+func (r *PriorityBand) HasTable(id uint8) bool {
+	if len(r.Tables) == 0 {
+		return true
+	}
+
+	return r.Tables[id]
+}
+
+// Contains returns whether priority is in the band.
+func (r *PriorityBand) Contains(priority uint16) bool {
+	return priority >= r.Min && priority <= r.Max
+}
+
+// overlaps returns whether r and other share any priority in a table.
+func (r *PriorityBand) overlaps(other *PriorityBand) bool {
+	if r.Max < other.Min || other.Max < r.Min {
+		return false
+	}
+	if len(r.Tables) == 0 || len(other.Tables) == 0 {
+		return true
+	}
+	for id := range r.Tables {
+		if other.Tables[id] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// priorityAllocator checks the priorities of the flows owned by the applications against
+// the bands reserved for them. It is not changed after it is made, so it can be shared by
+// the devices without a lock. A nil allocator reserves no band.
+type priorityAllocator struct {
+	// Key is the cookie owner ID of the application.
+	bands map[uint16]*PriorityBand
+}
+
+func newPriorityAllocator(bands []*PriorityBand) *priorityAllocator {
+	v := &priorityAllocator{
+		bands: make(map[uint16]*PriorityBand),
+	}
+	for _, b := range bands {
+		v.bands[CookieOwnerID(b.Owner)] = b
+	}
+
+	return v
+}
+
+// Band returns the band reserved for the application whose name is owner in the table whose
+// ID is tableID. It returns false if there is no such band.
+func (r *priorityAllocator) Band(owner string, tableID uint8) (min, max uint16, ok bool) {
+	if r == nil {
+		return 0, 0, false
+	}
+	b, ok := r.bands[CookieOwnerID(owner)]
+	if !ok || !b.HasTable(tableID) {
+		return 0, 0, false
+	}
+
+	return b.Min, b.Max, true
+}
+
+// Priority returns the priority of a flow of the application whose name is owner in the table
+// whose ID is tableID. offset is the rank of the flow among the flows of the application, where 0
+// is the lowest, counted from the lowest priority of the band of the application, or from base if
+// no band is reserved for the application. It returns ErrPriorityOutOfBand if the priority is
+// beyond the band.
+func (r *priorityAllocator) Priority(owner string, tableID uint8, base, offset uint16) (uint16, error) {
+	max := uint16(0xFFFF)
+	if min, v, ok := r.Band(owner, tableID); ok {
+		base, max = min, v
+	}
+	if offset > max-base {
+		return 0, errors.Wrapf(ErrPriorityOutOfBand, "%v flows of %v from priority %v exceed %v in table %v", uint32(offset)+1, owner, base, max, tableID)
+	}
+
+	return base + offset, nil
+}
+
+// Check returns ErrPriorityOutOfBand if flow, which adds or modifies flows owned by an
+// application, has a priority outside the band of the application, or inside the band of
+// another application. The flows of the applications that have no band in the table may
+// have any priority that is not reserved.
+func (r *priorityAllocator) Check(flow openflow.FlowMod) error {
+	if r == nil || len(r.bands) == 0 {
+		return nil
+	}
+	switch flow.Command() {
+	case openflow.FlowAdd, openflow.FlowModify, openflow.FlowModifyStrict:
+	default:
+		return nil
+	}
+	owner, ok := CookieOwner(flow.Cookie())
+	if !ok {
+		return nil
+	}
+
+	tableID, priority := flow.TableID(), flow.Priority()
+	if b, ok := r.bands[owner]; ok && b.HasTable(tableID) {
+		if !b.Contains(priority) {
+			return errors.Wrapf(ErrPriorityOutOfBand, "priority %v is not in %v-%v of %v in table %v", priority, b.Min, b.Max, b.Owner, tableID)
+		}
+		return nil
+	}
+	for id, b := range r.bands {
+		if id != owner && b.HasTable(tableID) && b.Contains(priority) {
+			return errors.Wrapf(ErrPriorityOutOfBand, "priority %v is reserved for %v in table %v", priority, b.Owner, tableID)
+		}
+	}
+
+	return nil
+}
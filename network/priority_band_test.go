@@ -0,0 +1,195 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"testing"
+
+	"github.com/superkkt/cherry/openflow"
+	"github.com/superkkt/cherry/openflow/of13"
+
+	"github.com/pkg/errors"
+	"github.com/superkkt/viper"
+)
+
+func TestParsePriorityBand(t *testing.T) {
+	b, err := ParsePriorityBand("Firewall", "0, 2", "1000 - 9999")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !b.HasTable(2) || b.HasTable(1) {
+		t.Fatalf("unexpected tables: %v", b)
+	}
+	if b.Min != 1000 || b.Max != 9999 || !b.Contains(1000) || b.Contains(10000) {
+		t.Fatalf("unexpected priorities: %v", b)
+	}
+
+	for _, v := range [][2]string{{"", ""}, {"", "100"}, {"", "200-100"}, {"", "0-65536"}, {"255", "1-2"}, {"x", "1-2"}} {
+		if _, err := ParsePriorityBand("invalid", v[0], v[1]); err == nil {
+			t.Fatalf("expected an error for tables=%q, priorities=%q", v[0], v[1])
+		}
+	}
+}
+
+func TestPriorityBandOverlaps(t *testing.T) {
+	band := func(tables, priorities string) *PriorityBand {
+		v, err := ParsePriorityBand("test", tables, priorities)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	if !band("", "10-20").overlaps(band("1", "20-30")) {
+		t.Fatal("expected an overlap with the band of all the tables")
+	}
+	if band("0", "10-20").overlaps(band("1", "10-20")) {
+		t.Fatal("unexpected overlap of different tables")
+	}
+	if band("0", "10-20").overlaps(band("0, 1", "21-30")) {
+		t.Fatal("unexpected overlap of different priorities")
+	}
+}
+
+func TestPriorityAllocator(t *testing.T) {
+	firewall, err := ParsePriorityBand("Firewall", "0", "1000-9999")
+	if err != nil {
+		t.Fatal(err)
+	}
+	blacklist, err := ParsePriorityBand("Blacklist", "", "20000-20000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	allocator := newPriorityAllocator([]*PriorityBand{firewall, blacklist})
+
+	if min, max, ok := allocator.Band("FIREWALL", 0); !ok || min != 1000 || max != 9999 {
+		t.Fatalf("unexpected band of Firewall: min=%v, max=%v, ok=%v", min, max, ok)
+	}
+	if _, _, ok := allocator.Band("Firewall", 1); ok {
+		t.Fatal("unexpected band of Firewall in table 1")
+	}
+
+	f := of13.NewFactory()
+	newFlow := func(cmd openflow.FlowModCmd, owner string, tableID uint8, priority uint16) openflow.FlowMod {
+		flow := newTestFlow(t, f, cmd, nil, 0)
+		if len(owner) > 0 {
+			flow.SetCookie(NewCookie(owner, 1))
+		}
+		flow.SetTableID(tableID)
+		flow.SetPriority(priority)
+		return flow
+	}
+	valid := []openflow.FlowMod{
+		newFlow(openflow.FlowAdd, "Firewall", 0, 1000),
+		// No band of Firewall in table 1.
+		newFlow(openflow.FlowAdd, "Firewall", 1, 30),
+		newFlow(openflow.FlowModify, "Blacklist", 1, 20000),
+		// Router has no band.
+		newFlow(openflow.FlowAdd, "Router", 0, 20),
+		// Flows that are not owned by any application.
+		newFlow(openflow.FlowAdd, "", 0, 20000),
+		// Deleting flows is not checked.
+		newFlow(openflow.FlowDelete, "Router", 0, 1000),
+	}
+	for i, v := range valid {
+		if err := allocator.Check(v); err != nil {
+			t.Fatalf("unexpected error for the flow %v: %v", i, err)
+		}
+	}
+	invalid := []openflow.FlowMod{
+		newFlow(openflow.FlowAdd, "Firewall", 0, 10000),
+		newFlow(openflow.FlowAdd, "Firewall", 1, 20000),
+		newFlow(openflow.FlowModifyStrict, "Router", 0, 5000),
+		newFlow(openflow.FlowAdd, "Blacklist", 2, 19999),
+	}
+	for i, v := range invalid {
+		if err := allocator.Check(v); errors.Cause(err) != ErrPriorityOutOfBand {
+			t.Fatalf("expected ErrPriorityOutOfBand for the flow %v, got=%v", i, err)
+		}
+	}
+
+	// Nil allocator reserves no band.
+	var none *priorityAllocator
+	if err := none.Check(invalid[0]); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPriorityAllocatorPriority(t *testing.T) {
+	qos, err := ParsePriorityBand("QoS", "0", "100-10099")
+	if err != nil {
+		t.Fatal(err)
+	}
+	allocator := newPriorityAllocator([]*PriorityBand{qos})
+
+	if v, err := allocator.Priority("QoS", 0, 1, 9999); err != nil || v != 10099 {
+		t.Fatalf("unexpected priority in the band: priority=%v, err=%v", v, err)
+	}
+	if _, err := allocator.Priority("QoS", 0, 1, 10000); errors.Cause(err) != ErrPriorityOutOfBand {
+		t.Fatalf("expected ErrPriorityOutOfBand beyond the band, got=%v", err)
+	}
+	// No band of QoS in table 1, and no band of Router.
+	if v, err := allocator.Priority("QoS", 1, 1, 5); err != nil || v != 6 {
+		t.Fatalf("unexpected priority without the band: priority=%v, err=%v", v, err)
+	}
+	if v, err := allocator.Priority("Router", 0, 20, 0); err != nil || v != 20 {
+		t.Fatalf("unexpected priority without the band: priority=%v, err=%v", v, err)
+	}
+	if _, err := allocator.Priority("Router", 0, 0xFFFF, 1); errors.Cause(err) != ErrPriorityOutOfBand {
+		t.Fatalf("expected ErrPriorityOutOfBand for an overflow, got=%v", err)
+	}
+
+	// Nil allocator reserves no band.
+	var none *priorityAllocator
+	if v, err := none.Priority("QoS", 0, 1, 5); err != nil || v != 6 {
+		t.Fatalf("unexpected priority of the nil allocator: priority=%v, err=%v", v, err)
+	}
+}
+
+// The default bands of the config file should load and give the applications the priorities
+// that they use without a band.
+func TestDefaultPriorityBands(t *testing.T) {
+	viper.SetConfigFile("../cherry.yaml")
+	if err := viper.ReadInConfig(); err != nil {
+		t.Fatal(err)
+	}
+	defer viper.Reset()
+
+	bands, err := LoadPriorityBands()
+	if err != nil {
+		t.Fatal(err)
+	}
+	allocator := newPriorityAllocator(bands)
+	expected := map[string]uint16{
+		"L2Switch":  normalFlowPriority,
+		"Router":    20,
+		"Intent":    30,
+		"QoS":       100,
+		"Firewall":  10100,
+		"Blacklist": 20000,
+	}
+	for owner, priority := range expected {
+		if min, _, ok := allocator.Band(owner, 1); !ok || min != priority {
+			t.Fatalf("unexpected band of %v: min=%v, ok=%v", owner, min, ok)
+		}
+	}
+}
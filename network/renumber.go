@@ -0,0 +1,106 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/superkkt/cherry/openflow"
+)
+
+// portRecord is a snapshot of a switch port that is used to detect port
+// renumbering across reconnections of a same device.
+type portRecord struct {
+	number uint32
+	name   string
+	mac    net.HardwareAddr
+}
+
+func newPortRecord(p openflow.Port) portRecord {
+	return portRecord{
+		number: p.Number(),
+		name:   p.Name(),
+		mac:    p.MAC(),
+	}
+}
+
+func (r portRecord) key() string {
+	return fmt.Sprintf("%v/%v", r.name, r.mac)
+}
+
+func isZeroMAC(mac net.HardwareAddr) bool {
+	return len(mac) == 0 || bytes.Equal(mac, []byte{0, 0, 0, 0, 0, 0})
+}
+
+// identifiable returns the records indexed by their name and MAC address. Records
+// that cannot be identified unambiguously, i.e., records that have an empty name,
+// a missing MAC address, or a duplicated name, are excluded.
+func identifiable(records []portRecord) map[string]portRecord {
+	names := make(map[string]int)
+	for _, v := range records {
+		names[v.name]++
+	}
+
+	result := make(map[string]portRecord)
+	for _, v := range records {
+		if len(strings.TrimSpace(v.name)) == 0 || isZeroMAC(v.mac) || names[v.name] > 1 {
+			continue
+		}
+		result[v.key()] = v
+	}
+
+	return result
+}
+
+// renumberPorts compares the previous port table of a device with the new one by
+// port name and MAC address. mapping contains the old-to-new port numbers of the
+// ports whose number has been changed, and deleted contains the old port numbers
+// that cannot be found (or cannot be identified unambiguously) in the new table.
+func renumberPorts(prev, cur []portRecord) (mapping map[uint32]uint32, deleted []uint32) {
+	mapping = make(map[uint32]uint32)
+	deleted = make([]uint32, 0)
+
+	old := identifiable(prev)
+	next := identifiable(cur)
+	for _, p := range prev {
+		if _, ok := old[p.key()]; !ok {
+			// Ambiguous port. Treat it as a deleted one.
+			deleted = append(deleted, p.number)
+			continue
+		}
+		n, ok := next[p.key()]
+		if !ok {
+			deleted = append(deleted, p.number)
+			continue
+		}
+		if n.number != p.number {
+			mapping[p.number] = n.number
+		}
+	}
+	sort.Slice(deleted, func(i, j int) bool { return deleted[i] < deleted[j] })
+
+	return mapping, deleted
+}
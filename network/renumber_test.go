@@ -0,0 +1,84 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+func mac(last byte) net.HardwareAddr {
+	return net.HardwareAddr([]byte{0x00, 0x11, 0x22, 0x33, 0x44, last})
+}
+
+func TestRenumberShuffledPorts(t *testing.T) {
+	prev := []portRecord{
+		{number: 1, name: "eth1", mac: mac(1)},
+		{number: 2, name: "eth2", mac: mac(2)},
+		{number: 3, name: "eth3", mac: mac(3)},
+		{number: 47, name: "eth4", mac: mac(4)},
+	}
+	// Simulate a reboot that shuffles the port numbers.
+	cur := []portRecord{
+		{number: 1, name: "eth1", mac: mac(1)},
+		{number: 3, name: "eth2", mac: mac(2)},
+		{number: 2, name: "eth3", mac: mac(3)},
+		{number: 12, name: "eth4", mac: mac(4)},
+	}
+
+	mapping, deleted := renumberPorts(prev, cur)
+	expected := map[uint32]uint32{2: 3, 3: 2, 47: 12}
+	if !reflect.DeepEqual(mapping, expected) {
+		t.Fatalf("unexpected mapping: expected=%v, got=%v", expected, mapping)
+	}
+	if len(deleted) != 0 {
+		t.Fatalf("unexpected deleted ports: %v", deleted)
+	}
+}
+
+func TestRenumberAmbiguousPorts(t *testing.T) {
+	prev := []portRecord{
+		{number: 1, name: "eth1", mac: mac(1)},
+		// Duplicated names.
+		{number: 2, name: "dup", mac: mac(2)},
+		{number: 3, name: "dup", mac: mac(3)},
+		// Missing MAC address.
+		{number: 4, name: "eth4", mac: net.HardwareAddr([]byte{0, 0, 0, 0, 0, 0})},
+		// Disappeared port.
+		{number: 5, name: "eth5", mac: mac(5)},
+	}
+	cur := []portRecord{
+		{number: 10, name: "eth1", mac: mac(1)},
+		{number: 20, name: "dup", mac: mac(2)},
+		{number: 30, name: "dup", mac: mac(3)},
+		{number: 40, name: "eth4", mac: net.HardwareAddr([]byte{0, 0, 0, 0, 0, 0})},
+	}
+
+	mapping, deleted := renumberPorts(prev, cur)
+	if !reflect.DeepEqual(mapping, map[uint32]uint32{1: 10}) {
+		t.Fatalf("unexpected mapping: %v", mapping)
+	}
+	if !reflect.DeepEqual(deleted, []uint32{2, 3, 4, 5}) {
+		t.Fatalf("unexpected deleted ports: %v", deleted)
+	}
+}
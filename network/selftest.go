@@ -0,0 +1,652 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/superkkt/cherry/clock"
+	"github.com/superkkt/cherry/openflow"
+	"github.com/superkkt/cherry/openflow/transceiver"
+	"github.com/superkkt/cherry/protocol"
+
+	"github.com/ant0ine/go-json-rest/rest"
+)
+
+const (
+	// selfTestOwner owns the flow installed by the self-test so that the flow has a cookie
+	// that no application uses.
+	selfTestOwner = "SelfTest"
+	// IEEE 802 local experimental EtherType, which is used by the probe packets and the flow
+	// of the self-test so that they never match production traffic.
+	selfTestEtherType = 0x88B5
+	// The self-test flow has the lowest priority except the table-miss flows.
+	selfTestPriority = 1
+	// The last usable group and meter IDs, which are not allocated by the applications.
+	selfTestGroupID = 0xFFFFFF00
+	selfTestMeterID = 0xFFFF0000
+	// Timeout of each step.
+	selfTestStepTimeout = 5 * time.Second
+)
+
+var (
+	// Locally administered MAC address of the probe packets.
+	selfTestMAC = net.HardwareAddr{0x02, 0x63, 0x68, 0x65, 0x72, 0x72}
+
+	ErrSelfTestRunning = errors.New("self-test is already running")
+)
+
+func init() {
+	if err := RegisterCookieOwner(selfTestOwner); err != nil {
+		panic(err)
+	}
+}
+
+// Results of the self-test steps.
+const (
+	SelfTestPass = "pass"
+	SelfTestFail = "fail"
+	SelfTestSkip = "skip"
+)
+
+type SelfTestStep struct {
+	Name   string `json:"name"`
+	Result string `json:"result"`
+	// Elapsed time of the step in microseconds.
+	Elapsed int64 `json:"elapsed_usec"`
+	// Reason of the failure, including the error message decoded from the device, or the
+	// reason why the step has been skipped.
+	Error string `json:"error,omitempty"`
+}
+
+type SelfTestResult struct {
+	Started time.Time `json:"started"`
+	// Passed is true if no step has failed.
+	Passed bool           `json:"passed"`
+	Steps  []SelfTestStep `json:"steps"`
+}
+
+// selfTest exercises a device by a scripted sequence of requests. Everything it creates on
+// the device is identified by the self-test cookie, group ID, and meter ID, and removed when
+// the test finishes, so that it never disturbs the production flows.
+type selfTest struct {
+	device  *Device
+	session *session
+	factory openflow.Factory
+	clock   clock.Clock
+	// Table for the self-test flow.
+	tableID  uint8
+	readOnly bool
+	result   SelfTestResult
+	// Things that have been created on the device and should be removed by cleanup.
+	flowAdded, groupAdded, meterAdded bool
+}
+
+// SelfTest runs the self-test on the device and returns its result. It returns ErrSelfTestRunning
+// if another self-test is running on the device.
+//
+// SelfTest should not be called while handling an event raised by this device for the same
+// reason as Barrier.
+func (r *Device) SelfTest(ctx context.Context) (SelfTestResult, error) {
+	t, err := r.newSelfTest()
+	if err != nil {
+		return SelfTestResult{}, err
+	}
+	defer r.finishSelfTest()
+
+	return t.run(ctx), nil
+}
+
+func (r *Device) newSelfTest() (*selfTest, error) {
+	// Write lock
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.closed {
+		return nil, ErrClosedDevice
+	}
+	if r.selfTestProbe != nil {
+		return nil, ErrSelfTestRunning
+	}
+	r.selfTestProbe = make(chan []byte, 1)
+
+	return &selfTest{
+		device:   r,
+		session:  r.session,
+		factory:  r.factory,
+		clock:    r.clock,
+		tableID:  r.selfTestTableID(),
+		readOnly: r.readOnly,
+	}, nil
+}
+
+func (r *Device) finishSelfTest() {
+	// Write lock
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.selfTestProbe = nil
+}
+
+// selfTestTableID returns the table with the highest ID that the pipeline does not use, or the
+// flow table if there is no such table.
+//
+// XXX: Caller should lock the mutex
+func (r *Device) selfTestTableID() uint8 {
+	if r.factory.ProtocolVersion() == openflow.OF10_VERSION {
+		// OpenFlow 1.0 has no table ID in FLOW_MOD.
+		return 0
+	}
+	for id := int(r.features.NumTables) - 1; id >= 0; id-- {
+		if uint8(id) == r.flowTableID || int16(id) == r.classifierTableID {
+			continue
+		}
+		return uint8(id)
+	}
+
+	return r.flowTableID
+}
+
+// deliverSelfTestProbe delivers the payload of a probe packet to the running self-test. The
+// probe is dropped if no self-test is waiting for it.
+func (r *Device) deliverSelfTestProbe(payload []byte) {
+	// Read lock
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if r.selfTestProbe == nil {
+		return
+	}
+	select {
+	case r.selfTestProbe <- payload:
+	default:
+	}
+}
+
+func (r *Device) selfTestProbeChan() <-chan []byte {
+	// Read lock
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return r.selfTestProbe
+}
+
+func isSelfTestProbe(e *protocol.Ethernet) bool {
+	return e.Type == selfTestEtherType && bytes.Equal(e.DstMAC, selfTestMAC)
+}
+
+func (r *selfTest) run(ctx context.Context) SelfTestResult {
+	r.result = SelfTestResult{Started: r.clock.Now(), Passed: true, Steps: []SelfTestStep{}}
+
+	r.step(ctx, "barrier", r.barrier)
+	r.step(ctx, "echo", r.echo)
+	if r.readOnly {
+		r.skip("read-only device", "flow_install", "flow_verify", "flow_delete")
+	} else if r.step(ctx, "flow_install", r.installFlow) {
+		r.step(ctx, "flow_verify", r.verifyFlow)
+		r.step(ctx, "flow_delete", r.deleteFlow)
+	} else {
+		r.skip("flow_install failed", "flow_verify", "flow_delete")
+	}
+	r.step(ctx, "port_stats", r.portStats)
+	r.step(ctx, "packet_out", r.packetOut)
+	switch {
+	case !r.device.SupportsGroups():
+		r.skip("groups are not supported", "group")
+	case r.readOnly:
+		r.skip("read-only device", "group")
+	default:
+		r.step(ctx, "group", r.group)
+	}
+	switch {
+	case r.factory.ProtocolVersion() < openflow.OF13_VERSION:
+		r.skip("meters are not supported", "meter")
+	case r.readOnly:
+		r.skip("read-only device", "meter")
+	default:
+		r.step(ctx, "meter", r.meter)
+	}
+	// Remove everything that the failed steps have left on the device.
+	r.cleanup()
+
+	return r.result
+}
+
+// step runs f with the step timeout, records its result, and returns whether it has passed.
+func (r *selfTest) step(ctx context.Context, name string, f func(context.Context) error) bool {
+	ctx, cancel := context.WithTimeout(ctx, selfTestStepTimeout)
+	defer cancel()
+
+	started := r.clock.Now()
+	err := f(ctx)
+	v := SelfTestStep{Name: name, Result: SelfTestPass, Elapsed: int64(r.clock.Now().Sub(started) / time.Microsecond)}
+	if err != nil {
+		v.Result = SelfTestFail
+		v.Error = err.Error()
+		r.result.Passed = false
+	}
+	r.result.Steps = append(r.result.Steps, v)
+
+	return err == nil
+}
+
+func (r *selfTest) skip(reason string, names ...string) {
+	for _, name := range names {
+		r.result.Steps = append(r.result.Steps, SelfTestStep{Name: name, Result: SelfTestSkip, Error: reason})
+	}
+}
+
+func (r *selfTest) barrier(ctx context.Context) error {
+	barrier, err := r.factory.NewBarrierRequest()
+	if err != nil {
+		return err
+	}
+	_, err = r.session.SendAndWait(ctx, barrier)
+
+	return err
+}
+
+func (r *selfTest) echo(ctx context.Context) error {
+	echo, err := r.factory.NewEchoRequest()
+	if err != nil {
+		return err
+	}
+	payload := make([]byte, 32)
+	if _, err := rand.Read(payload); err != nil {
+		return err
+	}
+	echo.SetData(payload)
+
+	reply, err := r.session.SendAndWait(ctx, echo)
+	if err != nil {
+		return err
+	}
+	v, ok := reply.(openflow.EchoReply)
+	if !ok {
+		return fmt.Errorf("unexpected reply: %T", reply)
+	}
+	if !bytes.Equal(v.Data(), payload) {
+		return fmt.Errorf("mismatched payload: expected=%x, got=%x", payload, v.Data())
+	}
+
+	return nil
+}
+
+func (r *selfTest) cookie() uint64 {
+	return NewCookie(selfTestOwner, 0)
+}
+
+func (r *selfTest) flowMatch() (openflow.Match, error) {
+	match, err := r.factory.NewMatch()
+	if err != nil {
+		return nil, err
+	}
+	match.SetEtherType(selfTestEtherType)
+	match.SetDstMAC(selfTestMAC)
+	if err := match.Error(); err != nil {
+		return nil, err
+	}
+
+	return match, nil
+}
+
+// newFlowMod returns a FLOW_MOD of the self-test flow, which drops the matched packets.
+func (r *selfTest) newFlowMod(cmd openflow.FlowModCmd) (openflow.FlowMod, error) {
+	match, err := r.flowMatch()
+	if err != nil {
+		return nil, err
+	}
+	flow, err := r.factory.NewFlowMod(cmd)
+	if err != nil {
+		return nil, err
+	}
+	flow.SetTableID(r.tableID)
+	flow.SetPriority(selfTestPriority)
+	flow.SetCookie(r.cookie())
+	if cmd == openflow.FlowDeleteStrict {
+		flow.SetCookieMask(0xFFFFFFFFFFFFFFFF)
+	}
+	flow.SetFlowMatch(match)
+	if err := flow.Error(); err != nil {
+		return nil, err
+	}
+
+	return flow, nil
+}
+
+func (r *selfTest) installFlow(ctx context.Context) error {
+	flow, err := r.newFlowMod(openflow.FlowAdd)
+	if err != nil {
+		return err
+	}
+	err = r.session.SendAndConfirm(ctx, flow)
+	r.flowAdded = applied(err)
+
+	return err
+}
+
+func (r *selfTest) verifyFlow(ctx context.Context) error {
+	match, err := r.flowMatch()
+	if err != nil {
+		return err
+	}
+	req, err := r.factory.NewFlowStatsRequest()
+	if err != nil {
+		return err
+	}
+	if r.factory.ProtocolVersion() == openflow.OF10_VERSION {
+		// The device has chosen the table.
+		req.SetTableID(0xFF)
+	} else {
+		req.SetTableID(r.tableID)
+	}
+	req.SetCookie(r.cookie())
+	req.SetCookieMask(0xFFFFFFFFFFFFFFFF)
+	req.SetMatch(match)
+	if err := req.Error(); err != nil {
+		return err
+	}
+
+	reply, err := r.session.SendAndWait(ctx, req)
+	if err != nil {
+		return err
+	}
+	v, ok := reply.(openflow.FlowStatsReply)
+	if !ok {
+		return fmt.Errorf("unexpected reply: %T", reply)
+	}
+	for _, s := range v.FlowStats() {
+		// OpenFlow 1.0 devices do not filter the flows by the cookie.
+		if s.Cookie == r.cookie() && s.Priority == selfTestPriority {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("installed flow is not found in the flow statistics (table=%v)", r.tableID)
+}
+
+func (r *selfTest) deleteFlow(ctx context.Context) error {
+	flow, err := r.newFlowMod(openflow.FlowDeleteStrict)
+	if err != nil {
+		return err
+	}
+	if err := r.session.SendAndConfirm(ctx, flow); err != nil {
+		return err
+	}
+	r.flowAdded = false
+
+	return nil
+}
+
+func (r *selfTest) portStats(ctx context.Context) error {
+	req, err := r.factory.NewPortStatsRequest()
+	if err != nil {
+		return err
+	}
+	reply, err := r.session.SendAndWait(ctx, req)
+	if err != nil {
+		return err
+	}
+	if _, ok := reply.(openflow.PortStatsReply); !ok {
+		return fmt.Errorf("unexpected reply: %T", reply)
+	}
+
+	return nil
+}
+
+// packetOut sends a probe packet to the controller port and waits until it comes back by PACKET_IN.
+func (r *selfTest) packetOut(ctx context.Context) error {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	probe := protocol.Ethernet{
+		SrcMAC:  selfTestMAC,
+		DstMAC:  selfTestMAC,
+		Type:    selfTestEtherType,
+		Payload: nonce,
+	}
+	data, err := probe.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	inPort := openflow.NewInPort()
+	inPort.SetController()
+	outPort := openflow.NewOutPort()
+	outPort.SetController()
+	action, err := r.factory.NewAction()
+	if err != nil {
+		return err
+	}
+	action.SetOutPort(outPort)
+	if err := r.device.SendPacketOut(inPort, action, openflow.NoBuffer, data); err != nil {
+		return err
+	}
+
+	probes := r.device.selfTestProbeChan()
+	for {
+		select {
+		case payload := <-probes:
+			// The payload may have padding for the minimum frame size.
+			if bytes.HasPrefix(payload, nonce) {
+				return nil
+			}
+		case <-ctx.Done():
+			return fmt.Errorf("probe packet is not received: %v", ctx.Err())
+		}
+	}
+}
+
+func (r *selfTest) newGroupMod(cmd openflow.GroupModCmd) (openflow.GroupMod, error) {
+	group, err := r.factory.NewGroupMod(cmd)
+	if err != nil {
+		return nil, err
+	}
+	// ALL group without buckets, which drops the packets.
+	group.SetGroupType(openflow.GroupAll)
+	group.SetGroupID(selfTestGroupID)
+	if err := group.Error(); err != nil {
+		return nil, err
+	}
+
+	return group, nil
+}
+
+func (r *selfTest) group(ctx context.Context) error {
+	add, err := r.newGroupMod(openflow.GroupAdd)
+	if err != nil {
+		return err
+	}
+	err = r.session.SendAndConfirm(ctx, add)
+	r.groupAdded = applied(err)
+	if err != nil {
+		return err
+	}
+
+	del, err := r.newGroupMod(openflow.GroupDelete)
+	if err != nil {
+		return err
+	}
+	if err := r.session.SendAndConfirm(ctx, del); err != nil {
+		return err
+	}
+	r.groupAdded = false
+
+	return nil
+}
+
+func (r *selfTest) newMeterMod(cmd openflow.MeterModCmd) (openflow.MeterMod, error) {
+	meter, err := r.factory.NewMeterMod(cmd)
+	if err != nil {
+		return nil, err
+	}
+	meter.SetMeterID(selfTestMeterID)
+	if cmd != openflow.MeterDelete {
+		meter.AddBand(openflow.MeterBand{Type: openflow.MeterBandDrop, Rate: 1000})
+	}
+	if err := meter.Error(); err != nil {
+		return nil, err
+	}
+
+	return meter, nil
+}
+
+func (r *selfTest) meter(ctx context.Context) error {
+	add, err := r.newMeterMod(openflow.MeterAdd)
+	if err != nil {
+		return err
+	}
+	err = r.session.SendAndConfirm(ctx, add)
+	r.meterAdded = applied(err)
+	if err != nil {
+		return err
+	}
+
+	del, err := r.newMeterMod(openflow.MeterDelete)
+	if err != nil {
+		return err
+	}
+	if err := r.session.SendAndConfirm(ctx, del); err != nil {
+		return err
+	}
+	r.meterAdded = false
+
+	return nil
+}
+
+// applied returns whether the request that has failed with err may have been applied by the
+// device, e.g., the device has not replied in time. The requests rejected by the device or
+// refused by the session have not been applied.
+func applied(err error) bool {
+	var e *openflow.DeviceError
+	return !errors.As(err, &e) && err != ErrSlave && err != ErrThrottled
+}
+
+// cleanup removes what the failed steps have left on the device. A failure of the cleanup is
+// recorded as a failed step.
+func (r *selfTest) cleanup() {
+	if !r.flowAdded && !r.groupAdded && !r.meterAdded {
+		return
+	}
+
+	// The context of the test may have been already canceled.
+	r.step(context.Background(), "cleanup", func(ctx context.Context) error {
+		var reqs []transceiver.Request
+		if r.flowAdded {
+			flow, err := r.newFlowMod(openflow.FlowDeleteStrict)
+			if err != nil {
+				return err
+			}
+			reqs = append(reqs, flow)
+		}
+		if r.groupAdded {
+			group, err := r.newGroupMod(openflow.GroupDelete)
+			if err != nil {
+				return err
+			}
+			reqs = append(reqs, group)
+		}
+		if r.meterAdded {
+			meter, err := r.newMeterMod(openflow.MeterDelete)
+			if err != nil {
+				return err
+			}
+			reqs = append(reqs, meter)
+		}
+
+		// Try all of them even if some fail.
+		var result error
+		for _, req := range reqs {
+			if err := r.session.SendAndConfirm(ctx, req); err != nil && result == nil {
+				result = err
+			}
+		}
+
+		return result
+	})
+}
+
+// selfTestResults keeps the last self-test result of each device so that it survives the
+// reconnection of the device.
+type selfTestResults struct {
+	mutex sync.Mutex
+	// Key is the DPID.
+	results map[string]SelfTestResult
+}
+
+func newSelfTestResults() *selfTestResults {
+	return &selfTestResults{results: make(map[string]SelfTestResult)}
+}
+
+func (r *selfTestResults) set(dpid string, result SelfTestResult) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.results[dpid] = result
+}
+
+// get returns nil if the device has never been tested.
+func (r *selfTestResults) get(dpid string) *SelfTestResult {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	v, ok := r.results[dpid]
+	if !ok {
+		return nil
+	}
+
+	return &v
+}
+
+// selfTestDevice runs the self-test on the device and returns its result, which is also shown by
+// getDevice afterwards.
+func (r *Controller) selfTestDevice(w rest.ResponseWriter, req *rest.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	device, ok := r.connectedDevice(w, req)
+	if !ok {
+		return
+	}
+
+	result, err := device.SelfTest(req.Context())
+	if err != nil {
+		status := http.StatusInternalServerError
+		if err == ErrSelfTestRunning {
+			status = http.StatusConflict
+		}
+		writeError(w, status, err)
+		return
+	}
+	r.selfTests.set(device.ID(), result)
+	logger.Infof("self-test of %v: passed=%v", device.ID(), result.Passed)
+
+	w.WriteJson(&result)
+}
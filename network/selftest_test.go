@@ -0,0 +1,384 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/superkkt/cherry/clock"
+	"github.com/superkkt/cherry/openflow"
+	"github.com/superkkt/cherry/openflow/of13"
+	"github.com/superkkt/cherry/openflow/transceiver"
+)
+
+// selfTestHandler accepts the replies, and passes the PACKET_INs to the session as usual.
+type selfTestHandler struct {
+	barrierHandler
+	session *session
+}
+
+// OnHello sets the negotiated factory to the device so that the device and the transceiver
+// share the transaction IDs.
+func (r *selfTestHandler) OnHello(f openflow.Factory, w transceiver.Writer, v openflow.Hello) error {
+	r.session.device.setFactory(f)
+	return r.barrierHandler.OnHello(f, w, v)
+}
+
+func (r *selfTestHandler) OnFlowStatsReply(openflow.Factory, transceiver.Writer, openflow.FlowStatsReply) error {
+	return nil
+}
+
+func (r *selfTestHandler) OnPortStatsReply(openflow.Factory, transceiver.Writer, openflow.PortStatsReply) error {
+	return nil
+}
+
+func (r *selfTestHandler) OnPacketIn(f openflow.Factory, w transceiver.Writer, v openflow.PacketIn) error {
+	return r.session.OnPacketIn(f, w, v)
+}
+
+// selfTestSwitch is an OpenFlow 1.3 switch that keeps the flows, groups, and meters
+// created by the controller.
+type selfTestSwitch struct {
+	conn   net.Conn
+	stream *transceiver.Stream
+	// reject returns the class of the error message that rejects packet, or false to accept it.
+	reject func(packet []byte) (class uint16, ok bool)
+	// stats updates entry, which is the flow statistics of the flow whose cookie is cookie,
+	// if it is not nil. modified is the number of MODIFY_STRICT FLOW_MODs received.
+	stats    func(cookie uint64, entry []byte, modified int)
+	modified int
+
+	mutex sync.Mutex
+	// Key is the cookie.
+	flows  map[uint64]bool
+	groups map[uint32]bool
+	meters map[uint32]bool
+}
+
+func (r *selfTestSwitch) serve() {
+	for {
+		packet, err := r.stream.ReadMessage()
+		if err != nil {
+			return
+		}
+		if reply := r.handle(packet); reply != nil {
+			r.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+			if _, err := r.conn.Write(reply); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (r *selfTestSwitch) handle(packet []byte) []byte {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	xid := packet[4:8]
+	if r.reject != nil {
+		if class, ok := r.reject(packet); ok {
+			v := newOF13Message(of13.OFPT_ERROR, xid, 4)
+			binary.BigEndian.PutUint16(v[8:10], class)
+			return withLength(append(v, packet...))
+		}
+	}
+
+	switch packet[1] {
+	case of13.OFPT_BARRIER_REQUEST:
+		return newOF13Message(of13.OFPT_BARRIER_REPLY, xid, 0)
+	case of13.OFPT_ECHO_REQUEST:
+		return withLength(append(newOF13Message(of13.OFPT_ECHO_REPLY, xid, 0), packet[8:]...))
+	case of13.OFPT_FLOW_MOD:
+		cookie := binary.BigEndian.Uint64(packet[8:16])
+		switch packet[25] {
+		case of13.OFPFC_ADD:
+			r.flows[cookie] = true
+		case of13.OFPFC_MODIFY_STRICT:
+			r.modified++
+		case of13.OFPFC_DELETE_STRICT:
+			delete(r.flows, cookie)
+		}
+	case of13.OFPT_GROUP_MOD:
+		id := binary.BigEndian.Uint32(packet[12:16])
+		r.groups[id] = binary.BigEndian.Uint16(packet[8:10]) == of13.OFPGC_ADD
+	case of13.OFPT_METER_MOD:
+		id := binary.BigEndian.Uint32(packet[12:16])
+		r.meters[id] = binary.BigEndian.Uint16(packet[8:10]) == of13.OFPMC_ADD
+	case of13.OFPT_MULTIPART_REQUEST:
+		return r.multipartReply(xid, binary.BigEndian.Uint16(packet[8:10]), packet[16])
+	case of13.OFPT_PACKET_OUT:
+		// Loop the packet back to the controller.
+		data := packet[24+binary.BigEndian.Uint16(packet[16:18]):]
+		v := newOF13Message(of13.OFPT_PACKET_IN, xid, 16+16+2)
+		binary.BigEndian.PutUint32(v[8:12], openflow.NoBuffer)
+		binary.BigEndian.PutUint16(v[12:14], uint16(len(data)))
+		v[14] = of13.OFPR_ACTION
+		// OXM match of the controller port as the ingress port.
+		copy(v[24:40], []byte{0, 1, 0, 12, 0x80, 0, 0, 4, 0xFF, 0xFF, 0xFF, 0xFD, 0, 0, 0, 0})
+		return withLength(append(v, data...))
+	}
+
+	return nil
+}
+
+func (r *selfTestSwitch) multipartReply(xid []byte, mpType uint16, tableID uint8) []byte {
+	v := newOF13Message(of13.OFPT_MULTIPART_REPLY, xid, 8)
+	binary.BigEndian.PutUint16(v[8:10], mpType)
+	if mpType != of13.OFPMP_FLOW {
+		// No port statistics.
+		return v
+	}
+	for cookie := range r.flows {
+		entry := make([]byte, 56)
+		binary.BigEndian.PutUint16(entry[0:2], 56)
+		entry[2] = tableID
+		binary.BigEndian.PutUint16(entry[12:14], selfTestPriority)
+		binary.BigEndian.PutUint64(entry[24:32], cookie)
+		// Empty OXM match.
+		copy(entry[48:52], []byte{0, 1, 0, 4})
+		if r.stats != nil {
+			r.stats(cookie, entry, r.modified)
+		}
+		v = append(v, entry...)
+	}
+
+	return withLength(v)
+}
+
+// newOF13Message returns a message whose body is size bytes of zeros.
+func newOF13Message(msgType uint8, xid []byte, size int) []byte {
+	v := make([]byte, 8+size)
+	v[0] = openflow.OF13_VERSION
+	v[1] = msgType
+	copy(v[4:8], xid)
+
+	return withLength(v)
+}
+
+// withLength updates the length field of the message to its actual length.
+func withLength(msg []byte) []byte {
+	binary.BigEndian.PutUint16(msg[2:4], uint16(len(msg)))
+	return msg
+}
+
+func (r *selfTestSwitch) count() (flows, groups, meters int) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, ok := range r.groups {
+		if ok {
+			groups++
+		}
+	}
+	for _, ok := range r.meters {
+		if ok {
+			meters++
+		}
+	}
+
+	return len(r.flows), groups, meters
+}
+
+// newSelfTestDevice returns an OF1.3 device with 4 tables whose switch is sw.
+func newSelfTestDevice(t *testing.T, sw *selfTestSwitch) (d *Device, cleanup func()) {
+	local, remote := net.Pipe()
+	s := &session{
+		clock:        clock.Real,
+		flowConflict: newConflictPolicy(),
+		limiter:      newSendLimiter(0, 0, clock.Real),
+		packetInGate: newPacketInGate(newPacketInPolicy(), clock.Real),
+	}
+	handler := &selfTestHandler{barrierHandler: barrierHandler{hello: make(chan struct{})}, session: s}
+	s.transceiver = transceiver.NewTransceiver(transceiver.NewStream(local), handler, clock.Real)
+	s.device = newDevice(s)
+	s.device.id = "1"
+	s.device.features.NumTables = 4
+	s.negotiated = true
+	ctx, cancel := context.WithCancel(context.Background())
+	go s.transceiver.Run(ctx)
+
+	sw.conn = remote
+	sw.stream = transceiver.NewStream(remote)
+	sw.flows = make(map[uint64]bool)
+	sw.groups = make(map[uint32]bool)
+	sw.meters = make(map[uint32]bool)
+	writeSwitch(t, remote, []byte{openflow.OF13_VERSION, of13.OFPT_HELLO, 0, 8, 0, 0, 0, 1})
+	select {
+	case <-handler.hello:
+	case <-time.After(5 * time.Second):
+		t.Fatal("HELLO is not dispatched")
+	}
+	go sw.serve()
+
+	return s.device, func() {
+		cancel()
+		s.transceiver.Close()
+		sw.stream.Close()
+		remote.Close()
+	}
+}
+
+func checkSelfTestSteps(t *testing.T, result SelfTestResult, expected map[string]string) {
+	if len(result.Steps) != len(expected) {
+		t.Fatalf("unexpected steps: %+v", result.Steps)
+	}
+	for _, v := range result.Steps {
+		if v.Result != expected[v.Name] {
+			t.Fatalf("unexpected result of %v: expected=%v, got=%+v", v.Name, expected[v.Name], v)
+		}
+	}
+}
+
+func TestSelfTest(t *testing.T) {
+	sw := &selfTestSwitch{}
+	d, cleanup := newSelfTestDevice(t, sw)
+	defer cleanup()
+
+	result, err := d.SelfTest(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.Passed {
+		t.Fatalf("unexpected failure: %+v", result.Steps)
+	}
+	// SupportsGroups is true because the device has not reported the table features.
+	checkSelfTestSteps(t, result, map[string]string{
+		"barrier":      SelfTestPass,
+		"echo":         SelfTestPass,
+		"flow_install": SelfTestPass,
+		"flow_verify":  SelfTestPass,
+		"flow_delete":  SelfTestPass,
+		"port_stats":   SelfTestPass,
+		"packet_out":   SelfTestPass,
+		"group":        SelfTestPass,
+		"meter":        SelfTestPass,
+	})
+	if flows, groups, meters := sw.count(); flows != 0 || groups != 0 || meters != 0 {
+		t.Fatalf("self-test has left flows=%v, groups=%v, meters=%v", flows, groups, meters)
+	}
+	if d.selfTestTableID() != 3 {
+		t.Fatalf("unexpected scratch table: %v", d.selfTestTableID())
+	}
+
+	// The next self-test can run after the previous one finishes.
+	if _, err := d.SelfTest(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestSelfTestRejected(t *testing.T) {
+	sw := &selfTestSwitch{
+		reject: func(packet []byte) (uint16, bool) {
+			return of13.OFPET_METER_MOD_FAILED, packet[1] == of13.OFPT_METER_MOD
+		},
+	}
+	d, cleanup := newSelfTestDevice(t, sw)
+	defer cleanup()
+
+	result, err := d.SelfTest(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Passed {
+		t.Fatal("expected a failure")
+	}
+	// No cleanup because the rejected meter has not been created.
+	checkSelfTestSteps(t, result, map[string]string{
+		"barrier":      SelfTestPass,
+		"echo":         SelfTestPass,
+		"flow_install": SelfTestPass,
+		"flow_verify":  SelfTestPass,
+		"flow_delete":  SelfTestPass,
+		"port_stats":   SelfTestPass,
+		"packet_out":   SelfTestPass,
+		"group":        SelfTestPass,
+		"meter":        SelfTestFail,
+	})
+	meter := result.Steps[len(result.Steps)-1]
+	if !strings.Contains(meter.Error, "METER_MOD_FAILED") {
+		t.Fatalf("unexpected error of the meter step: %v", meter.Error)
+	}
+}
+
+func TestSelfTestCleanup(t *testing.T) {
+	groupDeleted := false
+	sw := &selfTestSwitch{
+		reject: func(packet []byte) (uint16, bool) {
+			switch packet[1] {
+			case of13.OFPT_MULTIPART_REQUEST:
+				// No flow statistics.
+				return of13.OFPET_BAD_REQUEST, binary.BigEndian.Uint16(packet[8:10]) == of13.OFPMP_FLOW
+			case of13.OFPT_GROUP_MOD:
+				// Only the first deletion fails.
+				if binary.BigEndian.Uint16(packet[8:10]) == of13.OFPGC_DELETE && !groupDeleted {
+					groupDeleted = true
+					return of13.OFPET_GROUP_MOD_FAILED, true
+				}
+			}
+			return 0, false
+		},
+	}
+	d, cleanup := newSelfTestDevice(t, sw)
+	defer cleanup()
+
+	result, err := d.SelfTest(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// flow_delete removes the flow even if flow_verify fails, and cleanup removes the group
+	// that the group step has failed to delete.
+	checkSelfTestSteps(t, result, map[string]string{
+		"barrier":      SelfTestPass,
+		"echo":         SelfTestPass,
+		"flow_install": SelfTestPass,
+		"flow_verify":  SelfTestFail,
+		"flow_delete":  SelfTestPass,
+		"port_stats":   SelfTestPass,
+		"packet_out":   SelfTestPass,
+		"group":        SelfTestFail,
+		"meter":        SelfTestPass,
+		"cleanup":      SelfTestPass,
+	})
+	if result.Steps[len(result.Steps)-1].Name != "cleanup" {
+		t.Fatalf("cleanup should be the last step: %+v", result.Steps)
+	}
+	if flows, groups, meters := sw.count(); flows != 0 || groups != 0 || meters != 0 {
+		t.Fatalf("self-test has left flows=%v, groups=%v, meters=%v", flows, groups, meters)
+	}
+}
+
+func TestSelfTestRunning(t *testing.T) {
+	sw := &selfTestSwitch{}
+	d, cleanup := newSelfTestDevice(t, sw)
+	defer cleanup()
+
+	d.selfTestProbe = make(chan []byte, 1)
+	if _, err := d.SelfTest(context.Background()); err != ErrSelfTestRunning {
+		t.Fatalf("unexpected error: expected=%v, got=%v", ErrSelfTestRunning, err)
+	}
+}
@@ -0,0 +1,153 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"encoding"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/superkkt/cherry/clock"
+	"github.com/superkkt/cherry/metrics"
+	"github.com/superkkt/cherry/openflow"
+)
+
+var (
+	// ErrThrottled is returned when a message is not sent due to the send rate limit of the device.
+	ErrThrottled = errors.New("send rate limit of the device is exceeded")
+
+	messagesThrottled = metrics.NewCounterVec("cherry_openflow_messages_throttled_total", "Number of OpenFlow messages not sent due to the send rate limits.", "type")
+)
+
+// tokenBucket allows rate events per second on average, and bursts of up to rate events.
+type tokenBucket struct {
+	rate   float64
+	tokens float64
+	last   time.Time
+	clock  clock.Clock
+}
+
+func newTokenBucket(rate int, clk clock.Clock) *tokenBucket {
+	return &tokenBucket{
+		rate:   float64(rate),
+		tokens: float64(rate),
+		last:   clk.Now(),
+		clock:  clk,
+	}
+}
+
+// take consumes n tokens, and returns whether there were enough tokens.
+// XXX: Caller should lock the mutex before they call this function
+func (r *tokenBucket) take(n int) bool {
+	now := r.clock.Now()
+	r.tokens += now.Sub(r.last).Seconds() * r.rate
+	if r.tokens > r.rate {
+		r.tokens = r.rate
+	}
+	r.last = now
+
+	if r.tokens < float64(n) {
+		return false
+	}
+	r.tokens -= float64(n)
+
+	return true
+}
+
+// sendLimiter limits the FLOW_MOD and PACKET_OUT messages sent to a device, so that a buggy
+// application cannot overwhelm the slow CPU path of the switch. Other messages are not limited.
+type sendLimiter struct {
+	mutex sync.Mutex
+	// nil means unlimited.
+	flowMod, packetOut *tokenBucket
+}
+
+// newSendLimiter returns a limiter that allows flowModRate FLOW_MODs and packetOutRate
+// PACKET_OUTs per second. Zero rate means unlimited.
+func newSendLimiter(flowModRate, packetOutRate int, clk clock.Clock) *sendLimiter {
+	if flowModRate < 0 || packetOutRate < 0 {
+		panic("rate should be equal to or greater than zero")
+	}
+	if clk == nil {
+		panic("clock is nil")
+	}
+
+	v := new(sendLimiter)
+	if flowModRate > 0 {
+		v.flowMod = newTokenBucket(flowModRate, clk)
+	}
+	if packetOutRate > 0 {
+		v.packetOut = newTokenBucket(packetOutRate, clk)
+	}
+
+	return v
+}
+
+// allow returns whether all the messages in msgs can be sent now. The limit is applied to
+// msgs as a whole, so either all of them or none of them are allowed.
+func (r *sendLimiter) allow(msgs ...encoding.BinaryMarshaler) bool {
+	flowMods, packetOuts := 0, 0
+	for _, msg := range msgs {
+		switch msg.(type) {
+		case openflow.FlowMod:
+			flowMods++
+		case openflow.PacketOut:
+			packetOuts++
+		}
+	}
+	if flowMods == 0 && packetOuts == 0 {
+		return true
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	// Check both buckets before consuming any token.
+	if !r.check(r.flowMod, flowMods) {
+		messagesThrottled.Inc("flow_mod")
+		return false
+	}
+	if !r.check(r.packetOut, packetOuts) {
+		messagesThrottled.Inc("packet_out")
+		return false
+	}
+	if r.flowMod != nil {
+		r.flowMod.take(flowMods)
+	}
+	if r.packetOut != nil {
+		r.packetOut.take(packetOuts)
+	}
+
+	return true
+}
+
+// XXX: Caller should lock the mutex before they call this function
+func (r *sendLimiter) check(bucket *tokenBucket, n int) bool {
+	if bucket == nil || n == 0 {
+		return true
+	}
+	// Refill the bucket without consuming any token.
+	bucket.take(0)
+
+	return bucket.tokens >= float64(n)
+}
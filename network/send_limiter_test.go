@@ -0,0 +1,74 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"testing"
+	"time"
+
+	"github.com/superkkt/cherry/openflow"
+	"github.com/superkkt/cherry/openflow/of13"
+	"github.com/superkkt/cherry/testutil"
+)
+
+func TestSendLimiter(t *testing.T) {
+	clk := testutil.NewFakeClock(time.Unix(0, 0))
+	limiter := newSendLimiter(2, 0, clk)
+	f := of13.NewFactory()
+
+	newFlow := func() openflow.FlowMod {
+		flow, err := f.NewFlowMod(openflow.FlowAdd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return flow
+	}
+	barrier, err := f.NewBarrierRequest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Burst of the rate.
+	if !limiter.allow(newFlow()) || !limiter.allow(newFlow()) {
+		t.Fatal("flows within the burst should be allowed")
+	}
+	if limiter.allow(newFlow()) {
+		t.Fatal("flow exceeding the burst should be throttled")
+	}
+	// Other messages are not limited.
+	if !limiter.allow(barrier) {
+		t.Fatal("barrier should be allowed")
+	}
+
+	clk.Advance(500 * time.Millisecond)
+	if !limiter.allow(newFlow()) {
+		t.Fatal("flow should be allowed after the refill")
+	}
+	// A batch is allowed or throttled as a whole.
+	clk.Advance(time.Second)
+	if limiter.allow(newFlow(), newFlow(), newFlow()) {
+		t.Fatal("batch exceeding the burst should be throttled")
+	}
+	if !limiter.allow(newFlow(), newFlow()) {
+		t.Fatal("batch within the burst should be allowed")
+	}
+}
@@ -24,43 +24,145 @@ package network
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding"
 	"errors"
 	"fmt"
 	"net"
 	"strconv"
+	"sync"
 	"time"
 
+	"github.com/superkkt/cherry/audit"
+	"github.com/superkkt/cherry/clock"
+	"github.com/superkkt/cherry/metrics"
 	"github.com/superkkt/cherry/openflow"
 	"github.com/superkkt/cherry/openflow/of10"
 	"github.com/superkkt/cherry/openflow/of13"
+	"github.com/superkkt/cherry/openflow/of14"
 	"github.com/superkkt/cherry/openflow/transceiver"
 	"github.com/superkkt/cherry/protocol"
+
+	"github.com/superkkt/viper"
+
+	// Register the decoder of the Nicira extensions.
+	_ "github.com/superkkt/cherry/openflow/nicira"
 )
 
 var (
 	errNotNegotiated = errors.New("invalid command on non-negotiated session")
 )
 
+var (
+	packetInsReceived = metrics.NewCounterVec("cherry_packet_ins_received_total", "Number of PACKET_IN messages received from each switch.", "dpid")
+	packetInFloods    = metrics.NewCounterVec("cherry_packet_in_floods_total", "Number of times that the table-miss packets of each switch were blocked by the PACKET_IN flood guard.", "dpid")
+	handshakeFailures = metrics.NewCounterVec("cherry_handshake_failures_total", "Number of connections closed before completing the handshake.")
+)
+
 const (
 	deviceExplorerInterval = 1 * time.Minute
+	flowStatsInterval      = 10 * time.Second
 )
 
 type session struct {
 	negotiated  bool
+	remoteAddr  net.Addr
 	device      *Device
 	transceiver *transceiver.Transceiver
 	handler     transceiver.Handler
 	watcher     watcher
 	finder      Finder
 	listener    ControllerEventListener
+	// handshakeDone is called when the handshake is completed or the session is closed.
+	handshakeDone func()
+	// True after we check port renumbering with the first port list of the device.
+	portsChecked bool
+	// True after the saved links of the device are restored. Only accessed by the dispatcher goroutine.
+	linksRestored bool
+	packetInGate  *packetInGate
+	// Workers that deliver the PACKET_INs to the applications. nil means this session delivers them.
+	packetInWorkers *packetInWorkers
+	clock           clock.Clock
+	// Interval of the port statistics polling. 0 disables the polling.
+	portStatsInterval time.Duration
+	// Maximum time from the start of the session to the first FEATURES_REPLY.
+	handshakeTimeout time.Duration
+	handshakeRetry   handshakeRetry
+	negotiation      *negotiationTracker
+	admission        *admissionControl
+	// TLS connection of this session. nil if it is a plaintext connection.
+	tlsConn *tls.Conn
+	// nil disables the binding of the client certificates.
+	certBinder *certBinder
+	// True after we check the client certificate. Only accessed by the dispatcher goroutine.
+	certChecked bool
+	// Reconcile the flows of the device with its shadow copy whenever the flow stats are collected.
+	reconcileFlows bool
+	// Remove the saved flows owned by the applications that are not loaded when the device connects.
+	removeStaleFlows bool
+	mastership       *mastership
+	auxPolicy        auxPolicy
+	// What to do with the flows that conflict with the flows of other applications.
+	flowConflict conflictPolicy
+	// Flow priority bands reserved for the applications.
+	priorities *priorityAllocator
+	// Slices that confine the flows of their applications.
+	slices *sliceIndex
+	// Send rate limiter of FLOW_MODs and PACKET_OUTs. Shared by the auxiliary connections.
+	limiter *sendLimiter
+	// True while we wait for the FEATURES_REPLY that tells whether an OF1.3 connection
+	// is an auxiliary one. Only accessed by the dispatcher goroutine.
+	probing bool
+	hello   openflow.Hello
+	// Main device of this session if it is an auxiliary connection. nil for the main connections.
+	auxMutex sync.RWMutex
+	auxOf    *Device
+	capture  *sessionCapture
+	// Records the messages written to the device with their outcomes. nil if the audit log is disabled.
+	audit *sessionAudit
 }
 
 type sessionConfig struct {
-	conn     net.Conn
-	watcher  watcher
-	finder   Finder
-	listener ControllerEventListener
+	conn          net.Conn
+	watcher       watcher
+	finder        Finder
+	listener      ControllerEventListener
+	events        *eventBus
+	handshakeDone func()
+	packetIn      *packetInPolicy
+	// Workers that deliver the PACKET_INs to the applications. nil means the session delivers them.
+	packetInWorkers *packetInWorkers
+	clock           clock.Clock
+	// Interval of the port statistics polling. 0 disables the polling.
+	portStatsInterval time.Duration
+	// Maximum time from the start of the session to the first FEATURES_REPLY.
+	handshakeTimeout time.Duration
+	// Retry of the handshake messages that have failed transiently.
+	handshakeRetry handshakeRetry
+	// Consecutive negotiation failures of the switches.
+	negotiation *negotiationTracker
+	// Admission control of the switches by their DPIDs.
+	admission *admissionControl
+	// Binding of the client certificates to the DPIDs. nil disables it.
+	certBinder *certBinder
+	// Log of the FLOW_MODs, GROUP_MODs and METER_MODs sent to the device. nil disables it.
+	auditLog *audit.Log
+	// Reconcile the flows of the device with its shadow copy whenever the flow stats are collected.
+	reconcileFlows bool
+	// Remove the saved flows owned by the applications that are not loaded when the device connects.
+	removeStaleFlows bool
+	mastership       *mastership
+	// Messages sent over the auxiliary connections.
+	auxPolicy auxPolicy
+	// Maximum number of FLOW_MODs and PACKET_OUTs per second sent to the device. 0 means unlimited.
+	flowModRate, packetOutRate int
+	// What to do with the flows that conflict with the flows of other applications.
+	flowConflict conflictPolicy
+	// Flow priority bands reserved for the applications. nil reserves no band.
+	priorities *priorityAllocator
+	// Slices that confine the flows of their applications. nil has no slice.
+	slices  *sliceIndex
+	capture *captureManager
 }
 
 func checkParam(c sessionConfig) {
@@ -76,6 +178,33 @@ func checkParam(c sessionConfig) {
 	if c.listener == nil {
 		panic("Listener is nil")
 	}
+	if c.events == nil {
+		panic("Events is nil")
+	}
+	if c.handshakeDone == nil {
+		panic("HandshakeDone is nil")
+	}
+	if c.packetIn == nil {
+		panic("PacketIn is nil")
+	}
+	if c.clock == nil {
+		panic("Clock is nil")
+	}
+	if c.mastership == nil {
+		panic("Mastership is nil")
+	}
+	if c.capture == nil {
+		panic("Capture is nil")
+	}
+	if c.negotiation == nil {
+		panic("Negotiation is nil")
+	}
+	if c.admission == nil {
+		panic("Admission is nil")
+	}
+	if c.handshakeTimeout <= 0 {
+		panic("HandshakeTimeout should be greater than zero")
+	}
 }
 
 func newSession(c sessionConfig) *session {
@@ -83,11 +212,38 @@ func newSession(c sessionConfig) *session {
 
 	stream := transceiver.NewStream(c.conn)
 	v := new(session)
+	v.remoteAddr = c.conn.RemoteAddr()
 	v.watcher = c.watcher
 	v.finder = c.finder
-	v.listener = c.listener
+	// Publish the events of this session on the bus before passing them to the listener.
+	v.listener = &sessionPublisher{ControllerEventListener: c.listener, bus: c.events, session: v}
+	v.handshakeDone = c.handshakeDone
+	v.clock = c.clock
+	v.portStatsInterval = c.portStatsInterval
+	v.handshakeTimeout = c.handshakeTimeout
+	v.handshakeRetry = c.handshakeRetry
+	v.negotiation = c.negotiation
+	v.admission = c.admission
+	v.tlsConn, _ = c.conn.(*tls.Conn)
+	v.certBinder = c.certBinder
+	v.reconcileFlows = c.reconcileFlows
+	v.removeStaleFlows = c.removeStaleFlows
+	v.mastership = c.mastership
+	v.auxPolicy = c.auxPolicy
+	v.flowConflict = c.flowConflict
+	v.priorities = c.priorities
+	v.slices = c.slices
+	v.packetInGate = newPacketInGate(c.packetIn, c.clock)
+	v.packetInWorkers = c.packetInWorkers
+	v.limiter = newSendLimiter(c.flowModRate, c.packetOutRate, c.clock)
 	v.device = newDevice(v)
-	v.transceiver = transceiver.NewTransceiver(stream, v)
+	v.transceiver = transceiver.NewTransceiver(stream, v, c.clock)
+	v.capture = newSessionCapture(c.capture, c.conn.LocalAddr(), c.conn.RemoteAddr())
+	v.transceiver.SetCapturer(v.capture)
+	if c.auditLog != nil {
+		v.audit = newSessionAudit(c.auditLog, v)
+		v.transceiver.SetAuditor(v.audit)
+	}
 
 	return v
 }
@@ -102,27 +258,109 @@ func (r *session) OnHello(f openflow.Factory, w transceiver.Writer, v openflow.H
 
 	switch v.Version() {
 	case openflow.OF10_VERSION:
-		r.handler = newOF10Session(r.device)
-	case openflow.OF13_VERSION:
-		r.handler = newOF13Session(r.device)
+		r.handler = newOF10Session(r.device, r.handshakeRetry)
+	// OpenFlow 1.4 devices are initialized in the same way as OpenFlow 1.3 ones.
+	case openflow.OF13_VERSION, openflow.OF14_VERSION:
+		r.handler = newOF13Session(r.device, r.handshakeRetry)
 	default:
 		return fmt.Errorf("unsupported OpenFlow version: %v", v.Version())
 	}
 	r.device.setFactory(f)
 	r.negotiated = true
 
+	// An OF1.3 or later device may open auxiliary connections in addition to the main one.
+	// We cannot tell them apart until FEATURES_REPLY arrives, so ask the auxiliary ID before
+	// the version handler initializes the device, e.g., removes all the flows.
+	if v.Version() >= openflow.OF13_VERSION {
+		if err := r.handshakeRetry.do("HELLO", func() error { return sendHello(f, w) }); err != nil {
+			return fmt.Errorf("failed to send HELLO: %v", err)
+		}
+		if err := r.handshakeRetry.do("FEATURES_REQUEST", func() error { return sendFeaturesRequest(f, w) }); err != nil {
+			return fmt.Errorf("failed to send FEATURES_REQUEST: %v", err)
+		}
+		r.probing = true
+		r.hello = v
+		return nil
+	}
+
 	return r.handler.OnHello(f, w, v)
 }
 
+// mainDevice returns the main device of this session if it is an auxiliary connection.
+// Otherwise, it returns nil.
+func (r *session) mainDevice() *Device {
+	// Read lock
+	r.auxMutex.RLock()
+	defer r.auxMutex.RUnlock()
+
+	return r.auxOf
+}
+
+// eventDevice returns the device that the messages received from this session belong to.
+func (r *session) eventDevice() *Device {
+	if main := r.mainDevice(); main != nil {
+		return main
+	}
+
+	return r.device
+}
+
+// attachAuxiliary makes this session an auxiliary connection of the main device whose DPID
+// is same as the one in v.
+func (r *session) attachAuxiliary(v openflow.FeaturesReply) error {
+	dpid := strconv.FormatUint(v.DPID(), 10)
+	main := r.finder.Device(dpid)
+	if main == nil {
+		return fmt.Errorf("auxiliary connection (ID=%v) without the main connection: DPID=%v", v.AuxID(), dpid)
+	}
+
+	// NOTE: This is an anonymous function (NOT a goroutine!) that has a critical section.
+	func() {
+		// Write lock
+		r.auxMutex.Lock()
+		defer r.auxMutex.Unlock()
+
+		r.auxOf = main
+	}()
+
+	main.addAuxSession(r)
+	logger.Infof("auxiliary connection is ready: DPID=%v, AuxID=%v, policy=%v", dpid, v.AuxID(), r.auxPolicy)
+	r.negotiation.succeeded(r.remoteAddr)
+	// Let the next pending connection start its handshake.
+	r.handshakeDone()
+
+	return nil
+}
+
+// checkCertificate binds the client certificate of this session to dpid, or verifies that it is
+// the one bound to dpid, if the certificate binding is enabled.
+func (r *session) checkCertificate(dpid uint64) error {
+	if r.certChecked || r.tlsConn == nil || r.certBinder == nil {
+		return nil
+	}
+	if err := r.certBinder.check(dpid, r.tlsConn); err != nil {
+		return err
+	}
+	r.certChecked = true
+
+	return nil
+}
+
 func (r *session) OnError(f openflow.Factory, w transceiver.Writer, v openflow.Error) error {
+	err := v.Err()
+	if r.audit != nil {
+		r.audit.Replied(v.TransactionID(), err)
+	}
 	// Is this the CHECK_OVERLAP error?
-	if v.Class() == 3 && v.Code() == 1 {
+	if errors.Is(err, openflow.ErrFlowOverlap) {
 		// Ignore this CHECK_OVERLAP error
 		logger.Debug("FLOW_MOD is overlapped")
 		return nil
 	}
 
-	logger.Errorf("ERROR (DPID=%v, class=%v, code=%v, data=%v)", r.device.ID(), v.Class(), v.Code(), v.Data())
+	key := fmt.Sprintf("error %v/%v/%v", r.device.ID(), v.Class(), v.Code())
+	// The error includes the transaction ID of the failed request, and the data starts with its header.
+	rateLogger.Errorf(key, "ERROR (DPID=%v, data=%v): %v", r.device.ID(), v.Data(), err)
 	if !r.negotiated {
 		return errNotNegotiated
 	}
@@ -136,6 +374,26 @@ func (r *session) OnFeaturesReply(f openflow.Factory, w transceiver.Writer, v op
 	if !r.negotiated {
 		return errNotNegotiated
 	}
+	r.capture.setDPID(strconv.FormatUint(v.DPID(), 10))
+
+	// Reply for the auxiliary ID probe?
+	if r.probing {
+		r.probing = false
+		if v.AuxID() != 0 {
+			// The main connection has bound its certificate, so this only verifies it.
+			if err := r.checkCertificate(v.DPID()); err != nil {
+				return err
+			}
+			return r.attachAuxiliary(v)
+		}
+		// This is the main connection. Start the initialization that we have deferred,
+		// which will send FEATURES_REQUEST again after removing all the flows.
+		return r.handler.OnHello(f, w, r.hello)
+	}
+	// Auxiliary connection never becomes a device.
+	if r.mainDevice() != nil {
+		return nil
+	}
 
 	// First FeaturesReply packet?
 	if r.device.isReady() {
@@ -150,16 +408,61 @@ func (r *session) OnFeaturesReply(f openflow.Factory, w transceiver.Writer, v op
 	dpid := strconv.FormatUint(v.DPID(), 10)
 	// Already connected device?
 	if r.finder.Device(dpid) != nil {
-		return errors.New("duplicated device DPID (aux. connection is not supported yet)")
+		// Auxiliary connections have been attached above, so this is another main connection.
+		return fmt.Errorf("duplicated device DPID: %v", dpid)
+	}
+	switch r.admission.check(dpid, r) {
+	case admissionRejected:
+		return fmt.Errorf("switch is not admitted: DPID=%v", dpid)
+	case admissionPending:
+		// Keep the connection without initializing the device until the switch is approved,
+		// which sends FEATURES_REQUEST again.
+		r.handshakeDone()
+		return nil
 	}
+	// Verify the identity of the switch before we trust the DPID. It is done after the admission
+	// so that a switch that is not admitted cannot bind its certificate to the DPID.
+	if err := r.checkCertificate(v.DPID()); err != nil {
+		return err
+	}
+	// Request the device to change our role before it is used by others.
+	master, generation := r.mastership.get()
+	if master && r.device.isReadOnly() {
+		return errors.New("elected as the master during the handshake as a SLAVE")
+	}
+	if err := applyRole(r.device, master, generation); err != nil {
+		return err
+	}
+	shadow := r.watcher.FlowTable(dpid)
+	// The shadow copy may have been restored from the flows saved by the previous run, whose
+	// applications are not loaded anymore.
+	if n := shadow.Stale(); n > 0 {
+		if r.removeStaleFlows {
+			shadow.RemoveStale()
+			logger.Warningf("removed %v stale flows of %v owned by the applications that are not loaded", n, dpid)
+		} else {
+			logger.Warningf("%v has %v stale flows owned by the applications that are not loaded", dpid, n)
+		}
+	}
+	r.device.setShadowFlows(shadow)
 	r.device.setID(dpid)
 	logger.Infof("device is ready: DPID=%v, Description=%+v", dpid, r.device.Descriptions())
+	// OnHello has configured the default max_len of PACKET_INs as we did not know the
+	// DPID at that time. Apply the value overridden for this device, if any.
+	if length := r.device.MissSendLength(); length != r.packetInGate.policy.missSendLength("") {
+		if err := sendSetConfig(f, w, length); err != nil {
+			return fmt.Errorf("failed to send SET_CONFIG: %v", err)
+		}
+	}
 
 	// We assume a device is up after setting its DPID
 	if err := r.listener.OnDeviceUp(r.finder, r.device); err != nil {
 		return err
 	}
 	r.watcher.DeviceAdded(r.device)
+	r.negotiation.succeeded(r.remoteAddr)
+	// Let the next pending connection start its handshake.
+	r.handshakeDone()
 
 	features := Features{
 		DPID:       v.DPID(),
@@ -168,7 +471,63 @@ func (r *session) OnFeaturesReply(f openflow.Factory, w transceiver.Writer, v op
 	}
 	r.device.setFeatures(features)
 
-	return r.handler.OnFeaturesReply(f, w, v)
+	if err := r.handler.OnFeaturesReply(f, w, v); err != nil {
+		return err
+	}
+	// OF10 provides ports information in the FeaturesReply packet.
+	if ports := v.Ports(); ports != nil {
+		r.checkRenumbering(ports)
+		r.restoreLinks()
+	}
+	// Restore the flows that the device may have lost while it was disconnected, without waiting
+	// for the flow stats collector.
+	if r.reconcileFlows && r.watcher.FlowTable(dpid).Len() > 0 {
+		if err := sendFlowStatsRequest(f, w); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkRenumbering detects the ports that have been renumbered since the last
+// connection of this device, and then notifies the event listeners. It only
+// checks the first port list received after the device is connected.
+func (r *session) checkRenumbering(ports []openflow.Port) {
+	if r.portsChecked || !r.device.isReady() {
+		return
+	}
+	r.portsChecked = true
+
+	prev, ok := r.watcher.PortHistory(r.device.ID())
+	if !ok {
+		// This device is connected for the first time.
+		return
+	}
+	cur := make([]portRecord, 0)
+	for _, p := range ports {
+		cur = append(cur, newPortRecord(p))
+	}
+
+	mapping, deleted := renumberPorts(prev, cur)
+	if len(mapping) == 0 && len(deleted) == 0 {
+		return
+	}
+	logger.Warningf("ports have been renumbered: DPID=%v, mapping=%v, deleted=%v", r.device.ID(), mapping, deleted)
+
+	if err := r.listener.OnPortsRenumbered(r.finder, r.device, mapping, deleted); err != nil {
+		logger.Errorf("OnPortsRenumbered: %v", err)
+	}
+}
+
+// restoreLinks restores the saved links of this device once its ports are known. It is done
+// only for the first port list received after the device is connected.
+func (r *session) restoreLinks() {
+	if r.linksRestored || !r.device.isReady() {
+		return
+	}
+	r.linksRestored = true
+	r.watcher.RestoreLinks(r.device)
 }
 
 func (r *session) OnGetConfigReply(f openflow.Factory, w transceiver.Writer, v openflow.GetConfigReply) error {
@@ -202,6 +561,36 @@ func (r *session) OnDescReply(f openflow.Factory, w transceiver.Writer, v openfl
 	return r.handler.OnDescReply(f, w, v)
 }
 
+func (r *session) OnFlowStatsReply(f openflow.Factory, w transceiver.Writer, v openflow.FlowStatsReply) error {
+	logger.Debugf("FLOW_STATS_REPLY is received (# of flows=%v)", len(v.FlowStats()))
+
+	if !r.negotiated {
+		return errNotNegotiated
+	}
+	r.device.setFlowStats(v.FlowStats())
+	if r.reconcileFlows {
+		if err := r.device.reconcileFlows(v.FlowStats()); err != nil {
+			logger.Errorf("failed to reconcile the flows of %v: %v", r.device.ID(), err)
+		}
+	}
+
+	return r.handler.OnFlowStatsReply(f, w, v)
+}
+
+func (r *session) OnPortStatsReply(f openflow.Factory, w transceiver.Writer, v openflow.PortStatsReply) error {
+	logger.Debugf("PORT_STATS_REPLY is received (# of ports=%v)", len(v.PortStats()))
+
+	if !r.negotiated {
+		return errNotNegotiated
+	}
+	r.device.setPortStats(v.PortStats())
+	if err := r.listener.OnPortStatsUpdated(r.finder, r.device); err != nil {
+		logger.Errorf("OnPortStatsUpdated: %v", err)
+	}
+
+	return r.handler.OnPortStatsReply(f, w, v)
+}
+
 func (r *session) OnPortDescReply(f openflow.Factory, w transceiver.Writer, v openflow.PortDescReply) error {
 	logger.Debugf("PORT_DESC_REPLY is received (# of ports=%v)", len(v.Ports()))
 
@@ -209,10 +598,61 @@ func (r *session) OnPortDescReply(f openflow.Factory, w transceiver.Writer, v op
 		return errNotNegotiated
 	}
 
-	return r.handler.OnPortDescReply(f, w, v)
+	if err := r.handler.OnPortDescReply(f, w, v); err != nil {
+		return err
+	}
+	r.checkRenumbering(v.Ports())
+	r.restoreLinks()
+
+	return nil
+}
+
+func (r *session) OnTableFeaturesReply(f openflow.Factory, w transceiver.Writer, v openflow.TableFeaturesReply) error {
+	logger.Debugf("TABLE_FEATURES_REPLY is received (device=%v, # of tables=%v)", r.device.ID(), len(v.Tables()))
+
+	if !r.negotiated {
+		return errNotNegotiated
+	}
+	r.device.setTableFeatures(v.Tables())
+
+	return r.handler.OnTableFeaturesReply(f, w, v)
+}
+
+func (r *session) OnQueueGetConfigReply(f openflow.Factory, w transceiver.Writer, v openflow.QueueGetConfigReply) error {
+	logger.Debugf("QUEUE_GET_CONFIG_REPLY is received (device=%v, port=%v, # of queues=%v)", r.device.ID(), v.Port(), len(v.Queue()))
+
+	if !r.negotiated {
+		return errNotNegotiated
+	}
+
+	return r.handler.OnQueueGetConfigReply(f, w, v)
+}
+
+func (r *session) OnQueueStatsReply(f openflow.Factory, w transceiver.Writer, v openflow.QueueStatsReply) error {
+	logger.Debugf("QUEUE_STATS_REPLY is received (device=%v, # of queues=%v)", r.device.ID(), len(v.QueueStats()))
+
+	if !r.negotiated {
+		return errNotNegotiated
+	}
+	r.device.setQueueStats(v.QueueStats())
+
+	return r.handler.OnQueueStatsReply(f, w, v)
 }
 
+// BDDP (Broadcast Domain Discovery Protocol) packet is a LLDP packet sent to the broadcast
+// address with its own ethertype, which is flooded by the non-OpenFlow switches that drop LLDP.
+const bddpEtherType = 0x8942
+
 func newLLDPEtherFrame(deviceID string, port openflow.Port) ([]byte, error) {
+	// LLDP multicast MAC address and LLDP ethertype
+	return newDiscoveryFrame(deviceID, port, []byte{0x01, 0x80, 0xC2, 0x00, 0x00, 0x0E}, 0x88CC)
+}
+
+func newBDDPEtherFrame(deviceID string, port openflow.Port) ([]byte, error) {
+	return newDiscoveryFrame(deviceID, port, []byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}, bddpEtherType)
+}
+
+func newDiscoveryFrame(deviceID string, port openflow.Port, dstMAC net.HardwareAddr, etherType uint16) ([]byte, error) {
 	lldp := &protocol.LLDP{
 		ChassisID: protocol.LLDPChassisID{
 			SubType: 7, // Locally assigned alpha-numeric string
@@ -230,11 +670,9 @@ func newLLDPEtherFrame(deviceID string, port openflow.Port) ([]byte, error) {
 	}
 
 	ethernet := &protocol.Ethernet{
-		SrcMAC: port.MAC(),
-		// LLDP multicast MAC address
-		DstMAC: []byte{0x01, 0x80, 0xC2, 0x00, 0x00, 0x0E},
-		// LLDP ethertype
-		Type:    0x88CC,
+		SrcMAC:  port.MAC(),
+		DstMAC:  dstMAC,
+		Type:    etherType,
 		Payload: payload,
 	}
 	frame, err := ethernet.MarshalBinary()
@@ -245,12 +683,28 @@ func newLLDPEtherFrame(deviceID string, port openflow.Port) ([]byte, error) {
 	return frame, nil
 }
 
+// sendLLDP sends a LLDP packet to p, and then a BDDP packet if default.bddp is enabled.
 func sendLLDP(device *Device, p openflow.Port) error {
 	lldp, err := newLLDPEtherFrame(device.ID(), p)
 	if err != nil {
 		return err
 	}
+	if err := sendDiscoveryFrame(device, p, lldp); err != nil {
+		return err
+	}
+	if !viper.GetBool("default.bddp") {
+		return nil
+	}
+
+	bddp, err := newBDDPEtherFrame(device.ID(), p)
+	if err != nil {
+		return err
+	}
 
+	return sendDiscoveryFrame(device, p, bddp)
+}
+
+func sendDiscoveryFrame(device *Device, p openflow.Port, frame []byte) error {
 	outPort := openflow.NewOutPort()
 	outPort.SetValue(p.Number())
 
@@ -268,7 +722,7 @@ func sendLLDP(device *Device, p openflow.Port) error {
 	// From controller
 	out.SetInPort(openflow.NewInPort())
 	out.SetAction(action)
-	out.SetData(lldp)
+	out.SetData(frame)
 
 	return device.SendMessage(out)
 }
@@ -292,6 +746,10 @@ func (r *session) sendPortEvent(portNum uint32, up bool) {
 	}
 }
 
+func isPortUp(p openflow.Port) bool {
+	return p != nil && !p.IsPortDown() && !p.IsLinkDown()
+}
+
 func (r *session) updatePort(v openflow.PortStatus) {
 	port := v.Port()
 
@@ -304,6 +762,10 @@ func (r *session) updatePort(v openflow.PortStatus) {
 		if port.Number() > of13.OFPP_MAX {
 			return
 		}
+	case openflow.OF14_VERSION:
+		if port.Number() > of14.OFPP_MAX {
+			return
+		}
 	default:
 		panic("unsupported OpenFlow version")
 	}
@@ -318,12 +780,29 @@ func (r *session) OnPortStatus(f openflow.Factory, w transceiver.Writer, v openf
 	}
 
 	port := v.Port()
-	logger.Debugf("Device=%v, PortNum=%v, AdminUp=%v, LinkUp=%v", r.device.ID(), port.Number(), !port.IsPortDown(), !port.IsLinkDown())
+	logger.Debugf("Device=%v, PortNum=%v, Reason=%v, AdminUp=%v, LinkUp=%v", r.device.ID(), port.Number(), v.Reason(), !port.IsPortDown(), !port.IsLinkDown())
+
+	prev := r.device.Port(port.Number())
+	wasUp := prev != nil && isPortUp(prev.Value())
+	if v.Reason() == openflow.PortDeleted {
+		if prev == nil {
+			return r.handler.OnPortStatus(f, w, v)
+		}
+		if wasUp {
+			r.sendPortEvent(port.Number(), false)
+		}
+		r.watcher.PortRemoved(prev)
+		r.device.removePort(port.Number())
+
+		return r.handler.OnPortStatus(f, w, v)
+	}
 	r.updatePort(v)
 
-	// Send port event
-	up := !port.IsPortDown() && !port.IsLinkDown()
-	r.sendPortEvent(port.Number(), up)
+	// Send port event only if the port is new or its state has been changed.
+	up := isPortUp(port)
+	if prev == nil || up != wasUp {
+		r.sendPortEvent(port.Number(), up)
+	}
 
 	// Is this an enabled port?
 	if up && r.device.isReady() {
@@ -343,11 +822,17 @@ func (r *session) OnPortStatus(f openflow.Factory, w transceiver.Writer, v openf
 }
 
 func (r *session) OnFlowRemoved(f openflow.Factory, w transceiver.Writer, v openflow.FlowRemoved) error {
-	logger.Debugf("FLOW_REMOVED is received (cookie=%v)", v.Cookie())
+	logger.Debugf("FLOW_REMOVED is received (device=%v, cookie=%v, reason=%v, duration=%vs, packets=%v, bytes=%v)", r.device.ID(), v.Cookie(), v.Reason(), v.DurationSec(), v.PacketCount(), v.ByteCount())
 
 	if !r.negotiated {
 		return errNotNegotiated
 	}
+	if err := r.device.conflicts.Removed(v); err != nil {
+		logger.Errorf("failed to forget the removed flow: %v", err)
+	}
+	if err := r.device.flowRemoved(v); err != nil {
+		logger.Errorf("failed to apply the lifetime of the removed flow: %v", err)
+	}
 
 	if err := r.listener.OnFlowRemoved(r.finder, v); err != nil {
 		logger.Errorf("error on OnFlowRemoved listeners: %v", err)
@@ -370,6 +855,10 @@ func isLLDP(e *protocol.Ethernet) bool {
 	return e.Type == 0x88CC
 }
 
+func isBDDP(e *protocol.Ethernet) bool {
+	return e.Type == bddpEtherType
+}
+
 func getLLDP(packet []byte) (*protocol.LLDP, error) {
 	lldp := new(protocol.LLDP)
 	if err := lldp.UnmarshalBinary(packet); err != nil {
@@ -446,29 +935,91 @@ func (r *session) handleLLDP(inPort *Port, ethernet *protocol.Ethernet) error {
 	return nil
 }
 
+// handleBDDP adds an indirect link if the BDDP packet has come from another switch through
+// the non-OpenFlow switches.
+func (r *session) handleBDDP(inPort *Port, ethernet *protocol.Ethernet) error {
+	lldp, err := getLLDP(ethernet.Payload)
+	if err != nil {
+		return err
+	}
+	deviceID, portNum, err := extractDeviceInfo(lldp)
+	if err != nil {
+		logger.Debug("ignoring a BDDP packet issued by an unknown device")
+		return nil
+	}
+	port, err := r.findNeighborPort(deviceID, portNum)
+	if err != nil {
+		logger.Debugf("ignoring a BDDP packet: %v", err)
+		return nil
+	}
+	// The packet has been flooded back to the sender.
+	if port.ID() == inPort.ID() {
+		return nil
+	}
+	r.watcher.DeviceLinkedIndirectly([2]*Port{inPort, port})
+
+	return nil
+}
+
+// handleLACP adds inPort to the link aggregation of the LACP actor. We never answer the LACPDU.
+func (r *session) handleLACP(inPort *Port, ethernet *protocol.Ethernet) error {
+	lacp, err := getLACP(ethernet.Payload)
+	if err != nil {
+		logger.Debugf("ignoring an invalid LACP packet: %v", err)
+		return nil
+	}
+	// Individual link that cannot be aggregated?
+	if lacp.Actor.State&lacpStateAggregation == 0 {
+		logger.Debugf("ignoring a LACP packet of an individual link: %v", inPort.ID())
+		return nil
+	}
+	r.watcher.PortAggregated(inPort, aggregationKey(lacp.Actor))
+
+	return nil
+}
+
 func (r *session) OnPacketIn(f openflow.Factory, w transceiver.Writer, v openflow.PacketIn) error {
 	if !r.negotiated {
 		return errNotNegotiated
 	}
+	// PACKET_IN from an auxiliary connection belongs to its main device.
+	device := r.eventDevice()
+	packetInsReceived.Inc(device.ID())
 	logger.Debugf("PACKET_IN is received (device=%v, inport=%v, reason=%v, tableID=%v, cookie=%v)",
-		r.device.ID(), v.InPort(), v.Reason(), v.TableID(), v.Cookie())
+		device.ID(), v.InPort(), v.Reason(), v.TableID(), v.Cookie())
 
 	// Do nothing if the ingress device is not yet ready.
-	if r.device.isReady() == false {
-		logger.Debugf("ignoring PACKET_IN: device is not ready: device=%v, inPort=%v", r.device.ID(), v.InPort())
+	if device.isReady() == false {
+		logger.Debugf("ignoring PACKET_IN: device is not ready: device=%v, inPort=%v", device.ID(), v.InPort())
 		// Drop the incoming packet.
 		return nil
 	}
+	// Block the table-miss packets for a while if the device floods us with PACKET_INs.
+	// The auxiliary connections share the flood threshold of their main connection.
+	if device.session.packetInGate.overloaded() {
+		policy := device.session.packetInGate.policy
+		logger.Warningf("too many PACKET_INs from %v: blocking the table-miss packets for %v", device.ID(), policy.floodBlockTime)
+		packetInFloods.Inc(device.ID())
+		if err := device.blockTableMiss(policy.floodBlockTime); err != nil {
+			logger.Errorf("failed to block the table-miss packets of %v: %v", device.ID(), err)
+		}
+	}
 
 	ethernet, err := getEthernet(v.Data())
 	if err != nil {
 		return err
 	}
 	logger.Debugf("PACKET_IN ethernet: src=%v, dst=%v, type=%v", ethernet.SrcMAC, ethernet.DstMAC, ethernet.Type)
+	// The probes of the self-test come from the controller port, so check them before the ingress port.
+	if isSelfTestProbe(ethernet) {
+		device.deliverSelfTestProbe(ethernet.Payload)
+		return nil
+	}
 
-	inPort := r.device.Port(v.InPort())
+	inPort := device.Port(v.InPort())
 	if inPort == nil {
-		logger.Errorf("failed to find a port: deviceID=%v, portNum=%v, so ignore PACKET_IN..", r.device.ID(), v.InPort())
+		key := fmt.Sprintf("unknown port %v/%v", device.ID(), v.InPort())
+		rateLogger.Errorf(key, "failed to find a port: deviceID=%v, portNum=%v, so ignore PACKET_IN..", device.ID(), v.InPort())
 		return nil
 	}
 	// Process LLDP, and then add an edge among two switches. This should be executed
@@ -476,20 +1027,44 @@ func (r *session) OnPacketIn(f openflow.Factory, w transceiver.Writer, v openflo
 	if isLLDP(ethernet) {
 		return r.handleLLDP(inPort, ethernet)
 	}
+	if isBDDP(ethernet) {
+		return r.handleBDDP(inPort, ethernet)
+	}
+	// LACPDUs are link-local, so they should not be passed to the applications.
+	if isLACP(ethernet) {
+		return r.handleLACP(inPort, ethernet)
+	}
 	// Do nothing if the ingress port is an edge between switches and is disabled by STP.
 	if r.finder.IsEdge(inPort) && !r.finder.IsEnabledBySTP(inPort) {
-		logger.Debugf("ignoring PACKET_IN from %v:%v by STP", r.device.ID(), v.InPort())
+		logger.Debugf("ignoring PACKET_IN from %v:%v by STP", device.ID(), v.InPort())
+		return nil
+	}
+	// Do nothing if the packet-in policy of this reason does not allow it. The auxiliary
+	// connections share the rate limit of their main connection.
+	if !device.session.packetInGate.admit(v.Reason()) {
+		logger.Debugf("ignoring PACKET_IN from %v:%v by the packet-in policy (reason=%v)", device.ID(), v.InPort(), v.Reason())
 		return nil
 	}
 	// Call specific version handler
 	if err := r.handler.OnPacketIn(f, w, v); err != nil {
 		return err
 	}
+	if r.packetInWorkers == nil {
+		return r.listener.OnPacketIn(r.finder, inPort, ethernet)
+	}
+	r.packetInWorkers.dispatch(inPort, func() {
+		if err := r.listener.OnPacketIn(r.finder, inPort, ethernet); err != nil {
+			rateLogger.Errorf("packet-in "+device.ID(), "failed to process a PACKET_IN from %v: %v", inPort.ID(), err)
+		}
+	})
 
-	return r.listener.OnPacketIn(r.finder, inPort, ethernet)
+	return nil
 }
 
 func (r *session) OnBarrierReply(f openflow.Factory, w transceiver.Writer, v openflow.BarrierReply) error {
+	if r.audit != nil {
+		r.audit.Replied(v.TransactionID(), nil)
+	}
 	if !r.negotiated {
 		return errNotNegotiated
 	}
@@ -498,18 +1073,67 @@ func (r *session) OnBarrierReply(f openflow.Factory, w transceiver.Writer, v ope
 	return r.handler.OnBarrierReply(f, w, v)
 }
 
+func (r *session) OnRoleReply(f openflow.Factory, w transceiver.Writer, v openflow.RoleReply) error {
+	if !r.negotiated {
+		return errNotNegotiated
+	}
+	logger.Infof("ROLE_REPLY is received (device=%v, role=%v, generation_id=%v)", r.device.ID(), v.Role(), v.GenerationID())
+	r.device.setRole(v.Role(), v.GenerationID())
+
+	return r.handler.OnRoleReply(f, w, v)
+}
+
+func (r *session) OnExperimenter(f openflow.Factory, w transceiver.Writer, v openflow.Experimenter) error {
+	if !r.negotiated {
+		return errNotNegotiated
+	}
+
+	payload, err := openflow.DecodeExperimenter(v)
+	if err != nil {
+		logger.Debugf("failed to decode EXPERIMENTER (device=%v, experimenter=0x%08x, type=%v): %v", r.device.ID(), v.Experimenter(), v.ExpType(), err)
+	} else {
+		logger.Debugf("EXPERIMENTER is received (device=%v, experimenter=0x%08x, type=%v): %+v", r.device.ID(), v.Experimenter(), v.ExpType(), payload)
+	}
+
+	return r.handler.OnExperimenter(f, w, v)
+}
+
 func (r *session) Run(ctx context.Context) {
+	stopWatchdog := r.runHandshakeWatchdog(ctx)
 	stopExplorer := r.runDeviceExplorer(ctx)
 	logger.Debugf("started a new device explorer")
+	stopCollector := r.runFlowStatsCollector(ctx)
+	logger.Debugf("started a new flow stats collector")
+	stopPoller := r.runPortStatsPoller(ctx)
 
 	if err := r.transceiver.Run(ctx); err != nil {
 		logger.Errorf("openflow transceiver is unexpectedly closed: %v", err)
 	}
-	logger.Infof("disconnected device (DPID=%v)", r.device.ID())
+	if main := r.mainDevice(); main != nil {
+		logger.Infof("disconnected auxiliary connection (DPID=%v)", main.ID())
+		main.removeAuxSession(r)
+	} else {
+		logger.Infof("disconnected device (DPID=%v)", r.device.ID())
+		if r.device.isReady() == false {
+			handshakeFailures.Inc()
+			r.negotiation.failed(r.remoteAddr)
+		}
+	}
+	// Release the handshake slot if the session is closed before the handshake is completed.
+	r.handshakeDone()
+	r.admission.remove(r)
 
+	stopWatchdog()
 	stopExplorer()
+	stopCollector()
+	stopPoller()
 	r.transceiver.Close()
+	if r.audit != nil {
+		r.audit.Close()
+	}
 	r.device.Close()
+	// The auxiliary connections are useless without the main connection.
+	r.device.disconnectAux()
 	if r.device.isReady() {
 		if err := r.listener.OnDeviceDown(r.finder, r.device); err != nil {
 			logger.Errorf("OnDeviceDown: %v", err)
@@ -518,12 +1142,39 @@ func (r *session) Run(ctx context.Context) {
 	}
 }
 
+// runHandshakeWatchdog closes the connection if the device does not complete the
+// handshake, i.e., does not send its first FEATURES_REPLY, within the handshake timeout.
+func (r *session) runHandshakeWatchdog(ctx context.Context) context.CancelFunc {
+	subCtx, canceller := context.WithCancel(ctx)
+
+	go func() {
+		timer := r.clock.NewTimer(r.handshakeTimeout)
+		defer timer.Stop()
+
+		select {
+		case <-subCtx.Done():
+			return
+		case <-timer.C():
+			if r.device.isReady() || r.mainDevice() != nil || r.admission.isPending(r) {
+				return
+			}
+			logger.Warningf("closing the connection that has not completed the handshake in %v", r.handshakeTimeout)
+			if err := r.transceiver.Close(); err != nil {
+				logger.Errorf("failed to close the transceiver: %v", err)
+			}
+		}
+	}()
+
+	return canceller
+}
+
 func (r *session) runDeviceExplorer(ctx context.Context) context.CancelFunc {
 	subCtx, canceller := context.WithCancel(ctx)
 
 	go func() {
 		// Note taht ticker will deliver the first tick after specified duration.
-		ticker := time.Tick(deviceExplorerInterval)
+		ticker := r.clock.NewTicker(deviceExplorerInterval)
+		defer ticker.Stop()
 
 		// Infinite loop.
 		for {
@@ -532,11 +1183,15 @@ func (r *session) runDeviceExplorer(ctx context.Context) context.CancelFunc {
 			case <-subCtx.Done():
 				logger.Debugf("terminating the device explorer: deviceID=%v", r.device.ID())
 				return
-			case <-ticker:
+			case <-ticker.C():
 				if r.device.isReady() == false {
 					logger.Debug("skip to execute the device explorer due to incomplete device status")
 					continue
 				}
+				// The LLDP packets cannot be sent by a SLAVE.
+				if r.mastership.isSlave() {
+					continue
+				}
 				logger.Debugf("executing the device explorer: deviceID=%v", r.device.ID())
 
 				// Query switch ports information. LLDP will also be delivered to the ports in the query reply handlers.
@@ -548,8 +1203,8 @@ func (r *session) runDeviceExplorer(ctx context.Context) context.CancelFunc {
 						continue
 					}
 					logger.Debugf("sent a FeaturesRequest packet to %v", r.device.ID())
-				case openflow.OF13_VERSION:
-					// OF13 provides ports information in the PortDescriptionReply packet.
+				case openflow.OF13_VERSION, openflow.OF14_VERSION:
+					// OF13 and OF14 provide ports information in the PortDescriptionReply packet.
 					if err := sendPortDescriptionRequest(r.device.Factory(), r.device.Writer()); err != nil {
 						logger.Errorf("failed to send a port description request: %v", err)
 						continue
@@ -565,10 +1220,135 @@ func (r *session) runDeviceExplorer(ctx context.Context) context.CancelFunc {
 	return canceller
 }
 
+func (r *session) runFlowStatsCollector(ctx context.Context) context.CancelFunc {
+	subCtx, canceller := context.WithCancel(ctx)
+
+	go func() {
+		ticker := r.clock.NewTicker(flowStatsInterval)
+		defer ticker.Stop()
+
+		// Infinite loop.
+		for {
+			select {
+			case <-subCtx.Done():
+				logger.Debugf("terminating the flow stats collector: deviceID=%v", r.device.ID())
+				return
+			case <-ticker.C():
+				if r.device.isReady() == false {
+					continue
+				}
+				// The reply will be delivered to OnFlowStatsReply.
+				if err := sendFlowStatsRequest(r.device.Factory(), r.device.Writer()); err != nil {
+					logger.Errorf("failed to send a flow stats request: %v", err)
+					continue
+				}
+				logger.Debugf("sent a FlowStatsRequest packet to %v", r.device.ID())
+			}
+		}
+	}()
+
+	return canceller
+}
+
+func (r *session) runPortStatsPoller(ctx context.Context) context.CancelFunc {
+	subCtx, canceller := context.WithCancel(ctx)
+	if r.portStatsInterval == 0 {
+		// Polling is disabled.
+		return canceller
+	}
+	logger.Debugf("started a new port stats poller")
+
+	go func() {
+		ticker := r.clock.NewTicker(r.portStatsInterval)
+		defer ticker.Stop()
+
+		// Infinite loop.
+		for {
+			select {
+			case <-subCtx.Done():
+				logger.Debugf("terminating the port stats poller: deviceID=%v", r.device.ID())
+				return
+			case <-ticker.C():
+				if r.device.isReady() == false {
+					continue
+				}
+				// The reply will be delivered to OnPortStatsReply.
+				if err := sendPortStatsRequest(r.device.Factory(), r.device.Writer()); err != nil {
+					logger.Errorf("failed to send a port stats request: %v", err)
+					continue
+				}
+				logger.Debugf("sent a PortStatsRequest packet to %v", r.device.ID())
+				// The reply will be delivered to OnQueueStatsReply.
+				if err := sendQueueStatsRequest(r.device.Factory(), r.device.Writer()); err != nil {
+					logger.Errorf("failed to send a queue stats request: %v", err)
+					continue
+				}
+			}
+		}
+	}()
+
+	return canceller
+}
+
+// sendLimiter returns the send rate limiter of the device. The auxiliary connections share
+// the one of the main connection.
+func (r *session) sendLimiter() *sendLimiter {
+	if main := r.mainDevice(); main != nil {
+		return main.session.limiter
+	}
+
+	return r.limiter
+}
+
+// Write sends msg to the device. It returns ErrThrottled if msg is a FLOW_MOD or PACKET_OUT
+// and the send rate limit of the device is exceeded.
 func (r *session) Write(msg encoding.BinaryMarshaler) error {
+	if isStateChanging(msg) && r.mastership.isSlave() {
+		return ErrSlave
+	}
+	if !r.sendLimiter().allow(msg) {
+		return ErrThrottled
+	}
+
 	return r.transceiver.Write(msg)
 }
 
+func (r *session) RTT() time.Duration {
+	return r.transceiver.RTT()
+}
+
+func (r *session) SendAndWait(ctx context.Context, req transceiver.Request) (openflow.Header, error) {
+	return r.transceiver.SendAndWait(ctx, req)
+}
+
+func (r *session) SendAndConfirm(ctx context.Context, req transceiver.Request) error {
+	if isStateChanging(req) && r.mastership.isSlave() {
+		return ErrSlave
+	}
+	if !r.sendLimiter().allow(req) {
+		return ErrThrottled
+	}
+
+	return r.transceiver.SendAndConfirm(ctx, req)
+}
+
+// SendBundle returns ErrThrottled without sending anything if the FLOW_MODs and PACKET_OUTs
+// in reqs exceed the send rate limit of the device as a whole.
+func (r *session) SendBundle(ctx context.Context, reqs []transceiver.Request) error {
+	msgs := make([]encoding.BinaryMarshaler, len(reqs))
+	for i, req := range reqs {
+		if isStateChanging(req) && r.mastership.isSlave() {
+			return ErrSlave
+		}
+		msgs[i] = req
+	}
+	if !r.sendLimiter().allow(msgs...) {
+		return ErrThrottled
+	}
+
+	return r.transceiver.SendBundle(ctx, reqs)
+}
+
 func sendHello(f openflow.Factory, w transceiver.Writer) error {
 	msg, err := f.NewHello()
 	if err != nil {
@@ -578,13 +1358,13 @@ func sendHello(f openflow.Factory, w transceiver.Writer) error {
 	return w.Write(msg)
 }
 
-func sendSetConfig(f openflow.Factory, w transceiver.Writer) error {
+func sendSetConfig(f openflow.Factory, w transceiver.Writer, missSendLen uint16) error {
 	msg, err := f.NewSetConfig()
 	if err != nil {
 		return err
 	}
 	msg.SetFlags(openflow.FragNormal)
-	msg.SetMissSendLength(0xFFFF)
+	msg.SetMissSendLength(missSendLen)
 
 	return w.Write(msg)
 }
@@ -607,6 +1387,31 @@ func sendDescriptionRequest(f openflow.Factory, w transceiver.Writer) error {
 	return w.Write(msg)
 }
 
+func sendFlowStatsRequest(f openflow.Factory, w transceiver.Writer) error {
+	msg, err := f.NewFlowStatsRequest()
+	if err != nil {
+		return err
+	}
+	// All flows in all tables.
+	msg.SetTableID(0xFF)
+	match, err := f.NewMatch()
+	if err != nil {
+		return err
+	}
+	msg.SetMatch(match)
+
+	return w.Write(msg)
+}
+
+func sendPortStatsRequest(f openflow.Factory, w transceiver.Writer) error {
+	msg, err := f.NewPortStatsRequest()
+	if err != nil {
+		return err
+	}
+
+	return w.Write(msg)
+}
+
 func sendBarrierRequest(f openflow.Factory, w transceiver.Writer) error {
 	msg, err := f.NewBarrierRequest()
 	if err != nil {
@@ -625,6 +1430,24 @@ func sendPortDescriptionRequest(f openflow.Factory, w transceiver.Writer) error
 	return w.Write(msg)
 }
 
+func sendQueueStatsRequest(f openflow.Factory, w transceiver.Writer) error {
+	msg, err := f.NewQueueStatsRequest()
+	if err != nil {
+		return err
+	}
+
+	return w.Write(msg)
+}
+
+func sendTableFeaturesRequest(f openflow.Factory, w transceiver.Writer) error {
+	msg, err := f.NewTableFeaturesRequest()
+	if err != nil {
+		return err
+	}
+
+	return w.Write(msg)
+}
+
 // setARPSender installs a flow that sends all ARP packets to the controller.
 func setARPSender(f openflow.Factory, w transceiver.Writer) error {
 	// Permanent flow.
@@ -637,6 +1460,12 @@ func setLLDPSender(f openflow.Factory, w transceiver.Writer) error {
 	return setSpecialFlow(f, w, 0x88CC /* LLDP */, 100, 0, 0, false)
 }
 
+// setBDDPSender installs a flow that sends all BDDP packets to the controller.
+func setBDDPSender(f openflow.Factory, w transceiver.Writer) error {
+	// Permanent flow.
+	return setSpecialFlow(f, w, bddpEtherType, 100, 0, 0, false)
+}
+
 // setTemporaryDrop installs a temporary flow that drops all the packets.
 func setTemporaryDrop(f openflow.Factory, w transceiver.Writer) error {
 	// Temporary flow that will be removed after a few seconds.
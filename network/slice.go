@@ -0,0 +1,308 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/superkkt/cherry/openflow"
+
+	"github.com/pkg/errors"
+	"github.com/superkkt/viper"
+)
+
+var (
+	ErrOutOfSlice = errors.New("flow is out of the slice")
+)
+
+// Slice is a virtual partition of the physical network that is defined by a set of
+// VLANs, a set of switch ports, or both of them. The applications assigned to a slice
+// only see the packets and ports in the slice, and the flows programmed on behalf of
+// a slice should match only the traffic of the slice.
+type Slice struct {
+	Name string
+	// VLAN IDs of the slice. Empty means any VLAN.
+	VLANs map[uint16]bool
+	// Switch ports of the slice keyed by the DPID. Empty means all the ports.
+	Ports map[string]map[uint32]bool
+	// Names of the applications that are restricted to this slice.
+	Apps []string
+}
+
+// ParseSlice returns a slice whose name is name. vlans is a comma separated list of VLAN
+// IDs, ports is a comma separated list of DPID:port pairs, and apps is a comma separated
+// list of application names.
+func ParseSlice(name, vlans, ports, apps string) (*Slice, error) {
+	v := &Slice{
+		Name:  name,
+		VLANs: make(map[uint16]bool),
+		Ports: make(map[string]map[uint32]bool),
+	}
+
+	for _, s := range splitList(vlans) {
+		id, err := strconv.ParseUint(s, 10, 16)
+		if err != nil || id > 4095 {
+			return nil, fmt.Errorf("invalid VLAN ID: %v", s)
+		}
+		v.VLANs[uint16(id)] = true
+	}
+	for _, s := range splitList(ports) {
+		t := strings.Split(s, ":")
+		if len(t) != 2 {
+			return nil, fmt.Errorf("invalid DPID:port: %v", s)
+		}
+		dpid, err := strconv.ParseUint(t[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DPID: %v", s)
+		}
+		port, err := strconv.ParseUint(t[1], 10, 32)
+		if err != nil || port == 0 {
+			return nil, fmt.Errorf("invalid port number: %v", s)
+		}
+		id := strconv.FormatUint(dpid, 10)
+		if v.Ports[id] == nil {
+			v.Ports[id] = make(map[uint32]bool)
+		}
+		v.Ports[id][uint32(port)] = true
+	}
+	if len(v.VLANs) == 0 && len(v.Ports) == 0 {
+		return nil, fmt.Errorf("slice %v has neither VLANs nor ports", name)
+	}
+	v.Apps = splitList(apps)
+
+	return v, nil
+}
+
+func splitList(s string) []string {
+	result := []string{}
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); len(v) > 0 {
+			result = append(result, v)
+		}
+	}
+
+	return result
+}
+
+// LoadSlices returns the slices defined in the slice section of the config file. An
+// application cannot belong to more than one slice.
+func LoadSlices() ([]*Slice, error) {
+	names := []string{}
+	for name := range viper.GetStringMap("slice") {
+		names = append(names, name)
+	}
+	// Keep the order regardless of the map iteration.
+	sort.Strings(names)
+
+	result := []*Slice{}
+	apps := make(map[string]string)
+	for _, name := range names {
+		key := "slice." + name
+		s, err := ParseSlice(name, viper.GetString(key+".vlans"), viper.GetString(key+".ports"), viper.GetString(key+".applications"))
+		if err != nil {
+			return nil, err
+		}
+		for _, app := range s.Apps {
+			app = strings.ToUpper(app)
+			if other, ok := apps[app]; ok {
+				return nil, fmt.Errorf("application %v belongs to both slice %v and %v", app, other, name)
+			}
+			apps[app] = name
+		}
+		result = append(result, s)
+	}
+
+	return result, nil
+}
+
+// findSlice returns the slice whose name is name, case-insensitively, or nil if there is no such slice.
+func findSlice(slices []*Slice, name string) *Slice {
+	for _, v := range slices {
+		if strings.EqualFold(v.Name, name) {
+			return v
+		}
+	}
+
+	return nil
+}
+
+func (r *Slice) String() string {
+	vlans := []string{}
+	for id := range r.VLANs {
+		vlans = append(vlans, strconv.Itoa(int(id)))
+	}
+	sort.Strings(vlans)
+	ports := []string{}
+	for dpid, p := range r.Ports {
+		for num := range p {
+			ports = append(ports, fmt.Sprintf("%v:%v", dpid, num))
+		}
+	}
+	sort.Strings(ports)
+
+	return fmt.Sprintf("Slice %v: VLANs=%v, Ports=%v, Apps=%v", r.Name, vlans, ports, r.Apps)
+}
+
+// HasDevice returns whether the device whose DPID is dpid has any port in the slice.
+func (r *Slice) HasDevice(dpid string) bool {
+	if len(r.Ports) == 0 {
+		return true
+	}
+
+	return len(r.Ports[dpid]) > 0
+}
+
+// HasPort returns whether the port number port of the device whose DPID is dpid is in the slice.
+func (r *Slice) HasPort(dpid string, port uint32) bool {
+	if len(r.Ports) == 0 {
+		return true
+	}
+
+	return r.Ports[dpid][port]
+}
+
+// HasVLAN returns whether the VLAN whose ID is id is in the slice.
+func (r *Slice) HasVLAN(id uint16) bool {
+	if len(r.VLANs) == 0 {
+		return true
+	}
+
+	return r.VLANs[id]
+}
+
+// Contains returns whether a packet tagged with vlanID that has been received from
+// ingress belongs to the slice.
+func (r *Slice) Contains(ingress *Port, vlanID uint16) bool {
+	return r.HasPort(ingress.Device().ID(), ingress.Number()) && r.HasVLAN(vlanID)
+}
+
+// ValidateFlow returns ErrOutOfSlice if a flow, whose match is match and output port is out,
+// on the device whose DPID is dpid may match or emit any packet outside the slice. A nil out
+// means the flow drops the packets.
+func (r *Slice) ValidateFlow(dpid string, match openflow.Match, out *openflow.OutPort) error {
+	if !r.HasDevice(dpid) {
+		return errors.Wrapf(ErrOutOfSlice, "device %v is not in slice %v", dpid, r.Name)
+	}
+	if len(r.VLANs) > 0 {
+		wildcard, id := match.VLANID()
+		if wildcard || !r.VLANs[id] {
+			return errors.Wrapf(ErrOutOfSlice, "flow should match a VLAN of slice %v", r.Name)
+		}
+	}
+	if len(r.Ports) == 0 {
+		return nil
+	}
+
+	wildcard, inPort := match.InPort()
+	if wildcard || inPort.IsController() || !r.HasPort(dpid, inPort.Value()) {
+		return errors.Wrapf(ErrOutOfSlice, "flow should match an ingress port of slice %v", r.Name)
+	}
+	if out == nil || out.IsController() || out.IsInPort() || out.IsNone() {
+		return nil
+	}
+	// Flooding may emit the packets to the ports outside the slice.
+	if out.IsFlood() || out.IsAll() || out.IsTable() || !r.HasPort(dpid, out.Value()) {
+		return errors.Wrapf(ErrOutOfSlice, "output port %v is not in slice %v", out, r.Name)
+	}
+
+	return nil
+}
+
+// ValidateFlowMod is same as ValidateFlow except that it checks all the output actions of flow.
+// The flows that forward the packets to a group are rejected because the buckets of the group
+// are not known here.
+func (r *Slice) ValidateFlowMod(dpid string, flow openflow.FlowMod) error {
+	match := flow.FlowMatch()
+	if match == nil {
+		return errors.Wrapf(ErrOutOfSlice, "flow should have a match in slice %v", r.Name)
+	}
+
+	outputs := 0
+	if inst := flow.FlowInstruction(); inst != nil {
+		for _, action := range inst.Actions() {
+			if ok, _ := action.Group(); ok {
+				return errors.Wrapf(ErrOutOfSlice, "flow of slice %v cannot forward the packets to a group", r.Name)
+			}
+			out := action.OutPort()
+			if out == (openflow.OutPort{}) {
+				continue
+			}
+			if err := r.ValidateFlow(dpid, match, &out); err != nil {
+				return err
+			}
+			outputs++
+		}
+	}
+	if outputs > 0 {
+		return nil
+	}
+
+	// The flow drops the packets.
+	return r.ValidateFlow(dpid, match, nil)
+}
+
+// sliceIndex confines the flows owned by the applications of the slices to their slices. It is
+// not changed after it is made, so it can be shared by the devices without a lock. A nil index
+// has no slice.
+type sliceIndex struct {
+	// Key is the cookie owner ID of the application.
+	slices map[uint16]*Slice
+}
+
+func newSliceIndex(slices []*Slice) *sliceIndex {
+	v := &sliceIndex{
+		slices: make(map[uint16]*Slice),
+	}
+	for _, s := range slices {
+		for _, app := range s.Apps {
+			v.slices[CookieOwnerID(app)] = s
+		}
+	}
+
+	return v
+}
+
+// Check returns ErrOutOfSlice if flow, which adds or modifies flows owned by an application of a
+// slice on the device whose DPID is dpid, may match or emit any packet outside the slice.
+func (r *sliceIndex) Check(dpid string, flow openflow.FlowMod) error {
+	if r == nil || len(r.slices) == 0 {
+		return nil
+	}
+	switch flow.Command() {
+	case openflow.FlowAdd, openflow.FlowModify, openflow.FlowModifyStrict:
+	default:
+		return nil
+	}
+	owner, ok := CookieOwner(flow.Cookie())
+	if !ok {
+		return nil
+	}
+	s, ok := r.slices[owner]
+	if !ok {
+		return nil
+	}
+
+	return s.ValidateFlowMod(dpid, flow)
+}
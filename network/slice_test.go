@@ -0,0 +1,189 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"testing"
+
+	"github.com/superkkt/cherry/openflow"
+	"github.com/superkkt/cherry/openflow/of13"
+
+	"github.com/pkg/errors"
+)
+
+func TestParseSlice(t *testing.T) {
+	s, err := ParseSlice("tenant1", "10, 20", "1:1, 1:2, 2:5", "L2Switch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.HasVLAN(10) || s.HasVLAN(30) {
+		t.Fatalf("unexpected VLANs: %v", s)
+	}
+	if !s.HasPort("1", 2) || s.HasPort("1", 5) || !s.HasDevice("2") || s.HasDevice("3") {
+		t.Fatalf("unexpected ports: %v", s)
+	}
+	if len(s.Apps) != 1 || s.Apps[0] != "L2Switch" {
+		t.Fatalf("unexpected applications: %v", s.Apps)
+	}
+
+	for _, v := range [][2]string{{"", ""}, {"4096", ""}, {"", "1"}, {"", "1:0"}, {"", "x:1"}} {
+		if _, err := ParseSlice("invalid", v[0], v[1], ""); err == nil {
+			t.Fatalf("expected an error for vlans=%q, ports=%q", v[0], v[1])
+		}
+	}
+}
+
+func TestSliceValidateFlow(t *testing.T) {
+	s, err := ParseSlice("tenant1", "10", "1:1, 1:2", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := of13.NewFactory()
+	newMatch := func(vlanID uint16, inPort uint32) openflow.Match {
+		match, err := f.NewMatch()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if vlanID != 0 {
+			match.SetVLANID(vlanID)
+		}
+		if inPort != 0 {
+			port := openflow.NewInPort()
+			port.SetValue(inPort)
+			match.SetInPort(port)
+		}
+		return match
+	}
+	outPort := func(num uint32) *openflow.OutPort {
+		port := openflow.NewOutPort()
+		if num != 0 {
+			port.SetValue(num)
+		}
+		return &port
+	}
+
+	if err := s.ValidateFlow("1", newMatch(10, 1), outPort(2)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Drop.
+	if err := s.ValidateFlow("1", newMatch(10, 2), nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	invalid := []struct {
+		dpid  string
+		match openflow.Match
+		out   *openflow.OutPort
+	}{
+		{"2", newMatch(10, 1), outPort(2)}, // Unknown device
+		{"1", newMatch(0, 1), outPort(2)},  // Any VLAN
+		{"1", newMatch(20, 1), outPort(2)}, // Other VLAN
+		{"1", newMatch(10, 0), outPort(2)}, // Any ingress port
+		{"1", newMatch(10, 3), outPort(2)}, // Other ingress port
+		{"1", newMatch(10, 1), outPort(3)}, // Other output port
+		{"1", newMatch(10, 1), outPort(0)}, // Flood
+	}
+	for i, v := range invalid {
+		if err := s.ValidateFlow(v.dpid, v.match, v.out); err == nil {
+			t.Fatalf("expected an error for the flow %v", i)
+		}
+	}
+}
+
+func TestSliceIndex(t *testing.T) {
+	s, err := ParseSlice("tenant1", "10", "1:1, 1:2", "SliceTester")
+	if err != nil {
+		t.Fatal(err)
+	}
+	index := newSliceIndex([]*Slice{s})
+	f := of13.NewFactory()
+	newFlow := func(owner string, inPort uint32, actions ...openflow.Action) openflow.FlowMod {
+		match, err := f.NewMatch()
+		if err != nil {
+			t.Fatal(err)
+		}
+		match.SetVLANID(10)
+		port := openflow.NewInPort()
+		port.SetValue(inPort)
+		match.SetInPort(port)
+		flow, err := f.NewFlowMod(openflow.FlowAdd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		flow.SetCookie(NewCookie(owner, 0))
+		flow.SetFlowMatch(match)
+		if len(actions) > 0 {
+			inst, err := f.NewInstruction()
+			if err != nil {
+				t.Fatal(err)
+			}
+			inst.ApplyAction(actions[0])
+			flow.SetFlowInstruction(inst)
+		}
+		return flow
+	}
+	newAction := func(out uint32, group uint32) openflow.Action {
+		action, err := f.NewAction()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if group != 0 {
+			action.SetGroup(group)
+			return action
+		}
+		port := openflow.NewOutPort()
+		port.SetValue(out)
+		action.SetOutPort(port)
+		return action
+	}
+
+	tests := []struct {
+		flow  openflow.FlowMod
+		valid bool
+	}{
+		{newFlow("SliceTester", 1, newAction(2, 0)), true},
+		{newFlow("SliceTester", 1), true},                   // Drop
+		{newFlow("SliceTester", 3, newAction(2, 0)), false}, // Other ingress port
+		{newFlow("SliceTester", 1, newAction(3, 0)), false}, // Other output port
+		{newFlow("SliceTester", 1, newAction(0, 1)), false}, // Group
+		{newFlow("OtherTester", 3, newAction(3, 0)), true},  // Not in any slice
+		{newFlow("", 3, newAction(3, 0)), true},             // Not owned
+	}
+	for i, v := range tests {
+		err := index.Check("1", v.flow)
+		if v.valid && err != nil {
+			t.Fatalf("unexpected error for the flow %v: %v", i, err)
+		}
+		if !v.valid && errors.Cause(err) != ErrOutOfSlice {
+			t.Fatalf("expected ErrOutOfSlice for the flow %v, got %v", i, err)
+		}
+	}
+
+	// Deleting flows is not confined.
+	flow, err := f.NewFlowMod(openflow.FlowDelete)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flow.SetCookie(NewCookie("SliceTester", 0))
+	if err := index.Check("1", flow); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ant0ine/go-json-rest/rest"
+)
+
+// Snapshot is the state of the network seen by the controller at a moment, which is useful for
+// debugging and for warming up a cold-standby controller. A snapshot can be posted to the import
+// API as it is: the host locations are imported, and the other states are ignored because they
+// are rebuilt from the switches.
+type Snapshot struct {
+	Created time.Time        `json:"created"`
+	Devices []DeviceSnapshot `json:"devices"`
+	Links   []LinkInfo       `json:"links"`
+	// Locations of the registered hosts that have been discovered.
+	Hosts []HostLocationParam `json:"hosts"`
+}
+
+type DeviceSnapshot struct {
+	DeviceInfo
+	Ports []DevicePort `json:"ports"`
+	// Flows last collected from the device.
+	Flows []DeviceFlow `json:"flows"`
+	// FlowsCollected is the time when the flows were collected, or null if not yet collected.
+	FlowsCollected *time.Time `json:"flows_collected"`
+}
+
+func (r *Controller) snapshot() (Snapshot, error) {
+	s := Snapshot{
+		Created: r.clock.Now(),
+		Devices: []DeviceSnapshot{},
+		Links:   r.linkInfos(),
+		Hosts:   []HostLocationParam{},
+	}
+
+	for _, d := range r.topo.Devices() {
+		if d.isReady() == false {
+			continue
+		}
+		flows, collected := newDeviceFlows(d)
+		s.Devices = append(s.Devices, DeviceSnapshot{
+			DeviceInfo:     newDeviceInfo(d),
+			Ports:          newDevicePorts(d),
+			Flows:          flows,
+			FlowsCollected: collected,
+		})
+	}
+
+	hosts, err := r.db.Hosts()
+	if err != nil {
+		return Snapshot{}, err
+	}
+	for _, h := range hosts {
+		mac, err := net.ParseMAC(h.MAC)
+		if err != nil {
+			continue
+		}
+		node, status, err := r.topo.Node(mac)
+		if err != nil {
+			return Snapshot{}, err
+		}
+		if status != LocationDiscovered {
+			continue
+		}
+		s.Hosts = append(s.Hosts, HostLocationParam{
+			MAC: h.MAC,
+			// Without the network mask.
+			IP:   strings.Split(h.IP, "/")[0],
+			DPID: node.Port().Device().ID(),
+			Port: node.Port().Number(),
+		})
+	}
+
+	return s, nil
+}
+
+// exportSnapshot dumps the devices, ports, links, host locations, and flows of the network.
+func (r *Controller) exportSnapshot(w rest.ResponseWriter, req *rest.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	s, err := r.snapshot()
+	if err != nil {
+		logger.Errorf("failed to take a snapshot: %v", err)
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.WriteJson(&s)
+}
@@ -0,0 +1,319 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/ant0ine/go-json-rest/rest"
+)
+
+// SnapshotDiff is what has changed in the network from a snapshot (before) to another (after).
+// The statistics, such as the packet counters of the flows, are not compared.
+type SnapshotDiff struct {
+	Before         time.Time    `json:"before"`
+	After          time.Time    `json:"after"`
+	AddedDevices   []string     `json:"added_devices"`
+	RemovedDevices []string     `json:"removed_devices"`
+	Ports          []PortChange `json:"ports"`
+	AddedLinks     []LinkInfo   `json:"added_links"`
+	RemovedLinks   []LinkInfo   `json:"removed_links"`
+	Hosts          []HostMove   `json:"hosts"`
+	Flows          []FlowChange `json:"flows"`
+}
+
+// PortChange is a port of a device that exists in both of the snapshots. Before is null if the
+// port has been added, and After is null if the port has been removed.
+type PortChange struct {
+	DPID   string      `json:"dpid"`
+	Number uint32      `json:"number"`
+	Before *DevicePort `json:"before"`
+	After  *DevicePort `json:"after"`
+}
+
+// HostMove is a host whose location has changed. Before or After is null if the host was not
+// discovered in the snapshot.
+type HostMove struct {
+	MAC    string             `json:"mac"`
+	IP     string             `json:"ip"`
+	Before *HostLocationParam `json:"before"`
+	After  *HostLocationParam `json:"after"`
+}
+
+// FlowChange is a flow of a device that exists in both of the snapshots. The flows are identified
+// by their table IDs, priorities, and match fields as the switches do. Before is null if the flow
+// has been added, and After is null if the flow has been removed.
+type FlowChange struct {
+	DPID     string `json:"dpid"`
+	TableID  uint8  `json:"table_id"`
+	Priority uint16 `json:"priority"`
+	Match    string `json:"match"`
+	// Owner is the application that owns the flow after the change, or before the change if
+	// the flow has been removed.
+	Owner  string      `json:"owner,omitempty"`
+	Before *DeviceFlow `json:"before"`
+	After  *DeviceFlow `json:"after"`
+}
+
+type SnapshotDiffParam struct {
+	Before Snapshot `json:"before"`
+	// After is the live state of the network if it is null.
+	After *Snapshot `json:"after"`
+}
+
+// diffSnapshot compares two snapshots, or a snapshot with the live state of the network.
+func (r *Controller) diffSnapshot(w rest.ResponseWriter, req *rest.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	param := SnapshotDiffParam{}
+	if err := req.DecodeJsonPayload(&param); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if param.After == nil {
+		s, err := r.snapshot()
+		if err != nil {
+			logger.Errorf("failed to take a snapshot: %v", err)
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		param.After = &s
+	}
+
+	diff := diffSnapshots(param.Before, *param.After)
+	w.WriteJson(&diff)
+}
+
+func diffSnapshots(before, after Snapshot) SnapshotDiff {
+	diff := SnapshotDiff{
+		Before:         before.Created,
+		After:          after.Created,
+		AddedDevices:   []string{},
+		RemovedDevices: []string{},
+		Ports:          []PortChange{},
+		AddedLinks:     []LinkInfo{},
+		RemovedLinks:   []LinkInfo{},
+		Hosts:          []HostMove{},
+		Flows:          []FlowChange{},
+	}
+
+	devices := make(map[string]DeviceSnapshot)
+	for _, d := range before.Devices {
+		devices[d.DPID] = d
+	}
+	for _, d := range after.Devices {
+		prev, ok := devices[d.DPID]
+		if !ok {
+			diff.AddedDevices = append(diff.AddedDevices, d.DPID)
+			continue
+		}
+		delete(devices, d.DPID)
+		diff.Ports = append(diff.Ports, diffPorts(d.DPID, prev.Ports, d.Ports)...)
+		// Unknown flows cannot be compared.
+		if prev.FlowsCollected != nil && d.FlowsCollected != nil {
+			diff.Flows = append(diff.Flows, diffFlows(d.DPID, prev.Flows, d.Flows)...)
+		}
+	}
+	for dpid := range devices {
+		diff.RemovedDevices = append(diff.RemovedDevices, dpid)
+	}
+	sort.Strings(diff.AddedDevices)
+	sort.Strings(diff.RemovedDevices)
+	sort.Slice(diff.Ports, func(i, j int) bool {
+		if diff.Ports[i].DPID != diff.Ports[j].DPID {
+			return diff.Ports[i].DPID < diff.Ports[j].DPID
+		}
+		return diff.Ports[i].Number < diff.Ports[j].Number
+	})
+	sort.Slice(diff.Flows, func(i, j int) bool {
+		a, b := diff.Flows[i], diff.Flows[j]
+		if a.DPID != b.DPID {
+			return a.DPID < b.DPID
+		}
+		if a.TableID != b.TableID {
+			return a.TableID < b.TableID
+		}
+		if a.Priority != b.Priority {
+			// Higher priority first as the switches look up the flows.
+			return a.Priority > b.Priority
+		}
+		return a.Match < b.Match
+	})
+
+	diff.AddedLinks, diff.RemovedLinks = diffLinks(before.Links, after.Links)
+	diff.Hosts = diffHosts(before.Hosts, after.Hosts)
+
+	return diff
+}
+
+func diffPorts(dpid string, before, after []DevicePort) []PortChange {
+	result := []PortChange{}
+
+	ports := make(map[uint32]DevicePort)
+	for _, p := range before {
+		ports[p.Number] = p
+	}
+	for _, p := range after {
+		p := p
+		prev, ok := ports[p.Number]
+		if !ok {
+			result = append(result, PortChange{DPID: dpid, Number: p.Number, After: withoutStats(p)})
+			continue
+		}
+		delete(ports, p.Number)
+		if *withoutStats(prev) != *withoutStats(p) {
+			result = append(result, PortChange{DPID: dpid, Number: p.Number, Before: withoutStats(prev), After: withoutStats(p)})
+		}
+	}
+	for num, p := range ports {
+		result = append(result, PortChange{DPID: dpid, Number: num, Before: withoutStats(p)})
+	}
+
+	return result
+}
+
+func withoutStats(p DevicePort) *DevicePort {
+	p.Stats = nil
+	return &p
+}
+
+type flowChangeKey struct {
+	tableID  uint8
+	priority uint16
+	match    string
+}
+
+func diffFlows(dpid string, before, after []DeviceFlow) []FlowChange {
+	result := []FlowChange{}
+
+	// Keyed by the match fields so that a large flow table is compared in linear time.
+	flows := make(map[flowChangeKey]DeviceFlow, len(before))
+	for _, f := range before {
+		flows[flowChangeKey{f.TableID, f.Priority, f.Match.String()}] = f
+	}
+	for _, f := range after {
+		f := f
+		key := flowChangeKey{f.TableID, f.Priority, f.Match.String()}
+		change := FlowChange{DPID: dpid, TableID: key.tableID, Priority: key.priority, Match: key.match, Owner: f.Owner, After: &f}
+		prev, ok := flows[key]
+		if !ok {
+			result = append(result, change)
+			continue
+		}
+		delete(flows, key)
+		if sameFlow(prev, f) {
+			continue
+		}
+		change.Before = &prev
+		result = append(result, change)
+	}
+	for key, f := range flows {
+		f := f
+		result = append(result, FlowChange{DPID: dpid, TableID: key.tableID, Priority: key.priority, Match: key.match, Owner: f.Owner, Before: &f})
+	}
+
+	return result
+}
+
+// sameFlow compares the flows except their statistics.
+func sameFlow(a, b DeviceFlow) bool {
+	return a.Cookie == b.Cookie && a.IdleTimeout == b.IdleTimeout && a.HardTimeout == b.HardTimeout && a.Owner == b.Owner
+}
+
+func diffLinks(before, after []LinkInfo) (added, removed []LinkInfo) {
+	added, removed = []LinkInfo{}, []LinkInfo{}
+
+	links := make(map[LinkInfo]bool)
+	for _, l := range before {
+		links[normalizeLink(l)] = true
+	}
+	for _, l := range after {
+		l = normalizeLink(l)
+		if links[l] {
+			delete(links, l)
+			continue
+		}
+		added = append(added, l)
+	}
+	for l := range links {
+		removed = append(removed, l)
+	}
+	sortLinks(added)
+	sortLinks(removed)
+
+	return added, removed
+}
+
+// normalizeLink orders the end points of a link so that the same link has the same form
+// regardless of the direction it has been discovered.
+func normalizeLink(l LinkInfo) LinkInfo {
+	if l.DPID1 > l.DPID2 || (l.DPID1 == l.DPID2 && l.Port1 > l.Port2) {
+		l.DPID1, l.Port1, l.DPID2, l.Port2 = l.DPID2, l.Port2, l.DPID1, l.Port1
+	}
+	return l
+}
+
+func sortLinks(links []LinkInfo) {
+	sort.Slice(links, func(i, j int) bool {
+		a, b := links[i], links[j]
+		if a.DPID1 != b.DPID1 {
+			return a.DPID1 < b.DPID1
+		}
+		if a.Port1 != b.Port1 {
+			return a.Port1 < b.Port1
+		}
+		if a.DPID2 != b.DPID2 {
+			return a.DPID2 < b.DPID2
+		}
+		return a.Port2 < b.Port2
+	})
+}
+
+func diffHosts(before, after []HostLocationParam) []HostMove {
+	result := []HostMove{}
+
+	hosts := make(map[string]HostLocationParam)
+	for _, h := range before {
+		hosts[h.MAC] = h
+	}
+	for _, h := range after {
+		h := h
+		prev, ok := hosts[h.MAC]
+		if !ok {
+			result = append(result, HostMove{MAC: h.MAC, IP: h.IP, After: &h})
+			continue
+		}
+		delete(hosts, h.MAC)
+		if prev.DPID != h.DPID || prev.Port != h.Port {
+			result = append(result, HostMove{MAC: h.MAC, IP: h.IP, Before: &prev, After: &h})
+		}
+	}
+	for _, h := range hosts {
+		h := h
+		result = append(result, HostMove{MAC: h.MAC, IP: h.IP, Before: &h})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].MAC < result[j].MAC })
+
+	return result
+}
@@ -0,0 +1,83 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// Run "go test -update" to regenerate the golden files after changing the fixtures.
+var update = flag.Bool("update", false, "update the golden files")
+
+func readSnapshot(t *testing.T, file string) Snapshot {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := Snapshot{}
+	if err := json.Unmarshal(data, &s); err != nil {
+		t.Fatalf("%v: %v", file, err)
+	}
+
+	return s
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	dir := filepath.Join("testdata", "snapshot_diff")
+	before := readSnapshot(t, filepath.Join(dir, "before.json"))
+	after := readSnapshot(t, filepath.Join(dir, "after.json"))
+
+	data, err := json.MarshalIndent(diffSnapshots(before, after), "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = append(data, '\n')
+
+	golden := filepath.Join(dir, "diff.golden")
+	if *update {
+		if err := ioutil.WriteFile(golden, data, 0644); err != nil {
+			t.Fatalf("%v: %v", golden, err)
+		}
+		return
+	}
+	expected, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("%v: %v", golden, err)
+	}
+	if string(expected) != string(data) {
+		t.Fatalf("unexpected diff: expected=\n%s\ngot=\n%s", expected, data)
+	}
+}
+
+func TestDiffSameSnapshot(t *testing.T) {
+	s := readSnapshot(t, filepath.Join("testdata", "snapshot_diff", "before.json"))
+
+	diff := diffSnapshots(s, s)
+	if len(diff.AddedDevices) != 0 || len(diff.RemovedDevices) != 0 || len(diff.Ports) != 0 || len(diff.AddedLinks) != 0 ||
+		len(diff.RemovedLinks) != 0 || len(diff.Hosts) != 0 || len(diff.Flows) != 0 {
+		t.Fatalf("unexpected changes: %+v", diff)
+	}
+}
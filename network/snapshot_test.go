@@ -0,0 +1,142 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/superkkt/cherry/clock"
+)
+
+// snapshotDB is a database that only keeps the registered hosts and their locations.
+type snapshotDB struct {
+	drainDB
+	hosts []Host
+	// Key is the MAC address, and value is the port number on device 1.
+	locations map[string]uint32
+}
+
+func (r *snapshotDB) Hosts() ([]Host, error) {
+	return r.hosts, nil
+}
+
+func (r *snapshotDB) Location(mac net.HardwareAddr) (dpid string, port uint32, status LocationStatus, err error) {
+	port, ok := r.locations[mac.String()]
+	if !ok {
+		return "", 0, LocationUndiscovered, nil
+	}
+
+	return "1", port, LocationDiscovered, nil
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	db := &snapshotDB{
+		drainDB: drainDB{switches: map[uint64]bool{}},
+		hosts: []Host{
+			{ID: "1", IP: "10.0.0.1/24", MAC: "00:00:00:00:00:01"},
+			{ID: "2", IP: "10.0.0.2/24", MAC: "00:00:00:00:00:02"},
+		},
+		locations: map[string]uint32{
+			"00:00:00:00:00:01": 7,
+			// Beyond the range of uint16.
+			"00:00:00:00:00:02": 0x10007,
+		},
+	}
+	d, _, _, cleanup := newBarrierDevice(t)
+	defer cleanup()
+	d.id = "1"
+	for _, num := range []uint32{7, 0x10007} {
+		d.ports[num] = NewPort(d, num)
+	}
+	topo := newTopology(db, clock.Real)
+	d.session.watcher = topo
+	d.session.packetInGate = newPacketInGate(newPacketInPolicy(), clock.Real)
+	topo.DeviceAdded(d)
+	controller := &Controller{topo: topo, db: db, clock: clock.Real}
+
+	s, err := controller.snapshot()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := json.Marshal(&s)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The exported snapshot is posted to the import API as it is.
+	param := ImportParam{}
+	if err := json.Unmarshal(data, &param); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(param.Hosts) != 2 {
+		t.Fatalf("unexpected number of hosts: expected=2, got=%v", len(param.Hosts))
+	}
+
+	for i, expected := range []uint32{7, 0x10007} {
+		h := param.Hosts[i]
+		if h.MAC != db.hosts[i].MAC || h.IP != strings.Split(db.hosts[i].IP, "/")[0] || h.DPID != "1" {
+			t.Fatalf("unexpected host location: %+v", h)
+		}
+		if h.Port != expected {
+			t.Fatalf("unexpected port number: expected=%v, got=%v", expected, h.Port)
+		}
+	}
+
+	loc, err := param.Hosts[0].parse()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if loc.mac.String() != "00:00:00:00:00:01" || !loc.ip.Equal(net.ParseIP("10.0.0.1")) || loc.dpid != 1 || loc.port != 7 {
+		t.Fatalf("unexpected parsed location: %+v", loc)
+	}
+	// The database cannot store the port number, so it should be rejected rather than truncated.
+	if _, err := param.Hosts[1].parse(); err == nil {
+		t.Fatalf("expected an error for the port number %v", param.Hosts[1].Port)
+	}
+}
+
+func TestHostLocationParamParse(t *testing.T) {
+	tests := []struct {
+		param HostLocationParam
+		valid bool
+	}{
+		{HostLocationParam{MAC: "00:00:00:00:00:01", IP: "10.0.0.1", DPID: "1", Port: 1}, true},
+		{HostLocationParam{MAC: "00:00:00:00:00:01", IP: "10.0.0.1", DPID: "1", Port: 0xFFFF}, true},
+		{HostLocationParam{MAC: "00:00:00:00:00:01", IP: "10.0.0.1", DPID: "1", Port: 0x10000}, false},
+		{HostLocationParam{MAC: "00:00:00:00:00:01", IP: "10.0.0.1", DPID: "1", Port: 0}, false},
+		{HostLocationParam{MAC: "invalid", IP: "10.0.0.1", DPID: "1", Port: 1}, false},
+		{HostLocationParam{MAC: "00:00:00:00:00:01", IP: "invalid", DPID: "1", Port: 1}, false},
+		{HostLocationParam{MAC: "00:00:00:00:00:01", IP: "10.0.0.1", DPID: "invalid", Port: 1}, false},
+	}
+
+	for i, test := range tests {
+		loc, err := test.param.parse()
+		if (err == nil) != test.valid {
+			t.Fatalf("#%v: unexpected result: expected valid=%v, got err=%v", i, test.valid, err)
+		}
+		if err == nil && loc.port != test.param.Port {
+			t.Fatalf("#%v: unexpected port number: expected=%v, got=%v", i, test.param.Port, loc.port)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"testing"
+)
+
+func enabledLinks(topo *topology, links [][2]*Port) (enabled []int) {
+	for i, l := range links {
+		if topo.IsEnabledBySTP(l[0]) != topo.IsEnabledBySTP(l[1]) {
+			panic("both ends of a link should have the same STP state")
+		}
+		if topo.IsEnabledBySTP(l[0]) {
+			enabled = append(enabled, i)
+		}
+	}
+
+	return enabled
+}
+
+func TestSTPCrossConnected(t *testing.T) {
+	db := &drainDB{switches: map[uint64]bool{1: false, 2: false}}
+	topo := newTestTopology(db, []string{"1", "2"}, nil)
+	one, two := topo.Device("1"), topo.Device("2")
+
+	// Two switches cross-connected by redundant links.
+	links := [][2]*Port{
+		{NewPort(one, 10), NewPort(two, 10)},
+		{NewPort(one, 20), NewPort(two, 20)},
+	}
+	for _, l := range links {
+		topo.DeviceLinked(l)
+	}
+	enabled := enabledLinks(topo, links)
+	if len(enabled) != 1 {
+		t.Fatalf("only one of the redundant links should be enabled: enabled=%v", enabled)
+	}
+
+	// The blocked link should be enabled when the active one goes down.
+	active := enabled[0]
+	topo.PortRemoved(links[active][0])
+	enabled = enabledLinks(topo, links)
+	if len(enabled) != 1 || enabled[0] == active {
+		t.Fatalf("the blocked link should be enabled after the active one is removed: enabled=%v", enabled)
+	}
+}
+
+func TestSTPRing(t *testing.T) {
+	db := &drainDB{switches: map[uint64]bool{1: false, 2: false, 3: false, 4: false}}
+	topo := newTestTopology(db, []string{"1", "2", "3", "4"}, nil)
+
+	links := make([][2]*Port, 0)
+	for _, v := range [][2]string{{"1", "2"}, {"2", "3"}, {"3", "4"}, {"4", "1"}} {
+		src, dst := topo.Device(v[0]), topo.Device(v[1])
+		links = append(links, [2]*Port{NewPort(src, uint32(testDPID(dst.ID()))), NewPort(dst, uint32(testDPID(src.ID())))})
+	}
+	for _, l := range links {
+		topo.DeviceLinked(l)
+	}
+
+	// A spanning tree of 4 devices has 3 links, so exactly one link of the ring is blocked.
+	if enabled := enabledLinks(topo, links); len(enabled) != 3 {
+		t.Fatalf("unexpected number of enabled links: expected=3, got=%v", enabled)
+	}
+}
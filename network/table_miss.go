@@ -0,0 +1,139 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/superkkt/cherry/openflow"
+
+	"github.com/superkkt/viper"
+)
+
+// tableMissPolicy decides the table-miss flow entries installed on the tables of an OpenFlow
+// 1.3 device other than the ones used by the controller, i.e., the flow table and the
+// classifier table whose table-miss entries are always installed. Many switches drop the
+// table-miss packets by default, so the packets missed in the tables that the applications
+// use by GotoTable never reach the controller unless the table-miss entries are installed.
+type tableMissPolicy int
+
+const (
+	// Do not install the table-miss entries on the other tables.
+	tableMissNone tableMissPolicy = iota
+	// Send the table-miss packets to the controller.
+	tableMissController
+	// Drop the table-miss packets.
+	tableMissDrop
+)
+
+func (r tableMissPolicy) String() string {
+	switch r {
+	case tableMissNone:
+		return "none"
+	case tableMissController:
+		return "controller"
+	case tableMissDrop:
+		return "drop"
+	default:
+		panic(fmt.Sprintf("unexpected table-miss policy: %v", int(r)))
+	}
+}
+
+// parseTableMissPolicy parses s that should be one of "none", "controller" and "drop". Empty
+// s means "none".
+func parseTableMissPolicy(s string) (tableMissPolicy, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "none":
+		return tableMissNone, nil
+	case "controller":
+		return tableMissController, nil
+	case "drop":
+		return tableMissDrop, nil
+	default:
+		return tableMissNone, fmt.Errorf("unknown table-miss policy: %v", s)
+	}
+}
+
+func newTableMissPolicy() tableMissPolicy {
+	policy, err := parseTableMissPolicy(viper.GetString("default.table_miss"))
+	if err != nil {
+		logger.Errorf("invalid default.table_miss: %v (do not install the table-miss entries)", err)
+		return tableMissNone
+	}
+
+	return policy
+}
+
+// instruction returns the instruction of the table-miss entries, or nil if the entries drop
+// the packets, i.e., they have no instruction.
+func (r tableMissPolicy) instruction(f openflow.Factory) (openflow.Instruction, error) {
+	if r != tableMissController {
+		return nil, nil
+	}
+
+	inst, err := f.NewInstruction()
+	if err != nil {
+		return nil, err
+	}
+	outPort := openflow.NewOutPort()
+	outPort.SetController()
+	action, err := f.NewAction()
+	if err != nil {
+		return nil, err
+	}
+	action.SetOutPort(outPort)
+	inst.ApplyAction(action)
+
+	return inst, nil
+}
+
+// tables returns the IDs of the tables, among the numTables tables of a device, where the
+// table-miss entries should be installed. used is the tables whose table-miss entries are
+// installed by the controller regardless of the policy.
+func (r tableMissPolicy) tables(numTables uint8, used ...uint8) []uint8 {
+	if r == tableMissNone {
+		return nil
+	}
+
+	result := []uint8{}
+	for i := 0; i < int(numTables); i++ {
+		id := uint8(i)
+		// 0xFF means all the tables in a FLOW_MOD.
+		if id == 0xFF || containsTable(used, id) {
+			continue
+		}
+		result = append(result, id)
+	}
+
+	return result
+}
+
+func containsTable(tables []uint8, id uint8) bool {
+	for _, v := range tables {
+		if v == id {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,86 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"testing"
+
+	"github.com/superkkt/cherry/openflow/of13"
+)
+
+func TestParseTableMissPolicy(t *testing.T) {
+	valid := map[string]tableMissPolicy{
+		"":            tableMissNone,
+		"none":        tableMissNone,
+		" Controller": tableMissController,
+		"DROP":        tableMissDrop,
+	}
+	for s, expected := range valid {
+		v, err := parseTableMissPolicy(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != expected {
+			t.Fatalf("unexpected policy of %q: expected=%v, got=%v", s, expected, v)
+		}
+	}
+	if _, err := parseTableMissPolicy("flood"); err == nil {
+		t.Fatal("expected an error for an unknown policy")
+	}
+}
+
+func TestTableMissTables(t *testing.T) {
+	if v := tableMissNone.tables(4, 0); len(v) != 0 {
+		t.Fatalf("unexpected tables of none: %v", v)
+	}
+	v := tableMissDrop.tables(4, 0, 1)
+	if len(v) != 2 || v[0] != 2 || v[1] != 3 {
+		t.Fatalf("unexpected tables: %v", v)
+	}
+	// 0xFF is not a table ID.
+	if v := tableMissController.tables(0xFF); len(v) != 0xFF || v[len(v)-1] != 0xFE {
+		t.Fatalf("unexpected number of tables: %v", len(v))
+	}
+}
+
+func TestTableMissInstruction(t *testing.T) {
+	f := of13.NewFactory()
+	inst, err := tableMissDrop.instruction(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inst != nil {
+		t.Fatal("drop policy should have no instruction")
+	}
+
+	inst, err = tableMissController.instruction(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actions := inst.Actions()
+	if len(actions) != 1 {
+		t.Fatalf("unexpected number of actions: %v", len(actions))
+	}
+	if out := actions[0].OutPort(); !out.IsController() {
+		t.Fatalf("unexpected output port: %v", out)
+	}
+}
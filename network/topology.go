@@ -25,9 +25,12 @@ import (
 	"bytes"
 	"fmt"
 	"net"
+	"sort"
+	"strconv"
 	"sync"
 	"time"
 
+	"github.com/superkkt/cherry/clock"
 	"github.com/superkkt/cherry/graph"
 
 	"github.com/pkg/errors"
@@ -36,35 +39,90 @@ import (
 type watcher interface {
 	DeviceAdded(*Device)
 	DeviceLinked([2]*Port)
+	// DeviceLinkedIndirectly adds the link, between two ports, that goes through non-OpenFlow switches.
+	DeviceLinkedIndirectly([2]*Port)
+	// PortAggregated marks the port as a member of the link aggregation identified by key.
+	PortAggregated(p *Port, key string)
 	DeviceRemoved(*Device)
 	PortRemoved(*Port)
+	// PortHistory returns the ports of the device, whose ID is id, that were
+	// available when the device was disconnected last time.
+	PortHistory(id string) (ports []portRecord, ok bool)
+	// RestoreLinks adds the links of the device, which have been saved by the previous run, to the
+	// topology before they are discovered again.
+	RestoreLinks(d *Device)
+	// FlowTable returns the shadow copy of the permanent flows installed on the device whose ID is id.
+	// It is kept while the device is disconnected.
+	FlowTable(id string) *flowTable
+	// SetDrained marks the device, whose ID is id, as drained so that paths avoid it.
+	SetDrained(id string, drained bool) error
+	IsDrained(id string) bool
+	// IsTransit returns whether a path between other devices may traverse the device whose ID is id.
+	IsTransit(id string) bool
 }
 
 type Finder interface {
 	Device(id string) *Device
 	Devices() []*Device
-	// IsEnabledBySTP returns whether p is disabled by spanning tree protocol
+	// IsEnabledBySTP returns whether p is enabled by spanning tree protocol
 	IsEnabledBySTP(p *Port) bool
 	// IsEdge returns whether p is an edge among two switches
 	IsEdge(p *Port) bool
 	Node(mac net.HardwareAddr) (*Node, LocationStatus, error)
 	Path(srcDeviceID, dstDeviceID string) [][2]*Port
+	// NextHops returns the first hops of all the equal-cost shortest paths between two devices.
+	// Unlike Path, the paths may use the links disabled by spanning tree protocol.
+	NextHops(srcDeviceID, dstDeviceID string) [][2]*Port
+	// Links returns the discovered links among two switches.
+	Links() [][2]*Port
+	// Aggregation returns the active member ports, sorted by their IDs, of the link aggregation
+	// that p belongs to. It returns nil if p is not a member of any link aggregation.
+	Aggregation(p *Port) []*Port
+	// HostMoved notifies the event subscribers that the host, whose MAC and IP addresses are
+	// mac and ip, has been moved to port.
+	HostMoved(mac net.HardwareAddr, ip net.IP, port *Port)
 }
 
 type topology struct {
 	mutex sync.RWMutex
 	// Key is the device ID
-	devices  map[string]*Device
-	graph    *graph.Graph
+	devices map[string]*Device
+	// Key is the device ID, and value is the ports of the device that were
+	// available when the device was disconnected last time.
+	portHistory map[string][]portRecord
+	// Key is the device ID
+	flowTables map[string]*flowTable
+	// Store of the flow tables. nil means they are not saved.
+	intents *flowIntentStore
+	// Store of the links. nil means they are not saved.
+	savedLinks *linkStore
+	graph      *graph.Graph
+	// Key is the link ID. These are the links that we have announced by the link events.
+	links map[string]*link
+	// Member ports of the link aggregations discovered by LACP.
+	lags     *lagTable
 	listener TopologyEventListener
 	db       database
+	clock    clock.Clock
+	// Bus of the network events, which are published by the sessions and us.
+	events *eventBus
 }
 
-func newTopology(db database) *topology {
+func newTopology(db database, clk clock.Clock) *topology {
+	if clk == nil {
+		panic("clock is nil")
+	}
+
 	v := &topology{
-		devices: make(map[string]*Device),
-		graph:   graph.New(),
-		db:      db,
+		devices:     make(map[string]*Device),
+		portHistory: make(map[string][]portRecord),
+		flowTables:  make(map[string]*flowTable),
+		graph:       graph.New(),
+		links:       make(map[string]*link),
+		lags:        newLAGTable(clk),
+		db:          db,
+		clock:       clk,
+		events:      newEventBus(clk),
 	}
 	go v.staleEdgeRemover()
 
@@ -82,6 +140,10 @@ func (r *topology) String() string {
 	return buf.String()
 }
 
+func (r *topology) HostMoved(mac net.HardwareAddr, ip net.IP, port *Port) {
+	r.events.publishHostMoved(mac, ip, port.Device().ID(), port.Number())
+}
+
 func (r *topology) setEventListener(l TopologyEventListener) {
 	r.listener = l
 }
@@ -99,6 +161,67 @@ func (r *topology) sendEvent() {
 	}
 }
 
+// syncLinks compares the announced links with the graph edges, and returns the links
+// that have been newly discovered or have disappeared since the last call.
+// XXX: Caller should lock the mutex
+func (r *topology) syncLinks() (up, down []*link) {
+	edges := make(map[string]*link)
+	for _, e := range r.graph.Edges() {
+		l := e.(*link)
+		edges[l.ID()] = l
+		if _, ok := r.links[l.ID()]; !ok {
+			up = append(up, l)
+		}
+	}
+	for id, l := range r.links {
+		if _, ok := edges[id]; !ok {
+			down = append(down, l)
+		}
+	}
+	r.links = edges
+
+	return up, down
+}
+
+// Caller should make sure the mutex is unlocked before calling this function.
+// Otherwise, event listeners may cause a deadlock by calling other topology functions.
+func (r *topology) sendLinkEvents(up, down []*link) {
+	for _, l := range down {
+		logger.Infof("link down: %v:%v / %v:%v", l.ports[0].Device().ID(), l.ports[0].Number(), l.ports[1].Device().ID(), l.ports[1].Number())
+	}
+	for _, l := range up {
+		logger.Infof("link up: %v:%v / %v:%v", l.ports[0].Device().ID(), l.ports[0].Number(), l.ports[1].Device().ID(), l.ports[1].Number())
+	}
+
+	if r.listener == nil {
+		return
+	}
+
+	for _, l := range down {
+		if err := r.listener.OnLinkDown(r, l.ports); err != nil {
+			logger.Errorf("OnLinkDown: %v", err)
+		}
+	}
+	for _, l := range up {
+		if err := r.listener.OnLinkUp(r, l.ports); err != nil {
+			logger.Errorf("OnLinkUp: %v", err)
+		}
+	}
+}
+
+func (r *topology) Links() [][2]*Port {
+	// Read lock
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	v := make([][2]*Port, 0, len(r.links))
+	for _, l := range r.links {
+		v = append(v, l.ports)
+	}
+
+	return v
+}
+
 func (r *topology) Devices() []*Device {
 	// Read lock
 	r.mutex.RLock()
@@ -122,6 +245,9 @@ func (r *topology) Device(id string) *Device {
 }
 
 func (r *topology) DeviceAdded(d *Device) {
+	// Query the database before taking the lock so that a slow database does not block the topology.
+	drained := r.loadDrained(d.ID())
+
 	// NOTE: This is an anonymous function (NOT a goroutine!) that has a critical section.
 	func() {
 		// Write lock
@@ -129,19 +255,204 @@ func (r *topology) DeviceAdded(d *Device) {
 		defer r.mutex.Unlock()
 
 		r.devices[d.ID()] = d
+		// Restore the drained state so that a controller restart does not undo maintenance.
+		if drained {
+			logger.Infof("device %v is still being drained", d.ID())
+			r.graph.SetDrained(d, true)
+		}
 		r.graph.AddVertex(d)
 	}()
 	// XXX: Make sure the mutex is unlocked before calling sendEvent().
 	r.sendEvent()
 }
 
+// loadDrained returns whether the device whose ID is id has been drained in the database.
+func (r *topology) loadDrained(id string) bool {
+	dpid, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return false
+	}
+	sw, ok, err := r.db.Switch(dpid)
+	if err != nil {
+		logger.Errorf("failed to query the drained state of device %v: %v", id, err)
+		return false
+	}
+
+	return ok && sw.Drained
+}
+
 // XXX: Caller should lock the mutex
 func (r *topology) removeDevice(d *Device) {
+	// Remember the ports to detect port renumbering when the device reconnects.
+	ports := make([]portRecord, 0)
+	for _, p := range d.Ports() {
+		ports = append(ports, newPortRecord(p.Value()))
+	}
+	r.portHistory[d.ID()] = ports
+
 	// Remove from the device database
 	delete(r.devices, d.ID())
 }
 
+func (r *topology) PortHistory(id string) (ports []portRecord, ok bool) {
+	// Read lock
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	ports, ok = r.portHistory[id]
+	return ports, ok
+}
+
+func (r *topology) FlowTable(id string) *flowTable {
+	// Write lock
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	t, ok := r.flowTables[id]
+	if !ok {
+		t = newFlowTable()
+		t.persist(id, r.intents)
+		r.flowTables[id] = t
+	}
+
+	return t
+}
+
+// setFlowIntentStore restores the flow tables from the intents saved in store, and then saves
+// the changes of the flow tables in store. It should be called before any device is connected.
+func (r *topology) setFlowIntentStore(store *flowIntentStore) error {
+	tables, err := store.load()
+
+	// Write lock
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	// The new flow tables are saved even if the old ones cannot be loaded.
+	r.intents = store
+	if err != nil {
+		return err
+	}
+	for id, t := range tables {
+		logger.Infof("restored %v flow intents of %v", t.Len(), id)
+		r.flowTables[id] = t
+	}
+
+	return nil
+}
+
+// flowIntents returns the intents of all the devices, sorted by the DPIDs.
+func (r *topology) flowIntents() ([]FlowIntent, error) {
+	// Read lock
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var result []FlowIntent
+	for _, t := range r.flowTables {
+		v, err := t.Intents()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, v...)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.DPID != b.DPID {
+			// The DPIDs are decimal numbers.
+			if len(a.DPID) != len(b.DPID) {
+				return len(a.DPID) < len(b.DPID)
+			}
+			return a.DPID < b.DPID
+		}
+		if a.TableID != b.TableID {
+			return a.TableID < b.TableID
+		}
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+		return bytes.Compare(a.FlowMod, b.FlowMod) < 0
+	})
+
+	return result, nil
+}
+
+// removeStaleFlowIntents removes the intents owned by the applications that are not loaded from
+// the flow tables of all the devices. It returns the number of the removed intents.
+func (r *topology) removeStaleFlowIntents() int {
+	// Read lock
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	n := 0
+	for _, t := range r.flowTables {
+		n += len(t.RemoveStale())
+	}
+
+	return n
+}
+
+// setLinkStore saves the links in store whenever they are changed. The links saved by the
+// previous run are restored by RestoreLinks. It should be called before any device is connected.
+func (r *topology) setLinkStore(store *linkStore) {
+	// Write lock
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.savedLinks = store
+}
+
+// Caller should make sure the mutex is unlocked before calling this function.
+func (r *topology) saveLinks(up *link, down []*link) {
+	r.mutex.RLock()
+	store := r.savedLinks
+	r.mutex.RUnlock()
+
+	if store == nil {
+		return
+	}
+	store.save(up, down)
+}
+
+// RestoreLinks adds the saved links of d, whose other ends are connected and up, to the topology
+// without waiting for LLDP. The links that do not exist anymore are removed as stale edges later.
+func (r *topology) RestoreLinks(d *Device) {
+	r.mutex.RLock()
+	store := r.savedLinks
+	r.mutex.RUnlock()
+
+	if store == nil {
+		return
+	}
+	for _, v := range store.linksOf(d.ID()) {
+		ports := [2]*Port{r.savedPort(v.SrcDPID, v.SrcPort), r.savedPort(v.DstDPID, v.DstPort)}
+		if ports[0] == nil || ports[1] == nil {
+			continue
+		}
+		logger.Infof("restoring a saved link: %v, %v", ports[0].ID(), ports[1].ID())
+		if v.Indirect {
+			r.DeviceLinkedIndirectly(ports)
+		} else {
+			r.DeviceLinked(ports)
+		}
+	}
+}
+
+// savedPort returns the port of a saved link if it is up.
+func (r *topology) savedPort(dpid uint64, num uint32) *Port {
+	d := r.Device(strconv.FormatUint(dpid, 10))
+	if d == nil {
+		return nil
+	}
+	p := d.Port(num)
+	if p == nil || !isPortUp(p.Value()) {
+		return nil
+	}
+
+	return p
+}
+
 func (r *topology) DeviceRemoved(d *Device) {
+	var down []*link
+
 	// NOTE: This is an anonymous function (NOT a goroutine!) that has a critical section.
 	func() {
 		// Write lock
@@ -149,15 +460,20 @@ func (r *topology) DeviceRemoved(d *Device) {
 		defer r.mutex.Unlock()
 
 		r.removeDevice(d)
+		r.lags.removeDevice(d.ID())
 		r.graph.RemoveVertex(d)
+		_, down = r.syncLinks()
 	}()
 	// XXX: Make sure the mutex is unlocked before calling sendEvent().
 	r.sendEvent()
+	r.sendLinkEvents(nil, down)
 }
 
 func (r *topology) DeviceLinked(ports [2]*Port) {
 	var added bool
 	var err error
+	var v *link
+	var up, down []*link
 
 	// NOTE: This is an anonymous function (NOT a goroutine!) that has a critical section.
 	func() {
@@ -165,22 +481,147 @@ func (r *topology) DeviceLinked(ports [2]*Port) {
 		r.mutex.Lock()
 		defer r.mutex.Unlock()
 
-		link := newLink(ports)
-		added, err = r.graph.AddEdge(link)
+		// The LLDP packet has come back through the partner of a link aggregation.
+		if r.lags.sameAggregation(ports[0], ports[1]) {
+			logger.Debugf("ignoring the link between the members of a link aggregation: %v, %v", ports[0].ID(), ports[1].ID())
+			return
+		}
+		v = newLink(ports)
+		// The direct link replaces the indirect ones discovered by BDDP on the same ports.
+		for _, e := range r.graph.Edges() {
+			l := e.(*link)
+			if !l.indirect || !l.hasPort(ports[0], ports[1]) {
+				continue
+			}
+			r.graph.RemoveEdge(l.ports[0])
+			// The link itself has not been changed if it has the same ports.
+			if l.ID() != v.ID() {
+				added = true
+			}
+		}
+		var ok bool
+		ok, err = r.graph.AddEdge(v)
 		if err != nil {
 			logger.Errorf("failed to add a new graph edge: %v", err)
 			return
 		}
+		added = added || ok
+		up, down = r.syncLinks()
 	}()
 
 	// Send the event only if the topology has been changed.
 	if err == nil && added {
+		r.saveLinks(v, down)
 		// XXX: Make sure the mutex is unlocked before calling sendEvent().
 		r.sendEvent()
-		logger.Infof("devices have been linked: %v:%v / %v:%v", ports[0].Device().ID(), ports[0].Number(), ports[1].Device().ID(), ports[1].Number())
+		r.sendLinkEvents(up, down)
 	}
 }
 
+func (r *topology) DeviceLinkedIndirectly(ports [2]*Port) {
+	var added bool
+	var v *link
+	var up, down []*link
+
+	// NOTE: This is an anonymous function (NOT a goroutine!) that has a critical section.
+	func() {
+		// Write lock
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+
+		if r.lags.sameAggregation(ports[0], ports[1]) {
+			logger.Debugf("ignoring the indirect link between the members of a link aggregation: %v, %v", ports[0].ID(), ports[1].ID())
+			return
+		}
+		v = &link{ports: ports, indirect: true}
+		for _, e := range r.graph.Edges() {
+			l := e.(*link)
+			if !l.hasPort(ports[0], ports[1]) {
+				continue
+			}
+			// Refresh the timestamp of the same indirect link, but keep the direct link
+			// between the ports if it has been discovered by LLDP.
+			if l.ID() == v.ID() {
+				r.graph.AddEdge(v)
+				return
+			}
+			// A port connected to a non-OpenFlow network receives BDDP packets from all the
+			// switches connected to the network, and the first one is used.
+			logger.Debugf("ignoring the indirect link on the port that already has a link: %v, %v", ports[0].ID(), ports[1].ID())
+			return
+		}
+		var err error
+		added, err = r.graph.AddEdge(v)
+		if err != nil {
+			logger.Errorf("failed to add a new graph edge: %v", err)
+			return
+		}
+		if added {
+			logger.Infof("discovered an indirect link by BDDP: %v, %v", ports[0].ID(), ports[1].ID())
+		}
+		up, down = r.syncLinks()
+	}()
+
+	if added {
+		r.saveLinks(v, down)
+		// XXX: Make sure the mutex is unlocked before calling sendEvent().
+		r.sendEvent()
+		r.sendLinkEvents(up, down)
+	}
+}
+
+// isIndirectLink returns whether the link between ports has been discovered by BDDP.
+func (r *topology) isIndirectLink(ports [2]*Port) bool {
+	// Read lock
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	l, ok := r.links[newLink(ports).ID()]
+	return ok && l.indirect
+}
+
+func (r *topology) PortAggregated(p *Port, key string) {
+	if !r.lags.add(p, key) {
+		return
+	}
+	logger.Infof("port %v joined the link aggregation %v", p.ID(), key)
+
+	removed := false
+	var down []*link
+
+	// NOTE: This is an anonymous function (NOT a goroutine!) that has a critical section.
+	func() {
+		// Write lock
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+
+		// The links among the members of an aggregation are the loops through the partner.
+		for _, e := range r.graph.Edges() {
+			l := e.(*link)
+			if l.ports[0].ID() != p.ID() && l.ports[1].ID() != p.ID() {
+				continue
+			}
+			if r.lags.sameAggregation(l.ports[0], l.ports[1]) {
+				r.graph.RemoveEdge(p)
+				removed = true
+			}
+		}
+		if removed {
+			_, down = r.syncLinks()
+		}
+	}()
+
+	if removed {
+		// XXX: Make sure the mutex is unlocked before calling sendEvent().
+		r.sendEvent()
+		r.sendLinkEvents(nil, down)
+	}
+}
+
+func (r *topology) Aggregation(p *Port) []*Port {
+	return r.lags.aggregation(p)
+}
+
 // Node may return nil if the node is unregistered or still undiscovered.
 func (r *topology) Node(mac net.HardwareAddr) (*Node, LocationStatus, error) {
 	// Read lock
@@ -209,6 +650,7 @@ func (r *topology) Node(mac net.HardwareAddr) (*Node, LocationStatus, error) {
 
 func (r *topology) PortRemoved(p *Port) {
 	edge := false
+	var down []*link
 
 	// NOTE: This is an anonymous function (NOT a goroutine!) that has a critical section.
 	func() {
@@ -216,15 +658,19 @@ func (r *topology) PortRemoved(p *Port) {
 		r.mutex.Lock()
 		defer r.mutex.Unlock()
 
+		r.lags.remove(p)
 		if edge = r.graph.IsEdge(p); edge == true {
 			// Remove an edge from the graph if this port is an edge connected to another switch
 			r.graph.RemoveEdge(p)
+			_, down = r.syncLinks()
 		}
 	}()
 
 	if edge {
+		r.saveLinks(nil, down)
 		// XXX: Make sure the mutex is unlocked before calling sendEvent().
 		r.sendEvent()
+		r.sendLinkEvents(nil, down)
 	}
 }
 
@@ -252,6 +698,26 @@ func (r *topology) Path(srcDeviceID, dstDeviceID string) [][2]*Port {
 	return v
 }
 
+func (r *topology) NextHops(srcDeviceID, dstDeviceID string) [][2]*Port {
+	// Read lock
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	v := make([][2]*Port, 0)
+	src := r.devices[srcDeviceID]
+	dst := r.devices[dstDeviceID]
+	// Unknown source or destination device?
+	if src == nil || dst == nil {
+		return v
+	}
+
+	for _, e := range r.graph.FindNextHops(src, dst) {
+		v = append(v, pickPort(src, e.(*link)))
+	}
+
+	return v
+}
+
 func pickPort(d *Device, l *link) [2]*Port {
 	p := l.Points()
 	if p[0].Vertex().ID() == d.ID() {
@@ -261,21 +727,86 @@ func pickPort(d *Device, l *link) [2]*Port {
 	return [2]*Port{p[1].(*Port), p[0].(*Port)}
 }
 
+// vertexID refers a graph vertex, which may not be added yet, by its ID.
+type vertexID string
+
+func (r vertexID) ID() string {
+	return string(r)
+}
+
+func (r *topology) SetDrained(id string, drained bool) error {
+	dpid, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid device ID: %v", id)
+	}
+	ok, err := r.db.SetSwitchDrained(dpid, drained)
+	if err != nil {
+		return errors.Wrap(&networkErr{temporary: true, err: err}, "updating the drained state to the database")
+	}
+	if !ok {
+		return fmt.Errorf("unregistered switch: DPID=%v", id)
+	}
+
+	var changed bool
+	// NOTE: This is an anonymous function (NOT a goroutine!) that has a critical section.
+	func() {
+		// Write lock
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+
+		changed = r.graph.SetDrained(vertexID(id), drained)
+	}()
+
+	// Send the event only if the topology has been changed so that the
+	// applications move their paths according to the new spanning tree.
+	if changed {
+		if drained {
+			logger.Infof("draining device %v", id)
+		} else {
+			logger.Infof("undrained device %v", id)
+		}
+		// XXX: Make sure the mutex is unlocked before calling sendEvent().
+		r.sendEvent()
+	}
+
+	return nil
+}
+
+func (r *topology) IsDrained(id string) bool {
+	return r.graph.IsDrained(vertexID(id))
+}
+
+func (r *topology) IsTransit(id string) bool {
+	return r.graph.IsTransit(vertexID(id))
+}
+
 func (r *topology) IsEdge(p *Port) bool {
 	return r.graph.IsEdge(p)
 }
 
 func (r *topology) IsEnabledBySTP(p *Port) bool {
-	return r.graph.IsEnabledPoint(p)
+	if r.graph.IsEnabledPoint(p) {
+		return true
+	}
+	// The partner of a link aggregation treats the members as a single logical link, so all the
+	// members are enabled if one of them is.
+	for _, m := range r.lags.aggregation(p) {
+		if r.graph.IsEnabledPoint(m) {
+			return true
+		}
+	}
+
+	return false
 }
 
 // staleEdgeRemover removes stale edges that have not been updated for a long time.
 func (r *topology) staleEdgeRemover() {
-	ticker := time.Tick(10 * time.Second)
+	ticker := r.clock.NewTicker(10 * time.Second)
 
 	// Infinite loop.
-	for range ticker {
+	for range ticker.C() {
 		var removed bool
+		var down []*link
 
 		// NOTE: This is an anonymous function (NOT a goroutine!) that has a critical section.
 		func() {
@@ -285,13 +816,18 @@ func (r *topology) staleEdgeRemover() {
 
 			logger.Debug("trying to remove stale edges from the topology...")
 			removed = r.graph.RemoveStaleEdges(deviceExplorerInterval * 3)
+			if removed {
+				_, down = r.syncLinks()
+			}
 		}()
 
 		// Send the event only if the topology has been changed.
 		if removed {
 			logger.Debug("removed stale edge(s) from the topology")
+			r.saveLinks(nil, down)
 			// XXX: Make sure the mutex is unlocked before calling sendEvent().
 			r.sendEvent()
+			r.sendLinkEvents(nil, down)
 		}
 	}
 }
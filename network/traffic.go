@@ -0,0 +1,217 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/superkkt/cherry/clock"
+	"github.com/superkkt/cherry/protocol"
+	"github.com/superkkt/cherry/sflow"
+
+	"github.com/ant0ine/go-json-rest/rest"
+	"github.com/superkkt/viper"
+)
+
+// TrafficEntry is the traffic from a host to another, which is estimated from the packets
+// sampled by the sFlow agents of the switches.
+type TrafficEntry struct {
+	SrcMAC string `json:"src_mac"`
+	DstMAC string `json:"dst_mac"`
+	// Switch port where the traffic enters the network.
+	SrcDPID string `json:"src_dpid"`
+	SrcPort uint32 `json:"src_port"`
+	// Switch port that the destination host is connected to. Empty if the location is unknown.
+	DstDPID string `json:"dst_dpid,omitempty"`
+	DstPort uint32 `json:"dst_port,omitempty"`
+	// Estimated number of packets and bytes.
+	Packets   uint64    `json:"packets"`
+	Bytes     uint64    `json:"bytes"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// trafficMatrix accumulates the traffic among the hosts from the sFlow samples. Only the
+// packets sampled on the switch ports that are not connected to another switch are counted,
+// so that a packet sampled on every switch along its path is counted once. The sFlow agent
+// of a switch should use the address of its OpenFlow connection as the agent address, and
+// the OpenFlow port numbers as the interface indexes.
+type trafficMatrix struct {
+	mutex sync.Mutex
+	clock clock.Clock
+	// Key is the source and destination MAC addresses.
+	entries map[string]*TrafficEntry
+}
+
+func newTrafficMatrix(clk clock.Clock) *trafficMatrix {
+	if clk == nil {
+		panic("clock is nil")
+	}
+
+	return &trafficMatrix{
+		clock:   clk,
+		entries: make(map[string]*TrafficEntry),
+	}
+}
+
+// agentDevice returns the device whose OpenFlow connection comes from the agent address.
+func agentDevice(finder Finder, agent net.IP) *Device {
+	for _, d := range finder.Devices() {
+		if d.IsClosed() {
+			continue
+		}
+		addr, ok := d.RemoteAddr().(*net.TCPAddr)
+		if ok && addr.IP.Equal(agent) {
+			return d
+		}
+	}
+
+	return nil
+}
+
+// Add accumulates the flow samples of datagram d.
+func (r *trafficMatrix) Add(finder Finder, d sflow.Datagram) {
+	device := agentDevice(finder, d.AgentAddress)
+	if device == nil {
+		logger.Debugf("ignored the sFlow datagram from an unknown agent: %v", d.AgentAddress)
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := r.clock.Now()
+	for _, s := range d.Samples {
+		if s.Header == nil || s.Header.Protocol != sflow.HeaderProtocolEthernet || s.Input == sflow.UnknownInterface {
+			continue
+		}
+		ingress := device.Port(s.Input)
+		if ingress == nil || finder.IsEdge(ingress) {
+			continue
+		}
+		eth := new(protocol.Ethernet)
+		if err := eth.UnmarshalBinary(s.Header.Header); err != nil {
+			continue
+		}
+
+		key := eth.SrcMAC.String() + "/" + eth.DstMAC.String()
+		v, ok := r.entries[key]
+		if !ok {
+			v = &TrafficEntry{
+				SrcMAC:    eth.SrcMAC.String(),
+				DstMAC:    eth.DstMAC.String(),
+				FirstSeen: now,
+			}
+			r.entries[key] = v
+		}
+		// The host may have been moved.
+		v.SrcDPID = device.ID()
+		v.SrcPort = ingress.Number()
+		v.Packets += uint64(s.SamplingRate)
+		v.Bytes += uint64(s.SamplingRate) * uint64(s.Header.FrameLength)
+		v.LastSeen = now
+	}
+}
+
+// Entries returns the accumulated traffic sorted by the source and destination MAC addresses.
+func (r *trafficMatrix) Entries() []TrafficEntry {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	entries := make([]TrafficEntry, 0, len(r.entries))
+	for _, v := range r.entries {
+		entries = append(entries, *v)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].SrcMAC != entries[j].SrcMAC {
+			return entries[i].SrcMAC < entries[j].SrcMAC
+		}
+		return entries[i].DstMAC < entries[j].DstMAC
+	})
+
+	return entries
+}
+
+func (r *trafficMatrix) Reset() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.entries = make(map[string]*TrafficEntry)
+}
+
+// serveSFlow collects the sFlow datagrams on sflow.listen_addr and sflow.port.
+func (r *Controller) serveSFlow() {
+	addr := net.JoinHostPort(viper.GetString("sflow.listen_addr"), strconv.Itoa(viper.GetInt("sflow.port")))
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		logger.Errorf("failed to listen on the sFlow port: %v", err)
+		return
+	}
+	logger.Infof("collecting sFlow datagrams on %v", addr)
+
+	err = sflow.Serve(context.Background(), conn, func(d sflow.Datagram) {
+		r.traffic.Add(r.topo, d)
+	})
+	logger.Errorf("sFlow collector terminated: %v", err)
+}
+
+func (r *Controller) listTraffic(w rest.ResponseWriter, req *rest.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	entries := r.traffic.Entries()
+	for i, v := range entries {
+		mac, err := net.ParseMAC(v.DstMAC)
+		if err != nil {
+			continue
+		}
+		node, status, err := r.topo.Node(mac)
+		if err != nil {
+			logger.Errorf("failed to query the host location: %v", err)
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if status != LocationDiscovered {
+			continue
+		}
+		entries[i].DstDPID = node.Port().Device().ID()
+		entries[i].DstPort = node.Port().Number()
+	}
+
+	w.WriteJson(&struct {
+		Traffic []TrafficEntry `json:"traffic"`
+	}{entries})
+}
+
+func (r *Controller) resetTraffic(w rest.ResponseWriter, req *rest.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	r.traffic.Reset()
+	logger.Infof("reset the traffic matrix")
+
+	w.WriteJson(&struct{}{})
+}
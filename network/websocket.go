@@ -0,0 +1,248 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/superkkt/cherry/clock"
+)
+
+// Minimal server side implementation of the WebSocket protocol (RFC 6455) that is enough to
+// push the events to the clients. Messages from the clients are discarded.
+
+const (
+	wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+	wsOpText  = 0x1
+	wsOpClose = 0x8
+	wsOpPing  = 0x9
+	wsOpPong  = 0xA
+
+	wsCloseNormal          = 1000
+	wsClosePolicyViolation = 1008
+
+	// Maximum payload of the frames that we read from the clients.
+	wsMaxPayload   = 64 * 1024
+	wsWriteTimeout = 5 * time.Second
+)
+
+type wsConn struct {
+	conn net.Conn
+	rw   *bufio.ReadWriter
+	// Serializes the frames written by the event sender and the reader that replies to pings.
+	mutex sync.Mutex
+}
+
+func isWebSocketRequest(req *http.Request) bool {
+	return headerContains(req.Header, "Connection", "upgrade") && strings.EqualFold(req.Header.Get("Upgrade"), "websocket")
+}
+
+// headerContains returns whether the comma-separated header has token.
+func headerContains(h http.Header, name, token string) bool {
+	for _, v := range h[http.CanonicalHeaderKey(name)] {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// wsAcceptKey returns the value of the Sec-WebSocket-Accept header for key.
+func wsAcceptKey(key string) string {
+	h := sha1.Sum([]byte(key + wsGUID))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+// upgradeWebSocket switches the HTTP connection of req to the WebSocket protocol. The response
+// has not been written if an error is returned.
+func upgradeWebSocket(w http.ResponseWriter, req *http.Request) (*wsConn, error) {
+	if req.Method != "GET" || req.ProtoMajor != 1 {
+		return nil, errors.New("WebSocket requires a GET request of HTTP/1.1")
+	}
+	if !isWebSocketRequest(req) {
+		return nil, errors.New("not a WebSocket upgrade request")
+	}
+	if req.Header.Get("Sec-WebSocket-Version") != "13" {
+		return nil, errors.New("unsupported WebSocket version")
+	}
+	key := req.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		return nil, errors.New("missing Sec-WebSocket-Key")
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, errors.New("connection does not support hijacking")
+	}
+
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	v := &wsConn{conn: conn, rw: rw}
+	resp := "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: " + wsAcceptKey(key) + "\r\n\r\n"
+	if err := v.write([]byte(resp)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return v, nil
+}
+
+func (r *wsConn) write(p []byte) error {
+	// Socket deadlines are based on the wall clock.
+	r.conn.SetWriteDeadline(clock.Real.Now().Add(wsWriteTimeout))
+	if _, err := r.rw.Write(p); err != nil {
+		return err
+	}
+
+	return r.rw.Flush()
+}
+
+func (r *wsConn) writeFrame(opcode byte, payload []byte) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.write(encodeWSFrame(opcode, payload))
+}
+
+// encodeWSFrame returns an unmasked, unfragmented frame as the server should send.
+func encodeWSFrame(opcode byte, payload []byte) []byte {
+	header := []byte{0x80 | opcode}
+	switch n := len(payload); {
+	case n <= 125:
+		header = append(header, byte(n))
+	case n <= 0xFFFF:
+		header = append(header, 126, 0, 0)
+		binary.BigEndian.PutUint16(header[2:], uint16(n))
+	default:
+		header = append(header, 127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(header[2:], uint64(n))
+	}
+
+	return append(header, payload...)
+}
+
+func (r *wsConn) writeText(payload []byte) error {
+	return r.writeFrame(wsOpText, payload)
+}
+
+func (r *wsConn) writeClose(code uint16, reason string) error {
+	payload := make([]byte, 2, 2+len(reason))
+	binary.BigEndian.PutUint16(payload, code)
+
+	return r.writeFrame(wsOpClose, append(payload, reason...))
+}
+
+// readLoop reads the frames from the client until the client closes the connection or an
+// error occurs. It answers pings and discards the data frames.
+func (r *wsConn) readLoop() error {
+	for {
+		opcode, payload, err := r.readFrame()
+		if err != nil {
+			return err
+		}
+
+		switch opcode {
+		case wsOpPing:
+			if err := r.writeFrame(wsOpPong, payload); err != nil {
+				return err
+			}
+		case wsOpClose:
+			// Echo the status code back as required by the protocol.
+			r.writeFrame(wsOpClose, payload)
+			return io.EOF
+		}
+	}
+}
+
+func (r *wsConn) readFrame() (opcode byte, payload []byte, err error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(r.rw, header); err != nil {
+		return 0, nil, err
+	}
+	opcode = header[0] & 0x0F
+	masked := header[1]&0x80 != 0
+	if !masked {
+		return 0, nil, errors.New("unmasked frame from the client")
+	}
+
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		ext := make([]byte, 2)
+		if _, err := io.ReadFull(r.rw, ext); err != nil {
+			return 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext))
+	case 127:
+		ext := make([]byte, 8)
+		if _, err := io.ReadFull(r.rw, ext); err != nil {
+			return 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext)
+	}
+
+	mask := make([]byte, 4)
+	if _, err := io.ReadFull(r.rw, mask); err != nil {
+		return 0, nil, err
+	}
+	if length > wsMaxPayload {
+		// We do not use the data frames, so skip the large ones.
+		if _, err := io.CopyN(ioutil.Discard, r.rw, int64(length)); err != nil {
+			return 0, nil, err
+		}
+		return opcode, nil, nil
+	}
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(r.rw, payload); err != nil {
+		return 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+
+	return opcode, payload, nil
+}
+
+func (r *wsConn) Close() error {
+	return r.conn.Close()
+}
+
+func (r *wsConn) String() string {
+	return fmt.Sprintf("WebSocket(%v)", r.conn.RemoteAddr())
+}
@@ -0,0 +1,114 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package network
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebSocketAcceptKey(t *testing.T) {
+	// Example of RFC 6455 section 1.3.
+	if v := wsAcceptKey("dGhlIHNhbXBsZSBub25jZQ=="); v != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("unexpected accept key: %v", v)
+	}
+}
+
+// maskedFrame returns a frame that a client would send.
+func maskedFrame(opcode byte, payload []byte) []byte {
+	mask := []byte{1, 2, 3, 4}
+	v := []byte{0x80 | opcode, 0x80 | byte(len(payload))}
+	v = append(v, mask...)
+	for i, b := range payload {
+		v = append(v, b^mask[i%4])
+	}
+
+	return v
+}
+
+func TestWebSocketStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		conn, err := upgradeWebSocket(w, req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer conn.Close()
+		if err := conn.writeText([]byte(`{"type":"device_up"}`)); err != nil {
+			t.Error(err)
+			return
+		}
+		conn.readLoop()
+	}))
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	io.WriteString(conn, "GET /api/v1/events HTTP/1.1\r\nHost: localhost\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n"+
+		"Sec-WebSocket-Version: 13\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n\r\n")
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("unexpected response: %v, %v", resp.Status, resp.Header)
+	}
+
+	readFrame := func() (opcode byte, payload []byte) {
+		header := make([]byte, 2)
+		if _, err := io.ReadFull(reader, header); err != nil {
+			t.Fatal(err)
+		}
+		payload = make([]byte, header[1])
+		if _, err := io.ReadFull(reader, payload); err != nil {
+			t.Fatal(err)
+		}
+		return header[0] & 0x0F, payload
+	}
+
+	if opcode, payload := readFrame(); opcode != wsOpText || string(payload) != `{"type":"device_up"}` {
+		t.Fatalf("unexpected frame: opcode=%v, payload=%s", opcode, payload)
+	}
+	conn.Write(maskedFrame(wsOpPing, []byte("hi")))
+	if opcode, payload := readFrame(); opcode != wsOpPong || string(payload) != "hi" {
+		t.Fatalf("unexpected pong: opcode=%v, payload=%s", opcode, payload)
+	}
+	status := make([]byte, 2)
+	binary.BigEndian.PutUint16(status, wsCloseNormal)
+	conn.Write(maskedFrame(wsOpClose, status))
+	if opcode, payload := readFrame(); opcode != wsOpClose || binary.BigEndian.Uint16(payload) != wsCloseNormal {
+		t.Fatalf("unexpected close: opcode=%v, payload=%v", opcode, payload)
+	}
+}
@@ -0,0 +1,383 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package blacklist
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/superkkt/cherry/clock"
+	"github.com/superkkt/cherry/network"
+	"github.com/superkkt/cherry/northbound/app"
+	"github.com/superkkt/cherry/northbound/app/hosttracker"
+	"github.com/superkkt/cherry/openflow"
+	"github.com/superkkt/cherry/protocol"
+
+	"github.com/superkkt/go-logging"
+)
+
+var (
+	logger = logging.MustGetLogger("blacklist")
+)
+
+const (
+	// Interval to reload the blacklist from the database and to synchronize the flows of the edge switches.
+	syncInterval = 5 * time.Second
+	// Priority of the drop flows, which is higher than the ones of the firewall rules (10100 ~)
+	// so that the blacklisted hosts are not allowed by any rule. The lowest priority of the
+	// priority band of the application is used instead, if any.
+	dropPriority = 20000
+)
+
+// Entry is a blacklisted MAC or IPv4 address. Either MAC or IP is nil.
+type Entry struct {
+	ID  uint64
+	MAC net.HardwareAddr
+	IP  net.IP
+}
+
+func (r Entry) String() string {
+	if r.MAC != nil {
+		return fmt.Sprintf("Entry ID=%v, MAC=%v", r.ID, r.MAC)
+	}
+
+	return fmt.Sprintf("Entry ID=%v, IP=%v", r.ID, r.IP)
+}
+
+// key identifies the blacklisted address of the entry.
+func (r Entry) key() string {
+	if r.MAC != nil {
+		return "mac/" + r.MAC.String()
+	}
+
+	return "ip/" + r.IP.String()
+}
+
+// Blacklist drops the packets from the blacklisted MAC and IPv4 addresses, which are
+// managed by the REST API. It installs the drop flows on the edge switches, i.e., the
+// switches that have at least one port not connected to another switch, including the
+// switches connected later, and drops the PACKET_INs from the addresses before the next
+// applications receive them. When an address is newly blacklisted, the hosts learned with
+// the address are forgotten, and the flows heading to them are removed. This application
+// should precede HostTracker and Discovery so that they do not learn the blacklisted hosts.
+type Blacklist struct {
+	app.BaseProcessor
+	db      database
+	tracker tracker
+	clock   clock.Clock
+	once    sync.Once
+
+	mutex sync.Mutex
+	// Key is Entry.key().
+	entries map[string]Entry
+	// Entries whose learned hosts have been forgotten. Key is Entry.key().
+	forgotten map[string]bool
+	// Flows installed on each device. Key is the DPID, and value is the entries keyed by Entry.key().
+	installed map[string]map[string]Entry
+}
+
+type database interface {
+	// GetBlacklist returns all the blacklisted addresses.
+	GetBlacklist() ([]Entry, error)
+	// ResetHostLocations forgets the locations of the registered hosts whose MAC address is
+	// mac or IP address is ip. mac or ip can be nil.
+	ResetHostLocations(mac net.HardwareAddr, ip net.IP) error
+}
+
+type tracker interface {
+	// Forget forgets the hosts whose MAC address is mac or IP address is ip, and returns them.
+	Forget(mac net.HardwareAddr, ip net.IP) []hosttracker.Host
+}
+
+func New(db database, tracker tracker) *Blacklist {
+	return newBlacklist(db, tracker, clock.Real)
+}
+
+func newBlacklist(db database, tracker tracker, clk clock.Clock) *Blacklist {
+	if clk == nil {
+		panic("clock is nil")
+	}
+
+	return &Blacklist{
+		db:        db,
+		tracker:   tracker,
+		clock:     clk,
+		entries:   make(map[string]Entry),
+		forgotten: make(map[string]bool),
+		installed: make(map[string]map[string]Entry),
+	}
+}
+
+func (r *Blacklist) Name() string {
+	return "Blacklist"
+}
+
+func (r *Blacklist) String() string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return fmt.Sprintf("%v (entries=%v)", r.Name(), len(r.entries))
+}
+
+func (r *Blacklist) OnDeviceUp(finder network.Finder, device *network.Device) error {
+	// The device has removed all the flows when it connected.
+	r.mutex.Lock()
+	delete(r.installed, device.ID())
+	r.mutex.Unlock()
+
+	// Make sure that there is only one synchronizer in this application.
+	r.once.Do(func() {
+		go r.synchronizer(finder)
+	})
+
+	return r.BaseProcessor.OnDeviceUp(finder, device)
+}
+
+func (r *Blacklist) OnDeviceDown(finder network.Finder, device *network.Device) error {
+	r.mutex.Lock()
+	delete(r.installed, device.ID())
+	r.mutex.Unlock()
+
+	return r.BaseProcessor.OnDeviceDown(finder, device)
+}
+
+func (r *Blacklist) OnPacketIn(finder network.Finder, ingress *network.Port, eth *protocol.Ethernet) error {
+	r.mutex.Lock()
+	blacklisted := r.isBlacklisted(eth.SrcMAC, senderIP(eth))
+	r.mutex.Unlock()
+	if blacklisted {
+		logger.Debugf("dropping a packet from a blacklisted host: ingress=%v, src=%v", ingress.ID(), eth.SrcMAC)
+		// Drop the packet.
+		return nil
+	}
+
+	return r.BaseProcessor.OnPacketIn(finder, ingress, eth)
+}
+
+// XXX: Caller should lock the mutex before they call this function
+func (r *Blacklist) isBlacklisted(mac net.HardwareAddr, ip net.IP) bool {
+	if _, ok := r.entries[Entry{MAC: mac}.key()]; ok {
+		return true
+	}
+	if ip == nil {
+		return false
+	}
+	_, ok := r.entries[Entry{IP: ip}.key()]
+
+	return ok
+}
+
+// senderIP returns the source IPv4 address of eth, or nil if eth is neither IPv4 nor ARP.
+func senderIP(eth *protocol.Ethernet) net.IP {
+	switch eth.Type {
+	case 0x0800:
+		ip := new(protocol.IPv4)
+		if err := ip.UnmarshalBinary(eth.Payload); err != nil {
+			return nil
+		}
+		return ip.SrcIP
+	case 0x0806:
+		arp := new(protocol.ARP)
+		if err := arp.UnmarshalBinary(eth.Payload); err != nil {
+			return nil
+		}
+		return arp.SPA
+	default:
+		return nil
+	}
+}
+
+func (r *Blacklist) synchronizer(finder network.Finder) {
+	logger.Debug("executed the blacklist synchronizer")
+
+	ticker := r.clock.NewTicker(syncInterval)
+	defer ticker.Stop()
+
+	// Infinite loop.
+	for {
+		if err := r.sync(finder); err != nil {
+			logger.Errorf("failed to synchronize the blacklist: %v", err)
+		}
+		<-ticker.C()
+	}
+}
+
+// sync reloads the blacklist, forgets the newly blacklisted hosts, and then installs and
+// removes the flows of the edge switches according to the changes of the blacklist and
+// the topology.
+func (r *Blacklist) sync(finder network.Finder) error {
+	blacklist, err := r.db.GetBlacklist()
+	if err != nil {
+		return err
+	}
+	entries := make(map[string]Entry)
+	for _, v := range blacklist {
+		entries[v.key()] = v
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.entries = entries
+	for key := range r.forgotten {
+		if _, ok := entries[key]; !ok {
+			delete(r.forgotten, key)
+		}
+	}
+	for key, v := range entries {
+		if r.forgotten[key] {
+			continue
+		}
+		logger.Infof("blacklisted: %v", v)
+		if err := r.forget(finder, v); err != nil {
+			// Retry on the next synchronization.
+			logger.Errorf("failed to forget the blacklisted hosts of %v: %v", v, err)
+			continue
+		}
+		r.forgotten[key] = true
+	}
+
+	for _, device := range finder.Devices() {
+		if device.IsClosed() {
+			continue
+		}
+		desired := make(map[string]Entry)
+		if app.IsEdgeSwitch(finder, device) {
+			desired = entries
+		}
+		if err := r.syncDevice(device, desired); err != nil {
+			logger.Errorf("failed to synchronize the blacklist flows of %v: %v", device.ID(), err)
+			continue
+		}
+	}
+
+	return nil
+}
+
+// forget removes the learned hosts of the blacklisted entry and the flows heading to them.
+// XXX: Caller should lock the mutex before they call this function
+func (r *Blacklist) forget(finder network.Finder, entry Entry) error {
+	if err := r.db.ResetHostLocations(entry.MAC, entry.IP); err != nil {
+		return err
+	}
+
+	macs := []net.HardwareAddr{}
+	if entry.MAC != nil {
+		macs = append(macs, entry.MAC)
+	}
+	for _, h := range r.tracker.Forget(entry.MAC, entry.IP) {
+		logger.Infof("forgot the blacklisted host: %v", h)
+		if entry.MAC == nil {
+			macs = append(macs, h.MAC)
+		}
+	}
+	for _, mac := range macs {
+		for _, device := range finder.Devices() {
+			if device.IsClosed() {
+				continue
+			}
+			if err := device.RemoveFlowByMAC(mac); err != nil {
+				return err
+			}
+			logger.Debugf("removed flows whose destination MAC address is %v on %v", mac, device.ID())
+		}
+	}
+
+	return nil
+}
+
+// XXX: Caller should lock the mutex before they call this function
+func (r *Blacklist) syncDevice(device *network.Device, desired map[string]Entry) error {
+	installed, ok := r.installed[device.ID()]
+	if !ok {
+		installed = make(map[string]Entry)
+		r.installed[device.ID()] = installed
+	}
+
+	for key, v := range installed {
+		if _, ok := desired[key]; ok {
+			continue
+		}
+		if err := r.sendFlow(device, openflow.FlowDeleteStrict, v); err != nil {
+			return err
+		}
+		delete(installed, key)
+		logger.Debugf("removed the blacklist flow from %v: %v", device.ID(), v)
+	}
+	for key, v := range desired {
+		if _, ok := installed[key]; ok {
+			continue
+		}
+		if err := r.sendFlow(device, openflow.FlowAdd, v); err != nil {
+			return err
+		}
+		installed[key] = v
+		logger.Debugf("installed the blacklist flow on %v: %v", device.ID(), v)
+	}
+
+	return nil
+}
+
+func newMatch(f openflow.Factory, entry Entry) (openflow.Match, error) {
+	match, err := f.NewMatch()
+	if err != nil {
+		return nil, err
+	}
+	if entry.MAC != nil {
+		match.SetSrcMAC(entry.MAC)
+	} else {
+		match.SetEtherType(0x0800) // IPv4
+		match.SetSrcIP(&net.IPNet{IP: entry.IP.To4(), Mask: net.CIDRMask(32, 32)})
+	}
+
+	return match, nil
+}
+
+func (r *Blacklist) sendFlow(device *network.Device, cmd openflow.FlowModCmd, entry Entry) error {
+	f := device.Factory()
+	match, err := newMatch(f, entry)
+	if err != nil {
+		return err
+	}
+
+	flow, err := f.NewFlowMod(cmd)
+	if err != nil {
+		return err
+	}
+	priority, err := device.FlowPriority(r.Name(), device.FlowTableID(), dropPriority, 0)
+	if err != nil {
+		return err
+	}
+	flow.SetCookie(network.NewCookie(r.Name(), entry.ID))
+	flow.SetTableID(device.FlowTableID())
+	flow.SetPriority(priority)
+	flow.SetFlowMatch(match)
+	// The flow has no action, which drops the packets.
+
+	if cmd == openflow.FlowAdd {
+		return device.InstallFlowWithLifetime(flow, network.FlowLifetime{Class: network.FlowPermanent})
+	}
+
+	return device.InstallFlow(flow)
+}
@@ -0,0 +1,69 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package blacklist
+
+import (
+	"net"
+	"testing"
+
+	"github.com/superkkt/cherry/clock"
+	"github.com/superkkt/cherry/protocol"
+)
+
+func TestIsBlacklisted(t *testing.T) {
+	r := newBlacklist(nil, nil, clock.Real)
+	mac := net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}
+	ip := net.IPv4(10, 0, 0, 1)
+	for _, v := range []Entry{{ID: 1, MAC: mac}, {ID: 2, IP: ip}} {
+		r.entries[v.key()] = v
+	}
+
+	other := net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x66}
+	// The address parsed from a packet is 4 bytes long.
+	if !r.isBlacklisted(other, ip.To4()) {
+		t.Fatal("expected a blacklisted IP address")
+	}
+	if !r.isBlacklisted(mac, nil) {
+		t.Fatal("expected a blacklisted MAC address")
+	}
+	if r.isBlacklisted(other, net.IPv4(10, 0, 0, 2)) || r.isBlacklisted(other, nil) {
+		t.Fatal("unexpected blacklisted host")
+	}
+}
+
+func TestSenderIP(t *testing.T) {
+	mac := net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}
+	ip := net.IPv4(10, 0, 0, 1)
+	arp, err := protocol.NewARPRequest(mac, ip, net.IPv4(10, 0, 0, 2)).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	eth := &protocol.Ethernet{SrcMAC: mac, Type: 0x0806, Payload: arp}
+	if v := senderIP(eth); !ip.Equal(v) {
+		t.Fatalf("unexpected sender IP address: expected=%v, got=%v", ip, v)
+	}
+	eth.Type = 0x86DD // IPv6
+	if v := senderIP(eth); v != nil {
+		t.Fatalf("unexpected sender IP address: %v", v)
+	}
+}
@@ -0,0 +1,384 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package dhcp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/superkkt/cherry/clock"
+	"github.com/superkkt/cherry/network"
+	"github.com/superkkt/cherry/northbound/app"
+	"github.com/superkkt/cherry/northbound/app/hosttracker"
+	"github.com/superkkt/cherry/openflow"
+	"github.com/superkkt/cherry/protocol"
+
+	"github.com/superkkt/go-logging"
+	"github.com/superkkt/viper"
+)
+
+var (
+	logger = logging.MustGetLogger("dhcp")
+)
+
+const (
+	serverPort = 67
+	clientPort = 68
+
+	// Lease time that is assumed if the server does not specify it.
+	defaultLeaseTime = 24 * time.Hour
+	// Infinite lease time (RFC 2131).
+	infiniteLeaseTime = 0xFFFFFFFF
+)
+
+// Binding is an IP address leased to a host by a DHCP server.
+type Binding struct {
+	MAC net.HardwareAddr
+	IP  net.IP
+	// DPID and Port are the location where this host was attached when the address was leased.
+	// They are zero if the location was unknown.
+	DPID uint64
+	Port uint32
+	// Zero Expiration means an infinite lease.
+	Expiration time.Time
+}
+
+func (r Binding) String() string {
+	return fmt.Sprintf("MAC=%v, IP=%v, DPID=%v, Port=%v, Expiration=%v", r.MAC, r.IP, r.DPID, r.Port, r.Expiration)
+}
+
+type hostTracker interface {
+	Host(mac net.HardwareAddr) (host hosttracker.Host, ok bool)
+	HostsByIP(ip net.IP) []hosttracker.Host
+	Bind(mac net.HardwareAddr, ip net.IP) (ok bool)
+}
+
+// Snooper learns the IP address bindings from the DHCP messages, and feeds them to the host tracker
+// as authoritative address information. If a DHCP server is configured, requests from the clients
+// are relayed only to the server instead of being flooded, and replies from other servers are dropped.
+type Snooper struct {
+	app.BaseProcessor
+	clock   clock.Clock
+	tracker hostTracker
+	// DHCP server to relay the requests to. Relay is disabled if it is nil.
+	server net.IP
+
+	mutex sync.RWMutex
+	// Key is the MAC address.
+	bindings map[string]*Binding
+}
+
+func New(tracker hostTracker) *Snooper {
+	return newSnooper(clock.Real, tracker)
+}
+
+func newSnooper(clk clock.Clock, tracker hostTracker) *Snooper {
+	if clk == nil {
+		panic("clock is nil")
+	}
+	if tracker == nil {
+		panic("host tracker is nil")
+	}
+
+	return &Snooper{
+		clock:    clk,
+		tracker:  tracker,
+		bindings: make(map[string]*Binding),
+	}
+}
+
+func (r *Snooper) Init() error {
+	server := viper.GetString("dhcp.server")
+	if len(server) == 0 {
+		return nil
+	}
+	ip := net.ParseIP(server)
+	if ip == nil || ip.To4() == nil {
+		return fmt.Errorf("invalid dhcp.server in the config file: %v", server)
+	}
+	r.server = ip
+
+	return nil
+}
+
+func (r *Snooper) Name() string {
+	return "DHCPSnooping"
+}
+
+func (r *Snooper) String() string {
+	return fmt.Sprintf("%v", r.Name())
+}
+
+func (r *Snooper) Dependencies() []string {
+	return []string{"HostTracker"}
+}
+
+// Binding returns the binding of a host whose MAC address is mac. ok will be false if the host
+// does not have a lease or its lease is expired.
+func (r *Snooper) Binding(mac net.HardwareAddr) (binding Binding, ok bool) {
+	// Read lock
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	v, ok := r.bindings[mac.String()]
+	if !ok || r.isExpired(v) {
+		return Binding{}, false
+	}
+
+	return *v, true
+}
+
+// Bindings returns all the bindings that are not expired.
+func (r *Snooper) Bindings() []Binding {
+	// Read lock
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	v := make([]Binding, 0, len(r.bindings))
+	for _, b := range r.bindings {
+		if r.isExpired(b) {
+			continue
+		}
+		v = append(v, *b)
+	}
+
+	return v
+}
+
+// XXX: Caller should lock the mutex
+func (r *Snooper) isExpired(b *Binding) bool {
+	return !b.Expiration.IsZero() && !r.clock.Now().Before(b.Expiration)
+}
+
+// XXX: Caller should lock the mutex
+func (r *Snooper) purge() {
+	for mac, b := range r.bindings {
+		if r.isExpired(b) {
+			logger.Debugf("removing an expired binding: %v", b)
+			delete(r.bindings, mac)
+		}
+	}
+}
+
+// expiration returns the time when the lease in msg expires, or zero time if it is an infinite lease.
+func (r *Snooper) expiration(msg *protocol.DHCP) time.Time {
+	v, ok := msg.Option(protocol.DHCPOptionLeaseTime)
+	if !ok || len(v) != 4 {
+		return r.clock.Now().Add(defaultLeaseTime)
+	}
+	lease := binary.BigEndian.Uint32(v)
+	if lease == infiniteLeaseTime {
+		return time.Time{}
+	}
+
+	return r.clock.Now().Add(time.Duration(lease) * time.Second)
+}
+
+func (r *Snooper) bind(msg *protocol.DHCP) {
+	b := &Binding{
+		MAC:        msg.CHAddr,
+		IP:         msg.YIAddr,
+		Expiration: r.expiration(msg),
+	}
+	// The client should have been learned by the host tracker from its DHCP request.
+	if host, ok := r.tracker.Host(msg.CHAddr); ok {
+		b.DPID = host.DPID
+		b.Port = host.Port
+	}
+
+	// NOTE: This is an anonymous function (NOT a goroutine!) that has a critical section.
+	func() {
+		// Write lock
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+
+		r.purge()
+		r.bindings[b.MAC.String()] = b
+	}()
+	logger.Infof("learned a DHCP binding: %v", b)
+
+	if !r.tracker.Bind(b.MAC, b.IP) {
+		logger.Debugf("failed to bind %v to %v: unknown host", b.IP, b.MAC)
+	}
+}
+
+func (r *Snooper) release(mac net.HardwareAddr) {
+	// NOTE: This is an anonymous function (NOT a goroutine!) that has a critical section.
+	released := func() bool {
+		// Write lock
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+
+		if _, ok := r.bindings[mac.String()]; !ok {
+			return false
+		}
+		delete(r.bindings, mac.String())
+		return true
+	}()
+	if !released {
+		return
+	}
+	logger.Infof("released a DHCP binding: MAC=%v", mac)
+
+	r.tracker.Bind(mac, nil)
+}
+
+// snoop updates the bindings from msg that has been sent by src. It returns false if msg should be dropped.
+func (r *Snooper) snoop(src net.IP, msg *protocol.DHCP) bool {
+	switch msg.Op {
+	// BOOTREQUEST
+	case 1:
+		switch msg.MessageType() {
+		case protocol.DHCPRelease, protocol.DHCPDecline:
+			r.release(msg.CHAddr)
+		}
+	// BOOTREPLY
+	case 2:
+		if r.server != nil && !r.server.Equal(src) {
+			logger.Warningf("dropping a DHCP reply from an unknown server: %v", src)
+			return false
+		}
+		switch msg.MessageType() {
+		case protocol.DHCPAck:
+			// DHCPACK for DHCPINFORM does not have an assigned address.
+			if !msg.YIAddr.IsUnspecified() {
+				r.bind(msg)
+			}
+		case protocol.DHCPNak:
+			r.release(msg.CHAddr)
+		}
+	}
+
+	return true
+}
+
+// parse returns the IPv4 header and the DHCP message in eth, or nil if eth is not a DHCP packet.
+func parse(eth *protocol.Ethernet) (*protocol.IPv4, *protocol.DHCP) {
+	if eth.Type != 0x0800 {
+		return nil, nil
+	}
+	ip := new(protocol.IPv4)
+	if err := ip.UnmarshalBinary(eth.Payload); err != nil {
+		return nil, nil
+	}
+	// UDP
+	if ip.Protocol != 17 {
+		return nil, nil
+	}
+	udp := new(protocol.UDP)
+	if err := udp.UnmarshalBinary(ip.Payload); err != nil {
+		return nil, nil
+	}
+	if !(udp.SrcPort == clientPort && udp.DstPort == serverPort) && !(udp.SrcPort == serverPort && udp.DstPort == clientPort) {
+		return nil, nil
+	}
+	msg := new(protocol.DHCP)
+	if err := msg.UnmarshalBinary(udp.Payload); err != nil {
+		return nil, nil
+	}
+
+	return ip, msg
+}
+
+func (r *Snooper) OnPacketIn(finder network.Finder, ingress *network.Port, eth *protocol.Ethernet) error {
+	ip, msg := parse(eth)
+	if msg == nil {
+		// Propagate this packet to the next processors.
+		return r.BaseProcessor.OnPacketIn(finder, ingress, eth)
+	}
+	logger.Debugf("received a DHCP message: op=%v, type=%v, xid=%v, chaddr=%v", msg.Op, msg.MessageType(), msg.XID, msg.CHAddr)
+
+	if !r.snoop(ip.SrcIP, msg) {
+		return nil
+	}
+	if msg.Op == 1 && r.server != nil {
+		ok, err := r.relay(finder, eth)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+	}
+
+	// Propagate this packet to the next processors.
+	return r.BaseProcessor.OnPacketIn(finder, ingress, eth)
+}
+
+// relay sends eth to the port where the DHCP server is attached. ok will be false if the location of the
+// server is unknown, so that the request is flooded by the next processors.
+func (r *Snooper) relay(finder network.Finder, eth *protocol.Ethernet) (ok bool, err error) {
+	hosts := r.tracker.HostsByIP(r.server)
+	if len(hosts) != 1 {
+		logger.Debugf("failed to relay a DHCP request: unknown location of the DHCP server (%v)", r.server)
+		return false, nil
+	}
+	device := finder.Device(strconv.FormatUint(hosts[0].DPID, 10))
+	if device == nil {
+		return false, nil
+	}
+	port := device.Port(hosts[0].Port)
+	if port == nil {
+		return false, nil
+	}
+
+	packet, err := eth.MarshalBinary()
+	if err != nil {
+		return false, err
+	}
+	if err := sendPacket(port, packet); err != nil {
+		return false, err
+	}
+	logger.Debugf("relayed a DHCP request to %v: DPID=%v, Port=%v", r.server, hosts[0].DPID, hosts[0].Port)
+
+	return true, nil
+}
+
+func sendPacket(egress *network.Port, packet []byte) error {
+	f := egress.Device().Factory()
+
+	inPort := openflow.NewInPort()
+	inPort.SetController()
+
+	outPort := openflow.NewOutPort()
+	outPort.SetValue(egress.Number())
+
+	action, err := f.NewAction()
+	if err != nil {
+		return err
+	}
+	action.SetOutPort(outPort)
+
+	out, err := f.NewPacketOut()
+	if err != nil {
+		return err
+	}
+	out.SetInPort(inPort)
+	out.SetAction(action)
+	out.SetData(packet)
+
+	return egress.Device().SendMessage(out)
+}
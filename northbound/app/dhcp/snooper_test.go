@@ -0,0 +1,114 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package dhcp
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/superkkt/cherry/northbound/app/hosttracker"
+	"github.com/superkkt/cherry/protocol"
+	"github.com/superkkt/cherry/testutil"
+)
+
+type fakeTracker struct {
+	hosts map[string]hosttracker.Host
+}
+
+func (r *fakeTracker) Host(mac net.HardwareAddr) (host hosttracker.Host, ok bool) {
+	host, ok = r.hosts[mac.String()]
+	return host, ok
+}
+
+func (r *fakeTracker) HostsByIP(ip net.IP) []hosttracker.Host {
+	return nil
+}
+
+func (r *fakeTracker) Bind(mac net.HardwareAddr, ip net.IP) (ok bool) {
+	host, ok := r.hosts[mac.String()]
+	if !ok {
+		return false
+	}
+	host.IP = ip
+	r.hosts[mac.String()] = host
+
+	return true
+}
+
+func newMessage(op, msgType uint8, mac net.HardwareAddr, ip net.IP, lease []byte) *protocol.DHCP {
+	msg := &protocol.DHCP{
+		Op:      op,
+		CHAddr:  mac,
+		YIAddr:  ip,
+		Options: []protocol.DHCPOption{{Code: protocol.DHCPOptionMessageType, Data: []byte{msgType}}},
+	}
+	if lease != nil {
+		msg.Options = append(msg.Options, protocol.DHCPOption{Code: protocol.DHCPOptionLeaseTime, Data: lease})
+	}
+
+	return msg
+}
+
+func TestSnoop(t *testing.T) {
+	clock := testutil.NewFakeClock(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))
+	mac := net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}
+	ip := net.IPv4(10, 0, 0, 10)
+	server := net.IPv4(10, 0, 0, 1)
+	tracker := &fakeTracker{hosts: map[string]hosttracker.Host{mac.String(): {MAC: mac, DPID: 1, Port: 2}}}
+	snooper := newSnooper(clock, tracker)
+
+	// One hour lease.
+	if !snooper.snoop(server, newMessage(2, protocol.DHCPAck, mac, ip, []byte{0x00, 0x00, 0x0e, 0x10})) {
+		t.Fatal("DHCPACK should not be dropped")
+	}
+	b, ok := snooper.Binding(mac)
+	if !ok || !b.IP.Equal(ip) || b.DPID != 1 || b.Port != 2 {
+		t.Fatalf("unexpected binding: ok=%v, binding=%v", ok, b)
+	}
+	if host, _ := tracker.Host(mac); !host.IP.Equal(ip) {
+		t.Fatalf("binding is not fed to the host tracker: %v", host)
+	}
+	clock.Advance(time.Hour)
+	if _, ok := snooper.Binding(mac); ok {
+		t.Fatal("binding should be expired")
+	}
+
+	// Released by the client.
+	snooper.snoop(server, newMessage(2, protocol.DHCPAck, mac, ip, []byte{0xff, 0xff, 0xff, 0xff}))
+	snooper.snoop(ip, newMessage(1, protocol.DHCPRelease, mac, net.IPv4zero, nil))
+	if n := len(snooper.Bindings()); n != 0 {
+		t.Fatalf("unexpected number of bindings: expected=0, got=%v", n)
+	}
+	if host, _ := tracker.Host(mac); host.IP != nil {
+		t.Fatalf("released address is not removed from the host tracker: %v", host)
+	}
+
+	// Replies from an unknown server are dropped if the server is configured.
+	snooper.server = server
+	if snooper.snoop(net.IPv4(10, 0, 0, 2), newMessage(2, protocol.DHCPAck, mac, ip, nil)) {
+		t.Fatal("DHCPACK from an unknown server should be dropped")
+	}
+	if _, ok := snooper.Binding(mac); ok {
+		t.Fatal("DHCPACK from an unknown server should not be learned")
+	}
+}
@@ -209,6 +209,13 @@ func (r *processor) processARPReply(finder network.Finder, ingress *network.Port
 		return nil
 	}
 
+	// The host behind a link aggregation may answer through any member port. Keep the current
+	// location if it is one of the members so that the location does not flap among them.
+	if p := aggregatedLocation(finder, arp.SHA, ingress); p != nil {
+		logger.Debugf("host %v is located on %v that is aggregated with %v", arp.SHA, p.ID(), ingress.ID())
+		ingress = p
+	}
+
 	swDPID, err := strconv.ParseUint(ingress.Device().ID(), 10, 64)
 	if err != nil {
 		return fmt.Errorf("invalid device ID: %v", ingress.Device().ID())
@@ -222,6 +229,7 @@ func (r *processor) processARPReply(finder network.Finder, ingress *network.Port
 	// Remove installed flows for this host if the location has been changed.
 	if updated {
 		logger.Infof("update host location: IP=%v, MAC=%v, deviceID=%v, portNum=%v", arp.SPA, arp.SHA, swDPID, ingress.Number())
+		finder.HostMoved(arp.SHA, arp.SPA, ingress)
 		// Remove flows from all devices.
 		for _, device := range finder.Devices() {
 			if err := device.RemoveFlowByMAC(arp.SHA); err != nil {
@@ -238,6 +246,26 @@ func (r *processor) processARPReply(finder network.Finder, ingress *network.Port
 	return nil
 }
 
+// aggregatedLocation returns the current location of the host, whose MAC address is mac, if it
+// is one of the member ports of the link aggregation that ingress belongs to. Otherwise, it returns nil.
+func aggregatedLocation(finder network.Finder, mac net.HardwareAddr, ingress *network.Port) *network.Port {
+	members := finder.Aggregation(ingress)
+	if len(members) == 0 {
+		return nil
+	}
+	node, status, err := finder.Node(mac)
+	if err != nil || status != network.LocationDiscovered {
+		return nil
+	}
+	for _, m := range members {
+		if m.ID() == node.Port().ID() {
+			return node.Port()
+		}
+	}
+
+	return nil
+}
+
 func (r *processor) OnPortDown(finder network.Finder, port *network.Port) error {
 	swDPID, err := strconv.ParseUint(port.Device().ID(), 10, 64)
 	if err != nil {
@@ -0,0 +1,312 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package firewall
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/superkkt/cherry/clock"
+	"github.com/superkkt/cherry/network"
+	"github.com/superkkt/cherry/northbound/app"
+	"github.com/superkkt/cherry/openflow"
+	"github.com/superkkt/cherry/protocol"
+
+	"github.com/superkkt/go-logging"
+)
+
+var (
+	logger = logging.MustGetLogger("firewall")
+)
+
+// Interval to reload the rules from the database and to synchronize the flows of the edge switches.
+const syncInterval = 5 * time.Second
+
+// Firewall enforces the stateless allow and deny rules, which are managed by the REST API,
+// on the IPv4 packets. The rules are compiled into prioritized flows on the edge switches,
+// i.e., the switches that have at least one port not connected to another switch, and
+// PACKET_INs are also checked against the rules before the next applications receive them.
+// The flow of a rule has a cookie whose value is the rule ID, so that the hit counters of
+// the rule can be gathered from the flow stats.
+type Firewall struct {
+	app.BaseProcessor
+	db    database
+	clock clock.Clock
+	once  sync.Once
+
+	mutex sync.Mutex
+	// Sorted in the order they are evaluated.
+	rules []Rule
+	// Flows installed on each device. Key is the DPID, and value is the flows keyed by flow.key().
+	installed map[string]map[string]flow
+}
+
+// flow is a compiled rule installed on a table.
+type flow struct {
+	compiledRule
+	tableID uint8
+}
+
+func (r flow) key() string {
+	return fmt.Sprintf("%v/%v", r.tableID, r.compiledRule.key())
+}
+
+type database interface {
+	// GetFirewallRules returns all the firewall rules.
+	GetFirewallRules() ([]Rule, error)
+}
+
+func New(db database) *Firewall {
+	return newFirewall(db, clock.Real)
+}
+
+func newFirewall(db database, clk clock.Clock) *Firewall {
+	if clk == nil {
+		panic("clock is nil")
+	}
+
+	return &Firewall{
+		db:        db,
+		clock:     clk,
+		installed: make(map[string]map[string]flow),
+	}
+}
+
+func (r *Firewall) Init() error {
+	return nil
+}
+
+func (r *Firewall) Name() string {
+	return "Firewall"
+}
+
+func (r *Firewall) String() string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return fmt.Sprintf("%v (rules=%v)", r.Name(), len(r.rules))
+}
+
+func (r *Firewall) OnDeviceUp(finder network.Finder, device *network.Device) error {
+	// The device has removed all the flows when it connected.
+	r.mutex.Lock()
+	delete(r.installed, device.ID())
+	r.mutex.Unlock()
+
+	// Make sure that there is only one synchronizer in this application.
+	r.once.Do(func() {
+		go r.synchronizer(finder)
+	})
+
+	return r.BaseProcessor.OnDeviceUp(finder, device)
+}
+
+func (r *Firewall) OnDeviceDown(finder network.Finder, device *network.Device) error {
+	r.mutex.Lock()
+	delete(r.installed, device.ID())
+	r.mutex.Unlock()
+
+	return r.BaseProcessor.OnDeviceDown(finder, device)
+}
+
+func (r *Firewall) OnPacketIn(finder network.Finder, ingress *network.Port, eth *protocol.Ethernet) error {
+	p, ok := parsePacket(eth)
+	if !ok {
+		return r.BaseProcessor.OnPacketIn(finder, ingress, eth)
+	}
+
+	r.mutex.Lock()
+	allowed := evaluate(r.rules, p)
+	r.mutex.Unlock()
+	if !allowed {
+		logger.Debugf("dropping a denied packet: ingress=%v, src=%v, dst=%v, protocol=%v, srcPort=%v, dstPort=%v",
+			ingress.ID(), p.src, p.dst, p.protocol, p.srcPort, p.dstPort)
+		// Drop the packet.
+		return nil
+	}
+
+	return r.BaseProcessor.OnPacketIn(finder, ingress, eth)
+}
+
+// parsePacket returns the 5-tuple of eth. ok is false if eth is not an IPv4 packet.
+func parsePacket(eth *protocol.Ethernet) (p packet, ok bool) {
+	if eth.Type != 0x0800 {
+		return packet{}, false
+	}
+	ip := new(protocol.IPv4)
+	if err := ip.UnmarshalBinary(eth.Payload); err != nil {
+		return packet{}, false
+	}
+	p = packet{src: ip.SrcIP, dst: ip.DstIP, protocol: ip.Protocol}
+
+	switch ip.Protocol {
+	case 6:
+		tcp := new(protocol.TCP)
+		if err := tcp.UnmarshalBinary(ip.Payload); err == nil {
+			p.srcPort, p.dstPort = tcp.SrcPort, tcp.DstPort
+		}
+	case 17:
+		udp := new(protocol.UDP)
+		if err := udp.UnmarshalBinary(ip.Payload); err == nil {
+			p.srcPort, p.dstPort = udp.SrcPort, udp.DstPort
+		}
+	}
+
+	return p, true
+}
+
+func (r *Firewall) synchronizer(finder network.Finder) {
+	logger.Debug("executed the firewall synchronizer")
+
+	ticker := r.clock.NewTicker(syncInterval)
+	defer ticker.Stop()
+
+	// Infinite loop.
+	for {
+		if err := r.sync(finder); err != nil {
+			logger.Errorf("failed to synchronize the firewall rules: %v", err)
+		}
+		<-ticker.C()
+	}
+}
+
+// sync reloads the rules, and then installs and removes the flows of the edge switches
+// according to the changes of the rules and the topology.
+func (r *Firewall) sync(finder network.Finder) error {
+	rules, err := r.db.GetFirewallRules()
+	if err != nil {
+		return err
+	}
+	sortRules(rules)
+	compiled := compile(rules)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.rules = rules
+	for _, device := range finder.Devices() {
+		if device.IsClosed() {
+			continue
+		}
+		// The rules are enforced on the classifier table, if any, so that the allowed packets
+		// can be forwarded by the flow table. They skip the flows of the QoS classes.
+		classifier, tableID := device.ClassifierTableID()
+		if !classifier {
+			tableID = device.FlowTableID()
+		}
+		desired := make(map[string]flow)
+		if app.IsEdgeSwitch(finder, device) {
+			for _, c := range compiled {
+				v := flow{compiledRule: c, tableID: tableID}
+				desired[v.key()] = v
+			}
+		}
+		if err := r.syncDevice(device, desired); err != nil {
+			logger.Errorf("failed to synchronize the firewall flows of %v: %v", device.ID(), err)
+			continue
+		}
+	}
+
+	return nil
+}
+
+// XXX: Caller should lock the mutex before they call this function
+func (r *Firewall) syncDevice(device *network.Device, desired map[string]flow) error {
+	installed, ok := r.installed[device.ID()]
+	if !ok {
+		installed = make(map[string]flow)
+		r.installed[device.ID()] = installed
+	}
+
+	for key, v := range installed {
+		if _, ok := desired[key]; ok {
+			continue
+		}
+		if err := r.sendFlow(device, openflow.FlowDeleteStrict, v); err != nil {
+			return err
+		}
+		delete(installed, key)
+		logger.Debugf("removed the firewall flow from %v: %v", device.ID(), v)
+	}
+	for key, v := range desired {
+		if _, ok := installed[key]; ok {
+			continue
+		}
+		if err := r.sendFlow(device, openflow.FlowAdd, v); err != nil {
+			return err
+		}
+		installed[key] = v
+		logger.Debugf("installed the firewall flow on %v: %v", device.ID(), v)
+	}
+
+	return nil
+}
+
+func (r *Firewall) sendFlow(device *network.Device, cmd openflow.FlowModCmd, rule flow) error {
+	f := device.Factory()
+	match, err := rule.newMatch(f)
+	if err != nil {
+		return err
+	}
+
+	flow, err := f.NewFlowMod(cmd)
+	if err != nil {
+		return err
+	}
+	priority, err := device.FlowPriority(r.Name(), rule.tableID, baseFlowPriority, rule.rank)
+	if err != nil {
+		return err
+	}
+	flow.SetCookie(network.NewCookie(r.Name(), rule.ID))
+	flow.SetTableID(rule.tableID)
+	flow.SetPriority(priority)
+	flow.SetFlowMatch(match)
+	// The flow of a deny rule has no action, which drops the packets.
+	if rule.Allow {
+		inst, err := f.NewInstruction()
+		if err != nil {
+			return err
+		}
+		if flowTableID := device.FlowTableID(); rule.tableID != flowTableID {
+			inst.GotoTable(flowTableID)
+		} else {
+			// There is no table after the flow table, so the allowed packets are sent to
+			// the controller where the normal forwarding applications process them.
+			outPort := openflow.NewOutPort()
+			outPort.SetController()
+			action, err := f.NewAction()
+			if err != nil {
+				return err
+			}
+			action.SetOutPort(outPort)
+			inst.ApplyAction(action)
+		}
+		flow.SetFlowInstruction(inst)
+	}
+
+	if cmd == openflow.FlowAdd {
+		return device.InstallFlowWithLifetime(flow, network.FlowLifetime{Class: network.FlowPermanent})
+	}
+
+	return device.InstallFlow(flow)
+}
@@ -0,0 +1,197 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package firewall
+
+import (
+	"fmt"
+	"net"
+	"sort"
+
+	"github.com/superkkt/cherry/openflow"
+)
+
+// Rule is a stateless allow or deny rule on the 5-tuple of IPv4 packets. The zero
+// value of a field, or nil for the addresses, matches any value.
+type Rule struct {
+	ID uint64
+	// Rules are evaluated in the descending order of the priority. The rule whose ID
+	// is smaller wins if two rules have the same priority.
+	Priority uint16
+	Allow    bool
+	Src, Dst *net.IPNet
+	// IP protocol number, e.g., 6 for TCP and 17 for UDP.
+	Protocol uint8
+	// TCP or UDP port numbers.
+	SrcPort, DstPort uint16
+}
+
+func (r Rule) String() string {
+	action := "deny"
+	if r.Allow {
+		action = "allow"
+	}
+
+	return fmt.Sprintf("Rule ID=%v, Priority=%v, Action=%v, Src=%v, Dst=%v, Protocol=%v, SrcPort=%v, DstPort=%v",
+		r.ID, r.Priority, action, r.Src, r.Dst, r.Protocol, r.SrcPort, r.DstPort)
+}
+
+// sortRules sorts rules in the order they are evaluated.
+func sortRules(rules []Rule) {
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].Priority != rules[j].Priority {
+			return rules[i].Priority > rules[j].Priority
+		}
+		return rules[i].ID < rules[j].ID
+	})
+}
+
+type packet struct {
+	src, dst         net.IP
+	protocol         uint8
+	srcPort, dstPort uint16
+}
+
+func (r Rule) match(p packet) bool {
+	if r.Src != nil && !r.Src.Contains(p.src) {
+		return false
+	}
+	if r.Dst != nil && !r.Dst.Contains(p.dst) {
+		return false
+	}
+	if r.Protocol != 0 && r.Protocol != p.protocol {
+		return false
+	}
+	if r.SrcPort != 0 && r.SrcPort != p.srcPort {
+		return false
+	}
+	if r.DstPort != 0 && r.DstPort != p.dstPort {
+		return false
+	}
+
+	return true
+}
+
+func overlapNet(a, b *net.IPNet) bool {
+	if a == nil || b == nil {
+		return true
+	}
+
+	return a.Contains(b.IP) || b.Contains(a.IP)
+}
+
+func overlapValue(a, b uint16) bool {
+	return a == 0 || b == 0 || a == b
+}
+
+// overlap returns whether there is a packet that matches both r and other.
+func (r Rule) overlap(other Rule) bool {
+	return overlapNet(r.Src, other.Src) && overlapNet(r.Dst, other.Dst) &&
+		overlapValue(uint16(r.Protocol), uint16(other.Protocol)) &&
+		overlapValue(r.SrcPort, other.SrcPort) && overlapValue(r.DstPort, other.DstPort)
+}
+
+// evaluate returns whether p is allowed by rules that are sorted by sortRules. The
+// packets that do not match any rule are allowed.
+func evaluate(rules []Rule, p packet) bool {
+	for _, v := range rules {
+		if v.match(p) {
+			return v.Allow
+		}
+	}
+
+	return true
+}
+
+// compiledRule is a rule that should be installed on the edge switches as a flow.
+type compiledRule struct {
+	Rule
+	// Rank of the flow among the flows of the rules, where 0 is the lowest, which keeps the
+	// evaluation order of the rules. The priority of the flow is the rank above the lowest
+	// priority of the priority band of the application, or above baseFlowPriority.
+	rank uint16
+}
+
+// key identifies the flow of the compiled rule.
+func (r compiledRule) key() string {
+	return fmt.Sprintf("%v/%v", r.Rule, r.rank)
+}
+
+// The flows of the rules have higher priorities than the flows of the QoS classes (up to 10099)
+// on the classifier table, and than the ARP and LLDP senders (100) and the normal flows (10) on
+// the flow table, but they only match IPv4 packets.
+const baseFlowPriority = 10100
+
+// compile returns the rules that should be installed as flows. rules should be sorted by
+// sortRules. The packets that do not match any rule are allowed, so an allow rule needs a
+// flow only if it overrides a deny rule that follows it. The flow of an allow rule continues
+// processing the packets on the flow table where the forwarding flows are installed.
+func compile(rules []Rule) []compiledRule {
+	result := []compiledRule{}
+	for i, v := range rules {
+		if v.Allow && !overridesDeny(v, rules[i+1:]) {
+			continue
+		}
+		result = append(result, compiledRule{Rule: v})
+	}
+	// The first rule has the highest priority.
+	for i := range result {
+		result[i].rank = uint16(len(result) - 1 - i)
+	}
+
+	return result
+}
+
+func overridesDeny(allow Rule, following []Rule) bool {
+	for _, v := range following {
+		if !v.Allow && allow.overlap(v) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// newMatch returns the match of the flow of r.
+func (r compiledRule) newMatch(f openflow.Factory) (openflow.Match, error) {
+	match, err := f.NewMatch()
+	if err != nil {
+		return nil, err
+	}
+	match.SetEtherType(0x0800) // IPv4
+	if r.Src != nil {
+		match.SetSrcIP(r.Src)
+	}
+	if r.Dst != nil {
+		match.SetDstIP(r.Dst)
+	}
+	if r.Protocol != 0 {
+		match.SetIPProtocol(r.Protocol)
+	}
+	if r.SrcPort != 0 {
+		match.SetSrcPort(r.SrcPort)
+	}
+	if r.DstPort != 0 {
+		match.SetDstPort(r.DstPort)
+	}
+
+	return match, nil
+}
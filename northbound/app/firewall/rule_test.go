@@ -0,0 +1,75 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package firewall
+
+import (
+	"net"
+	"testing"
+)
+
+func mustCIDR(t *testing.T, s string) *net.IPNet {
+	_, n, err := net.ParseCIDR(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return n
+}
+
+func TestCompile(t *testing.T) {
+	rules := []Rule{
+		// Deny all the traffic to 10.0.1.0/24,
+		{ID: 1, Priority: 10, Dst: mustCIDR(t, "10.0.1.0/24")},
+		// except SSH from 10.0.0.0/24.
+		{ID: 2, Priority: 20, Allow: true, Src: mustCIDR(t, "10.0.0.0/24"), Dst: mustCIDR(t, "10.0.1.0/24"), Protocol: 6, DstPort: 22},
+		// Allow rule that does not override any deny rule.
+		{ID: 3, Priority: 5, Allow: true, Dst: mustCIDR(t, "10.0.1.0/24")},
+		{ID: 4, Priority: 30, Allow: true, Dst: mustCIDR(t, "10.0.2.0/24")},
+	}
+	sortRules(rules)
+
+	compiled := compile(rules)
+	if len(compiled) != 2 {
+		t.Fatalf("unexpected number of compiled rules: expected=2, got=%v", len(compiled))
+	}
+	if compiled[0].ID != 2 || compiled[1].ID != 1 {
+		t.Fatalf("unexpected compiled rules: %v", compiled)
+	}
+	if compiled[0].rank <= compiled[1].rank {
+		t.Fatalf("unexpected flow ranks: %v, %v", compiled[0].rank, compiled[1].rank)
+	}
+
+	ssh := packet{src: net.ParseIP("10.0.0.1"), dst: net.ParseIP("10.0.1.1"), protocol: 6, srcPort: 50000, dstPort: 22}
+	if !evaluate(rules, ssh) {
+		t.Fatalf("SSH packet is denied")
+	}
+	http := ssh
+	http.dstPort = 80
+	if evaluate(rules, http) {
+		t.Fatalf("HTTP packet is allowed")
+	}
+	other := http
+	other.dst = net.ParseIP("10.0.3.1")
+	if !evaluate(rules, other) {
+		t.Fatalf("packet that does not match any rule is denied")
+	}
+}
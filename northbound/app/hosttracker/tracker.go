@@ -0,0 +1,452 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package hosttracker
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/superkkt/cherry/clock"
+	"github.com/superkkt/cherry/network"
+	"github.com/superkkt/cherry/northbound/app"
+	"github.com/superkkt/cherry/protocol"
+
+	"github.com/superkkt/go-logging"
+)
+
+var (
+	logger = logging.MustGetLogger("hosttracker")
+)
+
+const (
+	// Hosts that have not sent any packet during this period are forgotten.
+	HostExpiration = 5 * time.Minute
+	// Maximum number of the IPv6 addresses of a host. The oldest one is forgotten first, e.g.,
+	// a temporary address (RFC 4941) that has been replaced.
+	maxIPv6Addresses = 8
+)
+
+// Host is an endpoint learned from the packets it has sent.
+type Host struct {
+	MAC net.HardwareAddr
+	// IP is nil if we have not seen any IPv4 or ARP packet from this host yet.
+	IP net.IP
+	// IPv6 addresses learned from the neighbor discovery messages of this host, e.g., its
+	// link-local and global addresses, in the order they are learned.
+	IPv6 []net.IP
+	// DPID and Port are the location where this host is attached.
+	DPID     uint64
+	Port     uint32
+	LastSeen time.Time
+}
+
+func (r Host) String() string {
+	return fmt.Sprintf("MAC=%v, IP=%v, IPv6=%v, DPID=%v, Port=%v, LastSeen=%v", r.MAC, r.IP, r.IPv6, r.DPID, r.Port, r.LastSeen)
+}
+
+// HasIP returns whether ip is the IPv4 address or one of the IPv6 addresses of this host.
+func (r Host) HasIP(ip net.IP) bool {
+	if r.IP != nil && r.IP.Equal(ip) {
+		return true
+	}
+	for _, v := range r.IPv6 {
+		if v.Equal(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// addIPv6 returns the IPv6 addresses of this host with ip. It does not modify r.IPv6 that
+// may be shared with the copies of this host.
+func (r Host) addIPv6(ip net.IP) []net.IP {
+	v := make([]net.IP, 0, len(r.IPv6)+1)
+	for _, addr := range r.IPv6 {
+		if !addr.Equal(ip) {
+			v = append(v, addr)
+		}
+	}
+	v = append(v, ip)
+	if len(v) > maxIPv6Addresses {
+		v = v[len(v)-maxIPv6Addresses:]
+	}
+
+	return v
+}
+
+// Tracker learns the locations of the hosts from PACKET_INs, and keeps them in the memory
+// so that other applications can query where the hosts are attached. Unlike the Discovery
+// application, it also tracks the hosts that are not registered in the database.
+type Tracker struct {
+	app.BaseProcessor
+	clock clock.Clock
+
+	mutex sync.RWMutex
+	// Key is the MAC address.
+	hosts     map[string]*Host
+	lastPurge time.Time
+}
+
+func New() *Tracker {
+	return newTracker(clock.Real)
+}
+
+func newTracker(clk clock.Clock) *Tracker {
+	if clk == nil {
+		panic("clock is nil")
+	}
+
+	return &Tracker{
+		clock:     clk,
+		hosts:     make(map[string]*Host),
+		lastPurge: clk.Now(),
+	}
+}
+
+func (r *Tracker) Name() string {
+	return "HostTracker"
+}
+
+func (r *Tracker) String() string {
+	return fmt.Sprintf("%v", r.Name())
+}
+
+// Host returns the host whose MAC address is mac. ok will be false if the host is unknown or expired.
+func (r *Tracker) Host(mac net.HardwareAddr) (host Host, ok bool) {
+	// Read lock
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	v, ok := r.hosts[mac.String()]
+	if !ok || r.isExpired(v) {
+		return Host{}, false
+	}
+
+	return *v, true
+}
+
+// HostsByIP returns the hosts whose IPv4 or IPv6 address is ip. There may be multiple hosts
+// if they are virtual machines or a redundant pair that share the IP address.
+func (r *Tracker) HostsByIP(ip net.IP) []Host {
+	// Read lock
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	v := make([]Host, 0)
+	for _, h := range r.hosts {
+		if !h.HasIP(ip) || r.isExpired(h) {
+			continue
+		}
+		v = append(v, *h)
+	}
+
+	return v
+}
+
+// Hosts returns all the hosts that are not expired.
+func (r *Tracker) Hosts() []Host {
+	// Read lock
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	v := make([]Host, 0, len(r.hosts))
+	for _, h := range r.hosts {
+		if r.isExpired(h) {
+			continue
+		}
+		v = append(v, *h)
+	}
+
+	return v
+}
+
+// XXX: Caller should lock the mutex
+func (r *Tracker) isExpired(h *Host) bool {
+	return r.clock.Since(h.LastSeen) >= HostExpiration
+}
+
+// XXX: Caller should lock the mutex
+func (r *Tracker) purge() {
+	// Do not scan the whole hosts on every PACKET_IN.
+	if r.clock.Since(r.lastPurge) < HostExpiration {
+		return
+	}
+	r.lastPurge = r.clock.Now()
+
+	for mac, h := range r.hosts {
+		if r.isExpired(h) {
+			logger.Debugf("removing an expired host: %v", h)
+			delete(r.hosts, mac)
+		}
+	}
+}
+
+// learn updates the location of a host whose MAC address is mac. ip is an IPv4 or IPv6
+// address, or nil if it is unknown. moved will be true if the host has been moved from
+// another location.
+func (r *Tracker) learn(mac net.HardwareAddr, ip net.IP, dpid uint64, port uint32) (moved bool) {
+	// Write lock
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.purge()
+
+	now := r.clock.Now()
+	h, ok := r.hosts[mac.String()]
+	if !ok || r.isExpired(h) {
+		h = &Host{MAC: mac, DPID: dpid, Port: port, LastSeen: now}
+		setIP(h, ip)
+		r.hosts[mac.String()] = h
+		logger.Debugf("learned a new host: %v", h)
+		return false
+	}
+
+	if h.DPID != dpid || h.Port != port {
+		logger.Infof("host has been moved: MAC=%v, from=%v:%v, to=%v:%v", mac, h.DPID, h.Port, dpid, port)
+		h.DPID = dpid
+		h.Port = port
+		moved = true
+	}
+	setIP(h, ip)
+	h.LastSeen = now
+
+	return moved
+}
+
+func setIP(h *Host, ip net.IP) {
+	switch {
+	case ip == nil:
+		return
+	case ip.To4() != nil:
+		h.IP = ip
+	default:
+		h.IPv6 = h.addIPv6(ip)
+	}
+}
+
+// Bind sets the IP address of a host whose MAC address is mac from an authoritative source
+// such as a DHCP server. Other hosts that had the same IP address lose it because the address
+// has been reassigned. ip can be nil if the address has been released. ok will be false if
+// the host is unknown or expired.
+func (r *Tracker) Bind(mac net.HardwareAddr, ip net.IP) (ok bool) {
+	// Write lock
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	h, ok := r.hosts[mac.String()]
+	if !ok || r.isExpired(h) {
+		return false
+	}
+
+	if ip != nil {
+		for _, v := range r.hosts {
+			if v != h && v.IP != nil && v.IP.Equal(ip) {
+				logger.Infof("IP address %v has been reassigned from %v to %v", ip, v.MAC, mac)
+				v.IP = nil
+			}
+		}
+	}
+	h.IP = ip
+
+	return true
+}
+
+// Forget forgets the hosts whose MAC address is mac or IPv4 or IPv6 address is ip, and returns
+// them. mac or ip can be nil.
+func (r *Tracker) Forget(mac net.HardwareAddr, ip net.IP) []Host {
+	// Write lock
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	v := make([]Host, 0)
+	r.forget(func(h *Host) bool {
+		if (mac != nil && bytes.Equal(h.MAC, mac)) || (ip != nil && h.HasIP(ip)) {
+			v = append(v, *h)
+			return true
+		}
+		return false
+	})
+
+	return v
+}
+
+// XXX: Caller should lock the mutex
+func (r *Tracker) forget(match func(h *Host) bool) {
+	for mac, h := range r.hosts {
+		if match(h) {
+			delete(r.hosts, mac)
+		}
+	}
+}
+
+func (r *Tracker) OnPacketIn(finder network.Finder, ingress *network.Port, eth *protocol.Ethernet) error {
+	// Do not learn the hosts from an edge among switches. They are not directly attached to it.
+	if finder.IsEdge(ingress) || isUnicast(eth.SrcMAC) == false {
+		return r.BaseProcessor.OnPacketIn(finder, ingress, eth)
+	}
+
+	dpid, err := strconv.ParseUint(ingress.Device().ID(), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid device ID: %v", ingress.Device().ID())
+	}
+
+	if r.learn(eth.SrcMAC, senderIP(eth), dpid, ingress.Number()) {
+		// Remove the flows heading to the old location of this host.
+		for _, device := range finder.Devices() {
+			if err := device.RemoveFlowByMAC(eth.SrcMAC); err != nil {
+				logger.Errorf("failed to remove flows from %v: %v", device.ID(), err)
+				continue
+			}
+			logger.Debugf("removed flows whose destination MAC address is %v on %v", eth.SrcMAC, device.ID())
+		}
+	}
+
+	// Propagate this packet to the next processors.
+	return r.BaseProcessor.OnPacketIn(finder, ingress, eth)
+}
+
+func isUnicast(mac net.HardwareAddr) bool {
+	return len(mac) == 6 && mac[0]&0x1 == 0
+}
+
+// senderIP returns the source IPv4 address of eth, or the IPv6 address of the sender of a
+// neighbor discovery message. It returns nil if eth is none of IPv4, ARP and the neighbor
+// discovery message.
+func senderIP(eth *protocol.Ethernet) net.IP {
+	switch eth.Type {
+	case 0x0800:
+		ip := new(protocol.IPv4)
+		if err := ip.UnmarshalBinary(eth.Payload); err != nil {
+			return nil
+		}
+		// DHCP clients use 0.0.0.0 as their source address.
+		if ip.SrcIP.IsUnspecified() {
+			return nil
+		}
+		return ip.SrcIP
+	case 0x0806:
+		arp := new(protocol.ARP)
+		if err := arp.UnmarshalBinary(eth.Payload); err != nil {
+			return nil
+		}
+		// ARP probes (RFC 5227) have all zero sender IP address.
+		if arp.SPA.IsUnspecified() {
+			return nil
+		}
+		return arp.SPA
+	case 0x86DD:
+		return neighborIP(eth.Payload)
+	default:
+		return nil
+	}
+}
+
+// neighborIP returns the IPv6 address of the sender of the neighbor solicitation or
+// advertisement in packet, or nil if packet is not the neighbor discovery message.
+func neighborIP(packet []byte) net.IP {
+	ip := new(protocol.IPv6)
+	if err := ip.UnmarshalBinary(packet); err != nil {
+		return nil
+	}
+	// ICMPv6?
+	if ip.NextHeader != 58 {
+		return nil
+	}
+	nd := new(protocol.NeighborDiscovery)
+	if err := nd.UnmarshalBinary(ip.Payload); err != nil {
+		return nil
+	}
+
+	if nd.Type == protocol.ICMPv6NeighborAdvertisement {
+		// The target of the advertisement is the address of the sender.
+		return nd.Target
+	}
+	// Duplicate address detection (RFC 4862) uses the unspecified source address.
+	if ip.SrcIP.IsUnspecified() {
+		return nil
+	}
+	return ip.SrcIP
+}
+
+func (r *Tracker) OnPortDown(finder network.Finder, port *network.Port) error {
+	dpid, err := strconv.ParseUint(port.Device().ID(), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid device ID: %v", port.Device().ID())
+	}
+
+	// NOTE: This is an anonymous function (NOT a goroutine!) that has a critical section.
+	func() {
+		// Write lock
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+
+		r.forget(func(h *Host) bool { return h.DPID == dpid && h.Port == port.Number() })
+	}()
+
+	// Propagate this event to the next processors.
+	return r.BaseProcessor.OnPortDown(finder, port)
+}
+
+func (r *Tracker) OnDeviceDown(finder network.Finder, device *network.Device) error {
+	dpid, err := strconv.ParseUint(device.ID(), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid device ID: %v", device.ID())
+	}
+
+	// NOTE: This is an anonymous function (NOT a goroutine!) that has a critical section.
+	func() {
+		// Write lock
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+
+		r.forget(func(h *Host) bool { return h.DPID == dpid })
+	}()
+
+	// Propagate this event to the next processors.
+	return r.BaseProcessor.OnDeviceDown(finder, device)
+}
+
+func (r *Tracker) OnLinkUp(finder network.Finder, ports [2]*network.Port) error {
+	// NOTE: This is an anonymous function (NOT a goroutine!) that has a critical section.
+	func() {
+		// Write lock
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+
+		// The hosts learned from the ports, which are now an edge among switches, are not directly attached to them.
+		for _, p := range ports {
+			dpid, err := strconv.ParseUint(p.Device().ID(), 10, 64)
+			if err != nil {
+				continue
+			}
+			r.forget(func(h *Host) bool { return h.DPID == dpid && h.Port == p.Number() })
+		}
+	}()
+
+	// Propagate this event to the next processors.
+	return r.BaseProcessor.OnLinkUp(finder, ports)
+}
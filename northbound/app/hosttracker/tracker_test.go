@@ -0,0 +1,172 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package hosttracker
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/superkkt/cherry/protocol"
+	"github.com/superkkt/cherry/testutil"
+)
+
+func TestLearnAndMove(t *testing.T) {
+	clock := testutil.NewFakeClock(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))
+	tracker := newTracker(clock)
+	mac := net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}
+	ip := net.IPv4(10, 0, 0, 1)
+
+	if tracker.learn(mac, nil, 1, 1) {
+		t.Fatal("a new host should not be reported as moved")
+	}
+	// The IP address is learned later from an ARP packet.
+	if tracker.learn(mac, ip, 1, 1) {
+		t.Fatal("a host on the same location should not be reported as moved")
+	}
+	host, ok := tracker.Host(mac)
+	if !ok || host.DPID != 1 || host.Port != 1 || !host.IP.Equal(ip) {
+		t.Fatalf("unexpected host: ok=%v, host=%v", ok, host)
+	}
+
+	if !tracker.learn(mac, nil, 2, 3) {
+		t.Fatal("a host on a different location should be reported as moved")
+	}
+	hosts := tracker.HostsByIP(ip)
+	if len(hosts) != 1 || hosts[0].DPID != 2 || hosts[0].Port != 3 {
+		t.Fatalf("unexpected hosts: %v", hosts)
+	}
+}
+
+func TestExpiration(t *testing.T) {
+	clock := testutil.NewFakeClock(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))
+	tracker := newTracker(clock)
+	mac := net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}
+
+	tracker.learn(mac, nil, 1, 1)
+	clock.Advance(HostExpiration - time.Second)
+	if _, ok := tracker.Host(mac); !ok {
+		t.Fatal("host has been expired too early")
+	}
+	clock.Advance(time.Second)
+	if _, ok := tracker.Host(mac); ok {
+		t.Fatal("host should be expired")
+	}
+	// An expired host appearing on another location is a new host, not a moved one.
+	if tracker.learn(mac, nil, 2, 2) {
+		t.Fatal("an expired host should not be reported as moved")
+	}
+	if n := len(tracker.Hosts()); n != 1 {
+		t.Fatalf("unexpected number of hosts: expected=1, got=%v", n)
+	}
+}
+
+func TestBind(t *testing.T) {
+	clock := testutil.NewFakeClock(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))
+	tracker := newTracker(clock)
+	oldMAC := net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}
+	newMAC := net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x66}
+	ip := net.IPv4(10, 0, 0, 1)
+
+	if tracker.Bind(newMAC, ip) {
+		t.Fatal("an unknown host should not be bound")
+	}
+	tracker.learn(oldMAC, ip, 1, 1)
+	tracker.learn(newMAC, nil, 1, 2)
+	if !tracker.Bind(newMAC, ip) {
+		t.Fatal("failed to bind a known host")
+	}
+	// The address has been reassigned to the new host.
+	hosts := tracker.HostsByIP(ip)
+	if len(hosts) != 1 || hosts[0].MAC.String() != newMAC.String() {
+		t.Fatalf("unexpected hosts: %v", hosts)
+	}
+
+	// Released.
+	tracker.Bind(newMAC, nil)
+	if n := len(tracker.HostsByIP(ip)); n != 0 {
+		t.Fatalf("unexpected number of hosts: expected=0, got=%v", n)
+	}
+}
+
+func newNeighborDiscovery(t *testing.T, typ uint8, src, target net.IP) *protocol.Ethernet {
+	nd := &protocol.NeighborDiscovery{Target: target}
+	nd.Type = typ
+	nd.SetPseudoHeader(src, net.ParseIP("ff02::1"))
+	payload, err := nd.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	packet, err := protocol.NewIPv6(src, net.ParseIP("ff02::1"), 58, payload).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &protocol.Ethernet{Type: 0x86DD, Payload: packet}
+}
+
+func TestLearnIPv6(t *testing.T) {
+	clock := testutil.NewFakeClock(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))
+	tracker := newTracker(clock)
+	mac := net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}
+	linkLocal := net.ParseIP("fe80::1")
+	global := net.ParseIP("2001:db8::1")
+
+	// Duplicate address detection has no sender address.
+	if ip := senderIP(newNeighborDiscovery(t, protocol.ICMPv6NeighborSolicitation, net.IPv6unspecified, global)); ip != nil {
+		t.Fatalf("unexpected sender of the duplicate address detection: %v", ip)
+	}
+	ns := newNeighborDiscovery(t, protocol.ICMPv6NeighborSolicitation, linkLocal, global)
+	if ip := senderIP(ns); !ip.Equal(linkLocal) {
+		t.Fatalf("unexpected sender of the solicitation: %v", ip)
+	}
+	na := newNeighborDiscovery(t, protocol.ICMPv6NeighborAdvertisement, linkLocal, global)
+	if ip := senderIP(na); !ip.Equal(global) {
+		t.Fatalf("unexpected sender of the advertisement: %v", ip)
+	}
+
+	tracker.learn(mac, net.IPv4(10, 0, 0, 1), 1, 1)
+	tracker.learn(mac, senderIP(ns), 1, 1)
+	tracker.learn(mac, senderIP(na), 1, 1)
+	// Learned again.
+	tracker.learn(mac, senderIP(ns), 1, 1)
+	host, ok := tracker.Host(mac)
+	if !ok || !host.IP.Equal(net.IPv4(10, 0, 0, 1)) || len(host.IPv6) != 2 || !host.IPv6[0].Equal(global) || !host.IPv6[1].Equal(linkLocal) {
+		t.Fatalf("unexpected host: ok=%v, host=%v", ok, host)
+	}
+	if hosts := tracker.HostsByIP(global); len(hosts) != 1 {
+		t.Fatalf("unexpected hosts: %v", hosts)
+	}
+
+	for i := 0; i < maxIPv6Addresses; i++ {
+		tracker.learn(mac, net.ParseIP(fmt.Sprintf("2001:db8::%x", 0x100+i)), 1, 1)
+	}
+	host, _ = tracker.Host(mac)
+	if len(host.IPv6) != maxIPv6Addresses || host.HasIP(linkLocal) {
+		t.Fatalf("the oldest address should be forgotten: %v", host)
+	}
+
+	if hosts := tracker.Forget(nil, net.ParseIP("2001:db8::100")); len(hosts) != 1 {
+		t.Fatalf("unexpected forgotten hosts: %v", hosts)
+	}
+}
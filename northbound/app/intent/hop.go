@@ -0,0 +1,76 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package intent
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/superkkt/cherry/network"
+)
+
+// hop is the flow of an intent on a device of its path.
+type hop struct {
+	device          *network.Device
+	inPort, outPort uint32
+	intentID        uint64
+	// True if the packets are sent from the destination to the source host of the intent.
+	reverse        bool
+	srcMAC, dstMAC net.HardwareAddr
+	// Bandwidth limit metered on this hop in kilobits per second. 0 means unlimited.
+	bandwidth uint32
+}
+
+func (r hop) String() string {
+	return fmt.Sprintf("Intent=%v, Reverse=%v, InPort=%v, OutPort=%v, SrcMAC=%v, DstMAC=%v, Bandwidth=%v",
+		r.intentID, r.reverse, r.inPort, r.outPort, r.srcMAC, r.dstMAC, r.bandwidth)
+}
+
+// key identifies the flow of the hop on its device.
+func (r hop) key() string {
+	return fmt.Sprintf("%v/%v/%v/%v/%v/%v/%v", r.intentID, r.reverse, r.inPort, r.outPort, r.srcMAC, r.dstMAC, r.bandwidth)
+}
+
+// meterID returns the ID of the meter that limits the bandwidth of the hop. Each direction
+// of an intent has its own meter.
+func (r hop) meterID() uint32 {
+	id := uint32(meterBase + r.intentID*2)
+	if r.reverse {
+		id++
+	}
+
+	return id
+}
+
+// hops returns the hops from src to dst, which are the ports where the source and destination
+// hosts are connected, along path whose elements are the egress port of a device and the
+// ingress port of the next device. path should be empty if src and dst are on the same device.
+func hops(src, dst *network.Port, path [][2]*network.Port) []hop {
+	result := make([]hop, 0, len(path)+1)
+	ingress := src
+	for _, link := range path {
+		result = append(result, hop{device: ingress.Device(), inPort: ingress.Number(), outPort: link[0].Number()})
+		ingress = link[1]
+	}
+
+	return append(result, hop{device: ingress.Device(), inPort: ingress.Number(), outPort: dst.Number()})
+}
@@ -0,0 +1,65 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package intent
+
+import (
+	"testing"
+
+	"github.com/superkkt/cherry/network"
+)
+
+func TestHops(t *testing.T) {
+	src, dst := network.NewPort(nil, 1), network.NewPort(nil, 2)
+	// Two links: device A (port 10) -> device B (port 20), and device B (port 21) -> device C (port 30).
+	path := [][2]*network.Port{
+		{network.NewPort(nil, 10), network.NewPort(nil, 20)},
+		{network.NewPort(nil, 21), network.NewPort(nil, 30)},
+	}
+
+	expected := [][2]uint32{{1, 10}, {20, 21}, {30, 2}}
+	result := hops(src, dst, path)
+	if len(result) != len(expected) {
+		t.Fatalf("unexpected number of hops: expected=%v, got=%v", len(expected), len(result))
+	}
+	for i, v := range expected {
+		if result[i].inPort != v[0] || result[i].outPort != v[1] {
+			t.Errorf("unexpected hop %v: expected=%v, got=%v", i, v, result[i])
+		}
+	}
+
+	// Hosts on the same device.
+	result = hops(src, dst, nil)
+	if len(result) != 1 || result[0].inPort != 1 || result[0].outPort != 2 {
+		t.Fatalf("unexpected hops: %v", result)
+	}
+}
+
+func TestMeterID(t *testing.T) {
+	forward := hop{intentID: 7}
+	reverse := hop{intentID: 7, reverse: true}
+	if forward.meterID() == reverse.meterID() {
+		t.Fatalf("same meter ID for both directions: %v", forward.meterID())
+	}
+	if (hop{intentID: 8}).meterID() == reverse.meterID() {
+		t.Fatalf("same meter ID for different intents: %v", reverse.meterID())
+	}
+}
@@ -0,0 +1,382 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package intent
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/superkkt/cherry/clock"
+	"github.com/superkkt/cherry/network"
+	"github.com/superkkt/cherry/northbound/app"
+	"github.com/superkkt/cherry/openflow"
+
+	"github.com/superkkt/go-logging"
+)
+
+var (
+	logger = logging.MustGetLogger("intent")
+)
+
+const (
+	// Interval to reload the intents from the database and to synchronize the flows of the switches.
+	syncInterval = 5 * time.Second
+	// Priority of the intent flows, which is higher than the ones of the normal flows (10) and
+	// the route flows (20), but lower than the ones of the firewall rules. The lowest priority
+	// of the priority band of the application is used instead, if any.
+	intentPriority = 30
+	// Meter IDs of the intents start from this value so that they do not collide with the
+	// meters of the other applications.
+	meterBase = 0x10000000
+	// Maximum intent ID whose meters can be allocated.
+	maxMeteredIntentID = (0xFFFF0000 - meterBase) / 2
+)
+
+// Intent is a connectivity request between two hosts that are managed by the REST API.
+type Intent struct {
+	ID             uint64
+	SrcMAC, DstMAC net.HardwareAddr
+	// Bandwidth limit of each direction in kilobits per second. 0 means unlimited.
+	Bandwidth uint32
+}
+
+func (r Intent) String() string {
+	return fmt.Sprintf("Intent ID=%v, SrcMAC=%v, DstMAC=%v, Bandwidth=%v", r.ID, r.SrcMAC, r.DstMAC, r.Bandwidth)
+}
+
+// Connectivity installs the flows of the intents on every hop of the shortest path between
+// the hosts of each intent in both directions, and moves them to the new path whenever the
+// topology or the host locations change. The packets of an intent are limited to its bandwidth
+// by the meters on the first hops if the switches support meters. The flow of an intent has a
+// cookie whose value is the intent ID.
+type Connectivity struct {
+	app.BaseProcessor
+	db    database
+	clock clock.Clock
+	once  sync.Once
+
+	mutex   sync.Mutex
+	intents []Intent
+	// Flows installed on each device. Key is the DPID, and value is the hops keyed by hop.key().
+	installed map[string]map[string]hop
+}
+
+type database interface {
+	// GetIntents returns all the intents.
+	GetIntents() ([]Intent, error)
+}
+
+func New(db database) *Connectivity {
+	return newConnectivity(db, clock.Real)
+}
+
+func newConnectivity(db database, clk clock.Clock) *Connectivity {
+	if clk == nil {
+		panic("clock is nil")
+	}
+
+	return &Connectivity{
+		db:        db,
+		clock:     clk,
+		installed: make(map[string]map[string]hop),
+	}
+}
+
+func (r *Connectivity) Init() error {
+	return nil
+}
+
+func (r *Connectivity) Name() string {
+	return "Intent"
+}
+
+func (r *Connectivity) String() string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return fmt.Sprintf("%v (intents=%v)", r.Name(), len(r.intents))
+}
+
+func (r *Connectivity) OnDeviceUp(finder network.Finder, device *network.Device) error {
+	// The device has removed all the flows when it connected.
+	r.mutex.Lock()
+	delete(r.installed, device.ID())
+	r.mutex.Unlock()
+
+	// Make sure that there is only one synchronizer in this application.
+	r.once.Do(func() {
+		go r.synchronizer(finder)
+	})
+
+	return r.BaseProcessor.OnDeviceUp(finder, device)
+}
+
+func (r *Connectivity) OnDeviceDown(finder network.Finder, device *network.Device) error {
+	r.mutex.Lock()
+	delete(r.installed, device.ID())
+	r.mutex.Unlock()
+
+	return r.BaseProcessor.OnDeviceDown(finder, device)
+}
+
+func (r *Connectivity) OnTopologyChange(finder network.Finder) error {
+	// Move the intents to the new paths without waiting for the synchronizer.
+	r.mutex.Lock()
+	r.apply(finder)
+	r.mutex.Unlock()
+
+	return r.BaseProcessor.OnTopologyChange(finder)
+}
+
+func (r *Connectivity) synchronizer(finder network.Finder) {
+	logger.Debug("executed the intent synchronizer")
+
+	ticker := r.clock.NewTicker(syncInterval)
+	defer ticker.Stop()
+
+	// Infinite loop.
+	for {
+		if err := r.sync(finder); err != nil {
+			logger.Errorf("failed to synchronize the intents: %v", err)
+		}
+		<-ticker.C()
+	}
+}
+
+// sync reloads the intents, and then installs and removes the flows of the switches according
+// to the changes of the intents, the host locations and the topology.
+func (r *Connectivity) sync(finder network.Finder) error {
+	intents, err := r.db.GetIntents()
+	if err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.intents = intents
+	r.apply(finder)
+
+	return nil
+}
+
+// XXX: Caller should lock the mutex before they call this function
+func (r *Connectivity) apply(finder network.Finder) {
+	// Key is the DPID.
+	desired := make(map[string]map[string]hop)
+	for _, v := range r.intents {
+		for _, reverse := range []bool{false, true} {
+			for _, h := range r.resolve(finder, v, reverse) {
+				id := h.device.ID()
+				if desired[id] == nil {
+					desired[id] = make(map[string]hop)
+				}
+				desired[id][h.key()] = h
+			}
+		}
+	}
+
+	for _, device := range finder.Devices() {
+		if device.IsClosed() {
+			continue
+		}
+		if err := r.syncDevice(device, desired[device.ID()]); err != nil {
+			logger.Errorf("failed to synchronize the intent flows of %v: %v", device.ID(), err)
+			continue
+		}
+	}
+}
+
+// resolve returns the hops of the path for the packets of intent. The packets are sent from
+// the destination to the source host if reverse is true. It returns nil if the hosts have not
+// been discovered or there is no path between them.
+func (r *Connectivity) resolve(finder network.Finder, intent Intent, reverse bool) []hop {
+	srcMAC, dstMAC := intent.SrcMAC, intent.DstMAC
+	if reverse {
+		srcMAC, dstMAC = dstMAC, srcMAC
+	}
+
+	src, ok := locate(finder, srcMAC)
+	if !ok {
+		return nil
+	}
+	dst, ok := locate(finder, dstMAC)
+	if !ok {
+		return nil
+	}
+	var path [][2]*network.Port
+	if src.Device().ID() != dst.Device().ID() {
+		if path = finder.Path(src.Device().ID(), dst.Device().ID()); len(path) == 0 {
+			logger.Debugf("no path for the intent: %v, reverse=%v", intent, reverse)
+			return nil
+		}
+	}
+
+	result := hops(src, dst, path)
+	for i := range result {
+		result[i].intentID = intent.ID
+		result[i].reverse = reverse
+		result[i].srcMAC, result[i].dstMAC = srcMAC, dstMAC
+	}
+	// The packets are metered on the first hop.
+	if intent.Bandwidth > 0 && intent.ID <= maxMeteredIntentID {
+		result[0].bandwidth = intent.Bandwidth
+	}
+
+	return result
+}
+
+// locate returns the port where the host whose MAC address is mac is connected.
+func locate(finder network.Finder, mac net.HardwareAddr) (port *network.Port, ok bool) {
+	node, status, err := finder.Node(mac)
+	if err != nil {
+		logger.Errorf("failed to locate a host (MAC=%v): %v", mac, err)
+		return nil, false
+	}
+	if status != network.LocationDiscovered {
+		return nil, false
+	}
+
+	return node.Port(), true
+}
+
+// XXX: Caller should lock the mutex before they call this function
+func (r *Connectivity) syncDevice(device *network.Device, desired map[string]hop) error {
+	installed, ok := r.installed[device.ID()]
+	if !ok {
+		installed = make(map[string]hop)
+		r.installed[device.ID()] = installed
+	}
+
+	for key, v := range installed {
+		if _, ok := desired[key]; ok {
+			continue
+		}
+		if err := r.removeHop(device, v); err != nil {
+			return err
+		}
+		delete(installed, key)
+		logger.Debugf("removed the intent flow from %v: %v", device.ID(), v)
+	}
+	for key, v := range desired {
+		if _, ok := installed[key]; ok {
+			continue
+		}
+		if err := r.installHop(device, v); err != nil {
+			return err
+		}
+		installed[key] = v
+		logger.Debugf("installed the intent flow on %v: %v", device.ID(), v)
+	}
+
+	return nil
+}
+
+func (r *Connectivity) installHop(device *network.Device, h hop) error {
+	if h.bandwidth > 0 {
+		if err := r.installMeter(device, h); err != nil {
+			return err
+		}
+	}
+
+	return r.sendFlow(device, openflow.FlowAdd, h)
+}
+
+func (r *Connectivity) installMeter(device *network.Device, h hop) error {
+	if !supportsMeters(device) {
+		logger.Warningf("bandwidth of the intent (ID=%v) is not limited on %v that does not support meters", h.intentID, device.ID())
+		return nil
+	}
+
+	band := openflow.MeterBand{Type: openflow.MeterBandDrop, Rate: h.bandwidth}
+	// Remove the meter left by the previous path, if any.
+	if err := device.DeleteMeter(h.meterID()); err != nil {
+		return err
+	}
+
+	return device.AddMeter(h.meterID(), false, []openflow.MeterBand{band})
+}
+
+// Meters are supported since OpenFlow 1.3.
+func supportsMeters(device *network.Device) bool {
+	return device.Factory().ProtocolVersion() >= openflow.OF13_VERSION
+}
+
+func (r *Connectivity) removeHop(device *network.Device, h hop) error {
+	if h.bandwidth > 0 && supportsMeters(device) {
+		// Removing the meter also removes the flow.
+		return device.DeleteMeter(h.meterID())
+	}
+
+	return r.sendFlow(device, openflow.FlowDeleteStrict, h)
+}
+
+func (r *Connectivity) sendFlow(device *network.Device, cmd openflow.FlowModCmd, h hop) error {
+	f := device.Factory()
+	match, err := f.NewMatch()
+	if err != nil {
+		return err
+	}
+	inPort := openflow.NewInPort()
+	inPort.SetValue(h.inPort)
+	match.SetInPort(inPort)
+	match.SetSrcMAC(h.srcMAC)
+	match.SetDstMAC(h.dstMAC)
+
+	outPort := openflow.NewOutPort()
+	outPort.SetValue(h.outPort)
+	action, err := f.NewAction()
+	if err != nil {
+		return err
+	}
+	action.SetOutPort(outPort)
+	inst, err := f.NewInstruction()
+	if err != nil {
+		return err
+	}
+	inst.ApplyAction(action)
+	if h.bandwidth > 0 && supportsMeters(device) {
+		inst.SetMeter(h.meterID())
+	}
+
+	flow, err := f.NewFlowMod(cmd)
+	if err != nil {
+		return err
+	}
+	priority, err := device.FlowPriority(r.Name(), device.FlowTableID(), intentPriority, 0)
+	if err != nil {
+		return err
+	}
+	flow.SetCookie(network.NewCookie(r.Name(), h.intentID))
+	flow.SetTableID(device.FlowTableID())
+	flow.SetPriority(priority)
+	flow.SetFlowMatch(match)
+	flow.SetFlowInstruction(inst)
+
+	if cmd == openflow.FlowAdd {
+		return device.InstallFlowWithLifetime(flow, network.FlowLifetime{Class: network.FlowPermanent})
+	}
+
+	return device.InstallFlow(flow)
+}
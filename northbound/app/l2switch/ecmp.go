@@ -0,0 +1,169 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package l2switch
+
+import (
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/superkkt/cherry/network"
+	"github.com/superkkt/cherry/openflow"
+)
+
+// groupWriter is a device that can have groups.
+type groupWriter interface {
+	ID() string
+	Factory() openflow.Factory
+	AddGroup(t openflow.GroupType, id uint32, buckets []openflow.Bucket) error
+	ModifyGroup(t openflow.GroupType, id uint32, buckets []openflow.Bucket) error
+}
+
+// nextHop is an egress port of an equal-cost path, and weight is the share of the traffic
+// that is forwarded to the port.
+type nextHop struct {
+	port   uint32
+	weight uint16
+}
+
+// newNextHops returns the next hops of the egress ports that are weighted by their link speeds.
+func newNextHops(egress []*network.Port) []nextHop {
+	result := make([]nextHop, 0, len(egress))
+	for _, v := range egress {
+		result = append(result, nextHop{port: v.Number(), weight: linkWeight(v)})
+	}
+
+	return result
+}
+
+// linkWeight returns the bucket weight of p that is proportional to its link speed.
+func linkWeight(p *network.Port) uint16 {
+	value := p.Value()
+	if value == nil {
+		return 1
+	}
+	// 1 per 10 Mbps
+	w := value.Speed() / 10
+	if w == 0 {
+		return 1
+	}
+	if w > math.MaxUint16 {
+		return math.MaxUint16
+	}
+
+	return uint16(w)
+}
+
+// ecmp manages the SELECT groups that balance the traffic toward a destination device over
+// the next hops of the equal-cost paths.
+type ecmp struct {
+	mutex sync.Mutex
+	// Key is the device ID.
+	devices map[string]*ecmpDevice
+}
+
+type ecmpDevice struct {
+	// Key is the destination device ID.
+	groups map[string]*ecmpGroup
+	nextID uint32
+}
+
+type ecmpGroup struct {
+	id   uint32
+	hops string
+}
+
+func newECMP() *ecmp {
+	return &ecmp{
+		devices: make(map[string]*ecmpDevice),
+	}
+}
+
+// group makes sure that the device has the SELECT group toward the destination device, whose
+// ID is dstDeviceID, over hops, and then returns the group ID.
+func (r *ecmp) group(device groupWriter, dstDeviceID string, hops []nextHop) (id uint32, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	d, ok := r.devices[device.ID()]
+	if !ok {
+		d = &ecmpDevice{groups: make(map[string]*ecmpGroup)}
+		r.devices[device.ID()] = d
+	}
+	signature := fmt.Sprintf("%v", hops)
+	g, ok := d.groups[dstDeviceID]
+	// Already installed?
+	if ok && g.hops == signature {
+		return g.id, nil
+	}
+
+	buckets, err := newBuckets(device.Factory(), hops)
+	if err != nil {
+		return 0, err
+	}
+	if ok {
+		err = device.ModifyGroup(openflow.GroupSelect, g.id, buckets)
+	} else {
+		g = &ecmpGroup{id: d.nextID}
+		err = device.AddGroup(openflow.GroupSelect, g.id, buckets)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		d.groups[dstDeviceID] = g
+		d.nextID++
+	}
+	g.hops = signature
+	logger.Debugf("updated the ECMP group %v on %v: destination=%v, hops=%v", g.id, device.ID(), dstDeviceID, signature)
+
+	return g.id, nil
+}
+
+func newBuckets(f openflow.Factory, hops []nextHop) ([]openflow.Bucket, error) {
+	result := make([]openflow.Bucket, 0, len(hops))
+	for _, v := range hops {
+		action, err := f.NewAction()
+		if err != nil {
+			return nil, err
+		}
+		outPort := openflow.NewOutPort()
+		outPort.SetValue(v.port)
+		action.SetOutPort(outPort)
+
+		b := openflow.NewSelectBucket(v.weight, action)
+		// The switch does not select the bucket whose port is down.
+		b.WatchPort = v.port
+		result = append(result, b)
+	}
+
+	return result, nil
+}
+
+// reset forgets the groups of the device whose ID is deviceID. The caller should remove
+// the groups from the device.
+func (r *ecmp) reset(deviceID string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.devices, deviceID)
+}
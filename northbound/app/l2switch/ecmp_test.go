@@ -0,0 +1,106 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package l2switch
+
+import (
+	"testing"
+
+	"github.com/superkkt/cherry/openflow"
+	"github.com/superkkt/cherry/openflow/of13"
+)
+
+type groupMod struct {
+	cmd     openflow.GroupModCmd
+	id      uint32
+	buckets []openflow.Bucket
+}
+
+type dummyGroupWriter struct {
+	id   string
+	mods []groupMod
+}
+
+func (r *dummyGroupWriter) ID() string {
+	return r.id
+}
+
+func (r *dummyGroupWriter) Factory() openflow.Factory {
+	return of13.NewFactory()
+}
+
+func (r *dummyGroupWriter) AddGroup(t openflow.GroupType, id uint32, buckets []openflow.Bucket) error {
+	r.mods = append(r.mods, groupMod{openflow.GroupAdd, id, buckets})
+	return nil
+}
+
+func (r *dummyGroupWriter) ModifyGroup(t openflow.GroupType, id uint32, buckets []openflow.Bucket) error {
+	r.mods = append(r.mods, groupMod{openflow.GroupModify, id, buckets})
+	return nil
+}
+
+func TestECMPGroup(t *testing.T) {
+	e := newECMP()
+	d1 := &dummyGroupWriter{id: "1"}
+	d2 := &dummyGroupWriter{id: "2"}
+	hops := []nextHop{{port: 1, weight: 100}, {port: 2, weight: 1000}}
+
+	id, err := e.group(d1, "3", hops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 0 || len(d1.mods) != 1 || d1.mods[0].cmd != openflow.GroupAdd {
+		t.Fatalf("unexpected group: id=%v, mods=%v", id, d1.mods)
+	}
+	for i, b := range d1.mods[0].buckets {
+		port := b.Action.OutPort()
+		if port.Value() != hops[i].port || b.Weight != hops[i].weight || b.WatchPort != hops[i].port {
+			t.Fatalf("unexpected bucket: %+v", b)
+		}
+	}
+
+	// Same hops should not update the group.
+	if id, err := e.group(d1, "3", hops); err != nil || id != 0 || len(d1.mods) != 1 {
+		t.Fatalf("unexpected group: id=%v, mods=%v, err=%v", id, d1.mods, err)
+	}
+	// Another destination has its own group.
+	if id, err := e.group(d1, "4", hops); err != nil || id != 1 || len(d1.mods) != 2 {
+		t.Fatalf("unexpected group: id=%v, mods=%v, err=%v", id, d1.mods, err)
+	}
+	// Group IDs are allocated per device.
+	if id, err := e.group(d2, "3", hops); err != nil || id != 0 || len(d2.mods) != 1 {
+		t.Fatalf("unexpected group: id=%v, mods=%v, err=%v", id, d2.mods, err)
+	}
+	// Changed hops should modify the existing group.
+	id, err = e.group(d1, "3", hops[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 0 || len(d1.mods) != 3 || d1.mods[2].cmd != openflow.GroupModify || len(d1.mods[2].buckets) != 1 {
+		t.Fatalf("unexpected group: id=%v, mods=%v", id, d1.mods)
+	}
+
+	// The groups should be added again after reset.
+	e.reset("1")
+	if id, err := e.group(d1, "3", hops); err != nil || id != 0 || d1.mods[3].cmd != openflow.GroupAdd {
+		t.Fatalf("unexpected group: id=%v, mods=%v, err=%v", id, d1.mods, err)
+	}
+}
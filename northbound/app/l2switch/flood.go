@@ -0,0 +1,105 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package l2switch
+
+import (
+	"github.com/superkkt/cherry/network"
+	"github.com/superkkt/cherry/northbound/app"
+)
+
+type portClassifier interface {
+	// IsEdge returns whether p is an edge among two switches
+	IsEdge(p *network.Port) bool
+	// IsEnabledBySTP returns whether p is enabled by spanning tree protocol
+	IsEnabledBySTP(p *network.Port) bool
+	// Aggregation returns the member ports of the link aggregation that p belongs to
+	Aggregation(p *network.Port) []*network.Port
+}
+
+// floodPorts returns the ports, among ports of the ingress device, that a flooded
+// frame received from ingress should be sent to. The frame is never sent back to
+// the ingress port and never sent to the inter-switch ports disabled by the spanning
+// tree. A frame received from an inter-switch port is only sent toward the edge
+// (host) ports and the remaining branches of the spanning tree, so it never goes
+// back to the switch it came from. The members of a link aggregation are a single
+// logical port: the frame is never sent back to the aggregation of the ingress port,
+// and is sent to only the first member of the other aggregations.
+func floodPorts(c portClassifier, ports []*network.Port, ingress *network.Port) []*network.Port {
+	result := make([]*network.Port, 0, len(ports))
+	for _, p := range ports {
+		if p.Number() == ingress.Number() {
+			continue
+		}
+		if c.IsEdge(p) && !c.IsEnabledBySTP(p) {
+			continue
+		}
+		if members := c.Aggregation(p); len(members) > 0 {
+			if !samePort(members[0], p) || hasPort(members, ingress) {
+				continue
+			}
+		}
+		result = append(result, p)
+	}
+
+	return result
+}
+
+func samePort(p1, p2 *network.Port) bool {
+	return p1.Device() == p2.Device() && p1.Number() == p2.Number()
+}
+
+func hasPort(ports []*network.Port, p *network.Port) bool {
+	for _, v := range ports {
+		if samePort(v, p) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type flooder struct {
+	packetOut func(egress *network.Port, packet []byte) error
+}
+
+// flood broadcasts packet to the ports selected by floodPorts on the ingress device. Only
+// the ports in the slice are selected if finder is the view of a slice.
+func (r *flooder) flood(finder network.Finder, ingress *network.Port, packet []byte) error {
+	ports := ingress.Device().Ports()
+	if v, ok := finder.(app.SlicedFinder); ok {
+		sliced := make([]*network.Port, 0, len(ports))
+		for _, p := range ports {
+			if v.HasPort(p) {
+				sliced = append(sliced, p)
+			}
+		}
+		ports = sliced
+	}
+	for _, p := range floodPorts(finder, ports, ingress) {
+		if err := r.packetOut(p, packet); err != nil {
+			rateLogger.Errorf("flood "+p.ID(), "failed to flood a packet to %v: %v", p.ID(), err)
+			continue
+		}
+	}
+
+	return nil
+}
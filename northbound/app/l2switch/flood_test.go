@@ -0,0 +1,120 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package l2switch
+
+import (
+	"testing"
+
+	"github.com/superkkt/cherry/network"
+)
+
+type dummyClassifier struct {
+	edges    map[*network.Port]bool
+	disabled map[*network.Port]bool
+	lags     [][]*network.Port
+}
+
+func (r *dummyClassifier) IsEdge(p *network.Port) bool {
+	return r.edges[p]
+}
+
+func (r *dummyClassifier) IsEnabledBySTP(p *network.Port) bool {
+	return !r.disabled[p]
+}
+
+func (r *dummyClassifier) Aggregation(p *network.Port) []*network.Port {
+	for _, members := range r.lags {
+		if hasPort(members, p) {
+			return members
+		}
+	}
+
+	return nil
+}
+
+// Two switches, A and B, have two hosts on port 1 and 2 respectively. They are
+// connected by two links: 3-3 and 4-4. The 4-4 link is disabled by STP.
+func TestFloodPorts(t *testing.T) {
+	a := make([]*network.Port, 4)
+	b := make([]*network.Port, 4)
+	for i := range a {
+		a[i] = network.NewPort(nil, uint32(i+1))
+		b[i] = network.NewPort(nil, uint32(i+1))
+	}
+	c := &dummyClassifier{
+		edges:    map[*network.Port]bool{a[2]: true, a[3]: true, b[2]: true, b[3]: true},
+		disabled: map[*network.Port]bool{a[3]: true, b[3]: true},
+	}
+
+	tests := []struct {
+		name     string
+		ports    []*network.Port
+		ingress  *network.Port
+		expected []*network.Port
+	}{
+		{"host to host and switch", a, a[0], []*network.Port{a[1], a[2]}},
+		{"host to host and switch (reverse)", b, b[1], []*network.Port{b[0], b[2]}},
+		{"switch to host only", b, b[2], []*network.Port{b[0], b[1]}},
+		{"switch to host only (reverse)", a, a[2], []*network.Port{a[0], a[1]}},
+	}
+	for _, test := range tests {
+		got := floodPorts(c, test.ports, test.ingress)
+		if len(got) != len(test.expected) {
+			t.Fatalf("%v: unexpected number of ports: expected=%v, got=%v", test.name, len(test.expected), len(got))
+		}
+		for i := range got {
+			if got[i] != test.expected[i] {
+				t.Fatalf("%v: unexpected port: expected=%v, got=%v", test.name, test.expected[i].Number(), got[i].Number())
+			}
+		}
+	}
+}
+
+// A switch has a host on port 1, and a multi-chassis partner whose link aggregation
+// consists of port 2 and 3 of the switch and a port of another switch.
+func TestFloodPortsAggregation(t *testing.T) {
+	ports := make([]*network.Port, 3)
+	for i := range ports {
+		ports[i] = network.NewPort(nil, uint32(i+1))
+	}
+	other := network.NewPort(nil, 9)
+	c := &dummyClassifier{
+		lags: [][]*network.Port{{ports[1], ports[2]}},
+	}
+
+	got := floodPorts(c, ports, ports[0])
+	if len(got) != 1 || got[0] != ports[1] {
+		t.Fatalf("unexpected ports: expected=[2], got=%v", got)
+	}
+	// Never flood back to the partner.
+	got = floodPorts(c, ports, ports[2])
+	if len(got) != 1 || got[0] != ports[0] {
+		t.Fatalf("unexpected ports: expected=[1], got=%v", got)
+	}
+
+	// The first member resides on the other switch.
+	c.lags = [][]*network.Port{{other, ports[1], ports[2]}}
+	got = floodPorts(c, ports, ports[0])
+	if len(got) != 0 {
+		t.Fatalf("unexpected ports: expected=[], got=%v", got)
+	}
+}
@@ -25,6 +25,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/superkkt/cherry/clock"
 	"github.com/superkkt/cherry/network"
 )
 
@@ -33,47 +34,52 @@ type stormController struct {
 	max        uint
 	broadcasts []time.Time
 	bcaster    broadcaster
+	clock      clock.Clock
 }
 
 type broadcaster interface {
-	flood(ingress *network.Port, packet []byte) error
+	flood(finder network.Finder, ingress *network.Port, packet []byte) error
 }
 
 // max is the number of broadcasts that are allowed per second.
-func newStormController(max uint, bcaster broadcaster) *stormController {
+func newStormController(max uint, bcaster broadcaster, clk clock.Clock) *stormController {
 	if max <= 0 {
 		panic("max should be greater than zero")
 	}
 	if bcaster == nil {
 		panic("bcaster is nil")
 	}
+	if clk == nil {
+		panic("clock is nil")
+	}
 
 	return &stormController{
 		max:        max,
 		broadcasts: make([]time.Time, 0),
 		bcaster:    bcaster,
+		clock:      clk,
 	}
 }
 
-func (r *stormController) broadcast(ingress *network.Port, packet []byte) error {
+func (r *stormController) broadcast(finder network.Finder, ingress *network.Port, packet []byte) error {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
-	t := time.Now()
+	t := r.clock.Now()
 	bcasts := append(r.broadcasts, t)
 	l := uint(len(bcasts))
 	if l <= r.max {
 		r.broadcasts = bcasts
-		return r.bcaster.flood(ingress, packet)
+		return r.bcaster.flood(finder, ingress, packet)
 	}
 	// Only allows r.max broadcasts per 1 second
 	if t.Sub(bcasts[0]) > 1*time.Second {
 		// Shrink (l > r.max)
 		r.broadcasts = bcasts[l-r.max : l]
-		return r.bcaster.flood(ingress, packet)
+		return r.bcaster.flood(finder, ingress, packet)
 	}
 	// Deny! r.broadcast should not be updated!
-	logger.Info("too many broadcasts: broadcast is denied to avoid the broadcast storm!")
+	rateLogger.Infof("storm", "too many broadcasts: broadcast is denied to avoid the broadcast storm!")
 
 	return nil
 }
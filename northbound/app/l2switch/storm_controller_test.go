@@ -22,35 +22,41 @@
 package l2switch
 
 import (
-	"fmt"
 	"testing"
 	"time"
 
 	"github.com/superkkt/cherry/network"
+	"github.com/superkkt/cherry/testutil"
 )
 
+// second is slightly longer than a second as a real sleep would be, because
+// the storm controller allows the next broadcasts only after more than a second.
+const second = time.Second + time.Millisecond
+
+func newTestClock() *testutil.FakeClock {
+	return testutil.NewFakeClock(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))
+}
+
 func TestStorm(t *testing.T) {
 	max := uint(100)
 	dummy := new(dummyFlooder)
-	storm := newStormController(max, new(dummyLogger), dummy)
-	fmt.Printf("%v\n", time.Now())
+	clock := newTestClock()
+	storm := newStormController(max, dummy, clock)
 	for i := uint(0); i < max; i++ {
-		storm.broadcast(nil, nil)
+		storm.broadcast(nil, nil, nil)
 		if dummy.getCounter() != uint64(i+1) {
 			t.Fatalf("Unexpected flood counter: expected=%v, got=%v", i+1, dummy.getCounter())
 		}
 	}
 	for i := 0; i < 10; i++ {
-		fmt.Printf("%v\n", time.Now())
-		storm.broadcast(nil, nil)
+		storm.broadcast(nil, nil, nil)
 		if dummy.getCounter() != uint64(max) {
 			t.Fatalf("Unexpected flood counter: expected=%v, got=%v", max, dummy.getCounter())
 		}
 	}
-	time.Sleep(1 * time.Second)
-	fmt.Printf("%v\n", time.Now())
+	clock.Advance(second)
 	for i := uint(0); i < max-1; i++ {
-		storm.broadcast(nil, nil)
+		storm.broadcast(nil, nil, nil)
 		if dummy.getCounter() != uint64(max+i+1) {
 			t.Fatalf("Unexpected flood counter: expected=%v, got=%v", max+1, dummy.getCounter())
 		}
@@ -60,32 +66,32 @@ func TestStorm(t *testing.T) {
 func TestPeriodicBroadcast(t *testing.T) {
 	max := uint(1)
 	dummy := new(dummyFlooder)
-	storm := newStormController(max, new(dummyLogger), dummy)
+	clock := newTestClock()
+	storm := newStormController(max, dummy, clock)
 	for i := 0; i < 10; i++ {
-		fmt.Printf("Count: %v, Timestamp: %v\n", i, time.Now())
-		storm.broadcast(nil, nil)
+		storm.broadcast(nil, nil, nil)
 		if dummy.getCounter() != uint64(i+1) {
 			t.Fatalf("Unexpected flood counter: expected=%v, got=%v", i+1, dummy.getCounter())
 		}
-		time.Sleep(1 * time.Second)
+		clock.Advance(second)
 	}
 }
 
 func TestPeriodicStorm(t *testing.T) {
 	max := uint(1)
 	dummy := new(dummyFlooder)
-	storm := newStormController(max, new(dummyLogger), dummy)
+	clock := newTestClock()
+	storm := newStormController(max, dummy, clock)
 	for i := 0; i < 10; i++ {
-		fmt.Printf("Count: %v, Timestamp: %v\n", i, time.Now())
-		storm.broadcast(nil, nil)
+		storm.broadcast(nil, nil, nil)
 		if dummy.getCounter() != uint64(i+1) {
 			t.Fatalf("Unexpected flood counter: expected=%v, got=%v", i+1, dummy.getCounter())
 		}
-		storm.broadcast(nil, nil)
+		storm.broadcast(nil, nil, nil)
 		if dummy.getCounter() != uint64(i+1) {
 			t.Fatalf("Unexpected flood counter: expected=%v, got=%v", i+1, dummy.getCounter())
 		}
-		time.Sleep(1 * time.Second)
+		clock.Advance(second)
 	}
 }
 
@@ -93,7 +99,7 @@ type dummyFlooder struct {
 	counter uint64
 }
 
-func (r *dummyFlooder) flood(ingress *network.Port, packet []byte) error {
+func (r *dummyFlooder) flood(finder network.Finder, ingress *network.Port, packet []byte) error {
 	r.counter++
 	return nil
 }
@@ -101,30 +107,3 @@ func (r *dummyFlooder) flood(ingress *network.Port, packet []byte) error {
 func (r *dummyFlooder) getCounter() uint64 {
 	return r.counter
 }
-
-type dummyLogger struct{}
-
-func (r *dummyLogger) Debug(m string) (err error) {
-	fmt.Printf("DEBUG: %v\n", m)
-	return nil
-}
-
-func (r *dummyLogger) Err(m string) (err error) {
-	fmt.Printf("ERR: %v\n", m)
-	return nil
-}
-
-func (r *dummyLogger) Info(m string) (err error) {
-	fmt.Printf("INFO: %v\n", m)
-	return nil
-}
-
-func (r *dummyLogger) Notice(m string) (err error) {
-	fmt.Printf("NOTICE: %v\n", m)
-	return nil
-}
-
-func (r *dummyLogger) Warning(m string) (err error) {
-	fmt.Printf("WARN: %v\n", m)
-	return nil
-}
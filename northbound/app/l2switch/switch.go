@@ -28,24 +28,36 @@ import (
 	"sync"
 	"time"
 
+	"github.com/superkkt/cherry/clock"
 	"github.com/superkkt/cherry/network"
 	"github.com/superkkt/cherry/northbound/app"
 	"github.com/superkkt/cherry/openflow"
 	"github.com/superkkt/cherry/protocol"
+	"github.com/superkkt/cherry/ratelog"
 
 	"github.com/pkg/errors"
 	"github.com/superkkt/go-logging"
+	"github.com/superkkt/viper"
 )
 
 var (
 	logger = logging.MustGetLogger("l2switch")
+	// Logger for the messages that may be repeated on every packet.
+	rateLogger = ratelog.New("l2switch", 10*time.Second)
 )
 
 type L2Switch struct {
 	app.BaseProcessor
 	stormCtrl *stormController
+	flooder   *flooder
+	ecmp      *ecmp
 	db        Database
 	once      sync.Once
+	clock     clock.Clock
+	// Flows installed by the flow manager, which are refreshed by touching them.
+	flows *installedFlows
+	// Verify how each device handles the touched flows if it is true.
+	verifyTouch bool
 }
 
 type Database interface {
@@ -54,20 +66,20 @@ type Database interface {
 }
 
 func New(db Database) *L2Switch {
-	return &L2Switch{
-		stormCtrl: newStormController(100, new(flooder)),
-		db:        db,
+	v := &L2Switch{
+		db:    db,
+		ecmp:  newECMP(),
+		clock: clock.Real,
+		flows: newInstalledFlows(),
 	}
-}
-
-type flooder struct{}
+	v.flooder = &flooder{packetOut: v.PacketOut}
+	v.stormCtrl = newStormController(100, v.flooder, v.clock)
 
-// flood broadcasts packet to all ports on the ingress device, except the ingress port itself.
-func (r *flooder) flood(ingress *network.Port, packet []byte) error {
-	return ingress.Device().Flood(ingress, packet)
+	return v
 }
 
 func (r *L2Switch) Init() error {
+	r.verifyTouch = viper.GetBool("l2switch.verify_touch")
 	return nil
 }
 
@@ -80,13 +92,15 @@ func isBroadcast(eth *protocol.Ethernet) bool {
 }
 
 type flowParam struct {
-	device  *network.Device
+	device *network.Device
+	// Ingress port to match. Zero means any port.
+	inPort  uint32
 	dstMAC  net.HardwareAddr
 	outPort uint32
 }
 
 func (r flowParam) String() string {
-	return fmt.Sprintf("Device=%v, DstMAC=%v, OutPort=%v", r.device.ID(), r.dstMAC, r.outPort)
+	return fmt.Sprintf("Device=%v, InPort=%v, DstMAC=%v, OutPort=%v", r.device.ID(), r.inPort, r.dstMAC, r.outPort)
 }
 
 func (r *L2Switch) setFlow(p flowParam) error {
@@ -95,12 +109,17 @@ func (r *L2Switch) setFlow(p flowParam) error {
 	if err != nil {
 		return err
 	}
+	if p.inPort != 0 {
+		inPort := openflow.NewInPort()
+		inPort.SetValue(p.inPort)
+		match.SetInPort(inPort)
+	}
 	match.SetDstMAC(p.dstMAC)
 
 	outPort := openflow.NewOutPort()
 	outPort.SetValue(p.outPort)
 
-	if err := p.device.SetFlow(match, outPort); err != nil {
+	if err := p.device.SetFlow(r.Name(), match, outPort); err != nil {
 		return err
 	}
 	logger.Debugf("installed a new flow rule: %v", p)
@@ -122,7 +141,7 @@ func (r *L2Switch) switching(p switchParam) error {
 		dstMAC:  p.ethernet.DstMAC,
 		outPort: p.egress.Number(),
 	}
-	if err := r.setFlow(param); err != nil {
+	if err := r.setSlicedFlow(p.finder, p.ingress, param); err != nil {
 		return err
 	}
 
@@ -143,7 +162,10 @@ func (r *L2Switch) OnPacketIn(finder network.Finder, ingress *network.Port, eth
 func (r *L2Switch) processPacket(finder network.Finder, ingress *network.Port, eth *protocol.Ethernet) (drop bool, err error) {
 	logger.Debugf("PACKET_IN.. Ingress=%v, SrcMAC=%v, DstMAC=%v", ingress.ID(), eth.SrcMAC, eth.DstMAC)
 
-	packet, err := eth.MarshalBinary()
+	// Strip the 802.1Q tag, if any, because the switches add the default VLAN tag on the egress ports.
+	untagged := *eth
+	untagged.Tagged = false
+	packet, err := untagged.MarshalBinary()
 	if err != nil {
 		return false, err
 	}
@@ -151,7 +173,7 @@ func (r *L2Switch) processPacket(finder network.Finder, ingress *network.Port, e
 	// Broadcast?
 	if isBroadcast(eth) {
 		logger.Debugf("broadcasting.. SrcMAC=%v, DstMAC=%v", eth.SrcMAC, eth.DstMAC)
-		return true, r.stormCtrl.broadcast(ingress, packet)
+		return true, r.stormCtrl.broadcast(finder, ingress, packet)
 	}
 
 	logger.Debugf("finding node for %v...", eth.DstMAC)
@@ -163,7 +185,7 @@ func (r *L2Switch) processPacket(finder network.Finder, ingress *network.Port, e
 		if status == network.LocationUndiscovered {
 			// Broadcast!
 			logger.Debugf("undiscovered node! broadcasting.. SrcMAC=%v, DstMAC=%v", eth.SrcMAC, eth.DstMAC)
-			return true, ingress.Device().Flood(ingress, packet)
+			return true, r.flooder.flood(finder, ingress, packet)
 		} else if status == network.LocationUnregistered {
 			// Drop!
 			logger.Debugf("unknown node! dropping.. SrcMAC=%v, DstMAC=%v", eth.SrcMAC, eth.DstMAC)
@@ -184,6 +206,11 @@ func (r *L2Switch) processPacket(finder network.Finder, ingress *network.Port, e
 	param := switchParam{}
 	// Check whether src and dst nodes reside on a same switch device
 	if ingress.Device().ID() == dstNode.Port().Device().ID() {
+		// Drop this packet if the destination is located on the ingress port to avoid sending the packet back
+		if ingress.Number() == dstNode.Port().Number() {
+			logger.Debugf("destination resides on the ingress port.. dropping SrcMAC=%v, DstMAC=%v", eth.SrcMAC, eth.DstMAC)
+			return true, nil
+		}
 		param = switchParam{
 			finder:    finder,
 			ethernet:  eth,
@@ -192,30 +219,137 @@ func (r *L2Switch) processPacket(finder network.Finder, ingress *network.Port, e
 			rawPacket: packet,
 		}
 	} else {
-		path := finder.Path(ingress.Device().ID(), dstNode.Port().Device().ID())
-		if len(path) == 0 {
+		egress := nextHops(finder, ingress.Device(), dstNode.Port())
+		if len(egress) == 0 {
 			logger.Debugf("empty path.. dropping SrcMAC=%v, DstMAC=%v", eth.SrcMAC, eth.DstMAC)
 			return true, nil
 		}
-		egress := path[0][0]
 		// Drop this packet if it goes back to the ingress port to avoid duplicated packet routing
-		if ingress.Number() == egress.Number() {
+		if ingress.Number() == egress[0].Number() {
 			logger.Debugf("ignore routing path that goes back to the ingress port (SrcMAC=%v, DstMAC=%v)", eth.SrcMAC, eth.DstMAC)
 			return true, nil
 		}
 
-		param = switchParam{
-			finder:    finder,
-			ethernet:  eth,
-			ingress:   ingress,
-			egress:    egress,
-			rawPacket: packet,
+		if _, ok := finder.(app.SlicedFinder); ok {
+			// The ingress ports of the other devices are not known, so the flow of a slice is
+			// installed on the ingress device only, and then on the next hops by their PACKET_INs.
+			param := flowParam{device: ingress.Device(), dstMAC: eth.DstMAC, outPort: egress[0].Number()}
+			if err := r.setSlicedFlow(finder, ingress, param); err != nil {
+				return true, err
+			}
+		} else {
+			// The equal-cost paths may traverse the links disabled by STP, whose PACKET_INs are ignored.
+			// So, we install the flows on all the devices at once instead of the ingress device only.
+			r.setFlows(finder, eth.DstMAC, dstNode.Port())
 		}
+		logger.Debugf("sending a packet (Src=%v, Dst=%v) to egress port %v..", eth.SrcMAC, eth.DstMAC, egress[0].ID())
+		return true, r.PacketOut(egress[0], packet)
 	}
 
 	return true, r.switching(param)
 }
 
+// setSlicedFlow installs the flow of p like setFlow. If finder is the view of a slice, the flow
+// also matches ingress because the flows of a slice should match its ingress ports, and the
+// packets are sent by PACKET_OUTs without any error if the flow is still out of the slice,
+// e.g., the slice does not have the default VLAN.
+func (r *L2Switch) setSlicedFlow(finder network.Finder, ingress *network.Port, p flowParam) error {
+	if _, ok := finder.(app.SlicedFinder); !ok {
+		return r.setFlow(p)
+	}
+
+	p.inPort = ingress.Number()
+	err := r.setFlow(p)
+	if errors.Cause(err) == network.ErrOutOfSlice {
+		logger.Debugf("skip to install a flow out of the slice: %v: %v", p, err)
+		return nil
+	}
+
+	return err
+}
+
+// nextHops returns the egress ports of device toward dst, which is the port where a destination
+// node is connected. Two or more ports are the next hops of the equal-cost paths, and no port
+// means that there is no path to dst.
+func nextHops(finder network.Finder, device *network.Device, dst *network.Port) []*network.Port {
+	// Reside on this device?
+	if device.ID() == dst.Device().ID() {
+		return []*network.Port{dst}
+	}
+
+	hops := finder.NextHops(device.ID(), dst.Device().ID())
+	if len(hops) == 0 {
+		// Fall back to the shortest path on the spanning tree.
+		path := finder.Path(device.ID(), dst.Device().ID())
+		if len(path) == 0 {
+			return nil
+		}
+		hops = path[:1]
+	}
+	result := make([]*network.Port, 0, len(hops))
+	for _, v := range hops {
+		result = append(result, v[0])
+	}
+
+	return result
+}
+
+// forward installs the flow that forwards the packets destined to mac on device to the egress
+// ports toward the destination device whose ID is dstDeviceID.
+func (r *L2Switch) forward(device *network.Device, dstDeviceID string, mac net.HardwareAddr, egress []*network.Port) error {
+	// The device cannot balance the traffic by itself without groups, so it just uses one of the paths.
+	if len(egress) == 1 || !device.SupportsGroups() {
+		flow := flowParam{
+			device:  device,
+			dstMAC:  mac,
+			outPort: egress[0].Number(),
+		}
+		// Touch the flow that is already installed to keep its counters.
+		if r.flows.installed(device.ID(), mac, flow.outPort) {
+			return r.touchFlow(flow)
+		}
+		if err := r.setFlow(flow); err != nil {
+			return err
+		}
+		r.flows.add(device.ID(), mac, flow.outPort)
+
+		return nil
+	}
+
+	id, err := r.ecmp.group(device, dstDeviceID, newNextHops(egress))
+	if err != nil {
+		return errors.Wrap(err, "updating the ECMP group")
+	}
+	match, err := device.Factory().NewMatch()
+	if err != nil {
+		return err
+	}
+	match.SetDstMAC(mac)
+	if err := device.SetGroupFlow(r.Name(), match, id); err != nil {
+		return err
+	}
+	logger.Debugf("installed a new flow rule: Device=%v, DstMAC=%v, Group=%v", device.ID(), mac, id)
+
+	return nil
+}
+
+// setFlows installs the flows for the packets destined to mac on all the devices, where dst is
+// the port the destination node is connected.
+func (r *L2Switch) setFlows(finder network.Finder, mac net.HardwareAddr, dst *network.Port) {
+	for _, device := range finder.Devices() {
+		egress := nextHops(finder, device, dst)
+		// No path to the destination node?
+		if len(egress) == 0 {
+			logger.Debugf("skip flow management for %v on %v: no path", mac, device.ID())
+			continue
+		}
+		if err := r.forward(device, dst.Device().ID(), mac, egress); err != nil {
+			logger.Errorf("failed to modify the flows for %v on %v: %v", mac, device.ID(), err)
+			continue
+		}
+	}
+}
+
 func (r *L2Switch) OnTopologyChange(finder network.Finder) error {
 	logger.Debug("OnTopologyChange..")
 
@@ -224,6 +358,7 @@ func (r *L2Switch) OnTopologyChange(finder network.Finder) error {
 	if err := r.removeAllFlows(finder.Devices()); err != nil {
 		return err
 	}
+	r.flows.removeAll()
 
 	return r.BaseProcessor.OnTopologyChange(finder)
 }
@@ -265,6 +400,7 @@ func (r *L2Switch) OnPortDown(finder network.Finder, port *network.Port) error {
 		return errors.Wrap(err, fmt.Sprintf("removing flows heading to port %v", port.ID()))
 	}
 	logger.Debugf("removed all flows heading to the port %v", port.ID())
+	r.flows.removePort(device.ID(), port.Number())
 
 	return r.BaseProcessor.OnPortDown(finder, port)
 }
@@ -276,15 +412,43 @@ func (r *L2Switch) OnDeviceUp(finder network.Finder, device *network.Device) err
 		go r.flowManager(finder)
 	})
 
+	// Remove the groups installed before the connection because we don't know their buckets.
+	r.ecmp.reset(device.ID())
+	r.flows.removeDevice(device.ID())
+	if device.SupportsGroups() {
+		if err := device.DeleteGroup(openflow.AllGroups); err != nil {
+			return errors.Wrap(err, "removing the groups")
+		}
+	}
+
 	return r.BaseProcessor.OnDeviceUp(finder, device)
 }
 
+func (r *L2Switch) OnDeviceDown(finder network.Finder, device *network.Device) error {
+	r.ecmp.reset(device.ID())
+	r.flows.removeDevice(device.ID())
+
+	return r.BaseProcessor.OnDeviceDown(finder, device)
+}
+
+func (r *L2Switch) OnFlowRemoved(finder network.Finder, flow openflow.FlowRemoved) error {
+	// The flow should be installed again instead of being touched. FLOW_REMOVED does not tell
+	// the device, so forget the flows for the address on all the devices.
+	if id, ok := network.CookieOwner(flow.Cookie()); ok && id == network.CookieOwnerID(r.Name()) {
+		if wildcard, mac := flow.Match().DstMAC(); !wildcard {
+			r.flows.removeMAC(mac)
+		}
+	}
+
+	return r.BaseProcessor.OnFlowRemoved(finder, flow)
+}
+
 func (r *L2Switch) flowManager(finder network.Finder) {
 	logger.Debug("executed flow manager")
 
-	ticker := time.Tick(35 * time.Second)
+	ticker := r.clock.NewTicker(35 * time.Second)
 	// Infinite loop.
-	for range ticker {
+	for range ticker.C() {
 		mac, err := r.db.MACAddrs()
 		if err != nil {
 			logger.Errorf("failed to get MAC addresses: %v", err)
@@ -319,32 +483,5 @@ func (r *L2Switch) modifyFlows(finder network.Finder, mac net.HardwareAddr) {
 	}
 
 	// Update the flows on all devices.
-	for _, device := range finder.Devices() {
-		var egress *network.Port
-
-		// Reside on this device?
-		if device.ID() == node.Port().Device().ID() {
-			logger.Debugf("reside on the same device: DPID=%v, Port=%v", device.ID(), node.Port().Number())
-			egress = node.Port()
-		} else {
-			// Find the shortest path from this device to an another device that is connected to the destination node.
-			path := finder.Path(device.ID(), node.Port().Device().ID())
-			// No path to the destination node?
-			if len(path) == 0 {
-				logger.Debugf("skip flow management for %v on %v: no path", mac, device.ID())
-				continue
-			}
-			egress = path[0][0]
-		}
-
-		flow := flowParam{
-			device:  device,
-			dstMAC:  mac,
-			outPort: egress.Number(),
-		}
-		if err := r.setFlow(flow); err != nil {
-			logger.Errorf("failed to modify the flows for %v on %v: %v", mac, device.ID(), err)
-			continue
-		}
-	}
+	r.setFlows(finder, mac, node.Port())
 }
@@ -0,0 +1,181 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package l2switch
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/superkkt/cherry/network"
+	"github.com/superkkt/cherry/openflow"
+)
+
+// installedFlows remembers the egress ports of the flows installed by the flow manager, so
+// that it can refresh the flows by touching them instead of reinstalling them, which resets
+// their counters.
+type installedFlows struct {
+	mutex sync.Mutex
+	// Key is the device ID, and then the destination MAC address.
+	ports map[string]map[string]uint32
+	// Devices whose touch behavior has been verified.
+	verified map[string]bool
+}
+
+func newInstalledFlows() *installedFlows {
+	return &installedFlows{
+		ports:    make(map[string]map[string]uint32),
+		verified: make(map[string]bool),
+	}
+}
+
+// installed returns whether the flow for mac forwarding to port has been installed on the device
+// whose ID is id.
+func (r *installedFlows) installed(id string, mac net.HardwareAddr, port uint32) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	v, ok := r.ports[id][mac.String()]
+	return ok && v == port
+}
+
+func (r *installedFlows) add(id string, mac net.HardwareAddr, port uint32) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	m, ok := r.ports[id]
+	if !ok {
+		m = make(map[string]uint32)
+		r.ports[id] = m
+	}
+	m[mac.String()] = port
+}
+
+// removeMAC forgets the flows for mac on all the devices.
+func (r *installedFlows) removeMAC(mac net.HardwareAddr) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, m := range r.ports {
+		delete(m, mac.String())
+	}
+}
+
+// removePort forgets the flows forwarding to the port whose number is port on the device whose ID is id.
+func (r *installedFlows) removePort(id string, port uint32) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for mac, v := range r.ports[id] {
+		if v == port {
+			delete(r.ports[id], mac)
+		}
+	}
+}
+
+// removeDevice forgets the flows and the verification of the device whose ID is id.
+func (r *installedFlows) removeDevice(id string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.ports, id)
+	delete(r.verified, id)
+}
+
+// removeAll forgets the flows on all the devices.
+func (r *installedFlows) removeAll() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.ports = make(map[string]map[string]uint32)
+}
+
+// verify returns true once for each device if the verification is not done yet.
+func (r *installedFlows) verify(id string) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.verified[id] {
+		return false
+	}
+	r.verified[id] = true
+
+	return true
+}
+
+func (r *installedFlows) unverify(id string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.verified, id)
+}
+
+// touchFlow refreshes the flow of p, which has been installed by setFlow, without resetting its
+// counters. If the verify mode is enabled, the first touch on each device also checks how the
+// device handles it.
+func (r *L2Switch) touchFlow(p flowParam) error {
+	f := p.device.Factory()
+	match, err := f.NewMatch()
+	if err != nil {
+		return err
+	}
+	match.SetDstMAC(p.dstMAC)
+	outPort := openflow.NewOutPort()
+	outPort.SetValue(p.outPort)
+
+	if !r.verifyTouch || !r.flows.verify(p.device.ID()) {
+		return p.device.TouchFlow(r.Name(), match, outPort)
+	}
+	// The verification waits for the replies of the device, which may be read by the goroutine
+	// calling this function, e.g., when the PACKET_IN workers are disabled. Verify, and touch
+	// the flow in the meantime, in another goroutine so that we do not block the PACKET_INs.
+	go r.verifyTouchFlow(p.device, match, outPort)
+
+	return nil
+}
+
+// verifyTouchFlow touches the flow for match forwarding to outPort on device, and logs how the
+// device has handled the touch. The flow is touched without the verification if it fails.
+func (r *L2Switch) verifyTouchFlow(device *network.Device, match openflow.Match, outPort openflow.OutPort) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	report, err := device.VerifyTouchFlow(ctx, r.Name(), match, outPort)
+	if err != nil {
+		// Try again on the next touch.
+		r.flows.unverify(device.ID())
+		logger.Debugf("failed to verify the touch on %v: %v", device.ID(), err)
+		if err := device.TouchFlow(r.Name(), match, outPort); err != nil {
+			logger.Errorf("failed to touch a flow on %v: %v", device.ID(), err)
+		}
+		return
+	}
+	switch {
+	case report.CountersReset:
+		logger.Warningf("device %v replaces a touched flow: its counters are reset (duration=%vs -> %vs)", device.ID(), report.Before, report.After)
+	case report.DurationReset:
+		logger.Infof("device %v restarts the timeouts of a touched flow (duration=%vs -> %vs)", device.ID(), report.Before, report.After)
+	default:
+		logger.Infof("device %v keeps the timeouts of a touched flow as the specification says (duration=%vs -> %vs)", device.ID(), report.Before, report.After)
+	}
+}
@@ -0,0 +1,62 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package l2switch
+
+import (
+	"net"
+	"testing"
+)
+
+func TestInstalledFlows(t *testing.T) {
+	mac1 := net.HardwareAddr{0x0a, 0, 0, 0, 0, 1}
+	mac2 := net.HardwareAddr{0x0a, 0, 0, 0, 0, 2}
+	flows := newInstalledFlows()
+	flows.add("1", mac1, 1)
+	flows.add("1", mac2, 2)
+	flows.add("2", mac1, 3)
+
+	if !flows.installed("1", mac1, 1) {
+		t.Fatal("the installed flow is not found")
+	}
+	// The egress port has been changed.
+	if flows.installed("1", mac1, 2) {
+		t.Fatal("the flow to another port is found")
+	}
+
+	flows.removePort("1", 2)
+	if flows.installed("1", mac2, 2) || !flows.installed("1", mac1, 1) {
+		t.Fatal("unexpected flows after the port is removed")
+	}
+	flows.removeMAC(mac1)
+	if flows.installed("1", mac1, 1) || flows.installed("2", mac1, 3) {
+		t.Fatal("unexpected flows after the address is removed")
+	}
+
+	if !flows.verify("1") || flows.verify("1") {
+		t.Fatal("the device is not verified only once")
+	}
+	flows.add("1", mac1, 1)
+	flows.removeDevice("1")
+	if flows.installed("1", mac1, 1) || !flows.verify("1") {
+		t.Fatal("unexpected state after the device is removed")
+	}
+}
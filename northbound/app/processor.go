@@ -29,6 +29,12 @@ import (
 	"github.com/superkkt/cherry/protocol"
 )
 
+// Processor is a north-bound application. Enabled applications are chained in the order
+// they appear in the config file, and each of them receives the network events from
+// the previous one. An application usually embeds BaseProcessor, overrides the events
+// it is interested in, and then calls the BaseProcessor method to propagate the event
+// to the next application. Returning without calling it stops the propagation.
+//
 // Processor should prepare to be executed by multiple goroutines simultaneously.
 type Processor interface {
 	Dependencies() []string
@@ -102,6 +108,15 @@ func (r *BaseProcessor) OnPortDown(finder network.Finder, port *network.Port) er
 	return next.OnPortDown(finder, port)
 }
 
+func (r *BaseProcessor) OnPortsRenumbered(finder network.Finder, device *network.Device, mapping map[uint32]uint32, deleted []uint32) error {
+	// Do nothging and execute the next processor if it exists
+	next, ok := r.Next()
+	if !ok {
+		return nil
+	}
+	return next.OnPortsRenumbered(finder, device, mapping, deleted)
+}
+
 func (r *BaseProcessor) OnTopologyChange(finder network.Finder) error {
 	// Do nothging and execute the next processor if it exists
 	next, ok := r.Next()
@@ -111,6 +126,24 @@ func (r *BaseProcessor) OnTopologyChange(finder network.Finder) error {
 	return next.OnTopologyChange(finder)
 }
 
+func (r *BaseProcessor) OnLinkUp(finder network.Finder, ports [2]*network.Port) error {
+	// Do nothging and execute the next processor if it exists
+	next, ok := r.Next()
+	if !ok {
+		return nil
+	}
+	return next.OnLinkUp(finder, ports)
+}
+
+func (r *BaseProcessor) OnLinkDown(finder network.Finder, ports [2]*network.Port) error {
+	// Do nothging and execute the next processor if it exists
+	next, ok := r.Next()
+	if !ok {
+		return nil
+	}
+	return next.OnLinkDown(finder, ports)
+}
+
 func (r *BaseProcessor) OnFlowRemoved(finder network.Finder, flow openflow.FlowRemoved) error {
 	// Do nothging and execute the next processor if it exists
 	next, ok := r.Next()
@@ -120,6 +153,15 @@ func (r *BaseProcessor) OnFlowRemoved(finder network.Finder, flow openflow.FlowR
 	return next.OnFlowRemoved(finder, flow)
 }
 
+func (r *BaseProcessor) OnPortStatsUpdated(finder network.Finder, device *network.Device) error {
+	// Do nothging and execute the next processor if it exists
+	next, ok := r.Next()
+	if !ok {
+		return nil
+	}
+	return next.OnPortStatsUpdated(finder, device)
+}
+
 func (r *BaseProcessor) Next() (next Processor, ok bool) {
 	if r.next != nil {
 		return r.next, true
@@ -157,3 +199,24 @@ func (r *BaseProcessor) PacketOut(egress *network.Port, packet []byte) error {
 
 	return egress.Device().SendMessage(out)
 }
+
+// SlicedFinder is the view of the network that the applications assigned to a slice see.
+// The flows of the applications should match only the traffic of the slice, otherwise the
+// devices reject them with network.ErrOutOfSlice.
+type SlicedFinder interface {
+	network.Finder
+	Slice() *network.Slice
+	// HasPort returns whether p is in the slice.
+	HasPort(p *network.Port) bool
+}
+
+// IsEdgeSwitch returns whether device has a port that is not connected to another switch.
+func IsEdgeSwitch(finder network.Finder, device *network.Device) bool {
+	for _, p := range device.Ports() {
+		if !finder.IsEdge(p) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -31,9 +31,11 @@ import (
 
 	"github.com/superkkt/cherry/network"
 	"github.com/superkkt/cherry/northbound/app"
+	"github.com/superkkt/cherry/northbound/app/hosttracker"
 	"github.com/superkkt/cherry/northbound/util/announcer"
 	"github.com/superkkt/cherry/openflow"
 	"github.com/superkkt/cherry/protocol"
+	"github.com/superkkt/cherry/ratelog"
 
 	"github.com/pkg/errors"
 	"github.com/superkkt/go-logging"
@@ -41,12 +43,15 @@ import (
 
 var (
 	logger = logging.MustGetLogger("proxyarp")
+	// Logger for the messages that may be repeated on every packet.
+	rateLogger = ratelog.New("proxyarp", 10*time.Second)
 )
 
 type ProxyARP struct {
 	app.BaseProcessor
-	db   database
-	once sync.Once
+	db      database
+	tracker hostTracker
+	once    sync.Once
 }
 
 type database interface {
@@ -54,14 +59,22 @@ type database interface {
 	GetActivatedHosts() ([]Host, error)
 }
 
+// hostTracker provides the hosts learned from the network, which may not be registered in the database.
+type hostTracker interface {
+	HostsByIP(ip net.IP) []hosttracker.Host
+}
+
 type Host struct {
 	IP  net.IP
 	MAC net.HardwareAddr
 }
 
-func New(db database) *ProxyARP {
+// New returns a ProxyARP that answers ARP requests for the hosts registered in db, and
+// also for the hosts learned by tracker if it is not nil.
+func New(db database, tracker hostTracker) *ProxyARP {
 	return &ProxyARP{
-		db: db,
+		db:      db,
+		tracker: tracker,
 	}
 }
 
@@ -111,6 +124,10 @@ func (r *ProxyARP) broadcaster(finder network.Finder) {
 }
 
 func (r *ProxyARP) OnPacketIn(finder network.Finder, ingress *network.Port, eth *protocol.Ethernet) error {
+	// IPv6?
+	if eth.Type == 0x86DD {
+		return r.onIPv6(finder, ingress, eth)
+	}
 	// ARP?
 	if eth.Type != 0x0806 {
 		return r.BaseProcessor.OnPacketIn(finder, ingress, eth)
@@ -132,13 +149,13 @@ func (r *ProxyARP) OnPacketIn(finder network.Finder, ingress *network.Port, eth
 	// ARP request?
 	if arp.Operation != 1 {
 		// Drop all ARP packets whose type is not a reqeust.
-		logger.Infof("drop ARP packet whose type is not a request.. ingress=%v (%v)", ingress.ID(), arp)
+		rateLogger.Infof("not request "+ingress.ID(), "drop ARP packet whose type is not a request.. ingress=%v (%v)", ingress.ID(), arp)
 		return nil
 	}
 
-	mac, ok, err := r.db.MAC(arp.TPA)
+	mac, ok, err := r.lookup(arp.TPA)
 	if err != nil {
-		return errors.Wrap(&proxyarpErr{temporary: true, err: err}, "failed to query MAC")
+		return err
 	}
 	if !ok {
 		logger.Debugf("drop the ARP request for unknown host (%v)", arp.TPA)
@@ -156,6 +173,26 @@ func (r *ProxyARP) OnPacketIn(finder network.Finder, ingress *network.Port, eth
 	return sendARPReply(ingress, reply)
 }
 
+// lookup returns the MAC address of ip from the database, or from the host tracker if ip is not registered.
+func (r *ProxyARP) lookup(ip net.IP) (mac net.HardwareAddr, ok bool, err error) {
+	mac, ok, err = r.db.MAC(ip)
+	if err != nil {
+		return nil, false, errors.Wrap(&proxyarpErr{temporary: true, err: err}, "failed to query MAC")
+	}
+	if ok || r.tracker == nil {
+		return mac, ok, nil
+	}
+
+	hosts := r.tracker.HostsByIP(ip)
+	// Do not answer if the address is ambiguous, e.g., duplicated IP addresses.
+	if len(hosts) != 1 {
+		return nil, false, nil
+	}
+	logger.Debugf("found %v from the host tracker: %v", ip, hosts[0])
+
+	return hosts[0].MAC, true, nil
+}
+
 func sendARPReply(ingress *network.Port, packet []byte) error {
 	f := ingress.Device().Factory()
 
@@ -0,0 +1,97 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package proxyarp
+
+import (
+	"bytes"
+	"net"
+
+	"github.com/superkkt/cherry/network"
+	"github.com/superkkt/cherry/protocol"
+)
+
+// onIPv6 answers the neighbor solicitations (RFC 4861) for the hosts learned by the host
+// tracker, which is the IPv6 equivalent of the ARP reply. Unlike ARP, the solicitations that
+// are not answered, e.g., duplicate address detections and the ones for unknown hosts, and
+// the other IPv6 packets are propagated to the next processors so that the target host can
+// answer the solicitation itself.
+func (r *ProxyARP) onIPv6(finder network.Finder, ingress *network.Port, eth *protocol.Ethernet) error {
+	ip := new(protocol.IPv6)
+	if err := ip.UnmarshalBinary(eth.Payload); err != nil {
+		return err
+	}
+	// ICMPv6?
+	if ip.NextHeader != 58 || r.tracker == nil {
+		return r.BaseProcessor.OnPacketIn(finder, ingress, eth)
+	}
+	ns := new(protocol.NeighborDiscovery)
+	if err := ns.UnmarshalBinary(ip.Payload); err != nil || ns.Type != protocol.ICMPv6NeighborSolicitation {
+		return r.BaseProcessor.OnPacketIn(finder, ingress, eth)
+	}
+	// Duplicate address detection should be answered by the host that has the address.
+	if ip.SrcIP.IsUnspecified() {
+		return r.BaseProcessor.OnPacketIn(finder, ingress, eth)
+	}
+
+	hosts := r.tracker.HostsByIP(ns.Target)
+	// Do not answer if the address is ambiguous, e.g., duplicated IP addresses, or if the
+	// sender is the target itself.
+	if len(hosts) != 1 || bytes.Equal(hosts[0].MAC, eth.SrcMAC) {
+		logger.Debugf("neighbor solicitation for unknown host (%v)", ns.Target)
+		return r.BaseProcessor.OnPacketIn(finder, ingress, eth)
+	}
+	logger.Debugf("neighbor solicitation for %v (%v)", ns.Target, hosts[0].MAC)
+
+	reply, err := makeNeighborAdvertisement(eth.SrcMAC, ip.SrcIP, ns.Target, hosts[0].MAC)
+	if err != nil {
+		return err
+	}
+	logger.Debugf("sending neighbor advertisement to %v..", ingress.ID())
+
+	return sendARPReply(ingress, reply)
+}
+
+// makeNeighborAdvertisement returns the Ethernet frame of the advertisement that answers the
+// solicitation from dstMAC and dstIP for target whose MAC address is mac.
+func makeNeighborAdvertisement(dstMAC net.HardwareAddr, dstIP, target net.IP, mac net.HardwareAddr) ([]byte, error) {
+	na := protocol.NewNeighborAdvertisement(target, mac)
+	na.SetPseudoHeader(target, dstIP)
+	payload, err := na.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	ip := protocol.NewIPv6(target, dstIP, 58, payload)
+	// Neighbor discovery messages should have 255 hop limit.
+	ip.HopLimit = 255
+	packet, err := ip.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	eth := protocol.Ethernet{
+		SrcMAC:  mac,
+		DstMAC:  dstMAC,
+		Type:    0x86DD,
+		Payload: packet,
+	}
+
+	return eth.MarshalBinary()
+}
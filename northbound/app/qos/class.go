@@ -0,0 +1,231 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package qos
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+
+	"github.com/superkkt/cherry/openflow"
+
+	"github.com/superkkt/viper"
+)
+
+// Maximum priority of a traffic class.
+const maxClassPriority = 9999
+
+// Class is a traffic class of IPv4 packets and the QoS treatment applied to them. The
+// zero value of a match field, or nil for the addresses, matches any value.
+type Class struct {
+	Name string
+	// Classes are evaluated in the descending order of the priority.
+	Priority uint16
+	Src, Dst *net.IPNet
+	// IP protocol number, e.g., 6 for TCP and 17 for UDP.
+	Protocol uint8
+	// TCP or UDP port numbers.
+	SrcPort, DstPort uint16
+	// DSCP value remarked on the packets. -1 keeps the DSCP value.
+	DSCP int
+	// ID of the egress queue on the output port. -1 uses the default queue.
+	Queue int64
+	// Rate limit in kilobits per second and its burst size in kilobits. 0 rate means unlimited.
+	Rate, Burst uint32
+
+	// ID of the class that is unique among the classes, which is also the cookie value of its flows.
+	id uint64
+	// ID of the meter of the class on the switches. 0 if the class has no rate limit.
+	meterID uint32
+}
+
+func (r Class) String() string {
+	return fmt.Sprintf("Class Name=%v, Priority=%v, Src=%v, Dst=%v, Protocol=%v, SrcPort=%v, DstPort=%v, DSCP=%v, Queue=%v, Rate=%v, Burst=%v",
+		r.Name, r.Priority, r.Src, r.Dst, r.Protocol, r.SrcPort, r.DstPort, r.DSCP, r.Queue, r.Rate, r.Burst)
+}
+
+type classParam struct {
+	priority                   int
+	src, dst                   string
+	protocol, srcPort, dstPort int
+	// -1 if they are not specified.
+	dscp, queue int
+	rate, burst int
+}
+
+func newClass(name string, p classParam) (Class, error) {
+	v := Class{Name: name, DSCP: p.dscp, Queue: int64(p.queue)}
+
+	if p.priority < 0 || p.priority > maxClassPriority {
+		return Class{}, fmt.Errorf("priority should be 0 ~ %v", maxClassPriority)
+	}
+	v.Priority = uint16(p.priority)
+	for _, n := range []struct {
+		s     string
+		field **net.IPNet
+	}{{p.src, &v.Src}, {p.dst, &v.Dst}} {
+		if len(n.s) == 0 {
+			continue
+		}
+		_, network, err := net.ParseCIDR(n.s)
+		if err != nil || network.IP.To4() == nil {
+			return Class{}, fmt.Errorf("invalid IPv4 network: %v", n.s)
+		}
+		*n.field = network
+	}
+	if p.protocol < 0 || p.protocol > 0xFF {
+		return Class{}, fmt.Errorf("invalid protocol: %v", p.protocol)
+	}
+	v.Protocol = uint8(p.protocol)
+	if p.srcPort < 0 || p.srcPort > 0xFFFF || p.dstPort < 0 || p.dstPort > 0xFFFF {
+		return Class{}, errors.New("invalid port number")
+	}
+	v.SrcPort, v.DstPort = uint16(p.srcPort), uint16(p.dstPort)
+	if (v.SrcPort != 0 || v.DstPort != 0) && v.Protocol != 6 && v.Protocol != 17 {
+		return Class{}, errors.New("port number without TCP or UDP protocol")
+	}
+	if p.dscp < -1 || p.dscp > 0x3F {
+		return Class{}, fmt.Errorf("invalid DSCP: %v", p.dscp)
+	}
+	if p.queue < -1 || int64(p.queue) >= 0xFFFFFFFF {
+		return Class{}, fmt.Errorf("invalid queue ID: %v", p.queue)
+	}
+	if p.rate < 0 || int64(p.rate) > 0xFFFFFFFF || p.burst < 0 || int64(p.burst) > 0xFFFFFFFF {
+		return Class{}, errors.New("invalid rate or burst")
+	}
+	v.Rate, v.Burst = uint32(p.rate), uint32(p.burst)
+	if v.Rate == 0 && v.Burst > 0 {
+		return Class{}, errors.New("burst without rate")
+	}
+	if v.DSCP == -1 && v.Queue == -1 && v.Rate == 0 {
+		return Class{}, errors.New("none of DSCP, queue and rate is specified")
+	}
+
+	return v, nil
+}
+
+// loadClasses returns the traffic classes defined in the qos.class section of the config file.
+func loadClasses() ([]Class, error) {
+	names := []string{}
+	for name := range viper.GetStringMap("qos.class") {
+		names = append(names, name)
+	}
+
+	result := []Class{}
+	for _, name := range names {
+		key := "qos.class." + name
+		p := classParam{
+			priority: viper.GetInt(key + ".priority"),
+			src:      viper.GetString(key + ".src"),
+			dst:      viper.GetString(key + ".dst"),
+			protocol: viper.GetInt(key + ".protocol"),
+			srcPort:  viper.GetInt(key + ".src_port"),
+			dstPort:  viper.GetInt(key + ".dst_port"),
+			dscp:     -1,
+			queue:    -1,
+			rate:     viper.GetInt(key + ".rate"),
+			burst:    viper.GetInt(key + ".burst"),
+		}
+		if viper.IsSet(key + ".dscp") {
+			p.dscp = viper.GetInt(key + ".dscp")
+		}
+		if viper.IsSet(key + ".queue") {
+			p.queue = viper.GetInt(key + ".queue")
+		}
+		c, err := newClass(name, p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid QoS class %v: %v", name, err)
+		}
+		result = append(result, c)
+	}
+	assignIDs(result)
+
+	return result, nil
+}
+
+// assignIDs sorts classes by their names, and then assigns the class IDs and the meter IDs
+// in that order, so that they are not changed by the map iteration of the config file.
+func assignIDs(classes []Class) {
+	sort.Slice(classes, func(i, j int) bool {
+		return classes[i].Name < classes[j].Name
+	})
+
+	meterID := uint32(0)
+	for i := range classes {
+		classes[i].id = uint64(i + 1)
+		if classes[i].Rate > 0 {
+			meterID++
+			classes[i].meterID = meterID
+		}
+	}
+}
+
+// bands returns the meter bands of the class that drop the packets exceeding the rate.
+func (r Class) bands() []openflow.MeterBand {
+	return []openflow.MeterBand{{Type: openflow.MeterBandDrop, Rate: r.Rate, BurstSize: r.Burst}}
+}
+
+// newMatch returns the match of the flow of r for the packets coming from the ingress port.
+func (r Class) newMatch(f openflow.Factory, ingress uint32) (openflow.Match, error) {
+	match, err := f.NewMatch()
+	if err != nil {
+		return nil, err
+	}
+	inPort := openflow.NewInPort()
+	inPort.SetValue(ingress)
+	match.SetInPort(inPort)
+	match.SetEtherType(0x0800) // IPv4
+	if r.Src != nil {
+		match.SetSrcIP(r.Src)
+	}
+	if r.Dst != nil {
+		match.SetDstIP(r.Dst)
+	}
+	if r.Protocol != 0 {
+		match.SetIPProtocol(r.Protocol)
+	}
+	if r.SrcPort != 0 {
+		match.SetSrcPort(r.SrcPort)
+	}
+	if r.DstPort != 0 {
+		match.SetDstPort(r.DstPort)
+	}
+
+	return match, nil
+}
+
+// newAction returns the action that remarks the DSCP and selects the egress queue of the packets.
+func (r Class) newAction(f openflow.Factory) (openflow.Action, error) {
+	action, err := f.NewAction()
+	if err != nil {
+		return nil, err
+	}
+	if r.DSCP != -1 {
+		action.SetIPDSCP(uint8(r.DSCP))
+	}
+	if r.Queue != -1 {
+		action.SetQueue(uint32(r.Queue))
+	}
+
+	return action, action.Error()
+}
@@ -0,0 +1,81 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package qos
+
+import (
+	"testing"
+)
+
+func TestNewClass(t *testing.T) {
+	valid := classParam{priority: 100, dst: "10.0.1.1/24", protocol: 17, dstPort: 5060, dscp: 46, queue: -1}
+	c, err := newClass("voice", valid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Dst.String() != "10.0.1.0/24" || c.Src != nil || c.DSCP != 46 || c.Queue != -1 || c.Rate != 0 {
+		t.Fatalf("unexpected class: %v", c)
+	}
+
+	invalid := []struct {
+		name  string
+		param func(p *classParam)
+	}{
+		{"priority", func(p *classParam) { p.priority = maxClassPriority + 1 }},
+		{"IPv6 network", func(p *classParam) { p.src = "fe80::/64" }},
+		{"port without TCP or UDP", func(p *classParam) { p.protocol = 1 }},
+		{"DSCP", func(p *classParam) { p.dscp = 64 }},
+		{"burst without rate", func(p *classParam) { p.burst = 100 }},
+		{"no treatment", func(p *classParam) { p.dscp = -1 }},
+	}
+	for _, v := range invalid {
+		p := valid
+		v.param(&p)
+		if _, err := newClass("voice", p); err == nil {
+			t.Errorf("expected an error for the invalid %v", v.name)
+		}
+	}
+}
+
+func TestAssignIDs(t *testing.T) {
+	classes := []Class{
+		{Name: "video", Rate: 5000},
+		{Name: "bulk", Rate: 1000},
+		{Name: "voice", DSCP: 46},
+	}
+	assignIDs(classes)
+
+	expected := []struct {
+		name    string
+		id      uint64
+		meterID uint32
+	}{
+		{"bulk", 1, 1},
+		{"video", 2, 2},
+		{"voice", 3, 0},
+	}
+	for i, v := range expected {
+		c := classes[i]
+		if c.Name != v.name || c.id != v.id || c.meterID != v.meterID {
+			t.Errorf("unexpected class IDs: expected=%+v, got=%v (id=%v, meterID=%v)", v, c.Name, c.id, c.meterID)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package qos
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/superkkt/cherry/clock"
+	"github.com/superkkt/cherry/network"
+	"github.com/superkkt/cherry/northbound/app"
+	"github.com/superkkt/cherry/openflow"
+
+	"github.com/superkkt/go-logging"
+)
+
+var (
+	logger = logging.MustGetLogger("qos")
+)
+
+// Interval to synchronize the flows of the switches with the ports connected to hosts.
+const syncInterval = 5 * time.Second
+
+// Priority of the flow of the class whose priority is zero, unless a priority band is reserved for
+// the QoS application. The flows of the classes are higher than the table-miss flow of the
+// classifier table, and the classes do not share the priorities of the other applications.
+const basePriority = 100
+
+// QoS applies the traffic classes defined in the config file to the IPv4 packets entering
+// the network from the hosts. For each class, it installs a meter on the switches if the
+// class has a rate limit, and a flow on the classifier table of the switches for each port
+// that is not connected to another switch. The flow meters the packets, remarks their DSCP,
+// selects their egress queue, and then passes them to the flow table where the normal
+// forwarding flows are installed. Only the OpenFlow 1.3 switches that have a classifier
+// table, i.e., qos.classifier_table is enabled, are supported.
+type QoS struct {
+	app.BaseProcessor
+	clock   clock.Clock
+	once    sync.Once
+	classes []Class
+
+	mutex sync.Mutex
+	// Flows installed on each device. Key is the DPID, and value is the flows keyed by flow.key().
+	installed map[string]map[string]flow
+	// Devices whose meters have been installed. Key is the DPID.
+	meters map[string]bool
+}
+
+// flow is the flow of a class for the packets coming from a port.
+type flow struct {
+	class Class
+	port  uint32
+}
+
+func (r flow) key() string {
+	return fmt.Sprintf("%v/%v", r.class.Name, r.port)
+}
+
+func New() *QoS {
+	return newQoS(clock.Real)
+}
+
+func newQoS(clk clock.Clock) *QoS {
+	if clk == nil {
+		panic("clock is nil")
+	}
+
+	return &QoS{
+		clock:     clk,
+		installed: make(map[string]map[string]flow),
+		meters:    make(map[string]bool),
+	}
+}
+
+func (r *QoS) Init() error {
+	classes, err := loadClasses()
+	if err != nil {
+		return err
+	}
+	r.classes = classes
+	for _, v := range classes {
+		logger.Infof("loaded a QoS class: %v", v)
+	}
+
+	return nil
+}
+
+func (r *QoS) Name() string {
+	return "QoS"
+}
+
+func (r *QoS) String() string {
+	return fmt.Sprintf("%v (classes=%v)", r.Name(), len(r.classes))
+}
+
+func (r *QoS) OnDeviceUp(finder network.Finder, device *network.Device) error {
+	// The device has removed all the flows when it connected.
+	r.reset(device)
+
+	// Make sure that there is only one synchronizer in this application.
+	r.once.Do(func() {
+		go r.synchronizer(finder)
+	})
+
+	return r.BaseProcessor.OnDeviceUp(finder, device)
+}
+
+func (r *QoS) OnDeviceDown(finder network.Finder, device *network.Device) error {
+	r.reset(device)
+
+	return r.BaseProcessor.OnDeviceDown(finder, device)
+}
+
+func (r *QoS) reset(device *network.Device) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.installed, device.ID())
+	delete(r.meters, device.ID())
+}
+
+func (r *QoS) synchronizer(finder network.Finder) {
+	logger.Debug("executed the QoS synchronizer")
+
+	ticker := r.clock.NewTicker(syncInterval)
+	defer ticker.Stop()
+
+	// Infinite loop.
+	for {
+		r.sync(finder)
+		<-ticker.C()
+	}
+}
+
+// sync installs and removes the flows of the switches according to the changes of the ports
+// connected to hosts.
+func (r *QoS) sync(finder network.Finder) {
+	if len(r.classes) == 0 {
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, device := range finder.Devices() {
+		if device.IsClosed() {
+			continue
+		}
+		// The classifier table is known after the device has been initialized.
+		if ok, _ := device.ClassifierTableID(); !ok {
+			continue
+		}
+		if err := r.installMeters(device); err != nil {
+			logger.Errorf("failed to install the QoS meters on %v: %v", device.ID(), err)
+			continue
+		}
+		desired := make(map[string]flow)
+		for _, p := range device.Ports() {
+			// Packets from another switch have been already classified by the switch.
+			if finder.IsEdge(p) {
+				continue
+			}
+			for _, c := range r.classes {
+				v := flow{class: c, port: p.Number()}
+				desired[v.key()] = v
+			}
+		}
+		if err := r.syncDevice(device, desired); err != nil {
+			logger.Errorf("failed to synchronize the QoS flows of %v: %v", device.ID(), err)
+			continue
+		}
+	}
+}
+
+// XXX: Caller should lock the mutex before they call this function
+func (r *QoS) installMeters(device *network.Device) error {
+	if r.meters[device.ID()] {
+		return nil
+	}
+
+	for _, c := range r.classes {
+		if c.meterID == 0 {
+			continue
+		}
+		// Remove the meter left by the previous connection, if any.
+		if err := device.DeleteMeter(c.meterID); err != nil {
+			return err
+		}
+		if err := device.AddMeter(c.meterID, false, c.bands()); err != nil {
+			return err
+		}
+		logger.Debugf("installed the QoS meter on %v: class=%v, meterID=%v", device.ID(), c.Name, c.meterID)
+	}
+	r.meters[device.ID()] = true
+
+	return nil
+}
+
+// XXX: Caller should lock the mutex before they call this function
+func (r *QoS) syncDevice(device *network.Device, desired map[string]flow) error {
+	installed, ok := r.installed[device.ID()]
+	if !ok {
+		installed = make(map[string]flow)
+		r.installed[device.ID()] = installed
+	}
+
+	for key, v := range installed {
+		if _, ok := desired[key]; ok {
+			continue
+		}
+		if err := r.sendFlow(device, openflow.FlowDeleteStrict, v); err != nil {
+			return err
+		}
+		delete(installed, key)
+		logger.Debugf("removed the QoS flow from %v: port=%v, %v", device.ID(), v.port, v.class)
+	}
+	for key, v := range desired {
+		if _, ok := installed[key]; ok {
+			continue
+		}
+		if err := r.sendFlow(device, openflow.FlowAdd, v); err != nil {
+			return err
+		}
+		installed[key] = v
+		logger.Debugf("installed the QoS flow on %v: port=%v, %v", device.ID(), v.port, v.class)
+	}
+
+	return nil
+}
+
+func (r *QoS) sendFlow(device *network.Device, cmd openflow.FlowModCmd, v flow) error {
+	ok, tableID := device.ClassifierTableID()
+	if !ok {
+		return fmt.Errorf("no classifier table on %v", device.ID())
+	}
+
+	f := device.Factory()
+	match, err := v.class.newMatch(f, v.port)
+	if err != nil {
+		return err
+	}
+	action, err := v.class.newAction(f)
+	if err != nil {
+		return err
+	}
+	inst, err := f.NewInstruction()
+	if err != nil {
+		return err
+	}
+	inst.ApplyActionAndGotoTable(action, device.FlowTableID())
+	if v.class.meterID != 0 {
+		inst.SetMeter(v.class.meterID)
+	}
+
+	flow, err := f.NewFlowMod(cmd)
+	if err != nil {
+		return err
+	}
+	priority, err := device.FlowPriority(r.Name(), tableID, basePriority, v.class.Priority)
+	if err != nil {
+		return err
+	}
+	flow.SetCookie(network.NewCookie(r.Name(), v.class.id))
+	flow.SetTableID(tableID)
+	flow.SetPriority(priority)
+	flow.SetFlowMatch(match)
+	flow.SetFlowInstruction(inst)
+
+	if cmd == openflow.FlowAdd {
+		return device.InstallFlowWithLifetime(flow, network.FlowLifetime{Class: network.FlowPermanent})
+	}
+
+	return device.InstallFlow(flow)
+}
@@ -0,0 +1,441 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package router
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/superkkt/cherry/clock"
+	"github.com/superkkt/cherry/network"
+	"github.com/superkkt/cherry/northbound/app"
+	"github.com/superkkt/cherry/northbound/app/hosttracker"
+	"github.com/superkkt/cherry/openflow"
+	"github.com/superkkt/cherry/protocol"
+
+	"github.com/superkkt/go-logging"
+	"github.com/superkkt/viper"
+)
+
+var (
+	logger = logging.MustGetLogger("router")
+)
+
+const (
+	// Interval to synchronize the routes of the switches with the learned hosts.
+	syncInterval = 5 * time.Second
+	// Priority of the route flows, which is higher than the one of the normal flows (10). The
+	// lowest priority of the priority band of the application is used instead, if any.
+	routePriority = 20
+)
+
+// Router routes the IPv4 packets among the configured subnets. It answers the ARP
+// requests for the gateway addresses with the virtual gateway MAC address, and installs
+// a route flow for each host learned by the host tracker on all the switches. The route
+// flow matches the packets sent to the gateway MAC address and the host IP address,
+// rewrites their MAC addresses, and forwards them toward the host. The switches after
+// the first hop forward the rewritten packets as normal L2 packets.
+type Router struct {
+	app.BaseProcessor
+	clock   clock.Clock
+	tracker hostTracker
+	once    sync.Once
+	// Virtual MAC address of all the gateways.
+	gatewayMAC net.HardwareAddr
+	gateways   []gateway
+
+	mutex sync.Mutex
+	// Routes installed on each device. Key is the DPID, and value is the routes keyed by route.key().
+	installed map[string]map[string]route
+}
+
+type hostTracker interface {
+	HostsByIP(ip net.IP) []hosttracker.Host
+	Hosts() []hosttracker.Host
+}
+
+type gateway struct {
+	ip     net.IP
+	subnet *net.IPNet
+}
+
+func New(tracker hostTracker) *Router {
+	return newRouter(clock.Real, tracker)
+}
+
+func newRouter(clk clock.Clock, tracker hostTracker) *Router {
+	if clk == nil {
+		panic("clock is nil")
+	}
+	if tracker == nil {
+		panic("host tracker is nil")
+	}
+
+	return &Router{
+		clock:     clk,
+		tracker:   tracker,
+		installed: make(map[string]map[string]route),
+	}
+}
+
+func (r *Router) Init() error {
+	mac, err := net.ParseMAC(viper.GetString("router.gateway_mac"))
+	if err != nil || len(mac) != 6 || mac[0]&0x1 != 0 {
+		return fmt.Errorf("invalid router.gateway_mac in the config file: %v", viper.GetString("router.gateway_mac"))
+	}
+	gateways, err := parseGateways(viper.GetString("router.gateways"))
+	if err != nil {
+		return fmt.Errorf("invalid router.gateways in the config file: %v", err)
+	}
+	r.gatewayMAC = mac
+	r.gateways = gateways
+
+	return nil
+}
+
+// parseGateways parses s that is a comma separated list of the gateway addresses with the
+// prefix lengths of their subnets, e.g., "10.0.1.1/24, 10.0.2.1/24".
+func parseGateways(s string) ([]gateway, error) {
+	result := []gateway{}
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); len(v) == 0 {
+			continue
+		}
+		ip, subnet, err := net.ParseCIDR(v)
+		if err != nil || ip.To4() == nil {
+			return nil, fmt.Errorf("invalid gateway: %v", v)
+		}
+		for _, g := range result {
+			if g.subnet.Contains(subnet.IP) || subnet.Contains(g.subnet.IP) {
+				return nil, fmt.Errorf("overlapped subnets: %v and %v", g.subnet, subnet)
+			}
+		}
+		result = append(result, gateway{ip: ip.To4(), subnet: subnet})
+	}
+
+	return result, nil
+}
+
+func (r *Router) Name() string {
+	return "Router"
+}
+
+func (r *Router) String() string {
+	return fmt.Sprintf("%v", r.Name())
+}
+
+func (r *Router) Dependencies() []string {
+	return []string{"HostTracker"}
+}
+
+// isGateway returns whether ip is one of the gateway addresses.
+func (r *Router) isGateway(ip net.IP) bool {
+	for _, g := range r.gateways {
+		if g.ip.Equal(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isRoutable returns whether ip is a host address in one of the subnets.
+func (r *Router) isRoutable(ip net.IP) bool {
+	for _, g := range r.gateways {
+		if g.subnet.Contains(ip) {
+			return !g.ip.Equal(ip)
+		}
+	}
+
+	return false
+}
+
+func (r *Router) OnDeviceUp(finder network.Finder, device *network.Device) error {
+	// The device has removed all the flows when it connected.
+	r.mutex.Lock()
+	delete(r.installed, device.ID())
+	r.mutex.Unlock()
+
+	// Make sure that there is only one synchronizer in this application.
+	r.once.Do(func() {
+		go r.synchronizer(finder)
+	})
+
+	return r.BaseProcessor.OnDeviceUp(finder, device)
+}
+
+func (r *Router) OnDeviceDown(finder network.Finder, device *network.Device) error {
+	r.mutex.Lock()
+	delete(r.installed, device.ID())
+	r.mutex.Unlock()
+
+	return r.BaseProcessor.OnDeviceDown(finder, device)
+}
+
+func (r *Router) OnPacketIn(finder network.Finder, ingress *network.Port, eth *protocol.Ethernet) error {
+	switch {
+	case eth.Type == 0x0806:
+		arp := new(protocol.ARP)
+		if err := arp.UnmarshalBinary(eth.Payload); err != nil {
+			return err
+		}
+		// ARP request for a gateway?
+		if arp.Operation == 1 && r.isGateway(arp.TPA) {
+			return r.replyARP(ingress, arp)
+		}
+	case eth.Type == 0x0800 && bytes.Equal(eth.DstMAC, r.gatewayMAC):
+		// The first packets arrive before the route flows are installed.
+		return r.route(finder, eth)
+	}
+
+	return r.BaseProcessor.OnPacketIn(finder, ingress, eth)
+}
+
+func (r *Router) replyARP(ingress *network.Port, request *protocol.ARP) error {
+	logger.Debugf("ARP request for the gateway %v from %v (%v)", request.TPA, request.SPA, ingress.ID())
+
+	reply, err := protocol.NewARPReply(r.gatewayMAC, request.SHA, request.TPA, request.SPA).MarshalBinary()
+	if err != nil {
+		return err
+	}
+	eth := protocol.Ethernet{
+		SrcMAC:  r.gatewayMAC,
+		DstMAC:  request.SHA,
+		Type:    0x0806,
+		Payload: reply,
+	}
+	packet, err := eth.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	return r.PacketOut(ingress, packet)
+}
+
+// route sends eth, which has been sent to the gateway MAC address, to its destination host.
+func (r *Router) route(finder network.Finder, eth *protocol.Ethernet) error {
+	ip := new(protocol.IPv4)
+	if err := ip.UnmarshalBinary(eth.Payload); err != nil {
+		return err
+	}
+	if !r.isRoutable(ip.DstIP) {
+		logger.Debugf("dropping the packet to the unroutable address %v", ip.DstIP)
+		return nil
+	}
+	host, egress, ok := r.lookup(finder, ip.DstIP)
+	if !ok {
+		logger.Debugf("dropping the packet to the unknown host %v", ip.DstIP)
+		return nil
+	}
+
+	// Strip the 802.1Q tag, if any, because the switches add the default VLAN tag on the egress ports.
+	routed := *eth
+	routed.Tagged = false
+	routed.SrcMAC = r.gatewayMAC
+	routed.DstMAC = host.MAC
+	packet, err := routed.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	logger.Debugf("routing the packet from %v to %v (%v)", ip.SrcIP, ip.DstIP, egress.ID())
+
+	return r.PacketOut(egress, packet)
+}
+
+// lookup returns the host whose IP address is ip and the port where it is attached.
+func (r *Router) lookup(finder network.Finder, ip net.IP) (host hosttracker.Host, port *network.Port, ok bool) {
+	hosts := r.tracker.HostsByIP(ip)
+	// Do not route if the address is ambiguous, e.g., duplicated IP addresses.
+	if len(hosts) != 1 {
+		return hosttracker.Host{}, nil, false
+	}
+	device := finder.Device(strconv.FormatUint(hosts[0].DPID, 10))
+	if device == nil {
+		return hosttracker.Host{}, nil, false
+	}
+	port = device.Port(hosts[0].Port)
+	if port == nil {
+		return hosttracker.Host{}, nil, false
+	}
+
+	return hosts[0], port, true
+}
+
+func (r *Router) synchronizer(finder network.Finder) {
+	logger.Debug("executed the route synchronizer")
+
+	ticker := r.clock.NewTicker(syncInterval)
+	defer ticker.Stop()
+
+	// Infinite loop.
+	for {
+		r.sync(finder)
+		<-ticker.C()
+	}
+}
+
+// route is a flow that routes the packets to a host.
+type route struct {
+	ip      net.IP
+	mac     net.HardwareAddr
+	outPort uint32
+}
+
+func (r route) String() string {
+	return fmt.Sprintf("IP=%v, MAC=%v, OutPort=%v", r.ip, r.mac, r.outPort)
+}
+
+func (r route) key() string {
+	return r.String()
+}
+
+// sync installs and removes the route flows of the switches according to the changes of
+// the learned hosts and the topology.
+func (r *Router) sync(finder network.Finder) {
+	// Key is the DPID.
+	desired := make(map[string]map[string]route)
+	devices := finder.Devices()
+	for _, d := range devices {
+		desired[d.ID()] = make(map[string]route)
+	}
+
+	for _, h := range r.tracker.Hosts() {
+		if h.IP == nil || !r.isRoutable(h.IP) {
+			continue
+		}
+		dst := finder.Device(strconv.FormatUint(h.DPID, 10))
+		if dst == nil {
+			continue
+		}
+		for _, d := range devices {
+			outPort := h.Port
+			if d.ID() != dst.ID() {
+				path := finder.Path(d.ID(), dst.ID())
+				// No path to the host?
+				if len(path) == 0 {
+					continue
+				}
+				outPort = path[0][0].Number()
+			}
+			v := route{ip: h.IP.To4(), mac: h.MAC, outPort: outPort}
+			desired[d.ID()][v.key()] = v
+		}
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, d := range devices {
+		if d.IsClosed() {
+			continue
+		}
+		if err := r.syncDevice(d, desired[d.ID()]); err != nil {
+			logger.Errorf("failed to synchronize the routes of %v: %v", d.ID(), err)
+			continue
+		}
+	}
+}
+
+// XXX: Caller should lock the mutex before they call this function
+func (r *Router) syncDevice(device *network.Device, desired map[string]route) error {
+	installed, ok := r.installed[device.ID()]
+	if !ok {
+		installed = make(map[string]route)
+		r.installed[device.ID()] = installed
+	}
+
+	for key, v := range installed {
+		if _, ok := desired[key]; ok {
+			continue
+		}
+		if err := r.sendFlow(device, openflow.FlowDeleteStrict, v); err != nil {
+			return err
+		}
+		delete(installed, key)
+		logger.Debugf("removed the route from %v: %v", device.ID(), v)
+	}
+	for key, v := range desired {
+		if _, ok := installed[key]; ok {
+			continue
+		}
+		if err := r.sendFlow(device, openflow.FlowAdd, v); err != nil {
+			return err
+		}
+		installed[key] = v
+		logger.Debugf("installed the route on %v: %v", device.ID(), v)
+	}
+
+	return nil
+}
+
+func (r *Router) sendFlow(device *network.Device, cmd openflow.FlowModCmd, v route) error {
+	f := device.Factory()
+	match, err := f.NewMatch()
+	if err != nil {
+		return err
+	}
+	match.SetEtherType(0x0800) // IPv4
+	match.SetDstMAC(r.gatewayMAC)
+	match.SetDstIP(&net.IPNet{IP: v.ip, Mask: net.CIDRMask(32, 32)})
+
+	outPort := openflow.NewOutPort()
+	outPort.SetValue(v.outPort)
+	action, err := f.NewAction()
+	if err != nil {
+		return err
+	}
+	action.SetSrcMAC(r.gatewayMAC)
+	action.SetDstMAC(v.mac)
+	action.SetOutPort(outPort)
+	inst, err := f.NewInstruction()
+	if err != nil {
+		return err
+	}
+	inst.ApplyAction(action)
+
+	flow, err := f.NewFlowMod(cmd)
+	if err != nil {
+		return err
+	}
+	priority, err := device.FlowPriority(r.Name(), device.FlowTableID(), routePriority, 0)
+	if err != nil {
+		return err
+	}
+	// The cookie value is the host IP address.
+	flow.SetCookie(network.NewCookie(r.Name(), uint64(binary.BigEndian.Uint32(v.ip))))
+	flow.SetTableID(device.FlowTableID())
+	flow.SetPriority(priority)
+	flow.SetFlowMatch(match)
+	flow.SetFlowInstruction(inst)
+
+	if cmd == openflow.FlowAdd {
+		return device.InstallFlowWithLifetime(flow, network.FlowLifetime{Class: network.FlowPermanent})
+	}
+
+	return device.InstallFlow(flow)
+}
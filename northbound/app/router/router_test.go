@@ -0,0 +1,61 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package router
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/superkkt/cherry/northbound/app/hosttracker"
+	"github.com/superkkt/cherry/testutil"
+)
+
+type fakeTracker struct{}
+
+func (r fakeTracker) HostsByIP(ip net.IP) []hosttracker.Host { return nil }
+func (r fakeTracker) Hosts() []hosttracker.Host              { return nil }
+
+func TestParseGateways(t *testing.T) {
+	gateways, err := parseGateways("10.0.1.1/24, 10.0.2.1/24,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := newRouter(testutil.NewFakeClock(time.Unix(0, 0)), fakeTracker{})
+	router.gateways = gateways
+
+	if !router.isGateway(net.ParseIP("10.0.2.1")) || router.isGateway(net.ParseIP("10.0.2.2")) {
+		t.Fatalf("unexpected gateway addresses: %v", gateways)
+	}
+	if !router.isRoutable(net.ParseIP("10.0.1.100")) {
+		t.Fatalf("host in the subnet is not routable")
+	}
+	if router.isRoutable(net.ParseIP("10.0.1.1")) || router.isRoutable(net.ParseIP("10.0.3.1")) {
+		t.Fatalf("gateway or host outside the subnets is routable")
+	}
+
+	for _, s := range []string{"10.0.1.1", "10.0.1.1/24, 10.0.1.2/16", "fe80::1/64"} {
+		if _, err := parseGateways(s); err == nil {
+			t.Fatalf("expected an error for %q", s)
+		}
+	}
+}
@@ -0,0 +1,522 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package northbound
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/superkkt/cherry/metrics"
+	"github.com/superkkt/cherry/network"
+	"github.com/superkkt/cherry/northbound/app"
+	"github.com/superkkt/cherry/openflow"
+	"github.com/superkkt/cherry/protocol"
+)
+
+type eventType int
+
+const (
+	evPacketIn eventType = iota
+	evPortUp
+	evPortDown
+	evPortsRenumbered
+	evDeviceUp
+	evDeviceDown
+	evFlowRemoved
+	evTopologyChange
+	evPortStatsUpdated
+	evLinkUp
+	evLinkDown
+	numEventTypes
+)
+
+var eventNames = [numEventTypes]string{
+	"PacketIn",
+	"PortUp",
+	"PortDown",
+	"PortsRenumbered",
+	"DeviceUp",
+	"DeviceDown",
+	"FlowRemoved",
+	"TopologyChange",
+	"PortStatsUpdated",
+	"LinkUp",
+	"LinkDown",
+}
+
+// Upper bounds of the latency histogram buckets. The last bucket has no upper bound.
+var latencyBuckets = [...]time.Duration{
+	100 * time.Microsecond,
+	1 * time.Millisecond,
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+	1 * time.Second,
+}
+
+type eventStats struct {
+	calls uint64
+	// Sum of the latencies in nanoseconds, including the latencies of the next applications.
+	latency uint64
+	buckets [len(latencyBuckets) + 1]uint64
+}
+
+func (r *eventStats) add(d time.Duration) {
+	atomic.AddUint64(&r.calls, 1)
+	atomic.AddUint64(&r.latency, uint64(d))
+	i := 0
+	for i < len(latencyBuckets) && d > latencyBuckets[i] {
+		i++
+	}
+	atomic.AddUint64(&r.buckets[i], 1)
+}
+
+// tracer logs the whole application chain for the sampled packets.
+type tracer struct {
+	rate float64 // Zero means the tracing is disabled.
+	dpid string  // Empty string means any device.
+	port uint32  // Zero means any port.
+
+	mutex  sync.Mutex
+	traced map[*protocol.Ethernet]bool
+}
+
+func newTracer(rate float64, dpid string, port uint32) *tracer {
+	return &tracer{
+		rate:   rate,
+		dpid:   dpid,
+		port:   port,
+		traced: make(map[*protocol.Ethernet]bool),
+	}
+}
+
+func (r *tracer) enabled() bool {
+	return r.rate > 0
+}
+
+func (r *tracer) sample(ingress *network.Port, eth *protocol.Ethernet) bool {
+	if len(r.dpid) > 0 && ingress.Device().ID() != r.dpid {
+		return false
+	}
+	if r.port != 0 && ingress.Number() != r.port {
+		return false
+	}
+	if rand.Float64() >= r.rate {
+		return false
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.traced[eth] = true
+
+	return true
+}
+
+func (r *tracer) isTraced(eth *protocol.Ethernet) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.traced[eth]
+}
+
+func (r *tracer) done(eth *protocol.Ethernet) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.traced, eth)
+}
+
+// instrument is a transparent wrapper of an application that counts the events
+// delivered to the application and measures how long the application takes to
+// process them. The application chain is not changed by this wrapper because
+// the chain is managed by the wrapped application itself.
+type instrument struct {
+	app.Processor
+	head   bool
+	tracer *tracer
+	stats  [numEventTypes]eventStats
+	// ID of the application in the flow cookies.
+	owner uint16
+	// Slice that the application is restricted to, or nil if it sees the whole network.
+	slice *network.Slice
+}
+
+func newInstrument(p app.Processor, t *tracer, s *network.Slice) *instrument {
+	return &instrument{
+		Processor: p,
+		tracer:    t,
+		owner:     network.CookieOwnerID(p.Name()),
+		slice:     s,
+	}
+}
+
+// view returns the finder that the application sees. The finder passed by the previous
+// application may be the view of its own slice.
+func (r *instrument) view(finder network.Finder) network.Finder {
+	if v, ok := finder.(*slicedFinder); ok {
+		finder = v.Finder
+	}
+	if r.slice == nil {
+		return finder
+	}
+
+	return &slicedFinder{Finder: finder, slice: r.slice}
+}
+
+func (r *instrument) hasPort(p *network.Port) bool {
+	return r.slice == nil || r.slice.HasPort(p.Device().ID(), p.Number())
+}
+
+func (r *instrument) hasDevice(d *network.Device) bool {
+	return r.slice == nil || r.slice.HasDevice(d.ID())
+}
+
+func (r *instrument) measure(t eventType, f func() error) error {
+	start := time.Now()
+	err := f()
+	r.stats[t].add(time.Since(start))
+
+	return err
+}
+
+func (r *instrument) OnPacketIn(finder network.Finder, ingress *network.Port, eth *protocol.Ethernet) error {
+	// Skip this application if the packet is outside its slice.
+	if r.slice != nil && !r.slice.Contains(ingress, eth.VLANID) {
+		next, ok := r.Next()
+		if !ok {
+			return nil
+		}
+		return next.OnPacketIn(finder, ingress, eth)
+	}
+	finder = r.view(finder)
+
+	if !r.tracer.enabled() {
+		return r.measure(evPacketIn, func() error { return r.Processor.OnPacketIn(finder, ingress, eth) })
+	}
+
+	traced := false
+	if r.head {
+		traced = r.tracer.sample(ingress, eth)
+		if traced {
+			defer r.tracer.done(eth)
+		}
+	} else {
+		traced = r.tracer.isTraced(eth)
+	}
+	if !traced {
+		return r.measure(evPacketIn, func() error { return r.Processor.OnPacketIn(finder, ingress, eth) })
+	}
+
+	logger.Infof("trace: PACKET_IN %p enters %v (ingress=%v, src=%v, dst=%v, type=0x%04x)", eth, r.Name(), ingress.ID(), eth.SrcMAC, eth.DstMAC, eth.Type)
+	start := time.Now()
+	err := r.measure(evPacketIn, func() error { return r.Processor.OnPacketIn(finder, ingress, eth) })
+	logger.Infof("trace: PACKET_IN %p leaves %v (elapsed=%v, err=%v)", eth, r.Name(), time.Since(start), err)
+
+	return err
+}
+
+func (r *instrument) OnPortUp(finder network.Finder, port *network.Port) error {
+	// Skip this application if the port is outside its slice.
+	if !r.hasPort(port) {
+		next, ok := r.Next()
+		if !ok {
+			return nil
+		}
+		return next.OnPortUp(finder, port)
+	}
+	finder = r.view(finder)
+
+	return r.measure(evPortUp, func() error { return r.Processor.OnPortUp(finder, port) })
+}
+
+func (r *instrument) OnPortDown(finder network.Finder, port *network.Port) error {
+	// Skip this application if the port is outside its slice.
+	if !r.hasPort(port) {
+		next, ok := r.Next()
+		if !ok {
+			return nil
+		}
+		return next.OnPortDown(finder, port)
+	}
+	finder = r.view(finder)
+
+	return r.measure(evPortDown, func() error { return r.Processor.OnPortDown(finder, port) })
+}
+
+func (r *instrument) OnPortsRenumbered(finder network.Finder, device *network.Device, mapping map[uint32]uint32, deleted []uint32) error {
+	// Skip this application if the device is outside its slice.
+	if !r.hasDevice(device) {
+		next, ok := r.Next()
+		if !ok {
+			return nil
+		}
+		return next.OnPortsRenumbered(finder, device, mapping, deleted)
+	}
+	finder = r.view(finder)
+
+	return r.measure(evPortsRenumbered, func() error { return r.Processor.OnPortsRenumbered(finder, device, mapping, deleted) })
+}
+
+func (r *instrument) OnDeviceUp(finder network.Finder, device *network.Device) error {
+	// Skip this application if the device is outside its slice.
+	if !r.hasDevice(device) {
+		next, ok := r.Next()
+		if !ok {
+			return nil
+		}
+		return next.OnDeviceUp(finder, device)
+	}
+	finder = r.view(finder)
+
+	return r.measure(evDeviceUp, func() error { return r.Processor.OnDeviceUp(finder, device) })
+}
+
+func (r *instrument) OnDeviceDown(finder network.Finder, device *network.Device) error {
+	// Skip this application if the device is outside its slice.
+	if !r.hasDevice(device) {
+		next, ok := r.Next()
+		if !ok {
+			return nil
+		}
+		return next.OnDeviceDown(finder, device)
+	}
+	finder = r.view(finder)
+
+	return r.measure(evDeviceDown, func() error { return r.Processor.OnDeviceDown(finder, device) })
+}
+
+func (r *instrument) OnFlowRemoved(finder network.Finder, flow openflow.FlowRemoved) error {
+	// Skip this application if the flow is owned by another application.
+	if owner, ok := network.CookieOwner(flow.Cookie()); ok && owner != r.owner {
+		next, ok := r.Next()
+		if !ok {
+			return nil
+		}
+		return next.OnFlowRemoved(finder, flow)
+	}
+	finder = r.view(finder)
+
+	return r.measure(evFlowRemoved, func() error { return r.Processor.OnFlowRemoved(finder, flow) })
+}
+
+func (r *instrument) OnTopologyChange(finder network.Finder) error {
+	finder = r.view(finder)
+
+	return r.measure(evTopologyChange, func() error { return r.Processor.OnTopologyChange(finder) })
+}
+
+func (r *instrument) OnLinkUp(finder network.Finder, ports [2]*network.Port) error {
+	// Skip this application if the link is outside its slice.
+	if !(r.hasPort(ports[0]) && r.hasPort(ports[1])) {
+		next, ok := r.Next()
+		if !ok {
+			return nil
+		}
+		return next.OnLinkUp(finder, ports)
+	}
+	finder = r.view(finder)
+
+	return r.measure(evLinkUp, func() error { return r.Processor.OnLinkUp(finder, ports) })
+}
+
+func (r *instrument) OnLinkDown(finder network.Finder, ports [2]*network.Port) error {
+	// Skip this application if the link is outside its slice.
+	if !(r.hasPort(ports[0]) && r.hasPort(ports[1])) {
+		next, ok := r.Next()
+		if !ok {
+			return nil
+		}
+		return next.OnLinkDown(finder, ports)
+	}
+	finder = r.view(finder)
+
+	return r.measure(evLinkDown, func() error { return r.Processor.OnLinkDown(finder, ports) })
+}
+
+func (r *instrument) OnPortStatsUpdated(finder network.Finder, device *network.Device) error {
+	// Skip this application if the device is outside its slice.
+	if !r.hasDevice(device) {
+		next, ok := r.Next()
+		if !ok {
+			return nil
+		}
+		return next.OnPortStatsUpdated(finder, device)
+	}
+	finder = r.view(finder)
+
+	return r.measure(evPortStatsUpdated, func() error { return r.Processor.OnPortStatsUpdated(finder, device) })
+}
+
+// appEventStats is the statistics of an event type delivered to an application. An event
+// that is delivered to an application but not to the next one is attributed as consumed
+// by the application.
+type appEventStats struct {
+	App      string `json:"app"`
+	Event    string `json:"event"`
+	Calls    uint64 `json:"calls"`
+	Consumed uint64 `json:"consumed"`
+	// Latency includes the latencies of the next applications, but SelfLatency does not.
+	Latency     time.Duration `json:"latency_ns"`
+	SelfLatency time.Duration `json:"self_latency_ns"`
+	// Number of the calls in each latency bucket. The last bucket has no upper bound.
+	Buckets []uint64 `json:"latency_buckets"`
+}
+
+// collectStats returns the statistics of the instrumented application chain in the order
+// of the chain. The event types that have never been delivered are omitted.
+func collectStats(chain []*instrument) []appEventStats {
+	var stats []appEventStats
+
+	for i, v := range chain {
+		for t := eventType(0); t < numEventTypes; t++ {
+			calls := atomic.LoadUint64(&v.stats[t].calls)
+			if calls == 0 {
+				continue
+			}
+			latency := atomic.LoadUint64(&v.stats[t].latency)
+
+			var nextCalls, nextLatency uint64
+			if i+1 < len(chain) {
+				nextCalls = atomic.LoadUint64(&chain[i+1].stats[t].calls)
+				nextLatency = atomic.LoadUint64(&chain[i+1].stats[t].latency)
+			}
+			var consumed, self uint64
+			if calls > nextCalls {
+				consumed = calls - nextCalls
+			}
+			if latency > nextLatency {
+				self = latency - nextLatency
+			}
+
+			buckets := make([]uint64, len(v.stats[t].buckets))
+			for j := range buckets {
+				buckets[j] = atomic.LoadUint64(&v.stats[t].buckets[j])
+			}
+			stats = append(stats, appEventStats{
+				App:         v.Name(),
+				Event:       eventNames[t],
+				Calls:       calls,
+				Consumed:    consumed,
+				Latency:     time.Duration(latency),
+				SelfLatency: time.Duration(self),
+				Buckets:     buckets,
+			})
+		}
+	}
+
+	return stats
+}
+
+// report writes the statistics of the instrumented application chain.
+func report(chain []*instrument) string {
+	var buf bytes.Buffer
+
+	stats := collectStats(chain)
+	for _, v := range chain {
+		buf.WriteString(fmt.Sprintf("%v:\n", v.Name()))
+		for _, s := range stats {
+			if s.App != v.Name() {
+				continue
+			}
+			buf.WriteString(fmt.Sprintf("\t%v: calls=%v, consumed=%v, avg_self_latency=%v, latency_histogram(%v)=%v\n",
+				s.Event, s.Calls, s.Consumed, s.SelfLatency/time.Duration(s.Calls), latencyBuckets, s.Buckets))
+		}
+	}
+
+	return buf.String()
+}
+
+var (
+	// Manager whose statistics are exported as the metrics. There is only one manager in a process.
+	exportedMutex sync.Mutex
+	exported      *Manager
+)
+
+func setExported(m *Manager) {
+	exportedMutex.Lock()
+	defer exportedMutex.Unlock()
+
+	exported = m
+}
+
+func exportedStats() []appEventStats {
+	exportedMutex.Lock()
+	m := exported
+	exportedMutex.Unlock()
+
+	if m == nil {
+		return nil
+	}
+	return m.stats()
+}
+
+func init() {
+	// The statistics are counted atomically on the event path and read only when the
+	// metrics are written.
+	counter := func(f func(s appEventStats) float64) func() []metrics.Sample {
+		return func() []metrics.Sample {
+			stats := exportedStats()
+			samples := make([]metrics.Sample, len(stats))
+			for i, s := range stats {
+				samples[i] = metrics.Sample{Values: []string{s.App, s.Event}, Value: f(s)}
+			}
+			return samples
+		}
+	}
+	metrics.NewCounterVecFunc("cherry_app_events_total", "Number of events delivered to the north-bound applications.",
+		counter(func(s appEventStats) float64 { return float64(s.Calls) }), "app", "event")
+	metrics.NewCounterVecFunc("cherry_app_events_consumed_total", "Number of events consumed by the north-bound applications, i.e., not delivered to the next application.",
+		counter(func(s appEventStats) float64 { return float64(s.Consumed) }), "app", "event")
+	metrics.NewCounterVecFunc("cherry_app_event_self_seconds_total", "Time spent by the north-bound applications themselves processing the events.",
+		counter(func(s appEventStats) float64 { return s.SelfLatency.Seconds() }), "app", "event")
+
+	bounds := make([]float64, len(latencyBuckets))
+	for i, v := range latencyBuckets {
+		bounds[i] = v.Seconds()
+	}
+	metrics.NewHistogramVecFunc("cherry_app_event_latency_seconds", "Latency of the north-bound applications processing the events, including the next applications.", bounds,
+		func() []metrics.HistogramSample {
+			stats := exportedStats()
+			samples := make([]metrics.HistogramSample, len(stats))
+			for i, s := range stats {
+				// Prometheus buckets are cumulative and the last one is +Inf.
+				cumulative := make([]uint64, len(bounds))
+				var count uint64
+				for j, n := range s.Buckets {
+					count += n
+					if j < len(cumulative) {
+						cumulative[j] = count
+					}
+				}
+				samples[i] = metrics.HistogramSample{
+					Values:  []string{s.App, s.Event},
+					Buckets: cumulative,
+					Count:   count,
+					Sum:     s.Latency.Seconds(),
+				}
+			}
+			return samples
+		}, "app", "event")
+}
@@ -0,0 +1,156 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package northbound
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/superkkt/cherry/metrics"
+	"github.com/superkkt/cherry/network"
+	"github.com/superkkt/cherry/northbound/app"
+)
+
+// testProcessor passes all the events to the next application unless consume is true.
+type testProcessor struct {
+	app.BaseProcessor
+	name    string
+	consume bool
+}
+
+func (r *testProcessor) Name() string {
+	return r.name
+}
+
+func (r *testProcessor) String() string {
+	return r.name
+}
+
+func (r *testProcessor) OnTopologyChange(finder network.Finder) error {
+	if r.consume {
+		return nil
+	}
+	return r.BaseProcessor.OnTopologyChange(finder)
+}
+
+// newTestManager returns a manager whose chain has an application that passes the
+// events followed by one that consumes them.
+func newTestManager() *Manager {
+	t := newTracer(0, "", 0)
+	first := newInstrument(&testProcessor{name: "First"}, t, nil)
+	first.head = true
+	second := newInstrument(&testProcessor{name: "Second", consume: true}, t, nil)
+	first.SetNext(second)
+
+	return &Manager{
+		head:   first,
+		tail:   second,
+		chain:  []*instrument{first, second},
+		tracer: t,
+	}
+}
+
+func TestCollectStats(t *testing.T) {
+	m := newTestManager()
+	for i := 0; i < 3; i++ {
+		if err := m.head.OnTopologyChange(nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats := m.stats()
+	if len(stats) != 2 {
+		t.Fatalf("expected the statistics of 2 applications, got %+v", stats)
+	}
+	for i, expected := range []struct {
+		app      string
+		consumed uint64
+	}{{"First", 0}, {"Second", 3}} {
+		s := stats[i]
+		if s.App != expected.app || s.Event != "TopologyChange" || s.Calls != 3 || s.Consumed != expected.consumed {
+			t.Fatalf("unexpected statistics: %+v", s)
+		}
+		var n uint64
+		for _, v := range s.Buckets {
+			n += v
+		}
+		if n != 3 {
+			t.Fatalf("unexpected latency buckets: %v", s.Buckets)
+		}
+	}
+}
+
+func TestStatsMetrics(t *testing.T) {
+	m := newTestManager()
+	setExported(m)
+	defer setExported(nil)
+	for i := 0; i < 3; i++ {
+		if err := m.head.OnTopologyChange(nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	metrics.WriteTo(&buf)
+	for _, expected := range []string{
+		`cherry_app_events_total{app="First",event="TopologyChange"} 3`,
+		`cherry_app_events_total{app="Second",event="TopologyChange"} 3`,
+		`cherry_app_events_consumed_total{app="First",event="TopologyChange"} 0`,
+		`cherry_app_events_consumed_total{app="Second",event="TopologyChange"} 3`,
+		`cherry_app_event_latency_seconds_bucket{app="First",event="TopologyChange",le="+Inf"} 3`,
+		`cherry_app_event_latency_seconds_count{app="Second",event="TopologyChange"} 3`,
+		`cherry_app_event_self_seconds_total{app="First",event="TopologyChange"} `,
+	} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Fatalf("missing %q in the metrics:\n%v", expected, buf.String())
+		}
+	}
+}
+
+func TestDebugHandler(t *testing.T) {
+	m := newTestManager()
+	if err := m.head.OnTopologyChange(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	m.DebugHandler().ServeHTTP(w, httptest.NewRequest("GET", "/debug/apps", nil))
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("unexpected content type: %v", ct)
+	}
+	var v struct {
+		Chain  []string        `json:"chain"`
+		Events []appEventStats `json:"events"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &v); err != nil {
+		t.Fatal(err)
+	}
+	if len(v.Chain) != 2 || v.Chain[0] != "First" || v.Chain[1] != "Second" {
+		t.Fatalf("unexpected chain: %v", v.Chain)
+	}
+	if len(v.Events) != 2 || v.Events[1].App != "Second" || v.Events[1].Consumed != 1 {
+		t.Fatalf("unexpected events: %+v", v.Events)
+	}
+}
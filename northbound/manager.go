@@ -23,21 +23,31 @@ package northbound
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"strings"
 	"sync"
 
 	"github.com/superkkt/cherry/database"
 	"github.com/superkkt/cherry/network"
 	"github.com/superkkt/cherry/northbound/app"
+	"github.com/superkkt/cherry/northbound/app/blacklist"
+	"github.com/superkkt/cherry/northbound/app/dhcp"
 	"github.com/superkkt/cherry/northbound/app/discovery"
+	"github.com/superkkt/cherry/northbound/app/firewall"
+	"github.com/superkkt/cherry/northbound/app/hosttracker"
+	"github.com/superkkt/cherry/northbound/app/intent"
 	"github.com/superkkt/cherry/northbound/app/l2switch"
 	"github.com/superkkt/cherry/northbound/app/monitor"
 	"github.com/superkkt/cherry/northbound/app/proxyarp"
+	"github.com/superkkt/cherry/northbound/app/qos"
+	"github.com/superkkt/cherry/northbound/app/router"
 	"github.com/superkkt/cherry/northbound/app/virtualip"
 
 	"github.com/pkg/errors"
 	"github.com/superkkt/go-logging"
+	"github.com/superkkt/viper"
 )
 
 var (
@@ -56,27 +66,65 @@ type application struct {
 type Manager struct {
 	mutex      sync.Mutex
 	apps       map[string]*application // Registered applications
-	head, tail app.Processor
+	head, tail *instrument
+	chain      []*instrument // Enabled applications in the order they receive events
+	tracer     *tracer
 	db         *database.MySQL
+	// Slices that the applications are restricted to. Key is the upper-cased application name.
+	slices map[string]*network.Slice
 }
 
 func NewManager(db *database.MySQL) (*Manager, error) {
+	slices, err := network.LoadSlices()
+	if err != nil {
+		return nil, errors.Wrap(err, "loading slices")
+	}
+
 	v := &Manager{
 		apps: make(map[string]*application),
-		db:   db,
+		tracer: newTracer(
+			viper.GetFloat64("default.trace.sample_rate"),
+			viper.GetString("default.trace.dpid"),
+			uint32(viper.GetInt("default.trace.port")),
+		),
+		db:     db,
+		slices: make(map[string]*network.Slice),
+	}
+	for _, s := range slices {
+		for _, name := range s.Apps {
+			v.slices[strings.ToUpper(name)] = s
+		}
 	}
 	// Registering north-bound applications
+	tracker := hosttracker.New()
 	v.register(discovery.New(db))
+	v.register(tracker)
 	v.register(l2switch.New(db))
-	v.register(proxyarp.New(db))
+	v.register(proxyarp.New(db, tracker))
+	v.register(dhcp.New(tracker))
 	v.register(monitor.New())
 	v.register(virtualip.New(db))
+	v.register(firewall.New(db))
+	v.register(router.New(tracker))
+	v.register(qos.New())
+	v.register(intent.New(db))
+	v.register(blacklist.New(db, tracker))
+	// Export the event statistics of the applications as the metrics.
+	setExported(v)
 
 	return v, nil
 }
 
 func (r *Manager) register(app app.Processor) {
-	r.apps[strings.ToUpper(app.Name())] = &application{
+	name := strings.ToUpper(app.Name())
+	if _, ok := r.apps[name]; ok {
+		panic(fmt.Sprintf("duplicated application name: %v", app.Name()))
+	}
+	// The FLOW_REMOVED messages are delivered to the owner of the flow by this ID.
+	if err := network.RegisterCookieOwner(app.Name()); err != nil {
+		panic(err.Error())
+	}
+	r.apps[name] = &application{
 		instance: app,
 		enabled:  false,
 	}
@@ -109,6 +157,10 @@ func (r *Manager) Enable(appName string) error {
 	if !ok {
 		return fmt.Errorf("unknown application: %v", appName)
 	}
+	// Enabling an application twice makes a loop in the processing chain.
+	if v.enabled {
+		return fmt.Errorf("already enabled application: %v", appName)
+	}
 	app := v.instance
 
 	if err := app.Init(); err != nil {
@@ -118,15 +170,24 @@ func (r *Manager) Enable(appName string) error {
 		return errors.Wrap(err, "checking dependencies")
 	}
 	v.enabled = true
+	// The saved flows of this application are not stale any more.
+	network.LoadCookieOwner(app.Name())
 	logger.Debugf("enabled %v application", appName)
 
+	slice := r.slices[strings.ToUpper(appName)]
+	if slice != nil {
+		logger.Infof("%v application is restricted to slice %v", appName, slice.Name)
+	}
+	wrapper := newInstrument(app, r.tracer, slice)
+	r.chain = append(r.chain, wrapper)
 	if r.head == nil {
-		r.head = app
-		r.tail = app
+		wrapper.head = true
+		r.head = wrapper
+		r.tail = wrapper
 		return nil
 	}
-	r.tail.SetNext(app)
-	r.tail = app
+	r.tail.SetNext(wrapper)
+	r.tail = wrapper
 
 	return nil
 }
@@ -146,15 +207,62 @@ func (r *Manager) String() string {
 	defer r.mutex.Unlock()
 
 	var buf bytes.Buffer
-	app := r.head
-	for app != nil {
-		buf.WriteString(fmt.Sprintf("%v\n", app))
-		next, ok := app.Next()
+	if r.head == nil {
+		return buf.String()
+	}
+
+	var p app.Processor = r.head
+	for p != nil {
+		buf.WriteString(fmt.Sprintf("%v\n", p))
+		next, ok := p.Next()
 		if !ok {
 			break
 		}
-		app = next
+		p = next
 	}
+	buf.WriteString(fmt.Sprintf("\nEvent statistics:\n%v", report(r.chain)))
 
 	return buf.String()
 }
+
+// stats returns the event statistics of the enabled applications.
+func (r *Manager) stats() []appEventStats {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return collectStats(r.chain)
+}
+
+// DebugHandler returns a HTTP handler that serves the application chain, the trace
+// configuration, and the event statistics of the applications in JSON.
+func (r *Manager) DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.mutex.Lock()
+		chain := make([]string, len(r.chain))
+		for i, v := range r.chain {
+			chain[i] = v.Name()
+		}
+		stats := collectStats(r.chain)
+		r.mutex.Unlock()
+
+		v := struct {
+			Chain []string `json:"chain"`
+			Trace struct {
+				SampleRate float64 `json:"sample_rate"`
+				DPID       string  `json:"dpid,omitempty"`
+				Port       uint32  `json:"port,omitempty"`
+			} `json:"trace"`
+			Events []appEventStats `json:"events"`
+		}{Chain: chain, Events: stats}
+		if r.tracer != nil {
+			v.Trace.SampleRate = r.tracer.rate
+			v.Trace.DPID = r.tracer.dpid
+			v.Trace.Port = r.tracer.port
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(v); err != nil {
+			logger.Errorf("failed to write the application statistics: %v", err)
+		}
+	})
+}
@@ -0,0 +1,130 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package northbound
+
+import (
+	"net"
+
+	"github.com/superkkt/cherry/network"
+)
+
+// slicedFinder is a view of the network that only shows the devices, links and paths in
+// a slice to the applications assigned to the slice. The applications compute their flows
+// from this view, so they cannot direct traffic to the ports outside the slice.
+type slicedFinder struct {
+	network.Finder
+	slice *network.Slice
+}
+
+func (r *slicedFinder) Device(id string) *network.Device {
+	if !r.slice.HasDevice(id) {
+		return nil
+	}
+
+	return r.Finder.Device(id)
+}
+
+func (r *slicedFinder) Devices() []*network.Device {
+	result := []*network.Device{}
+	for _, d := range r.Finder.Devices() {
+		if r.slice.HasDevice(d.ID()) {
+			result = append(result, d)
+		}
+	}
+
+	return result
+}
+
+// Slice implements app.SlicedFinder.
+func (r *slicedFinder) Slice() *network.Slice {
+	return r.slice
+}
+
+// HasPort implements app.SlicedFinder.
+func (r *slicedFinder) HasPort(p *network.Port) bool {
+	return r.slice.HasPort(p.Device().ID(), p.Number())
+}
+
+// Node returns LocationUnregistered if the node is connected to a port outside the slice.
+func (r *slicedFinder) Node(mac net.HardwareAddr) (*network.Node, network.LocationStatus, error) {
+	node, status, err := r.Finder.Node(mac)
+	if err != nil || status != network.LocationDiscovered {
+		return node, status, err
+	}
+	if !r.HasPort(node.Port()) {
+		return nil, network.LocationUnregistered, nil
+	}
+
+	return node, status, nil
+}
+
+// Path returns nil if the path between two devices leaves the slice.
+func (r *slicedFinder) Path(srcDeviceID, dstDeviceID string) [][2]*network.Port {
+	path := r.Finder.Path(srcDeviceID, dstDeviceID)
+	for _, hop := range path {
+		if !r.HasPort(hop[0]) || !r.HasPort(hop[1]) {
+			return nil
+		}
+	}
+
+	return path
+}
+
+// NextHops only returns the hops whose links are in the slice.
+func (r *slicedFinder) NextHops(srcDeviceID, dstDeviceID string) [][2]*network.Port {
+	result := [][2]*network.Port{}
+	for _, hop := range r.Finder.NextHops(srcDeviceID, dstDeviceID) {
+		if r.HasPort(hop[0]) && r.HasPort(hop[1]) {
+			result = append(result, hop)
+		}
+	}
+
+	return result
+}
+
+// Aggregation only returns the members in the slice.
+func (r *slicedFinder) Aggregation(p *network.Port) []*network.Port {
+	members := r.Finder.Aggregation(p)
+	if members == nil {
+		return nil
+	}
+
+	result := []*network.Port{}
+	for _, m := range members {
+		if r.HasPort(m) {
+			result = append(result, m)
+		}
+	}
+
+	return result
+}
+
+func (r *slicedFinder) Links() [][2]*network.Port {
+	result := [][2]*network.Port{}
+	for _, link := range r.Finder.Links() {
+		if r.HasPort(link[0]) && r.HasPort(link[1]) {
+			result = append(result, link)
+		}
+	}
+
+	return result
+}
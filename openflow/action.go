@@ -29,20 +29,90 @@ import (
 )
 
 type Action interface {
+	// AddExperimenter appends a vendor-specific action that will be applied before the output
+	AddExperimenter(action ExperimenterAction)
 	DstMAC() (ok bool, mac net.HardwareAddr)
 	encoding.BinaryMarshaler
 	encoding.BinaryUnmarshaler
 	Queue() (ok bool, queue uint32)
 	// Error() returns last error message
 	Error() error
+	Experimenters() []ExperimenterAction
+	// Group returns the ID of the group that will process the packet
+	Group() (ok bool, id uint32)
+	// DstIP returns the IPv4 or IPv6 destination address that will be written to the packet
+	DstIP() (ok bool, ip net.IP)
+	// DstPort returns the transport protocol and its destination port number that will be written to the packet
+	DstPort() (ok bool, protocol uint8, port uint16)
+	// IPDSCP returns the DSCP value that will be written to the IP ToS field
+	IPDSCP() (ok bool, dscp uint8)
+	// MPLSLabel returns the label that will be written to the outermost MPLS shim header
+	MPLSLabel() (ok bool, label uint32)
+	// MPLSTC returns the traffic class that will be written to the outermost MPLS shim header
+	MPLSTC() (ok bool, tc uint8)
 	OutPort() OutPort
+	// PopMPLS returns the Ethernet type of the payload after the outermost MPLS shim header is removed
+	PopMPLS() (ok bool, ethType uint16)
+	// PopVLAN returns whether the outermost VLAN tag is removed before the other actions
+	PopVLAN() bool
+	// PushMPLS returns the Ethernet type of the MPLS shim header pushed after the pops
+	PushMPLS() (ok bool, ethType uint16)
+	// PushVLAN returns the Ethernet type of the VLAN tag pushed after PushMPLS
+	PushVLAN() (ok bool, ethType uint16)
+	// SetDstIP rewrites the destination address of the IPv4 or IPv6 packet
+	SetDstIP(ip net.IP)
 	SetDstMAC(mac net.HardwareAddr)
+	// SetDstPort rewrites the destination port number of the transport protocol (TCP, UDP or SCTP)
+	SetDstPort(protocol uint8, port uint16)
+	// SetGroup applies the group whose ID is id to the packet
+	SetGroup(id uint32)
+	// SetIPDSCP remarks the 6-bit DSCP value of the IP ToS field
+	SetIPDSCP(dscp uint8)
+	// SetMPLSLabel rewrites the 20-bit label of the outermost MPLS shim header
+	SetMPLSLabel(label uint32)
+	// SetMPLSTC rewrites the 3-bit traffic class of the outermost MPLS shim header
+	SetMPLSTC(tc uint8)
+	// SetQueue enqueues the packet into the egress queue whose ID is queue on the output port
 	SetQueue(queue uint32)
 	SetOutPort(port OutPort)
+	// SetPopMPLS removes the outermost MPLS shim header of the packet, whose payload has the Ethernet type ethType
+	SetPopMPLS(ethType uint16)
+	// SetPopVLAN removes the outermost VLAN tag of the packet
+	SetPopVLAN()
+	// SetPushMPLS pushes a new MPLS shim header whose Ethernet type is 0x8847 (unicast) or 0x8848 (multicast)
+	SetPushMPLS(ethType uint16)
+	// SetPushVLAN pushes a new VLAN tag whose Ethernet type is 0x8100 (802.1Q) or 0x88A8 (802.1ad)
+	SetPushVLAN(ethType uint16)
+	// SetSrcIP rewrites the source address of the IPv4 or IPv6 packet
+	SetSrcIP(ip net.IP)
 	SetSrcMAC(mac net.HardwareAddr)
+	// SetSrcPort rewrites the source port number of the transport protocol (TCP, UDP or SCTP)
+	SetSrcPort(protocol uint8, port uint16)
 	SetVLANID(vid uint16)
+	// SetVLANPriority rewrites the 3-bit priority code point of the outermost VLAN tag
+	SetVLANPriority(p uint8)
+	SrcIP() (ok bool, ip net.IP)
 	SrcMAC() (ok bool, mac net.HardwareAddr)
+	SrcPort() (ok bool, protocol uint8, port uint16)
+	// SetTunnelID sets the tunnel ID, e.g., the VNI of VXLAN or the key of GRE, of the packet
+	// that will be encapsulated by the tunnel port
+	SetTunnelID(id uint64)
+	// TunnelID returns the tunnel ID that will be written to the packet
+	TunnelID() (ok bool, id uint64)
 	VLANID() (ok bool, vid uint16)
+	VLANPriority() (ok bool, priority uint8)
+}
+
+// Transport protocols whose port numbers can be rewritten
+const (
+	ProtocolTCP  = 6
+	ProtocolUDP  = 17
+	ProtocolSCTP = 132
+)
+
+type transportPort struct {
+	protocol uint8
+	port     uint16
 }
 
 type BaseAction struct {
@@ -52,12 +122,43 @@ type BaseAction struct {
 	dstMAC *net.HardwareAddr
 	queue  int64
 	vlanID int32
+	dscp   int16
+	group  int64
+
+	// Header fields rewritten by the set-field actions
+	vlanPCP   int16
+	mplsLabel int64
+	mplsTC    int16
+	srcIP     net.IP
+	dstIP     net.IP
+	srcPort   *transportPort
+	dstPort   *transportPort
+	tunnelID  *uint64
+	// Pop the outermost VLAN tag
+	popVLAN bool
+	// Ethernet type of the VLAN tag to push
+	pushVLAN int32
+	// Ethernet type of the payload of the MPLS shim header to pop
+	popMPLS int32
+	// Ethernet type of the MPLS shim header to push
+	pushMPLS int32
+	// Vendor-specific actions
+	experimenters []ExperimenterAction
 }
 
 func NewBaseAction() *BaseAction {
 	return &BaseAction{
-		queue:  -1,
-		vlanID: -1,
+		queue:    -1,
+		vlanID:   -1,
+		dscp:     -1,
+		group:    -1,
+		pushVLAN: -1,
+
+		vlanPCP:   -1,
+		mplsLabel: -1,
+		mplsTC:    -1,
+		popMPLS:   -1,
+		pushMPLS:  -1,
 	}
 }
 
@@ -73,6 +174,199 @@ func (r *BaseAction) SetVLANID(vid uint16) {
 	r.vlanID = int32(vid)
 }
 
+func (r *BaseAction) VLANPriority() (ok bool, priority uint8) {
+	if r.vlanPCP == -1 {
+		return false, 0
+	}
+
+	return true, uint8(r.vlanPCP)
+}
+
+func (r *BaseAction) SetVLANPriority(p uint8) {
+	// PCP is a 3-bit field
+	if p > 0x7 {
+		r.err = errors.New("SetVLANPriority: invalid VLAN priority")
+		return
+	}
+
+	r.vlanPCP = int16(p)
+}
+
+func (r *BaseAction) MPLSLabel() (ok bool, label uint32) {
+	if r.mplsLabel == -1 {
+		return false, 0
+	}
+
+	return true, uint32(r.mplsLabel)
+}
+
+func (r *BaseAction) SetMPLSLabel(label uint32) {
+	// Label is a 20-bit field
+	if label > 0xFFFFF {
+		r.err = errors.New("SetMPLSLabel: invalid MPLS label")
+		return
+	}
+
+	r.mplsLabel = int64(label)
+}
+
+func (r *BaseAction) MPLSTC() (ok bool, tc uint8) {
+	if r.mplsTC == -1 {
+		return false, 0
+	}
+
+	return true, uint8(r.mplsTC)
+}
+
+func (r *BaseAction) SetMPLSTC(tc uint8) {
+	// TC is a 3-bit field
+	if tc > 0x7 {
+		r.err = errors.New("SetMPLSTC: invalid MPLS traffic class")
+		return
+	}
+
+	r.mplsTC = int16(tc)
+}
+
+// normalizeIP returns the 4-byte form of an IPv4 address, or the 16-byte form of an IPv6
+// address. It returns nil if ip is invalid.
+func normalizeIP(ip net.IP) net.IP {
+	if v := ip.To4(); v != nil {
+		return v
+	}
+
+	return ip.To16()
+}
+
+func (r *BaseAction) SrcIP() (ok bool, ip net.IP) {
+	if r.srcIP == nil {
+		return false, nil
+	}
+
+	return true, r.srcIP
+}
+
+func (r *BaseAction) SetSrcIP(ip net.IP) {
+	v := normalizeIP(ip)
+	if v == nil {
+		r.err = errors.Wrap(ErrInvalidIPAddress, "SetSrcIP")
+		return
+	}
+
+	r.srcIP = v
+}
+
+func (r *BaseAction) DstIP() (ok bool, ip net.IP) {
+	if r.dstIP == nil {
+		return false, nil
+	}
+
+	return true, r.dstIP
+}
+
+func (r *BaseAction) SetDstIP(ip net.IP) {
+	v := normalizeIP(ip)
+	if v == nil {
+		r.err = errors.Wrap(ErrInvalidIPAddress, "SetDstIP")
+		return
+	}
+
+	r.dstIP = v
+}
+
+func isTransportProtocol(protocol uint8) bool {
+	return protocol == ProtocolTCP || protocol == ProtocolUDP || protocol == ProtocolSCTP
+}
+
+func (r *BaseAction) SrcPort() (ok bool, protocol uint8, port uint16) {
+	if r.srcPort == nil {
+		return false, 0, 0
+	}
+
+	return true, r.srcPort.protocol, r.srcPort.port
+}
+
+func (r *BaseAction) SetSrcPort(protocol uint8, port uint16) {
+	if !isTransportProtocol(protocol) {
+		r.err = errors.Wrap(ErrUnsupportedIPProtocol, "SetSrcPort")
+		return
+	}
+
+	r.srcPort = &transportPort{protocol: protocol, port: port}
+}
+
+func (r *BaseAction) DstPort() (ok bool, protocol uint8, port uint16) {
+	if r.dstPort == nil {
+		return false, 0, 0
+	}
+
+	return true, r.dstPort.protocol, r.dstPort.port
+}
+
+func (r *BaseAction) SetDstPort(protocol uint8, port uint16) {
+	if !isTransportProtocol(protocol) {
+		r.err = errors.Wrap(ErrUnsupportedIPProtocol, "SetDstPort")
+		return
+	}
+
+	r.dstPort = &transportPort{protocol: protocol, port: port}
+}
+
+func (r *BaseAction) TunnelID() (ok bool, id uint64) {
+	if r.tunnelID == nil {
+		return false, 0
+	}
+
+	return true, *r.tunnelID
+}
+
+func (r *BaseAction) SetTunnelID(id uint64) {
+	r.tunnelID = &id
+}
+
+func (r *BaseAction) IPDSCP() (ok bool, dscp uint8) {
+	if r.dscp == -1 {
+		return false, 0
+	}
+
+	return true, uint8(r.dscp)
+}
+
+func (r *BaseAction) SetIPDSCP(dscp uint8) {
+	// DSCP is a 6-bit field
+	if dscp > 0x3F {
+		r.err = errors.Wrap(ErrInvalidDSCP, "SetIPDSCP")
+		return
+	}
+
+	r.dscp = int16(dscp)
+}
+
+func (r *BaseAction) Group() (ok bool, id uint32) {
+	if r.group == -1 {
+		return false, 0
+	}
+
+	return true, uint32(r.group)
+}
+
+func (r *BaseAction) SetGroup(id uint32) {
+	r.group = int64(id)
+}
+
+func (r *BaseAction) AddExperimenter(action ExperimenterAction) {
+	if action == nil {
+		r.err = errors.New("AddExperimenter: nil action")
+		return
+	}
+
+	r.experimenters = append(r.experimenters, action)
+}
+
+func (r *BaseAction) Experimenters() []ExperimenterAction {
+	return r.experimenters
+}
+
 func (r *BaseAction) Queue() (ok bool, queue uint32) {
 	if r.queue == -1 {
 		return false, 0
@@ -93,6 +387,60 @@ func (r *BaseAction) OutPort() OutPort {
 	return r.output
 }
 
+func (r *BaseAction) PopVLAN() bool {
+	return r.popVLAN
+}
+
+func (r *BaseAction) SetPopVLAN() {
+	r.popVLAN = true
+}
+
+func (r *BaseAction) PushVLAN() (ok bool, ethType uint16) {
+	if r.pushVLAN == -1 {
+		return false, 0
+	}
+
+	return true, uint16(r.pushVLAN)
+}
+
+func (r *BaseAction) SetPushVLAN(ethType uint16) {
+	if ethType != 0x8100 && ethType != 0x88A8 {
+		r.err = errors.New("SetPushVLAN: invalid Ethernet type of the VLAN tag")
+		return
+	}
+
+	r.pushVLAN = int32(ethType)
+}
+
+func (r *BaseAction) PopMPLS() (ok bool, ethType uint16) {
+	if r.popMPLS == -1 {
+		return false, 0
+	}
+
+	return true, uint16(r.popMPLS)
+}
+
+func (r *BaseAction) SetPopMPLS(ethType uint16) {
+	r.popMPLS = int32(ethType)
+}
+
+func (r *BaseAction) PushMPLS() (ok bool, ethType uint16) {
+	if r.pushMPLS == -1 {
+		return false, 0
+	}
+
+	return true, uint16(r.pushMPLS)
+}
+
+func (r *BaseAction) SetPushMPLS(ethType uint16) {
+	if ethType != 0x8847 && ethType != 0x8848 {
+		r.err = errors.New("SetPushMPLS: invalid Ethernet type of the MPLS shim header")
+		return
+	}
+
+	r.pushMPLS = int32(ethType)
+}
+
 func (r *BaseAction) SetSrcMAC(mac net.HardwareAddr) {
 	if mac == nil || len(mac) < 6 {
 		r.err = errors.Wrap(ErrInvalidMACAddress, "SetSrcMAC")
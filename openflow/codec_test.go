@@ -0,0 +1,429 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package openflow_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/superkkt/cherry/openflow"
+	"github.com/superkkt/cherry/openflow/of10"
+	"github.com/superkkt/cherry/openflow/of13"
+	"github.com/superkkt/cherry/openflow/of14"
+)
+
+// Run "go test -update" to regenerate the golden files after adding new fixtures.
+var update = flag.Bool("update", false, "update the golden files")
+
+// A fixture is a file named <message type>.<description>.hex under testdata/<version>.
+// It contains the raw message bytes in hex, and whitespaces and comments that start
+// with '#' are ignored. The golden file, which has the same name except the .golden
+// extension, contains the decoded message rendered by describe().
+var fixtureVersions = map[string]openflow.Factory{
+	"of10": of10.NewFactory(),
+	"of13": of13.NewFactory(),
+	"of14": of14.NewFactory(),
+}
+
+// decoders returns an empty message for each message type in the fixture file names.
+var decoders = map[string]func(f openflow.Factory) (encoding.BinaryUnmarshaler, error){
+	"hello":             func(f openflow.Factory) (encoding.BinaryUnmarshaler, error) { return f.NewHello() },
+	"error":             func(f openflow.Factory) (encoding.BinaryUnmarshaler, error) { return f.NewError() },
+	"echo_request":      func(f openflow.Factory) (encoding.BinaryUnmarshaler, error) { return f.NewEchoRequest() },
+	"echo_reply":        func(f openflow.Factory) (encoding.BinaryUnmarshaler, error) { return f.NewEchoReply() },
+	"features_reply":    func(f openflow.Factory) (encoding.BinaryUnmarshaler, error) { return f.NewFeaturesReply() },
+	"get_config_reply":  func(f openflow.Factory) (encoding.BinaryUnmarshaler, error) { return f.NewGetConfigReply() },
+	"desc_reply":        func(f openflow.Factory) (encoding.BinaryUnmarshaler, error) { return f.NewDescReply() },
+	"port_desc_reply":   func(f openflow.Factory) (encoding.BinaryUnmarshaler, error) { return f.NewPortDescReply() },
+	"port_status":       func(f openflow.Factory) (encoding.BinaryUnmarshaler, error) { return f.NewPortStatus() },
+	"flow_removed":      func(f openflow.Factory) (encoding.BinaryUnmarshaler, error) { return f.NewFlowRemoved() },
+	"flow_mod":          func(f openflow.Factory) (encoding.BinaryUnmarshaler, error) { return f.NewFlowMod(openflow.FlowAdd) },
+	"group_mod":         func(f openflow.Factory) (encoding.BinaryUnmarshaler, error) { return f.NewGroupMod(openflow.GroupAdd) },
+	"flow_stats_reply":  func(f openflow.Factory) (encoding.BinaryUnmarshaler, error) { return f.NewFlowStatsReply() },
+	"port_stats_reply":  func(f openflow.Factory) (encoding.BinaryUnmarshaler, error) { return f.NewPortStatsReply() },
+	"queue_stats_reply": func(f openflow.Factory) (encoding.BinaryUnmarshaler, error) { return f.NewQueueStatsReply() },
+	"table_features_reply": func(f openflow.Factory) (encoding.BinaryUnmarshaler, error) {
+		return f.NewTableFeaturesReply()
+	},
+	"meter_mod":     func(f openflow.Factory) (encoding.BinaryUnmarshaler, error) { return f.NewMeterMod(openflow.MeterAdd) },
+	"packet_in":     func(f openflow.Factory) (encoding.BinaryUnmarshaler, error) { return f.NewPacketIn() },
+	"role_reply":    func(f openflow.Factory) (encoding.BinaryUnmarshaler, error) { return f.NewRoleReply() },
+	"barrier_reply": func(f openflow.Factory) (encoding.BinaryUnmarshaler, error) { return f.NewBarrierReply() },
+}
+
+func TestCodecFixtures(t *testing.T) {
+	for version, factory := range fixtureVersions {
+		files, err := filepath.Glob(filepath.Join("testdata", version, "*.hex"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(files) == 0 {
+			t.Fatalf("no fixture for %v", version)
+		}
+		for _, file := range files {
+			testFixture(t, factory, file)
+		}
+	}
+}
+
+func testFixture(t *testing.T, factory openflow.Factory, file string) {
+	data, err := readHex(file)
+	if err != nil {
+		t.Fatalf("%v: %v", file, err)
+	}
+
+	msgType := strings.SplitN(filepath.Base(file), ".", 2)[0]
+	decoder, ok := decoders[msgType]
+	if !ok {
+		t.Fatalf("%v: unknown message type: %v", file, msgType)
+	}
+	msg, err := decoder(factory)
+	if err != nil {
+		t.Fatalf("%v: %v", file, err)
+	}
+	if err := msg.UnmarshalBinary(data); err != nil {
+		t.Fatalf("%v: failed to decode: %v", file, err)
+	}
+
+	// Round trip for the messages that can be encoded as well.
+	if m, ok := msg.(encoding.BinaryMarshaler); ok {
+		encoded, err := m.MarshalBinary()
+		if err != nil {
+			t.Fatalf("%v: failed to encode: %v", file, err)
+		}
+		if !bytes.Equal(data, encoded) {
+			t.Errorf("%v: round trip mismatch:\n%v", file, hexDiff(data, encoded))
+		}
+	}
+
+	golden := strings.TrimSuffix(file, ".hex") + ".golden"
+	desc := describe(msg)
+	if *update {
+		if err := ioutil.WriteFile(golden, []byte(desc), 0644); err != nil {
+			t.Fatalf("%v: %v", golden, err)
+		}
+		return
+	}
+	expected, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("%v: %v", golden, err)
+	}
+	if string(expected) != desc {
+		t.Errorf("%v: unexpected decoded message:\nexpected:\n%v\ngot:\n%v", file, string(expected), desc)
+	}
+}
+
+func readHex(file string) ([]byte, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var buf bytes.Buffer
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		buf.WriteString(strings.Join(strings.Fields(line), ""))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return hex.DecodeString(buf.String())
+}
+
+// hexDiff renders the 16-byte rows of expected and got that are different.
+func hexDiff(expected, got []byte) string {
+	var buf bytes.Buffer
+	buf.WriteString(fmt.Sprintf("length: expected=%v, got=%v\n", len(expected), len(got)))
+	max := len(expected)
+	if len(got) > max {
+		max = len(got)
+	}
+	for offset := 0; offset < max; offset += 16 {
+		e := row(expected, offset)
+		g := row(got, offset)
+		if bytes.Equal(e, g) {
+			continue
+		}
+		buf.WriteString(fmt.Sprintf("%04x: -% x\n", offset, e))
+		buf.WriteString(fmt.Sprintf("%04x: +% x\n", offset, g))
+	}
+
+	return buf.String()
+}
+
+func row(data []byte, offset int) []byte {
+	if offset >= len(data) {
+		return nil
+	}
+	end := offset + 16
+	if end > len(data) {
+		end = len(data)
+	}
+
+	return data[offset:end]
+}
+
+// describe renders msg using the accessors of the abstract interfaces so that the
+// rendered text is stable regardless of the internal representation of msg.
+func describe(msg interface{}) string {
+	var buf bytes.Buffer
+	w := func(format string, args ...interface{}) {
+		buf.WriteString(fmt.Sprintf(format, args...))
+		buf.WriteString("\n")
+	}
+
+	if v, ok := msg.(openflow.Header); ok {
+		w("version: %v", v.Version())
+		w("type: %v", v.Type())
+		w("xid: %v", v.TransactionID())
+	}
+
+	switch v := msg.(type) {
+	case openflow.Hello:
+		if versions := v.Versions(); versions != nil {
+			w("versions: %v", versions)
+		}
+	case openflow.Error:
+		w("class: %v", v.Class())
+		w("code: %v", v.Code())
+		w("data: %x", v.Data())
+	case openflow.Echo:
+		w("data: %x", v.Data())
+	case openflow.FeaturesReply:
+		w("dpid: %v", v.DPID())
+		w("num_buffers: %v", v.NumBuffers())
+		w("num_tables: %v", v.NumTables())
+		w("aux_id: %v", v.AuxID())
+		w("capabilities: %#x", v.Capabilities())
+		w("actions: %#x", v.Actions())
+		describePorts(w, v.Ports())
+	case openflow.GetConfigReply:
+		w("flags: %v", v.Flags())
+		w("miss_send_length: %v", v.MissSendLength())
+	case openflow.DescReply:
+		w("manufacturer: %q", v.Manufacturer())
+		w("hardware: %q", v.Hardware())
+		w("software: %q", v.Software())
+		w("serial: %q", v.Serial())
+		w("description: %q", v.Description())
+	case openflow.PortDescReply:
+		describePorts(w, v.Ports())
+	case openflow.PortStatus:
+		w("reason: %v", v.Reason())
+		describePorts(w, []openflow.Port{v.Port()})
+	case openflow.FlowRemoved:
+		w("cookie: %#x", v.Cookie())
+		w("priority: %v", v.Priority())
+		w("reason: %v", v.Reason())
+		w("table_id: %v", v.TableID())
+		w("duration: %v.%09v", v.DurationSec(), v.DurationNanoSec())
+		w("idle_timeout: %v", v.IdleTimeout())
+		w("hard_timeout: %v", v.HardTimeout())
+		w("packet_count: %v", v.PacketCount())
+		w("byte_count: %v", v.ByteCount())
+		describeMatch(w, v.Match())
+	case openflow.FlowStatsReply:
+		for _, s := range v.FlowStats() {
+			w("flow: table_id=%v, priority=%v, cookie=%#x, duration=%v.%09v, idle_timeout=%v, hard_timeout=%v, packet_count=%v, byte_count=%v",
+				s.TableID, s.Priority, s.Cookie, s.DurationSec, s.DurationNanoSec, s.IdleTimeout, s.HardTimeout, s.PacketCount, s.ByteCount)
+			describeMatch(w, s.Match)
+		}
+	case openflow.PortStatsReply:
+		for _, s := range v.PortStats() {
+			w("port_stats: %+v", s)
+		}
+	case openflow.QueueStatsReply:
+		for _, s := range v.QueueStats() {
+			w("queue_stats: %+v", s)
+		}
+	case openflow.TableFeaturesReply:
+		for _, t := range v.Tables() {
+			describeTableFeatures(w, t)
+		}
+	case openflow.FlowMod:
+		w("command: %v", v.Command())
+		w("table_id: %v", v.TableID())
+		w("cookie: %#x", v.Cookie())
+		w("cookie_mask: %#x", v.CookieMask())
+		w("priority: %v", v.Priority())
+		w("idle_timeout: %v", v.IdleTimeout())
+		w("hard_timeout: %v", v.HardTimeout())
+		w("buffer_id: %#x", v.BufferID())
+		port := v.OutPort()
+		w("out_port: none=%v, value=%v", port.IsNone(), port.Value())
+		describeMatch(w, v.FlowMatch())
+		if inst := v.FlowInstruction(); inst != nil {
+			for _, a := range inst.Actions() {
+				describeAction(w, a)
+			}
+		}
+	case openflow.GroupMod:
+		w("command: %v", v.Command())
+		w("group_type: %v", v.GroupType())
+		w("group_id: %v", v.GroupID())
+		for _, b := range v.Buckets() {
+			w("bucket: weight=%v, watch_port=%#x, watch_group=%#x", b.Weight, b.WatchPort, b.WatchGroup)
+			describeAction(w, b.Action)
+		}
+	case openflow.MeterMod:
+		w("command: %v", v.Command())
+		w("meter_id: %v", v.MeterID())
+		w("packet_rate: %v", v.PacketRate())
+		w("burst: %v", v.Burst())
+		for _, b := range v.Bands() {
+			w("band: %+v", b)
+		}
+	case openflow.RoleReply:
+		w("role: %v", v.Role())
+		w("generation_id: %v", v.GenerationID())
+	case openflow.PacketIn:
+		w("buffer_id: %#x", v.BufferID())
+		w("length: %v", v.Length())
+		w("in_port: %v", v.InPort())
+		w("table_id: %v", v.TableID())
+		w("reason: %v", v.Reason())
+		w("cookie: %#x", v.Cookie())
+		w("data: %x", v.Data())
+	}
+
+	return buf.String()
+}
+
+// describeTableFeatures renders the properties that the switch has reported, i.e., non-nil lists.
+func describeTableFeatures(w func(string, ...interface{}), t openflow.TableFeatures) {
+	w("table: id=%v, name=%q, metadata_match=%#x, metadata_write=%#x, max_entries=%v", t.TableID, t.Name, t.MetadataMatch, t.MetadataWrite, t.MaxEntries)
+	for _, p := range []struct {
+		name     string
+		value    interface{}
+		reported bool
+	}{
+		{"instructions", t.Instructions, t.Instructions != nil},
+		{"instructions_miss", t.InstructionsMiss, t.InstructionsMiss != nil},
+		{"next_tables", t.NextTables, t.NextTables != nil},
+		{"next_tables_miss", t.NextTablesMiss, t.NextTablesMiss != nil},
+		{"write_actions", t.WriteActions, t.WriteActions != nil},
+		{"write_actions_miss", t.WriteActionsMiss, t.WriteActionsMiss != nil},
+		{"apply_actions", t.ApplyActions, t.ApplyActions != nil},
+		{"apply_actions_miss", t.ApplyActionsMiss, t.ApplyActionsMiss != nil},
+		{"match", t.Match, t.Match != nil},
+		{"wildcards", t.Wildcards, t.Wildcards != nil},
+		{"write_setfield", t.WriteSetField, t.WriteSetField != nil},
+		{"write_setfield_miss", t.WriteSetFieldMiss, t.WriteSetFieldMiss != nil},
+		{"apply_setfield", t.ApplySetField, t.ApplySetField != nil},
+		{"apply_setfield_miss", t.ApplySetFieldMiss, t.ApplySetFieldMiss != nil},
+	} {
+		if p.reported {
+			w("table.%v: %+v", p.name, p.value)
+		}
+	}
+}
+
+func describePorts(w func(string, ...interface{}), ports []openflow.Port) {
+	for _, p := range ports {
+		w("port: number=%v, mac=%v, name=%q, port_down=%v, link_down=%v, copper=%v, fiber=%v, autonego=%v, speed=%v",
+			p.Number(), p.MAC(), p.Name(), p.IsPortDown(), p.IsLinkDown(), p.IsCopper(), p.IsFiber(), p.IsAutoNego(), p.Speed())
+	}
+}
+
+func describeAction(w func(string, ...interface{}), a openflow.Action) {
+	if ok, mac := a.DstMAC(); ok {
+		w("action.dst_mac: %v", mac)
+	}
+	if ok, id := a.Group(); ok {
+		w("action.group: %v", id)
+	}
+	if ok, q := a.Queue(); ok {
+		w("action.queue: %v", q)
+	}
+	if port := a.OutPort(); port != (openflow.OutPort{}) {
+		w("action.output: %v", port.Value())
+	}
+}
+
+func describeMatch(w func(string, ...interface{}), m openflow.Match) {
+	if m == nil {
+		w("match: nil")
+		return
+	}
+	if wildcard, port := m.InPort(); !wildcard {
+		w("match.in_port: %v", port.Value())
+	}
+	if wildcard, mac := m.SrcMAC(); !wildcard {
+		w("match.src_mac: %v", mac)
+	}
+	if wildcard, mac := m.DstMAC(); !wildcard {
+		w("match.dst_mac: %v", mac)
+	}
+	if wildcard, t := m.EtherType(); !wildcard {
+		w("match.ether_type: %#04x", t)
+	}
+	if wildcard, id := m.VLANID(); !wildcard {
+		w("match.vlan_id: %v", id)
+	}
+	if wildcard, p := m.IPProtocol(); !wildcard {
+		w("match.ip_protocol: %v", p)
+	}
+	if ip := m.SrcIP(); !isWildcardIP(ip) {
+		w("match.src_ip: %v", ip)
+	}
+	if ip := m.DstIP(); !isWildcardIP(ip) {
+		w("match.dst_ip: %v", ip)
+	}
+	if wildcard, p := m.SrcPort(); !wildcard {
+		w("match.src_port: %v", p)
+	}
+	if wildcard, p := m.DstPort(); !wildcard {
+		w("match.dst_port: %v", p)
+	}
+	for _, v := range m.Extensions() {
+		if v.Class == 0xFFFF {
+			w("match.extension: class=%#04x, field=%v, experimenter=%#08x, value=%x, mask=%x", v.Class, v.Field, v.Experimenter, v.Value, v.Mask)
+		} else {
+			w("match.extension: class=%#04x, field=%v, value=%x, mask=%x", v.Class, v.Field, v.Value, v.Mask)
+		}
+	}
+}
+
+func isWildcardIP(ip *net.IPNet) bool {
+	if ip == nil || ip.IP == nil {
+		return true
+	}
+	ones, _ := ip.Mask.Size()
+
+	return ones == 0
+}
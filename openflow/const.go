@@ -24,4 +24,6 @@ package openflow
 const (
 	OF10_VERSION = 0x01
 	OF13_VERSION = 0x04
+	OF14_VERSION = 0x05
+	OF15_VERSION = 0x06
 )
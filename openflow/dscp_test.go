@@ -0,0 +1,205 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package openflow_test
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/superkkt/cherry/openflow"
+	"github.com/superkkt/cherry/openflow/of10"
+	"github.com/superkkt/cherry/openflow/of13"
+)
+
+// dscpClass classifies IPv4 packets by DSCP and remarks them before sending to port 1.
+type dscpClass struct {
+	name   string
+	match  uint8
+	remark uint8
+	// Expected encodings for each protocol version
+	of13Match, of13Action string
+	of10Match, of10Action string
+}
+
+var dscpPolicy = []dscpClass{
+	{
+		name:       "voice",
+		match:      46, // EF
+		remark:     46,
+		of13Match:  "0001000f80000a020800800010012e00",
+		of13Action: "00190010800010012e000000000000000000001000000001ffff000000000000",
+		of10Match:  "001820ef0000000000000000000000000000000000000800b8000000000000000000000000000000",
+		of10Action: "00080008b8000000000000080001ffff",
+	},
+	{
+		name:       "video",
+		match:      34, // AF41
+		remark:     26, // AF31
+		of13Match:  "0001000f80000a020800800010012200",
+		of13Action: "00190010800010011a000000000000000000001000000001ffff000000000000",
+		of10Match:  "001820ef000000000000000000000000000000000000080088000000000000000000000000000000",
+		of10Action: "0008000868000000000000080001ffff",
+	},
+	{
+		name:       "bulk",
+		match:      10, // AF11
+		remark:     0,  // Best effort
+		of13Match:  "0001000f80000a020800800010010a00",
+		of13Action: "001900108000100100000000000000000000001000000001ffff000000000000",
+		of10Match:  "001820ef000000000000000000000000000000000000080028000000000000000000000000000000",
+		of10Action: "0008000800000000000000080001ffff",
+	},
+}
+
+func TestDSCPPolicyEncoding(t *testing.T) {
+	factories := []struct {
+		name    string
+		factory openflow.Factory
+	}{
+		{"of13", of13.NewFactory()},
+		{"of10", of10.NewFactory()},
+	}
+
+	for _, f := range factories {
+		for _, c := range dscpPolicy {
+			expectedMatch, expectedAction := c.of13Match, c.of13Action
+			if f.name == "of10" {
+				expectedMatch, expectedAction = c.of10Match, c.of10Action
+			}
+
+			match, err := f.factory.NewMatch()
+			if err != nil {
+				t.Fatal(err)
+			}
+			match.SetEtherType(0x0800)
+			match.SetIPDSCP(c.match)
+			data, err := match.MarshalBinary()
+			if err != nil {
+				t.Fatalf("%v/%v: marshaling match: %v", f.name, c.name, err)
+			}
+			if v := hex.EncodeToString(data); v != expectedMatch {
+				t.Errorf("%v/%v: unexpected match: expected=%v, got=%v", f.name, c.name, expectedMatch, v)
+			}
+
+			decoded, err := f.factory.NewMatch()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := decoded.UnmarshalBinary(data); err != nil {
+				t.Fatalf("%v/%v: unmarshaling match: %v", f.name, c.name, err)
+			}
+			if wildcard, dscp := decoded.IPDSCP(); wildcard || dscp != c.match {
+				t.Errorf("%v/%v: unexpected decoded DSCP: wildcard=%v, dscp=%v", f.name, c.name, wildcard, dscp)
+			}
+
+			action, err := f.factory.NewAction()
+			if err != nil {
+				t.Fatal(err)
+			}
+			action.SetIPDSCP(c.remark)
+			port := openflow.NewOutPort()
+			port.SetValue(1)
+			action.SetOutPort(port)
+			data, err = action.MarshalBinary()
+			if err != nil {
+				t.Fatalf("%v/%v: marshaling action: %v", f.name, c.name, err)
+			}
+			if v := hex.EncodeToString(data); v != expectedAction {
+				t.Errorf("%v/%v: unexpected action: expected=%v, got=%v", f.name, c.name, expectedAction, v)
+			}
+
+			decodedAction, err := f.factory.NewAction()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := decodedAction.UnmarshalBinary(data); err != nil {
+				t.Fatalf("%v/%v: unmarshaling action: %v", f.name, c.name, err)
+			}
+			if ok, dscp := decodedAction.IPDSCP(); !ok || dscp != c.remark {
+				t.Errorf("%v/%v: unexpected decoded remark: ok=%v, dscp=%v", f.name, c.name, ok, dscp)
+			}
+		}
+	}
+}
+
+func TestSetIPDSCPPrerequisites(t *testing.T) {
+	for _, f := range []openflow.Factory{of13.NewFactory(), of10.NewFactory()} {
+		// Neither IPv4 nor IPv6 Ethernet type
+		match, err := f.NewMatch()
+		if err != nil {
+			t.Fatal(err)
+		}
+		match.SetEtherType(0x0806)
+		match.SetIPDSCP(46)
+		if match.Error() == nil {
+			t.Errorf("expected an error for DSCP on a non-IP match")
+		}
+
+		// DSCP is only 6 bits
+		match, err = f.NewMatch()
+		if err != nil {
+			t.Fatal(err)
+		}
+		match.SetEtherType(0x0800)
+		match.SetIPDSCP(64)
+		if match.Error() == nil {
+			t.Errorf("expected an error for an out of range DSCP")
+		}
+	}
+}
+
+func TestSetIPDSCPIPv6(t *testing.T) {
+	match, err := of13.NewFactory().NewMatch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	match.SetEtherType(0x86DD)
+	match.SetIPDSCP(46)
+	data, err := match.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := hex.EncodeToString(data); v != "0001000f80000a0286dd800010012e00" {
+		t.Fatalf("unexpected match: %v", v)
+	}
+	decoded, err := of13.NewFactory().NewMatch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if wildcard, dscp := decoded.IPDSCP(); wildcard || dscp != 46 {
+		t.Fatalf("unexpected decoded DSCP: wildcard=%v, dscp=%v", wildcard, dscp)
+	}
+
+	// OpenFlow 1.0 cannot match IPv6 packets.
+	match, err = of10.NewFactory().NewMatch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	match.SetEtherType(0x86DD)
+	match.SetIPDSCP(46)
+	if match.Error() == nil {
+		t.Fatalf("expected an error for DSCP on an OpenFlow 1.0 IPv6 match")
+	}
+}
@@ -24,6 +24,33 @@ package openflow
 import (
 	"encoding"
 	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Version-independent causes of the error messages. They can be checked against
+// the error returned by Error.Err() using errors.Is.
+var (
+	ErrHelloFailed         = errors.New("hello failed")
+	ErrBadRequest          = errors.New("bad request")
+	ErrBadAction           = errors.New("bad action")
+	ErrBadInstruction      = errors.New("bad instruction")
+	ErrBadMatch            = errors.New("bad match")
+	ErrFlowModFailed       = errors.New("flow modification failed")
+	ErrGroupModFailed      = errors.New("group modification failed")
+	ErrPortModFailed       = errors.New("port modification failed")
+	ErrTableModFailed      = errors.New("table modification failed")
+	ErrQueueOpFailed       = errors.New("queue operation failed")
+	ErrSwitchConfigFailed  = errors.New("switch configuration failed")
+	ErrRoleRequestFailed   = errors.New("role request failed")
+	ErrMeterModFailed      = errors.New("meter modification failed")
+	ErrTableFeaturesFailed = errors.New("table features request failed")
+	ErrExperimenterFailed  = errors.New("experimenter error")
+	// More specific causes that take precedence over the above ones.
+	ErrTableFull   = errors.New("flow table is full")
+	ErrFlowOverlap = errors.New("flow overlaps with an existing flow")
+	ErrPermission  = errors.New("permission denied")
+	ErrNotMaster   = errors.New("controller is not the master")
 )
 
 type Error interface {
@@ -31,9 +58,49 @@ type Error interface {
 	Class() uint16 // Error type
 	Code() uint16
 	Data() []byte
+	// Err returns the Go error value of this message, which is a *DeviceError.
+	Err() error
 	encoding.BinaryUnmarshaler
 }
 
+// DeviceError is the Go error value of an error message sent by a device.
+type DeviceError struct {
+	// Transaction ID of the request that caused this error.
+	XID   uint32
+	Class uint16 // Error type
+	Code  uint16
+	// Name is the symbolic name of the class and code such as FLOW_MOD_FAILED/TABLE_FULL.
+	Name string
+	// Data usually contains at least 64 bytes of the failed request.
+	Data  []byte
+	cause error
+}
+
+// NewDeviceError returns the Go error value of msg. cause is one of the version-independent
+// causes such as ErrTableFull, or nil if the error cannot be classified.
+func NewDeviceError(msg Error, name string, cause error) *DeviceError {
+	return &DeviceError{
+		XID:   msg.TransactionID(),
+		Class: msg.Class(),
+		Code:  msg.Code(),
+		Name:  name,
+		Data:  msg.Data(),
+		cause: cause,
+	}
+}
+
+func (r *DeviceError) Error() string {
+	if r.Name == "" {
+		return fmt.Sprintf("device replied an error: class=%v, code=%v, xid=%v", r.Class, r.Code, r.XID)
+	}
+	return fmt.Sprintf("device replied an error: %v (class=%v, code=%v, xid=%v)", r.Name, r.Class, r.Code, r.XID)
+}
+
+// Unwrap returns the version-independent cause of this error, or nil if it is unknown.
+func (r *DeviceError) Unwrap() error {
+	return r.cause
+}
+
 type BaseError struct {
 	Message
 	class uint16
@@ -53,6 +120,12 @@ func (r *BaseError) Data() []byte {
 	return r.data
 }
 
+// Err returns an unclassified DeviceError. The version-specific error messages override
+// this to name the class and code.
+func (r *BaseError) Err() error {
+	return NewDeviceError(r, "", nil)
+}
+
 func (r *BaseError) UnmarshalBinary(data []byte) error {
 	if err := r.Message.UnmarshalBinary(data); err != nil {
 		return err
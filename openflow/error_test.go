@@ -0,0 +1,77 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package openflow_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/superkkt/cherry/openflow"
+	"github.com/superkkt/cherry/openflow/of10"
+	"github.com/superkkt/cherry/openflow/of13"
+)
+
+func TestErrorClassification(t *testing.T) {
+	tests := []struct {
+		factory openflow.Factory
+		class   uint16
+		code    uint16
+		name    string
+		cause   error
+	}{
+		{of10.NewFactory(), of10.OFPET_FLOW_MOD_FAILED, of10.OFPFMFC_ALL_TABLES_FULL, "FLOW_MOD_FAILED/ALL_TABLES_FULL", openflow.ErrTableFull},
+		{of10.NewFactory(), of10.OFPET_FLOW_MOD_FAILED, of10.OFPFMFC_OVERLAP, "FLOW_MOD_FAILED/OVERLAP", openflow.ErrFlowOverlap},
+		{of10.NewFactory(), of10.OFPET_BAD_ACTION, 4, "BAD_ACTION/BAD_OUT_PORT", openflow.ErrBadAction},
+		{of13.NewFactory(), of13.OFPET_FLOW_MOD_FAILED, of13.OFPFMFC_TABLE_FULL, "FLOW_MOD_FAILED/TABLE_FULL", openflow.ErrTableFull},
+		{of13.NewFactory(), of13.OFPET_BAD_REQUEST, of13.OFPBRC_IS_SLAVE, "BAD_REQUEST/IS_SLAVE", openflow.ErrNotMaster},
+		{of13.NewFactory(), of13.OFPET_BAD_MATCH, 11, "BAD_MATCH/EPERM", openflow.ErrPermission},
+		// Unknown code of a known class
+		{of13.NewFactory(), of13.OFPET_BAD_INSTRUCTION, 100, "BAD_INSTRUCTION/100", openflow.ErrBadInstruction},
+		// Unknown class
+		{of13.NewFactory(), 100, 0, "", nil},
+	}
+
+	for _, test := range tests {
+		packet := []byte{test.factory.ProtocolVersion(), 1, 0, 16, 0, 0, 0, 7, 0, 0, 0, 0, 0xde, 0xad, 0xbe, 0xef}
+		packet[8], packet[9] = byte(test.class>>8), byte(test.class)
+		packet[10], packet[11] = byte(test.code>>8), byte(test.code)
+
+		msg, err := test.factory.NewError()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := msg.UnmarshalBinary(packet); err != nil {
+			t.Fatal(err)
+		}
+
+		var e *openflow.DeviceError
+		if !errors.As(msg.Err(), &e) {
+			t.Fatalf("unexpected error type: %T", msg.Err())
+		}
+		if e.XID != 7 || e.Name != test.name || len(e.Data) != 4 {
+			t.Fatalf("unexpected device error: %+v", e)
+		}
+		if errors.Unwrap(e) != test.cause {
+			t.Fatalf("%v: unexpected cause: %v", test.name, errors.Unwrap(e))
+		}
+	}
+}
@@ -0,0 +1,231 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package openflow
+
+import (
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var (
+	ErrUnknownExperimenter = errors.New("unknown experimenter")
+)
+
+// Experimenter is a vendor-specific message, which is OFPT_VENDOR in OpenFlow 1.0 and
+// OFPT_EXPERIMENTER in OpenFlow 1.3. OpenFlow 1.0 does not have the exp_type field, but
+// we take the first 4 bytes of the vendor data as the type because the vendor extensions
+// such as Nicira's have a 32-bit subtype there.
+type Experimenter interface {
+	Data() []byte
+	encoding.BinaryMarshaler
+	encoding.BinaryUnmarshaler
+	Error() error
+	Experimenter() uint32
+	ExpType() uint32
+	Header
+	SetData(data []byte)
+	SetExperimenter(id uint32)
+	SetExpType(t uint32)
+}
+
+type BaseExperimenter struct {
+	err error
+	Message
+	experimenter uint32
+	expType      uint32
+	data         []byte
+}
+
+func (r *BaseExperimenter) Error() error {
+	return r.err
+}
+
+func (r *BaseExperimenter) Experimenter() uint32 {
+	return r.experimenter
+}
+
+func (r *BaseExperimenter) SetExperimenter(id uint32) {
+	r.experimenter = id
+}
+
+func (r *BaseExperimenter) ExpType() uint32 {
+	return r.expType
+}
+
+func (r *BaseExperimenter) SetExpType(t uint32) {
+	r.expType = t
+}
+
+func (r *BaseExperimenter) Data() []byte {
+	return r.data
+}
+
+func (r *BaseExperimenter) SetData(data []byte) {
+	r.data = data
+}
+
+func (r *BaseExperimenter) MarshalBinary() ([]byte, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	v := make([]byte, 8+len(r.data))
+	binary.BigEndian.PutUint32(v[0:4], r.experimenter)
+	binary.BigEndian.PutUint32(v[4:8], r.expType)
+	copy(v[8:], r.data)
+	r.SetPayload(v)
+
+	return r.Message.MarshalBinary()
+}
+
+func (r *BaseExperimenter) UnmarshalBinary(data []byte) error {
+	if err := r.Message.UnmarshalBinary(data); err != nil {
+		return err
+	}
+
+	payload := r.Payload()
+	if payload == nil || len(payload) < 8 {
+		return ErrInvalidPacketLength
+	}
+	r.experimenter = binary.BigEndian.Uint32(payload[0:4])
+	r.expType = binary.BigEndian.Uint32(payload[4:8])
+	r.data = payload[8:]
+
+	return nil
+}
+
+// ExperimenterAction is a vendor-specific action. The action header and the experimenter ID
+// are the same in OpenFlow 1.0 and 1.3, so an ExperimenterAction only encodes its body that
+// follows the experimenter ID.
+type ExperimenterAction interface {
+	encoding.BinaryMarshaler
+	Experimenter() uint32
+}
+
+// MarshalExperimenterAction encodes action including its header, which is OFPAT_VENDOR in
+// OpenFlow 1.0 and OFPAT_EXPERIMENTER in OpenFlow 1.3.
+func MarshalExperimenterAction(action ExperimenterAction) ([]byte, error) {
+	body, err := action.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	v := make([]byte, 8+len(body))
+	binary.BigEndian.PutUint16(v[0:2], 0xFFFF)
+	binary.BigEndian.PutUint32(v[4:8], action.Experimenter())
+	copy(v[8:], body)
+	// Add padding to align as a multiple of 8
+	if rem := len(v) % 8; rem > 0 {
+		v = append(v, make([]byte, 8-rem)...)
+	}
+	if len(v) > 0xFFFF {
+		return nil, ErrInvalidPacketLength
+	}
+	binary.BigEndian.PutUint16(v[2:4], uint16(len(v)))
+
+	return v, nil
+}
+
+// UnmarshalExperimenterAction decodes an OFPAT_VENDOR or OFPAT_EXPERIMENTER action in data. It
+// returns ErrUnknownExperimenter if the experimenter of the action is not registered.
+func UnmarshalExperimenterAction(data []byte) (ExperimenterAction, error) {
+	if len(data) < 8 {
+		return nil, ErrInvalidPacketLength
+	}
+	length := binary.BigEndian.Uint16(data[2:4])
+	if length < 8 || len(data) < int(length) {
+		return nil, ErrInvalidPacketLength
+	}
+
+	return DecodeExperimenterAction(binary.BigEndian.Uint32(data[4:8]), data[8:length])
+}
+
+// ExperimenterDecoder decodes the vendor-specific payloads of an experimenter.
+type ExperimenterDecoder interface {
+	// DecodeMessage returns a vendor-specific value decoded from msg.
+	DecodeMessage(msg Experimenter) (interface{}, error)
+	// DecodeAction returns an action decoded from data that follows the experimenter ID.
+	DecodeAction(data []byte) (ExperimenterAction, error)
+}
+
+var experimenters = struct {
+	sync.RWMutex
+	m map[uint32]ExperimenterDecoder
+}{
+	m: make(map[uint32]ExperimenterDecoder),
+}
+
+// RegisterExperimenter registers the decoder of the experimenter whose ID is id. It panics if
+// the experimenter is already registered, so it should be called from the init function of the
+// package that implements the extensions.
+func RegisterExperimenter(id uint32, decoder ExperimenterDecoder) {
+	if decoder == nil {
+		panic("decoder is nil")
+	}
+
+	// Write lock
+	experimenters.Lock()
+	defer experimenters.Unlock()
+
+	if _, ok := experimenters.m[id]; ok {
+		panic(fmt.Sprintf("duplicated experimenter: 0x%08x", id))
+	}
+	experimenters.m[id] = decoder
+}
+
+func experimenterDecoder(id uint32) (ExperimenterDecoder, error) {
+	// Read lock
+	experimenters.RLock()
+	defer experimenters.RUnlock()
+
+	decoder, ok := experimenters.m[id]
+	if !ok {
+		return nil, ErrUnknownExperimenter
+	}
+
+	return decoder, nil
+}
+
+// DecodeExperimenter decodes the vendor-specific payload of msg using the registered decoder.
+// It returns ErrUnknownExperimenter if the experimenter of msg is not registered.
+func DecodeExperimenter(msg Experimenter) (interface{}, error) {
+	decoder, err := experimenterDecoder(msg.Experimenter())
+	if err != nil {
+		return nil, err
+	}
+
+	return decoder.DecodeMessage(msg)
+}
+
+// DecodeExperimenterAction decodes data that follows the experimenter ID of an action using the
+// registered decoder. It returns ErrUnknownExperimenter if the experimenter is not registered.
+func DecodeExperimenterAction(id uint32, data []byte) (ExperimenterAction, error) {
+	decoder, err := experimenterDecoder(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return decoder.DecodeAction(data)
+}
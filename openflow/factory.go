@@ -37,6 +37,10 @@ var (
 	ErrMissingEtherType      = errors.New("missing Ethernet type")
 	ErrUnsupportedMatchType  = errors.New("unsupported flow match type")
 	ErrInvalidPropertyMethod = errors.New("invalid property method")
+	ErrInvalidDSCP           = errors.New("invalid DSCP value")
+	ErrInvalidMPLSLabel      = errors.New("invalid MPLS label")
+	ErrInvalidMPLSTC         = errors.New("invalid MPLS traffic class")
+	ErrInvalidFlowLabel      = errors.New("invalid IPv6 flow label")
 )
 
 // Abstract factory
@@ -50,24 +54,34 @@ type Factory interface {
 	NewEchoRequest() (EchoRequest, error)
 	NewEchoReply() (EchoReply, error)
 	NewError() (Error, error)
+	NewExperimenter() (Experimenter, error)
 	NewFeaturesRequest() (FeaturesRequest, error)
 	NewFeaturesReply() (FeaturesReply, error)
 	NewFlowMod(cmd FlowModCmd) (FlowMod, error)
 	NewFlowRemoved() (FlowRemoved, error)
 	NewFlowStatsRequest() (FlowStatsRequest, error)
-	// TODO: NewFlowStatsReply() (FlowStatsReply, error)
+	NewFlowStatsReply() (FlowStatsReply, error)
 	NewGetConfigRequest() (GetConfigRequest, error)
 	NewGetConfigReply() (GetConfigReply, error)
+	NewGroupMod(cmd GroupModCmd) (GroupMod, error)
 	NewHello() (Hello, error)
 	NewInstruction() (Instruction, error)
 	NewMatch() (Match, error)
+	NewMeterMod(cmd MeterModCmd) (MeterMod, error)
 	NewPacketIn() (PacketIn, error)
 	NewPacketOut() (PacketOut, error)
 	NewPortDescRequest() (PortDescRequest, error)
 	NewPortDescReply() (PortDescReply, error)
+	NewPortStatsRequest() (PortStatsRequest, error)
+	NewPortStatsReply() (PortStatsReply, error)
 	NewPortStatus() (PortStatus, error)
 	NewQueueGetConfigRequest() (QueueGetConfigRequest, error)
+	NewQueueGetConfigReply() (QueueGetConfigReply, error)
+	NewQueueStatsRequest() (QueueStatsRequest, error)
+	NewQueueStatsReply() (QueueStatsReply, error)
+	NewRoleRequest() (RoleRequest, error)
+	NewRoleReply() (RoleReply, error)
 	NewSetConfig() (SetConfig, error)
 	NewTableFeaturesRequest() (TableFeaturesRequest, error)
-	// TODO: NewTableFeaturesReply() (TableFeaturesReply, error)
+	NewTableFeaturesReply() (TableFeaturesReply, error)
 }
@@ -31,12 +31,25 @@ const (
 	FlowAdd FlowModCmd = iota
 	FlowModify
 	FlowDelete
+	// Modify the flow that strictly matches the match fields and priority.
+	FlowModifyStrict
+	// Delete the flow that strictly matches the match fields and priority.
+	FlowDeleteStrict
+)
+
+const (
+	// NoBuffer is the buffer ID of a FLOW_MOD that does not refer a packet buffered in the switch.
+	NoBuffer = 0xFFFFFFFF
 )
 
 type FlowMod interface {
+	// BufferID returns the ID of the buffered packet that will be applied to the flow.
+	BufferID() uint32
+	Command() FlowModCmd
 	Cookie() uint64
 	CookieMask() uint64
 	encoding.BinaryMarshaler
+	encoding.BinaryUnmarshaler
 	Error() error
 	FlowInstruction() Instruction
 	FlowMatch() Match
@@ -45,6 +58,9 @@ type FlowMod interface {
 	IdleTimeout() uint16
 	OutPort() OutPort
 	Priority() uint16
+	// SetBufferID sets the ID of the buffered packet, which has been delivered by
+	// PACKET_IN, to be applied to the flow. Default is NoBuffer.
+	SetBufferID(id uint32)
 	SetCookie(cookie uint64)
 	SetCookieMask(mask uint64)
 	SetFlowInstruction(action Instruction)
@@ -25,6 +25,16 @@ import (
 	"encoding"
 )
 
+// Reasons of the flow removal. They are same in OpenFlow 1.0 and 1.3.
+const (
+	FlowRemovedIdleTimeout uint8 = iota
+	FlowRemovedHardTimeout
+	FlowRemovedDelete
+	// OpenFlow 1.3 only
+	FlowRemovedGroupDelete
+	FlowRemovedMeterDelete
+)
+
 type FlowRemoved interface {
 	Header
 	Cookie() uint64
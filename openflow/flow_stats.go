@@ -40,4 +40,22 @@ type FlowStatsRequest interface {
 	TableID() uint8
 }
 
-// TODO: Implement FlowStatsReply
+// FlowStats is an entry of the flow statistics reply.
+type FlowStats struct {
+	TableID         uint8
+	DurationSec     uint32
+	DurationNanoSec uint32
+	Priority        uint16
+	IdleTimeout     uint16
+	HardTimeout     uint16
+	Cookie          uint64
+	PacketCount     uint64
+	ByteCount       uint64
+	Match           Match
+}
+
+type FlowStatsReply interface {
+	Header
+	FlowStats() []FlowStats
+	encoding.BinaryUnmarshaler
+}
@@ -0,0 +1,106 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package openflow
+
+import (
+	"encoding"
+)
+
+type GroupModCmd uint8
+
+const (
+	GroupAdd GroupModCmd = iota
+	GroupModify
+	GroupDelete
+)
+
+type GroupType uint8
+
+const (
+	// Execute all buckets, e.g., multicast and flooding.
+	GroupAll GroupType = iota
+	// Execute one bucket selected by the switch, e.g., ECMP.
+	GroupSelect
+	// Execute the only one bucket.
+	GroupIndirect
+	// Execute the first live bucket.
+	GroupFastFailover
+)
+
+const (
+	// AllGroups is the group ID that represents all groups in a group delete command.
+	AllGroups = 0xFFFFFFFC
+	// NoWatch is the watch port or group of a bucket that is not watched.
+	NoWatch = 0xFFFFFFFF
+)
+
+// Bucket is a set of actions of a group.
+type Bucket struct {
+	// Weight is only used by the select groups.
+	Weight uint16
+	// WatchPort and WatchGroup are only used by the fast failover groups, and
+	// the bucket is live if the watched port or group is live.
+	WatchPort  uint32
+	WatchGroup uint32
+	Action     Action
+}
+
+// NewBucket returns a bucket for the all and indirect groups.
+func NewBucket(act Action) Bucket {
+	return Bucket{
+		WatchPort:  NoWatch,
+		WatchGroup: NoWatch,
+		Action:     act,
+	}
+}
+
+// NewSelectBucket returns a bucket for the select groups. The switch selects a
+// bucket with the probability proportional to its weight.
+func NewSelectBucket(weight uint16, act Action) Bucket {
+	v := NewBucket(act)
+	v.Weight = weight
+
+	return v
+}
+
+// NewFailoverBucket returns a bucket for the fast failover groups, which is live
+// while watchPort is up.
+func NewFailoverBucket(watchPort uint32, act Action) Bucket {
+	v := NewBucket(act)
+	v.WatchPort = watchPort
+
+	return v
+}
+
+type GroupMod interface {
+	AddBucket(b Bucket)
+	Buckets() []Bucket
+	Command() GroupModCmd
+	encoding.BinaryMarshaler
+	encoding.BinaryUnmarshaler
+	Error() error
+	GroupID() uint32
+	GroupType() GroupType
+	Header
+	SetGroupID(id uint32)
+	SetGroupType(t GroupType)
+}
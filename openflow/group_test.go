@@ -0,0 +1,106 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package openflow_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"path/filepath"
+	"testing"
+
+	"github.com/superkkt/cherry/openflow"
+	"github.com/superkkt/cherry/openflow/of10"
+	"github.com/superkkt/cherry/openflow/of13"
+)
+
+func outputAction(t *testing.T, f openflow.Factory, port uint32) openflow.Action {
+	action, err := f.NewAction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := openflow.NewOutPort()
+	out.SetValue(port)
+	action.SetOutPort(out)
+
+	return action
+}
+
+func TestFastFailoverGroup(t *testing.T) {
+	expected, err := readHex(filepath.Join("testdata", "of13", "group_mod.fast_failover.hex"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := of13.NewFactory()
+	group := of13.NewGroupMod(3, of13.OFPGC_ADD)
+	group.SetGroupType(openflow.GroupFastFailover)
+	group.SetGroupID(1)
+	group.AddBucket(openflow.NewFailoverBucket(1, outputAction(t, f, 1)))
+	group.AddBucket(openflow.NewFailoverBucket(2, outputAction(t, f, 2)))
+	v, err := group.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(v, expected) {
+		t.Fatalf("unexpected encoding:\n%v", hexDiff(expected, v))
+	}
+}
+
+func TestGroupAction(t *testing.T) {
+	action := of13.NewAction()
+	action.SetGroup(7)
+	v, err := action.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// OFPAT_GROUP without any output action.
+	if hex.EncodeToString(v) != "0016000800000007" {
+		t.Fatalf("unexpected encoding: %x", v)
+	}
+
+	action = of10.NewAction()
+	action.SetGroup(7)
+	if _, err := action.MarshalBinary(); err == nil {
+		t.Fatal("expected an error for the group action of OpenFlow 1.0")
+	}
+}
+
+func TestInvalidGroupMod(t *testing.T) {
+	f := of13.NewFactory()
+
+	// Indirect group with two buckets.
+	group := of13.NewGroupMod(1, of13.OFPGC_ADD)
+	group.SetGroupType(openflow.GroupIndirect)
+	group.SetGroupID(1)
+	group.AddBucket(openflow.NewBucket(outputAction(t, f, 1)))
+	group.AddBucket(openflow.NewBucket(outputAction(t, f, 2)))
+	if _, err := group.MarshalBinary(); err == nil {
+		t.Fatal("expected an error for the indirect group with two buckets")
+	}
+
+	// All groups can only be deleted.
+	group = of13.NewGroupMod(2, of13.OFPGC_MODIFY)
+	group.SetGroupID(openflow.AllGroups)
+	if _, err := group.MarshalBinary(); err == nil {
+		t.Fatal("expected an error for modifying all groups")
+	}
+}
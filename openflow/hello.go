@@ -23,16 +23,72 @@ package openflow
 
 import (
 	"encoding"
+	"encoding/binary"
+	"sort"
+)
+
+const (
+	// Hello element type for the bitmap of the supported versions.
+	helloElemVersionBitmap = 1
 )
 
 type Hello interface {
 	Header
 	encoding.BinaryMarshaler
 	encoding.BinaryUnmarshaler
+	// Versions returns the protocol versions, in ascending order, advertised by the
+	// version bitmap element. It returns nil if there is no version bitmap.
+	Versions() []uint8
+	// SetVersions sets the version bitmap element to advertise versions.
+	SetVersions(versions []uint8)
 }
 
 type BaseHello struct {
 	Message
+	versions []uint8
+}
+
+func (r *BaseHello) Versions() []uint8 {
+	return r.versions
+}
+
+func (r *BaseHello) SetVersions(versions []uint8) {
+	if len(versions) == 0 {
+		r.versions = nil
+		r.SetPayload(nil)
+		return
+	}
+
+	var max uint8
+	for _, v := range versions {
+		if v > max {
+			max = v
+		}
+	}
+	bitmap := make([]byte, 4*(int(max)/32+1))
+	for _, v := range versions {
+		i := int(v) / 32
+		word := binary.BigEndian.Uint32(bitmap[i*4:])
+		binary.BigEndian.PutUint32(bitmap[i*4:], word|1<<(v%32))
+	}
+
+	length := 4 + len(bitmap)
+	// Elements are padded to align as a multiple of 8
+	elem := make([]byte, (length+7)/8*8)
+	binary.BigEndian.PutUint16(elem[0:2], helloElemVersionBitmap)
+	binary.BigEndian.PutUint16(elem[2:4], uint16(length))
+	copy(elem[4:], bitmap)
+
+	r.versions = sortedVersions(versions)
+	r.SetPayload(elem)
+}
+
+func sortedVersions(versions []uint8) []uint8 {
+	v := make([]uint8, len(versions))
+	copy(v, versions)
+	sort.Slice(v, func(i, j int) bool { return v[i] < v[j] })
+
+	return v
 }
 
 func (r *BaseHello) MarshalBinary() ([]byte, error) {
@@ -40,5 +96,75 @@ func (r *BaseHello) MarshalBinary() ([]byte, error) {
 }
 
 func (r *BaseHello) UnmarshalBinary(data []byte) error {
-	return r.Message.UnmarshalBinary(data)
+	if err := r.Message.UnmarshalBinary(data); err != nil {
+		return err
+	}
+
+	r.versions = nil
+	// Hello elements have been introduced since OpenFlow 1.3.
+	if r.Version() < OF13_VERSION {
+		return nil
+	}
+	buf := r.Payload()
+	for len(buf) >= 4 {
+		t := binary.BigEndian.Uint16(buf[0:2])
+		length := int(binary.BigEndian.Uint16(buf[2:4]))
+		if length < 4 || len(buf) < length {
+			return ErrInvalidPacketLength
+		}
+
+		if t == helloElemVersionBitmap {
+			bitmap := buf[4:length]
+			versions := make([]uint8, 0)
+			for i := 0; i+4 <= len(bitmap); i += 4 {
+				word := binary.BigEndian.Uint32(bitmap[i : i+4])
+				for bit := uint(0); bit < 32; bit++ {
+					v := i/4*32 + int(bit)
+					if word&(1<<bit) != 0 && v <= 0xFF {
+						versions = append(versions, uint8(v))
+					}
+				}
+			}
+			r.versions = versions
+		}
+
+		// Skip the padding
+		length = (length + 7) / 8 * 8
+		if length > len(buf) {
+			break
+		}
+		buf = buf[length:]
+	}
+
+	return nil
+}
+
+// NegotiateVersion returns the highest version supported by both us and the peer
+// that has sent hello. If hello does not have the version bitmap, the negotiated
+// version is the smaller one of the hello version and our highest version as the
+// specification describes. ok will be false if there is no such version.
+func NegotiateVersion(hello Hello, supported []uint8) (version uint8, ok bool) {
+	if len(supported) == 0 {
+		return 0, false
+	}
+	ours := sortedVersions(supported)
+
+	theirs := hello.Versions()
+	if theirs == nil {
+		v := hello.Version()
+		if highest := ours[len(ours)-1]; v > highest {
+			v = highest
+		}
+		theirs = []uint8{v}
+	}
+
+	for i := len(ours) - 1; i >= 0; i-- {
+		for _, v := range theirs {
+			if v == ours[i] {
+				return v, true
+			}
+		}
+	}
+
+	return 0, false
 }
@@ -0,0 +1,90 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package openflow_test
+
+import (
+	"testing"
+
+	"github.com/superkkt/cherry/openflow"
+	"github.com/superkkt/cherry/openflow/of13"
+)
+
+func TestNegotiateVersion(t *testing.T) {
+	supported := []uint8{openflow.OF10_VERSION, openflow.OF13_VERSION}
+	tests := []struct {
+		version  uint8
+		bitmap   []uint8
+		expected uint8
+		ok       bool
+	}{
+		// Without the version bitmap, the smaller one of both the highest versions is chosen.
+		{version: 0x01, expected: 0x01, ok: true},
+		{version: 0x04, expected: 0x04, ok: true},
+		{version: 0x06, expected: 0x04, ok: true},
+		{version: 0x02, ok: false},
+		// With the version bitmap, the highest common version is chosen.
+		{version: 0x04, bitmap: []uint8{0x01}, expected: 0x01, ok: true},
+		{version: 0x06, bitmap: []uint8{0x01, 0x04, 0x05, 0x06}, expected: 0x04, ok: true},
+		{version: 0x06, bitmap: []uint8{0x01, 0x05, 0x06}, expected: 0x01, ok: true},
+		{version: 0x06, bitmap: []uint8{0x05, 0x06}, ok: false},
+		// Bitmap of multiple words.
+		{version: 0x24, bitmap: []uint8{0x24, 0x04}, expected: 0x04, ok: true},
+	}
+
+	for _, test := range tests {
+		v := &openflow.BaseHello{Message: openflow.NewMessage(test.version, 0, 1)}
+		v.SetVersions(test.bitmap)
+		data, err := v.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		hello := new(openflow.BaseHello)
+		if err := hello.UnmarshalBinary(data); err != nil {
+			t.Fatalf("failed to decode hello (%+v): %v", test, err)
+		}
+
+		version, ok := openflow.NegotiateVersion(hello, supported)
+		if ok != test.ok || version != test.expected {
+			t.Errorf("unexpected negotiation for %+v: version=%v, ok=%v", test, version, ok)
+		}
+	}
+}
+
+func TestHelloAdvertisesVersions(t *testing.T) {
+	hello, err := of13.NewFactory().NewHello()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := hello.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded := new(openflow.BaseHello)
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	v := decoded.Versions()
+	if len(v) != 2 || v[0] != openflow.OF10_VERSION || v[1] != openflow.OF13_VERSION {
+		t.Fatalf("unexpected advertised versions: %v", v)
+	}
+}
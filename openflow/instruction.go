@@ -26,9 +26,35 @@ import (
 )
 
 type Instruction interface {
+	// Actions returns the actions applied or written by the instructions, or nil if there is
+	// no action.
+	Actions() []Action
 	ApplyAction(act Action)
+	// ApplyActionAndGotoTable applies act to the packet, and then continues processing the
+	// packet on the table whose ID is tableID. The output of act is optional.
+	ApplyActionAndGotoTable(act Action, tableID uint8)
 	encoding.BinaryMarshaler
 	Error() error
 	GotoTable(tableID uint8)
+	// SetInstructions replaces the instructions with set. The meter set by SetMeter is kept
+	// unless set has its own meter.
+	SetInstructions(set InstructionSet)
+	// SetMeter applies the meter whose ID is id to the packets before the actions.
+	SetMeter(id uint32)
 	WriteAction(act Action)
 }
+
+// InstructionSet is the instructions of a flow. The switch executes them in the order of
+// the fields regardless of the order in which they are encoded. A nil action or the zero
+// value of a field means that the instruction does not exist.
+type InstructionSet struct {
+	Meter uint32
+	Apply Action
+	Write Action
+	// Bits of the metadata written by Metadata.
+	MetadataMask uint64
+	Metadata     uint64
+	// GotoTable is valid only if Goto is true.
+	Goto      bool
+	GotoTable uint8
+}
@@ -0,0 +1,387 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package openflow
+
+import (
+	"net"
+
+	"github.com/pkg/errors"
+)
+
+// ActionBuilder builds an Action by chaining the actions, e.g.,
+//
+//	action, err := openflow.NewActionBuilder(f).PopVLAN().SetQueue(1).Output(2).Build()
+//
+// The switch executes the actions in the order of PopVLAN, PopMPLS, PushMPLS, PushVLAN, the
+// set-field actions, SetQueue, Group and Output regardless of the order in which they are chained.
+type ActionBuilder struct {
+	factory Factory
+	// First error of the chained actions.
+	err      error
+	output   *OutPort
+	group    *uint32
+	queue    *uint32
+	popVLAN  bool
+	pushVLAN *uint16
+	popMPLS  *uint16
+	pushMPLS *uint16
+	// Set-field actions
+	fields []func(Action)
+}
+
+func NewActionBuilder(f Factory) *ActionBuilder {
+	if f == nil {
+		panic("factory is nil")
+	}
+
+	return &ActionBuilder{factory: f}
+}
+
+func (r *ActionBuilder) fail(err error) *ActionBuilder {
+	if r.err == nil {
+		r.err = err
+	}
+	return r
+}
+
+// Output sends the packet to the switch port whose number is port.
+func (r *ActionBuilder) Output(port uint32) *ActionBuilder {
+	p := NewOutPort()
+	p.SetValue(port)
+	return r.OutputPort(p)
+}
+
+// OutputPort sends the packet to port, which can be a reserved port, e.g., the controller.
+func (r *ActionBuilder) OutputPort(port OutPort) *ActionBuilder {
+	if r.output != nil {
+		return r.fail(errors.New("Output: duplicated output"))
+	}
+	if port == (OutPort{}) {
+		return r.fail(errors.New("Output: invalid port number"))
+	}
+	r.output = &port
+	return r
+}
+
+// Group processes the packet by the group whose ID is id.
+func (r *ActionBuilder) Group(id uint32) *ActionBuilder {
+	if r.group != nil {
+		return r.fail(errors.New("Group: duplicated group"))
+	}
+	r.group = &id
+	return r
+}
+
+// PushVLAN pushes a new VLAN tag whose Ethernet type is 0x8100 (802.1Q) or 0x88A8 (802.1ad).
+func (r *ActionBuilder) PushVLAN(ethType uint16) *ActionBuilder {
+	if r.pushVLAN != nil {
+		return r.fail(errors.New("PushVLAN: duplicated push"))
+	}
+	if ethType != 0x8100 && ethType != 0x88A8 {
+		return r.fail(errors.New("PushVLAN: invalid Ethernet type of the VLAN tag"))
+	}
+	r.pushVLAN = &ethType
+	return r
+}
+
+// PopVLAN removes the outermost VLAN tag of the packet.
+func (r *ActionBuilder) PopVLAN() *ActionBuilder {
+	if r.popVLAN {
+		return r.fail(errors.New("PopVLAN: duplicated pop"))
+	}
+	r.popVLAN = true
+	return r
+}
+
+// PushMPLS pushes a new MPLS shim header whose Ethernet type is 0x8847 (unicast) or 0x8848 (multicast).
+func (r *ActionBuilder) PushMPLS(ethType uint16) *ActionBuilder {
+	if r.pushMPLS != nil {
+		return r.fail(errors.New("PushMPLS: duplicated push"))
+	}
+	if ethType != 0x8847 && ethType != 0x8848 {
+		return r.fail(errors.New("PushMPLS: invalid Ethernet type of the MPLS shim header"))
+	}
+	r.pushMPLS = &ethType
+	return r
+}
+
+// PopMPLS removes the outermost MPLS shim header of the packet, whose payload has the Ethernet
+// type ethType, e.g., 0x0800 if the last label is popped from an IPv4 packet.
+func (r *ActionBuilder) PopMPLS(ethType uint16) *ActionBuilder {
+	if r.popMPLS != nil {
+		return r.fail(errors.New("PopMPLS: duplicated pop"))
+	}
+	r.popMPLS = &ethType
+	return r
+}
+
+// SetQueue enqueues the packet into the egress queue whose ID is queue on the output port.
+func (r *ActionBuilder) SetQueue(queue uint32) *ActionBuilder {
+	if r.queue != nil {
+		return r.fail(errors.New("SetQueue: duplicated queue"))
+	}
+	r.queue = &queue
+	return r
+}
+
+func (r *ActionBuilder) setField(setter func(Action)) *ActionBuilder {
+	r.fields = append(r.fields, setter)
+	return r
+}
+
+func (r *ActionBuilder) SetEthSrc(mac net.HardwareAddr) *ActionBuilder {
+	return r.setField(func(a Action) { a.SetSrcMAC(mac) })
+}
+
+func (r *ActionBuilder) SetEthDst(mac net.HardwareAddr) *ActionBuilder {
+	return r.setField(func(a Action) { a.SetDstMAC(mac) })
+}
+
+// SetVLANID rewrites the VLAN ID of the outermost VLAN tag, which can be pushed by PushVLAN.
+func (r *ActionBuilder) SetVLANID(id uint16) *ActionBuilder {
+	if id > 0xFFF {
+		return r.fail(errors.New("SetVLANID: invalid VLAN ID"))
+	}
+	return r.setField(func(a Action) { a.SetVLANID(id) })
+}
+
+func (r *ActionBuilder) SetVLANPriority(p uint8) *ActionBuilder {
+	return r.setField(func(a Action) { a.SetVLANPriority(p) })
+}
+
+func (r *ActionBuilder) SetMPLSLabel(label uint32) *ActionBuilder {
+	return r.setField(func(a Action) { a.SetMPLSLabel(label) })
+}
+
+func (r *ActionBuilder) SetMPLSTC(tc uint8) *ActionBuilder {
+	return r.setField(func(a Action) { a.SetMPLSTC(tc) })
+}
+
+func (r *ActionBuilder) SetIPDSCP(dscp uint8) *ActionBuilder {
+	return r.setField(func(a Action) { a.SetIPDSCP(dscp) })
+}
+
+// SetIPSrc rewrites the source address of the IPv4 or IPv6 packet.
+func (r *ActionBuilder) SetIPSrc(ip net.IP) *ActionBuilder {
+	return r.setField(func(a Action) { a.SetSrcIP(ip) })
+}
+
+// SetIPDst rewrites the destination address of the IPv4 or IPv6 packet.
+func (r *ActionBuilder) SetIPDst(ip net.IP) *ActionBuilder {
+	return r.setField(func(a Action) { a.SetDstIP(ip) })
+}
+
+// SetSrcPort rewrites the source port number of the transport protocol, e.g., ProtocolTCP.
+func (r *ActionBuilder) SetSrcPort(protocol uint8, port uint16) *ActionBuilder {
+	return r.setField(func(a Action) { a.SetSrcPort(protocol, port) })
+}
+
+// SetDstPort rewrites the destination port number of the transport protocol, e.g., ProtocolTCP.
+func (r *ActionBuilder) SetDstPort(protocol uint8, port uint16) *ActionBuilder {
+	return r.setField(func(a Action) { a.SetDstPort(protocol, port) })
+}
+
+// SetTunnelID sets the tunnel ID, e.g., the VNI of VXLAN or the key of GRE, of the packet
+// that will be encapsulated by the output tunnel port.
+func (r *ActionBuilder) SetTunnelID(id uint64) *ActionBuilder {
+	return r.setField(func(a Action) { a.SetTunnelID(id) })
+}
+
+// Build validates the chained actions and returns the action. The action without an output
+// and a group should be applied with GotoTable of InstructionBuilder, so that the packet is
+// output by the next table.
+func (r *ActionBuilder) Build() (Action, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	if r.queue != nil && r.output == nil {
+		return nil, errors.New("SetQueue: missing output")
+	}
+
+	action, err := r.factory.NewAction()
+	if err != nil {
+		return nil, err
+	}
+	if r.popVLAN {
+		action.SetPopVLAN()
+	}
+	if r.popMPLS != nil {
+		action.SetPopMPLS(*r.popMPLS)
+	}
+	if r.pushMPLS != nil {
+		action.SetPushMPLS(*r.pushMPLS)
+	}
+	if r.pushVLAN != nil {
+		action.SetPushVLAN(*r.pushVLAN)
+	}
+	for _, v := range r.fields {
+		v(action)
+	}
+	if r.queue != nil {
+		action.SetQueue(*r.queue)
+	}
+	if r.group != nil {
+		action.SetGroup(*r.group)
+	}
+	if r.output != nil {
+		action.SetOutPort(*r.output)
+	}
+	if err := action.Error(); err != nil {
+		return nil, err
+	}
+	if _, err := action.MarshalBinary(); err != nil {
+		return nil, err
+	}
+
+	return action, nil
+}
+
+// hasOutput returns whether act sends the packet out of the switch or to a group.
+func hasOutput(act Action) bool {
+	if ok, _ := act.Group(); ok {
+		return true
+	}
+
+	return act.OutPort() != (OutPort{}) || len(act.Experimenters()) > 0
+}
+
+// InstructionBuilder builds an Instruction by chaining the instructions, e.g.,
+//
+//	inst, err := openflow.NewInstructionBuilder(f).ApplyActions(action).WriteMetadata(1, 0xFF).GotoTable(2).Build()
+//
+// The switch executes the instructions in the order of Meter, ApplyActions, WriteActions,
+// WriteMetadata and GotoTable regardless of the order in which they are chained.
+type InstructionBuilder struct {
+	factory Factory
+	// First error of the chained instructions.
+	err error
+	set InstructionSet
+}
+
+func NewInstructionBuilder(f Factory) *InstructionBuilder {
+	if f == nil {
+		panic("factory is nil")
+	}
+
+	return &InstructionBuilder{factory: f}
+}
+
+func (r *InstructionBuilder) fail(err error) *InstructionBuilder {
+	if r.err == nil {
+		r.err = err
+	}
+	return r
+}
+
+// Meter applies the meter whose ID is id to the packets before the actions.
+func (r *InstructionBuilder) Meter(id uint32) *InstructionBuilder {
+	if r.set.Meter != 0 {
+		return r.fail(errors.New("Meter: duplicated meter"))
+	}
+	if id == 0 {
+		return r.fail(errors.New("Meter: invalid meter ID"))
+	}
+	r.set.Meter = id
+	return r
+}
+
+// ApplyActions applies act to the packet immediately.
+func (r *InstructionBuilder) ApplyActions(act Action) *InstructionBuilder {
+	if r.set.Apply != nil {
+		return r.fail(errors.New("ApplyActions: duplicated actions"))
+	}
+	if act == nil {
+		return r.fail(errors.New("ApplyActions: nil action"))
+	}
+	r.set.Apply = act
+	return r
+}
+
+// WriteActions merges act into the action set of the packet, which is executed at the end
+// of the pipeline.
+func (r *InstructionBuilder) WriteActions(act Action) *InstructionBuilder {
+	if r.set.Write != nil {
+		return r.fail(errors.New("WriteActions: duplicated actions"))
+	}
+	if act == nil {
+		return r.fail(errors.New("WriteActions: nil action"))
+	}
+	r.set.Write = act
+	return r
+}
+
+// WriteMetadata writes the bits of metadata selected by mask to the metadata of the packet,
+// which the next tables can match.
+func (r *InstructionBuilder) WriteMetadata(metadata, mask uint64) *InstructionBuilder {
+	if r.set.MetadataMask != 0 {
+		return r.fail(errors.New("WriteMetadata: duplicated metadata"))
+	}
+	if mask == 0 {
+		return r.fail(errors.New("WriteMetadata: empty mask"))
+	}
+	r.set.Metadata = metadata
+	r.set.MetadataMask = mask
+	return r
+}
+
+// GotoTable continues processing the packet on the table whose ID is tableID.
+func (r *InstructionBuilder) GotoTable(tableID uint8) *InstructionBuilder {
+	if r.set.Goto {
+		return r.fail(errors.New("GotoTable: duplicated table"))
+	}
+	r.set.Goto = true
+	r.set.GotoTable = tableID
+	return r
+}
+
+// Build validates the chained instructions and returns the instruction.
+func (r *InstructionBuilder) Build() (Instruction, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	if r.set.Apply == nil && r.set.Write == nil && r.set.MetadataMask == 0 && !r.set.Goto {
+		return nil, errors.New("empty instruction")
+	}
+	if !r.set.Goto {
+		// The packet would be dropped by the output to port 0.
+		if r.set.Apply != nil && !hasOutput(r.set.Apply) && r.set.Write == nil {
+			return nil, errors.New("ApplyActions: missing output without GotoTable")
+		}
+		if r.set.Write != nil && !hasOutput(r.set.Write) {
+			return nil, errors.New("WriteActions: missing output without GotoTable")
+		}
+	}
+
+	inst, err := r.factory.NewInstruction()
+	if err != nil {
+		return nil, err
+	}
+	inst.SetInstructions(r.set)
+	if err := inst.Error(); err != nil {
+		return nil, err
+	}
+	if _, err := inst.MarshalBinary(); err != nil {
+		return nil, err
+	}
+
+	return inst, nil
+}
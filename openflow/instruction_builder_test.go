@@ -0,0 +1,129 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package openflow_test
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/superkkt/cherry/openflow"
+	"github.com/superkkt/cherry/openflow/of10"
+	"github.com/superkkt/cherry/openflow/of13"
+)
+
+func TestActionBuilder(t *testing.T) {
+	f := of13.NewFactory()
+	// Chained regardless of the order of execution.
+	action, err := openflow.NewActionBuilder(f).Output(2).SetQueue(1).PushVLAN(0x8100).PopVLAN().Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := action.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "0012000800000000" + "0011000881000000" + "0015000800000001" + "0000001000000002ffff000000000000"
+	if v := hex.EncodeToString(data); v != expected {
+		t.Fatalf("unexpected action: expected=%v, got=%v", expected, v)
+	}
+
+	decoded, err := f.NewAction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if ok, ethType := decoded.PushVLAN(); !decoded.PopVLAN() || !ok || ethType != 0x8100 {
+		t.Fatalf("unexpected decoded VLAN actions: pop=%v, push=%v/%v", decoded.PopVLAN(), ok, ethType)
+	}
+
+	// OpenFlow 1.0 strips the tag.
+	action, err = openflow.NewActionBuilder(of10.NewFactory()).PopVLAN().Output(2).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err = action.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := hex.EncodeToString(data); v != "0003000800000000"+"000000080002ffff" {
+		t.Fatalf("unexpected of10 action: %v", v)
+	}
+
+	for i, v := range []*openflow.ActionBuilder{
+		openflow.NewActionBuilder(f).Output(1).Output(2),
+		openflow.NewActionBuilder(f).SetQueue(1),
+		openflow.NewActionBuilder(f).PushVLAN(0x0800),
+		openflow.NewActionBuilder(f).Output(0),
+		openflow.NewActionBuilder(of10.NewFactory()).PushVLAN(0x8100).Output(1),
+		openflow.NewActionBuilder(of10.NewFactory()).Group(1),
+	} {
+		if _, err := v.Build(); err == nil {
+			t.Fatalf("#%v: expected an error", i)
+		}
+	}
+}
+
+func TestInstructionBuilder(t *testing.T) {
+	f := of13.NewFactory()
+	pop, err := openflow.NewActionBuilder(f).PopVLAN().Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	inst, err := openflow.NewInstructionBuilder(f).GotoTable(2).WriteMetadata(0x1, 0xFF).ApplyActions(pop).Meter(3).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := inst.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "0006000800000003" + "00040010000000000012000800000000" +
+		"00020018000000000000000000000001" + "00000000000000ff" + "0001000802000000"
+	if v := hex.EncodeToString(data); v != expected {
+		t.Fatalf("unexpected instruction: expected=%v, got=%v", expected, v)
+	}
+
+	// The action set outputs the packet.
+	output, err := openflow.NewActionBuilder(f).Output(1).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := openflow.NewInstructionBuilder(f).ApplyActions(pop).WriteActions(output).Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, v := range []*openflow.InstructionBuilder{
+		openflow.NewInstructionBuilder(f),
+		openflow.NewInstructionBuilder(f).Meter(1),
+		openflow.NewInstructionBuilder(f).ApplyActions(pop),
+		openflow.NewInstructionBuilder(f).WriteActions(pop).GotoTable(1).GotoTable(2),
+		openflow.NewInstructionBuilder(f).WriteMetadata(1, 0).GotoTable(1),
+		openflow.NewInstructionBuilder(f).GotoTable(0xFF),
+		openflow.NewInstructionBuilder(of10.NewFactory()).ApplyActions(output).GotoTable(1),
+	} {
+		if _, err := v.Build(); err == nil {
+			t.Fatalf("#%v: expected an error", i)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package openflow_test
+
+import (
+	"encoding/hex"
+	"net"
+	"testing"
+
+	"github.com/superkkt/cherry/openflow"
+	"github.com/superkkt/cherry/openflow/of10"
+	"github.com/superkkt/cherry/openflow/of13"
+)
+
+func TestIPv6Match(t *testing.T) {
+	f := of13.NewFactory()
+	_, network, _ := net.ParseCIDR("2001:db8::/64")
+	target := net.ParseIP("2001:db8::2")
+	match, err := openflow.NewMatchBuilder(f).
+		IPv6NDTarget(target).
+		ICMPv6Type(135).
+		IPv6FlowLabel(0x12345).
+		IPv6Src(network).
+		IPProto(58).
+		EthType(0x86DD).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := match.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "00010054" +
+		"80000a02" + "86dd" + // eth_type
+		"80001401" + "3a" + // ip_proto
+		"80003520" + "20010db8000000000000000000000000" + "ffffffffffffffff0000000000000000" + // ipv6_src
+		"80003804" + "00012345" + // ipv6_flabel
+		"80003a01" + "87" + // icmpv6_type
+		"80003e10" + "20010db8000000000000000000000002" + // ipv6_nd_target
+		"00000000" // padding
+	if v := hex.EncodeToString(data); v != expected {
+		t.Fatalf("unexpected match:\nexpected=%v\ngot=     %v", expected, v)
+	}
+
+	decoded, err := f.NewMatch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if v := decoded.SrcIP(); v.String() != network.String() {
+		t.Fatalf("unexpected source IP: %v", v)
+	}
+	if wildcard, label := decoded.IPv6FlowLabel(); wildcard || label != 0x12345 {
+		t.Fatalf("unexpected flow label: %v", label)
+	}
+	if wildcard, typ := decoded.ICMPv6Type(); wildcard || typ != 135 {
+		t.Fatalf("unexpected ICMPv6 type: %v", typ)
+	}
+	if wildcard, ip := decoded.IPv6NDTarget(); wildcard || !ip.Equal(target) {
+		t.Fatalf("unexpected ND target: %v", ip)
+	}
+
+	// TCP ports of IPv6.
+	if _, err := openflow.NewMatchBuilder(f).EthType(0x86DD).IPProto(6).TCPDst(80).IPv6Dst(network).Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	_, ipv4, _ := net.ParseCIDR("10.0.0.0/8")
+	for i, v := range []*openflow.MatchBuilder{
+		openflow.NewMatchBuilder(f).IPv6Src(network),
+		openflow.NewMatchBuilder(f).EthType(0x0800).IPv6Src(network),
+		openflow.NewMatchBuilder(f).EthType(0x86DD).IPv4Src(ipv4),
+		openflow.NewMatchBuilder(f).EthType(0x86DD).IPv6Src(ipv4),
+		openflow.NewMatchBuilder(f).EthType(0x86DD).IPv6FlowLabel(0x100000),
+		openflow.NewMatchBuilder(f).EthType(0x86DD).ICMPv6Type(135),
+		openflow.NewMatchBuilder(f).EthType(0x86DD).IPProto(6).ICMPv6Type(135),
+		openflow.NewMatchBuilder(f).EthType(0x86DD).IPProto(58).IPv6NDTarget(target),
+		openflow.NewMatchBuilder(f).EthType(0x86DD).IPProto(58).ICMPv6Type(128).IPv6NDTarget(target),
+		openflow.NewMatchBuilder(f).EthType(0x86DD).IPProto(58).ICMPv6Type(136).IPv6NDTarget(net.ParseIP("10.0.0.1")),
+		openflow.NewMatchBuilder(of10.NewFactory()).EthType(0x86DD).IPv6Src(network),
+		openflow.NewMatchBuilder(of10.NewFactory()).EthType(0x86DD).IPv6FlowLabel(1),
+	} {
+		if _, err := v.Build(); err == nil {
+			t.Fatalf("#%v: expected an error", i)
+		}
+	}
+}
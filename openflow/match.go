@@ -26,6 +26,19 @@ import (
 	"net"
 )
 
+// OXM is a match field whose class is not OFPXMC_OPENFLOW_BASIC, such as the Nicira extended
+// match (NXM) fields. OpenFlow 1.0 does not support them.
+type OXM struct {
+	Class uint16
+	Field uint8
+	// Experimenter ID of the OFPXMC_EXPERIMENTER class (0xFFFF), which precedes the value and
+	// mask in the wire format. It is ignored for the other classes.
+	Experimenter uint32
+	Value        []byte
+	// Mask is nil if the field is not masked. Otherwise, it should have the same length with Value.
+	Mask []byte
+}
+
 type Match interface {
 	DstIP() *net.IPNet
 	DstMAC() (wildcard bool, mac net.HardwareAddr)
@@ -35,21 +48,57 @@ type Match interface {
 	encoding.BinaryUnmarshaler
 	Error() error
 	EtherType() (wildcard bool, etherType uint16)
+	// Extensions returns the OXM fields of the non-basic classes
+	Extensions() []OXM
 	// InPort returns switch port number
 	InPort() (wildcard bool, inport InPort)
+	// IPDSCP returns the 6-bit DSCP value of the IPv4 ToS field or the IPv6 traffic class field
+	IPDSCP() (wildcard bool, dscp uint8)
+	// ICMPv6Type returns the type of the ICMPv6 message
+	ICMPv6Type() (wildcard bool, t uint8)
 	IPProtocol() (wildcard bool, protocol uint8)
+	// IPv6FlowLabel returns the 20-bit flow label of the IPv6 header
+	IPv6FlowLabel() (wildcard bool, label uint32)
+	// IPv6NDTarget returns the target address of the neighbor solicitation or advertisement
+	IPv6NDTarget() (wildcard bool, ip net.IP)
+	// MPLSLabel returns the 20-bit label of the outermost MPLS shim header
+	MPLSLabel() (wildcard bool, label uint32)
+	// MPLSTC returns the 3-bit traffic class of the outermost MPLS shim header
+	MPLSTC() (wildcard bool, tc uint8)
+	// SetDstIP sets the destination IPv4 or IPv6 network according to the Ethernet type
 	SetDstIP(ip *net.IPNet)
 	SetDstMAC(mac net.HardwareAddr)
 	// SetDstPort sets protocol (TCP or UDP) destination port number
 	SetDstPort(p uint16)
 	SetEtherType(t uint16)
+	// SetExtension sets an OXM field of a non-basic class. It replaces the field that has the same class, field number,
+	// and experimenter ID.
+	SetExtension(oxm OXM)
 	// SetInPort sets switch port number
 	SetInPort(port InPort)
+	// SetIPDSCP sets the 6-bit DSCP value of the IPv4 ToS field or the IPv6 traffic class
+	// field. OpenFlow 1.0 only supports IPv4.
+	SetIPDSCP(dscp uint8)
+	// SetICMPv6Type sets the type of the ICMPv6 message, which requires IP protocol 58 (ICMPv6)
+	SetICMPv6Type(t uint8)
 	SetIPProtocol(p uint8)
+	// SetIPv6FlowLabel sets the 20-bit flow label of the IPv6 header
+	SetIPv6FlowLabel(label uint32)
+	// SetIPv6NDTarget sets the target address of the neighbor solicitation (ICMPv6 type 135) or
+	// advertisement (ICMPv6 type 136)
+	SetIPv6NDTarget(ip net.IP)
+	// SetMPLSLabel sets the 20-bit label of the outermost MPLS shim header
+	SetMPLSLabel(label uint32)
+	// SetMPLSTC sets the 3-bit traffic class of the outermost MPLS shim header
+	SetMPLSTC(tc uint8)
+	// SetSrcIP sets the source IPv4 or IPv6 network according to the Ethernet type
 	SetSrcIP(ip *net.IPNet)
 	SetSrcMAC(mac net.HardwareAddr)
 	// SetSrcPort sets protocol (TCP or UDP) source port number
 	SetSrcPort(p uint16)
+	// SetTunnelID sets the tunnel ID, e.g., the VNI of VXLAN or the key of GRE, of the packet
+	// received from a tunnel port. The bits of id whose mask bits are 0 are wildcarded.
+	SetTunnelID(id, mask uint64)
 	SetVLANID(id uint16)
 	SetVLANPriority(p uint8)
 	SetWildcardEtherType()
@@ -59,15 +108,23 @@ type Match interface {
 	SetWildcardSrcMAC()
 	// SetWildcardSrcPort sets protocol (TCP or UDP) source port number as a wildcard
 	SetWildcardSrcPort()
+	SetWildcardTunnelID()
 	// SetWildcardInPort sets switch port number as a wildcard
 	SetWildcardInPort()
+	SetWildcardIPDSCP()
+	SetWildcardICMPv6Type()
 	SetWildcardIPProtocol()
+	SetWildcardIPv6FlowLabel()
+	SetWildcardIPv6NDTarget()
+	SetWildcardMPLSLabel()
+	SetWildcardMPLSTC()
 	SetWildcardVLANID()
 	SetWildcardVLANPriority()
 	SrcIP() *net.IPNet
 	SrcMAC() (wildcard bool, mac net.HardwareAddr)
 	// SrcPort returns protocol (TCP or UDP) source port number
 	SrcPort() (wildcard bool, port uint16)
+	TunnelID() (wildcard bool, id, mask uint64)
 	VLANID() (wildcard bool, vlanID uint16)
 	VLANPriority() (wildcard bool, priority uint8)
 }
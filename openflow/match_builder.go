@@ -0,0 +1,402 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package openflow
+
+import (
+	"net"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// Match fields of MatchBuilder in the order that they are set on a Match, so that the
+// prerequisites of a field are set before the field.
+type matchField int
+
+const (
+	fieldInPort matchField = iota
+	fieldEthDst
+	fieldEthSrc
+	fieldEthType
+	fieldVLANID
+	fieldVLANPriority
+	fieldIPDSCP
+	fieldIPProto
+	fieldIPv4Src
+	fieldIPv4Dst
+	fieldTCPSrc
+	fieldTCPDst
+	fieldUDPSrc
+	fieldUDPDst
+	fieldMPLSLabel
+	fieldMPLSTC
+	fieldIPv6Src
+	fieldIPv6Dst
+	fieldIPv6FLabel
+	fieldICMPv6Type
+	fieldIPv6NDTarget
+	fieldTunnelID
+)
+
+var matchFieldNames = map[matchField]string{
+	fieldInPort:       "in_port",
+	fieldEthDst:       "eth_dst",
+	fieldEthSrc:       "eth_src",
+	fieldEthType:      "eth_type",
+	fieldVLANID:       "vlan_vid",
+	fieldVLANPriority: "vlan_pcp",
+	fieldIPDSCP:       "ip_dscp",
+	fieldIPProto:      "ip_proto",
+	fieldIPv4Src:      "ipv4_src",
+	fieldIPv4Dst:      "ipv4_dst",
+	fieldTCPSrc:       "tcp_src",
+	fieldTCPDst:       "tcp_dst",
+	fieldUDPSrc:       "udp_src",
+	fieldUDPDst:       "udp_dst",
+	fieldMPLSLabel:    "mpls_label",
+	fieldMPLSTC:       "mpls_tc",
+	fieldIPv6Src:      "ipv6_src",
+	fieldIPv6Dst:      "ipv6_dst",
+	fieldIPv6FLabel:   "ipv6_flabel",
+	fieldICMPv6Type:   "icmpv6_type",
+	fieldIPv6NDTarget: "ipv6_nd_target",
+	fieldTunnelID:     "tunnel_id",
+}
+
+func (r matchField) String() string {
+	return matchFieldNames[r]
+}
+
+// MatchBuilder builds a Match by chaining the fields, e.g.,
+//
+//	match, err := openflow.NewMatchBuilder(f).InPort(1).EthType(0x0800).IPv4Src(network).Build()
+//
+// The fields can be chained in any order. Build validates the prerequisites of the fields,
+// e.g., ip_proto requires eth_type of IPv4 or IPv6, and then returns the match that is
+// encoded in the wire format of the factory, e.g., the OXM fields padded to the 8-byte
+// boundary.
+type MatchBuilder struct {
+	factory Factory
+	// First error of the chained fields.
+	err     error
+	fields  map[matchField]func(Match)
+	ext     []OXM
+	ethType uint16
+	ipProto uint8
+
+	icmpv6Type uint8
+}
+
+func NewMatchBuilder(f Factory) *MatchBuilder {
+	if f == nil {
+		panic("factory is nil")
+	}
+
+	return &MatchBuilder{
+		factory: f,
+		fields:  make(map[matchField]func(Match)),
+	}
+}
+
+func (r *MatchBuilder) set(field matchField, setter func(Match)) *MatchBuilder {
+	r.fields[field] = setter
+	return r
+}
+
+func (r *MatchBuilder) fail(field matchField, err error) *MatchBuilder {
+	if r.err == nil {
+		r.err = errors.Wrap(err, field.String())
+	}
+	return r
+}
+
+func (r *MatchBuilder) InPort(port uint32) *MatchBuilder {
+	return r.set(fieldInPort, func(m Match) {
+		inport := NewInPort()
+		inport.SetValue(port)
+		m.SetInPort(inport)
+	})
+}
+
+func (r *MatchBuilder) EthDst(mac net.HardwareAddr) *MatchBuilder {
+	if len(mac) != 6 {
+		return r.fail(fieldEthDst, ErrInvalidMACAddress)
+	}
+	return r.set(fieldEthDst, func(m Match) { m.SetDstMAC(mac) })
+}
+
+func (r *MatchBuilder) EthSrc(mac net.HardwareAddr) *MatchBuilder {
+	if len(mac) != 6 {
+		return r.fail(fieldEthSrc, ErrInvalidMACAddress)
+	}
+	return r.set(fieldEthSrc, func(m Match) { m.SetSrcMAC(mac) })
+}
+
+func (r *MatchBuilder) EthType(t uint16) *MatchBuilder {
+	r.ethType = t
+	return r.set(fieldEthType, func(m Match) { m.SetEtherType(t) })
+}
+
+func (r *MatchBuilder) VLANID(id uint16) *MatchBuilder {
+	// 12-bit VLAN ID
+	if id > 0xFFF {
+		return r.fail(fieldVLANID, errors.New("invalid VLAN ID"))
+	}
+	return r.set(fieldVLANID, func(m Match) { m.SetVLANID(id) })
+}
+
+func (r *MatchBuilder) VLANPriority(p uint8) *MatchBuilder {
+	// 3-bit priority code point
+	if p > 0x7 {
+		return r.fail(fieldVLANPriority, errors.New("invalid VLAN priority"))
+	}
+	return r.set(fieldVLANPriority, func(m Match) { m.SetVLANPriority(p) })
+}
+
+// IPDSCP matches the 6-bit DSCP value of the IP ToS field.
+func (r *MatchBuilder) IPDSCP(dscp uint8) *MatchBuilder {
+	if dscp > 0x3F {
+		return r.fail(fieldIPDSCP, ErrInvalidDSCP)
+	}
+	return r.set(fieldIPDSCP, func(m Match) { m.SetIPDSCP(dscp) })
+}
+
+func (r *MatchBuilder) IPProto(p uint8) *MatchBuilder {
+	r.ipProto = p
+	return r.set(fieldIPProto, func(m Match) { m.SetIPProtocol(p) })
+}
+
+func (r *MatchBuilder) IPv4Src(ip *net.IPNet) *MatchBuilder {
+	if ip == nil || ip.IP.To4() == nil {
+		return r.fail(fieldIPv4Src, ErrInvalidIPAddress)
+	}
+	return r.set(fieldIPv4Src, func(m Match) { m.SetSrcIP(ip) })
+}
+
+func (r *MatchBuilder) IPv4Dst(ip *net.IPNet) *MatchBuilder {
+	if ip == nil || ip.IP.To4() == nil {
+		return r.fail(fieldIPv4Dst, ErrInvalidIPAddress)
+	}
+	return r.set(fieldIPv4Dst, func(m Match) { m.SetDstIP(ip) })
+}
+
+func (r *MatchBuilder) TCPSrc(port uint16) *MatchBuilder {
+	return r.set(fieldTCPSrc, func(m Match) { m.SetSrcPort(port) })
+}
+
+func (r *MatchBuilder) TCPDst(port uint16) *MatchBuilder {
+	return r.set(fieldTCPDst, func(m Match) { m.SetDstPort(port) })
+}
+
+func (r *MatchBuilder) UDPSrc(port uint16) *MatchBuilder {
+	return r.set(fieldUDPSrc, func(m Match) { m.SetSrcPort(port) })
+}
+
+func (r *MatchBuilder) UDPDst(port uint16) *MatchBuilder {
+	return r.set(fieldUDPDst, func(m Match) { m.SetDstPort(port) })
+}
+
+func (r *MatchBuilder) IPv6Src(ip *net.IPNet) *MatchBuilder {
+	if ip == nil || ip.IP.To16() == nil || ip.IP.To4() != nil {
+		return r.fail(fieldIPv6Src, ErrInvalidIPAddress)
+	}
+	return r.set(fieldIPv6Src, func(m Match) { m.SetSrcIP(ip) })
+}
+
+func (r *MatchBuilder) IPv6Dst(ip *net.IPNet) *MatchBuilder {
+	if ip == nil || ip.IP.To16() == nil || ip.IP.To4() != nil {
+		return r.fail(fieldIPv6Dst, ErrInvalidIPAddress)
+	}
+	return r.set(fieldIPv6Dst, func(m Match) { m.SetDstIP(ip) })
+}
+
+// IPv6FlowLabel matches the 20-bit flow label of the IPv6 header.
+func (r *MatchBuilder) IPv6FlowLabel(label uint32) *MatchBuilder {
+	if label > 0xFFFFF {
+		return r.fail(fieldIPv6FLabel, ErrInvalidFlowLabel)
+	}
+	return r.set(fieldIPv6FLabel, func(m Match) { m.SetIPv6FlowLabel(label) })
+}
+
+func (r *MatchBuilder) ICMPv6Type(t uint8) *MatchBuilder {
+	r.icmpv6Type = t
+	return r.set(fieldICMPv6Type, func(m Match) { m.SetICMPv6Type(t) })
+}
+
+// IPv6NDTarget matches the target address of the neighbor solicitation or advertisement.
+func (r *MatchBuilder) IPv6NDTarget(ip net.IP) *MatchBuilder {
+	if ip.To16() == nil || ip.To4() != nil {
+		return r.fail(fieldIPv6NDTarget, ErrInvalidIPAddress)
+	}
+	return r.set(fieldIPv6NDTarget, func(m Match) { m.SetIPv6NDTarget(ip) })
+}
+
+// TunnelID matches the tunnel ID, e.g., the VNI of VXLAN or the key of GRE, of the packet
+// received from a tunnel port. The bits of id whose mask bits are 0 are wildcarded.
+func (r *MatchBuilder) TunnelID(id, mask uint64) *MatchBuilder {
+	if mask == 0 {
+		return r.fail(fieldTunnelID, errors.New("zero mask"))
+	}
+	return r.set(fieldTunnelID, func(m Match) { m.SetTunnelID(id, mask) })
+}
+
+// MPLSLabel matches the 20-bit label of the outermost MPLS shim header.
+func (r *MatchBuilder) MPLSLabel(label uint32) *MatchBuilder {
+	if label > 0xFFFFF {
+		return r.fail(fieldMPLSLabel, ErrInvalidMPLSLabel)
+	}
+	return r.set(fieldMPLSLabel, func(m Match) { m.SetMPLSLabel(label) })
+}
+
+// MPLSTC matches the 3-bit traffic class of the outermost MPLS shim header.
+func (r *MatchBuilder) MPLSTC(tc uint8) *MatchBuilder {
+	if tc > 0x7 {
+		return r.fail(fieldMPLSTC, ErrInvalidMPLSTC)
+	}
+	return r.set(fieldMPLSTC, func(m Match) { m.SetMPLSTC(tc) })
+}
+
+// Extension matches an OXM field of a non-basic class, which OpenFlow 1.0 does not support.
+func (r *MatchBuilder) Extension(oxm OXM) *MatchBuilder {
+	r.ext = append(r.ext, oxm)
+	return r
+}
+
+func (r *MatchBuilder) has(fields ...matchField) bool {
+	for _, v := range fields {
+		if _, ok := r.fields[v]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// first returns the first field of fields that has been chained.
+func (r *MatchBuilder) first(fields ...matchField) matchField {
+	for _, v := range fields {
+		if _, ok := r.fields[v]; ok {
+			return v
+		}
+	}
+	panic("no chained field")
+}
+
+func containsEtherType(types []uint16, t uint16) bool {
+	for _, v := range types {
+		if v == t {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validate checks the prerequisites of the chained fields.
+func (r *MatchBuilder) validate() error {
+	for _, v := range []struct {
+		ethTypes []uint16
+		fields   []matchField
+	}{
+		{[]uint16{0x0800, 0x86DD}, []matchField{fieldIPDSCP, fieldIPProto}},
+		{[]uint16{0x0800}, []matchField{fieldIPv4Src, fieldIPv4Dst}},
+		{[]uint16{0x86DD}, []matchField{fieldIPv6Src, fieldIPv6Dst, fieldIPv6FLabel}},
+		{[]uint16{0x8847, 0x8848}, []matchField{fieldMPLSLabel, fieldMPLSTC}},
+	} {
+		if !r.has(v.fields...) {
+			continue
+		}
+		field := r.first(v.fields...)
+		if !r.has(fieldEthType) {
+			return errors.Wrap(ErrMissingEtherType, field.String())
+		}
+		if !containsEtherType(v.ethTypes, r.ethType) {
+			return errors.Wrap(ErrUnsupportedEtherType, field.String())
+		}
+	}
+
+	for _, v := range []struct {
+		proto  uint8
+		fields []matchField
+	}{
+		{0x06, []matchField{fieldTCPSrc, fieldTCPDst}},
+		{0x11, []matchField{fieldUDPSrc, fieldUDPDst}},
+		{0x3A, []matchField{fieldICMPv6Type}},
+	} {
+		if !r.has(v.fields...) {
+			continue
+		}
+		field := r.first(v.fields...)
+		if !r.has(fieldIPProto) {
+			return errors.Wrap(ErrMissingIPProtocol, field.String())
+		}
+		if r.ipProto != v.proto {
+			return errors.Wrap(ErrUnsupportedIPProtocol, field.String())
+		}
+	}
+
+	if r.has(fieldVLANPriority) && !r.has(fieldVLANID) {
+		return errors.New("vlan_pcp: missing VLAN ID")
+	}
+	// Neighbor Solicitation or Advertisement?
+	if r.has(fieldIPv6NDTarget) && (!r.has(fieldICMPv6Type) || (r.icmpv6Type != 135 && r.icmpv6Type != 136)) {
+		return errors.New("ipv6_nd_target: ICMPv6 type is not a neighbor solicitation or advertisement")
+	}
+
+	return nil
+}
+
+// Build validates the chained fields and returns the match. The match is checked to be
+// encoded successfully.
+func (r *MatchBuilder) Build() (Match, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	if err := r.validate(); err != nil {
+		return nil, err
+	}
+
+	match, err := r.factory.NewMatch()
+	if err != nil {
+		return nil, err
+	}
+	fields := make([]int, 0, len(r.fields))
+	for v := range r.fields {
+		fields = append(fields, int(v))
+	}
+	// The prerequisites precede their dependents.
+	sort.Ints(fields)
+	for _, v := range fields {
+		r.fields[matchField(v)](match)
+	}
+	for _, v := range r.ext {
+		match.SetExtension(v)
+	}
+	if err := match.Error(); err != nil {
+		return nil, err
+	}
+	if _, err := match.MarshalBinary(); err != nil {
+		return nil, err
+	}
+
+	return match, nil
+}
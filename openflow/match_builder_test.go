@@ -0,0 +1,111 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package openflow_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"testing"
+
+	"github.com/superkkt/cherry/openflow"
+	"github.com/superkkt/cherry/openflow/of10"
+	"github.com/superkkt/cherry/openflow/of13"
+
+	"github.com/pkg/errors"
+)
+
+func TestMatchBuilder(t *testing.T) {
+	_, network, _ := net.ParseCIDR("10.0.0.0/8")
+	for _, f := range []openflow.Factory{of13.NewFactory(), of10.NewFactory()} {
+		// Chained regardless of the prerequisites.
+		built, err := openflow.NewMatchBuilder(f).TCPDst(80).IPv4Src(network).IPProto(6).EthType(0x0800).InPort(1).Build()
+		if err != nil {
+			t.Fatalf("v%v: %v", f.ProtocolVersion(), err)
+		}
+
+		expected, err := f.NewMatch()
+		if err != nil {
+			t.Fatal(err)
+		}
+		inport := openflow.NewInPort()
+		inport.SetValue(1)
+		expected.SetInPort(inport)
+		expected.SetEtherType(0x0800)
+		expected.SetIPProtocol(6)
+		expected.SetSrcIP(network)
+		expected.SetDstPort(80)
+
+		v1, err := built.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		v2, err := expected.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(v1, v2) {
+			t.Fatalf("v%v: unexpected match: expected=%x, got=%x", f.ProtocolVersion(), v2, v1)
+		}
+	}
+
+	// OXM fields are padded to the 8-byte boundary, and the length excludes the padding.
+	match, err := openflow.NewMatchBuilder(of13.NewFactory()).EthType(0x0800).IPProto(17).UDPSrc(53).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := match.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Header (4) + eth_type (6) + ip_proto (5) + udp_src (6)
+	if len(data) != 24 || binary.BigEndian.Uint16(data[2:4]) != 21 {
+		t.Fatalf("unexpected OXM length: %x", data)
+	}
+}
+
+func TestMatchBuilderPrerequisites(t *testing.T) {
+	f := of13.NewFactory()
+	_, network, _ := net.ParseCIDR("10.0.0.0/8")
+	tests := []struct {
+		builder *openflow.MatchBuilder
+		err     error
+	}{
+		{openflow.NewMatchBuilder(f).IPProto(6), openflow.ErrMissingEtherType},
+		{openflow.NewMatchBuilder(f).EthType(0x0806).IPv4Dst(network), openflow.ErrUnsupportedEtherType},
+		{openflow.NewMatchBuilder(f).EthType(0x0800).TCPSrc(22), openflow.ErrMissingIPProtocol},
+		{openflow.NewMatchBuilder(f).EthType(0x0800).IPProto(6).UDPDst(53), openflow.ErrUnsupportedIPProtocol},
+		{openflow.NewMatchBuilder(f).EthType(0x0800).IPDSCP(64), openflow.ErrInvalidDSCP},
+		{openflow.NewMatchBuilder(f).EthSrc(net.HardwareAddr{1, 2, 3}), openflow.ErrInvalidMACAddress},
+		{openflow.NewMatchBuilder(f).EthType(0x0800).IPv4Src(&net.IPNet{IP: net.ParseIP("2001:db8::1")}), openflow.ErrInvalidIPAddress},
+		{openflow.NewMatchBuilder(f).VLANPriority(3), nil},
+	}
+	for i, v := range tests {
+		_, err := v.builder.Build()
+		if err == nil {
+			t.Fatalf("#%v: expected an error", i)
+		}
+		if v.err != nil && errors.Cause(err) != v.err {
+			t.Fatalf("#%v: expected %v, got %v", i, v.err, err)
+		}
+	}
+}
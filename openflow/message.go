@@ -53,6 +53,12 @@ func (r *Message) Version() uint8 {
 	return r.version
 }
 
+// SetVersion changes the protocol version of the message. It lets a newer protocol reuse
+// the messages of an older one whose wire format has not been changed.
+func (r *Message) SetVersion(version uint8) {
+	r.version = version
+}
+
 func (r *Message) Type() uint8 {
 	return r.msgType
 }
@@ -108,7 +114,12 @@ func (r *Message) UnmarshalBinary(data []byte) error {
 		return ErrInvalidPacketLength
 	}
 	r.xid = binary.BigEndian.Uint32(data[4:8])
-	r.payload = data[8:r.length]
+	end := int(r.length)
+	// A reassembled multipart reply can be larger than the 16-bit length field.
+	if r.length == 0xFFFF {
+		end = len(data)
+	}
+	r.payload = data[8:end]
 
 	return nil
 }
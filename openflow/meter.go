@@ -0,0 +1,76 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package openflow
+
+import (
+	"encoding"
+)
+
+type MeterModCmd uint8
+
+const (
+	MeterAdd MeterModCmd = iota
+	MeterModify
+	MeterDelete
+)
+
+type MeterBandType uint8
+
+const (
+	// Drop the packets exceeding the band rate.
+	MeterBandDrop MeterBandType = iota
+	// Increase the drop precedence of the DSCP field of the packets exceeding
+	// the band rate.
+	MeterBandDSCPRemark
+)
+
+// MeterBand is a rate band of a meter. The band whose rate is the highest
+// among the bands whose rate is lower than the measured rate is applied.
+type MeterBand struct {
+	Type MeterBandType
+	// Rate is in kilobits per second, or packets per second if the meter measures the packet rate.
+	Rate uint32
+	// BurstSize is only used when the burst is enabled on the meter.
+	BurstSize uint32
+	// PrecLevel is the amount to be added to the drop precedence of DSCP.
+	// It is only used by the DSCP remark bands.
+	PrecLevel uint8
+}
+
+type MeterMod interface {
+	AddBand(b MeterBand)
+	Bands() []MeterBand
+	// Burst returns whether the burst sizes of the bands are applied.
+	Burst() bool
+	Command() MeterModCmd
+	encoding.BinaryMarshaler
+	encoding.BinaryUnmarshaler
+	Error() error
+	Header
+	MeterID() uint32
+	// PacketRate returns whether the rates of the bands are in packets per second
+	// instead of kilobits per second.
+	PacketRate() bool
+	SetBurst(burst bool)
+	SetMeterID(id uint32)
+	SetPacketRate(pktps bool)
+}
@@ -0,0 +1,91 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package openflow_test
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/superkkt/cherry/openflow/of10"
+	"github.com/superkkt/cherry/openflow/of13"
+)
+
+func TestMeterInstruction(t *testing.T) {
+	f := of13.NewFactory()
+	inst, err := f.NewInstruction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	inst.ApplyAction(outputAction(t, f, 1))
+	inst.SetMeter(5)
+	v, err := inst.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// OFPIT_METER followed by OFPIT_APPLY_ACTIONS.
+	expected := "0006000800000005" + "0004001800000000" + "0000001000000001ffff000000000000"
+	if hex.EncodeToString(v) != expected {
+		t.Fatalf("unexpected encoding: %x", v)
+	}
+
+	inst.SetMeter(0)
+	if inst.Error() == nil {
+		t.Fatal("expected an error for the zero meter ID")
+	}
+
+	inst = new(of10.Instruction)
+	inst.SetMeter(5)
+	if inst.Error() == nil {
+		t.Fatal("expected an error for the meter of OpenFlow 1.0")
+	}
+}
+
+func TestApplyActionAndGotoTable(t *testing.T) {
+	f := of13.NewFactory()
+	action, err := f.NewAction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	action.SetIPDSCP(46)
+	action.SetQueue(2)
+	inst, err := f.NewInstruction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	inst.ApplyActionAndGotoTable(action, 1)
+	inst.SetMeter(3)
+	v, err := inst.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// OFPIT_METER, OFPIT_APPLY_ACTIONS without the output, and then OFPIT_GOTO_TABLE.
+	expected := "0006000800000003" + "0004002000000000" + "00190010800010012e00000000000000" + "0015000800000002" + "0001000801000000"
+	if hex.EncodeToString(v) != expected {
+		t.Fatalf("unexpected encoding: %x", v)
+	}
+
+	inst = new(of10.Instruction)
+	inst.ApplyActionAndGotoTable(action, 1)
+	if inst.Error() == nil {
+		t.Fatal("expected an error for the GotoTable of OpenFlow 1.0")
+	}
+}
@@ -0,0 +1,131 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package openflow_test
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/superkkt/cherry/openflow"
+	"github.com/superkkt/cherry/openflow/of10"
+	"github.com/superkkt/cherry/openflow/of13"
+)
+
+func TestMPLSActions(t *testing.T) {
+	f := of13.NewFactory()
+	// Chained regardless of the execution order.
+	action, err := openflow.NewActionBuilder(f).SetMPLSLabel(100).PushMPLS(0x8847).PopVLAN().Output(1).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := action.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "0012000800000000" + // pop_vlan
+		"0013000888470000" + // push_mpls
+		"00190010" + "80004404" + "00000064" + "00000000" + // mpls_label
+		"0000001000000001ffff000000000000"
+	if v := hex.EncodeToString(data); v != expected {
+		t.Fatalf("unexpected action:\nexpected=%v\ngot=     %v", expected, v)
+	}
+
+	action, err = openflow.NewActionBuilder(f).PopMPLS(0x0800).Output(2).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err = action.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := f.NewAction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if ok, ethType := decoded.PopMPLS(); !ok || ethType != 0x0800 {
+		t.Fatalf("unexpected pop MPLS: %v, %#x", ok, ethType)
+	}
+	if ok, _ := decoded.PushMPLS(); ok {
+		t.Fatal("unexpected push MPLS")
+	}
+
+	for i, v := range []*openflow.ActionBuilder{
+		openflow.NewActionBuilder(f).PushMPLS(0x0800).Output(1),
+		openflow.NewActionBuilder(f).PushMPLS(0x8847).PushMPLS(0x8847).Output(1),
+		openflow.NewActionBuilder(f).PopMPLS(0x0800).PopMPLS(0x0800).Output(1),
+		openflow.NewActionBuilder(of10.NewFactory()).PushMPLS(0x8847).Output(1),
+		openflow.NewActionBuilder(of10.NewFactory()).PopMPLS(0x0800).Output(1),
+	} {
+		if _, err := v.Build(); err == nil {
+			t.Fatalf("#%v: expected an error", i)
+		}
+	}
+}
+
+func TestMPLSMatch(t *testing.T) {
+	f := of13.NewFactory()
+	match, err := openflow.NewMatchBuilder(f).MPLSTC(5).MPLSLabel(100).EthType(0x8847).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := match.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "00010017" +
+		"80000a02" + "8847" + // eth_type
+		"80004404" + "00000064" + // mpls_label
+		"80004601" + "05" + // mpls_tc
+		"00" // padding
+	if v := hex.EncodeToString(data); v != expected {
+		t.Fatalf("unexpected match: expected=%v, got=%v", expected, v)
+	}
+
+	decoded, err := f.NewMatch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if wildcard, label := decoded.MPLSLabel(); wildcard || label != 100 {
+		t.Fatalf("unexpected MPLS label: %v", label)
+	}
+	if wildcard, tc := decoded.MPLSTC(); wildcard || tc != 5 {
+		t.Fatalf("unexpected MPLS traffic class: %v", tc)
+	}
+
+	for i, v := range []*openflow.MatchBuilder{
+		openflow.NewMatchBuilder(f).MPLSLabel(100),
+		openflow.NewMatchBuilder(f).EthType(0x0800).MPLSLabel(100),
+		openflow.NewMatchBuilder(f).EthType(0x8847).MPLSLabel(0x100000),
+		openflow.NewMatchBuilder(f).EthType(0x8848).MPLSTC(8),
+		openflow.NewMatchBuilder(of10.NewFactory()).EthType(0x8847).MPLSLabel(100),
+	} {
+		if _, err := v.Build(); err == nil {
+			t.Fatalf("#%v: expected an error", i)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package openflow_test
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/superkkt/cherry/openflow/of13"
+)
+
+// portDescPart returns an OpenFlow 1.3 port description reply that contains
+// the ports numbered from first to first+n-1.
+func portDescPart(xid uint32, first, n int, more bool) []byte {
+	v := make([]byte, 16+n*64)
+	v[0] = 0x04
+	v[1] = of13.OFPT_MULTIPART_REPLY
+	binary.BigEndian.PutUint16(v[2:4], uint16(len(v)))
+	binary.BigEndian.PutUint32(v[4:8], xid)
+	binary.BigEndian.PutUint16(v[8:10], of13.OFPMP_PORT_DESC)
+	if more {
+		binary.BigEndian.PutUint16(v[10:12], of13.OFPMPF_REPLY_MORE)
+	}
+	for i := 0; i < n; i++ {
+		binary.BigEndian.PutUint32(v[16+i*64:], uint32(first+i))
+	}
+
+	return v
+}
+
+func TestMultipartAssembler(t *testing.T) {
+	tests := []struct {
+		name  string
+		parts []int // Number of ports in each part
+	}{
+		{"single", []int{2}},
+		{"fragmented", []int{1, 3, 2}},
+		// Larger than the 16-bit length field of the OpenFlow header.
+		{"oversized", []int{1000, 1000}},
+	}
+
+	for _, test := range tests {
+		assembler := of13.NewMultipartAssembler()
+		var complete []byte
+		first := 1
+		for i, n := range test.parts {
+			more := i < len(test.parts)-1
+			// Interleave an unrelated single part reply.
+			if v, err := assembler.Add(portDescPart(99, 1, 1, false)); err != nil || v == nil {
+				t.Fatalf("%v: unexpected result for the unrelated reply: %v", test.name, err)
+			}
+			v, err := assembler.Add(portDescPart(1, first, n, more))
+			if err != nil {
+				t.Fatalf("%v: failed to add part %v: %v", test.name, i, err)
+			}
+			if more && v != nil {
+				t.Fatalf("%v: reply completed early at part %v", test.name, i)
+			}
+			complete = v
+			first += n
+		}
+		if complete == nil {
+			t.Fatalf("%v: reply is not completed", test.name)
+		}
+
+		reply := new(of13.PortDescReply)
+		if err := reply.UnmarshalBinary(complete); err != nil {
+			t.Fatalf("%v: failed to unmarshal: %v", test.name, err)
+		}
+		ports := reply.Ports()
+		if len(ports) != first-1 {
+			t.Fatalf("%v: expected %v ports, got %v", test.name, first-1, len(ports))
+		}
+		for i, p := range ports {
+			if p.Number() != uint32(i+1) {
+				t.Fatalf("%v: expected port %v, got %v", test.name, i+1, p.Number())
+			}
+		}
+	}
+}
@@ -0,0 +1,323 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+// Package nicira implements the Nicira vendor extensions of OpenFlow that are widely used by
+// Open vSwitch. Importing this package registers the Nicira decoder to the openflow package.
+package nicira
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/superkkt/cherry/openflow"
+)
+
+const (
+	// VendorID is the experimenter ID of Nicira (NX_VENDOR_ID).
+	VendorID = 0x00002320
+)
+
+// Message subtypes
+const (
+	NXT_ROLE_REQUEST      = 10
+	NXT_ROLE_REPLY        = 11
+	NXT_SET_CONTROLLER_ID = 20
+)
+
+// Action subtypes
+const (
+	NXAST_RESUBMIT       = 1
+	NXAST_SET_TUNNEL     = 2
+	NXAST_SET_TUNNEL64   = 9
+	NXAST_RESUBMIT_TABLE = 14
+)
+
+// Roles of the NXT_ROLE_REQUEST and NXT_ROLE_REPLY messages
+const (
+	NX_ROLE_OTHER  = 0
+	NX_ROLE_MASTER = 1
+	NX_ROLE_SLAVE  = 2
+)
+
+// NXM field classes
+const (
+	OFPXMC_NXM_0 = 0x0000
+	OFPXMC_NXM_1 = 0x0001
+)
+
+// NXM_1 fields
+const (
+	NXM_NX_REG0         = 0
+	NXM_NX_TUN_ID       = 16
+	NXM_NX_TUN_IPV4_SRC = 31
+	NXM_NX_TUN_IPV4_DST = 32
+)
+
+const (
+	numRegisters = 8
+	// Table ID of the resubmit action that means the current table.
+	resubmitCurrentTable = 0xFF
+	// InPort is the port number that makes the resubmit action use the ingress port of the packet.
+	// Nicira actions always use the 16-bit port numbers of OpenFlow 1.0 (OFPP_IN_PORT).
+	InPort = 0xFFF8
+)
+
+func init() {
+	openflow.RegisterExperimenter(VendorID, decoder{})
+}
+
+// TunnelID returns the NXM_NX_TUN_ID match field, which is the VNI of VXLAN or the key of GRE.
+func TunnelID(id uint64) openflow.OXM {
+	v := make([]byte, 8)
+	binary.BigEndian.PutUint64(v, id)
+
+	return openflow.OXM{Class: OFPXMC_NXM_1, Field: NXM_NX_TUN_ID, Value: v}
+}
+
+// TunnelIPv4Src returns the NXM_NX_TUN_IPV4_SRC match field, which is the source IPv4 address of the tunnel.
+func TunnelIPv4Src(ip net.IP) (openflow.OXM, error) {
+	return tunnelIPv4(NXM_NX_TUN_IPV4_SRC, ip)
+}
+
+// TunnelIPv4Dst returns the NXM_NX_TUN_IPV4_DST match field, which is the destination IPv4 address of the tunnel.
+func TunnelIPv4Dst(ip net.IP) (openflow.OXM, error) {
+	return tunnelIPv4(NXM_NX_TUN_IPV4_DST, ip)
+}
+
+func tunnelIPv4(field uint8, ip net.IP) (openflow.OXM, error) {
+	v := ip.To4()
+	if v == nil {
+		return openflow.OXM{}, openflow.ErrInvalidIPAddress
+	}
+
+	return openflow.OXM{Class: OFPXMC_NXM_1, Field: field, Value: []byte(v)}, nil
+}
+
+// Register returns the NXM_NX_REGn match field whose value is masked by mask. The registers are
+// 32-bit scratch fields that are initialized to zero when a packet enters the switch.
+func Register(n uint8, value, mask uint32) (openflow.OXM, error) {
+	if n >= numRegisters {
+		return openflow.OXM{}, fmt.Errorf("invalid register number: %v", n)
+	}
+
+	oxm := openflow.OXM{Class: OFPXMC_NXM_1, Field: NXM_NX_REG0 + n, Value: make([]byte, 4)}
+	binary.BigEndian.PutUint32(oxm.Value, value)
+	if mask != 0xFFFFFFFF {
+		oxm.Mask = make([]byte, 4)
+		binary.BigEndian.PutUint32(oxm.Mask, mask)
+	}
+
+	return oxm, nil
+}
+
+// Resubmit is the NXAST_RESUBMIT and NXAST_RESUBMIT_TABLE actions that look up a flow table again
+// as if the packet has been received from InPort.
+type Resubmit struct {
+	// InPort is a 16-bit port number, or nicira.InPort to use the ingress port of the packet.
+	InPort uint16
+	// Table is the flow table to search, or 0xFF to search the current table.
+	Table uint8
+}
+
+// NewResubmit returns an action that searches the current table again as if the packet has been received from port.
+func NewResubmit(port uint16) *Resubmit {
+	return &Resubmit{InPort: port, Table: resubmitCurrentTable}
+}
+
+// NewResubmitTable returns an action that searches table as if the packet has been received from port.
+func NewResubmitTable(port uint16, table uint8) *Resubmit {
+	return &Resubmit{InPort: port, Table: table}
+}
+
+func (r *Resubmit) Experimenter() uint32 {
+	return VendorID
+}
+
+func (r *Resubmit) MarshalBinary() ([]byte, error) {
+	v := make([]byte, 8)
+	subtype := uint16(NXAST_RESUBMIT_TABLE)
+	if r.Table == resubmitCurrentTable {
+		subtype = NXAST_RESUBMIT
+	}
+	binary.BigEndian.PutUint16(v[0:2], subtype)
+	binary.BigEndian.PutUint16(v[2:4], r.InPort)
+	v[4] = r.Table
+	// v[5:8] is padding
+
+	return v, nil
+}
+
+// SetTunnel is the NXAST_SET_TUNNEL64 action that sets the tunnel ID, e.g., the VNI of VXLAN or
+// the key of GRE, of the packet that will be encapsulated by the output tunnel port. It is the
+// equivalent of the set-field action of the tunnel ID for OpenFlow 1.0 switches.
+type SetTunnel struct {
+	ID uint64
+}
+
+func NewSetTunnel(id uint64) *SetTunnel {
+	return &SetTunnel{ID: id}
+}
+
+func (r *SetTunnel) Experimenter() uint32 {
+	return VendorID
+}
+
+func (r *SetTunnel) MarshalBinary() ([]byte, error) {
+	v := make([]byte, 16)
+	binary.BigEndian.PutUint16(v[0:2], NXAST_SET_TUNNEL64)
+	// v[2:8] is padding
+	binary.BigEndian.PutUint64(v[8:16], r.ID)
+
+	return v, nil
+}
+
+// RawAction is a Nicira action that this package does not interpret. It is kept as it is so that
+// the flows read from a switch can be installed again without losing their actions.
+type RawAction struct {
+	Subtype uint16
+	// Data follows the subtype, including the padding.
+	Data []byte
+}
+
+func (r *RawAction) Experimenter() uint32 {
+	return VendorID
+}
+
+func (r *RawAction) MarshalBinary() ([]byte, error) {
+	v := make([]byte, 2+len(r.Data))
+	binary.BigEndian.PutUint16(v[0:2], r.Subtype)
+	copy(v[2:], r.Data)
+
+	return v, nil
+}
+
+func getRole(role openflow.ControllerRole) (uint32, error) {
+	switch role {
+	case openflow.RoleEqual:
+		return NX_ROLE_OTHER, nil
+	case openflow.RoleMaster:
+		return NX_ROLE_MASTER, nil
+	case openflow.RoleSlave:
+		return NX_ROLE_SLAVE, nil
+	default:
+		return 0, fmt.Errorf("unsupported controller role: %v", role)
+	}
+}
+
+// NewRoleRequest returns an NXT_ROLE_REQUEST message, which is the controller role request for
+// OpenFlow 1.0 switches. Unlike OFPT_ROLE_REQUEST, it does not have the generation ID.
+func NewRoleRequest(f openflow.Factory, role openflow.ControllerRole) (openflow.Experimenter, error) {
+	v, err := getRole(role)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := f.NewExperimenter()
+	if err != nil {
+		return nil, err
+	}
+	msg.SetExperimenter(VendorID)
+	msg.SetExpType(NXT_ROLE_REQUEST)
+	data := make([]byte, 4)
+	binary.BigEndian.PutUint32(data, v)
+	msg.SetData(data)
+
+	return msg, nil
+}
+
+// NewSetControllerID returns an NXT_SET_CONTROLLER_ID message that sets the controller ID of this
+// connection, which is included in the asynchronous messages such as PACKET_IN.
+func NewSetControllerID(f openflow.Factory, id uint16) (openflow.Experimenter, error) {
+	msg, err := f.NewExperimenter()
+	if err != nil {
+		return nil, err
+	}
+	msg.SetExperimenter(VendorID)
+	msg.SetExpType(NXT_SET_CONTROLLER_ID)
+	data := make([]byte, 8)
+	// data[0:6] is padding
+	binary.BigEndian.PutUint16(data[6:8], id)
+	msg.SetData(data)
+
+	return msg, nil
+}
+
+// RoleReply is the decoded NXT_ROLE_REPLY message.
+type RoleReply struct {
+	Role openflow.ControllerRole
+}
+
+type decoder struct{}
+
+func (r decoder) DecodeMessage(msg openflow.Experimenter) (interface{}, error) {
+	switch msg.ExpType() {
+	case NXT_ROLE_REPLY:
+		data := msg.Data()
+		if len(data) < 4 {
+			return nil, openflow.ErrInvalidPacketLength
+		}
+		switch binary.BigEndian.Uint32(data[0:4]) {
+		case NX_ROLE_OTHER:
+			return RoleReply{Role: openflow.RoleEqual}, nil
+		case NX_ROLE_MASTER:
+			return RoleReply{Role: openflow.RoleMaster}, nil
+		case NX_ROLE_SLAVE:
+			return RoleReply{Role: openflow.RoleSlave}, nil
+		default:
+			return nil, errors.New("unexpected Nicira controller role")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported Nicira message subtype: %v", msg.ExpType())
+	}
+}
+
+func (r decoder) DecodeAction(data []byte) (openflow.ExperimenterAction, error) {
+	if len(data) < 2 {
+		return nil, openflow.ErrInvalidPacketLength
+	}
+
+	subtype := binary.BigEndian.Uint16(data[0:2])
+	switch subtype {
+	case NXAST_RESUBMIT, NXAST_RESUBMIT_TABLE:
+		if len(data) < 5 {
+			return nil, openflow.ErrInvalidPacketLength
+		}
+		action := &Resubmit{InPort: binary.BigEndian.Uint16(data[2:4]), Table: data[4]}
+		// NXAST_RESUBMIT always searches the current table.
+		if subtype == NXAST_RESUBMIT {
+			action.Table = resubmitCurrentTable
+		}
+		return action, nil
+	case NXAST_SET_TUNNEL:
+		if len(data) < 8 {
+			return nil, openflow.ErrInvalidPacketLength
+		}
+		return &SetTunnel{ID: uint64(binary.BigEndian.Uint32(data[4:8]))}, nil
+	case NXAST_SET_TUNNEL64:
+		if len(data) < 16 {
+			return nil, openflow.ErrInvalidPacketLength
+		}
+		return &SetTunnel{ID: binary.BigEndian.Uint64(data[8:16])}, nil
+	default:
+		return &RawAction{Subtype: subtype, Data: append([]byte(nil), data[2:]...)}, nil
+	}
+}
@@ -0,0 +1,197 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package nicira_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/superkkt/cherry/openflow"
+	"github.com/superkkt/cherry/openflow/nicira"
+	"github.com/superkkt/cherry/openflow/of10"
+	"github.com/superkkt/cherry/openflow/of13"
+)
+
+func TestResubmitAction(t *testing.T) {
+	for _, f := range []openflow.Factory{of10.NewFactory(), of13.NewFactory()} {
+		action, err := f.NewAction()
+		if err != nil {
+			t.Fatal(err)
+		}
+		action.AddExperimenter(nicira.NewResubmitTable(nicira.InPort, 3))
+		v, err := action.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The output is omitted because it has not been set.
+		if hex.EncodeToString(v) != "ffff0010"+"00002320"+"000efff8"+"03000000" {
+			t.Fatalf("OF%x: unexpected encoding: %x", f.ProtocolVersion(), v)
+		}
+
+		decoded, err := f.NewAction()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := decoded.UnmarshalBinary(v); err != nil {
+			t.Fatal(err)
+		}
+		actions := decoded.Experimenters()
+		if len(actions) != 1 {
+			t.Fatalf("OF%x: unexpected number of experimenter actions: %v", f.ProtocolVersion(), len(actions))
+		}
+		resubmit, ok := actions[0].(*nicira.Resubmit)
+		if !ok || resubmit.InPort != nicira.InPort || resubmit.Table != 3 {
+			t.Fatalf("OF%x: unexpected action: %+v", f.ProtocolVersion(), actions[0])
+		}
+	}
+}
+
+func TestUnknownAction(t *testing.T) {
+	// NXAST_DEC_TTL
+	v, _ := hex.DecodeString("ffff0010" + "00002320" + "0012" + "000000000000")
+	action := of13.NewAction()
+	if err := action.UnmarshalBinary(v); err != nil {
+		t.Fatal(err)
+	}
+	actions := action.Experimenters()
+	if len(actions) != 1 {
+		t.Fatalf("unexpected number of experimenter actions: %v", len(actions))
+	}
+	// Unknown actions should be encoded again without loss.
+	encoded, err := openflow.MarshalExperimenterAction(actions[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(encoded, v) {
+		t.Fatalf("unexpected encoding: %x", encoded)
+	}
+}
+
+func TestSetTunnelAction(t *testing.T) {
+	action, err := of10.NewFactory().NewAction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	action.AddExperimenter(nicira.NewSetTunnel(100))
+	v, err := action.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(v) != "ffff0018"+"00002320"+"0009000000000000"+"0000000000000064" {
+		t.Fatalf("unexpected encoding: %x", v)
+	}
+
+	// NXAST_SET_TUNNEL has a 32-bit tunnel ID.
+	v32, err := hex.DecodeString("ffff0010" + "00002320" + "0002000000000064")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, data := range [][]byte{v, v32} {
+		decoded, err := of10.NewFactory().NewAction()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := decoded.UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+		actions := decoded.Experimenters()
+		if len(actions) != 1 {
+			t.Fatalf("unexpected number of experimenter actions: %v", len(actions))
+		}
+		if tunnel, ok := actions[0].(*nicira.SetTunnel); !ok || tunnel.ID != 100 {
+			t.Fatalf("unexpected action: %+v", actions[0])
+		}
+	}
+}
+
+func TestExtendedMatch(t *testing.T) {
+	reg, err := nicira.Register(1, 0x5, 0xFF)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	match := of13.NewMatch()
+	match.SetInPort(openflow.NewInPort())
+	match.SetExtension(nicira.TunnelID(100))
+	match.SetExtension(reg)
+	v, err := match.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The wire format is tested by testdata/of13/flow_mod.nicira_match.hex in the openflow package.
+	decoded := of13.NewMatch()
+	if err := decoded.UnmarshalBinary(v); err != nil {
+		t.Fatal(err)
+	}
+	ext := decoded.Extensions()
+	if len(ext) != 2 {
+		t.Fatalf("unexpected number of extensions: %v", len(ext))
+	}
+	if ext[0].Field != nicira.NXM_NX_TUN_ID || !bytes.Equal(ext[0].Value, []byte{0, 0, 0, 0, 0, 0, 0, 100}) || ext[0].Mask != nil {
+		t.Fatalf("unexpected tunnel ID: %+v", ext[0])
+	}
+	if ext[1].Field != nicira.NXM_NX_REG0+1 || !bytes.Equal(ext[1].Value, reg.Value) || !bytes.Equal(ext[1].Mask, reg.Mask) {
+		t.Fatalf("unexpected register: %+v", ext[1])
+	}
+
+	if _, err := nicira.Register(8, 0, 0xFFFFFFFF); err == nil {
+		t.Fatal("expected an error for an invalid register number")
+	}
+	of10Match := of10.NewMatch()
+	of10Match.SetExtension(nicira.TunnelID(100))
+	if of10Match.Error() == nil {
+		t.Fatal("expected an error for OpenFlow 1.0")
+	}
+}
+
+func TestRoleMessages(t *testing.T) {
+	req, err := nicira.NewRoleRequest(of10.NewFactory(), openflow.RoleMaster)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := req.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(v) != "01040014"+"00000001"+"00002320"+"0000000a"+"00000001" {
+		t.Fatalf("unexpected encoding: %x", v)
+	}
+
+	reply := of10.NewExperimenter(0)
+	packet, _ := hex.DecodeString("01040014" + "00000001" + "00002320" + "0000000b" + "00000002")
+	if err := reply.UnmarshalBinary(packet); err != nil {
+		t.Fatal(err)
+	}
+	payload, err := openflow.DecodeExperimenter(reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := payload.(nicira.RoleReply); !ok || v.Role != openflow.RoleSlave {
+		t.Fatalf("unexpected payload: %+v", payload)
+	}
+
+	reply.SetExperimenter(0x12345678)
+	if _, err := openflow.DecodeExperimenter(reply); err != openflow.ErrUnknownExperimenter {
+		t.Fatalf("expected ErrUnknownExperimenter, got %v", err)
+	}
+}
@@ -23,6 +23,7 @@ package of10
 
 import (
 	"encoding/binary"
+	"errors"
 	"net"
 
 	"github.com/superkkt/cherry/openflow"
@@ -117,12 +118,91 @@ func marshalVLANID(vid uint16) ([]byte, error) {
 	return v, nil
 }
 
+func marshalVLANPriority(pcp uint8) []byte {
+	v := make([]byte, 8)
+	binary.BigEndian.PutUint16(v[0:2], uint16(OFPAT_SET_VLAN_PCP))
+	binary.BigEndian.PutUint16(v[2:4], 8)
+	v[4] = pcp
+	// v[5:8] is padding
+
+	return v
+}
+
+func marshalIP(t uint16, ip net.IP) ([]byte, error) {
+	ipv4 := ip.To4()
+	if ipv4 == nil {
+		return nil, errors.New("of10 does not support rewriting IPv6 addresses")
+	}
+
+	v := make([]byte, 8)
+	binary.BigEndian.PutUint16(v[0:2], t)
+	binary.BigEndian.PutUint16(v[2:4], 8)
+	copy(v[4:8], ipv4)
+
+	return v, nil
+}
+
+func marshalTransportPort(t uint16, port uint16) []byte {
+	v := make([]byte, 8)
+	binary.BigEndian.PutUint16(v[0:2], t)
+	binary.BigEndian.PutUint16(v[2:4], 8)
+	binary.BigEndian.PutUint16(v[4:6], port)
+	// v[6:8] is padding
+
+	return v
+}
+
+func marshalStripVLAN() []byte {
+	v := make([]byte, 8)
+	binary.BigEndian.PutUint16(v[0:2], uint16(OFPAT_STRIP_VLAN))
+	binary.BigEndian.PutUint16(v[2:4], 8)
+	// v[4:8] is padding
+
+	return v
+}
+
+func marshalTOS(dscp uint8) ([]byte, error) {
+	v := make([]byte, 8)
+	binary.BigEndian.PutUint16(v[0:2], uint16(OFPAT_SET_NW_TOS))
+	binary.BigEndian.PutUint16(v[2:4], 8)
+	// nw_tos carries DSCP in its upper 6 bits
+	v[4] = dscp << 2
+	// v[5:8] is padding
+
+	return v, nil
+}
+
 func (r *Action) MarshalBinary() ([]byte, error) {
 	if err := r.Error(); err != nil {
 		return nil, err
 	}
 
+	if ok, _ := r.Group(); ok {
+		return nil, errors.New("of10 does not support group action")
+	}
+	if ok, _ := r.PushVLAN(); ok {
+		return nil, errors.New("of10 does not support push VLAN action")
+	}
+	if ok, _ := r.MPLSLabel(); ok {
+		return nil, errors.New("of10 does not support MPLS")
+	}
+	if ok, _ := r.MPLSTC(); ok {
+		return nil, errors.New("of10 does not support MPLS")
+	}
+	if ok, _ := r.TunnelID(); ok {
+		return nil, errors.New("of10 does not support tunnel ID; use nicira.SetTunnel instead")
+	}
+	if ok, _ := r.PushMPLS(); ok {
+		return nil, errors.New("of10 does not support MPLS")
+	}
+	if ok, _ := r.PopMPLS(); ok {
+		return nil, errors.New("of10 does not support MPLS")
+	}
+
 	result := make([]byte, 0)
+	if r.PopVLAN() {
+		result = append(result, marshalStripVLAN()...)
+	}
 	if ok, srcMAC := r.SrcMAC(); ok {
 		v, err := marshalMAC(OFPAT_SET_DL_SRC, srcMAC)
 		if err != nil {
@@ -146,6 +226,49 @@ func (r *Action) MarshalBinary() ([]byte, error) {
 		}
 		result = append(result, v...)
 	}
+	if ok, pcp := r.VLANPriority(); ok {
+		result = append(result, marshalVLANPriority(pcp)...)
+	}
+	if ok, dscp := r.IPDSCP(); ok {
+		v, err := marshalTOS(dscp)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, v...)
+	}
+	if ok, ip := r.SrcIP(); ok {
+		v, err := marshalIP(OFPAT_SET_NW_SRC, ip)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, v...)
+	}
+	if ok, ip := r.DstIP(); ok {
+		v, err := marshalIP(OFPAT_SET_NW_DST, ip)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, v...)
+	}
+	// The transport protocol is decided by the packet.
+	if ok, _, port := r.SrcPort(); ok {
+		result = append(result, marshalTransportPort(OFPAT_SET_TP_SRC, port)...)
+	}
+	if ok, _, port := r.DstPort(); ok {
+		result = append(result, marshalTransportPort(OFPAT_SET_TP_DST, port)...)
+	}
+	for _, e := range r.Experimenters() {
+		v, err := openflow.MarshalExperimenterAction(e)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, v...)
+	}
+	// Output is optional if the packet will be processed by vendor-specific actions. The
+	// zero OutPort (port number 0) means the output has not been set.
+	if len(r.Experimenters()) > 0 && r.OutPort() == (openflow.OutPort{}) {
+		return result, nil
+	}
 
 	// XXX: Output action should be specified as a last element of this action command.
 	var buf []byte
@@ -220,6 +343,55 @@ func (r *Action) UnmarshalBinary(data []byte) error {
 			if err := r.Error(); err != nil {
 				return err
 			}
+		case OFPAT_STRIP_VLAN:
+			r.SetPopVLAN()
+		case OFPAT_SET_VLAN_PCP:
+			if len(buf) < 8 {
+				return openflow.ErrInvalidPacketLength
+			}
+			r.SetVLANPriority(buf[4])
+			if err := r.Error(); err != nil {
+				return err
+			}
+		case OFPAT_SET_NW_SRC, OFPAT_SET_NW_DST:
+			if len(buf) < 8 {
+				return openflow.ErrInvalidPacketLength
+			}
+			ip := net.IPv4(buf[4], buf[5], buf[6], buf[7])
+			if t == OFPAT_SET_NW_SRC {
+				r.SetSrcIP(ip)
+			} else {
+				r.SetDstIP(ip)
+			}
+		case OFPAT_SET_TP_SRC, OFPAT_SET_TP_DST:
+			if len(buf) < 8 {
+				return openflow.ErrInvalidPacketLength
+			}
+			// OpenFlow 1.0 does not specify the protocol, which is decided by the packet.
+			port := binary.BigEndian.Uint16(buf[4:6])
+			if t == OFPAT_SET_TP_SRC {
+				r.SetSrcPort(openflow.ProtocolTCP, port)
+			} else {
+				r.SetDstPort(openflow.ProtocolTCP, port)
+			}
+		case OFPAT_SET_NW_TOS:
+			if len(buf) < 8 {
+				return openflow.ErrInvalidPacketLength
+			}
+			r.SetIPDSCP(buf[4] >> 2)
+			if err := r.Error(); err != nil {
+				return err
+			}
+		case OFPAT_VENDOR:
+			action, err := openflow.UnmarshalExperimenterAction(buf)
+			if err == openflow.ErrUnknownExperimenter {
+				// Skip the actions of unknown vendors.
+				break
+			}
+			if err != nil {
+				return err
+			}
+			r.AddExperimenter(action)
 		default:
 			// Do nothing
 		}
@@ -72,6 +72,24 @@ const (
 	OFPP_NONE       = 0xffff
 )
 
+const (
+	OFPQ_ALL = 0xffffffff /* All ones is used to indicate all queues in a port (for stats retrieval). */
+)
+
+const (
+	OFPET_HELLO_FAILED    = 0 /* Hello protocol failed. */
+	OFPET_BAD_REQUEST     = 1 /* Request was not understood. */
+	OFPET_BAD_ACTION      = 2 /* Error in action description. */
+	OFPET_FLOW_MOD_FAILED = 3 /* Problem modifying flow entry. */
+	OFPET_PORT_MOD_FAILED = 4 /* Port mod request failed. */
+	OFPET_QUEUE_OP_FAILED = 5 /* Queue operation failed. */
+)
+
+const (
+	OFPFMFC_ALL_TABLES_FULL = 0 /* Flow not added because of full tables. */
+	OFPFMFC_OVERLAP         = 1 /* Attempted to add overlapping flow with CHECK_OVERLAP flag set. */
+)
+
 const (
 	OFPFW_IN_PORT     = 1 << 0  /* Switch input port. */
 	OFPFW_DL_VLAN     = 1 << 1  /* VLAN id. */
@@ -181,3 +199,8 @@ const (
 	OFPPR_DELETE = 1
 	OFPPR_MODIFY = 2
 )
+
+const (
+	OFPR_NO_MATCH = 0
+	OFPR_ACTION   = 1
+)
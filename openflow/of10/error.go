@@ -0,0 +1,75 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package of10
+
+import (
+	"fmt"
+
+	"github.com/superkkt/cherry/openflow"
+)
+
+type errorClass struct {
+	name  string
+	cause error
+	// Names of the codes indexed by the code value.
+	codes []string
+}
+
+var errorClasses = map[uint16]errorClass{
+	OFPET_HELLO_FAILED:    {"HELLO_FAILED", openflow.ErrHelloFailed, []string{"INCOMPATIBLE", "EPERM"}},
+	OFPET_BAD_REQUEST:     {"BAD_REQUEST", openflow.ErrBadRequest, []string{"BAD_VERSION", "BAD_TYPE", "BAD_STAT", "BAD_VENDOR", "BAD_SUBTYPE", "EPERM", "BAD_LEN", "BUFFER_EMPTY", "BUFFER_UNKNOWN"}},
+	OFPET_BAD_ACTION:      {"BAD_ACTION", openflow.ErrBadAction, []string{"BAD_TYPE", "BAD_LEN", "BAD_VENDOR", "BAD_VENDOR_TYPE", "BAD_OUT_PORT", "BAD_ARGUMENT", "EPERM", "TOO_MANY", "BAD_QUEUE"}},
+	OFPET_FLOW_MOD_FAILED: {"FLOW_MOD_FAILED", openflow.ErrFlowModFailed, []string{"ALL_TABLES_FULL", "OVERLAP", "EPERM", "BAD_EMERG_TIMEOUT", "BAD_COMMAND", "UNSUPPORTED"}},
+	OFPET_PORT_MOD_FAILED: {"PORT_MOD_FAILED", openflow.ErrPortModFailed, []string{"BAD_PORT", "BAD_HW_ADDR"}},
+	OFPET_QUEUE_OP_FAILED: {"QUEUE_OP_FAILED", openflow.ErrQueueOpFailed, []string{"BAD_PORT", "BAD_QUEUE", "EPERM"}},
+}
+
+type Error struct {
+	openflow.BaseError
+}
+
+func NewError() openflow.Error {
+	return new(Error)
+}
+
+func (r *Error) Err() error {
+	class, ok := errorClasses[r.Class()]
+	if !ok {
+		return openflow.NewDeviceError(r, "", nil)
+	}
+
+	code := fmt.Sprintf("%v", r.Code())
+	if int(r.Code()) < len(class.codes) {
+		code = class.codes[r.Code()]
+	}
+	cause := class.cause
+	switch {
+	case code == "EPERM":
+		cause = openflow.ErrPermission
+	case r.Class() == OFPET_FLOW_MOD_FAILED && r.Code() == OFPFMFC_ALL_TABLES_FULL:
+		cause = openflow.ErrTableFull
+	case r.Class() == OFPET_FLOW_MOD_FAILED && r.Code() == OFPFMFC_OVERLAP:
+		cause = openflow.ErrFlowOverlap
+	}
+
+	return openflow.NewDeviceError(r, class.name+"/"+code, cause)
+}
@@ -104,6 +104,10 @@ func getFlowModCmd(cmd openflow.FlowModCmd) uint16 {
 		c = OFPFC_MODIFY
 	case openflow.FlowDelete:
 		c = OFPFC_DELETE
+	case openflow.FlowModifyStrict:
+		c = OFPFC_MODIFY_STRICT
+	case openflow.FlowDeleteStrict:
+		c = OFPFC_DELETE_STRICT
 	default:
 		panic(fmt.Sprintf("unexpected FlowModCmd: %v", cmd))
 	}
@@ -127,6 +131,30 @@ func (r *Factory) NewPacketOut() (openflow.PacketOut, error) {
 	return NewPacketOut(r.getTransactionID()), nil
 }
 
+func (r *Factory) NewGroupMod(cmd openflow.GroupModCmd) (openflow.GroupMod, error) {
+	return nil, errors.New("of10 does not support GroupMod")
+}
+
+func (r *Factory) NewMeterMod(cmd openflow.MeterModCmd) (openflow.MeterMod, error) {
+	return nil, errors.New("of10 does not support MeterMod")
+}
+
+func (r *Factory) NewRoleRequest() (openflow.RoleRequest, error) {
+	return nil, errors.New("of10 does not support RoleRequest")
+}
+
+func (r *Factory) NewRoleReply() (openflow.RoleReply, error) {
+	return nil, errors.New("of10 does not support RoleReply")
+}
+
+func (r *Factory) NewPortStatsRequest() (openflow.PortStatsRequest, error) {
+	return NewPortStatsRequest(r.getTransactionID()), nil
+}
+
+func (r *Factory) NewPortStatsReply() (openflow.PortStatsReply, error) {
+	return new(PortStatsReply), nil
+}
+
 func (r *Factory) NewPortStatus() (openflow.PortStatus, error) {
 	return new(PortStatus), nil
 }
@@ -143,7 +171,9 @@ func (r *Factory) NewFlowStatsRequest() (openflow.FlowStatsRequest, error) {
 	return NewFlowStatsRequest(r.getTransactionID()), nil
 }
 
-// TODO: NewFlowStatsReply() (openflow.FlowStatsReply, error) {
+func (r *Factory) NewFlowStatsReply() (openflow.FlowStatsReply, error) {
+	return new(FlowStatsReply), nil
+}
 
 func (r *Factory) NewPortDescRequest() (openflow.PortDescRequest, error) {
 	return nil, errors.New("of10 does not support PortDescRequest")
@@ -157,11 +187,17 @@ func (r *Factory) NewTableFeaturesRequest() (openflow.TableFeaturesRequest, erro
 	return nil, errors.New("of10 does not support TableFeaturesRequest")
 }
 
+func (r *Factory) NewTableFeaturesReply() (openflow.TableFeaturesReply, error) {
+	return nil, errors.New("of10 does not support TableFeaturesReply")
+}
+
 func (r *Factory) NewError() (openflow.Error, error) {
-	return new(openflow.BaseError), nil
+	return NewError(), nil
 }
 
-// TODO: NewTableFeaturesReply() (TableFeaturesReply, error)
+func (r *Factory) NewExperimenter() (openflow.Experimenter, error) {
+	return NewExperimenter(r.getTransactionID()), nil
+}
 
 func (r *Factory) NewInstruction() (openflow.Instruction, error) {
 	return new(Instruction), nil
@@ -170,3 +206,15 @@ func (r *Factory) NewInstruction() (openflow.Instruction, error) {
 func (r *Factory) NewQueueGetConfigRequest() (openflow.QueueGetConfigRequest, error) {
 	return NewQueueGetConfigRequest(r.getTransactionID()), nil
 }
+
+func (r *Factory) NewQueueGetConfigReply() (openflow.QueueGetConfigReply, error) {
+	return new(QueueGetConfigReply), nil
+}
+
+func (r *Factory) NewQueueStatsRequest() (openflow.QueueStatsRequest, error) {
+	return NewQueueStatsRequest(r.getTransactionID()), nil
+}
+
+func (r *Factory) NewQueueStatsReply() (openflow.QueueStatsReply, error) {
+	return new(QueueStatsReply), nil
+}
@@ -24,6 +24,7 @@ package of10
 import (
 	"encoding/binary"
 	"errors"
+	"fmt"
 
 	"github.com/superkkt/cherry/openflow"
 )
@@ -33,6 +34,7 @@ type FlowMod struct {
 	openflow.Message
 	command     uint16
 	cookie      uint64
+	bufferID    uint32
 	idleTimeout uint16
 	hardTimeout uint16
 	priority    uint16
@@ -47,12 +49,38 @@ func NewFlowMod(xid uint32, cmd uint16) openflow.FlowMod {
 	outPort.SetNone()
 
 	return &FlowMod{
-		Message: openflow.NewMessage(openflow.OF10_VERSION, OFPT_FLOW_MOD, xid),
-		command: cmd,
-		outPort: outPort,
+		Message:  openflow.NewMessage(openflow.OF10_VERSION, OFPT_FLOW_MOD, xid),
+		command:  cmd,
+		outPort:  outPort,
+		bufferID: openflow.NoBuffer,
 	}
 }
 
+func (r *FlowMod) Command() openflow.FlowModCmd {
+	switch r.command {
+	case OFPFC_ADD:
+		return openflow.FlowAdd
+	case OFPFC_MODIFY:
+		return openflow.FlowModify
+	case OFPFC_DELETE:
+		return openflow.FlowDelete
+	case OFPFC_MODIFY_STRICT:
+		return openflow.FlowModifyStrict
+	case OFPFC_DELETE_STRICT:
+		return openflow.FlowDeleteStrict
+	default:
+		panic(fmt.Sprintf("unexpected flow command: %v", r.command))
+	}
+}
+
+func (r *FlowMod) BufferID() uint32 {
+	return r.bufferID
+}
+
+func (r *FlowMod) SetBufferID(id uint32) {
+	r.bufferID = id
+}
+
 func (r *FlowMod) Error() error {
 	return r.err
 }
@@ -148,7 +176,7 @@ func (r *FlowMod) MarshalBinary() ([]byte, error) {
 	binary.BigEndian.PutUint16(v[10:12], r.idleTimeout)
 	binary.BigEndian.PutUint16(v[12:14], r.hardTimeout)
 	binary.BigEndian.PutUint16(v[14:16], r.priority)
-	binary.BigEndian.PutUint32(v[16:20], OFP_NO_BUFFER)
+	binary.BigEndian.PutUint32(v[16:20], r.bufferID)
 	if r.outPort.IsNone() {
 		binary.BigEndian.PutUint16(v[20:22], OFPP_NONE)
 	} else {
@@ -176,3 +204,48 @@ func (r *FlowMod) MarshalBinary() ([]byte, error) {
 	r.SetPayload(result)
 	return r.Message.MarshalBinary()
 }
+
+// UnmarshalBinary decodes a FLOW_MOD that has been encoded by MarshalBinary, e.g., to restore
+// a flow saved by the controller. The flags are ignored as we always set OFPFF_SEND_FLOW_REM.
+func (r *FlowMod) UnmarshalBinary(data []byte) error {
+	if err := r.Message.UnmarshalBinary(data); err != nil {
+		return err
+	}
+
+	payload := r.Payload()
+	if payload == nil || len(payload) < 64 {
+		return openflow.ErrInvalidPacketLength
+	}
+	match := NewMatch()
+	if err := match.UnmarshalBinary(payload[0:40]); err != nil {
+		return err
+	}
+	r.match = match
+	r.cookie = binary.BigEndian.Uint64(payload[40:48])
+	r.command = binary.BigEndian.Uint16(payload[48:50])
+	if r.command > OFPFC_DELETE_STRICT {
+		return fmt.Errorf("unexpected flow command: %v", r.command)
+	}
+	r.idleTimeout = binary.BigEndian.Uint16(payload[50:52])
+	r.hardTimeout = binary.BigEndian.Uint16(payload[52:54])
+	r.priority = binary.BigEndian.Uint16(payload[54:56])
+	r.bufferID = binary.BigEndian.Uint32(payload[56:60])
+	r.outPort = openflow.NewOutPort()
+	if port := binary.BigEndian.Uint16(payload[60:62]); port == OFPP_NONE {
+		r.outPort.SetNone()
+	} else {
+		r.outPort.SetValue(uint32(port))
+	}
+
+	r.instruction = nil
+	if len(payload) == 64 {
+		return nil
+	}
+	action := NewAction()
+	if err := action.UnmarshalBinary(payload[64:]); err != nil {
+		return err
+	}
+	r.instruction = &Instruction{action: action}
+
+	return nil
+}
@@ -109,4 +109,57 @@ func (r *FlowStatsRequest) MarshalBinary() ([]byte, error) {
 	return r.Message.MarshalBinary()
 }
 
-// TODO: Implement FlowStatsReply
+type FlowStatsReply struct {
+	openflow.Message
+	stats []openflow.FlowStats
+}
+
+func (r FlowStatsReply) FlowStats() []openflow.FlowStats {
+	return r.stats
+}
+
+func (r *FlowStatsReply) UnmarshalBinary(data []byte) error {
+	if err := r.Message.UnmarshalBinary(data); err != nil {
+		return err
+	}
+
+	payload := r.Payload()
+	if payload == nil || len(payload) < 4 {
+		return openflow.ErrInvalidPacketLength
+	}
+	// payload[0:4] is the stats type and flags
+	r.stats = nil
+	body := payload[4:]
+	for len(body) > 0 {
+		if len(body) < 88 {
+			return openflow.ErrInvalidPacketLength
+		}
+		length := int(binary.BigEndian.Uint16(body[0:2]))
+		if length < 88 || len(body) < length {
+			return openflow.ErrInvalidPacketLength
+		}
+
+		v := openflow.FlowStats{
+			TableID: body[2],
+			// body[3] is padding
+			Match:           NewMatch(),
+			DurationSec:     binary.BigEndian.Uint32(body[44:48]),
+			DurationNanoSec: binary.BigEndian.Uint32(body[48:52]),
+			Priority:        binary.BigEndian.Uint16(body[52:54]),
+			IdleTimeout:     binary.BigEndian.Uint16(body[54:56]),
+			HardTimeout:     binary.BigEndian.Uint16(body[56:58]),
+			// body[58:64] is padding
+			Cookie:      binary.BigEndian.Uint64(body[64:72]),
+			PacketCount: binary.BigEndian.Uint64(body[72:80]),
+			ByteCount:   binary.BigEndian.Uint64(body[80:88]),
+		}
+		if err := v.Match.UnmarshalBinary(body[4:44]); err != nil {
+			return err
+		}
+		// Actions after the counters are ignored.
+		r.stats = append(r.stats, v)
+		body = body[length:]
+	}
+
+	return nil
+}
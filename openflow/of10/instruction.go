@@ -40,6 +40,10 @@ func (r *Instruction) GotoTable(tableID uint8) {
 	// OpenFlow 1.0 does not support GotoTable
 }
 
+func (r *Instruction) SetMeter(id uint32) {
+	r.err = errors.New("OpenFlow 1.0 does not support meters")
+}
+
 func (r *Instruction) WriteAction(act openflow.Action) {
 	if act == nil {
 		panic("act is nil")
@@ -54,6 +58,34 @@ func (r *Instruction) ApplyAction(act openflow.Action) {
 	r.action = act
 }
 
+func (r *Instruction) ApplyActionAndGotoTable(act openflow.Action, tableID uint8) {
+	r.err = errors.New("OpenFlow 1.0 does not support GotoTable")
+}
+
+func (r *Instruction) SetInstructions(set openflow.InstructionSet) {
+	if set.Meter != 0 || set.MetadataMask != 0 || set.Goto {
+		r.err = errors.New("OpenFlow 1.0 supports only the actions")
+		return
+	}
+	if (set.Apply == nil) == (set.Write == nil) {
+		r.err = errors.New("OpenFlow 1.0 supports only one list of the actions")
+		return
+	}
+	if set.Apply != nil {
+		r.action = set.Apply
+	} else {
+		r.action = set.Write
+	}
+}
+
+func (r *Instruction) Actions() []openflow.Action {
+	if r.action == nil {
+		return nil
+	}
+
+	return []openflow.Action{r.action}
+}
+
 func (r *Instruction) MarshalBinary() ([]byte, error) {
 	if r.err != nil {
 		return nil, r.err
@@ -45,6 +45,7 @@ type Wildcard struct {
 	SrcIP        uint8
 	DstIP        uint8
 	VLANPriority bool /* VLAN priority. */
+	TOS          bool /* IP ToS (DSCP field, 6 bits). */
 }
 
 func newWildcardAll() *Wildcard {
@@ -60,6 +61,7 @@ func newWildcardAll() *Wildcard {
 		SrcIP:        32,
 		DstIP:        32,
 		VLANPriority: true,
+		TOS:          true,
 	}
 }
 
@@ -104,8 +106,9 @@ func (r *Wildcard) MarshalBinary() ([]byte, error) {
 	if r.VLANPriority {
 		v = v | OFPFW_DL_VLAN_PCP
 	}
-	// ToS is always wildcarded
-	v = v | OFPFW_NW_TOS
+	if r.TOS {
+		v = v | OFPFW_NW_TOS
+	}
 
 	data := make([]byte, 4)
 	binary.BigEndian.PutUint32(data[0:4], v)
@@ -148,6 +151,9 @@ func (r *Wildcard) UnmarshalBinary(data []byte) error {
 	if w&OFPFW_DL_VLAN_PCP != 0 {
 		r.VLANPriority = true
 	}
+	if w&OFPFW_NW_TOS != 0 {
+		r.TOS = true
+	}
 
 	return nil
 }
@@ -161,6 +167,7 @@ type Match struct {
 	vlanID       uint16
 	vlanPriority uint8
 	etherType    uint16
+	tos          uint8
 	protocol     uint8
 	srcIP        net.IP
 	dstIP        net.IP
@@ -183,6 +190,14 @@ func (r *Match) Error() error {
 	return r.err
 }
 
+func (r *Match) SetExtension(oxm openflow.OXM) {
+	r.err = errors.New("SetExtension: of10 does not support the extensible match")
+}
+
+func (r *Match) Extensions() []openflow.OXM {
+	return nil
+}
+
 func (r *Match) SetWildcardSrcPort() {
 	r.srcPort = 0
 	r.wildcards.SrcPort = true
@@ -281,6 +296,104 @@ func (r *Match) IPProtocol() (wildcard bool, protocol uint8) {
 	return r.wildcards.Protocol, r.protocol
 }
 
+func (r *Match) SetWildcardIPDSCP() {
+	r.tos = 0
+	r.wildcards.TOS = true
+}
+
+func (r *Match) SetIPDSCP(dscp uint8) {
+	// DSCP is a 6-bit field
+	if dscp > 0x3F {
+		r.err = errors.Wrap(openflow.ErrInvalidDSCP, "SetIPDSCP")
+		return
+	}
+	// IPv4? OpenFlow 1.0 cannot match the traffic class of IPv6 packets.
+	if r.etherType != 0x0800 {
+		r.err = errors.Wrap(openflow.ErrUnsupportedEtherType, "SetIPDSCP")
+		return
+	}
+
+	// OpenFlow 1.0 matches the whole ToS byte whose upper 6 bits are DSCP
+	r.tos = dscp << 2
+	r.wildcards.TOS = false
+}
+
+func (r *Match) IPDSCP() (wildcard bool, dscp uint8) {
+	return r.wildcards.TOS, r.tos >> 2
+}
+
+func (r *Match) SetWildcardIPv6FlowLabel() {
+	// Do nothing
+}
+
+func (r *Match) SetIPv6FlowLabel(label uint32) {
+	r.err = errors.New("SetIPv6FlowLabel: of10 does not support IPv6")
+}
+
+func (r *Match) IPv6FlowLabel() (wildcard bool, label uint32) {
+	return true, 0
+}
+
+func (r *Match) SetWildcardICMPv6Type() {
+	// Do nothing
+}
+
+func (r *Match) SetICMPv6Type(t uint8) {
+	r.err = errors.New("SetICMPv6Type: of10 does not support IPv6")
+}
+
+func (r *Match) ICMPv6Type() (wildcard bool, t uint8) {
+	return true, 0
+}
+
+func (r *Match) SetWildcardIPv6NDTarget() {
+	// Do nothing
+}
+
+func (r *Match) SetIPv6NDTarget(ip net.IP) {
+	r.err = errors.New("SetIPv6NDTarget: of10 does not support IPv6")
+}
+
+func (r *Match) IPv6NDTarget() (wildcard bool, ip net.IP) {
+	return true, nil
+}
+
+func (r *Match) SetWildcardTunnelID() {
+	// Do nothing
+}
+
+func (r *Match) SetTunnelID(id, mask uint64) {
+	r.err = errors.New("SetTunnelID: of10 does not support tunnel ID")
+}
+
+func (r *Match) TunnelID() (wildcard bool, id, mask uint64) {
+	return true, 0, 0
+}
+
+func (r *Match) SetWildcardMPLSLabel() {
+	// Do nothing
+}
+
+func (r *Match) SetMPLSLabel(label uint32) {
+	r.err = errors.New("SetMPLSLabel: of10 does not support MPLS")
+}
+
+func (r *Match) MPLSLabel() (wildcard bool, label uint32) {
+	return true, 0
+}
+
+func (r *Match) SetWildcardMPLSTC() {
+	// Do nothing
+}
+
+func (r *Match) SetMPLSTC(tc uint8) {
+	r.err = errors.New("SetMPLSTC: of10 does not support MPLS")
+}
+
+func (r *Match) MPLSTC() (wildcard bool, tc uint8) {
+	return true, 0
+}
+
 func (r *Match) SetWildcardInPort() {
 	r.inPort = 0
 	r.wildcards.InPort = true
@@ -451,6 +564,7 @@ func (r *Match) MarshalBinary() ([]byte, error) {
 	data[20] = r.vlanPriority
 	// data[21] = padding
 	binary.BigEndian.PutUint16(data[22:24], r.etherType)
+	data[24] = r.tos
 	data[25] = r.protocol
 	// data[26:28] = padding
 	srcIP := r.srcIP.To4()
@@ -487,6 +601,7 @@ func (r *Match) UnmarshalBinary(data []byte) error {
 	r.vlanPriority = data[20]
 	// data[21] = padding
 	r.etherType = binary.BigEndian.Uint16(data[22:24])
+	r.tos = data[24]
 	r.protocol = data[25]
 	// data[26:28] = padding
 	r.srcIP = net.IPv4(data[28], data[29], data[30], data[31])
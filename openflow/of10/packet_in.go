@@ -57,8 +57,15 @@ func (r PacketIn) TableID() uint8 {
 	return 0
 }
 
-func (r PacketIn) Reason() uint8 {
-	return r.reason
+func (r PacketIn) Reason() openflow.PacketInReason {
+	switch r.reason {
+	case OFPR_NO_MATCH:
+		return openflow.PacketInNoMatch
+	case OFPR_ACTION:
+		return openflow.PacketInAction
+	default:
+		return openflow.PacketInUnknown
+	}
 }
 
 func (r PacketIn) Cookie() uint64 {
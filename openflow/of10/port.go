@@ -83,6 +83,14 @@ func (r Port) IsAutoNego() bool {
 	return r.current&OFPPF_AUTONEG != 0
 }
 
+func (r Port) Config() uint32 {
+	return r.config
+}
+
+func (r Port) State() uint32 {
+	return r.state
+}
+
 func (r Port) Speed() uint64 {
 	switch {
 	case r.current&OFPPF_10MB_HD != 0:
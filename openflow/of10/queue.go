@@ -147,6 +147,9 @@ func (r *Queue) UnmarshalBinary(data []byte) error {
 		if err := p.UnmarshalBinary(data[i:]); err != nil {
 			return err
 		}
+		if p.Length() == 0 {
+			return openflow.ErrInvalidPacketLength
+		}
 		r.property = append(r.property, p)
 		i += int(p.Length())
 	}
@@ -186,6 +189,9 @@ func (r *QueueGetConfigReply) UnmarshalBinary(data []byte) error {
 		if err := q.UnmarshalBinary(payload[i:]); err != nil {
 			return err
 		}
+		if q.Length() == 0 {
+			return openflow.ErrInvalidPacketLength
+		}
 		r.queue = append(r.queue, q)
 		i += int(q.Length())
 	}
@@ -0,0 +1,88 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package of10
+
+import (
+	"encoding/binary"
+
+	"github.com/superkkt/cherry/openflow"
+)
+
+type QueueStatsRequest struct {
+	openflow.Message
+}
+
+func NewQueueStatsRequest(xid uint32) openflow.QueueStatsRequest {
+	return &QueueStatsRequest{
+		Message: openflow.NewMessage(openflow.OF10_VERSION, OFPT_STATS_REQUEST, xid),
+	}
+}
+
+func (r *QueueStatsRequest) MarshalBinary() ([]byte, error) {
+	v := make([]byte, 12)
+	binary.BigEndian.PutUint16(v[0:2], OFPST_QUEUE)
+	// v[2:4] is flags, but not yet defined
+	// All ports
+	binary.BigEndian.PutUint16(v[4:6], OFPP_ALL)
+	// v[6:8] is padding
+	// All queues
+	binary.BigEndian.PutUint32(v[8:12], OFPQ_ALL)
+	r.SetPayload(v)
+
+	return r.Message.MarshalBinary()
+}
+
+type QueueStatsReply struct {
+	openflow.Message
+	stats []openflow.QueueStats
+}
+
+func (r QueueStatsReply) QueueStats() []openflow.QueueStats {
+	return r.stats
+}
+
+func (r *QueueStatsReply) UnmarshalBinary(data []byte) error {
+	if err := r.Message.UnmarshalBinary(data); err != nil {
+		return err
+	}
+
+	payload := r.Payload()
+	if payload == nil || len(payload) < 4 || (len(payload)-4)%32 != 0 {
+		return openflow.ErrInvalidPacketLength
+	}
+	// payload[0:4] is the stats type and flags
+	body := payload[4:]
+	r.stats = make([]openflow.QueueStats, len(body)/32)
+	for i := range r.stats {
+		buf := body[i*32:]
+		r.stats[i] = openflow.QueueStats{
+			PortNumber: uint32(binary.BigEndian.Uint16(buf[0:2])),
+			// buf[2:4] is padding
+			QueueID:   binary.BigEndian.Uint32(buf[4:8]),
+			TxBytes:   binary.BigEndian.Uint64(buf[8:16]),
+			TxPackets: binary.BigEndian.Uint64(buf[16:24]),
+			TxErrors:  binary.BigEndian.Uint64(buf[24:32]),
+		}
+	}
+
+	return nil
+}
@@ -92,16 +92,182 @@ func marshalMAC(t uint8, mac net.HardwareAddr) ([]byte, error) {
 	return v, nil
 }
 
-// TODO: Marshal Enqueue
+func marshalDSCP(dscp uint8) ([]byte, error) {
+	tlv, err := marshalUint8TLV(OFPXMT_OFB_IP_DSCP, dscp)
+	if err != nil {
+		return nil, err
+	}
+
+	v := make([]byte, 4+len(tlv))
+	binary.BigEndian.PutUint16(v[0:2], OFPAT_SET_FIELD)
+	// Add padding to align as a multiple of 8
+	rem := (len(v)) % 8
+	if rem > 0 {
+		v = append(v, bytes.Repeat([]byte{0}, 8-rem)...)
+	}
+	binary.BigEndian.PutUint16(v[2:4], uint16(len(v)))
+	copy(v[4:], tlv)
+
+	return v, nil
+}
+
+func marshalGroup(id uint32) ([]byte, error) {
+	if id > OFPG_MAX {
+		return nil, errors.New("invalid group ID")
+	}
+
+	v := make([]byte, 8)
+	binary.BigEndian.PutUint16(v[0:2], OFPAT_GROUP)
+	binary.BigEndian.PutUint16(v[2:4], 8)
+	binary.BigEndian.PutUint32(v[4:8], id)
+
+	return v, nil
+}
+
+func marshalQueue(queue uint32) ([]byte, error) {
+	if queue == OFPQ_ALL {
+		return nil, errors.New("invalid queue ID")
+	}
+
+	v := make([]byte, 8)
+	binary.BigEndian.PutUint16(v[0:2], OFPAT_SET_QUEUE)
+	binary.BigEndian.PutUint16(v[2:4], 8)
+	binary.BigEndian.PutUint32(v[4:8], queue)
+
+	return v, nil
+}
+
+func marshalPopVLAN() []byte {
+	v := make([]byte, 8)
+	binary.BigEndian.PutUint16(v[0:2], OFPAT_POP_VLAN)
+	binary.BigEndian.PutUint16(v[2:4], 8)
+	// v[4:8] is padding
+
+	return v
+}
+
+// marshalPushPop encodes a push or pop action of type t whose Ethernet type is ethType.
+func marshalPushPop(t uint16, ethType uint16) []byte {
+	v := make([]byte, 8)
+	binary.BigEndian.PutUint16(v[0:2], t)
+	binary.BigEndian.PutUint16(v[2:4], 8)
+	binary.BigEndian.PutUint16(v[4:6], ethType)
+	// v[6:8] is padding
+
+	return v
+}
 
-// TODO: Marshal SetVLANVID
+// marshalSetField encodes the set-field action of the basic OXM field whose value is value.
+func marshalSetField(field uint8, value []byte) []byte {
+	// Add padding to align as a multiple of 8
+	length := (8 + len(value) + 7) / 8 * 8
+	v := make([]byte, length)
+	binary.BigEndian.PutUint16(v[0:2], OFPAT_SET_FIELD)
+	binary.BigEndian.PutUint16(v[2:4], uint16(length))
+	header := uint32(OFPXMC_OPENFLOW_BASIC)<<16 | uint32(field)<<9 | uint32(len(value))
+	binary.BigEndian.PutUint32(v[4:8], header)
+	copy(v[8:], value)
+
+	return v
+}
+
+func uint16Bytes(v uint16) []byte {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, v)
+	return b
+}
+
+func uint32Bytes(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, v)
+	return b
+}
+
+// portField returns the OXM fields of the source and destination port numbers of the transport protocol.
+func portField(protocol uint8) (src, dst uint8) {
+	switch protocol {
+	case openflow.ProtocolTCP:
+		return OFPXMT_OFB_TCP_SRC, OFPXMT_OFB_TCP_DST
+	case openflow.ProtocolUDP:
+		return OFPXMT_OFB_UDP_SRC, OFPXMT_OFB_UDP_DST
+	case openflow.ProtocolSCTP:
+		return OFPXMT_OFB_SCTP_SRC, OFPXMT_OFB_SCTP_DST
+	default:
+		panic("unexpected transport protocol")
+	}
+}
+
+// marshalSetFields encodes the header fields rewritten by the action except the MAC addresses
+// and DSCP.
+func (r *Action) marshalSetFields() []byte {
+	result := make([]byte, 0)
+	if ok, vid := r.VLANID(); ok {
+		result = append(result, marshalSetField(OFPXMT_OFB_VLAN_VID, uint16Bytes(vid|OFPVID_PRESENT))...)
+	}
+	if ok, pcp := r.VLANPriority(); ok {
+		result = append(result, marshalSetField(OFPXMT_OFB_VLAN_PCP, []byte{pcp})...)
+	}
+	if ok, label := r.MPLSLabel(); ok {
+		result = append(result, marshalSetField(OFPXMT_OFB_MPLS_LABEL, uint32Bytes(label))...)
+	}
+	if ok, tc := r.MPLSTC(); ok {
+		result = append(result, marshalSetField(OFPXMT_OFB_MPLS_TC, []byte{tc})...)
+	}
+	if ok, ip := r.SrcIP(); ok {
+		field := uint8(OFPXMT_OFB_IPV4_SRC)
+		if len(ip) == net.IPv6len {
+			field = OFPXMT_OFB_IPV6_SRC
+		}
+		result = append(result, marshalSetField(field, ip)...)
+	}
+	if ok, ip := r.DstIP(); ok {
+		field := uint8(OFPXMT_OFB_IPV4_DST)
+		if len(ip) == net.IPv6len {
+			field = OFPXMT_OFB_IPV6_DST
+		}
+		result = append(result, marshalSetField(field, ip)...)
+	}
+	if ok, protocol, port := r.SrcPort(); ok {
+		field, _ := portField(protocol)
+		result = append(result, marshalSetField(field, uint16Bytes(port))...)
+	}
+	if ok, protocol, port := r.DstPort(); ok {
+		_, field := portField(protocol)
+		result = append(result, marshalSetField(field, uint16Bytes(port))...)
+	}
+	if ok, id := r.TunnelID(); ok {
+		v := make([]byte, 8)
+		binary.BigEndian.PutUint64(v, id)
+		result = append(result, marshalSetField(OFPXMT_OFB_TUNNEL_ID, v)...)
+	}
+
+	return result
+}
 
 func (r *Action) MarshalBinary() ([]byte, error) {
+	return r.marshal(true)
+}
+
+// marshal encodes the actions. The output is omitted if it has not been set and requireOutput is false.
+func (r *Action) marshal(requireOutput bool) ([]byte, error) {
 	if err := r.Error(); err != nil {
 		return nil, err
 	}
 
 	result := make([]byte, 0)
+	// The tags are popped and pushed before the header fields are rewritten.
+	if r.PopVLAN() {
+		result = append(result, marshalPopVLAN()...)
+	}
+	if ok, ethType := r.PopMPLS(); ok {
+		result = append(result, marshalPushPop(OFPAT_POP_MPLS, ethType)...)
+	}
+	if ok, ethType := r.PushMPLS(); ok {
+		result = append(result, marshalPushPop(OFPAT_PUSH_MPLS, ethType)...)
+	}
+	if ok, ethType := r.PushVLAN(); ok {
+		result = append(result, marshalPushPop(OFPAT_PUSH_VLAN, ethType)...)
+	}
 	if ok, srcMAC := r.SrcMAC(); ok {
 		v, err := marshalMAC(OFPXMT_OFB_ETH_SRC, srcMAC)
 		if err != nil {
@@ -116,6 +282,45 @@ func (r *Action) MarshalBinary() ([]byte, error) {
 		}
 		result = append(result, v...)
 	}
+	if ok, dscp := r.IPDSCP(); ok {
+		v, err := marshalDSCP(dscp)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, v...)
+	}
+	result = append(result, r.marshalSetFields()...)
+	// Set-queue should precede the output so that the packet is sent to the queue of the output port.
+	if ok, queue := r.Queue(); ok {
+		v, err := marshalQueue(queue)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, v...)
+	}
+	for _, e := range r.Experimenters() {
+		v, err := openflow.MarshalExperimenterAction(e)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, v...)
+	}
+
+	if ok, group := r.Group(); ok {
+		v, err := marshalGroup(group)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, v...)
+	}
+	// Output is optional if the packet will be processed by a group, vendor-specific
+	// actions, or the next table. The zero OutPort (port number 0) means the output has
+	// not been set.
+	if ok, _ := r.Group(); ok || len(r.Experimenters()) > 0 || !requireOutput {
+		if r.OutPort() == (openflow.OutPort{}) {
+			return result, nil
+		}
+	}
 
 	v, err := marshalOutput(r.OutPort())
 	if err != nil {
@@ -126,9 +331,78 @@ func (r *Action) MarshalBinary() ([]byte, error) {
 	return result, nil
 }
 
-// TODO: Unmarshal Enqueue
+// unmarshalSetField decodes the set-field action of the basic OXM field whose value is value.
+func (r *Action) unmarshalSetField(field uint8, value []byte) error {
+	switch field {
+	case OFPXMT_OFB_IP_DSCP, OFPXMT_OFB_VLAN_PCP, OFPXMT_OFB_MPLS_TC:
+		if len(value) < 1 {
+			return openflow.ErrInvalidPacketLength
+		}
+	case OFPXMT_OFB_VLAN_VID, OFPXMT_OFB_TCP_SRC, OFPXMT_OFB_TCP_DST, OFPXMT_OFB_UDP_SRC, OFPXMT_OFB_UDP_DST, OFPXMT_OFB_SCTP_SRC, OFPXMT_OFB_SCTP_DST:
+		if len(value) < 2 {
+			return openflow.ErrInvalidPacketLength
+		}
+	case OFPXMT_OFB_MPLS_LABEL, OFPXMT_OFB_IPV4_SRC, OFPXMT_OFB_IPV4_DST:
+		if len(value) < 4 {
+			return openflow.ErrInvalidPacketLength
+		}
+	case OFPXMT_OFB_ETH_DST, OFPXMT_OFB_ETH_SRC:
+		if len(value) < 6 {
+			return openflow.ErrInvalidPacketLength
+		}
+	case OFPXMT_OFB_TUNNEL_ID:
+		if len(value) < 8 {
+			return openflow.ErrInvalidPacketLength
+		}
+	case OFPXMT_OFB_IPV6_SRC, OFPXMT_OFB_IPV6_DST:
+		if len(value) < 16 {
+			return openflow.ErrInvalidPacketLength
+		}
+	}
 
-// TODO: Unmarshal SetVLANVID
+	switch field {
+	case OFPXMT_OFB_ETH_DST:
+		r.SetDstMAC(value[:6])
+	case OFPXMT_OFB_ETH_SRC:
+		r.SetSrcMAC(value[:6])
+	case OFPXMT_OFB_IP_DSCP:
+		r.SetIPDSCP(value[0])
+	case OFPXMT_OFB_VLAN_VID:
+		r.SetVLANID(binary.BigEndian.Uint16(value) &^ OFPVID_PRESENT)
+	case OFPXMT_OFB_VLAN_PCP:
+		r.SetVLANPriority(value[0])
+	case OFPXMT_OFB_MPLS_LABEL:
+		r.SetMPLSLabel(binary.BigEndian.Uint32(value))
+	case OFPXMT_OFB_MPLS_TC:
+		r.SetMPLSTC(value[0])
+	case OFPXMT_OFB_IPV4_SRC:
+		r.SetSrcIP(net.IP(value[:4]))
+	case OFPXMT_OFB_IPV4_DST:
+		r.SetDstIP(net.IP(value[:4]))
+	case OFPXMT_OFB_IPV6_SRC:
+		r.SetSrcIP(net.IP(value[:16]))
+	case OFPXMT_OFB_IPV6_DST:
+		r.SetDstIP(net.IP(value[:16]))
+	case OFPXMT_OFB_TCP_SRC:
+		r.SetSrcPort(openflow.ProtocolTCP, binary.BigEndian.Uint16(value))
+	case OFPXMT_OFB_TCP_DST:
+		r.SetDstPort(openflow.ProtocolTCP, binary.BigEndian.Uint16(value))
+	case OFPXMT_OFB_UDP_SRC:
+		r.SetSrcPort(openflow.ProtocolUDP, binary.BigEndian.Uint16(value))
+	case OFPXMT_OFB_UDP_DST:
+		r.SetDstPort(openflow.ProtocolUDP, binary.BigEndian.Uint16(value))
+	case OFPXMT_OFB_SCTP_SRC:
+		r.SetSrcPort(openflow.ProtocolSCTP, binary.BigEndian.Uint16(value))
+	case OFPXMT_OFB_SCTP_DST:
+		r.SetDstPort(openflow.ProtocolSCTP, binary.BigEndian.Uint16(value))
+	case OFPXMT_OFB_TUNNEL_ID:
+		r.SetTunnelID(binary.BigEndian.Uint64(value))
+	default:
+		// Do nothing
+	}
+
+	return r.Error()
+}
 
 func (r *Action) UnmarshalBinary(data []byte) error {
 	buf := data
@@ -150,6 +424,49 @@ func (r *Action) UnmarshalBinary(data []byte) error {
 			if err := r.Error(); err != nil {
 				return err
 			}
+		case OFPAT_GROUP:
+			if len(buf) < 8 {
+				return openflow.ErrInvalidPacketLength
+			}
+			r.SetGroup(binary.BigEndian.Uint32(buf[4:8]))
+		case OFPAT_POP_VLAN:
+			r.SetPopVLAN()
+		case OFPAT_PUSH_VLAN:
+			if len(buf) < 8 {
+				return openflow.ErrInvalidPacketLength
+			}
+			r.SetPushVLAN(binary.BigEndian.Uint16(buf[4:6]))
+			if err := r.Error(); err != nil {
+				return err
+			}
+		case OFPAT_PUSH_MPLS:
+			if len(buf) < 8 {
+				return openflow.ErrInvalidPacketLength
+			}
+			r.SetPushMPLS(binary.BigEndian.Uint16(buf[4:6]))
+			if err := r.Error(); err != nil {
+				return err
+			}
+		case OFPAT_POP_MPLS:
+			if len(buf) < 8 {
+				return openflow.ErrInvalidPacketLength
+			}
+			r.SetPopMPLS(binary.BigEndian.Uint16(buf[4:6]))
+		case OFPAT_SET_QUEUE:
+			if len(buf) < 8 {
+				return openflow.ErrInvalidPacketLength
+			}
+			r.SetQueue(binary.BigEndian.Uint32(buf[4:8]))
+		case OFPAT_EXPERIMENTER:
+			action, err := openflow.UnmarshalExperimenterAction(buf)
+			if err == openflow.ErrUnknownExperimenter {
+				// Skip the actions of unknown vendors.
+				break
+			}
+			if err != nil {
+				return err
+			}
+			r.AddExperimenter(action)
 		case OFPAT_SET_FIELD:
 			if len(buf) < 8 {
 				return openflow.ErrInvalidPacketLength
@@ -160,26 +477,12 @@ func (r *Action) UnmarshalBinary(data []byte) error {
 				return errors.New("unsupported TLV class")
 			}
 			field := header >> 9 & 0x7F
-
-			switch field {
-			case OFPXMT_OFB_ETH_DST:
-				if len(buf) < 14 {
-					return openflow.ErrInvalidPacketLength
-				}
-				r.SetDstMAC(buf[8:14])
-				if err := r.Error(); err != nil {
-					return err
-				}
-			case OFPXMT_OFB_ETH_SRC:
-				if len(buf) < 14 {
-					return openflow.ErrInvalidPacketLength
-				}
-				r.SetSrcMAC(buf[8:14])
-				if err := r.Error(); err != nil {
-					return err
-				}
-			default:
-				// Do nothing
+			n := int(header & 0xFF)
+			if len(buf) < 8+n {
+				return openflow.ErrInvalidPacketLength
+			}
+			if err := r.unmarshalSetField(uint8(field), buf[8:8+n]); err != nil {
+				return err
 			}
 		default:
 			// Do nothing
@@ -65,8 +65,15 @@ const (
 )
 
 const (
-	OFPAT_OUTPUT    = 0
-	OFPAT_SET_FIELD = 25
+	OFPAT_OUTPUT       = 0
+	OFPAT_PUSH_VLAN    = 17
+	OFPAT_POP_VLAN     = 18
+	OFPAT_PUSH_MPLS    = 19
+	OFPAT_POP_MPLS     = 20
+	OFPAT_SET_QUEUE    = 21
+	OFPAT_GROUP        = 22
+	OFPAT_SET_FIELD    = 25
+	OFPAT_EXPERIMENTER = 0xffff
 )
 
 const (
@@ -84,6 +91,49 @@ const (
 	OFPP_ANY        = 0xffffffff /* Wildcard */
 )
 
+const (
+	OFPQ_ALL = 0xffffffff /* All ones is used to indicate all queues in a port (for stats retrieval). */
+)
+
+const (
+	OFPET_HELLO_FAILED          = 0      /* Hello protocol failed. */
+	OFPET_BAD_REQUEST           = 1      /* Request was not understood. */
+	OFPET_BAD_ACTION            = 2      /* Error in action description. */
+	OFPET_BAD_INSTRUCTION       = 3      /* Error in instruction list. */
+	OFPET_BAD_MATCH             = 4      /* Error in match. */
+	OFPET_FLOW_MOD_FAILED       = 5      /* Problem modifying flow entry. */
+	OFPET_GROUP_MOD_FAILED      = 6      /* Problem modifying group entry. */
+	OFPET_PORT_MOD_FAILED       = 7      /* Port mod request failed. */
+	OFPET_TABLE_MOD_FAILED      = 8      /* Table mod request failed. */
+	OFPET_QUEUE_OP_FAILED       = 9      /* Queue operation failed. */
+	OFPET_SWITCH_CONFIG_FAILED  = 10     /* Switch config request failed. */
+	OFPET_ROLE_REQUEST_FAILED   = 11     /* Controller Role request failed. */
+	OFPET_METER_MOD_FAILED      = 12     /* Error in meter. */
+	OFPET_TABLE_FEATURES_FAILED = 13     /* Setting table features failed. */
+	OFPET_EXPERIMENTER          = 0xffff /* Experimenter error messages. */
+)
+
+const (
+	OFPBRC_IS_SLAVE = 10 /* Denied because controller is slave. */
+)
+
+const (
+	OFPFMFC_TABLE_FULL = 1 /* Flow not added because table was full. */
+	OFPFMFC_OVERLAP    = 3 /* Attempted to add overlapping flow with CHECK_OVERLAP flag set. */
+)
+
+const (
+	OFPVID_PRESENT = 0x1000 /* Bit that indicate that a VLAN id is set */
+	OFPVID_NONE    = 0x0000 /* No VLAN id was set. */
+)
+
+const (
+	OFPXMC_NXM_0          = 0x0000 /* Backward compatibility with NXM */
+	OFPXMC_NXM_1          = 0x0001 /* Backward compatibility with NXM */
+	OFPXMC_OPENFLOW_BASIC = 0x8000 /* Basic class for OpenFlow */
+	OFPXMC_EXPERIMENTER   = 0xFFFF /* Experimenter class */
+)
+
 const (
 	OFPXMT_OFB_IN_PORT = iota
 	OFPXMT_OFB_IN_PHY_PORT
@@ -252,9 +302,65 @@ const (
 )
 
 const (
+	OFPMPF_REQ_MORE   = 1 << 0 /* More requests to follow. */
+	OFPMPF_REPLY_MORE = 1 << 0 /* More replies to follow. */
+)
+
+const (
+	OFPTT_MAX = 0xfe /* Last usable table number. */
+	OFPTT_ALL = 0xff /* Wildcard table used for table config, flow stats and flow deletes. */
+)
+
+const (
+	OFPG_MAX = 0xffffff00 /* Last usable group number. */
+	OFPG_ALL = 0xfffffffc /* Represents all groups for group delete commands. */
 	OFPG_ANY = 0xffffffff
 )
 
+const (
+	OFPGC_ADD    = 0 /* New group. */
+	OFPGC_MODIFY = 1 /* Modify all matching groups. */
+	OFPGC_DELETE = 2 /* Delete all matching groups. */
+)
+
+const (
+	OFPGT_ALL      = 0 /* All (multicast/broadcast) group. */
+	OFPGT_SELECT   = 1 /* Select group. */
+	OFPGT_INDIRECT = 2 /* Indirect group. */
+	OFPGT_FF       = 3 /* Fast failover group. */
+)
+
+const (
+	OFPCR_ROLE_NOCHANGE = 0 /* Don't change current role. */
+	OFPCR_ROLE_EQUAL    = 1 /* Default role, full access. */
+	OFPCR_ROLE_MASTER   = 2 /* Full access, at most one master. */
+	OFPCR_ROLE_SLAVE    = 3 /* Read-only access. */
+)
+
+const (
+	OFPM_MAX = 0xffff0000 /* Last usable meter. */
+	OFPM_ALL = 0xffffffff /* Represents all meters for stat requests commands. */
+)
+
+const (
+	OFPMC_ADD    = 0 /* New meter. */
+	OFPMC_MODIFY = 1 /* Modify specified meter. */
+	OFPMC_DELETE = 2 /* Delete specified meter. */
+)
+
+const (
+	OFPMF_KBPS  = 1 << 0 /* Rate value in kb/s (kilo-bit per second). */
+	OFPMF_PKTPS = 1 << 1 /* Rate value in packet/sec. */
+	OFPMF_BURST = 1 << 2 /* Do burst size. */
+	OFPMF_STATS = 1 << 3 /* Collect statistics. */
+)
+
+const (
+	OFPMBT_DROP         = 1 /* Drop packet. */
+	OFPMBT_DSCP_REMARK  = 2 /* Remark DSCP in the IP header. */
+	OFPMBT_EXPERIMENTER = 0xFFFF
+)
+
 const (
 	OFPC_FRAG_NORMAL = 0      /* No special handling for fragments. */
 	OFPC_FRAG_DROP   = 1 << 0 /* Drop fragments. */
@@ -268,6 +374,12 @@ const (
 	OFPPR_MODIFY = 2
 )
 
+const (
+	OFPR_NO_MATCH    = 0
+	OFPR_ACTION      = 1
+	OFPR_INVALID_TTL = 2
+)
+
 const (
 	OFPIT_GOTO_TABLE     = 1      /* Setup the next table in the lookup pipeline */
 	OFPIT_WRITE_METADATA = 2      /* Setup the metadata field for use later in pipeline */
@@ -277,3 +389,22 @@ const (
 	OFPIT_METER          = 6      /* Apply meter (rate limiter) */
 	OFPIT_EXPERIMENTER   = 0xFFFF /* Experimenter instruction */
 )
+
+const (
+	OFPTFPT_INSTRUCTIONS        = 0      /* Instructions property. */
+	OFPTFPT_INSTRUCTIONS_MISS   = 1      /* Instructions for table-miss. */
+	OFPTFPT_NEXT_TABLES         = 2      /* Next Table property. */
+	OFPTFPT_NEXT_TABLES_MISS    = 3      /* Next Table for table-miss. */
+	OFPTFPT_WRITE_ACTIONS       = 4      /* Write Actions property. */
+	OFPTFPT_WRITE_ACTIONS_MISS  = 5      /* Write Actions for table-miss. */
+	OFPTFPT_APPLY_ACTIONS       = 6      /* Apply Actions property. */
+	OFPTFPT_APPLY_ACTIONS_MISS  = 7      /* Apply Actions for table-miss. */
+	OFPTFPT_MATCH               = 8      /* Match property. */
+	OFPTFPT_WILDCARDS           = 10     /* Wildcards property. */
+	OFPTFPT_WRITE_SETFIELD      = 12     /* Write Set-Field property. */
+	OFPTFPT_WRITE_SETFIELD_MISS = 13     /* Write Set-Field for table-miss. */
+	OFPTFPT_APPLY_SETFIELD      = 14     /* Apply Set-Field property. */
+	OFPTFPT_APPLY_SETFIELD_MISS = 15     /* Apply Set-Field for table-miss. */
+	OFPTFPT_EXPERIMENTER        = 0xFFFE /* Experimenter property. */
+	OFPTFPT_EXPERIMENTER_MISS   = 0xFFFF /* Experimenter for table-miss. */
+)
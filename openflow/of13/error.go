@@ -0,0 +1,105 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package of13
+
+import (
+	"fmt"
+
+	"github.com/superkkt/cherry/openflow"
+)
+
+type errorClass struct {
+	name  string
+	cause error
+	// Names of the codes indexed by the code value.
+	codes []string
+}
+
+var errorClasses = map[uint16]errorClass{
+	OFPET_HELLO_FAILED: {"HELLO_FAILED", openflow.ErrHelloFailed, []string{"INCOMPATIBLE", "EPERM"}},
+	OFPET_BAD_REQUEST: {"BAD_REQUEST", openflow.ErrBadRequest, []string{
+		"BAD_VERSION", "BAD_TYPE", "BAD_MULTIPART", "BAD_EXPERIMENTER", "BAD_EXP_TYPE", "EPERM", "BAD_LEN",
+		"BUFFER_EMPTY", "BUFFER_UNKNOWN", "BAD_TABLE_ID", "IS_SLAVE", "BAD_PORT", "BAD_PACKET", "MULTIPART_BUFFER_OVERFLOW",
+	}},
+	OFPET_BAD_ACTION: {"BAD_ACTION", openflow.ErrBadAction, []string{
+		"BAD_TYPE", "BAD_LEN", "BAD_EXPERIMENTER", "BAD_EXP_TYPE", "BAD_OUT_PORT", "BAD_ARGUMENT", "EPERM", "TOO_MANY",
+		"BAD_QUEUE", "BAD_OUT_GROUP", "MATCH_INCONSISTENT", "UNSUPPORTED_ORDER", "BAD_TAG", "BAD_SET_TYPE", "BAD_SET_LEN", "BAD_SET_ARGUMENT",
+	}},
+	OFPET_BAD_INSTRUCTION: {"BAD_INSTRUCTION", openflow.ErrBadInstruction, []string{
+		"UNKNOWN_INST", "UNSUP_INST", "BAD_TABLE_ID", "UNSUP_METADATA", "UNSUP_METADATA_MASK", "BAD_EXPERIMENTER", "BAD_EXP_TYPE", "BAD_LEN", "EPERM",
+	}},
+	OFPET_BAD_MATCH: {"BAD_MATCH", openflow.ErrBadMatch, []string{
+		"BAD_TYPE", "BAD_LEN", "BAD_TAG", "BAD_DL_ADDR_MASK", "BAD_NW_ADDR_MASK", "BAD_WILDCARDS", "BAD_FIELD", "BAD_VALUE", "BAD_MASK", "BAD_PREREQ", "DUP_FIELD", "EPERM",
+	}},
+	OFPET_FLOW_MOD_FAILED: {"FLOW_MOD_FAILED", openflow.ErrFlowModFailed, []string{
+		"UNKNOWN", "TABLE_FULL", "BAD_TABLE_ID", "OVERLAP", "EPERM", "BAD_TIMEOUT", "BAD_COMMAND", "BAD_FLAGS",
+	}},
+	OFPET_GROUP_MOD_FAILED: {"GROUP_MOD_FAILED", openflow.ErrGroupModFailed, []string{
+		"GROUP_EXISTS", "INVALID_GROUP", "WEIGHT_UNSUPPORTED", "OUT_OF_GROUPS", "OUT_OF_BUCKETS", "CHAINING_UNSUPPORTED", "WATCH_UNSUPPORTED",
+		"LOOP", "UNKNOWN_GROUP", "CHAINED_GROUP", "BAD_TYPE", "BAD_COMMAND", "BAD_BUCKET", "BAD_WATCH", "EPERM",
+	}},
+	OFPET_PORT_MOD_FAILED:      {"PORT_MOD_FAILED", openflow.ErrPortModFailed, []string{"BAD_PORT", "BAD_HW_ADDR", "BAD_CONFIG", "BAD_ADVERTISE", "EPERM"}},
+	OFPET_TABLE_MOD_FAILED:     {"TABLE_MOD_FAILED", openflow.ErrTableModFailed, []string{"BAD_TABLE", "BAD_CONFIG", "EPERM"}},
+	OFPET_QUEUE_OP_FAILED:      {"QUEUE_OP_FAILED", openflow.ErrQueueOpFailed, []string{"BAD_PORT", "BAD_QUEUE", "EPERM"}},
+	OFPET_SWITCH_CONFIG_FAILED: {"SWITCH_CONFIG_FAILED", openflow.ErrSwitchConfigFailed, []string{"BAD_FLAGS", "BAD_LEN", "EPERM"}},
+	OFPET_ROLE_REQUEST_FAILED:  {"ROLE_REQUEST_FAILED", openflow.ErrRoleRequestFailed, []string{"STALE", "UNSUP", "BAD_ROLE"}},
+	OFPET_METER_MOD_FAILED: {"METER_MOD_FAILED", openflow.ErrMeterModFailed, []string{
+		"UNKNOWN", "METER_EXISTS", "INVALID_METER", "UNKNOWN_METER", "BAD_COMMAND", "BAD_FLAGS", "BAD_RATE", "BAD_BURST",
+		"BAD_BAND", "BAD_BAND_VALUE", "OUT_OF_METERS", "OUT_OF_BANDS",
+	}},
+	OFPET_TABLE_FEATURES_FAILED: {"TABLE_FEATURES_FAILED", openflow.ErrTableFeaturesFailed, []string{"BAD_TABLE", "BAD_METADATA", "BAD_TYPE", "BAD_LEN", "BAD_ARGUMENT", "EPERM"}},
+	// The code is defined by the experimenter.
+	OFPET_EXPERIMENTER: {"EXPERIMENTER", openflow.ErrExperimenterFailed, nil},
+}
+
+type Error struct {
+	openflow.BaseError
+}
+
+func NewError() openflow.Error {
+	return new(Error)
+}
+
+func (r *Error) Err() error {
+	class, ok := errorClasses[r.Class()]
+	if !ok {
+		return openflow.NewDeviceError(r, "", nil)
+	}
+
+	code := fmt.Sprintf("%v", r.Code())
+	if int(r.Code()) < len(class.codes) {
+		code = class.codes[r.Code()]
+	}
+	cause := class.cause
+	switch {
+	case code == "EPERM":
+		cause = openflow.ErrPermission
+	case r.Class() == OFPET_BAD_REQUEST && r.Code() == OFPBRC_IS_SLAVE:
+		cause = openflow.ErrNotMaster
+	case r.Class() == OFPET_FLOW_MOD_FAILED && r.Code() == OFPFMFC_TABLE_FULL:
+		cause = openflow.ErrTableFull
+	case r.Class() == OFPET_FLOW_MOD_FAILED && r.Code() == OFPFMFC_OVERLAP:
+		cause = openflow.ErrFlowOverlap
+	}
+
+	return openflow.NewDeviceError(r, class.name+"/"+code, cause)
+}
@@ -103,6 +103,10 @@ func getFlowModCmd(cmd openflow.FlowModCmd) uint8 {
 		c = OFPFC_MODIFY
 	case openflow.FlowDelete:
 		c = OFPFC_DELETE
+	case openflow.FlowModifyStrict:
+		c = OFPFC_MODIFY_STRICT
+	case openflow.FlowDeleteStrict:
+		c = OFPFC_DELETE_STRICT
 	default:
 		panic(fmt.Sprintf("unexpected FlowModCmd: %v", cmd))
 	}
@@ -126,6 +130,62 @@ func (r *Factory) NewPacketOut() (openflow.PacketOut, error) {
 	return NewPacketOut(r.getTransactionID()), nil
 }
 
+func getGroupModCmd(cmd openflow.GroupModCmd) uint16 {
+	var c uint16
+	switch cmd {
+	case openflow.GroupAdd:
+		c = OFPGC_ADD
+	case openflow.GroupModify:
+		c = OFPGC_MODIFY
+	case openflow.GroupDelete:
+		c = OFPGC_DELETE
+	default:
+		panic(fmt.Sprintf("unexpected GroupModCmd: %v", cmd))
+	}
+
+	return c
+}
+
+func (r *Factory) NewGroupMod(cmd openflow.GroupModCmd) (openflow.GroupMod, error) {
+	return NewGroupMod(r.getTransactionID(), getGroupModCmd(cmd)), nil
+}
+
+func getMeterModCmd(cmd openflow.MeterModCmd) uint16 {
+	var c uint16
+	switch cmd {
+	case openflow.MeterAdd:
+		c = OFPMC_ADD
+	case openflow.MeterModify:
+		c = OFPMC_MODIFY
+	case openflow.MeterDelete:
+		c = OFPMC_DELETE
+	default:
+		panic(fmt.Sprintf("unexpected MeterModCmd: %v", cmd))
+	}
+
+	return c
+}
+
+func (r *Factory) NewMeterMod(cmd openflow.MeterModCmd) (openflow.MeterMod, error) {
+	return NewMeterMod(r.getTransactionID(), getMeterModCmd(cmd)), nil
+}
+
+func (r *Factory) NewRoleRequest() (openflow.RoleRequest, error) {
+	return NewRoleRequest(r.getTransactionID()), nil
+}
+
+func (r *Factory) NewRoleReply() (openflow.RoleReply, error) {
+	return new(RoleReply), nil
+}
+
+func (r *Factory) NewPortStatsRequest() (openflow.PortStatsRequest, error) {
+	return NewPortStatsRequest(r.getTransactionID()), nil
+}
+
+func (r *Factory) NewPortStatsReply() (openflow.PortStatsReply, error) {
+	return new(PortStatsReply), nil
+}
+
 func (r *Factory) NewPortStatus() (openflow.PortStatus, error) {
 	return new(PortStatus), nil
 }
@@ -142,7 +202,9 @@ func (r *Factory) NewFlowStatsRequest() (openflow.FlowStatsRequest, error) {
 	return NewFlowStatsRequest(r.getTransactionID()), nil
 }
 
-// TODO: NewFlowStatsReply() (openflow.FlowStatsReply, error) {
+func (r *Factory) NewFlowStatsReply() (openflow.FlowStatsReply, error) {
+	return new(FlowStatsReply), nil
+}
 
 func (r *Factory) NewPortDescRequest() (openflow.PortDescRequest, error) {
 	return NewPortDescRequest(r.getTransactionID()), nil
@@ -156,11 +218,17 @@ func (r *Factory) NewTableFeaturesRequest() (openflow.TableFeaturesRequest, erro
 	return NewTableFeaturesRequest(r.getTransactionID()), nil
 }
 
+func (r *Factory) NewTableFeaturesReply() (openflow.TableFeaturesReply, error) {
+	return new(TableFeaturesReply), nil
+}
+
 func (r *Factory) NewError() (openflow.Error, error) {
-	return new(openflow.BaseError), nil
+	return NewError(), nil
 }
 
-// TODO: NewTableFeaturesReply() (TableFeaturesReply, error)
+func (r *Factory) NewExperimenter() (openflow.Experimenter, error) {
+	return NewExperimenter(r.getTransactionID()), nil
+}
 
 func (r *Factory) NewInstruction() (openflow.Instruction, error) {
 	return new(Instruction), nil
@@ -169,3 +237,15 @@ func (r *Factory) NewInstruction() (openflow.Instruction, error) {
 func (r *Factory) NewQueueGetConfigRequest() (openflow.QueueGetConfigRequest, error) {
 	return NewQueueGetConfigRequest(r.getTransactionID()), nil
 }
+
+func (r *Factory) NewQueueGetConfigReply() (openflow.QueueGetConfigReply, error) {
+	return new(QueueGetConfigReply), nil
+}
+
+func (r *Factory) NewQueueStatsRequest() (openflow.QueueStatsRequest, error) {
+	return NewQueueStatsRequest(r.getTransactionID()), nil
+}
+
+func (r *Factory) NewQueueStatsReply() (openflow.QueueStatsReply, error) {
+	return new(QueueStatsReply), nil
+}
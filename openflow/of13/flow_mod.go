@@ -24,6 +24,7 @@ package of13
 import (
 	"encoding/binary"
 	"errors"
+	"fmt"
 
 	"github.com/superkkt/cherry/openflow"
 )
@@ -33,6 +34,7 @@ type FlowMod struct {
 	openflow.Message
 	command     uint8
 	cookie      uint64
+	bufferID    uint32
 	cookieMask  uint64
 	tableID     uint8
 	idleTimeout uint16
@@ -49,12 +51,38 @@ func NewFlowMod(xid uint32, cmd uint8) openflow.FlowMod {
 	outPort.SetNone()
 
 	return &FlowMod{
-		Message: openflow.NewMessage(openflow.OF13_VERSION, OFPT_FLOW_MOD, xid),
-		command: cmd,
-		outPort: outPort,
+		Message:  openflow.NewMessage(openflow.OF13_VERSION, OFPT_FLOW_MOD, xid),
+		command:  cmd,
+		outPort:  outPort,
+		bufferID: openflow.NoBuffer,
 	}
 }
 
+func (r *FlowMod) Command() openflow.FlowModCmd {
+	switch r.command {
+	case OFPFC_ADD:
+		return openflow.FlowAdd
+	case OFPFC_MODIFY:
+		return openflow.FlowModify
+	case OFPFC_DELETE:
+		return openflow.FlowDelete
+	case OFPFC_MODIFY_STRICT:
+		return openflow.FlowModifyStrict
+	case OFPFC_DELETE_STRICT:
+		return openflow.FlowDeleteStrict
+	default:
+		panic(fmt.Sprintf("unexpected flow command: %v", r.command))
+	}
+}
+
+func (r *FlowMod) BufferID() uint32 {
+	return r.bufferID
+}
+
+func (r *FlowMod) SetBufferID(id uint32) {
+	r.bufferID = id
+}
+
 func (r *FlowMod) Error() error {
 	return r.err
 }
@@ -150,7 +178,7 @@ func (r *FlowMod) MarshalBinary() ([]byte, error) {
 	binary.BigEndian.PutUint16(v[18:20], r.idleTimeout)
 	binary.BigEndian.PutUint16(v[20:22], r.hardTimeout)
 	binary.BigEndian.PutUint16(v[22:24], r.priority)
-	binary.BigEndian.PutUint32(v[24:28], OFP_NO_BUFFER)
+	binary.BigEndian.PutUint32(v[24:28], r.bufferID)
 	if r.outPort.IsNone() {
 		binary.BigEndian.PutUint32(v[28:32], OFPP_ANY)
 	} else {
@@ -179,3 +207,56 @@ func (r *FlowMod) MarshalBinary() ([]byte, error) {
 	r.SetPayload(v)
 	return r.Message.MarshalBinary()
 }
+
+// UnmarshalBinary decodes a FLOW_MOD that has been encoded by MarshalBinary, e.g., to restore
+// a flow saved by the controller. The flags are ignored as we always set OFPFF_SEND_FLOW_REM.
+func (r *FlowMod) UnmarshalBinary(data []byte) error {
+	if err := r.Message.UnmarshalBinary(data); err != nil {
+		return err
+	}
+
+	payload := r.Payload()
+	if payload == nil || len(payload) < 48 {
+		return openflow.ErrInvalidPacketLength
+	}
+	r.cookie = binary.BigEndian.Uint64(payload[0:8])
+	r.cookieMask = binary.BigEndian.Uint64(payload[8:16])
+	r.tableID = payload[16]
+	r.command = payload[17]
+	if r.command > OFPFC_DELETE_STRICT {
+		return fmt.Errorf("unexpected flow command: %v", r.command)
+	}
+	r.idleTimeout = binary.BigEndian.Uint16(payload[18:20])
+	r.hardTimeout = binary.BigEndian.Uint16(payload[20:22])
+	r.priority = binary.BigEndian.Uint16(payload[22:24])
+	r.bufferID = binary.BigEndian.Uint32(payload[24:28])
+	r.outPort = openflow.NewOutPort()
+	if port := binary.BigEndian.Uint32(payload[28:32]); port == OFPP_ANY {
+		r.outPort.SetNone()
+	} else {
+		r.outPort.SetValue(port)
+	}
+
+	match := NewMatch()
+	if err := match.UnmarshalBinary(payload[40:]); err != nil {
+		return err
+	}
+	r.match = match
+	// The match is padded to a multiple of 8 bytes.
+	length := int(binary.BigEndian.Uint16(payload[42:44]))
+	length = (length + 7) / 8 * 8
+	if len(payload) < 40+length {
+		return openflow.ErrInvalidPacketLength
+	}
+	r.instruction = nil
+	if len(payload) == 40+length {
+		return nil
+	}
+	inst := new(Instruction)
+	if err := inst.UnmarshalBinary(payload[40+length:]); err != nil {
+		return err
+	}
+	r.instruction = inst
+
+	return nil
+}
@@ -0,0 +1,250 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package of13
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/superkkt/cherry/openflow"
+)
+
+// ValidateFlowMod returns an error if flow cannot be installed into the flow tables whose
+// capabilities are tables, which are reported by a table features reply. The properties that
+// the switch has not reported are not checked.
+func ValidateFlowMod(tables []openflow.TableFeatures, flow openflow.FlowMod) error {
+	var table *openflow.TableFeatures
+	for i := range tables {
+		if tables[i].TableID == flow.TableID() {
+			table = &tables[i]
+			break
+		}
+	}
+	if table == nil {
+		return fmt.Errorf("table %v does not exist", flow.TableID())
+	}
+
+	var fields []openflow.OXMField
+	if match := flow.FlowMatch(); match != nil {
+		v, err := match.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		if fields, err = matchFields(v); err != nil {
+			return err
+		}
+	}
+	if err := validateMatch(table, fields); err != nil {
+		return err
+	}
+
+	inst := flow.FlowInstruction()
+	if inst == nil {
+		// No instruction drops the packets.
+		return nil
+	}
+	v, err := inst.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	// Table-miss flow entry has the lowest priority and wildcards all fields.
+	miss := flow.Priority() == 0 && len(fields) == 0
+
+	return validateInstructions(table, miss, v)
+}
+
+func validateMatch(table *openflow.TableFeatures, fields []openflow.OXMField) error {
+	if table.Match == nil {
+		return nil
+	}
+	for _, f := range fields {
+		v, ok := findOXMField(table.Match, f)
+		if !ok {
+			return fmt.Errorf("table %v does not support matching on OXM field %v:%v", table.TableID, f.Class, f.Field)
+		}
+		if f.HasMask && !v.HasMask {
+			return fmt.Errorf("table %v does not support masking OXM field %v:%v", table.TableID, f.Class, f.Field)
+		}
+	}
+
+	if table.Wildcards == nil {
+		return nil
+	}
+	// The fields that cannot be wildcarded should be in the match.
+	for _, f := range table.Match {
+		if _, ok := findOXMField(fields, f); ok {
+			continue
+		}
+		if _, ok := findOXMField(table.Wildcards, f); !ok {
+			return fmt.Errorf("table %v requires OXM field %v:%v in the match", table.TableID, f.Class, f.Field)
+		}
+	}
+
+	return nil
+}
+
+func validateInstructions(table *openflow.TableFeatures, miss bool, data []byte) error {
+	instructions := table.Instructions
+	nextTables := table.NextTables
+	writeActions, applyActions := table.WriteActions, table.ApplyActions
+	writeSetField, applySetField := table.WriteSetField, table.ApplySetField
+	if miss {
+		if table.InstructionsMiss != nil {
+			instructions = table.InstructionsMiss
+		}
+		if table.NextTablesMiss != nil {
+			nextTables = table.NextTablesMiss
+		}
+		if table.WriteActionsMiss != nil {
+			writeActions = table.WriteActionsMiss
+		}
+		if table.ApplyActionsMiss != nil {
+			applyActions = table.ApplyActionsMiss
+		}
+		if table.WriteSetFieldMiss != nil {
+			writeSetField = table.WriteSetFieldMiss
+		}
+		if table.ApplySetFieldMiss != nil {
+			applySetField = table.ApplySetFieldMiss
+		}
+	}
+
+	for len(data) >= 4 {
+		t := binary.BigEndian.Uint16(data[0:2])
+		length := int(binary.BigEndian.Uint16(data[2:4]))
+		if length < 4 || len(data) < length {
+			return openflow.ErrInvalidPacketLength
+		}
+		if instructions != nil && !containsUint16(instructions, t) {
+			return fmt.Errorf("table %v does not support instruction type %v", table.TableID, t)
+		}
+
+		var err error
+		switch t {
+		case OFPIT_GOTO_TABLE:
+			if length < 8 {
+				return openflow.ErrInvalidPacketLength
+			}
+			if nextTables != nil && !containsUint8(nextTables, data[4]) {
+				err = fmt.Errorf("table %v cannot go to table %v", table.TableID, data[4])
+			}
+		case OFPIT_WRITE_ACTIONS:
+			err = validateActions(table.TableID, writeActions, writeSetField, data[8:length])
+		case OFPIT_APPLY_ACTIONS:
+			err = validateActions(table.TableID, applyActions, applySetField, data[8:length])
+		}
+		if err != nil {
+			return err
+		}
+
+		data = data[length:]
+	}
+
+	return nil
+}
+
+func validateActions(tableID uint8, actions []uint16, setFields []openflow.OXMField, data []byte) error {
+	for len(data) >= 4 {
+		t := binary.BigEndian.Uint16(data[0:2])
+		length := int(binary.BigEndian.Uint16(data[2:4]))
+		if length < 4 || len(data) < length {
+			return openflow.ErrInvalidPacketLength
+		}
+		if actions != nil && !containsUint16(actions, t) {
+			return fmt.Errorf("table %v does not support action type %v", tableID, t)
+		}
+		if t == OFPAT_SET_FIELD && setFields != nil {
+			if length < 8 {
+				return openflow.ErrInvalidPacketLength
+			}
+			header := binary.BigEndian.Uint32(data[4:8])
+			f := openflow.OXMField{Class: uint16(header >> 16), Field: uint8(header >> 9 & 0x7F)}
+			if _, ok := findOXMField(setFields, f); !ok {
+				return fmt.Errorf("table %v does not support setting OXM field %v:%v", tableID, f.Class, f.Field)
+			}
+		}
+
+		data = data[length:]
+	}
+
+	return nil
+}
+
+// matchFields returns the fields of an encoded ofp_match.
+func matchFields(data []byte) ([]openflow.OXMField, error) {
+	if len(data) < 4 {
+		return nil, openflow.ErrInvalidPacketLength
+	}
+	length := int(binary.BigEndian.Uint16(data[2:4]))
+	if length < 4 || len(data) < length {
+		return nil, openflow.ErrInvalidPacketLength
+	}
+
+	fields := make([]openflow.OXMField, 0)
+	buf := data[4:length]
+	for len(buf) >= 4 {
+		header := binary.BigEndian.Uint32(buf[0:4])
+		fields = append(fields, openflow.OXMField{
+			Class:   uint16(header >> 16),
+			Field:   uint8(header >> 9 & 0x7F),
+			HasMask: header>>8&0x1 == 1,
+		})
+		n := 4 + int(header&0xFF)
+		if len(buf) < n {
+			return nil, openflow.ErrInvalidPacketLength
+		}
+		buf = buf[n:]
+	}
+
+	return fields, nil
+}
+
+// findOXMField returns the field in fields that has the same class and field number with f.
+func findOXMField(fields []openflow.OXMField, f openflow.OXMField) (openflow.OXMField, bool) {
+	for _, v := range fields {
+		if v.Class == f.Class && v.Field == f.Field {
+			return v, true
+		}
+	}
+
+	return openflow.OXMField{}, false
+}
+
+func containsUint16(list []uint16, v uint16) bool {
+	for _, e := range list {
+		if e == v {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsUint8(list []uint8, v uint8) bool {
+	for _, e := range list {
+		if e == v {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,207 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package of13
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/superkkt/cherry/openflow"
+)
+
+type GroupMod struct {
+	err error
+	openflow.Message
+	command   uint16
+	groupType uint8
+	groupID   uint32
+	buckets   []openflow.Bucket
+}
+
+func NewGroupMod(xid uint32, cmd uint16) openflow.GroupMod {
+	return &GroupMod{
+		Message: openflow.NewMessage(openflow.OF13_VERSION, OFPT_GROUP_MOD, xid),
+		command: cmd,
+	}
+}
+
+func (r *GroupMod) Error() error {
+	return r.err
+}
+
+func (r *GroupMod) Command() openflow.GroupModCmd {
+	switch r.command {
+	case OFPGC_ADD:
+		return openflow.GroupAdd
+	case OFPGC_MODIFY:
+		return openflow.GroupModify
+	case OFPGC_DELETE:
+		return openflow.GroupDelete
+	default:
+		panic(fmt.Sprintf("unexpected group command: %v", r.command))
+	}
+}
+
+func (r *GroupMod) GroupType() openflow.GroupType {
+	switch r.groupType {
+	case OFPGT_ALL:
+		return openflow.GroupAll
+	case OFPGT_SELECT:
+		return openflow.GroupSelect
+	case OFPGT_INDIRECT:
+		return openflow.GroupIndirect
+	case OFPGT_FF:
+		return openflow.GroupFastFailover
+	default:
+		panic(fmt.Sprintf("unexpected group type: %v", r.groupType))
+	}
+}
+
+func (r *GroupMod) SetGroupType(t openflow.GroupType) {
+	switch t {
+	case openflow.GroupAll:
+		r.groupType = OFPGT_ALL
+	case openflow.GroupSelect:
+		r.groupType = OFPGT_SELECT
+	case openflow.GroupIndirect:
+		r.groupType = OFPGT_INDIRECT
+	case openflow.GroupFastFailover:
+		r.groupType = OFPGT_FF
+	default:
+		r.err = fmt.Errorf("SetGroupType: unexpected group type: %v", t)
+	}
+}
+
+func (r *GroupMod) GroupID() uint32 {
+	return r.groupID
+}
+
+func (r *GroupMod) SetGroupID(id uint32) {
+	if id > OFPG_MAX && id != OFPG_ALL {
+		r.err = fmt.Errorf("SetGroupID: invalid group ID: %v", id)
+		return
+	}
+	r.groupID = id
+}
+
+func (r *GroupMod) Buckets() []openflow.Bucket {
+	return r.buckets
+}
+
+func (r *GroupMod) AddBucket(b openflow.Bucket) {
+	if b.Action == nil {
+		panic("bucket action is nil")
+	}
+	r.buckets = append(r.buckets, b)
+}
+
+func marshalBucket(b openflow.Bucket) ([]byte, error) {
+	action, err := b.Action.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	v := make([]byte, 16)
+	binary.BigEndian.PutUint16(v[0:2], uint16(16+len(action)))
+	binary.BigEndian.PutUint16(v[2:4], b.Weight)
+	binary.BigEndian.PutUint32(v[4:8], b.WatchPort)
+	binary.BigEndian.PutUint32(v[8:12], b.WatchGroup)
+	// v[12:16] is padding
+	v = append(v, action...)
+
+	return v, nil
+}
+
+func (r *GroupMod) MarshalBinary() ([]byte, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	if r.groupID == OFPG_ALL && r.command != OFPGC_DELETE {
+		return nil, errors.New("all groups can be specified only by the delete command")
+	}
+	if r.groupType == OFPGT_INDIRECT && r.command != OFPGC_DELETE && len(r.buckets) != 1 {
+		return nil, errors.New("indirect group should have exactly one bucket")
+	}
+
+	v := make([]byte, 8)
+	binary.BigEndian.PutUint16(v[0:2], r.command)
+	v[2] = r.groupType
+	// v[3] is padding
+	binary.BigEndian.PutUint32(v[4:8], r.groupID)
+	for _, b := range r.buckets {
+		bucket, err := marshalBucket(b)
+		if err != nil {
+			return nil, err
+		}
+		v = append(v, bucket...)
+	}
+	r.SetPayload(v)
+
+	return r.Message.MarshalBinary()
+}
+
+func (r *GroupMod) UnmarshalBinary(data []byte) error {
+	if err := r.Message.UnmarshalBinary(data); err != nil {
+		return err
+	}
+
+	payload := r.Payload()
+	if payload == nil || len(payload) < 8 {
+		return openflow.ErrInvalidPacketLength
+	}
+	r.command = binary.BigEndian.Uint16(payload[0:2])
+	if r.command > OFPGC_DELETE {
+		return fmt.Errorf("unexpected group command: %v", r.command)
+	}
+	r.groupType = payload[2]
+	if r.groupType > OFPGT_FF {
+		return fmt.Errorf("unexpected group type: %v", r.groupType)
+	}
+	r.groupID = binary.BigEndian.Uint32(payload[4:8])
+
+	r.buckets = nil
+	buf := payload[8:]
+	for len(buf) > 0 {
+		if len(buf) < 16 {
+			return openflow.ErrInvalidPacketLength
+		}
+		length := int(binary.BigEndian.Uint16(buf[0:2]))
+		if length < 16 || len(buf) < length {
+			return openflow.ErrInvalidPacketLength
+		}
+
+		action := NewAction()
+		if err := action.UnmarshalBinary(buf[16:length]); err != nil {
+			return err
+		}
+		r.buckets = append(r.buckets, openflow.Bucket{
+			Weight:     binary.BigEndian.Uint16(buf[2:4]),
+			WatchPort:  binary.BigEndian.Uint32(buf[4:8]),
+			WatchGroup: binary.BigEndian.Uint32(buf[8:12]),
+			Action:     action,
+		})
+		buf = buf[length:]
+	}
+
+	return nil
+}
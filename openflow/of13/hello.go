@@ -26,7 +26,11 @@ import (
 )
 
 func NewHello(xid uint32) openflow.Hello {
-	return &openflow.BaseHello{
+	v := &openflow.BaseHello{
 		Message: openflow.NewMessage(openflow.OF13_VERSION, OFPT_HELLO, xid),
 	}
+	// Advertise all the versions that we support.
+	v.SetVersions([]uint8{openflow.OF10_VERSION, openflow.OF13_VERSION})
+
+	return v
 }
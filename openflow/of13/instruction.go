@@ -25,6 +25,7 @@ import (
 	"encoding"
 	"encoding/binary"
 	"errors"
+	"fmt"
 
 	"github.com/superkkt/cherry/openflow"
 )
@@ -32,6 +33,78 @@ import (
 type Instruction struct {
 	err   error
 	value encoding.BinaryMarshaler
+	// 0 means no meter
+	meter uint32
+}
+
+type instructionSet struct {
+	set openflow.InstructionSet
+}
+
+func marshalActions(t uint16, act openflow.Action, requireOutput bool) ([]byte, error) {
+	var action []byte
+	var err error
+	if v, ok := act.(*Action); ok {
+		action, err = v.marshal(requireOutput)
+	} else {
+		action, err = act.MarshalBinary()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	v := make([]byte, 8)
+	v = append(v, action...)
+	binary.BigEndian.PutUint16(v[0:2], t)
+	binary.BigEndian.PutUint16(v[2:4], uint16(len(v)))
+
+	return v, nil
+}
+
+func marshalWriteMetadata(metadata, mask uint64) []byte {
+	v := make([]byte, 24)
+	binary.BigEndian.PutUint16(v[0:2], OFPIT_WRITE_METADATA)
+	binary.BigEndian.PutUint16(v[2:4], 24)
+	// v[4:8] is padding
+	binary.BigEndian.PutUint64(v[8:16], metadata)
+	binary.BigEndian.PutUint64(v[16:24], mask)
+
+	return v
+}
+
+// MarshalBinary encodes the instructions except the meter in the order of execution.
+func (r *instructionSet) MarshalBinary() ([]byte, error) {
+	result := make([]byte, 0)
+	// The packet is output by the action set or the next table if the actions have no output.
+	if r.set.Apply != nil {
+		v, err := marshalActions(OFPIT_APPLY_ACTIONS, r.set.Apply, !r.set.Goto && r.set.Write == nil)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, v...)
+	}
+	if r.set.Write != nil {
+		v, err := marshalActions(OFPIT_WRITE_ACTIONS, r.set.Write, !r.set.Goto)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, v...)
+	}
+	if r.set.MetadataMask != 0 {
+		result = append(result, marshalWriteMetadata(r.set.Metadata, r.set.MetadataMask)...)
+	}
+	if r.set.Goto {
+		v, err := (&gotoTable{tableID: r.set.GotoTable}).MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, v...)
+	}
+	if len(result) == 0 {
+		return nil, errors.New("empty instruction set")
+	}
+
+	return result, nil
 }
 
 type gotoTable struct {
@@ -92,6 +165,49 @@ func (r *applyAction) MarshalBinary() ([]byte, error) {
 	return v, nil
 }
 
+type applyAndGoto struct {
+	action  openflow.Action
+	tableID uint8
+}
+
+func (r *applyAndGoto) MarshalBinary() ([]byte, error) {
+	if r.action == nil {
+		return nil, errors.New("empty action")
+	}
+
+	var action []byte
+	var err error
+	// The packet is output by the next table if the action has no output.
+	if v, ok := r.action.(*Action); ok {
+		action, err = v.marshal(false)
+	} else {
+		action, err = r.action.MarshalBinary()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	v := make([]byte, 8)
+	v = append(v, action...)
+	binary.BigEndian.PutUint16(v[0:2], OFPIT_APPLY_ACTIONS)
+	binary.BigEndian.PutUint16(v[2:4], uint16(len(v)))
+	next, err := (&gotoTable{tableID: r.tableID}).MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	return append(v, next...), nil
+}
+
+func marshalMeter(id uint32) []byte {
+	v := make([]byte, 8)
+	binary.BigEndian.PutUint16(v[0:2], OFPIT_METER)
+	binary.BigEndian.PutUint16(v[2:4], 8)
+	binary.BigEndian.PutUint32(v[4:8], id)
+
+	return v
+}
+
 func (r *Instruction) Error() error {
 	return r.err
 }
@@ -114,6 +230,54 @@ func (r *Instruction) ApplyAction(act openflow.Action) {
 	r.value = &applyAction{action: act}
 }
 
+func (r *Instruction) ApplyActionAndGotoTable(act openflow.Action, tableID uint8) {
+	if act == nil {
+		panic("act is nil")
+	}
+	r.value = &applyAndGoto{action: act, tableID: tableID}
+}
+
+func (r *Instruction) SetInstructions(set openflow.InstructionSet) {
+	if set.Meter != 0 {
+		r.SetMeter(set.Meter)
+	}
+	if set.Goto && set.GotoTable > OFPTT_MAX {
+		r.err = errors.New("SetInstructions: invalid table ID")
+		return
+	}
+	r.value = &instructionSet{set: set}
+}
+
+func (r *Instruction) Actions() []openflow.Action {
+	switch v := r.value.(type) {
+	case *writeAction:
+		return []openflow.Action{v.action}
+	case *applyAction:
+		return []openflow.Action{v.action}
+	case *applyAndGoto:
+		return []openflow.Action{v.action}
+	case *instructionSet:
+		var actions []openflow.Action
+		if v.set.Apply != nil {
+			actions = append(actions, v.set.Apply)
+		}
+		if v.set.Write != nil {
+			actions = append(actions, v.set.Write)
+		}
+		return actions
+	default:
+		return nil
+	}
+}
+
+func (r *Instruction) SetMeter(id uint32) {
+	if id == 0 || id > OFPM_MAX {
+		r.err = errors.New("SetMeter: invalid meter ID")
+		return
+	}
+	r.meter = id
+}
+
 func (r *Instruction) MarshalBinary() ([]byte, error) {
 	if r.err != nil {
 		return nil, r.err
@@ -122,6 +286,81 @@ func (r *Instruction) MarshalBinary() ([]byte, error) {
 	if r.value == nil {
 		return nil, errors.New("empty action of an instruction")
 	}
+	v, err := r.value.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	if r.meter != 0 {
+		v = append(marshalMeter(r.meter), v...)
+	}
+
+	return v, nil
+}
+
+// UnmarshalBinary decodes the instructions of a FLOW_MOD into the same form as they have been
+// made by the setters so that they are encoded again to the same bytes.
+func (r *Instruction) UnmarshalBinary(data []byte) error {
+	var set openflow.InstructionSet
+	var n int
+	buf := data
+	for len(buf) > 0 {
+		if len(buf) < 8 {
+			return openflow.ErrInvalidPacketLength
+		}
+		t := binary.BigEndian.Uint16(buf[0:2])
+		length := int(binary.BigEndian.Uint16(buf[2:4]))
+		if length < 8 || len(buf) < length {
+			return openflow.ErrInvalidPacketLength
+		}
+
+		switch t {
+		case OFPIT_GOTO_TABLE:
+			set.Goto = true
+			set.GotoTable = buf[4]
+		case OFPIT_WRITE_METADATA:
+			if length < 24 {
+				return openflow.ErrInvalidPacketLength
+			}
+			set.Metadata = binary.BigEndian.Uint64(buf[8:16])
+			set.MetadataMask = binary.BigEndian.Uint64(buf[16:24])
+		case OFPIT_WRITE_ACTIONS, OFPIT_APPLY_ACTIONS:
+			action := NewAction()
+			if err := action.UnmarshalBinary(buf[8:length]); err != nil {
+				return err
+			}
+			if t == OFPIT_WRITE_ACTIONS {
+				set.Write = action
+			} else {
+				set.Apply = action
+			}
+		case OFPIT_METER:
+			r.SetMeter(binary.BigEndian.Uint32(buf[4:8]))
+			if err := r.Error(); err != nil {
+				return err
+			}
+			// The meter is not a part of the value.
+			n--
+		default:
+			return fmt.Errorf("unsupported instruction type: %v", t)
+		}
+		n++
+		buf = buf[length:]
+	}
+
+	switch {
+	case n == 1 && set.Apply != nil:
+		r.ApplyAction(set.Apply)
+	case n == 1 && set.Write != nil:
+		r.WriteAction(set.Write)
+	case n == 1 && set.Goto:
+		r.GotoTable(set.GotoTable)
+	case n == 2 && set.Apply != nil && set.Goto:
+		r.ApplyActionAndGotoTable(set.Apply, set.GotoTable)
+	case n > 0:
+		r.value = &instructionSet{set: set}
+	default:
+		return errors.New("empty instruction")
+	}
 
-	return r.value.MarshalBinary()
+	return nil
 }
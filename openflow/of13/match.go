@@ -26,6 +26,7 @@ import (
 	"encoding/binary"
 	"fmt"
 	"net"
+	"sort"
 	"sync"
 
 	"github.com/superkkt/cherry/openflow"
@@ -33,10 +34,18 @@ import (
 	"github.com/pkg/errors"
 )
 
+// tunnelID is the value of OFPXMT_OFB_TUNNEL_ID.
+type tunnelID struct {
+	id   uint64
+	mask uint64
+}
+
 type Match struct {
 	err   error
 	mutex sync.Mutex
 	m     map[uint]interface{}
+	// OXM fields of the non-basic classes
+	ext []openflow.OXM
 }
 
 // NewMatch returns a Match whose fields are all wildcarded
@@ -50,6 +59,95 @@ func (r *Match) Error() error {
 	return r.err
 }
 
+func (r *Match) SetExtension(oxm openflow.OXM) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if oxm.Class == OFPXMC_OPENFLOW_BASIC {
+		r.err = errors.New("SetExtension: OFPXMC_OPENFLOW_BASIC class")
+		return
+	}
+	if oxm.Field > 0x7F {
+		r.err = errors.New("SetExtension: invalid field number")
+		return
+	}
+	if len(oxm.Value) == 0 || (oxm.Mask != nil && len(oxm.Mask) != len(oxm.Value)) || oxmLength(oxm) > 0xFF {
+		r.err = errors.New("SetExtension: invalid value or mask length")
+		return
+	}
+
+	for i, v := range r.ext {
+		if v.Class == oxm.Class && v.Field == oxm.Field && (v.Class != OFPXMC_EXPERIMENTER || v.Experimenter == oxm.Experimenter) {
+			r.ext[i] = oxm
+			return
+		}
+	}
+	r.ext = append(r.ext, oxm)
+}
+
+func (r *Match) Extensions() []openflow.OXM {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return append([]openflow.OXM(nil), r.ext...)
+}
+
+// oxmLength returns the length of the payload of oxm, which includes the experimenter ID of
+// the experimenter class.
+func oxmLength(oxm openflow.OXM) int {
+	length := len(oxm.Value) + len(oxm.Mask)
+	if oxm.Class == OFPXMC_EXPERIMENTER {
+		length += 4
+	}
+
+	return length
+}
+
+func marshalOXM(oxm openflow.OXM) []byte {
+	var hasmask uint32
+	if oxm.Mask != nil {
+		hasmask = 1
+	}
+	length := oxmLength(oxm)
+
+	v := make([]byte, 4, 4+length)
+	header := uint32(oxm.Class)<<16 | uint32(oxm.Field)<<9 | hasmask<<8 | uint32(length)
+	binary.BigEndian.PutUint32(v[0:4], header)
+	if oxm.Class == OFPXMC_EXPERIMENTER {
+		exp := make([]byte, 4)
+		binary.BigEndian.PutUint32(exp, oxm.Experimenter)
+		v = append(v, exp...)
+	}
+	v = append(v, oxm.Value...)
+	v = append(v, oxm.Mask...)
+
+	return v
+}
+
+func unmarshalOXM(class uint16, field, hasmask uint8, payload []byte) (openflow.OXM, error) {
+	oxm := openflow.OXM{Class: class, Field: field}
+	// The experimenter ID is not a part of the value and mask.
+	if class == OFPXMC_EXPERIMENTER {
+		if len(payload) < 4 {
+			return openflow.OXM{}, openflow.ErrInvalidPacketLength
+		}
+		oxm.Experimenter = binary.BigEndian.Uint32(payload[0:4])
+		payload = payload[4:]
+	}
+	if hasmask == 1 {
+		if len(payload)%2 != 0 {
+			return openflow.OXM{}, openflow.ErrInvalidPacketLength
+		}
+		n := len(payload) / 2
+		oxm.Value = append([]byte(nil), payload[:n]...)
+		oxm.Mask = append([]byte(nil), payload[n:]...)
+	} else {
+		oxm.Value = append([]byte(nil), payload...)
+	}
+
+	return oxm, nil
+}
+
 func (r *Match) SetWildcardSrcPort() {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
@@ -67,8 +165,7 @@ func (r *Match) SetSrcPort(p uint16) {
 		r.err = errors.Wrap(openflow.ErrMissingEtherType, "SetSrcPort")
 		return
 	}
-	// IPv4?
-	if etherType.(uint16) != 0x0800 {
+	if !isIPEtherType(etherType.(uint16)) {
 		r.err = errors.Wrap(openflow.ErrUnsupportedEtherType, "SetSrcPort")
 		return
 	}
@@ -128,8 +225,7 @@ func (r *Match) SetDstPort(p uint16) {
 		r.err = errors.Wrap(openflow.ErrMissingEtherType, "SetDstPort")
 		return
 	}
-	// IPv4?
-	if etherType.(uint16) != 0x0800 {
+	if !isIPEtherType(etherType.(uint16)) {
 		r.err = errors.Wrap(openflow.ErrUnsupportedEtherType, "SetDstPort")
 		return
 	}
@@ -240,8 +336,7 @@ func (r *Match) SetIPProtocol(p uint8) {
 		r.err = errors.Wrap(openflow.ErrMissingEtherType, "SetIPProtocol")
 		return
 	}
-	// IPv4?
-	if etherType.(uint16) != 0x0800 {
+	if !isIPEtherType(etherType.(uint16)) {
 		r.err = errors.Wrap(openflow.ErrUnsupportedEtherType, "SetIPProtocol")
 		return
 	}
@@ -261,6 +356,298 @@ func (r *Match) IPProtocol() (wildcard bool, protocol uint8) {
 	return true, 0
 }
 
+func (r *Match) SetWildcardIPDSCP() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.m, OFPXMT_OFB_IP_DSCP)
+}
+
+func (r *Match) SetIPDSCP(dscp uint8) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	// DSCP is a 6-bit field
+	if dscp > 0x3F {
+		r.err = errors.Wrap(openflow.ErrInvalidDSCP, "SetIPDSCP")
+		return
+	}
+	etherType, ok := r.m[OFPXMT_OFB_ETH_TYPE]
+	if !ok {
+		r.err = errors.Wrap(openflow.ErrMissingEtherType, "SetIPDSCP")
+		return
+	}
+	if !isIPEtherType(etherType.(uint16)) {
+		r.err = errors.Wrap(openflow.ErrUnsupportedEtherType, "SetIPDSCP")
+		return
+	}
+
+	r.m[OFPXMT_OFB_IP_DSCP] = dscp
+}
+
+func (r *Match) IPDSCP() (wildcard bool, dscp uint8) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	v, ok := r.m[OFPXMT_OFB_IP_DSCP]
+	if ok {
+		return false, v.(uint8)
+	}
+
+	return true, 0
+}
+
+// isMPLS returns whether the Ethernet type of the match is MPLS unicast or multicast.
+// XXX: Caller should lock the mutex before they call this function
+func (r *Match) isMPLS(caller string) bool {
+	etherType, ok := r.m[OFPXMT_OFB_ETH_TYPE]
+	if !ok {
+		r.err = errors.Wrap(openflow.ErrMissingEtherType, caller)
+		return false
+	}
+	if etherType.(uint16) != 0x8847 && etherType.(uint16) != 0x8848 {
+		r.err = errors.Wrap(openflow.ErrUnsupportedEtherType, caller)
+		return false
+	}
+
+	return true
+}
+
+func (r *Match) SetWildcardMPLSLabel() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.m, OFPXMT_OFB_MPLS_LABEL)
+}
+
+func (r *Match) SetMPLSLabel(label uint32) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	// Label is a 20-bit field
+	if label > 0xFFFFF {
+		r.err = errors.Wrap(openflow.ErrInvalidMPLSLabel, "SetMPLSLabel")
+		return
+	}
+	if !r.isMPLS("SetMPLSLabel") {
+		return
+	}
+
+	r.m[OFPXMT_OFB_MPLS_LABEL] = label
+}
+
+func (r *Match) MPLSLabel() (wildcard bool, label uint32) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	v, ok := r.m[OFPXMT_OFB_MPLS_LABEL]
+	if ok {
+		return false, v.(uint32)
+	}
+
+	return true, 0
+}
+
+func (r *Match) SetWildcardMPLSTC() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.m, OFPXMT_OFB_MPLS_TC)
+}
+
+func (r *Match) SetMPLSTC(tc uint8) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	// TC is a 3-bit field
+	if tc > 0x7 {
+		r.err = errors.Wrap(openflow.ErrInvalidMPLSTC, "SetMPLSTC")
+		return
+	}
+	if !r.isMPLS("SetMPLSTC") {
+		return
+	}
+
+	r.m[OFPXMT_OFB_MPLS_TC] = tc
+}
+
+func (r *Match) MPLSTC() (wildcard bool, tc uint8) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	v, ok := r.m[OFPXMT_OFB_MPLS_TC]
+	if ok {
+		return false, v.(uint8)
+	}
+
+	return true, 0
+}
+
+// isIPEtherType returns whether t is the Ethernet type of IPv4 or IPv6.
+func isIPEtherType(t uint16) bool {
+	return t == 0x0800 || t == 0x86DD
+}
+
+// isIPv6 returns whether the Ethernet type of the match is IPv6.
+// XXX: Caller should lock the mutex before they call this function
+func (r *Match) isIPv6(caller string) bool {
+	etherType, ok := r.m[OFPXMT_OFB_ETH_TYPE]
+	if !ok {
+		r.err = errors.Wrap(openflow.ErrMissingEtherType, caller)
+		return false
+	}
+	if etherType.(uint16) != 0x86DD {
+		r.err = errors.Wrap(openflow.ErrUnsupportedEtherType, caller)
+		return false
+	}
+
+	return true
+}
+
+func (r *Match) SetWildcardIPv6FlowLabel() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.m, OFPXMT_OFB_IPV6_FLABEL)
+}
+
+func (r *Match) SetIPv6FlowLabel(label uint32) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	// Flow label is a 20-bit field
+	if label > 0xFFFFF {
+		r.err = errors.Wrap(openflow.ErrInvalidFlowLabel, "SetIPv6FlowLabel")
+		return
+	}
+	if !r.isIPv6("SetIPv6FlowLabel") {
+		return
+	}
+
+	r.m[OFPXMT_OFB_IPV6_FLABEL] = label
+}
+
+func (r *Match) IPv6FlowLabel() (wildcard bool, label uint32) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	v, ok := r.m[OFPXMT_OFB_IPV6_FLABEL]
+	if ok {
+		return false, v.(uint32)
+	}
+
+	return true, 0
+}
+
+func (r *Match) SetWildcardICMPv6Type() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.m, OFPXMT_OFB_ICMPV6_TYPE)
+	// The ND target depends on the ICMPv6 type.
+	delete(r.m, OFPXMT_OFB_IPV6_ND_TARGET)
+}
+
+func (r *Match) SetICMPv6Type(t uint8) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if !r.isIPv6("SetICMPv6Type") {
+		return
+	}
+	proto, ok := r.m[OFPXMT_OFB_IP_PROTO]
+	if !ok {
+		r.err = errors.Wrap(openflow.ErrMissingIPProtocol, "SetICMPv6Type")
+		return
+	}
+	// ICMPv6?
+	if proto.(uint8) != 58 {
+		r.err = errors.Wrap(openflow.ErrUnsupportedIPProtocol, "SetICMPv6Type")
+		return
+	}
+
+	r.m[OFPXMT_OFB_ICMPV6_TYPE] = t
+}
+
+func (r *Match) ICMPv6Type() (wildcard bool, t uint8) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	v, ok := r.m[OFPXMT_OFB_ICMPV6_TYPE]
+	if ok {
+		return false, v.(uint8)
+	}
+
+	return true, 0
+}
+
+func (r *Match) SetWildcardIPv6NDTarget() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.m, OFPXMT_OFB_IPV6_ND_TARGET)
+}
+
+func (r *Match) SetIPv6NDTarget(ip net.IP) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if ip.To16() == nil || ip.To4() != nil {
+		r.err = errors.Wrap(openflow.ErrInvalidIPAddress, "SetIPv6NDTarget")
+		return
+	}
+	t, ok := r.m[OFPXMT_OFB_ICMPV6_TYPE]
+	// Neighbor Solicitation or Advertisement?
+	if !ok || (t.(uint8) != 135 && t.(uint8) != 136) {
+		r.err = errors.New("SetIPv6NDTarget: ICMPv6 type is not a neighbor solicitation or advertisement")
+		return
+	}
+
+	r.m[OFPXMT_OFB_IPV6_ND_TARGET] = ip.To16()
+}
+
+func (r *Match) IPv6NDTarget() (wildcard bool, ip net.IP) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	v, ok := r.m[OFPXMT_OFB_IPV6_ND_TARGET]
+	if ok {
+		return false, v.(net.IP)
+	}
+
+	return true, nil
+}
+
+func (r *Match) SetWildcardTunnelID() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.m, OFPXMT_OFB_TUNNEL_ID)
+}
+
+func (r *Match) SetTunnelID(id, mask uint64) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if mask == 0 {
+		delete(r.m, OFPXMT_OFB_TUNNEL_ID)
+		return
+	}
+	r.m[OFPXMT_OFB_TUNNEL_ID] = tunnelID{id: id & mask, mask: mask}
+}
+
+func (r *Match) TunnelID() (wildcard bool, id, mask uint64) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	v, ok := r.m[OFPXMT_OFB_TUNNEL_ID]
+	if ok {
+		return false, v.(tunnelID).id, v.(tunnelID).mask
+	}
+
+	return true, 0, 0
+}
+
 func (r *Match) SetWildcardInPort() {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
@@ -372,13 +759,25 @@ func (r *Match) SetSrcIP(ip *net.IPNet) {
 		r.err = errors.Wrap(openflow.ErrMissingEtherType, "SetSrcIP")
 		return
 	}
-	// IPv4?
-	if etherType.(uint16) != 0x0800 {
+	switch etherType.(uint16) {
+	case 0x0800:
+		if ip.IP.To4() == nil {
+			r.err = errors.Wrap(openflow.ErrInvalidIPAddress, "SetSrcIP")
+			return
+		}
+		r.m[OFPXMT_OFB_IPV4_SRC] = ip
+		delete(r.m, OFPXMT_OFB_IPV6_SRC)
+	case 0x86DD:
+		if ip.IP.To4() != nil || len(ip.Mask) != net.IPv6len {
+			r.err = errors.Wrap(openflow.ErrInvalidIPAddress, "SetSrcIP")
+			return
+		}
+		r.m[OFPXMT_OFB_IPV6_SRC] = ip
+		delete(r.m, OFPXMT_OFB_IPV4_SRC)
+	default:
 		r.err = errors.Wrap(openflow.ErrUnsupportedEtherType, "SetSrcIP")
 		return
 	}
-
-	r.m[OFPXMT_OFB_IPV4_SRC] = ip
 }
 
 func (r *Match) SrcIP() *net.IPNet {
@@ -389,6 +788,10 @@ func (r *Match) SrcIP() *net.IPNet {
 	if ok {
 		return v.(*net.IPNet)
 	}
+	v, ok = r.m[OFPXMT_OFB_IPV6_SRC]
+	if ok {
+		return v.(*net.IPNet)
+	}
 
 	return &net.IPNet{
 		IP:   net.IPv4zero,
@@ -413,13 +816,25 @@ func (r *Match) SetDstIP(ip *net.IPNet) {
 		r.err = errors.Wrap(openflow.ErrMissingEtherType, "SetDstIP")
 		return
 	}
-	// IPv4?
-	if etherType.(uint16) != 0x0800 {
+	switch etherType.(uint16) {
+	case 0x0800:
+		if ip.IP.To4() == nil {
+			r.err = errors.Wrap(openflow.ErrInvalidIPAddress, "SetDstIP")
+			return
+		}
+		r.m[OFPXMT_OFB_IPV4_DST] = ip
+		delete(r.m, OFPXMT_OFB_IPV6_DST)
+	case 0x86DD:
+		if ip.IP.To4() != nil || len(ip.Mask) != net.IPv6len {
+			r.err = errors.Wrap(openflow.ErrInvalidIPAddress, "SetDstIP")
+			return
+		}
+		r.m[OFPXMT_OFB_IPV6_DST] = ip
+		delete(r.m, OFPXMT_OFB_IPV4_DST)
+	default:
 		r.err = errors.Wrap(openflow.ErrUnsupportedEtherType, "SetDstIP")
 		return
 	}
-
-	r.m[OFPXMT_OFB_IPV4_DST] = ip
 }
 
 func (r *Match) DstIP() *net.IPNet {
@@ -430,6 +845,10 @@ func (r *Match) DstIP() *net.IPNet {
 	if ok {
 		return v.(*net.IPNet)
 	}
+	v, ok = r.m[OFPXMT_OFB_IPV6_DST]
+	if ok {
+		return v.(*net.IPNet)
+	}
 
 	return &net.IPNet{
 		IP:   net.IPv4zero,
@@ -486,12 +905,35 @@ func marshalHardwareAddrTLV(field uint8, mac net.HardwareAddr) ([]byte, error) {
 	return data, nil
 }
 
+func marshalIPv6NetTLV(field uint8, ip *net.IPNet) ([]byte, error) {
+	data := make([]byte, 36)
+	// TLV header
+	var header uint32 = 0x8000<<16 | uint32(field)<<9 | 0x1<<8 | 32
+	binary.BigEndian.PutUint32(data[0:4], header)
+	ipv6 := ip.IP.To16()
+	if ipv6 == nil || len(ip.Mask) != net.IPv6len {
+		return nil, openflow.ErrInvalidIPAddress
+	}
+	copy(data[4:20], ipv6)
+	copy(data[20:36], ip.Mask)
+	return data, nil
+}
+
+func marshalIPv6TLV(field uint8, ip net.IP) ([]byte, error) {
+	data := make([]byte, 20)
+	// TLV header
+	var header uint32 = 0x8000<<16 | uint32(field)<<9 | 0x0<<8 | 16
+	binary.BigEndian.PutUint32(data[0:4], header)
+	copy(data[4:20], ip.To16())
+	return data, nil
+}
+
 func marshalUint8TLV(field uint8, v uint8) ([]byte, error) {
 	data := make([]byte, 5)
 	// TLV header
 	var header uint32 = 0x8000<<16 | uint32(field)<<9 | 0x0<<8 | 1
 	binary.BigEndian.PutUint32(data[0:4], header)
-	data[5] = v
+	data[4] = v
 	return data, nil
 }
 
@@ -513,6 +955,26 @@ func marshalUint32TLV(field uint8, v uint32) ([]byte, error) {
 	return data, nil
 }
 
+func marshalTunnelIDTLV(v tunnelID) ([]byte, error) {
+	// Exact match?
+	if v.mask == 0xFFFFFFFFFFFFFFFF {
+		data := make([]byte, 12)
+		// TLV header
+		var header uint32 = 0x8000<<16 | uint32(OFPXMT_OFB_TUNNEL_ID)<<9 | 0x0<<8 | 8
+		binary.BigEndian.PutUint32(data[0:4], header)
+		binary.BigEndian.PutUint64(data[4:12], v.id)
+		return data, nil
+	}
+
+	data := make([]byte, 20)
+	// TLV header
+	var header uint32 = 0x8000<<16 | uint32(OFPXMT_OFB_TUNNEL_ID)<<9 | 0x1<<8 | 16
+	binary.BigEndian.PutUint32(data[0:4], header)
+	binary.BigEndian.PutUint64(data[4:12], v.id)
+	binary.BigEndian.PutUint64(data[12:20], v.mask)
+	return data, nil
+}
+
 func marshalTLV(id uint, v interface{}) ([]byte, error) {
 	switch id {
 	case OFPXMT_OFB_IN_PORT:
@@ -533,6 +995,9 @@ func marshalTLV(id uint, v interface{}) ([]byte, error) {
 	case OFPXMT_OFB_VLAN_PCP:
 		priority := v.(uint8)
 		return marshalUint8TLV(OFPXMT_OFB_VLAN_PCP, priority)
+	case OFPXMT_OFB_IP_DSCP:
+		dscp := v.(uint8)
+		return marshalUint8TLV(OFPXMT_OFB_IP_DSCP, dscp)
 	case OFPXMT_OFB_IP_PROTO:
 		protocol := v.(uint8)
 		return marshalUint8TLV(OFPXMT_OFB_IP_PROTO, protocol)
@@ -554,6 +1019,29 @@ func marshalTLV(id uint, v interface{}) ([]byte, error) {
 	case OFPXMT_OFB_UDP_DST:
 		port := v.(uint16)
 		return marshalUint16TLV(OFPXMT_OFB_UDP_DST, port)
+	case OFPXMT_OFB_IPV6_SRC:
+		ip := v.(*net.IPNet)
+		return marshalIPv6NetTLV(OFPXMT_OFB_IPV6_SRC, ip)
+	case OFPXMT_OFB_IPV6_DST:
+		ip := v.(*net.IPNet)
+		return marshalIPv6NetTLV(OFPXMT_OFB_IPV6_DST, ip)
+	case OFPXMT_OFB_IPV6_FLABEL:
+		label := v.(uint32)
+		return marshalUint32TLV(OFPXMT_OFB_IPV6_FLABEL, label)
+	case OFPXMT_OFB_ICMPV6_TYPE:
+		t := v.(uint8)
+		return marshalUint8TLV(OFPXMT_OFB_ICMPV6_TYPE, t)
+	case OFPXMT_OFB_IPV6_ND_TARGET:
+		ip := v.(net.IP)
+		return marshalIPv6TLV(OFPXMT_OFB_IPV6_ND_TARGET, ip)
+	case OFPXMT_OFB_TUNNEL_ID:
+		return marshalTunnelIDTLV(v.(tunnelID))
+	case OFPXMT_OFB_MPLS_LABEL:
+		label := v.(uint32)
+		return marshalUint32TLV(OFPXMT_OFB_MPLS_LABEL, label)
+	case OFPXMT_OFB_MPLS_TC:
+		tc := v.(uint8)
+		return marshalUint8TLV(OFPXMT_OFB_MPLS_TC, tc)
 	default:
 		panic(fmt.Sprintf("unexpected TLV type: %v", id))
 	}
@@ -569,13 +1057,24 @@ func (r *Match) MarshalBinary() ([]byte, error) {
 
 	data := make([]byte, 4)
 	binary.BigEndian.PutUint16(data[0:2], OFPMT_OXM)
-	for k, v := range r.m {
-		tlv, err := marshalTLV(k, v)
+	// Encode the TLVs in field order so that the same match always
+	// produces the same bytes; prerequisites precede their dependents.
+	keys := make([]int, 0, len(r.m))
+	for k := range r.m {
+		keys = append(keys, int(k))
+	}
+	sort.Ints(keys)
+	for _, k := range keys {
+		tlv, err := marshalTLV(uint(k), r.m[uint(k)])
 		if err != nil {
 			return nil, err
 		}
 		data = append(data, tlv...)
 	}
+	// Vendor-specific fields follow the basic fields.
+	for _, v := range r.ext {
+		data = append(data, marshalOXM(v)...)
+	}
 	// ofp_match.length does not include padding
 	binary.BigEndian.PutUint16(data[2:4], uint16(len(data)))
 	// Add padding to align as a multiple of 8
@@ -651,15 +1150,65 @@ func (r *Match) unmarshalIPNetTLV(field uint8, hasmask uint8, data []byte) error
 	return nil
 }
 
+func (r *Match) unmarshalIPv6NetTLV(field uint8, hasmask uint8, data []byte) error {
+	length := 20
+	if hasmask == 1 {
+		length = 36
+	}
+	if len(data) < length {
+		return openflow.ErrInvalidPacketLength
+	}
+
+	ip := make(net.IP, net.IPv6len)
+	copy(ip, data[4:20])
+	mask := net.CIDRMask(128, 128)
+	if hasmask == 1 {
+		copy(mask, data[20:36])
+	}
+
+	r.m[uint(field)] = &net.IPNet{
+		IP:   ip,
+		Mask: mask,
+	}
+
+	return nil
+}
+
+func (r *Match) unmarshalIPv6TLV(field uint8, data []byte) error {
+	if len(data) < 20 {
+		return openflow.ErrInvalidPacketLength
+	}
+	ip := make(net.IP, net.IPv6len)
+	copy(ip, data[4:20])
+	r.m[uint(field)] = ip
+
+	return nil
+}
+
+func (r *Match) unmarshalTunnelIDTLV(hasmask uint8, data []byte) error {
+	length := 12
+	if hasmask == 1 {
+		length = 20
+	}
+	if len(data) < length {
+		return openflow.ErrInvalidPacketLength
+	}
+
+	v := tunnelID{id: binary.BigEndian.Uint64(data[4:12]), mask: 0xFFFFFFFFFFFFFFFF}
+	if hasmask == 1 {
+		v.mask = binary.BigEndian.Uint64(data[12:20])
+	}
+	r.m[OFPXMT_OFB_TUNNEL_ID] = v
+
+	return nil
+}
+
 func (r *Match) unmarshalTLV(data []byte) error {
 	buf := data
 	// TLV header length is 4 bytes
 	for len(buf) >= 4 {
 		header := binary.BigEndian.Uint32(buf[0:4])
 		class := header >> 16 & 0xFFFF
-		if class != 0x8000 {
-			return errors.New("unsupported TLV class")
-		}
 		field := header >> 9 & 0x7F
 		hasmask := header >> 8 & 0x1
 		length := header & 0xFF
@@ -667,6 +1216,15 @@ func (r *Match) unmarshalTLV(data []byte) error {
 		if len(buf) < int(4+length) {
 			return openflow.ErrInvalidPacketLength
 		}
+		if class != OFPXMC_OPENFLOW_BASIC {
+			oxm, err := unmarshalOXM(uint16(class), uint8(field), uint8(hasmask), buf[4:4+length])
+			if err != nil {
+				return err
+			}
+			r.ext = append(r.ext, oxm)
+			buf = buf[4+length:]
+			continue
+		}
 
 		switch field {
 		case OFPXMT_OFB_IN_PORT:
@@ -693,6 +1251,10 @@ func (r *Match) unmarshalTLV(data []byte) error {
 			if err := r.unmarshalUint8TLV(OFPXMT_OFB_VLAN_PCP, buf); err != nil {
 				return err
 			}
+		case OFPXMT_OFB_IP_DSCP:
+			if err := r.unmarshalUint8TLV(OFPXMT_OFB_IP_DSCP, buf); err != nil {
+				return err
+			}
 		case OFPXMT_OFB_IP_PROTO:
 			if err := r.unmarshalUint8TLV(OFPXMT_OFB_IP_PROTO, buf); err != nil {
 				return err
@@ -721,6 +1283,38 @@ func (r *Match) unmarshalTLV(data []byte) error {
 			if err := r.unmarshalUint16TLV(OFPXMT_OFB_UDP_DST, buf); err != nil {
 				return err
 			}
+		case OFPXMT_OFB_IPV6_SRC:
+			if err := r.unmarshalIPv6NetTLV(OFPXMT_OFB_IPV6_SRC, uint8(hasmask), buf); err != nil {
+				return err
+			}
+		case OFPXMT_OFB_IPV6_DST:
+			if err := r.unmarshalIPv6NetTLV(OFPXMT_OFB_IPV6_DST, uint8(hasmask), buf); err != nil {
+				return err
+			}
+		case OFPXMT_OFB_IPV6_FLABEL:
+			if err := r.unmarshalUint32TLV(OFPXMT_OFB_IPV6_FLABEL, buf); err != nil {
+				return err
+			}
+		case OFPXMT_OFB_ICMPV6_TYPE:
+			if err := r.unmarshalUint8TLV(OFPXMT_OFB_ICMPV6_TYPE, buf); err != nil {
+				return err
+			}
+		case OFPXMT_OFB_IPV6_ND_TARGET:
+			if err := r.unmarshalIPv6TLV(OFPXMT_OFB_IPV6_ND_TARGET, buf); err != nil {
+				return err
+			}
+		case OFPXMT_OFB_TUNNEL_ID:
+			if err := r.unmarshalTunnelIDTLV(uint8(hasmask), buf); err != nil {
+				return err
+			}
+		case OFPXMT_OFB_MPLS_LABEL:
+			if err := r.unmarshalUint32TLV(OFPXMT_OFB_MPLS_LABEL, buf); err != nil {
+				return err
+			}
+		case OFPXMT_OFB_MPLS_TC:
+			if err := r.unmarshalUint8TLV(OFPXMT_OFB_MPLS_TC, buf); err != nil {
+				return err
+			}
 		default:
 			// Do nothing
 		}
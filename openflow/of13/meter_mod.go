@@ -0,0 +1,193 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package of13
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/superkkt/cherry/openflow"
+)
+
+type MeterMod struct {
+	err error
+	openflow.Message
+	command uint16
+	flags   uint16
+	meterID uint32
+	bands   []openflow.MeterBand
+}
+
+func NewMeterMod(xid uint32, cmd uint16) openflow.MeterMod {
+	return &MeterMod{
+		Message: openflow.NewMessage(openflow.OF13_VERSION, OFPT_METER_MOD, xid),
+		command: cmd,
+		flags:   OFPMF_KBPS,
+	}
+}
+
+func (r *MeterMod) Error() error {
+	return r.err
+}
+
+func (r *MeterMod) Command() openflow.MeterModCmd {
+	switch r.command {
+	case OFPMC_ADD:
+		return openflow.MeterAdd
+	case OFPMC_MODIFY:
+		return openflow.MeterModify
+	case OFPMC_DELETE:
+		return openflow.MeterDelete
+	default:
+		panic(fmt.Sprintf("unexpected meter command: %v", r.command))
+	}
+}
+
+func (r *MeterMod) MeterID() uint32 {
+	return r.meterID
+}
+
+func (r *MeterMod) SetMeterID(id uint32) {
+	if id == 0 || (id > OFPM_MAX && id != OFPM_ALL) {
+		r.err = fmt.Errorf("SetMeterID: invalid meter ID: %v", id)
+		return
+	}
+	r.meterID = id
+}
+
+func (r *MeterMod) PacketRate() bool {
+	return r.flags&OFPMF_PKTPS != 0
+}
+
+func (r *MeterMod) SetPacketRate(pktps bool) {
+	r.flags &^= OFPMF_KBPS | OFPMF_PKTPS
+	if pktps {
+		r.flags |= OFPMF_PKTPS
+	} else {
+		r.flags |= OFPMF_KBPS
+	}
+}
+
+func (r *MeterMod) Burst() bool {
+	return r.flags&OFPMF_BURST != 0
+}
+
+func (r *MeterMod) SetBurst(burst bool) {
+	if burst {
+		r.flags |= OFPMF_BURST
+	} else {
+		r.flags &^= OFPMF_BURST
+	}
+}
+
+func (r *MeterMod) Bands() []openflow.MeterBand {
+	return r.bands
+}
+
+func (r *MeterMod) AddBand(b openflow.MeterBand) {
+	if b.Type != openflow.MeterBandDrop && b.Type != openflow.MeterBandDSCPRemark {
+		r.err = fmt.Errorf("AddBand: unexpected meter band type: %v", b.Type)
+		return
+	}
+	r.bands = append(r.bands, b)
+}
+
+func marshalMeterBand(b openflow.MeterBand) []byte {
+	v := make([]byte, 16)
+	switch b.Type {
+	case openflow.MeterBandDrop:
+		binary.BigEndian.PutUint16(v[0:2], OFPMBT_DROP)
+		// v[12:16] is padding
+	case openflow.MeterBandDSCPRemark:
+		binary.BigEndian.PutUint16(v[0:2], OFPMBT_DSCP_REMARK)
+		v[12] = b.PrecLevel
+		// v[13:16] is padding
+	default:
+		panic(fmt.Sprintf("unexpected meter band type: %v", b.Type))
+	}
+	binary.BigEndian.PutUint16(v[2:4], 16)
+	binary.BigEndian.PutUint32(v[4:8], b.Rate)
+	binary.BigEndian.PutUint32(v[8:12], b.BurstSize)
+
+	return v
+}
+
+func (r *MeterMod) MarshalBinary() ([]byte, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	if r.meterID == 0 {
+		return nil, errors.New("empty meter ID")
+	}
+	if r.meterID == OFPM_ALL && r.command != OFPMC_DELETE {
+		return nil, errors.New("all meters can be specified only by the delete command")
+	}
+
+	v := make([]byte, 8)
+	binary.BigEndian.PutUint16(v[0:2], r.command)
+	binary.BigEndian.PutUint16(v[2:4], r.flags)
+	binary.BigEndian.PutUint32(v[4:8], r.meterID)
+	for _, b := range r.bands {
+		v = append(v, marshalMeterBand(b)...)
+	}
+	r.SetPayload(v)
+
+	return r.Message.MarshalBinary()
+}
+
+func (r *MeterMod) UnmarshalBinary(data []byte) error {
+	if err := r.Message.UnmarshalBinary(data); err != nil {
+		return err
+	}
+
+	payload := r.Payload()
+	if payload == nil || len(payload) < 8 || (len(payload)-8)%16 != 0 {
+		return openflow.ErrInvalidPacketLength
+	}
+	r.command = binary.BigEndian.Uint16(payload[0:2])
+	if r.command > OFPMC_DELETE {
+		return fmt.Errorf("unexpected meter command: %v", r.command)
+	}
+	r.flags = binary.BigEndian.Uint16(payload[2:4])
+	r.meterID = binary.BigEndian.Uint32(payload[4:8])
+
+	r.bands = nil
+	for buf := payload[8:]; len(buf) > 0; buf = buf[16:] {
+		band := openflow.MeterBand{
+			Rate:      binary.BigEndian.Uint32(buf[4:8]),
+			BurstSize: binary.BigEndian.Uint32(buf[8:12]),
+		}
+		switch t := binary.BigEndian.Uint16(buf[0:2]); t {
+		case OFPMBT_DROP:
+			band.Type = openflow.MeterBandDrop
+		case OFPMBT_DSCP_REMARK:
+			band.Type = openflow.MeterBandDSCPRemark
+			band.PrecLevel = buf[12]
+		default:
+			return fmt.Errorf("unsupported meter band type: %v", t)
+		}
+		r.bands = append(r.bands, band)
+	}
+
+	return nil
+}
@@ -0,0 +1,138 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package of13
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/superkkt/cherry/openflow"
+)
+
+const (
+	// Maximum size of a reassembled multipart reply. Replies larger than this
+	// are discarded to protect us from a switch that never clears the
+	// OFPMPF_REPLY_MORE flag.
+	maxMultipartReplySize = 16 * 1024 * 1024
+)
+
+var (
+	ErrMultipartReplyTooLarge = errors.New("too large multipart reply")
+)
+
+// multipartReply is the common header of all multipart replies. Typed replies
+// embed it and decode their own body from Body().
+type multipartReply struct {
+	openflow.Message
+	mpType uint16
+	flags  uint16
+	body   []byte
+}
+
+func (r *multipartReply) MultipartType() uint16 {
+	return r.mpType
+}
+
+func (r *multipartReply) Flags() uint16 {
+	return r.flags
+}
+
+func (r *multipartReply) Body() []byte {
+	return r.body
+}
+
+func (r *multipartReply) UnmarshalBinary(data []byte) error {
+	if err := r.Message.UnmarshalBinary(data); err != nil {
+		return err
+	}
+
+	payload := r.Payload()
+	if payload == nil || len(payload) < 8 {
+		return openflow.ErrInvalidPacketLength
+	}
+	r.mpType = binary.BigEndian.Uint16(payload[0:2])
+	r.flags = binary.BigEndian.Uint16(payload[2:4])
+	// payload[4:8] is padding
+	r.body = payload[8:]
+
+	return nil
+}
+
+type multipartKey struct {
+	xid    uint32
+	mpType uint16
+}
+
+// MultipartAssembler reassembles multipart replies that are split into
+// several messages by the OFPMPF_REPLY_MORE flag. It is not thread-safe.
+type MultipartAssembler struct {
+	pending map[multipartKey][]byte
+}
+
+func NewMultipartAssembler() *MultipartAssembler {
+	return &MultipartAssembler{
+		pending: make(map[multipartKey][]byte),
+	}
+}
+
+// Add adds a multipart reply packet. It returns nil if more parts of the reply
+// are expected. Otherwise, it returns the complete reply whose body consists of
+// the bodies of all the parts in the received order. The length field of the
+// complete reply is set to 0xFFFF if the reply is larger than 64KB.
+func (r *MultipartAssembler) Add(packet []byte) ([]byte, error) {
+	reply := new(multipartReply)
+	if err := reply.UnmarshalBinary(packet); err != nil {
+		return nil, err
+	}
+	key := multipartKey{xid: reply.TransactionID(), mpType: reply.MultipartType()}
+
+	prev, ok := r.pending[key]
+	if !ok && reply.Flags()&OFPMPF_REPLY_MORE == 0 {
+		// Single part reply.
+		return packet, nil
+	}
+	if !ok {
+		// The first part becomes the header of the complete reply.
+		prev = append([]byte(nil), packet[:16]...)
+	}
+	if len(prev)+len(reply.Body()) > maxMultipartReplySize {
+		delete(r.pending, key)
+		return nil, ErrMultipartReplyTooLarge
+	}
+	v := append(prev, reply.Body()...)
+
+	if reply.Flags()&OFPMPF_REPLY_MORE != 0 {
+		r.pending[key] = v
+		return nil, nil
+	}
+	delete(r.pending, key)
+
+	length := len(v)
+	if length > 0xFFFF {
+		length = 0xFFFF
+	}
+	binary.BigEndian.PutUint16(v[2:4], uint16(length))
+	// Clear the flags of the complete reply.
+	binary.BigEndian.PutUint16(v[10:12], 0)
+
+	return v, nil
+}
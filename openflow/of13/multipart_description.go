@@ -49,7 +49,7 @@ func (r *DescRequest) MarshalBinary() ([]byte, error) {
 }
 
 type DescReply struct {
-	openflow.Message
+	multipartReply
 	manufacturer string
 	hardware     string
 	software     string
@@ -78,19 +78,19 @@ func (r DescReply) Description() string {
 }
 
 func (r *DescReply) UnmarshalBinary(data []byte) error {
-	if err := r.Message.UnmarshalBinary(data); err != nil {
+	if err := r.multipartReply.UnmarshalBinary(data); err != nil {
 		return err
 	}
 
-	payload := r.Payload()
-	if payload == nil || len(payload) < 1064 {
+	body := r.Body()
+	if len(body) < 1056 {
 		return openflow.ErrInvalidPacketLength
 	}
-	r.manufacturer = strings.TrimRight(string(payload[8:264]), "\x00")
-	r.hardware = strings.TrimRight(string(payload[264:520]), "\x00")
-	r.software = strings.TrimRight(string(payload[520:776]), "\x00")
-	r.serial = strings.TrimRight(string(payload[776:808]), "\x00")
-	r.description = strings.TrimRight(string(payload[808:1064]), "\x00")
+	r.manufacturer = strings.TrimRight(string(body[0:256]), "\x00")
+	r.hardware = strings.TrimRight(string(body[256:512]), "\x00")
+	r.software = strings.TrimRight(string(body[512:768]), "\x00")
+	r.serial = strings.TrimRight(string(body[768:800]), "\x00")
+	r.description = strings.TrimRight(string(body[800:1056]), "\x00")
 
 	return nil
 }
@@ -111,4 +111,52 @@ func (r *FlowStatsRequest) MarshalBinary() ([]byte, error) {
 	return r.Message.MarshalBinary()
 }
 
-// TODO: Implement FlowStatsReply
+type FlowStatsReply struct {
+	multipartReply
+	stats []openflow.FlowStats
+}
+
+func (r FlowStatsReply) FlowStats() []openflow.FlowStats {
+	return r.stats
+}
+
+func (r *FlowStatsReply) UnmarshalBinary(data []byte) error {
+	if err := r.multipartReply.UnmarshalBinary(data); err != nil {
+		return err
+	}
+
+	r.stats = nil
+	body := r.Body()
+	for len(body) > 0 {
+		if len(body) < 56 {
+			return openflow.ErrInvalidPacketLength
+		}
+		length := int(binary.BigEndian.Uint16(body[0:2]))
+		if length < 56 || len(body) < length {
+			return openflow.ErrInvalidPacketLength
+		}
+
+		v := openflow.FlowStats{
+			TableID: body[2],
+			// body[3] is padding
+			DurationSec:     binary.BigEndian.Uint32(body[4:8]),
+			DurationNanoSec: binary.BigEndian.Uint32(body[8:12]),
+			Priority:        binary.BigEndian.Uint16(body[12:14]),
+			IdleTimeout:     binary.BigEndian.Uint16(body[14:16]),
+			HardTimeout:     binary.BigEndian.Uint16(body[16:18]),
+			// body[18:20] is flags and body[20:24] is padding
+			Cookie:      binary.BigEndian.Uint64(body[24:32]),
+			PacketCount: binary.BigEndian.Uint64(body[32:40]),
+			ByteCount:   binary.BigEndian.Uint64(body[40:48]),
+			Match:       NewMatch(),
+		}
+		// Instructions after the match are ignored.
+		if err := v.Match.UnmarshalBinary(body[48:length]); err != nil {
+			return err
+		}
+		r.stats = append(r.stats, v)
+		body = body[length:]
+	}
+
+	return nil
+}
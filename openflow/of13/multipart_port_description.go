@@ -48,7 +48,7 @@ func (r *PortDescRequest) MarshalBinary() ([]byte, error) {
 }
 
 type PortDescReply struct {
-	openflow.Message
+	multipartReply
 	ports []openflow.Port
 }
 
@@ -57,21 +57,18 @@ func (r PortDescReply) Ports() []openflow.Port {
 }
 
 func (r *PortDescReply) UnmarshalBinary(data []byte) error {
-	if err := r.Message.UnmarshalBinary(data); err != nil {
+	if err := r.multipartReply.UnmarshalBinary(data); err != nil {
 		return err
 	}
 
-	payload := r.Payload()
-	if payload == nil || len(payload) < 8 {
-		return openflow.ErrInvalidPacketLength
-	}
-	nPorts := (len(payload) - 8) / 64
+	body := r.Body()
+	nPorts := len(body) / 64
 	if nPorts == 0 {
 		return nil
 	}
 	r.ports = make([]openflow.Port, nPorts)
 	for i := 0; i < nPorts; i++ {
-		buf := payload[8+i*64:]
+		buf := body[i*64:]
 		r.ports[i] = new(Port)
 		if err := r.ports[i].UnmarshalBinary(buf[0:64]); err != nil {
 			return err
@@ -0,0 +1,86 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package of13
+
+import (
+	"encoding/binary"
+
+	"github.com/superkkt/cherry/openflow"
+)
+
+type QueueStatsRequest struct {
+	openflow.Message
+}
+
+func NewQueueStatsRequest(xid uint32) openflow.QueueStatsRequest {
+	return &QueueStatsRequest{
+		Message: openflow.NewMessage(openflow.OF13_VERSION, OFPT_MULTIPART_REQUEST, xid),
+	}
+}
+
+func (r *QueueStatsRequest) MarshalBinary() ([]byte, error) {
+	v := make([]byte, 16)
+	// Multipart queue stats request
+	binary.BigEndian.PutUint16(v[0:2], OFPMP_QUEUE)
+	// v[2:8] is flags and padding
+	// All ports
+	binary.BigEndian.PutUint32(v[8:12], OFPP_ANY)
+	// All queues
+	binary.BigEndian.PutUint32(v[12:16], OFPQ_ALL)
+	r.SetPayload(v)
+
+	return r.Message.MarshalBinary()
+}
+
+type QueueStatsReply struct {
+	multipartReply
+	stats []openflow.QueueStats
+}
+
+func (r QueueStatsReply) QueueStats() []openflow.QueueStats {
+	return r.stats
+}
+
+func (r *QueueStatsReply) UnmarshalBinary(data []byte) error {
+	if err := r.multipartReply.UnmarshalBinary(data); err != nil {
+		return err
+	}
+
+	body := r.Body()
+	if len(body)%40 != 0 {
+		return openflow.ErrInvalidPacketLength
+	}
+	r.stats = make([]openflow.QueueStats, len(body)/40)
+	for i := range r.stats {
+		buf := body[i*40:]
+		r.stats[i] = openflow.QueueStats{
+			PortNumber: binary.BigEndian.Uint32(buf[0:4]),
+			QueueID:    binary.BigEndian.Uint32(buf[4:8]),
+			TxBytes:    binary.BigEndian.Uint64(buf[8:16]),
+			TxPackets:  binary.BigEndian.Uint64(buf[16:24]),
+			TxErrors:   binary.BigEndian.Uint64(buf[24:32]),
+			// buf[32:40] is the duration
+		}
+	}
+
+	return nil
+}
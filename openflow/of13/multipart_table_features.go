@@ -22,6 +22,7 @@
 package of13
 
 import (
+	"bytes"
 	"encoding/binary"
 
 	"github.com/superkkt/cherry/openflow"
@@ -47,4 +48,140 @@ func (r *TableFeaturesRequest) MarshalBinary() ([]byte, error) {
 	return r.Message.MarshalBinary()
 }
 
-// TODO: Implement TableFeaturesReply
+type TableFeaturesReply struct {
+	multipartReply
+	tables []openflow.TableFeatures
+}
+
+func (r TableFeaturesReply) Tables() []openflow.TableFeatures {
+	return r.tables
+}
+
+func (r *TableFeaturesReply) UnmarshalBinary(data []byte) error {
+	if err := r.multipartReply.UnmarshalBinary(data); err != nil {
+		return err
+	}
+
+	body := r.Body()
+	r.tables = make([]openflow.TableFeatures, 0)
+	// ofp_table_features header is 64 bytes
+	for len(body) >= 64 {
+		length := int(binary.BigEndian.Uint16(body[0:2]))
+		if length < 64 || len(body) < length {
+			return openflow.ErrInvalidPacketLength
+		}
+		table, err := unmarshalTableFeatures(body[:length])
+		if err != nil {
+			return err
+		}
+		r.tables = append(r.tables, table)
+		body = body[length:]
+	}
+
+	return nil
+}
+
+func unmarshalTableFeatures(data []byte) (openflow.TableFeatures, error) {
+	v := openflow.TableFeatures{
+		TableID: data[2],
+		// data[3:8] is padding
+		Name:          string(bytes.TrimRight(data[8:40], "\x00")),
+		MetadataMatch: binary.BigEndian.Uint64(data[40:48]),
+		MetadataWrite: binary.BigEndian.Uint64(data[48:56]),
+		// data[56:60] is the deprecated config field
+		MaxEntries: binary.BigEndian.Uint32(data[60:64]),
+	}
+
+	props := data[64:]
+	// Property header is 4 bytes
+	for len(props) >= 4 {
+		t := binary.BigEndian.Uint16(props[0:2])
+		length := int(binary.BigEndian.Uint16(props[2:4]))
+		if length < 4 || len(props) < length {
+			return openflow.TableFeatures{}, openflow.ErrInvalidPacketLength
+		}
+		body := props[4:length]
+
+		switch t {
+		case OFPTFPT_INSTRUCTIONS:
+			v.Instructions = unmarshalTypeList(body)
+		case OFPTFPT_INSTRUCTIONS_MISS:
+			v.InstructionsMiss = unmarshalTypeList(body)
+		case OFPTFPT_NEXT_TABLES:
+			v.NextTables = append([]uint8{}, body...)
+		case OFPTFPT_NEXT_TABLES_MISS:
+			v.NextTablesMiss = append([]uint8{}, body...)
+		case OFPTFPT_WRITE_ACTIONS:
+			v.WriteActions = unmarshalTypeList(body)
+		case OFPTFPT_WRITE_ACTIONS_MISS:
+			v.WriteActionsMiss = unmarshalTypeList(body)
+		case OFPTFPT_APPLY_ACTIONS:
+			v.ApplyActions = unmarshalTypeList(body)
+		case OFPTFPT_APPLY_ACTIONS_MISS:
+			v.ApplyActionsMiss = unmarshalTypeList(body)
+		case OFPTFPT_MATCH:
+			v.Match = unmarshalOXMList(body)
+		case OFPTFPT_WILDCARDS:
+			v.Wildcards = unmarshalOXMList(body)
+		case OFPTFPT_WRITE_SETFIELD:
+			v.WriteSetField = unmarshalOXMList(body)
+		case OFPTFPT_WRITE_SETFIELD_MISS:
+			v.WriteSetFieldMiss = unmarshalOXMList(body)
+		case OFPTFPT_APPLY_SETFIELD:
+			v.ApplySetField = unmarshalOXMList(body)
+		case OFPTFPT_APPLY_SETFIELD_MISS:
+			v.ApplySetFieldMiss = unmarshalOXMList(body)
+		default:
+			// Do nothing
+		}
+
+		// Properties are padded to align as a multiple of 8, but the last one may not be padded.
+		next := (length + 7) / 8 * 8
+		if next > len(props) {
+			break
+		}
+		props = props[next:]
+	}
+
+	return v, nil
+}
+
+// unmarshalTypeList returns the types of the instruction or action headers in data. Each header
+// has the type and the length of the header itself, which is longer than 4 bytes for experimenters.
+func unmarshalTypeList(data []byte) []uint16 {
+	v := make([]uint16, 0)
+	for len(data) >= 4 {
+		v = append(v, binary.BigEndian.Uint16(data[0:2]))
+		length := int(binary.BigEndian.Uint16(data[2:4]))
+		if length < 4 || len(data) < length {
+			break
+		}
+		data = data[length:]
+	}
+
+	return v
+}
+
+// unmarshalOXMList returns the match fields of the OXM headers in data.
+func unmarshalOXMList(data []byte) []openflow.OXMField {
+	v := make([]openflow.OXMField, 0)
+	for len(data) >= 4 {
+		header := binary.BigEndian.Uint32(data[0:4])
+		v = append(v, openflow.OXMField{
+			Class:   uint16(header >> 16),
+			Field:   uint8(header >> 9 & 0x7F),
+			HasMask: header>>8&0x1 == 1,
+		})
+		// Experimenter OXM headers have the 32-bit experimenter ID.
+		if header>>16 == OFPXMC_EXPERIMENTER {
+			if len(data) < 8 {
+				break
+			}
+			data = data[8:]
+			continue
+		}
+		data = data[4:]
+	}
+
+	return v
+}
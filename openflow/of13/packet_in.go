@@ -58,14 +58,26 @@ func (r PacketIn) TableID() uint8 {
 	return r.tableID
 }
 
-func (r PacketIn) Reason() uint8 {
-	return r.reason
+func (r PacketIn) Reason() openflow.PacketInReason {
+	switch r.reason {
+	case OFPR_NO_MATCH:
+		return openflow.PacketInNoMatch
+	case OFPR_ACTION:
+		return openflow.PacketInAction
+	case OFPR_INVALID_TTL:
+		return openflow.PacketInInvalidTTL
+	default:
+		return openflow.PacketInUnknown
+	}
 }
 
 func (r PacketIn) Cookie() uint64 {
 	return r.cookie
 }
 
+// UnmarshalBinary decodes data without copying it, so the data of the packet refers to data.
+// It does not decode the whole match because the ingress port is the only match field that a
+// PACKET_IN carries in practice, and decoding the match costs many allocations per PACKET_IN.
 func (r *PacketIn) UnmarshalBinary(data []byte) error {
 	if err := r.Message.UnmarshalBinary(data); err != nil {
 		return err
@@ -81,25 +93,54 @@ func (r *PacketIn) UnmarshalBinary(data []byte) error {
 	r.tableID = payload[7]
 	r.cookie = binary.BigEndian.Uint64(payload[8:16])
 
-	match := NewMatch()
-	if err := match.UnmarshalBinary(payload[16:]); err != nil {
+	if binary.BigEndian.Uint16(payload[16:18]) != OFPMT_OXM {
+		return openflow.ErrUnsupportedMatchType
+	}
+	matchLength := binary.BigEndian.Uint16(payload[18:20])
+	if matchLength < 4 || len(payload) < 16+int(matchLength) {
+		return openflow.ErrInvalidPacketLength
+	}
+	inPort, err := findInPort(payload[20 : 16+matchLength])
+	if err != nil {
 		return err
 	}
-	_, inport := match.InPort()
-	r.inPort = inport.Value()
+	r.inPort = inPort
 
-	matchLength := binary.BigEndian.Uint16(payload[18:20])
 	// Calculate padding length
 	rem := matchLength % 8
 	if rem > 0 {
 		matchLength += 8 - rem
 	}
 
-	dataOffset := 16 + matchLength + 2 // +2 is padding
-	if len(payload) >= int(dataOffset) {
+	r.data = nil
+	dataOffset := 16 + int(matchLength) + 2 // +2 is padding
+	if len(payload) >= dataOffset {
 		// TODO: Check data size by comparing with r.Length
 		r.data = payload[dataOffset:]
 	}
 
 	return nil
 }
+
+// findInPort returns the ingress port in the OXM TLVs, or zero if there is no ingress port.
+func findInPort(tlv []byte) (uint32, error) {
+	// TLV header length is 4 bytes
+	for len(tlv) >= 4 {
+		header := binary.BigEndian.Uint32(tlv[0:4])
+		class := header >> 16 & 0xFFFF
+		field := header >> 9 & 0x7F
+		length := header & 0xFF
+		if len(tlv) < int(4+length) {
+			return 0, openflow.ErrInvalidPacketLength
+		}
+		if class == OFPXMC_OPENFLOW_BASIC && field == OFPXMT_OFB_IN_PORT {
+			if length < 4 {
+				return 0, openflow.ErrInvalidPacketLength
+			}
+			return binary.BigEndian.Uint32(tlv[4:8]), nil
+		}
+		tlv = tlv[4+length:]
+	}
+
+	return 0, nil
+}
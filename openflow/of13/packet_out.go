@@ -30,17 +30,27 @@ import (
 type PacketOut struct {
 	err error
 	openflow.Message
-	inPort openflow.InPort
-	action openflow.Action
-	data   []byte
+	inPort   openflow.InPort
+	action   openflow.Action
+	data     []byte
+	bufferID uint32
 }
 
 func NewPacketOut(xid uint32) openflow.PacketOut {
 	return &PacketOut{
-		Message: openflow.NewMessage(openflow.OF13_VERSION, OFPT_PACKET_OUT, xid),
+		Message:  openflow.NewMessage(openflow.OF13_VERSION, OFPT_PACKET_OUT, xid),
+		bufferID: openflow.NoBuffer,
 	}
 }
 
+func (r *PacketOut) BufferID() uint32 {
+	return r.bufferID
+}
+
+func (r *PacketOut) SetBufferID(id uint32) {
+	r.bufferID = id
+}
+
 func (r *PacketOut) Error() error {
 	return r.err
 }
@@ -90,7 +100,7 @@ func (r *PacketOut) MarshalBinary() ([]byte, error) {
 	}
 
 	v := make([]byte, 16)
-	binary.BigEndian.PutUint32(v[0:4], OFP_NO_BUFFER)
+	binary.BigEndian.PutUint32(v[0:4], r.bufferID)
 	port := r.inPort.Value()
 	if r.inPort.IsController() {
 		port = OFPP_CONTROLLER
@@ -99,7 +109,8 @@ func (r *PacketOut) MarshalBinary() ([]byte, error) {
 	binary.BigEndian.PutUint16(v[8:10], uint16(len(action)))
 	// v[10:16] is padding
 	v = append(v, action...)
-	if r.data != nil && len(r.data) > 0 {
+	// The switch uses the buffered packet instead of the data.
+	if r.bufferID == OFP_NO_BUFFER && len(r.data) > 0 {
 		v = append(v, r.data...)
 	}
 
@@ -84,6 +84,14 @@ func (r Port) IsAutoNego() bool {
 	return r.current&OFPPF_AUTONEG != 0
 }
 
+func (r Port) Config() uint32 {
+	return r.config
+}
+
+func (r Port) State() uint32 {
+	return r.state
+}
+
 func (r *Port) Speed() uint64 {
 	switch {
 	case r.current&OFPPF_10MB_HD != 0:
@@ -107,7 +115,8 @@ func (r *Port) Speed() uint64 {
 	case r.current&OFPPF_1TB_FD != 0:
 		return 1000000
 	default:
-		return 0
+		// OFPPF_OTHER or no feature bits: use the current speed in kbps.
+		return uint64(r.currentSpeed / 1000)
 	}
 }
 
@@ -81,7 +81,7 @@ func (r *QueueProperty) Length() uint16 {
 }
 
 func (r *QueueProperty) Rate() (uint16, error) {
-	if r.typ != openflow.OFPQT_MIN_RATE || r.typ != openflow.OFPQT_MAX_RATE {
+	if r.typ != openflow.OFPQT_MIN_RATE && r.typ != openflow.OFPQT_MAX_RATE {
 		return 0x0, openflow.ErrInvalidPropertyMethod
 	}
 	return r.rate, nil
@@ -161,6 +161,9 @@ func (r *Queue) UnmarshalBinary(data []byte) error {
 		if err := p.UnmarshalBinary(data[i:]); err != nil {
 			return err
 		}
+		if p.Length() == 0 {
+			return openflow.ErrInvalidPacketLength
+		}
 		r.property = append(r.property, p)
 		i += int(p.Length())
 	}
@@ -200,6 +203,9 @@ func (r *QueueGetConfigReply) UnmarshalBinary(data []byte) error {
 		if err := q.UnmarshalBinary(payload[i:]); err != nil {
 			return err
 		}
+		if q.Length() == 0 {
+			return openflow.ErrInvalidPacketLength
+		}
 		r.queue = append(r.queue, q)
 		i += int(q.Length())
 	}
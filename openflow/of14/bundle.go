@@ -0,0 +1,141 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package of14
+
+import (
+	"encoding"
+	"encoding/binary"
+
+	"github.com/superkkt/cherry/openflow"
+)
+
+// BundleControl opens, closes, commits or discards a bundle, or replies to such a request.
+type BundleControl struct {
+	openflow.Message
+	bundleID uint32
+	ctrlType uint16
+	flags    uint16
+}
+
+// NewBundleControl returns a bundle control request whose type is one of OFPBCT_*_REQUEST.
+// flags is a bitmap of OFPBF_* flags.
+func NewBundleControl(xid, bundleID uint32, ctrlType, flags uint16) *BundleControl {
+	return &BundleControl{
+		Message:  openflow.NewMessage(openflow.OF14_VERSION, OFPT_BUNDLE_CONTROL, xid),
+		bundleID: bundleID,
+		ctrlType: ctrlType,
+		flags:    flags,
+	}
+}
+
+func (r *BundleControl) BundleID() uint32 {
+	return r.bundleID
+}
+
+func (r *BundleControl) ControlType() uint16 {
+	return r.ctrlType
+}
+
+func (r *BundleControl) Flags() uint16 {
+	return r.flags
+}
+
+func (r *BundleControl) MarshalBinary() ([]byte, error) {
+	v := make([]byte, 8)
+	binary.BigEndian.PutUint32(v[0:4], r.bundleID)
+	binary.BigEndian.PutUint16(v[4:6], r.ctrlType)
+	binary.BigEndian.PutUint16(v[6:8], r.flags)
+	// No properties
+	r.SetPayload(v)
+
+	return r.Message.MarshalBinary()
+}
+
+func (r *BundleControl) UnmarshalBinary(data []byte) error {
+	if err := r.Message.UnmarshalBinary(data); err != nil {
+		return err
+	}
+
+	payload := r.Payload()
+	if payload == nil || len(payload) < 8 {
+		return openflow.ErrInvalidPacketLength
+	}
+	r.bundleID = binary.BigEndian.Uint32(payload[0:4])
+	r.ctrlType = binary.BigEndian.Uint16(payload[4:6])
+	r.flags = binary.BigEndian.Uint16(payload[6:8])
+	// Ignore the properties
+
+	return nil
+}
+
+// BundleAdd adds a message to an opened bundle.
+type BundleAdd struct {
+	openflow.Message
+	bundleID uint32
+	flags    uint16
+	msg      encoding.BinaryMarshaler
+}
+
+// NewBundleAdd returns a message that adds msg to the bundle whose ID is bundleID. flags
+// should be same as the ones used to open the bundle.
+//
+// The caller is responsible for encoding msg in the OpenFlow 1.4 layout, e.g., an OpenFlow 1.3
+// FLOW_MOD has the same layout. The version and transaction ID in the header of msg are
+// overwritten by the ones of the bundle add message as required by the specification.
+func NewBundleAdd(xid, bundleID uint32, flags uint16, msg encoding.BinaryMarshaler) *BundleAdd {
+	if msg == nil {
+		panic("msg is nil")
+	}
+
+	return &BundleAdd{
+		Message:  openflow.NewMessage(openflow.OF14_VERSION, OFPT_BUNDLE_ADD_MESSAGE, xid),
+		bundleID: bundleID,
+		flags:    flags,
+		msg:      msg,
+	}
+}
+
+func (r *BundleAdd) BundleID() uint32 {
+	return r.bundleID
+}
+
+func (r *BundleAdd) MarshalBinary() ([]byte, error) {
+	inner, err := r.msg.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	if len(inner) < 8 {
+		return nil, openflow.ErrInvalidPacketLength
+	}
+	inner[0] = openflow.OF14_VERSION
+	binary.BigEndian.PutUint32(inner[4:8], r.TransactionID())
+
+	v := make([]byte, 8+len(inner))
+	binary.BigEndian.PutUint32(v[0:4], r.bundleID)
+	// v[4:6] is padding
+	binary.BigEndian.PutUint16(v[6:8], r.flags)
+	copy(v[8:], inner)
+	// No properties
+	r.SetPayload(v)
+
+	return r.Message.MarshalBinary()
+}
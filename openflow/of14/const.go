@@ -0,0 +1,170 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package of14
+
+const (
+	OFPT_HELLO              = 0
+	OFPT_PORT_STATUS        = 12
+	OFPT_TABLE_MOD          = 17
+	OFPT_MULTIPART_REQUEST  = 18
+	OFPT_MULTIPART_REPLY    = 19
+	OFPT_BUNDLE_CONTROL     = 33
+	OFPT_BUNDLE_ADD_MESSAGE = 34
+)
+
+const (
+	OFPMP_PORT_STATS   = 4
+	OFPMP_QUEUE_STATS  = 5
+	OFPMP_PORT_DESC    = 13
+	OFPMP_FLOW_MONITOR = 16
+)
+
+const (
+	OFPMPF_REQ_MORE   = 1 << 0 /* More requests to follow. */
+	OFPMPF_REPLY_MORE = 1 << 0 /* More replies to follow. */
+)
+
+const (
+	OFPP_MAX = 0xffffff00
+	OFPP_ANY = 0xffffffff
+)
+
+const (
+	OFPG_ANY  = 0xffffffff
+	OFPTT_ALL = 0xff
+)
+
+const (
+	OFPPR_ADD    = 0
+	OFPPR_DELETE = 1
+	OFPPR_MODIFY = 2
+)
+
+const (
+	OFPPC_PORT_DOWN = 1 << 0 /* Port is administratively down. */
+)
+
+const (
+	OFPPS_LINK_DOWN = 1 << 0 /* No physical link present. */
+	OFPPS_BLOCKED   = 1 << 1
+	OFPPS_LIVE      = 1 << 2
+)
+
+const (
+	OFPPF_10MB_HD    = 1 << 0
+	OFPPF_10MB_FD    = 1 << 1
+	OFPPF_100MB_HD   = 1 << 2
+	OFPPF_100MB_FD   = 1 << 3
+	OFPPF_1GB_HD     = 1 << 4
+	OFPPF_1GB_FD     = 1 << 5
+	OFPPF_10GB_FD    = 1 << 6
+	OFPPF_40GB_FD    = 1 << 7
+	OFPPF_100GB_FD   = 1 << 8
+	OFPPF_1TB_FD     = 1 << 9
+	OFPPF_OTHER      = 1 << 10
+	OFPPF_COPPER     = 1 << 11
+	OFPPF_FIBER      = 1 << 12
+	OFPPF_AUTONEG    = 1 << 13
+	OFPPF_PAUSE      = 1 << 14
+	OFPPF_PAUSE_ASYM = 1 << 15
+)
+
+/* Port stats property types. */
+const (
+	OFPPSPT_ETHERNET     = 0      /* Ethernet property. */
+	OFPPSPT_OPTICAL      = 1      /* Optical property. */
+	OFPPSPT_EXPERIMENTER = 0xFFFF /* Experimenter property. */
+)
+
+/* Port description property types. */
+const (
+	OFPPDPT_ETHERNET     = 0      /* Ethernet property. */
+	OFPPDPT_OPTICAL      = 1      /* Optical property. */
+	OFPPDPT_EXPERIMENTER = 0xFFFF /* Experimenter property. */
+)
+
+/* Bundle control message types. */
+const (
+	OFPBCT_OPEN_REQUEST    = 0
+	OFPBCT_OPEN_REPLY      = 1
+	OFPBCT_CLOSE_REQUEST   = 2
+	OFPBCT_CLOSE_REPLY     = 3
+	OFPBCT_COMMIT_REQUEST  = 4
+	OFPBCT_COMMIT_REPLY    = 5
+	OFPBCT_DISCARD_REQUEST = 6
+	OFPBCT_DISCARD_REPLY   = 7
+)
+
+/* Bundle configuration flags. */
+const (
+	OFPBF_ATOMIC  = 1 << 0 /* Execute atomically. */
+	OFPBF_ORDERED = 1 << 1 /* Execute in specified order. */
+)
+
+/* Flags to configure the table. */
+const (
+	OFPTC_EVICTION       = 1 << 2 /* Authorise table to evict flows. */
+	OFPTC_VACANCY_EVENTS = 1 << 3 /* Enable vacancy events. */
+)
+
+/* Table Mod property types. */
+const (
+	OFPTMPT_EVICTION     = 0x2    /* Eviction property. */
+	OFPTMPT_VACANCY      = 0x3    /* Vacancy property. */
+	OFPTMPT_EXPERIMENTER = 0xFFFF /* Experimenter property. */
+)
+
+/* Eviction flags. */
+const (
+	OFPTMPEF_OTHER      = 1 << 0 /* Using other factors. */
+	OFPTMPEF_IMPORTANCE = 1 << 1 /* Using flow entry importance. */
+	OFPTMPEF_LIFETIME   = 1 << 2 /* Using flow entry lifetime. */
+)
+
+/* Flow monitor flags. */
+const (
+	OFPFMF_INITIAL      = 1 << 0 /* Initially matching flows. */
+	OFPFMF_ADD          = 1 << 1 /* New matching flows as they are added. */
+	OFPFMF_REMOVED      = 1 << 2 /* Old matching flows as they are removed. */
+	OFPFMF_MODIFY       = 1 << 3 /* Matching flows as they are changed. */
+	OFPFMF_INSTRUCTIONS = 1 << 4 /* If set, instructions are included. */
+	OFPFMF_NO_ABBREV    = 1 << 5 /* If set, include own changes in full. */
+	OFPFMF_ONLY_OWN     = 1 << 6 /* If set, don't include other controllers. */
+)
+
+/* Flow monitor commands. */
+const (
+	OFPFMC_ADD    = 0 /* New flow monitor. */
+	OFPFMC_MODIFY = 1 /* Modify existing flow monitor. */
+	OFPFMC_DELETE = 2 /* Delete/cancel existing flow monitor. */
+)
+
+/* Flow update events. */
+const (
+	OFPFME_INITIAL  = 0 /* Flow present when flow monitor created. */
+	OFPFME_ADDED    = 1 /* Flow was added. */
+	OFPFME_REMOVED  = 2 /* Flow was removed. */
+	OFPFME_MODIFIED = 3 /* Flow instructions were changed. */
+	OFPFME_ABBREV   = 4 /* Abbreviated reply. */
+	OFPFME_PAUSED   = 5 /* Monitoring paused (out of buffer space). */
+	OFPFME_RESUMED  = 6 /* Monitoring resumed. */
+)
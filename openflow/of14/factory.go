@@ -0,0 +1,267 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package of14
+
+import (
+	"github.com/superkkt/cherry/openflow"
+	"github.com/superkkt/cherry/openflow/of13"
+)
+
+// Factory makes OpenFlow 1.4 messages. The messages whose wire format has not been changed
+// since OpenFlow 1.3 are made by the embedded OF1.3 factory and then stamped with our version.
+// The embedded factory also allocates all the transaction IDs so that they never collide.
+type Factory struct {
+	*of13.Factory
+}
+
+func NewFactory() openflow.Factory {
+	return &Factory{Factory: new(of13.Factory)}
+}
+
+// setVersion stamps msg, which is made by the embedded factory, with our version. All the
+// messages embed openflow.Message, so they have SetVersion.
+func setVersion(msg openflow.Header) {
+	msg.(interface{ SetVersion(uint8) }).SetVersion(openflow.OF14_VERSION)
+}
+
+func (r *Factory) ProtocolVersion() uint8 {
+	return openflow.OF14_VERSION
+}
+
+func (r *Factory) NewHello() (openflow.Hello, error) {
+	msg, err := r.Factory.NewHello()
+	if err != nil {
+		return nil, err
+	}
+	v := &openflow.BaseHello{
+		Message: openflow.NewMessage(openflow.OF14_VERSION, OFPT_HELLO, msg.TransactionID()),
+	}
+	// Advertise all the versions that we support.
+	v.SetVersions([]uint8{openflow.OF10_VERSION, openflow.OF13_VERSION, openflow.OF14_VERSION})
+
+	return v, nil
+}
+
+func (r *Factory) NewEchoRequest() (openflow.EchoRequest, error) {
+	msg, err := r.Factory.NewEchoRequest()
+	if err != nil {
+		return nil, err
+	}
+	setVersion(msg)
+
+	return msg, nil
+}
+
+func (r *Factory) NewEchoReply() (openflow.EchoReply, error) {
+	msg, err := r.Factory.NewEchoReply()
+	if err != nil {
+		return nil, err
+	}
+	setVersion(msg)
+
+	return msg, nil
+}
+
+func (r *Factory) NewBarrierRequest() (openflow.BarrierRequest, error) {
+	msg, err := r.Factory.NewBarrierRequest()
+	if err != nil {
+		return nil, err
+	}
+	setVersion(msg)
+
+	return msg, nil
+}
+
+func (r *Factory) NewSetConfig() (openflow.SetConfig, error) {
+	msg, err := r.Factory.NewSetConfig()
+	if err != nil {
+		return nil, err
+	}
+	setVersion(msg)
+
+	return msg, nil
+}
+
+func (r *Factory) NewGetConfigRequest() (openflow.GetConfigRequest, error) {
+	msg, err := r.Factory.NewGetConfigRequest()
+	if err != nil {
+		return nil, err
+	}
+	setVersion(msg)
+
+	return msg, nil
+}
+
+func (r *Factory) NewFeaturesRequest() (openflow.FeaturesRequest, error) {
+	msg, err := r.Factory.NewFeaturesRequest()
+	if err != nil {
+		return nil, err
+	}
+	setVersion(msg)
+
+	return msg, nil
+}
+
+func (r *Factory) NewFlowMod(cmd openflow.FlowModCmd) (openflow.FlowMod, error) {
+	msg, err := r.Factory.NewFlowMod(cmd)
+	if err != nil {
+		return nil, err
+	}
+	setVersion(msg)
+
+	return msg, nil
+}
+
+func (r *Factory) NewPacketOut() (openflow.PacketOut, error) {
+	msg, err := r.Factory.NewPacketOut()
+	if err != nil {
+		return nil, err
+	}
+	setVersion(msg)
+
+	return msg, nil
+}
+
+func (r *Factory) NewGroupMod(cmd openflow.GroupModCmd) (openflow.GroupMod, error) {
+	msg, err := r.Factory.NewGroupMod(cmd)
+	if err != nil {
+		return nil, err
+	}
+	setVersion(msg)
+
+	return msg, nil
+}
+
+func (r *Factory) NewMeterMod(cmd openflow.MeterModCmd) (openflow.MeterMod, error) {
+	msg, err := r.Factory.NewMeterMod(cmd)
+	if err != nil {
+		return nil, err
+	}
+	setVersion(msg)
+
+	return msg, nil
+}
+
+func (r *Factory) NewRoleRequest() (openflow.RoleRequest, error) {
+	msg, err := r.Factory.NewRoleRequest()
+	if err != nil {
+		return nil, err
+	}
+	setVersion(msg)
+
+	return msg, nil
+}
+
+func (r *Factory) NewPortStatsRequest() (openflow.PortStatsRequest, error) {
+	msg, err := r.Factory.NewPortStatsRequest()
+	if err != nil {
+		return nil, err
+	}
+	setVersion(msg)
+
+	return msg, nil
+}
+
+func (r *Factory) NewPortStatsReply() (openflow.PortStatsReply, error) {
+	return new(PortStatsReply), nil
+}
+
+func (r *Factory) NewPortStatus() (openflow.PortStatus, error) {
+	return new(PortStatus), nil
+}
+
+func (r *Factory) NewDescRequest() (openflow.DescRequest, error) {
+	msg, err := r.Factory.NewDescRequest()
+	if err != nil {
+		return nil, err
+	}
+	setVersion(msg)
+
+	return msg, nil
+}
+
+func (r *Factory) NewFlowStatsRequest() (openflow.FlowStatsRequest, error) {
+	msg, err := r.Factory.NewFlowStatsRequest()
+	if err != nil {
+		return nil, err
+	}
+	setVersion(msg)
+
+	return msg, nil
+}
+
+func (r *Factory) NewPortDescRequest() (openflow.PortDescRequest, error) {
+	msg, err := r.Factory.NewPortDescRequest()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewPortDescRequest(msg.TransactionID()), nil
+}
+
+func (r *Factory) NewPortDescReply() (openflow.PortDescReply, error) {
+	return new(PortDescReply), nil
+}
+
+func (r *Factory) NewTableFeaturesRequest() (openflow.TableFeaturesRequest, error) {
+	msg, err := r.Factory.NewTableFeaturesRequest()
+	if err != nil {
+		return nil, err
+	}
+	setVersion(msg)
+
+	return msg, nil
+}
+
+func (r *Factory) NewExperimenter() (openflow.Experimenter, error) {
+	msg, err := r.Factory.NewExperimenter()
+	if err != nil {
+		return nil, err
+	}
+	setVersion(msg)
+
+	return msg, nil
+}
+
+func (r *Factory) NewQueueGetConfigRequest() (openflow.QueueGetConfigRequest, error) {
+	msg, err := r.Factory.NewQueueGetConfigRequest()
+	if err != nil {
+		return nil, err
+	}
+	setVersion(msg)
+
+	return msg, nil
+}
+
+func (r *Factory) NewQueueStatsRequest() (openflow.QueueStatsRequest, error) {
+	msg, err := r.Factory.NewQueueStatsRequest()
+	if err != nil {
+		return nil, err
+	}
+	setVersion(msg)
+
+	return msg, nil
+}
+
+func (r *Factory) NewQueueStatsReply() (openflow.QueueStatsReply, error) {
+	return new(QueueStatsReply), nil
+}
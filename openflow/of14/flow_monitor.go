@@ -0,0 +1,172 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package of14
+
+import (
+	"encoding/binary"
+
+	"github.com/superkkt/cherry/openflow"
+	"github.com/superkkt/cherry/openflow/of13"
+)
+
+// FlowMonitorRequest asks the switch to notify us of the changes of the flows that
+// match the monitor, including the changes made by other controllers.
+type FlowMonitorRequest struct {
+	openflow.Message
+	monitorID uint32
+	flags     uint16
+	command   uint8
+	tableID   uint8
+	outPort   uint32
+	match     openflow.Match
+}
+
+// NewFlowMonitorRequest returns a flow monitor request whose command is one of OFPFMC_*.
+// flags is a bitmap of OFPFMF_* flags. The monitor matches all the flows in all the tables
+// by default.
+func NewFlowMonitorRequest(xid, monitorID uint32, command uint8, flags uint16) *FlowMonitorRequest {
+	return &FlowMonitorRequest{
+		Message:   openflow.NewMessage(openflow.OF14_VERSION, OFPT_MULTIPART_REQUEST, xid),
+		monitorID: monitorID,
+		flags:     flags,
+		command:   command,
+		tableID:   OFPTT_ALL,
+		outPort:   OFPP_ANY,
+		// The match is same as the one of OpenFlow 1.3.
+		match: of13.NewMatch(),
+	}
+}
+
+// SetTableID restricts the monitor to the table whose ID is id.
+func (r *FlowMonitorRequest) SetTableID(id uint8) {
+	r.tableID = id
+}
+
+// SetOutPort restricts the monitor to the flows that have an output action to port.
+func (r *FlowMonitorRequest) SetOutPort(port uint32) {
+	r.outPort = port
+}
+
+// Match returns the match of the monitor, which is an OpenFlow 1.3 match.
+func (r *FlowMonitorRequest) Match() openflow.Match {
+	return r.match
+}
+
+func (r *FlowMonitorRequest) MarshalBinary() ([]byte, error) {
+	match, err := r.match.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	v := make([]byte, 16)
+	binary.BigEndian.PutUint32(v[0:4], r.monitorID)
+	binary.BigEndian.PutUint32(v[4:8], r.outPort)
+	binary.BigEndian.PutUint32(v[8:12], OFPG_ANY)
+	binary.BigEndian.PutUint16(v[12:14], r.flags)
+	v[14] = r.tableID
+	v[15] = r.command
+	v = append(v, match...)
+	r.SetPayload(marshalMultipartRequest(OFPMP_FLOW_MONITOR, v))
+
+	return r.Message.MarshalBinary()
+}
+
+// FlowUpdate is a notification of the flow monitor.
+type FlowUpdate struct {
+	// One of OFPFME_*
+	Event uint16
+	// Fields of the full events: INITIAL, ADDED, REMOVED and MODIFIED.
+	TableID     uint8
+	Reason      uint8 // Reason of REMOVED: one of openflow.FlowRemoved*
+	IdleTimeout uint16
+	HardTimeout uint16
+	Priority    uint16
+	Cookie      uint64
+	Match       openflow.Match
+	// Transaction ID of our own change in the ABBREV event.
+	XID uint32
+}
+
+type FlowMonitorReply struct {
+	multipartReply
+	updates []FlowUpdate
+}
+
+func (r FlowMonitorReply) Updates() []FlowUpdate {
+	return r.updates
+}
+
+func (r *FlowMonitorReply) UnmarshalBinary(data []byte) error {
+	if err := r.multipartReply.UnmarshalBinary(data); err != nil {
+		return err
+	}
+
+	r.updates = nil
+	body := r.Body()
+	for len(body) >= 4 {
+		length := binary.BigEndian.Uint16(body[0:2])
+		if length < 4 || len(body) < int(length) {
+			return openflow.ErrInvalidPacketLength
+		}
+		update, err := unmarshalFlowUpdate(body[:length])
+		if err != nil {
+			return err
+		}
+		r.updates = append(r.updates, update)
+		body = body[length:]
+	}
+
+	return nil
+}
+
+func unmarshalFlowUpdate(data []byte) (FlowUpdate, error) {
+	v := FlowUpdate{
+		Event: binary.BigEndian.Uint16(data[2:4]),
+	}
+
+	switch v.Event {
+	case OFPFME_INITIAL, OFPFME_ADDED, OFPFME_REMOVED, OFPFME_MODIFIED:
+		if len(data) < 32 {
+			return FlowUpdate{}, openflow.ErrInvalidPacketLength
+		}
+		v.TableID = data[4]
+		v.Reason = data[5]
+		v.IdleTimeout = binary.BigEndian.Uint16(data[6:8])
+		v.HardTimeout = binary.BigEndian.Uint16(data[8:10])
+		v.Priority = binary.BigEndian.Uint16(data[10:12])
+		// data[12:16] is zeros
+		v.Cookie = binary.BigEndian.Uint64(data[16:24])
+		v.Match = of13.NewMatch()
+		// The instructions after the match are ignored.
+		if err := v.Match.UnmarshalBinary(data[24:]); err != nil {
+			return FlowUpdate{}, err
+		}
+	case OFPFME_ABBREV:
+		if len(data) < 8 {
+			return FlowUpdate{}, openflow.ErrInvalidPacketLength
+		}
+		v.XID = binary.BigEndian.Uint32(data[4:8])
+	}
+	// PAUSED and RESUMED have no field.
+
+	return v, nil
+}
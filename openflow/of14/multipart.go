@@ -0,0 +1,76 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package of14
+
+import (
+	"encoding/binary"
+
+	"github.com/superkkt/cherry/openflow"
+)
+
+// multipartReply is the common header of all multipart replies. Typed replies
+// embed it and decode their own body from Body().
+type multipartReply struct {
+	openflow.Message
+	mpType uint16
+	flags  uint16
+	body   []byte
+}
+
+func (r *multipartReply) MultipartType() uint16 {
+	return r.mpType
+}
+
+func (r *multipartReply) Flags() uint16 {
+	return r.flags
+}
+
+func (r *multipartReply) Body() []byte {
+	return r.body
+}
+
+func (r *multipartReply) UnmarshalBinary(data []byte) error {
+	if err := r.Message.UnmarshalBinary(data); err != nil {
+		return err
+	}
+
+	payload := r.Payload()
+	if payload == nil || len(payload) < 8 {
+		return openflow.ErrInvalidPacketLength
+	}
+	r.mpType = binary.BigEndian.Uint16(payload[0:2])
+	r.flags = binary.BigEndian.Uint16(payload[2:4])
+	// payload[4:8] is padding
+	r.body = payload[8:]
+
+	return nil
+}
+
+// marshalMultipartRequest returns the payload of a multipart request whose type is mpType.
+func marshalMultipartRequest(mpType uint16, body []byte) []byte {
+	v := make([]byte, 8+len(body))
+	binary.BigEndian.PutUint16(v[0:2], mpType)
+	// v[2:8] is flags and padding
+	copy(v[8:], body)
+
+	return v
+}
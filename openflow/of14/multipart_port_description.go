@@ -0,0 +1,72 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package of14
+
+import (
+	"github.com/superkkt/cherry/openflow"
+)
+
+type PortDescRequest struct {
+	openflow.Message
+}
+
+func NewPortDescRequest(xid uint32) openflow.PortDescRequest {
+	return &PortDescRequest{
+		Message: openflow.NewMessage(openflow.OF14_VERSION, OFPT_MULTIPART_REQUEST, xid),
+	}
+}
+
+func (r *PortDescRequest) MarshalBinary() ([]byte, error) {
+	// No body
+	r.SetPayload(marshalMultipartRequest(OFPMP_PORT_DESC, nil))
+
+	return r.Message.MarshalBinary()
+}
+
+type PortDescReply struct {
+	multipartReply
+	ports []openflow.Port
+}
+
+func (r PortDescReply) Ports() []openflow.Port {
+	return r.ports
+}
+
+func (r *PortDescReply) UnmarshalBinary(data []byte) error {
+	if err := r.multipartReply.UnmarshalBinary(data); err != nil {
+		return err
+	}
+
+	r.ports = nil
+	// Ports have variable lengths due to their properties.
+	body := r.Body()
+	for len(body) > 0 {
+		port := new(Port)
+		if err := port.UnmarshalBinary(body); err != nil {
+			return err
+		}
+		r.ports = append(r.ports, port)
+		body = body[port.Length():]
+	}
+
+	return nil
+}
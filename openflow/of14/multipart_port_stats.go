@@ -0,0 +1,105 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package of14
+
+import (
+	"encoding/binary"
+
+	"github.com/superkkt/cherry/openflow"
+)
+
+// PortStatsReply is the port stats reply of OpenFlow 1.4, which moves the ethernet
+// error counters of OpenFlow 1.3 into a variable-length list of properties.
+type PortStatsReply struct {
+	multipartReply
+	stats []openflow.PortStats
+}
+
+func (r PortStatsReply) PortStats() []openflow.PortStats {
+	return r.stats
+}
+
+func (r *PortStatsReply) UnmarshalBinary(data []byte) error {
+	if err := r.multipartReply.UnmarshalBinary(data); err != nil {
+		return err
+	}
+
+	r.stats = nil
+	body := r.Body()
+	for len(body) > 0 {
+		if len(body) < 80 {
+			return openflow.ErrInvalidPacketLength
+		}
+		length := binary.BigEndian.Uint16(body[0:2])
+		if length < 80 || len(body) < int(length) {
+			return openflow.ErrInvalidPacketLength
+		}
+		// body[2:4] is padding
+		stats := openflow.PortStats{
+			PortNumber: binary.BigEndian.Uint32(body[4:8]),
+			// body[8:16] is the duration
+			RxPackets: binary.BigEndian.Uint64(body[16:24]),
+			TxPackets: binary.BigEndian.Uint64(body[24:32]),
+			RxBytes:   binary.BigEndian.Uint64(body[32:40]),
+			TxBytes:   binary.BigEndian.Uint64(body[40:48]),
+			RxDropped: binary.BigEndian.Uint64(body[48:56]),
+			TxDropped: binary.BigEndian.Uint64(body[56:64]),
+			RxErrors:  binary.BigEndian.Uint64(body[64:72]),
+			TxErrors:  binary.BigEndian.Uint64(body[72:80]),
+		}
+		if err := unmarshalPortStatsProps(&stats, body[80:length]); err != nil {
+			return err
+		}
+		r.stats = append(r.stats, stats)
+		body = body[length:]
+	}
+
+	return nil
+}
+
+func unmarshalPortStatsProps(stats *openflow.PortStats, props []byte) error {
+	for len(props) >= 4 {
+		propType := binary.BigEndian.Uint16(props[0:2])
+		propLen := binary.BigEndian.Uint16(props[2:4])
+		if propLen < 4 || len(props) < int(propLen) {
+			return openflow.ErrInvalidPacketLength
+		}
+		if propType == OFPPSPT_ETHERNET {
+			if propLen < 40 {
+				return openflow.ErrInvalidPacketLength
+			}
+			// props[4:8] is padding
+			stats.RxFrameErr = binary.BigEndian.Uint64(props[8:16])
+			stats.RxOverErr = binary.BigEndian.Uint64(props[16:24])
+			stats.RxCRCErr = binary.BigEndian.Uint64(props[24:32])
+			stats.Collisions = binary.BigEndian.Uint64(props[32:40])
+		}
+		// Ignore the optical and experimenter properties. Properties are padded to
+		// a multiple of 8 bytes.
+		props = props[propLen:]
+		if pad := int(propLen+7)/8*8 - int(propLen); pad > 0 && len(props) >= pad {
+			props = props[pad:]
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package of14
+
+import (
+	"encoding/binary"
+
+	"github.com/superkkt/cherry/openflow"
+)
+
+// QueueStatsReply is the queue stats reply of OpenFlow 1.4, whose entries have a length
+// field followed by a list of properties.
+type QueueStatsReply struct {
+	multipartReply
+	stats []openflow.QueueStats
+}
+
+func (r QueueStatsReply) QueueStats() []openflow.QueueStats {
+	return r.stats
+}
+
+func (r *QueueStatsReply) UnmarshalBinary(data []byte) error {
+	if err := r.multipartReply.UnmarshalBinary(data); err != nil {
+		return err
+	}
+
+	r.stats = nil
+	body := r.Body()
+	for len(body) > 0 {
+		if len(body) < 48 {
+			return openflow.ErrInvalidPacketLength
+		}
+		length := binary.BigEndian.Uint16(body[0:2])
+		if length < 48 || len(body) < int(length) {
+			return openflow.ErrInvalidPacketLength
+		}
+		// body[2:8] is padding
+		r.stats = append(r.stats, openflow.QueueStats{
+			PortNumber: binary.BigEndian.Uint32(body[8:12]),
+			QueueID:    binary.BigEndian.Uint32(body[12:16]),
+			TxBytes:    binary.BigEndian.Uint64(body[16:24]),
+			TxPackets:  binary.BigEndian.Uint64(body[24:32]),
+			TxErrors:   binary.BigEndian.Uint64(body[32:40]),
+			// body[40:48] is the duration. Ignore the experimenter properties.
+		})
+		body = body[length:]
+	}
+
+	return nil
+}
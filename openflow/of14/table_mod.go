@@ -0,0 +1,72 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package of14
+
+import (
+	"encoding/binary"
+
+	"github.com/superkkt/cherry/openflow"
+)
+
+// TableMod configures a flow table, e.g., allows the switch to evict flows from the table
+// when it is full instead of rejecting new flows.
+type TableMod struct {
+	openflow.Message
+	tableID uint8
+	config  uint32
+	// Bitmap of OFPTMPEF_* flags. The eviction property is omitted if it is zero.
+	eviction uint32
+}
+
+// NewTableMod returns a TABLE_MOD message that sets the configuration of the table whose ID
+// is tableID, or all tables if it is OFPTT_ALL. config is a bitmap of OFPTC_* flags.
+func NewTableMod(xid uint32, tableID uint8, config uint32) *TableMod {
+	return &TableMod{
+		Message: openflow.NewMessage(openflow.OF14_VERSION, OFPT_TABLE_MOD, xid),
+		tableID: tableID,
+		config:  config,
+	}
+}
+
+// SetEviction enables the flow eviction, and tells the switch which factors it uses to
+// choose the flows to be evicted. flags is a bitmap of OFPTMPEF_* flags.
+func (r *TableMod) SetEviction(flags uint32) {
+	r.config |= OFPTC_EVICTION
+	r.eviction = flags
+}
+
+func (r *TableMod) MarshalBinary() ([]byte, error) {
+	v := make([]byte, 8)
+	v[0] = r.tableID
+	// v[1:4] is padding
+	binary.BigEndian.PutUint32(v[4:8], r.config)
+	if r.eviction != 0 {
+		prop := make([]byte, 8)
+		binary.BigEndian.PutUint16(prop[0:2], OFPTMPT_EVICTION)
+		binary.BigEndian.PutUint16(prop[2:4], 8)
+		binary.BigEndian.PutUint32(prop[4:8], r.eviction)
+		v = append(v, prop...)
+	}
+	r.SetPayload(v)
+
+	return r.Message.MarshalBinary()
+}
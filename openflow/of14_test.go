@@ -0,0 +1,148 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package openflow_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/superkkt/cherry/openflow"
+	"github.com/superkkt/cherry/openflow/of13"
+	"github.com/superkkt/cherry/openflow/of14"
+)
+
+func TestOF14PortDescReply(t *testing.T) {
+	packet := []byte{openflow.OF14_VERSION, of14.OFPT_MULTIPART_REPLY, 0, 0, 0, 0, 0, 0, 0, of14.OFPMP_PORT_DESC, 0, 0, 0, 0, 0, 0}
+	// A port with the ethernet property.
+	port := make([]byte, 72)
+	binary.BigEndian.PutUint32(port[0:4], 1)
+	binary.BigEndian.PutUint16(port[4:6], 72)
+	copy(port[8:14], []byte{0, 1, 2, 3, 4, 5})
+	copy(port[16:32], "eth1")
+	binary.BigEndian.PutUint16(port[40:42], of14.OFPPDPT_ETHERNET)
+	binary.BigEndian.PutUint16(port[42:44], 32)
+	binary.BigEndian.PutUint32(port[48:52], of14.OFPPF_10GB_FD|of14.OFPPF_FIBER)
+	packet = append(packet, port...)
+	// A port without properties whose link is down.
+	port = make([]byte, 40)
+	binary.BigEndian.PutUint32(port[0:4], 2)
+	binary.BigEndian.PutUint16(port[4:6], 40)
+	binary.BigEndian.PutUint32(port[36:40], of14.OFPPS_LINK_DOWN)
+	packet = append(packet, port...)
+	binary.BigEndian.PutUint16(packet[2:4], uint16(len(packet)))
+
+	reply := new(of14.PortDescReply)
+	if err := reply.UnmarshalBinary(packet); err != nil {
+		t.Fatal(err)
+	}
+	ports := reply.Ports()
+	if len(ports) != 2 {
+		t.Fatalf("unexpected number of ports: %v", len(ports))
+	}
+	if ports[0].Number() != 1 || ports[0].Name() != "eth1" || ports[0].MAC().String() != "00:01:02:03:04:05" {
+		t.Fatalf("unexpected port: number=%v, name=%v, mac=%v", ports[0].Number(), ports[0].Name(), ports[0].MAC())
+	}
+	if ports[0].Speed() != 10000 || !ports[0].IsFiber() || ports[0].IsLinkDown() {
+		t.Fatalf("unexpected link features: speed=%v, fiber=%v", ports[0].Speed(), ports[0].IsFiber())
+	}
+	if ports[1].Number() != 2 || !ports[1].IsLinkDown() || ports[1].Speed() != 0 {
+		t.Fatalf("unexpected port: number=%v, speed=%v", ports[1].Number(), ports[1].Speed())
+	}
+}
+
+func TestOF14BundleAdd(t *testing.T) {
+	barrier := of13.NewBarrierRequest(0xFF)
+	add := of14.NewBundleAdd(7, 1, of14.OFPBF_ATOMIC, barrier)
+	packet, err := add.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []byte{
+		// Header
+		openflow.OF14_VERSION, of14.OFPT_BUNDLE_ADD_MESSAGE, 0, 24, 0, 0, 0, 7,
+		// Bundle ID, padding and flags
+		0, 0, 0, 1, 0, 0, 0, of14.OFPBF_ATOMIC,
+		// Barrier request whose version and XID are rewritten.
+		openflow.OF14_VERSION, of13.OFPT_BARRIER_REQUEST, 0, 8, 0, 0, 0, 7,
+	}
+	if !bytes.Equal(packet, expected) {
+		t.Fatalf("unexpected bundle add message: %x", packet)
+	}
+}
+
+func TestOF14Factory(t *testing.T) {
+	f := of14.NewFactory()
+	if v := f.ProtocolVersion(); v != openflow.OF14_VERSION {
+		t.Fatalf("unexpected protocol version: %v", v)
+	}
+
+	// All the outgoing messages should have the version of OpenFlow 1.4.
+	var messages []openflow.Header
+	add := func(msg openflow.Header, err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		messages = append(messages, msg)
+	}
+	add(f.NewHello())
+	add(f.NewEchoRequest())
+	add(f.NewEchoReply())
+	add(f.NewBarrierRequest())
+	add(f.NewSetConfig())
+	add(f.NewGetConfigRequest())
+	add(f.NewFeaturesRequest())
+	add(f.NewFlowMod(openflow.FlowAdd))
+	add(f.NewPacketOut())
+	add(f.NewGroupMod(openflow.GroupAdd))
+	add(f.NewMeterMod(openflow.MeterAdd))
+	add(f.NewRoleRequest())
+	add(f.NewPortStatsRequest())
+	add(f.NewDescRequest())
+	add(f.NewFlowStatsRequest())
+	add(f.NewPortDescRequest())
+	add(f.NewTableFeaturesRequest())
+	add(f.NewExperimenter())
+	add(f.NewQueueGetConfigRequest())
+	add(f.NewQueueStatsRequest())
+
+	xids := make(map[uint32]bool)
+	for _, msg := range messages {
+		if msg.Version() != openflow.OF14_VERSION {
+			t.Errorf("unexpected version of %T: %v", msg, msg.Version())
+		}
+		if xids[msg.TransactionID()] {
+			t.Errorf("duplicated transaction ID of %T: %v", msg, msg.TransactionID())
+		}
+		xids[msg.TransactionID()] = true
+	}
+
+	hello, err := f.NewHello()
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := hello.Versions()
+	if len(v) != 3 || v[2] != openflow.OF14_VERSION {
+		t.Fatalf("unexpected advertised versions: %v", v)
+	}
+}
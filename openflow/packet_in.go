@@ -23,15 +23,44 @@ package openflow
 
 import (
 	"encoding"
+	"fmt"
 )
 
+type PacketInReason uint8
+
+const (
+	// No matching flow (table-miss flow entry).
+	PacketInNoMatch PacketInReason = iota
+	// Action explicitly output to controller.
+	PacketInAction
+	// Packet has invalid TTL.
+	PacketInInvalidTTL
+	// Reason that is not defined by the switch's OpenFlow version.
+	PacketInUnknown PacketInReason = 0xff
+)
+
+func (r PacketInReason) String() string {
+	switch r {
+	case PacketInNoMatch:
+		return "no_match"
+	case PacketInAction:
+		return "action"
+	case PacketInInvalidTTL:
+		return "invalid_ttl"
+	case PacketInUnknown:
+		return "unknown"
+	default:
+		return fmt.Sprintf("unknown(%v)", uint8(r))
+	}
+}
+
 type PacketIn interface {
 	Header
 	BufferID() uint32
 	Length() uint16
 	InPort() uint32
 	TableID() uint8
-	Reason() uint8
+	Reason() PacketInReason
 	Cookie() uint64
 	Data() []byte
 	encoding.BinaryUnmarshaler
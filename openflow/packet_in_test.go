@@ -0,0 +1,118 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package openflow_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/superkkt/cherry/openflow"
+	"github.com/superkkt/cherry/openflow/of10"
+	"github.com/superkkt/cherry/openflow/of13"
+)
+
+var (
+	arpRequest = mustDecodeHex("ffffffffffff 0a0000000001 0806 0001 0800 06 04 0001 0a0000000001 c0a80001 000000000000 c0a80002")
+	packetIns  = map[string][]byte{
+		"of10": mustDecodeHex("01 0a 003c 00000000 ffffffff 002a 0007 00 00" + hex.EncodeToString(arpRequest)),
+		// Match has a metadata field before the ingress port.
+		"of13": mustDecodeHex("04 0a 005c 00000000 ffffffff 002a 00 01 0000000000000000" +
+			"0001 0018 80000408 0000000000000001 80000004 00000007 0000" + hex.EncodeToString(arpRequest)),
+		// OpenFlow 1.4 has the same PACKET_IN as OpenFlow 1.3.
+		"of14": mustDecodeHex("05 0a 005c 00000000 ffffffff 002a 00 01 0000000000000000" +
+			"0001 0018 80000408 0000000000000001 80000004 00000007 0000" + hex.EncodeToString(arpRequest)),
+	}
+)
+
+func mustDecodeHex(s string) []byte {
+	v, err := hex.DecodeString(strings.Replace(s, " ", "", -1))
+	if err != nil {
+		panic(err)
+	}
+
+	return v
+}
+
+func TestPacketInUnmarshal(t *testing.T) {
+	for version, factory := range fixtureVersions {
+		msg, err := factory.NewPacketIn()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := msg.UnmarshalBinary(packetIns[version]); err != nil {
+			t.Fatalf("%v: %v", version, err)
+		}
+		if msg.InPort() != 7 || msg.Length() != 42 || msg.BufferID() != openflow.NoBuffer {
+			t.Fatalf("%v: unexpected PACKET_IN: inPort=%v, length=%v, bufferID=%v", version, msg.InPort(), msg.Length(), msg.BufferID())
+		}
+		if !bytes.Equal(msg.Data(), arpRequest) {
+			t.Fatalf("%v: unexpected data: %x", version, msg.Data())
+		}
+
+		// The same message is reused without any allocation.
+		allocs := testing.AllocsPerRun(100, func() {
+			if err := msg.UnmarshalBinary(packetIns[version]); err != nil {
+				t.Fatal(err)
+			}
+		})
+		if allocs != 0 {
+			t.Fatalf("%v: expected no allocation, got %v", version, allocs)
+		}
+	}
+}
+
+func TestPacketInInvalidMatch(t *testing.T) {
+	tests := [][]byte{
+		// Truncated TLV of the ingress port.
+		mustDecodeHex("04 0a 0024 00000000 ffffffff 002a 00 00 0000000000000000 0001 000a 80000004 0000 0000"),
+		// Match length exceeding the message.
+		mustDecodeHex("04 0a 0024 00000000 ffffffff 002a 00 00 0000000000000000 0001 0040 80000004 0000 0007"),
+		// Standard match of OpenFlow 1.1.
+		mustDecodeHex("04 0a 0024 00000000 ffffffff 002a 00 00 0000000000000000 0000 000c 80000004 0000 0007"),
+	}
+	for i, packet := range tests {
+		if err := new(of13.PacketIn).UnmarshalBinary(packet); err == nil {
+			t.Fatalf("test #%v: expected an error", i)
+		}
+	}
+}
+
+func BenchmarkPacketInUnmarshal(b *testing.B) {
+	for _, version := range []string{"of10", "of13"} {
+		b.Run(version, func(b *testing.B) {
+			var msg openflow.PacketIn = new(of13.PacketIn)
+			if version == "of10" {
+				msg = new(of10.PacketIn)
+			}
+			packet := packetIns[version]
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := msg.UnmarshalBinary(packet); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -27,12 +27,17 @@ import (
 
 type PacketOut interface {
 	Action() Action
+	// BufferID returns the ID of the packet buffered in the switch, or NoBuffer.
+	BufferID() uint32
 	Data() []byte
 	encoding.BinaryMarshaler
 	Error() error
 	Header
 	InPort() InPort
 	SetAction(action Action)
+	// SetBufferID sets the ID of the packet buffered in the switch, which has been
+	// delivered by PACKET_IN. The data is not sent if the buffer ID is not NoBuffer.
+	SetBufferID(id uint32)
 	SetData(data []byte)
 	SetInPort(port InPort)
 }
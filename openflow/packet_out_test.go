@@ -0,0 +1,79 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package openflow_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/superkkt/cherry/openflow"
+	"github.com/superkkt/cherry/openflow/of10"
+	"github.com/superkkt/cherry/openflow/of13"
+)
+
+func TestPacketOutBufferID(t *testing.T) {
+	tests := []struct {
+		factory openflow.Factory
+		// Length of the PACKET_OUT header before the actions
+		header int
+	}{
+		{of10.NewFactory(), 8 + 8},
+		{of13.NewFactory(), 8 + 16},
+	}
+	data := []byte{0xde, 0xad, 0xbe, 0xef}
+
+	for _, test := range tests {
+		for _, id := range []uint32{openflow.NoBuffer, 0x1234} {
+			out, err := test.factory.NewPacketOut()
+			if err != nil {
+				t.Fatal(err)
+			}
+			action, err := test.factory.NewAction()
+			if err != nil {
+				t.Fatal(err)
+			}
+			port := openflow.NewOutPort()
+			port.SetValue(2)
+			action.SetOutPort(port)
+			inPort := openflow.NewInPort()
+			inPort.SetValue(1)
+			out.SetInPort(inPort)
+			out.SetAction(action)
+			out.SetData(data)
+			out.SetBufferID(id)
+
+			v, err := out.MarshalBinary()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := binary.BigEndian.Uint32(v[8:12]); got != id {
+				t.Fatalf("version %v: unexpected buffer ID: expected=%#x, got=%#x", test.factory.ProtocolVersion(), id, got)
+			}
+			// The data should be sent only if there is no buffered packet.
+			hasData := bytes.HasSuffix(v, data)
+			if hasData != (id == openflow.NoBuffer) {
+				t.Fatalf("version %v: unexpected data for buffer ID %#x: %x", test.factory.ProtocolVersion(), id, v[test.header:])
+			}
+		}
+	}
+}
@@ -147,6 +147,10 @@ type Port interface {
 	IsCopper() bool
 	IsFiber() bool
 	IsAutoNego() bool
+	// Config and State return the raw bitmaps of OFPPC_* and OFPPS_* flags, which
+	// depend on the OpenFlow version.
+	Config() uint32
+	State() uint32
 	// Speed returns current link speed in MB
 	Speed() uint64
 	encoding.BinaryUnmarshaler
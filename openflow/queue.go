@@ -64,3 +64,25 @@ type QueueGetConfigReply interface {
 	Queue() []Queue
 	encoding.BinaryUnmarshaler
 }
+
+// QueueStatsRequest queries the statistics of all queues of all ports.
+type QueueStatsRequest interface {
+	Header
+	encoding.BinaryMarshaler
+}
+
+// QueueStats is an entry of the queue statistics reply.
+type QueueStats struct {
+	PortNumber uint32
+	QueueID    uint32
+	TxBytes    uint64
+	TxPackets  uint64
+	// Number of packets dropped due to overrun
+	TxErrors uint64
+}
+
+type QueueStatsReply interface {
+	Header
+	QueueStats() []QueueStats
+	encoding.BinaryUnmarshaler
+}
@@ -0,0 +1,78 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package openflow
+
+import (
+	"encoding"
+)
+
+type ControllerRole uint8
+
+const (
+	// Don't change the current role.
+	RoleNoChange ControllerRole = iota
+	// Full access, and at least one controller has this role. This is the default role.
+	RoleEqual
+	// Full access, and at most one controller has this role.
+	RoleMaster
+	// Read-only access.
+	RoleSlave
+)
+
+func (r ControllerRole) String() string {
+	switch r {
+	case RoleNoChange:
+		return "NOCHANGE"
+	case RoleEqual:
+		return "EQUAL"
+	case RoleMaster:
+		return "MASTER"
+	case RoleSlave:
+		return "SLAVE"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// IsStaleGenerationID returns whether the generation ID id is older than last.
+// Generation IDs wrap around, so they are compared using the signed distance.
+func IsStaleGenerationID(id, last uint64) bool {
+	return int64(id-last) < 0
+}
+
+type RoleRequest interface {
+	encoding.BinaryMarshaler
+	Error() error
+	// GenerationID is only meaningful for the MASTER and SLAVE roles.
+	GenerationID() uint64
+	Header
+	Role() ControllerRole
+	SetGenerationID(id uint64)
+	SetRole(role ControllerRole)
+}
+
+type RoleReply interface {
+	GenerationID() uint64
+	Header
+	Role() ControllerRole
+	encoding.BinaryUnmarshaler
+}
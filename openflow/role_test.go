@@ -0,0 +1,63 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package openflow_test
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/superkkt/cherry/openflow"
+	"github.com/superkkt/cherry/openflow/of13"
+)
+
+func TestRoleRequest(t *testing.T) {
+	req := of13.NewRoleRequest(11)
+	req.SetRole(openflow.RoleSlave)
+	req.SetGenerationID(42)
+	v, err := req.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(v) != "04180018"+"0000000b"+"0000000300000000"+"000000000000002a" {
+		t.Fatalf("unexpected encoding: %x", v)
+	}
+}
+
+func TestStaleGenerationID(t *testing.T) {
+	tests := []struct {
+		id, last uint64
+		stale    bool
+	}{
+		{1, 1, false},
+		{2, 1, false},
+		{1, 2, true},
+		// Wrap around
+		{0, 0xFFFFFFFFFFFFFFFF, false},
+		{0xFFFFFFFFFFFFFFFF, 0, true},
+	}
+
+	for _, test := range tests {
+		if stale := openflow.IsStaleGenerationID(test.id, test.last); stale != test.stale {
+			t.Errorf("IsStaleGenerationID(%v, %v): expected=%v, got=%v", test.id, test.last, test.stale, stale)
+		}
+	}
+}
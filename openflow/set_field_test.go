@@ -0,0 +1,121 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package openflow_test
+
+import (
+	"encoding/hex"
+	"net"
+	"testing"
+
+	"github.com/superkkt/cherry/openflow"
+	"github.com/superkkt/cherry/openflow/of10"
+	"github.com/superkkt/cherry/openflow/of13"
+)
+
+func TestSetFieldActions(t *testing.T) {
+	f := of13.NewFactory()
+	action, err := openflow.NewActionBuilder(f).
+		SetVLANID(100).
+		SetVLANPriority(5).
+		SetMPLSLabel(0x12345).
+		SetMPLSTC(3).
+		SetIPSrc(net.ParseIP("10.0.0.1")).
+		SetIPDst(net.ParseIP("2001:db8::1")).
+		SetSrcPort(openflow.ProtocolUDP, 53).
+		SetDstPort(openflow.ProtocolSCTP, 80).
+		Output(1).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := action.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "00190010" + "80000c02" + "1064" + "000000000000" + // vlan_vid with OFPVID_PRESENT
+		"00190010" + "80000e01" + "05" + "00000000000000" + // vlan_pcp
+		"00190010" + "80004404" + "00012345" + "00000000" + // mpls_label
+		"00190010" + "80004601" + "03" + "00000000000000" + // mpls_tc
+		"00190010" + "80001604" + "0a000001" + "00000000" + // ipv4_src
+		"00190018" + "80003610" + "20010db8000000000000000000000001" + // ipv6_dst
+		"00190010" + "80001e02" + "0035" + "000000000000" + // udp_src
+		"00190010" + "80002402" + "0050" + "000000000000" + // sctp_dst
+		"0000001000000001ffff000000000000"
+	if v := hex.EncodeToString(data); v != expected {
+		t.Fatalf("unexpected action:\nexpected=%v\ngot=     %v", expected, v)
+	}
+
+	decoded, err := f.NewAction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if ok, vid := decoded.VLANID(); !ok || vid != 100 {
+		t.Fatalf("unexpected VLAN ID: %v", vid)
+	}
+	if ok, label := decoded.MPLSLabel(); !ok || label != 0x12345 {
+		t.Fatalf("unexpected MPLS label: %v", label)
+	}
+	if ok, ip := decoded.DstIP(); !ok || !ip.Equal(net.ParseIP("2001:db8::1")) {
+		t.Fatalf("unexpected destination IP: %v", ip)
+	}
+	if ok, protocol, port := decoded.SrcPort(); !ok || protocol != openflow.ProtocolUDP || port != 53 {
+		t.Fatalf("unexpected source port: %v/%v", protocol, port)
+	}
+	// Encoded again in the same way.
+	again, err := decoded.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(again) != expected {
+		t.Fatalf("unexpected encoding of the decoded action: %x", again)
+	}
+
+	// OpenFlow 1.0 rewrites the IPv4 addresses and the transport ports.
+	action, err = openflow.NewActionBuilder(of10.NewFactory()).SetIPSrc(net.ParseIP("10.0.0.1")).SetDstPort(openflow.ProtocolTCP, 80).Output(1).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err = action.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = "000600080a000001" + "000a000800500000" + "000000080001ffff"
+	if v := hex.EncodeToString(data); v != expected {
+		t.Fatalf("unexpected of10 action: expected=%v, got=%v", expected, v)
+	}
+
+	for i, v := range []*openflow.ActionBuilder{
+		openflow.NewActionBuilder(f).SetVLANPriority(8).Output(1),
+		openflow.NewActionBuilder(f).SetMPLSLabel(0x100000).Output(1),
+		openflow.NewActionBuilder(f).SetIPSrc(net.IP{1, 2}).Output(1),
+		openflow.NewActionBuilder(f).SetSrcPort(1, 80).Output(1),
+		openflow.NewActionBuilder(of10.NewFactory()).SetIPDst(net.ParseIP("2001:db8::1")).Output(1),
+		openflow.NewActionBuilder(of10.NewFactory()).SetMPLSTC(1).Output(1),
+	} {
+		if _, err := v.Build(); err == nil {
+			t.Fatalf("#%v: expected an error", i)
+		}
+	}
+}
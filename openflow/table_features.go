@@ -30,4 +30,47 @@ type TableFeaturesRequest interface {
 	encoding.BinaryMarshaler
 }
 
-// TODO: Implement TableFeaturesReply
+// OXMField identifies a match field in the table features.
+type OXMField struct {
+	Class uint16
+	Field uint8
+	// HasMask is true if the field can be masked.
+	HasMask bool
+}
+
+// TableFeatures is the capabilities of a flow table. A nil list means that the switch has not
+// reported the property, so it should not be used to reject a flow. The *Miss lists are the
+// capabilities of the table-miss flow entry, and they are the same with the regular lists if nil.
+type TableFeatures struct {
+	TableID       uint8
+	Name          string
+	MetadataMatch uint64
+	MetadataWrite uint64
+	MaxEntries    uint32
+	// OFPIT_* instruction types
+	Instructions     []uint16
+	InstructionsMiss []uint16
+	// Tables that can be the target of the goto-table instruction
+	NextTables     []uint8
+	NextTablesMiss []uint8
+	// OFPAT_* action types
+	WriteActions     []uint16
+	WriteActionsMiss []uint16
+	ApplyActions     []uint16
+	ApplyActionsMiss []uint16
+	// Fields that can be matched
+	Match []OXMField
+	// Fields that can be omitted from the match
+	Wildcards []OXMField
+	// Fields that can be set by the set-field actions
+	WriteSetField     []OXMField
+	WriteSetFieldMiss []OXMField
+	ApplySetField     []OXMField
+	ApplySetFieldMiss []OXMField
+}
+
+type TableFeaturesReply interface {
+	Header
+	Tables() []TableFeatures
+	encoding.BinaryUnmarshaler
+}
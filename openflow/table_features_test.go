@@ -0,0 +1,99 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package openflow_test
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/superkkt/cherry/openflow"
+	"github.com/superkkt/cherry/openflow/of13"
+)
+
+func newTableFeaturesReply(t *testing.T) openflow.TableFeaturesReply {
+	packet, err := readHex(filepath.Join("testdata", "of13", "table_features_reply.classifier.hex"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply, err := of13.NewFactory().NewTableFeaturesReply()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := reply.UnmarshalBinary(packet); err != nil {
+		t.Fatal(err)
+	}
+
+	return reply
+}
+
+func TestValidateFlowMod(t *testing.T) {
+	tables := newTableFeaturesReply(t).Tables()
+	f := of13.NewFactory()
+	mac := net.HardwareAddr{0, 1, 2, 3, 4, 5}
+
+	newFlow := func(tableID uint8, setMatch func(openflow.Match), setInst func(openflow.Instruction, openflow.Action)) openflow.FlowMod {
+		flow, _ := f.NewFlowMod(openflow.FlowAdd)
+		flow.SetTableID(tableID)
+		flow.SetPriority(10)
+		match, _ := f.NewMatch()
+		setMatch(match)
+		flow.SetFlowMatch(match)
+		inst, _ := f.NewInstruction()
+		action, _ := f.NewAction()
+		port := openflow.NewOutPort()
+		port.SetValue(1)
+		action.SetOutPort(port)
+		setInst(inst, action)
+		flow.SetFlowInstruction(inst)
+		return flow
+	}
+	dstMAC := func(m openflow.Match) { m.SetDstMAC(mac) }
+	apply := func(i openflow.Instruction, a openflow.Action) { i.ApplyAction(a) }
+
+	tests := []struct {
+		name  string
+		flow  openflow.FlowMod
+		valid bool
+	}{
+		{"supported", newFlow(0, dstMAC, func(i openflow.Instruction, a openflow.Action) { a.SetDstMAC(mac); i.ApplyAction(a) }), true},
+		{"masked IP", newFlow(0, func(m openflow.Match) {
+			m.SetEtherType(0x0800)
+			m.SetDstIP(&net.IPNet{IP: net.IPv4(10, 0, 0, 0), Mask: net.CIDRMask(24, 32)})
+		}, apply), true},
+		{"unknown table", newFlow(1, dstMAC, apply), false},
+		{"unsupported match", newFlow(0, func(m openflow.Match) { m.SetSrcMAC(mac) }, apply), false},
+		{"unsupported next table", newFlow(0, dstMAC, func(i openflow.Instruction, a openflow.Action) { i.GotoTable(2) }), false},
+		{"supported next table", newFlow(0, dstMAC, func(i openflow.Instruction, a openflow.Action) { i.GotoTable(1) }), true},
+		{"unsupported set-field", newFlow(0, dstMAC, func(i openflow.Instruction, a openflow.Action) { a.SetSrcMAC(mac); i.ApplyAction(a) }), false},
+		{"unsupported instruction", newFlow(0, dstMAC, func(i openflow.Instruction, a openflow.Action) { i.WriteAction(a) }), false},
+	}
+	for _, test := range tests {
+		err := of13.ValidateFlowMod(tables, test.flow)
+		if test.valid && err != nil {
+			t.Errorf("%v: unexpected error: %v", test.name, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("%v: expected an error", test.name)
+		}
+	}
+}
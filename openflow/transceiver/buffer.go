@@ -0,0 +1,174 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package transceiver
+
+import (
+	"bufio"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/superkkt/cherry/metrics"
+)
+
+// Size of the read buffer of a stream, which can hold the largest OpenFlow message.
+const readBufferSize = 0xFFFF
+
+// Capacities of the message buffers in increasing order. A message larger than the largest
+// one is allocated without the pool.
+var bufferClasses = []int{64, 256, 1024, 4096, 16384, 65536}
+
+// PoolStats is the statistics of a buffer pool.
+type PoolStats struct {
+	// Number of the buffers taken from the pool.
+	Gets uint64
+	// Number of the buffers newly allocated because the pool had no buffer to reuse. It is
+	// included in Gets, so Gets - Allocs is the number of the reused buffers.
+	Allocs uint64
+	// Number of the buffers returned to the pool.
+	Puts uint64
+}
+
+// poolCounters are updated atomically.
+type poolCounters struct {
+	gets, allocs, puts uint64
+}
+
+func (r *poolCounters) stats() PoolStats {
+	return PoolStats{
+		Gets:   atomic.LoadUint64(&r.gets),
+		Allocs: atomic.LoadUint64(&r.allocs),
+		Puts:   atomic.LoadUint64(&r.puts),
+	}
+}
+
+// register exposes the counters as the metrics whose names start with prefix.
+func (r *poolCounters) register(prefix, buffers string) {
+	metrics.NewCounterFunc(prefix+"_gets_total", "Number of the "+buffers+" taken from the pool.", func() float64 {
+		return float64(atomic.LoadUint64(&r.gets))
+	})
+	metrics.NewCounterFunc(prefix+"_allocs_total", "Number of the "+buffers+" allocated because the pool was empty.", func() float64 {
+		return float64(atomic.LoadUint64(&r.allocs))
+	})
+	metrics.NewCounterFunc(prefix+"_puts_total", "Number of the "+buffers+" returned to the pool.", func() float64 {
+		return float64(atomic.LoadUint64(&r.puts))
+	})
+}
+
+// messagePool reuses the buffers of the OpenFlow messages. The buffers of the sent messages are
+// returned to the pool after they are written, and the received messages are read into the
+// buffers taken from the pool. The received messages are not returned by default because the
+// handlers may keep them, e.g., the data of a PACKET_IN, so the pool mostly recycles the
+// buffers of the sent messages for the received ones.
+type messagePool struct {
+	poolCounters
+	// Pool of each buffer class, which keeps *[]byte.
+	classes []sync.Pool
+}
+
+// readerPool reuses the read buffers of the streams across the connections.
+type readerPool struct {
+	poolCounters
+	pool sync.Pool
+}
+
+var (
+	messages = newMessagePool()
+	readers  = new(readerPool)
+)
+
+func init() {
+	messages.register("cherry_openflow_message_buffers", "OpenFlow message buffers")
+	readers.register("cherry_openflow_read_buffers", "read buffers of the OpenFlow connections")
+}
+
+func newMessagePool() *messagePool {
+	return &messagePool{
+		classes: make([]sync.Pool, len(bufferClasses)),
+	}
+}
+
+// get returns a buffer whose length is n. Its content is not initialized.
+func (r *messagePool) get(n int) []byte {
+	atomic.AddUint64(&r.gets, 1)
+	for i, size := range bufferClasses {
+		if n > size {
+			continue
+		}
+		if v := r.classes[i].Get(); v != nil {
+			return (*v.(*[]byte))[:n]
+		}
+		atomic.AddUint64(&r.allocs, 1)
+		return make([]byte, n, size)
+	}
+	atomic.AddUint64(&r.allocs, 1)
+
+	return make([]byte, n)
+}
+
+// put returns buf to the pool. buf should not be used after put.
+func (r *messagePool) put(buf []byte) {
+	// Largest class that buf can hold.
+	class := -1
+	for i, size := range bufferClasses {
+		if cap(buf) < size {
+			break
+		}
+		class = i
+	}
+	if class < 0 {
+		return
+	}
+	atomic.AddUint64(&r.puts, 1)
+	buf = buf[:bufferClasses[class]]
+	r.classes[class].Put(&buf)
+}
+
+func (r *readerPool) get(channel io.Reader) *bufio.Reader {
+	atomic.AddUint64(&r.gets, 1)
+	if v := r.pool.Get(); v != nil {
+		reader := v.(*bufio.Reader)
+		reader.Reset(channel)
+		return reader
+	}
+	atomic.AddUint64(&r.allocs, 1)
+
+	return bufio.NewReaderSize(channel, readBufferSize)
+}
+
+// put returns reader to the pool. reader should not be used after put.
+func (r *readerPool) put(reader *bufio.Reader) {
+	atomic.AddUint64(&r.puts, 1)
+	// Do not keep the closed channel.
+	reader.Reset(nil)
+	r.pool.Put(reader)
+}
+
+// MessagePoolStats returns the statistics of the pool of the OpenFlow message buffers.
+func MessagePoolStats() PoolStats {
+	return messages.stats()
+}
+
+// ReaderPoolStats returns the statistics of the pool of the read buffers of the streams.
+func ReaderPoolStats() PoolStats {
+	return readers.stats()
+}
@@ -0,0 +1,109 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package transceiver
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/superkkt/cherry/openflow/of13"
+)
+
+func TestMessagePoolClasses(t *testing.T) {
+	pool := newMessagePool()
+
+	tests := []struct {
+		length, capacity int
+	}{
+		{1, 64},
+		{64, 64},
+		{100, 256},
+		{65536, 65536},
+		{70000, 70000},
+	}
+	for _, test := range tests {
+		buf := pool.get(test.length)
+		if len(buf) != test.length || cap(buf) != test.capacity {
+			t.Fatalf("unexpected buffer for %v bytes: len=%v, cap=%v", test.length, len(buf), cap(buf))
+		}
+	}
+
+	// Too small buffer is not pooled.
+	pool.put(make([]byte, 10))
+	if s := pool.stats(); s.Gets != 5 || s.Allocs != 5 || s.Puts != 0 {
+		t.Fatalf("unexpected stats: %+v", s)
+	}
+}
+
+func TestMessagePoolReuse(t *testing.T) {
+	pool := newMessagePool()
+
+	for i := 0; i < 100; i++ {
+		// A buffer of 300 bytes is kept in the class of 256 bytes.
+		buf := pool.get(200)
+		pool.put(append(buf, make([]byte, 100)...))
+	}
+	s := pool.stats()
+	if s.Gets != 100 || s.Puts != 100 {
+		t.Fatalf("unexpected stats: %+v", s)
+	}
+	// sync.Pool may drop some buffers, e.g., by the garbage collection.
+	if s.Gets-s.Allocs == 0 {
+		t.Fatalf("no buffer has been reused: %+v", s)
+	}
+}
+
+func TestStreamReadMessage(t *testing.T) {
+	local, remote := net.Pipe()
+	defer remote.Close()
+	stream := NewStream(local)
+	before := ReaderPoolStats()
+
+	echo := append(testPacket(of13.OFPT_ECHO_REQUEST), 1, 2, 3, 4)
+	echo[3] = 12
+	barrier := testPacket(of13.OFPT_BARRIER_REPLY)
+	go remote.Write(append(append([]byte(nil), echo...), barrier...))
+
+	for _, expected := range [][]byte{echo, barrier} {
+		packet, err := stream.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(packet, expected) {
+			t.Fatalf("unexpected message: expected=%v, got=%v", expected, packet)
+		}
+	}
+
+	if err := stream.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.ReadMessage(); err != io.ErrClosedPipe {
+		t.Fatalf("expected io.ErrClosedPipe, got %v", err)
+	}
+	// The read buffer is returned once even if the stream is closed twice.
+	stream.Close()
+	if puts := ReaderPoolStats().Puts - before.Puts; puts != 1 {
+		t.Fatalf("expected a returned read buffer, got %v", puts)
+	}
+}
@@ -0,0 +1,136 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package transceiver
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/superkkt/cherry/openflow"
+	"github.com/superkkt/cherry/openflow/of14"
+)
+
+// SendBundle applies all the requests in reqs atomically. The device applies either all of
+// them or none of them if it supports the bundle messages of OpenFlow 1.4. Otherwise, reqs are
+// sent as a barrier-delimited sequence by SendBatch, so some of them may have been applied
+// even if an error is returned.
+//
+// SendBundle should not be called by the handler functions for the same reason as SendAndWait.
+func (r *Transceiver) SendBundle(ctx context.Context, reqs []Request) error {
+	if _, version := r.Version(); version < openflow.OF14_VERSION {
+		return r.SendBatch(ctx, reqs)
+	}
+
+	id := atomic.AddUint32(&r.bundleID, 1)
+	const flags = of14.OFPBF_ATOMIC | of14.OFPBF_ORDERED
+
+	if err := r.sendBundleControl(ctx, id, of14.OFPBCT_OPEN_REQUEST, flags); err != nil {
+		return err
+	}
+
+	replies := make([]<-chan openflow.Header, len(reqs))
+	for i, req := range reqs {
+		c, err := r.pending.add(req.TransactionID())
+		if err != nil {
+			r.discardBundle(ctx, id, flags)
+			return err
+		}
+		defer r.pending.remove(req.TransactionID())
+		replies[i] = c
+
+		// The bundle add message has the same transaction ID as its request so that the
+		// error message caused by the request is delivered to us.
+		if err := r.WriteContext(ctx, of14.NewBundleAdd(req.TransactionID(), id, flags, req)); err != nil {
+			r.discardBundle(ctx, id, flags)
+			return err
+		}
+	}
+
+	// The device validates the requests while adding them into the bundle.
+	if err := r.sendBundleControl(ctx, id, of14.OFPBCT_CLOSE_REQUEST, flags); err != nil {
+		r.discardBundle(ctx, id, flags)
+		return err
+	}
+	if err := firstError(replies); err != nil {
+		r.discardBundle(ctx, id, flags)
+		return err
+	}
+
+	return r.sendBundleControl(ctx, id, of14.OFPBCT_COMMIT_REQUEST, flags)
+}
+
+// sendBundleControl sends a bundle control request and waits for its reply.
+func (r *Transceiver) sendBundleControl(ctx context.Context, id uint32, ctrlType, flags uint16) error {
+	xid, err := r.newTransactionID()
+	if err != nil {
+		return err
+	}
+
+	reply, err := r.SendAndWait(ctx, of14.NewBundleControl(xid, id, ctrlType, flags))
+	if err != nil {
+		return err
+	}
+	v, ok := reply.(*of14.BundleControl)
+	if !ok {
+		return fmt.Errorf("unexpected reply for the bundle control request: %T", reply)
+	}
+	// Reply type is always the request type plus one.
+	if v.ControlType() != ctrlType+1 {
+		return fmt.Errorf("unexpected bundle control reply: bundleID=%v, type=%v", id, v.ControlType())
+	}
+
+	return nil
+}
+
+// discardBundle discards the bundle whose ID is id. Errors are only logged because the
+// caller is already returning another error.
+func (r *Transceiver) discardBundle(ctx context.Context, id uint32, flags uint16) {
+	if err := r.sendBundleControl(ctx, id, of14.OFPBCT_DISCARD_REQUEST, flags); err != nil {
+		logger.Errorf("failed to discard the bundle: bundleID=%v, err=%v", id, err)
+	}
+}
+
+// handleBundleControl delivers the bundle control reply to sendBundleControl. The handler
+// is not notified because only SendBundle sends the bundle control requests.
+func (r *Transceiver) handleBundleControl(packet []byte) error {
+	msg := new(of14.BundleControl)
+	if err := msg.UnmarshalBinary(packet); err != nil {
+		return err
+	}
+	logger.Debugf("received a BUNDLE_CONTROL reply: bundleID=%v, type=%v", msg.BundleID(), msg.ControlType())
+
+	r.pending.complete(msg)
+
+	return nil
+}
+
+// newTransactionID allocates a transaction ID from the factory so that it does not collide
+// with the ones of the messages made by the factory.
+func (r *Transceiver) newTransactionID() (uint32, error) {
+	msg, err := r.factory.NewBarrierRequest()
+	if err != nil {
+		return 0, err
+	}
+
+	return msg.TransactionID(), nil
+}
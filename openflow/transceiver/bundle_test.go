@@ -0,0 +1,179 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package transceiver
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/superkkt/cherry/clock"
+	"github.com/superkkt/cherry/openflow"
+	"github.com/superkkt/cherry/openflow/of13"
+	"github.com/superkkt/cherry/openflow/of14"
+)
+
+// bundleSwitch is a fake OpenFlow 1.4 switch that replies to the bundle control requests and
+// rejects the bundle add message whose transaction ID is reject.
+type bundleSwitch struct {
+	mutex    sync.Mutex
+	controls []uint16
+	added    []uint32
+	reject   uint32
+}
+
+func (r *bundleSwitch) run(t *testing.T, conn net.Conn, stream *Stream) {
+	for {
+		packet, err := stream.ReadMessage()
+		if err != nil {
+			return
+		}
+		xid := binary.BigEndian.Uint32(packet[4:8])
+
+		var reply []byte
+		switch packet[1] {
+		case of14.OFPT_BUNDLE_CONTROL:
+			ctrlType := binary.BigEndian.Uint16(packet[12:14])
+			r.record(func() { r.controls = append(r.controls, ctrlType) })
+			reply = append([]byte(nil), packet...)
+			binary.BigEndian.PutUint16(reply[12:14], ctrlType+1)
+		case of14.OFPT_BUNDLE_ADD_MESSAGE:
+			// The inner message should have the same transaction ID as the bundle add message.
+			if inner := binary.BigEndian.Uint32(packet[20:24]); inner != xid {
+				t.Errorf("mis-matched transaction ID of the inner message: outer=%v, inner=%v", xid, inner)
+			}
+			r.record(func() { r.added = append(r.added, xid) })
+			if xid != r.reject {
+				continue
+			}
+			// OFPET_BUNDLE_FAILED error message.
+			reply = []byte{openflow.OF14_VERSION, of13.OFPT_ERROR, 0, 12, 0, 0, 0, 0, 0, 17, 0, 0}
+			binary.BigEndian.PutUint32(reply[4:8], xid)
+		default:
+			continue
+		}
+		conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+		if _, err := conn.Write(reply); err != nil {
+			return
+		}
+	}
+}
+
+func (r *bundleSwitch) record(f func()) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	f()
+}
+
+func (r *bundleSwitch) result() (controls []uint16, added []uint32) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return append([]uint16(nil), r.controls...), append([]uint32(nil), r.added...)
+}
+
+// startBundleTest returns a transceiver that has negotiated OpenFlow 1.4 with sw.
+func startBundleTest(t *testing.T, sw *bundleSwitch) (trans *Transceiver, cleanup func()) {
+	trans, remote, device, cleanup := startNegotiated(t, openflow.OF14_VERSION, clock.Real)
+	go sw.run(t, remote, device)
+
+	return trans, cleanup
+}
+
+func newBundleFlows(t *testing.T, f openflow.Factory, n int) []Request {
+	reqs := make([]Request, n)
+	for i := range reqs {
+		flow, err := f.NewFlowMod(openflow.FlowAdd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		match, err := f.NewMatch()
+		if err != nil {
+			t.Fatal(err)
+		}
+		flow.SetFlowMatch(match)
+		reqs[i] = flow
+	}
+
+	return reqs
+}
+
+func TestSendBundle(t *testing.T) {
+	sw := new(bundleSwitch)
+	trans, cleanup := startBundleTest(t, sw)
+	defer cleanup()
+
+	reqs := newBundleFlows(t, trans.factory, 2)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := trans.SendBundle(ctx, reqs); err != nil {
+		t.Fatal(err)
+	}
+
+	controls, added := sw.result()
+	expected := []uint16{of14.OFPBCT_OPEN_REQUEST, of14.OFPBCT_CLOSE_REQUEST, of14.OFPBCT_COMMIT_REQUEST}
+	if !equalUint16s(controls, expected) {
+		t.Fatalf("unexpected bundle controls: expected=%v, got=%v", expected, controls)
+	}
+	if len(added) != 2 || added[0] != reqs[0].TransactionID() || added[1] != reqs[1].TransactionID() {
+		t.Fatalf("unexpected bundle add messages: %v", added)
+	}
+}
+
+func TestSendBundleRejected(t *testing.T) {
+	sw := new(bundleSwitch)
+	trans, cleanup := startBundleTest(t, sw)
+	defer cleanup()
+
+	reqs := newBundleFlows(t, trans.factory, 2)
+	sw.record(func() { sw.reject = reqs[1].TransactionID() })
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := trans.SendBundle(ctx, reqs)
+	e, ok := err.(*openflow.DeviceError)
+	if !ok || e.XID != reqs[1].TransactionID() {
+		t.Fatalf("expected the device error of the rejected request, got %v", err)
+	}
+
+	// The bundle should be discarded instead of being committed.
+	controls, _ := sw.result()
+	expected := []uint16{of14.OFPBCT_OPEN_REQUEST, of14.OFPBCT_CLOSE_REQUEST, of14.OFPBCT_DISCARD_REQUEST}
+	if !equalUint16s(controls, expected) {
+		t.Fatalf("unexpected bundle controls: expected=%v, got=%v", expected, controls)
+	}
+}
+
+func equalUint16s(a, b []uint16) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,78 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package transceiver
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/superkkt/cherry/openflow"
+)
+
+// pendingTable keeps the requests waiting for their replies. Key is the transaction ID.
+type pendingTable struct {
+	mutex    sync.Mutex
+	requests map[uint32]chan openflow.Header
+}
+
+func newPendingTable() *pendingTable {
+	return &pendingTable{
+		requests: make(map[uint32]chan openflow.Header),
+	}
+}
+
+func (r *pendingTable) add(xid uint32) (<-chan openflow.Header, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.requests[xid]; ok {
+		return nil, fmt.Errorf("duplicated pending transaction ID: %v", xid)
+	}
+	// Buffered channel so that complete() never blocks.
+	c := make(chan openflow.Header, 1)
+	r.requests[xid] = c
+
+	return c, nil
+}
+
+func (r *pendingTable) remove(xid uint32) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.requests, xid)
+}
+
+// complete delivers msg to the request that has the same transaction ID. It returns
+// false if there is no such request.
+func (r *pendingTable) complete(msg openflow.Header) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	c, ok := r.requests[msg.TransactionID()]
+	if !ok {
+		return false
+	}
+	delete(r.requests, msg.TransactionID())
+	c <- msg
+
+	return true
+}
@@ -0,0 +1,302 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package transceiver
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/superkkt/cherry/clock"
+	"github.com/superkkt/cherry/openflow"
+	"github.com/superkkt/cherry/openflow/of13"
+)
+
+// replyHandler accepts the replies that are also delivered to the handler.
+type replyHandler struct {
+	helloHandler
+}
+
+func (r *replyHandler) OnError(openflow.Factory, Writer, openflow.Error) error {
+	return nil
+}
+
+func (r *replyHandler) OnBarrierReply(openflow.Factory, Writer, openflow.BarrierReply) error {
+	return nil
+}
+
+// manualClock expires the timers of maxReplyWait only when expire is sent.
+type manualClock struct {
+	clock.Clock
+	expire chan time.Time
+}
+
+func (r *manualClock) NewTimer(d time.Duration) clock.Timer {
+	if d != maxReplyWait {
+		return r.Clock.NewTimer(d)
+	}
+	return manualTimer{r.expire}
+}
+
+type manualTimer struct {
+	c chan time.Time
+}
+
+func (r manualTimer) C() <-chan time.Time {
+	return r.c
+}
+
+func (r manualTimer) Reset(d time.Duration) bool {
+	return true
+}
+
+func (r manualTimer) Stop() bool {
+	return true
+}
+
+// startNegotiated returns a transceiver that has negotiated version with the device whose
+// end of the connection is remote. cleanup stops the transceiver and closes the connection.
+func startNegotiated(t *testing.T, version uint8, clk clock.Clock) (trans *Transceiver, remote net.Conn, device *Stream, cleanup func()) {
+	local, remote := net.Pipe()
+	handler := &replyHandler{helloHandler{hello: make(chan openflow.Factory, 1)}}
+	trans = NewTransceiver(NewStream(local), handler, clk)
+	ctx, cancel := context.WithCancel(context.Background())
+	go trans.Run(ctx)
+
+	device = NewStream(remote)
+	writePacket(t, remote, []byte{version, of13.OFPT_HELLO, 0, 8, 0, 0, 0, 1})
+	select {
+	case <-handler.hello:
+	case <-time.After(5 * time.Second):
+		t.Fatal("HELLO is not dispatched")
+	}
+
+	return trans, remote, device, func() {
+		cancel()
+		trans.Close()
+		device.Close()
+		remote.Close()
+	}
+}
+
+// sendAndWait calls SendAndWait in a new goroutine and returns its transaction ID and the
+// channel that receives the result.
+func sendAndWait(t *testing.T, ctx context.Context, trans *Transceiver) (xid uint32, result <-chan error) {
+	req, err := trans.factory.NewBarrierRequest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := make(chan error, 1)
+	go func() {
+		reply, err := trans.SendAndWait(ctx, req)
+		if err == nil && reply.TransactionID() != req.TransactionID() {
+			err = fmt.Errorf("unexpected reply: xid=%v", reply.TransactionID())
+		}
+		c <- err
+	}()
+
+	return req.TransactionID(), c
+}
+
+func waitResult(t *testing.T, result <-chan error) error {
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(5 * time.Second):
+		t.Fatal("SendAndWait did not return")
+		return nil
+	}
+}
+
+func isPending(trans *Transceiver, xid uint32) bool {
+	trans.pending.mutex.Lock()
+	defer trans.pending.mutex.Unlock()
+
+	_, ok := trans.pending.requests[xid]
+	return ok
+}
+
+func barrierReply(xid uint32) []byte {
+	return []byte{openflow.OF13_VERSION, of13.OFPT_BARRIER_REPLY, 0, 8, byte(xid >> 24), byte(xid >> 16), byte(xid >> 8), byte(xid)}
+}
+
+func TestPendingTable(t *testing.T) {
+	table := newPendingTable()
+	c, err := table.add(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := table.add(1); err == nil {
+		t.Fatal("expected an error for the duplicated transaction ID")
+	}
+
+	if table.complete(of13.NewBarrierRequest(2)) {
+		t.Fatal("completed the unknown transaction ID")
+	}
+	// complete never blocks even if no one is receiving.
+	if !table.complete(of13.NewBarrierRequest(1)) {
+		t.Fatal("failed to complete the pending request")
+	}
+	if reply := <-c; reply.TransactionID() != 1 {
+		t.Fatalf("unexpected reply: xid=%v", reply.TransactionID())
+	}
+	// The request is removed once it is completed.
+	if table.complete(of13.NewBarrierRequest(1)) {
+		t.Fatal("completed the same request twice")
+	}
+
+	if _, err := table.add(3); err != nil {
+		t.Fatal(err)
+	}
+	table.remove(3)
+	if table.complete(of13.NewBarrierRequest(3)) {
+		t.Fatal("completed the removed request")
+	}
+}
+
+func TestSendAndWaitReply(t *testing.T) {
+	trans, remote, device, cleanup := startNegotiated(t, openflow.OF13_VERSION, clock.Real)
+	defer cleanup()
+
+	xid, result := sendAndWait(t, context.Background(), trans)
+	readType(t, device, of13.OFPT_BARRIER_REQUEST)
+	// The reply of another transaction should not be matched.
+	writePacket(t, remote, barrierReply(xid+100))
+	writePacket(t, remote, barrierReply(xid))
+	if err := waitResult(t, result); err != nil {
+		t.Fatal(err)
+	}
+	if isPending(trans, xid) {
+		t.Fatal("the request is still pending after its reply")
+	}
+}
+
+func TestSendAndWaitError(t *testing.T) {
+	trans, remote, device, cleanup := startNegotiated(t, openflow.OF13_VERSION, clock.Real)
+	defer cleanup()
+
+	xid, result := sendAndWait(t, context.Background(), trans)
+	readType(t, device, of13.OFPT_BARRIER_REQUEST)
+	// OFPET_BAD_REQUEST/OFPBRC_BAD_TYPE
+	writePacket(t, remote, []byte{openflow.OF13_VERSION, of13.OFPT_ERROR, 0, 12, byte(xid >> 24), byte(xid >> 16), byte(xid >> 8), byte(xid), 0, 1, 0, 1})
+	err := waitResult(t, result)
+	e, ok := err.(*openflow.DeviceError)
+	if !ok || e.XID != xid {
+		t.Fatalf("expected the device error, got %v", err)
+	}
+}
+
+func TestSendAndWaitTimeout(t *testing.T) {
+	clk := &manualClock{Clock: clock.Real, expire: make(chan time.Time, 1)}
+	trans, _, device, cleanup := startNegotiated(t, openflow.OF13_VERSION, clk)
+	defer cleanup()
+
+	// maxReplyWait is used if ctx has no deadline.
+	xid, result := sendAndWait(t, context.Background(), trans)
+	readType(t, device, of13.OFPT_BARRIER_REQUEST)
+	clk.expire <- time.Now()
+	if err := waitResult(t, result); err == nil || !strings.Contains(err.Error(), "timeout") {
+		t.Fatalf("expected the timeout error, got %v", err)
+	}
+	if isPending(trans, xid) {
+		t.Fatal("the request is still pending after the timeout")
+	}
+
+	// The deadline of ctx overrides maxReplyWait.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	xid, result = sendAndWait(t, ctx, trans)
+	readType(t, device, of13.OFPT_BARRIER_REQUEST)
+	if err := waitResult(t, result); err != context.DeadlineExceeded {
+		t.Fatalf("expected the deadline error, got %v", err)
+	}
+	if isPending(trans, xid) {
+		t.Fatal("the request is still pending after the deadline")
+	}
+}
+
+func TestSendAndWaitClosed(t *testing.T) {
+	trans, remote, device, cleanup := startNegotiated(t, openflow.OF13_VERSION, clock.Real)
+	defer cleanup()
+
+	xid, result := sendAndWait(t, context.Background(), trans)
+	readType(t, device, of13.OFPT_BARRIER_REQUEST)
+	// Run returns when the device closes the connection.
+	remote.Close()
+	if err := waitResult(t, result); err != errClosed {
+		t.Fatalf("expected errClosed, got %v", err)
+	}
+	if isPending(trans, xid) {
+		t.Fatal("the request is still pending after the transceiver is closed")
+	}
+}
+
+func TestSendAndWaitEcho(t *testing.T) {
+	trans, remote, device, cleanup := startNegotiated(t, openflow.OF13_VERSION, clock.Real)
+	defer cleanup()
+
+	req, err := trans.factory.NewEchoRequest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetData([]byte{1, 2, 3})
+	c := make(chan error, 1)
+	replies := make(chan openflow.EchoReply, 1)
+	go func() {
+		reply, err := trans.SendAndWait(context.Background(), req)
+		if err == nil {
+			v, ok := reply.(openflow.EchoReply)
+			if !ok || !bytes.Equal(v.Data(), []byte{1, 2, 3}) {
+				err = fmt.Errorf("unexpected reply: %+v", reply)
+			}
+			replies <- v
+		}
+		c <- err
+	}()
+
+	// Skip the keepalive pings.
+	packet := readType(t, device, of13.OFPT_ECHO_REQUEST)
+	for binary.BigEndian.Uint32(packet[4:8]) != req.TransactionID() {
+		packet = readType(t, device, of13.OFPT_ECHO_REQUEST)
+	}
+	// Echo the request back with its payload.
+	packet[1] = of13.OFPT_ECHO_REPLY
+	writePacket(t, remote, packet)
+	if err := waitResult(t, c); err != nil {
+		t.Fatal(err)
+	}
+	reply := <-replies
+
+	// The reader recycles its buffers for the next messages, which should not change the reply.
+	for i := 0; i < 8; i++ {
+		writePacket(t, remote, []byte{openflow.OF13_VERSION, of13.OFPT_ECHO_REQUEST, 0, 11, 0, 0, 0, 0x7f, 9, 9, 9})
+		readType(t, device, of13.OFPT_ECHO_REPLY)
+	}
+	if !bytes.Equal(reply.Data(), []byte{1, 2, 3}) {
+		t.Fatalf("reply data has been overwritten: %v", reply.Data())
+	}
+}
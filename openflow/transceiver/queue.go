@@ -0,0 +1,194 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package transceiver
+
+import (
+	"context"
+	"time"
+
+	"github.com/superkkt/cherry/clock"
+	"github.com/superkkt/cherry/openflow"
+	"github.com/superkkt/cherry/openflow/of10"
+	"github.com/superkkt/cherry/openflow/of13"
+
+	"github.com/pkg/errors"
+)
+
+// ErrQueueFull is returned by Write if the outbound queue is full. Low priority messages are
+// dropped immediately, and others after the queue stays full for writeTimeout or until the
+// deadline of the context given to WriteContext.
+var ErrQueueFull = errors.New("outbound message queue is full")
+
+var errClosed = errors.New("transceiver is closed")
+
+type priority int
+
+const (
+	// ECHO_REQUEST and ECHO_REPLY that keep the connection alive.
+	priorityHigh priority = iota
+	// All the messages other than the ones of the high and low priorities.
+	priorityNormal
+	// PACKET_OUT that can be dropped without breaking anything other than the packet itself.
+	priorityLow
+)
+
+// Capacity of the outbound queue of each priority.
+const (
+	highQueueSize   = 64
+	normalQueueSize = 1024
+	lowQueueSize    = 256
+)
+
+// outQueue is the prioritized outbound message queue of a transceiver. The writer goroutine
+// always sends the messages in the high priority queue first, and the ones in the low priority
+// queue only if the other queues are empty. Messages of the same priority are sent in order.
+//
+// NOTE: BARRIER_REQUEST has the normal priority because it has to follow the messages that it
+// fences. It would be meaningless if it overtook them.
+type outQueue struct {
+	high, normal, low chan []byte
+	clock             clock.Clock
+}
+
+func newOutQueue(clk clock.Clock) *outQueue {
+	if clk == nil {
+		panic("clock is nil")
+	}
+
+	return &outQueue{
+		clock:  clk,
+		high:   make(chan []byte, highQueueSize),
+		normal: make(chan []byte, normalQueueSize),
+		low:    make(chan []byte, lowQueueSize),
+	}
+}
+
+// classify returns the priority of the encoded OpenFlow message packet.
+func classify(packet []byte) priority {
+	if len(packet) < 2 {
+		return priorityNormal
+	}
+
+	switch packet[0] {
+	case openflow.OF10_VERSION:
+		switch packet[1] {
+		case of10.OFPT_ECHO_REQUEST, of10.OFPT_ECHO_REPLY:
+			return priorityHigh
+		case of10.OFPT_PACKET_OUT:
+			return priorityLow
+		}
+	// OpenFlow 1.4 has the same message types as OpenFlow 1.3 except the new ones.
+	case openflow.OF13_VERSION, openflow.OF14_VERSION:
+		switch packet[1] {
+		case of13.OFPT_ECHO_REQUEST, of13.OFPT_ECHO_REPLY:
+			return priorityHigh
+		case of13.OFPT_PACKET_OUT:
+			return priorityLow
+		}
+	}
+
+	return priorityNormal
+}
+
+// push enqueues packet. A low priority packet is dropped immediately if its queue is full.
+// Otherwise, push blocks until the writer goroutine makes a room, ctx is done, done is closed,
+// or timeout elapses. timeout is ignored if ctx has a deadline.
+func (r *outQueue) push(ctx context.Context, packet []byte, timeout time.Duration, done <-chan struct{}) error {
+	select {
+	case <-done:
+		return errClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	var q chan []byte
+	switch classify(packet) {
+	case priorityHigh:
+		q = r.high
+	case priorityLow:
+		select {
+		case r.low <- packet:
+			return nil
+		default:
+			messagesDropped.Inc()
+			return ErrQueueFull
+		}
+	default:
+		q = r.normal
+	}
+
+	// Fast path that does not allocate a timer.
+	select {
+	case q <- packet:
+		return nil
+	default:
+	}
+
+	var expired <-chan time.Time
+	if _, ok := ctx.Deadline(); !ok {
+		timer := r.clock.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C()
+	}
+
+	select {
+	case q <- packet:
+		return nil
+	case <-done:
+		return errClosed
+	case <-ctx.Done():
+		messagesDropped.Inc()
+		return ctx.Err()
+	case <-expired:
+		messagesDropped.Inc()
+		return ErrQueueFull
+	}
+}
+
+// pop dequeues the packet of the highest priority. It blocks until a packet is available or
+// done is closed. ok will be false if done is closed.
+func (r *outQueue) pop(done <-chan struct{}) (packet []byte, ok bool) {
+	select {
+	case packet = <-r.high:
+		return packet, true
+	default:
+	}
+	select {
+	case packet = <-r.high:
+		return packet, true
+	case packet = <-r.normal:
+		return packet, true
+	default:
+	}
+
+	// All the queues were empty. Take whatever arrives first.
+	select {
+	case packet = <-r.high:
+	case packet = <-r.normal:
+	case packet = <-r.low:
+	case <-done:
+		return nil, false
+	}
+
+	return packet, true
+}
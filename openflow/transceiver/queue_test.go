@@ -0,0 +1,127 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package transceiver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/superkkt/cherry/clock"
+	"github.com/superkkt/cherry/openflow"
+	"github.com/superkkt/cherry/openflow/of13"
+)
+
+func testPacket(msgType uint8) []byte {
+	return []byte{openflow.OF13_VERSION, msgType, 0, 8, 0, 0, 0, 0}
+}
+
+func TestOutQueuePriority(t *testing.T) {
+	q := newOutQueue(clock.Real)
+	done := make(chan struct{})
+
+	for _, msgType := range []uint8{of13.OFPT_PACKET_OUT, of13.OFPT_FLOW_MOD, of13.OFPT_BARRIER_REQUEST, of13.OFPT_ECHO_REQUEST} {
+		if err := q.push(context.Background(), testPacket(msgType), time.Second, done); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The barrier should not overtake the flow-mod that it fences.
+	expected := []uint8{of13.OFPT_ECHO_REQUEST, of13.OFPT_FLOW_MOD, of13.OFPT_BARRIER_REQUEST, of13.OFPT_PACKET_OUT}
+	for _, msgType := range expected {
+		p, ok := q.pop(done)
+		if !ok {
+			t.Fatal("unexpected closed queue")
+		}
+		if p[1] != msgType {
+			t.Fatalf("unexpected message type: expected=%v, got=%v", msgType, p[1])
+		}
+	}
+
+	close(done)
+	if _, ok := q.pop(done); ok {
+		t.Fatal("pop should fail after done is closed")
+	}
+	if err := q.push(context.Background(), testPacket(of13.OFPT_FLOW_MOD), time.Second, done); err == nil {
+		t.Fatal("push should fail after done is closed")
+	}
+}
+
+func TestOutQueueFull(t *testing.T) {
+	q := newOutQueue(clock.Real)
+	done := make(chan struct{})
+
+	for i := 0; i < lowQueueSize; i++ {
+		if err := q.push(context.Background(), testPacket(of13.OFPT_PACKET_OUT), time.Second, done); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The low priority message is dropped without blocking.
+	if err := q.push(context.Background(), testPacket(of13.OFPT_PACKET_OUT), time.Hour, done); err != ErrQueueFull {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i := 0; i < normalQueueSize; i++ {
+		if err := q.push(context.Background(), testPacket(of13.OFPT_FLOW_MOD), time.Second, done); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The normal priority message waits for the writer.
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		q.pop(done)
+	}()
+	if err := q.push(context.Background(), testPacket(of13.OFPT_FLOW_MOD), time.Minute, done); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := q.push(context.Background(), testPacket(of13.OFPT_FLOW_MOD), 10*time.Millisecond, done); err != ErrQueueFull {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestOutQueueContext(t *testing.T) {
+	q := newOutQueue(clock.Real)
+	done := make(chan struct{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	// The cancelled context fails even if the queue has a room.
+	if err := q.push(ctx, testPacket(of13.OFPT_FLOW_MOD), time.Second, done); err != context.Canceled {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i := 0; i < normalQueueSize; i++ {
+		if err := q.push(context.Background(), testPacket(of13.OFPT_FLOW_MOD), time.Second, done); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The deadline of the context overrides the timeout.
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := q.push(ctx, testPacket(of13.OFPT_FLOW_MOD), time.Hour, done); err != context.DeadlineExceeded {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("push blocked too long: %v", elapsed)
+	}
+}
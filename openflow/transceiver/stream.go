@@ -23,13 +23,20 @@ package transceiver
 
 import (
 	"bufio"
+	"encoding/binary"
 	"io"
+	"sync"
 	"time"
+
+	"github.com/superkkt/cherry/openflow"
 )
 
-// Stream is a buffered I/O channel.
+// Stream is a buffered I/O channel. Its read buffer is taken from a pool shared by all the
+// streams, and returned to the pool when the stream is closed.
 type Stream struct {
-	channel      io.ReadWriteCloser
+	channel io.ReadWriteCloser
+	// readMutex protects reader, which is nil after the stream is closed.
+	readMutex    sync.Mutex
 	reader       *bufio.Reader
 	readTimeout  time.Duration
 	writeTimeout time.Duration
@@ -42,10 +49,9 @@ type Deadline interface {
 
 // NewStream returns a new buffered I/O channel. channel is an underlying I/O channel that implements io.ReadWriteCloser.
 func NewStream(channel io.ReadWriteCloser) *Stream {
-	r := bufio.NewReaderSize(channel, 0xFFFF)
 	return &Stream{
 		channel: channel,
-		reader:  r,
+		reader:  readers.get(channel),
 	}
 }
 
@@ -59,28 +65,51 @@ func (r *Stream) SetWriteTimeout(t time.Duration) {
 	r.writeTimeout = t
 }
 
-// Read is a wrapper function of bufio.Reader.Read().
-func (r *Stream) Read(p []byte) (n int, err error) {
+// lockReader locks the read buffer, and sets the read deadline. It returns false if the stream
+// has been closed. The caller should call unlockReader if it returns true.
+func (r *Stream) lockReader() bool {
+	r.readMutex.Lock()
+	if r.reader == nil {
+		r.readMutex.Unlock()
+		return false
+	}
 	if r.readTimeout > 0 {
 		d, ok := r.channel.(Deadline)
 		if ok {
 			d.SetReadDeadline(time.Now().Add(r.readTimeout))
-			defer d.SetReadDeadline(time.Time{})
 		}
 	}
 
-	return r.reader.Read(p)
+	return true
 }
 
-// Peek is a wrapper function of bufio.Reader.Peek().
-func (r *Stream) Peek(n int) (p []byte, err error) {
+func (r *Stream) unlockReader() {
 	if r.readTimeout > 0 {
 		d, ok := r.channel.(Deadline)
 		if ok {
-			d.SetReadDeadline(time.Now().Add(r.readTimeout))
-			defer d.SetReadDeadline(time.Time{})
+			d.SetReadDeadline(time.Time{})
 		}
 	}
+	r.readMutex.Unlock()
+}
+
+// Read is a wrapper function of bufio.Reader.Read().
+func (r *Stream) Read(p []byte) (n int, err error) {
+	if !r.lockReader() {
+		return 0, io.ErrClosedPipe
+	}
+	defer r.unlockReader()
+
+	return r.reader.Read(p)
+}
+
+// Peek is a wrapper function of bufio.Reader.Peek(). The returned bytes are valid until the
+// next read or Close.
+func (r *Stream) Peek(n int) (p []byte, err error) {
+	if !r.lockReader() {
+		return nil, io.ErrClosedPipe
+	}
+	defer r.unlockReader()
 
 	return r.reader.Peek(n)
 }
@@ -88,18 +117,20 @@ func (r *Stream) Peek(n int) (p []byte, err error) {
 // ReadN reads exactly n bytes from this socket. It returns non-nil error if len(p) < n,
 // and the data, whose length is len(p) bytes long, still remains in the socket buffer.
 func (r *Stream) ReadN(n int) (p []byte, err error) {
-	if r.readTimeout > 0 {
-		d, ok := r.channel.(Deadline)
-		if ok {
-			d.SetReadDeadline(time.Now().Add(r.readTimeout))
-			defer d.SetReadDeadline(time.Time{})
-		}
+	if !r.lockReader() {
+		return nil, io.ErrClosedPipe
 	}
+	defer r.unlockReader()
 
-	p = make([]byte, n)
+	return r.readN(n)
+}
+
+// XXX: Caller should lock the reader
+func (r *Stream) readN(n int) (p []byte, err error) {
 	if _, err = r.reader.Peek(n); err != nil {
 		return nil, err
 	}
+	p = messages.get(n)
 	c, err := r.reader.Read(p)
 	if c != n {
 		panic("insufficient read")
@@ -111,6 +142,26 @@ func (r *Stream) ReadN(n int) (p []byte, err error) {
 	return p, nil
 }
 
+// ReadMessage reads an OpenFlow message. The message remains in the socket buffer if an error
+// is returned.
+func (r *Stream) ReadMessage() (packet []byte, err error) {
+	if !r.lockReader() {
+		return nil, io.ErrClosedPipe
+	}
+	defer r.unlockReader()
+
+	header, err := r.reader.Peek(8) // peek ofp_header
+	if err != nil {
+		return nil, err
+	}
+	length := binary.BigEndian.Uint16(header[2:4])
+	if length < 8 {
+		return nil, openflow.ErrInvalidPacketLength
+	}
+
+	return r.readN(int(length))
+}
+
 // Write is a wrapper function of net.Conn.Write().
 func (r *Stream) Write(p []byte) (n int, err error) {
 	if r.writeTimeout > 0 {
@@ -124,7 +175,17 @@ func (r *Stream) Write(p []byte) (n int, err error) {
 	return r.channel.Write(p)
 }
 
-// Close is a wrapper function of net.Conn.Close().
+// Close is a wrapper function of net.Conn.Close(). It also returns the read buffer to the pool.
 func (r *Stream) Close() error {
-	return r.channel.Close()
+	// Closing the channel wakes up the reader blocked in the channel.
+	err := r.channel.Close()
+
+	r.readMutex.Lock()
+	defer r.readMutex.Unlock()
+	if r.reader != nil {
+		readers.put(r.reader)
+		r.reader = nil
+	}
+
+	return err
 }
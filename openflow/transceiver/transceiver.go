@@ -26,11 +26,18 @@ import (
 	"encoding"
 	"encoding/binary"
 	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/superkkt/cherry/clock"
+	"github.com/superkkt/cherry/metrics"
 	"github.com/superkkt/cherry/openflow"
 	"github.com/superkkt/cherry/openflow/of10"
 	"github.com/superkkt/cherry/openflow/of13"
+	"github.com/superkkt/cherry/openflow/of14"
+	"github.com/superkkt/cherry/ratelog"
 
 	"github.com/pkg/errors"
 	"github.com/superkkt/go-logging"
@@ -38,32 +45,103 @@ import (
 
 var (
 	logger = logging.MustGetLogger("transceiver")
+	// Logger for the messages that may be repeated on every packet.
+	rateLogger = ratelog.New("transceiver", 10*time.Second)
+)
+
+var (
+	messagesReceived = metrics.NewCounterVec("cherry_openflow_messages_received_total", "Number of OpenFlow messages received from switches.", "version", "type")
+	messagesSent     = metrics.NewCounterVec("cherry_openflow_messages_sent_total", "Number of OpenFlow messages sent to switches.", "version", "type")
+	flowModsSent     = metrics.NewCounterVec("cherry_flow_mods_sent_total", "Number of FLOW_MOD messages sent to switches.")
+	messagesDropped  = metrics.NewCounterVec("cherry_openflow_messages_dropped_total", "Number of OpenFlow messages dropped due to the full outbound queue.")
+	echoRTT          = metrics.NewHistogramVec("cherry_echo_rtt_seconds", "Round-trip time of the echo requests to switches.",
+		[]float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5})
+)
+
+var (
+	// Protocol versions that we support.
+	supportedVersions = []uint8{openflow.OF10_VERSION, openflow.OF13_VERSION, openflow.OF14_VERSION}
 )
 
 const (
 	// Allowed idle time before we send an echo request to a switch.
 	maxIdleTime = 10 * time.Second
+	// The connection is closed if a switch does not reply to this number of consecutive echo requests.
+	maxMissedEchoes = 3
 	// I/O timeouts (These timeouts should be less than maxIdleTime).
 	readTimeout  = 1 * time.Second
 	writeTimeout = readTimeout * 2
+	// Maximum time to wait for the reply of a request sent by SendAndWait.
+	maxReplyWait = 10 * time.Second
 )
 
 type Writer interface {
 	Write(msg encoding.BinaryMarshaler) error
 }
 
+// Request is a message that expects a reply having the same transaction ID.
+type Request interface {
+	openflow.Header
+	encoding.BinaryMarshaler
+}
+
 type WriteCloser interface {
 	Writer
 	Close() error
 }
 
+// Capturer records the raw messages exchanged with the device. sent is true if packet is sent
+// to the device. Capture is called by the reader and writer goroutines concurrently, and it
+// should not modify or retain packet.
+type Capturer interface {
+	Capture(sent bool, packet []byte)
+}
+
+// Auditor records the messages written to the device. err is the result of queueing msg. Audit
+// is called by the goroutines that write the messages, so it should be safe for concurrent use.
+type Auditor interface {
+	Audit(msg encoding.BinaryMarshaler, err error)
+}
+
 type Transceiver struct {
-	stream      *Stream
-	observer    Handler
-	version     uint8
-	factory     openflow.Factory
+	stream   *Stream
+	observer Handler
+	version  uint8
+	factory  openflow.Factory
+	// 1 after version and factory are set by the negotiation. Accessed atomically.
+	negotiated  int32
 	pingCounter uint
-	closed      bool
+	// Round-trip time of the last echo request in nanoseconds. Accessed atomically.
+	rtt int64
+	// 1 after Close is called. Accessed atomically because the session and its handshake
+	// watchdog may close the transceiver at the same time.
+	closed int32
+	clock  clock.Clock
+	// Reassembler for the OpenFlow 1.3 multipart replies.
+	multipart *of13.MultipartAssembler
+	// Requests waiting for their replies by SendAndWait.
+	pending *pendingTable
+	// Outbound messages waiting for the writer goroutine.
+	out *outQueue
+	// Last bundle ID used by SendBundle. Accessed atomically.
+	bundleID uint32
+	// done is closed when Run returns.
+	done chan struct{}
+	// Reader and writer goroutines started by Run.
+	workers sync.WaitGroup
+	// Capturer set by SetCapturer, which is stored as a capturerValue.
+	capturer atomic.Value
+	// Auditor set by SetAuditor, which is stored as an auditorValue.
+	auditor atomic.Value
+}
+
+// capturerValue wraps a Capturer because atomic.Value cannot store nil.
+type capturerValue struct {
+	Capturer
+}
+
+type auditorValue struct {
+	Auditor
 }
 
 type Handler interface {
@@ -73,24 +151,67 @@ type Handler interface {
 	OnGetConfigReply(openflow.Factory, Writer, openflow.GetConfigReply) error
 	OnDescReply(openflow.Factory, Writer, openflow.DescReply) error
 	OnPortDescReply(openflow.Factory, Writer, openflow.PortDescReply) error
+	OnFlowStatsReply(openflow.Factory, Writer, openflow.FlowStatsReply) error
+	OnPortStatsReply(openflow.Factory, Writer, openflow.PortStatsReply) error
 	OnPortStatus(openflow.Factory, Writer, openflow.PortStatus) error
 	OnFlowRemoved(openflow.Factory, Writer, openflow.FlowRemoved) error
 	OnPacketIn(openflow.Factory, Writer, openflow.PacketIn) error
 	OnBarrierReply(openflow.Factory, Writer, openflow.BarrierReply) error
+	OnRoleReply(openflow.Factory, Writer, openflow.RoleReply) error
+	OnExperimenter(openflow.Factory, Writer, openflow.Experimenter) error
+	OnTableFeaturesReply(openflow.Factory, Writer, openflow.TableFeaturesReply) error
+	OnQueueGetConfigReply(openflow.Factory, Writer, openflow.QueueGetConfigReply) error
+	OnQueueStatsReply(openflow.Factory, Writer, openflow.QueueStatsReply) error
 }
 
-func NewTransceiver(stream *Stream, handler Handler) *Transceiver {
+func NewTransceiver(stream *Stream, handler Handler, clk clock.Clock) *Transceiver {
 	if stream == nil {
 		panic("stream is nil")
 	}
 	if handler == nil {
 		panic("handler is nil")
 	}
+	if clk == nil {
+		panic("clock is nil")
+	}
 
 	return &Transceiver{
-		stream:   stream,
-		observer: handler,
+		stream:    stream,
+		observer:  handler,
+		clock:     clk,
+		multipart: of13.NewMultipartAssembler(),
+		pending:   newPendingTable(),
+		out:       newOutQueue(clk),
+		done:      make(chan struct{}),
+	}
+}
+
+// SetCapturer sets c that records the messages exchanged with the device from now on. nil c
+// stops the recording.
+func (r *Transceiver) SetCapturer(c Capturer) {
+	r.capturer.Store(capturerValue{c})
+}
+
+func (r *Transceiver) capture(sent bool, packet []byte) {
+	v, ok := r.capturer.Load().(capturerValue)
+	if !ok || v.Capturer == nil {
+		return
+	}
+	v.Capture(sent, packet)
+}
+
+// SetAuditor sets a that records the messages written to the device from now on. nil a
+// stops the recording.
+func (r *Transceiver) SetAuditor(a Auditor) {
+	r.auditor.Store(auditorValue{a})
+}
+
+func (r *Transceiver) audit(msg encoding.BinaryMarshaler, err error) {
+	v, ok := r.auditor.Load().(auditorValue)
+	if !ok || v.Auditor == nil {
+		return
 	}
+	v.Audit(msg, err)
 }
 
 func (r *Transceiver) Version() (negotiated bool, version uint8) {
@@ -102,6 +223,13 @@ func (r *Transceiver) Version() (negotiated bool, version uint8) {
 	return true, r.version
 }
 
+// RTT returns the round-trip time of the control channel measured by the last echo request.
+// It returns 0 if it has not been measured yet. Echo requests are only sent when the
+// connection is idle, so the value may be old on a busy connection.
+func (r *Transceiver) RTT() time.Duration {
+	return time.Duration(atomic.LoadInt64(&r.rtt))
+}
+
 func isTimeout(err error) bool {
 	type Timeout interface {
 		Timeout() bool
@@ -115,8 +243,8 @@ func isTimeout(err error) bool {
 }
 
 func (r *Transceiver) sendEchoRequest() error {
-	if r.pingCounter > 2 {
-		return errors.New("device does not respond to our echo request")
+	if r.pingCounter >= maxMissedEchoes {
+		return fmt.Errorf("device does not respond to our %v echo requests", r.pingCounter)
 	}
 
 	echo, err := r.factory.NewEchoRequest()
@@ -124,7 +252,7 @@ func (r *Transceiver) sendEchoRequest() error {
 		return err
 	}
 	// We use current timestamp to check network latency between our controller and a switch.
-	timestamp, err := time.Now().GobEncode()
+	timestamp, err := r.clock.Now().GobEncode()
 	if err != nil {
 		return err
 	}
@@ -138,14 +266,37 @@ func (r *Transceiver) sendEchoRequest() error {
 	return nil
 }
 
+// Run reads and dispatches the messages from the device until ctx is done or the connection is
+// closed. The messages flow through three goroutines connected by the bounded channels:
+//
+//   - The reader reads the messages from the connection, and handles the echo requests and
+//     replies by itself. It forwards the other messages to the dispatcher without blocking, and
+//     drops them if the dispatcher falls too far behind, so that a slow or blocked handler
+//     cannot delay the echo replies, which would make the device drop the connection.
+//   - The dispatcher, which is the goroutine calling Run, calls the handler for each message.
+//   - The writer writes the messages in the outbound queue to the connection.
+//
+// Run shuts them down in order before it returns: it first closes the outbound queue so that
+// no more message is accepted and the requests waiting for their replies wake up, and then
+// stops the reader and the writer, and waits for them so that none of them uses the stream
+// after Run returns. The reader stops within readTimeout, and the writer within writeTimeout.
+// The connection itself is closed by Close.
 func (r *Transceiver) Run(ctx context.Context) error {
-	defer logger.Info("transceiver is closed")
 	r.stream.SetReadTimeout(readTimeout)
 	r.stream.SetWriteTimeout(writeTimeout)
 
 	readerCtx, cancelReader := context.WithCancel(ctx)
-	defer cancelReader()
+	defer func() {
+		// Wake up the writer and the requests waiting for their replies.
+		close(r.done)
+		cancelReader()
+		r.workers.Wait()
+		logger.Info("transceiver is closed")
+	}()
 	reader := r.runReader(readerCtx)
+	// The writer goroutine stops when done is closed.
+	r.workers.Add(1)
+	go r.runWriter()
 
 	// Negotiate the protocol version
 	packet, err := r.negotiate(ctx, reader)
@@ -161,7 +312,7 @@ func (r *Transceiver) Run(ctx context.Context) error {
 				return err
 			}
 			// Ignore the temporary error. Just log the error and keep go on.
-			logger.Errorf("failed to dispatch the packet: %v", err)
+			rateLogger.Errorf("dispatch", "failed to dispatch the packet: %v", err)
 		}
 
 		// Read the next packet
@@ -184,10 +335,13 @@ func (r *Transceiver) Run(ctx context.Context) error {
 }
 
 func (r *Transceiver) negotiate(ctx context.Context, reader <-chan []byte) (packet []byte, err error) {
+	timer := r.clock.NewTimer(30 * time.Second)
+	defer timer.Stop()
+
 	select {
 	case <-ctx.Done():
 		return nil, errors.New("context done")
-	case <-time.After(30 * time.Second):
+	case <-timer.C():
 		return nil, errors.New("inactive for too long")
 	case packet, ok := <-reader:
 		if !ok {
@@ -197,17 +351,37 @@ func (r *Transceiver) negotiate(ctx context.Context, reader <-chan []byte) (pack
 		if packet[1] != 0x00 {
 			return nil, errors.New("missing HELLO message")
 		}
+		hello := new(openflow.BaseHello)
+		if err := hello.UnmarshalBinary(packet); err != nil {
+			return nil, errors.Wrap(err, "failed to decode HELLO message")
+		}
 
 		// Version negotiation
-		if packet[0] < openflow.OF13_VERSION {
+		version, ok := openflow.NegotiateVersion(hello, supportedVersions)
+		if !ok {
+			return nil, fmt.Errorf("no common protocol version: hello version=%v, bitmap=%v", hello.Version(), hello.Versions())
+		}
+		switch version {
+		case openflow.OF10_VERSION:
 			r.version = openflow.OF10_VERSION
 			r.factory = of10.NewFactory()
 			logger.Info("negotiated to openflow version 1.0")
-		} else {
+		case openflow.OF13_VERSION:
 			r.version = openflow.OF13_VERSION
 			r.factory = of13.NewFactory()
 			logger.Info("negotiated to openflow version 1.3")
+		case openflow.OF14_VERSION:
+			r.version = openflow.OF14_VERSION
+			r.factory = of14.NewFactory()
+			logger.Info("negotiated to openflow version 1.4")
+		default:
+			panic(fmt.Sprintf("unexpected negotiated version: %v", version))
 		}
+		// The peer's HELLO may have a different version from the negotiated one. Dispatch
+		// it as a HELLO of the negotiated version so that the handler uses the right one.
+		packet[0] = version
+
+		atomic.StoreInt32(&r.negotiated, 1)
 
 		// Return the initial packet to dispatch it.
 		return packet, nil
@@ -217,12 +391,14 @@ func (r *Transceiver) negotiate(ctx context.Context, reader <-chan []byte) (pack
 func (r *Transceiver) runReader(ctx context.Context) <-chan []byte {
 	// Buffered channel
 	c := make(chan []byte, 4096)
+	r.workers.Add(1)
 	go func() {
+		defer r.workers.Done()
 		// The channel c will be closed when this goroutine returns in order to notice the connection has been closed.
 		defer close(c)
 		defer logger.Info("transceiver reader is closed")
 
-		lastActivated := time.Now()
+		lastActivated := r.clock.Now()
 		for {
 			select {
 			case <-ctx.Done():
@@ -238,8 +414,9 @@ func (r *Transceiver) runReader(ctx context.Context) <-chan []byte {
 					logger.Errorf("failed to read the next packet: %v", err)
 					return
 				}
-				// Timeout occurrs. Send a ping request if necessary.
-				if time.Now().After(lastActivated.Add(maxIdleTime)) {
+				// Timeout occurrs. Send a ping request if necessary. The echo request needs the
+				// negotiated version, and the negotiation has its own timeout.
+				if atomic.LoadInt32(&r.negotiated) == 1 && r.clock.Now().After(lastActivated.Add(maxIdleTime)) {
 					if err := r.sendEchoRequest(); err != nil {
 						logger.Errorf("failed to send an echo request: %v", err)
 						return
@@ -248,7 +425,9 @@ func (r *Transceiver) runReader(ctx context.Context) <-chan []byte {
 				continue
 			}
 			// Update the timestamp
-			lastActivated = time.Now()
+			lastActivated = r.clock.Now()
+			r.capture(false, packet)
+			messagesReceived.Inc(strconv.Itoa(int(packet[0])), strconv.Itoa(int(packet[1])))
 
 			ok, err := r.handleEcho(packet)
 			if err != nil {
@@ -258,6 +437,7 @@ func (r *Transceiver) runReader(ctx context.Context) <-chan []byte {
 			if ok {
 				// Do not forward the echo request and response
 				// packets because this reader handles them.
+				messages.put(packet)
 				continue
 			}
 
@@ -266,7 +446,8 @@ func (r *Transceiver) runReader(ctx context.Context) <-chan []byte {
 			case c <- packet:
 			default:
 				// Drop the packet if we cannot immediately carry it.
-				logger.Warning("transceiver buffer full: drop the incoming packet!")
+				rateLogger.Warningf("buffer full", "transceiver buffer full: drop the incoming packet!")
+				messages.put(packet)
 			}
 		}
 	}()
@@ -282,33 +463,168 @@ func isTemporaryErr(err error) bool {
 }
 
 func (r *Transceiver) readPacket() ([]byte, error) {
-	header, err := r.stream.Peek(8) // peek ofp_header
-	if err != nil {
-		return nil, err
+	return r.stream.ReadMessage()
+}
+
+// Write enqueues msg into the outbound queue, and the writer goroutine sends it to the device
+// later. So, a nil error does not mean that msg has been sent. Write returns ErrQueueFull if
+// msg is a low priority one, such as PACKET_OUT, and the queue is full, or if the queue stays
+// full for writeTimeout. See outQueue for the priorities.
+//
+// The encoded message is returned to the buffer pool after it is written, so MarshalBinary of
+// msg should return a new byte slice that is not referenced elsewhere.
+func (r *Transceiver) Write(msg encoding.BinaryMarshaler) error {
+	return r.WriteContext(context.Background(), msg)
+}
+
+// WriteContext is same as Write except that it gives up waiting for a room in the full queue
+// when ctx is done, and waits until the deadline of ctx, if any, instead of writeTimeout. It
+// returns ctx.Err() in that case. Once msg is queued, it is sent regardless of ctx.
+func (r *Transceiver) WriteContext(ctx context.Context, msg encoding.BinaryMarshaler) error {
+	packet, err := msg.MarshalBinary()
+	if err == nil {
+		err = r.out.push(ctx, packet, writeTimeout, r.done)
 	}
+	r.audit(msg, err)
 
-	length := binary.BigEndian.Uint16(header[2:4])
-	if length < 8 {
-		return nil, openflow.ErrInvalidPacketLength
+	return err
+}
+
+func (r *Transceiver) runWriter() {
+	defer r.workers.Done()
+	defer logger.Debug("writer goroutine is finished")
+
+	for {
+		packet, ok := r.out.pop(r.done)
+		if !ok {
+			return
+		}
+		if _, err := r.stream.Write(packet); err != nil {
+			logger.Errorf("failed to write a packet: %v", err)
+			// Close the connection so that the reader and Run also stop.
+			r.stream.Close()
+			return
+		}
+		r.capture(true, packet)
+		countSent(packet)
+		// Recycle the buffer for the incoming messages.
+		messages.put(packet)
 	}
-	packet, err := r.stream.ReadN(int(length))
+}
+
+func countSent(packet []byte) {
+	if len(packet) < 8 {
+		return
+	}
+	messagesSent.Inc(strconv.Itoa(int(packet[0])), strconv.Itoa(int(packet[1])))
+
+	switch {
+	case packet[0] == openflow.OF10_VERSION && packet[1] == of10.OFPT_FLOW_MOD:
+		flowModsSent.Inc()
+	case (packet[0] == openflow.OF13_VERSION || packet[0] == openflow.OF14_VERSION) && packet[1] == of13.OFPT_FLOW_MOD:
+		flowModsSent.Inc()
+	}
+}
+
+// SendAndWait sends req and waits until the reply that has the same transaction ID
+// arrives, ctx is done, or maxReplyWait elapses if ctx has no deadline. The reply is also delivered to the
+// handler as usual. The Go error value of the error message, which is a *openflow.DeviceError,
+// is returned if the device replies an error message.
+// Multipart replies are returned after all the parts have been reassembled.
+//
+// SendAndWait should not be called by the handler functions because they are executed
+// by the goroutine that dispatches the replies.
+func (r *Transceiver) SendAndWait(ctx context.Context, req Request) (reply openflow.Header, err error) {
+	c, err := r.pending.add(req.TransactionID())
 	if err != nil {
 		return nil, err
 	}
+	defer r.pending.remove(req.TransactionID())
+
+	if err := r.WriteContext(ctx, req); err != nil {
+		return nil, err
+	}
+
+	// The deadline of ctx, if any, overrides maxReplyWait.
+	var expired <-chan time.Time
+	if _, ok := ctx.Deadline(); !ok {
+		timer := r.clock.NewTimer(maxReplyWait)
+		defer timer.Stop()
+		expired = timer.C()
+	}
 
-	return packet, nil
+	select {
+	case reply = <-c:
+		if e, ok := reply.(openflow.Error); ok {
+			return reply, e.Err()
+		}
+		return reply, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-expired:
+		return nil, fmt.Errorf("timeout waiting for the reply: xid=%v", req.TransactionID())
+	case <-r.done:
+		return nil, errClosed
+	}
 }
 
-func (r *Transceiver) Write(msg encoding.BinaryMarshaler) error {
-	packet, err := msg.MarshalBinary()
+// SendAndConfirm sends req, which has no reply on success such as FLOW_MOD, followed by a
+// barrier request, and waits until the device finishes processing req. The Go error value of
+// the error message, which is a *openflow.DeviceError, is returned if the device rejects req.
+//
+// SendAndConfirm should not be called by the handler functions for the same reason as SendAndWait.
+func (r *Transceiver) SendAndConfirm(ctx context.Context, req Request) error {
+	return r.SendBatch(ctx, []Request{req})
+}
+
+// SendBatch is same as SendAndConfirm except that it sends all the requests in reqs followed
+// by a single barrier request. The error of the first rejected request is returned. Note that
+// the requests accepted by the device are not rolled back even if others are rejected.
+//
+// SendBatch should not be called by the handler functions for the same reason as SendAndWait.
+func (r *Transceiver) SendBatch(ctx context.Context, reqs []Request) error {
+	barrier, err := r.factory.NewBarrierRequest()
 	if err != nil {
 		return err
 	}
 
-	if _, err := r.stream.Write(packet); err != nil {
+	replies := make([]<-chan openflow.Header, len(reqs))
+	for i, req := range reqs {
+		c, err := r.pending.add(req.TransactionID())
+		if err != nil {
+			return err
+		}
+		defer r.pending.remove(req.TransactionID())
+		replies[i] = c
+	}
+
+	for _, req := range reqs {
+		if err := r.WriteContext(ctx, req); err != nil {
+			return err
+		}
+	}
+	// The device should process the messages in order, so any error caused by reqs arrives
+	// before the barrier reply.
+	if _, err := r.SendAndWait(ctx, barrier); err != nil {
 		return err
 	}
 
+	return firstError(replies)
+}
+
+// firstError returns the Go error value of the first error message in replies without
+// blocking. It returns nil if there is no error message.
+func firstError(replies []<-chan openflow.Header) error {
+	for _, c := range replies {
+		select {
+		case reply := <-c:
+			if e, ok := reply.(openflow.Error); ok {
+				return e.Err()
+			}
+		default:
+		}
+	}
+
 	return nil
 }
 
@@ -316,7 +632,8 @@ func (r *Transceiver) handleEcho(packet []byte) (ok bool, err error) {
 	switch packet[0] {
 	case openflow.OF10_VERSION:
 		return r.handleOF10Echo(packet)
-	case openflow.OF13_VERSION:
+	// OpenFlow 1.4 has the same echo messages as OpenFlow 1.3.
+	case openflow.OF13_VERSION, openflow.OF14_VERSION:
 		return r.handleOF13Echo(packet)
 	default:
 		return false, openflow.ErrUnsupportedVersion
@@ -357,6 +674,8 @@ func (r *Transceiver) dispatch(packet []byte) error {
 		return r.handleOF10Message(packet)
 	case openflow.OF13_VERSION:
 		return r.handleOF13Message(packet)
+	case openflow.OF14_VERSION:
+		return r.handleOF14Message(packet)
 	default:
 		return openflow.ErrUnsupportedVersion
 	}
@@ -376,6 +695,12 @@ func (r *Transceiver) handleOF10Message(packet []byte) error {
 		switch binary.BigEndian.Uint16(packet[8:10]) {
 		case of10.OFPST_DESC:
 			return r.handleDescReply(packet)
+		case of10.OFPST_FLOW:
+			return r.handleFlowStatsReply(packet)
+		case of10.OFPST_PORT:
+			return r.handlePortStatsReply(packet)
+		case of10.OFPST_QUEUE:
+			return r.handleQueueStatsReply(packet)
 		default:
 			// Unsupported message. Do nothing.
 			return nil
@@ -388,6 +713,10 @@ func (r *Transceiver) handleOF10Message(packet []byte) error {
 		return r.handlePacketIn(packet)
 	case of10.OFPT_BARRIER_REPLY:
 		return r.handleBarrierReply(packet)
+	case of10.OFPT_QUEUE_GET_CONFIG_REPLY:
+		return r.handleQueueGetConfigReply(packet)
+	case of10.OFPT_VENDOR:
+		return r.handleExperimenter(packet)
 	default:
 		// Unsupported message. Do nothing.
 		return nil
@@ -405,11 +734,27 @@ func (r *Transceiver) handleOF13Message(packet []byte) error {
 	case of13.OFPT_GET_CONFIG_REPLY:
 		return r.handleGetConfigReply(packet)
 	case of13.OFPT_MULTIPART_REPLY:
+		packet, err := r.multipart.Add(packet)
+		if err != nil {
+			return err
+		}
+		if packet == nil {
+			// Wait for the remaining parts of this reply.
+			return nil
+		}
 		switch binary.BigEndian.Uint16(packet[8:10]) {
 		case of13.OFPMP_DESC:
 			return r.handleDescReply(packet)
 		case of13.OFPMP_PORT_DESC:
 			return r.handlePortDescReply(packet)
+		case of13.OFPMP_FLOW:
+			return r.handleFlowStatsReply(packet)
+		case of13.OFPMP_PORT_STATS:
+			return r.handlePortStatsReply(packet)
+		case of13.OFPMP_TABLE_FEATURES:
+			return r.handleTableFeaturesReply(packet)
+		case of13.OFPMP_QUEUE:
+			return r.handleQueueStatsReply(packet)
 		default:
 			// Unsupported message. Do nothing.
 			return nil
@@ -422,12 +767,29 @@ func (r *Transceiver) handleOF13Message(packet []byte) error {
 		return r.handlePacketIn(packet)
 	case of13.OFPT_BARRIER_REPLY:
 		return r.handleBarrierReply(packet)
+	case of13.OFPT_ROLE_REPLY:
+		return r.handleRoleReply(packet)
+	case of13.OFPT_QUEUE_GET_CONFIG_REPLY:
+		return r.handleQueueGetConfigReply(packet)
+	case of13.OFPT_EXPERIMENTER:
+		return r.handleExperimenter(packet)
 	default:
 		// Unsupported message. Do nothing.
 		return nil
 	}
 }
 
+func (r *Transceiver) handleOF14Message(packet []byte) error {
+	switch packet[1] {
+	case of14.OFPT_BUNDLE_CONTROL:
+		return r.handleBundleControl(packet)
+	default:
+		// The messages that OpenFlow 1.4 shares with OpenFlow 1.3 have the same types and
+		// the same formats except the ones decoded by the OF1.4 factory, e.g., PORT_STATUS.
+		return r.handleOF13Message(packet)
+	}
+}
+
 func (r *Transceiver) handleEchoRequest(packet []byte) error {
 	msg, err := r.factory.NewEchoRequest()
 	if err != nil {
@@ -460,11 +822,20 @@ func (r *Transceiver) handleEchoReply(packet []byte) error {
 	if err != nil {
 		return err
 	}
-	if err := msg.UnmarshalBinary(packet); err != nil {
+	// The message does not copy its data, and the reader recycles packet as soon as we return.
+	// Decode a copy so that the reply passed to SendAndWait stays valid.
+	if err := msg.UnmarshalBinary(append([]byte(nil), packet...)); err != nil {
 		return err
 	}
 	logger.Debug("received an ECHO_REPLY packet")
 
+	// Reset the ping counter
+	r.pingCounter = 0
+	// The reply of an echo request sent by SendAndWait has no timestamp.
+	if r.pending.complete(msg) {
+		return nil
+	}
+
 	data := msg.Data()
 	if data == nil || len(data) != 8 {
 		// Some broken switch sends an unexpected echo reply data.
@@ -475,13 +846,13 @@ func (r *Transceiver) handleEchoReply(packet []byte) error {
 			logger.Debug("unexpected timestamp data in the ECHO_REPLY packet")
 		} else {
 			// Network latency
-			logger.Debugf("transceiver latency: %v", time.Now().Sub(timestamp))
+			rtt := r.clock.Since(timestamp)
+			atomic.StoreInt64(&r.rtt, int64(rtt))
+			echoRTT.Observe(rtt.Seconds())
+			logger.Debugf("transceiver latency: %v", rtt)
 		}
 	}
 
-	// Reset the ping counter
-	r.pingCounter = 0
-
 	return nil
 }
 
@@ -506,6 +877,8 @@ func (r *Transceiver) handleError(packet []byte) error {
 		return err
 	}
 
+	r.pending.complete(msg)
+
 	return r.observer.OnError(r.factory, r, msg)
 }
 
@@ -518,6 +891,8 @@ func (r *Transceiver) handleFeaturesReply(packet []byte) error {
 		return err
 	}
 
+	r.pending.complete(msg)
+
 	return r.observer.OnFeaturesReply(r.factory, r, msg)
 }
 
@@ -530,6 +905,8 @@ func (r *Transceiver) handleGetConfigReply(packet []byte) error {
 		return err
 	}
 
+	r.pending.complete(msg)
+
 	return r.observer.OnGetConfigReply(r.factory, r, msg)
 }
 
@@ -542,6 +919,8 @@ func (r *Transceiver) handleDescReply(packet []byte) error {
 		return err
 	}
 
+	r.pending.complete(msg)
+
 	return r.observer.OnDescReply(r.factory, r, msg)
 }
 
@@ -554,9 +933,81 @@ func (r *Transceiver) handlePortDescReply(packet []byte) error {
 		return err
 	}
 
+	r.pending.complete(msg)
+
 	return r.observer.OnPortDescReply(r.factory, r, msg)
 }
 
+func (r *Transceiver) handleFlowStatsReply(packet []byte) error {
+	msg, err := r.factory.NewFlowStatsReply()
+	if err != nil {
+		return err
+	}
+	if err := msg.UnmarshalBinary(packet); err != nil {
+		return err
+	}
+
+	r.pending.complete(msg)
+
+	return r.observer.OnFlowStatsReply(r.factory, r, msg)
+}
+
+func (r *Transceiver) handlePortStatsReply(packet []byte) error {
+	msg, err := r.factory.NewPortStatsReply()
+	if err != nil {
+		return err
+	}
+	if err := msg.UnmarshalBinary(packet); err != nil {
+		return err
+	}
+
+	r.pending.complete(msg)
+
+	return r.observer.OnPortStatsReply(r.factory, r, msg)
+}
+
+func (r *Transceiver) handleTableFeaturesReply(packet []byte) error {
+	msg, err := r.factory.NewTableFeaturesReply()
+	if err != nil {
+		return err
+	}
+	if err := msg.UnmarshalBinary(packet); err != nil {
+		return err
+	}
+
+	r.pending.complete(msg)
+
+	return r.observer.OnTableFeaturesReply(r.factory, r, msg)
+}
+
+func (r *Transceiver) handleQueueGetConfigReply(packet []byte) error {
+	msg, err := r.factory.NewQueueGetConfigReply()
+	if err != nil {
+		return err
+	}
+	if err := msg.UnmarshalBinary(packet); err != nil {
+		return err
+	}
+
+	r.pending.complete(msg)
+
+	return r.observer.OnQueueGetConfigReply(r.factory, r, msg)
+}
+
+func (r *Transceiver) handleQueueStatsReply(packet []byte) error {
+	msg, err := r.factory.NewQueueStatsReply()
+	if err != nil {
+		return err
+	}
+	if err := msg.UnmarshalBinary(packet); err != nil {
+		return err
+	}
+
+	r.pending.complete(msg)
+
+	return r.observer.OnQueueStatsReply(r.factory, r, msg)
+}
+
 func (r *Transceiver) handlePortStatus(packet []byte) error {
 	msg, err := r.factory.NewPortStatus()
 	if err != nil {
@@ -602,18 +1053,44 @@ func (r *Transceiver) handleBarrierReply(packet []byte) error {
 		return err
 	}
 
+	r.pending.complete(msg)
+
 	return r.observer.OnBarrierReply(r.factory, r, msg)
 }
 
-func (r *Transceiver) Close() error {
-	if r.closed {
-		return nil
+func (r *Transceiver) handleRoleReply(packet []byte) error {
+	msg, err := r.factory.NewRoleReply()
+	if err != nil {
+		return err
 	}
+	if err := msg.UnmarshalBinary(packet); err != nil {
+		return err
+	}
+
+	r.pending.complete(msg)
 
-	if err := r.stream.Close(); err != nil {
+	return r.observer.OnRoleReply(r.factory, r, msg)
+}
+
+func (r *Transceiver) handleExperimenter(packet []byte) error {
+	msg, err := r.factory.NewExperimenter()
+	if err != nil {
+		return err
+	}
+	if err := msg.UnmarshalBinary(packet); err != nil {
 		return err
 	}
-	r.closed = true
 
-	return nil
+	r.pending.complete(msg)
+
+	return r.observer.OnExperimenter(r.factory, r, msg)
+}
+
+// Close closes the stream. It is safe to call Close more than once, even concurrently.
+func (r *Transceiver) Close() error {
+	if !atomic.CompareAndSwapInt32(&r.closed, 0, 1) {
+		return nil
+	}
+
+	return r.stream.Close()
 }
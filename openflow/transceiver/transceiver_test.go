@@ -0,0 +1,195 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package transceiver
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/superkkt/cherry/clock"
+	"github.com/superkkt/cherry/openflow"
+	"github.com/superkkt/cherry/openflow/of13"
+)
+
+// blockingHandler blocks in OnPacketIn until release is closed. The other handler functions
+// than OnHello are not expected to be called.
+type blockingHandler struct {
+	Handler
+	blocked chan struct{}
+	release chan struct{}
+}
+
+func (r *blockingHandler) OnHello(openflow.Factory, Writer, openflow.Hello) error {
+	return nil
+}
+
+func (r *blockingHandler) OnPacketIn(openflow.Factory, Writer, openflow.PacketIn) error {
+	close(r.blocked)
+	<-r.release
+	return nil
+}
+
+// helloHandler reports the factory and the HELLO message given to OnHello.
+type helloHandler struct {
+	Handler
+	hello chan openflow.Factory
+}
+
+func (r *helloHandler) OnHello(f openflow.Factory, w Writer, v openflow.Hello) error {
+	r.hello <- f
+	return nil
+}
+
+// readType reads the messages from conn until it finds the one whose type is msgType.
+func readType(t *testing.T, stream *Stream, msgType uint8) []byte {
+	for {
+		packet, err := stream.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if packet[1] == msgType {
+			return packet
+		}
+	}
+}
+
+func writePacket(t *testing.T, conn net.Conn, packet []byte) {
+	conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Write(packet); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunBlockedHandler(t *testing.T) {
+	local, remote := net.Pipe()
+	defer remote.Close()
+	handler := &blockingHandler{blocked: make(chan struct{}), release: make(chan struct{})}
+	trans := NewTransceiver(NewStream(local), handler, clock.Real)
+	defer trans.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- trans.Run(ctx) }()
+
+	device := NewStream(remote)
+	defer device.Close()
+	writePacket(t, remote, []byte{openflow.OF13_VERSION, of13.OFPT_HELLO, 0, 8, 0, 0, 0, 1})
+	packetIn := []byte{
+		openflow.OF13_VERSION, of13.OFPT_PACKET_IN, 0, 34, 0, 0, 0, 2,
+		0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, // buffer_id, total_len, reason, table_id, cookie
+		0, 1, 0, 4, 0, 0, 0, 0, // empty match with padding
+		0, 0, // pad
+	}
+	writePacket(t, remote, packetIn)
+	select {
+	case <-handler.blocked:
+	case <-time.After(5 * time.Second):
+		t.Fatal("PACKET_IN is not dispatched")
+	}
+
+	// The echo request is replied even if the handler is blocked.
+	writePacket(t, remote, []byte{openflow.OF13_VERSION, of13.OFPT_ECHO_REQUEST, 0, 12, 0, 0, 0, 3, 1, 2, 3, 4})
+	reply := readType(t, device, of13.OFPT_ECHO_REPLY)
+	if len(reply) != 12 || reply[7] != 3 {
+		t.Fatalf("unexpected echo reply: %v", reply)
+	}
+
+	cancel()
+	close(handler.release)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return")
+	}
+
+	// The reader has stopped before Run returned, so no one reads this message.
+	remote.SetWriteDeadline(time.Now().Add(100 * time.Millisecond))
+	if _, err := remote.Write([]byte{openflow.OF13_VERSION, of13.OFPT_BARRIER_REPLY, 0, 8, 0, 0, 0, 4}); err == nil {
+		t.Fatal("the reader is still running after Run returned")
+	}
+	if err := trans.Write(of13.NewBarrierRequest(5)); err != errClosed {
+		t.Fatalf("expected errClosed, got %v", err)
+	}
+}
+
+func TestNegotiateOF14(t *testing.T) {
+	local, remote := net.Pipe()
+	defer remote.Close()
+	handler := &helloHandler{hello: make(chan openflow.Factory, 1)}
+	trans := NewTransceiver(NewStream(local), handler, clock.Real)
+	defer trans.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go trans.Run(ctx)
+
+	device := NewStream(remote)
+	defer device.Close()
+	// HELLO that advertises OpenFlow 1.0, 1.3 and 1.4 in its version bitmap.
+	writePacket(t, remote, []byte{
+		openflow.OF14_VERSION, of13.OFPT_HELLO, 0, 16, 0, 0, 0, 1,
+		0, 1, 0, 8, 0, 0, 0, 0x32,
+	})
+	select {
+	case f := <-handler.hello:
+		if v := f.ProtocolVersion(); v != openflow.OF14_VERSION {
+			t.Fatalf("unexpected negotiated version: %v", v)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("HELLO is not dispatched")
+	}
+	if negotiated, version := trans.Version(); !negotiated || version != openflow.OF14_VERSION {
+		t.Fatalf("unexpected version: negotiated=%v, version=%v", negotiated, version)
+	}
+
+	// The messages shared with OpenFlow 1.3 are handled as well.
+	writePacket(t, remote, []byte{openflow.OF14_VERSION, of13.OFPT_ECHO_REQUEST, 0, 12, 0, 0, 0, 2, 1, 2, 3, 4})
+	reply := readType(t, device, of13.OFPT_ECHO_REPLY)
+	if len(reply) != 12 || reply[0] != openflow.OF14_VERSION || reply[7] != 2 {
+		t.Fatalf("unexpected echo reply: %v", reply)
+	}
+}
+
+func TestCloseConcurrently(t *testing.T) {
+	local, remote := net.Pipe()
+	defer remote.Close()
+	trans := NewTransceiver(NewStream(local), &helloHandler{}, clock.Real)
+
+	// The session and its handshake watchdog may close the transceiver at the same time.
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := trans.Close(); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,98 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package openflow_test
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/superkkt/cherry/openflow"
+	"github.com/superkkt/cherry/openflow/of10"
+	"github.com/superkkt/cherry/openflow/of13"
+)
+
+func TestTunnelID(t *testing.T) {
+	f := of13.NewFactory()
+	for _, v := range []struct {
+		mask     uint64
+		expected string
+	}{
+		{0xFFFFFFFFFFFFFFFF, "00010010" + "80004c08" + "0000000000001234"},
+		{0xFFFF00, "00010018" + "80004d10" + "0000000000001200" + "0000000000ffff00"},
+	} {
+		match, err := openflow.NewMatchBuilder(f).TunnelID(0x1234, v.mask).Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := match.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hex.EncodeToString(data) != v.expected {
+			t.Fatalf("unexpected match: expected=%v, got=%x", v.expected, data)
+		}
+		decoded, err := f.NewMatch()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := decoded.UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+		if wildcard, id, mask := decoded.TunnelID(); wildcard || id != 0x1234&v.mask || mask != v.mask {
+			t.Fatalf("unexpected tunnel ID: %#x/%#x", id, mask)
+		}
+	}
+
+	action, err := openflow.NewActionBuilder(f).SetTunnelID(100).Output(1).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := action.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "00190010" + "80004c08" + "0000000000000064" + "0000001000000001ffff000000000000"
+	if v := hex.EncodeToString(data); v != expected {
+		t.Fatalf("unexpected action:\nexpected=%v\ngot=     %v", expected, v)
+	}
+	decoded, err := f.NewAction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if ok, id := decoded.TunnelID(); !ok || id != 100 {
+		t.Fatalf("unexpected tunnel ID: %v", id)
+	}
+
+	// OpenFlow 1.0 needs the Nicira extensions.
+	if _, err := openflow.NewMatchBuilder(of10.NewFactory()).TunnelID(100, 0xFFFFFFFFFFFFFFFF).Build(); err == nil {
+		t.Fatal("expected an error for the of10 match")
+	}
+	if _, err := openflow.NewActionBuilder(of10.NewFactory()).SetTunnelID(100).Output(1).Build(); err == nil {
+		t.Fatal("expected an error for the of10 action")
+	}
+	if _, err := openflow.NewMatchBuilder(f).TunnelID(100, 0).Build(); err == nil {
+		t.Fatal("expected an error for the zero mask")
+	}
+}
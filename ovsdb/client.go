@@ -0,0 +1,277 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+// Package ovsdb implements a client of the Open vSwitch database management protocol (RFC 7047),
+// which is JSON-RPC over a TCP or Unix domain socket, to configure the bridges, controllers and
+// tunnel ports of Open vSwitch without ovs-vsctl.
+package ovsdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/superkkt/go-logging"
+)
+
+var (
+	logger = logging.MustGetLogger("ovsdb")
+)
+
+var (
+	ErrClosedClient = errors.New("already closed OVSDB client")
+)
+
+// RPCError is the error replied by the server for a JSON-RPC request.
+type RPCError struct {
+	Method string
+	Err    interface{}
+}
+
+func (r *RPCError) Error() string {
+	return fmt.Sprintf("OVSDB %v failed: %v", r.Method, r.Err)
+}
+
+// message is a JSON-RPC 1.0 request, response or notification.
+type message struct {
+	Method string          `json:"method,omitempty"`
+	Params json.RawMessage `json:"params,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  interface{}     `json:"error,omitempty"`
+	ID     interface{}     `json:"id"`
+}
+
+type Client struct {
+	conn net.Conn
+
+	mutex   sync.Mutex
+	encoder *json.Encoder
+	nextID  uint64
+	// Key is the request ID.
+	pending map[uint64]chan *message
+	closed  bool
+	// Closed when the receiver terminates.
+	done chan struct{}
+}
+
+// Dial connects to the OVSDB server at addr. network is "tcp" or "unix", e.g., Dial(ctx, "tcp", "10.0.0.1:6640")
+// or Dial(ctx, "unix", "/var/run/openvswitch/db.sock").
+func Dial(ctx context.Context, network, addr string) (*Client, error) {
+	if network != "tcp" && network != "unix" {
+		return nil, fmt.Errorf("unsupported network: %v", network)
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewClient(conn), nil
+}
+
+// NewClient returns a client that communicates with the OVSDB server through conn.
+func NewClient(conn net.Conn) *Client {
+	if conn == nil {
+		panic("nil connection")
+	}
+
+	v := &Client{
+		conn:    conn,
+		encoder: json.NewEncoder(conn),
+		pending: make(map[uint64]chan *message),
+		done:    make(chan struct{}),
+	}
+	go v.receive()
+
+	return v
+}
+
+func (r *Client) Close() error {
+	// NOTE: This is an anonymous function (NOT a goroutine!) that has a critical section.
+	func() {
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+
+		r.closed = true
+	}()
+	err := r.conn.Close()
+	<-r.done
+
+	return err
+}
+
+func (r *Client) receive() {
+	defer close(r.done)
+	defer r.abort()
+
+	decoder := json.NewDecoder(r.conn)
+	// Infinite loop.
+	for {
+		msg := new(message)
+		if err := decoder.Decode(msg); err != nil {
+			logger.Debugf("terminating the OVSDB receiver: %v", err)
+			return
+		}
+
+		switch {
+		case msg.Method == "echo":
+			// Keepalive request from the server.
+			if err := r.send(&message{Result: msg.Params, ID: msg.ID}); err != nil {
+				logger.Errorf("failed to reply an OVSDB echo: %v", err)
+				return
+			}
+		case len(msg.Method) > 0:
+			// Notifications such as update and locked are not used.
+			logger.Debugf("ignoring an OVSDB %v message", msg.Method)
+		default:
+			r.complete(msg)
+		}
+	}
+}
+
+func (r *Client) complete(msg *message) {
+	id, ok := msg.ID.(float64)
+	if !ok {
+		logger.Errorf("invalid OVSDB response ID: %v", msg.ID)
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	c, ok := r.pending[uint64(id)]
+	if !ok {
+		logger.Debugf("ignoring an OVSDB response for an unknown request: id=%v", id)
+		return
+	}
+	delete(r.pending, uint64(id))
+	c <- msg
+}
+
+// abort wakes up the requests waiting for their responses after the connection is closed.
+func (r *Client) abort() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.closed = true
+	for id, c := range r.pending {
+		close(c)
+		delete(r.pending, id)
+	}
+}
+
+func (r *Client) send(msg *message) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.encoder.Encode(msg)
+}
+
+// call sends a request of method, and then decodes its result into result.
+func (r *Client) call(ctx context.Context, method string, params []interface{}, result interface{}) error {
+	p, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	var id uint64
+	c := make(chan *message, 1)
+	// NOTE: This is an anonymous function (NOT a goroutine!) that has a critical section.
+	err = func() error {
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+
+		if r.closed {
+			return ErrClosedClient
+		}
+		r.nextID++
+		id = r.nextID
+		r.pending[id] = c
+
+		return r.encoder.Encode(&message{Method: method, Params: p, ID: id})
+	}()
+	if err != nil {
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+		// NOTE: This is an anonymous function (NOT a goroutine!) that has a critical section.
+		func() {
+			r.mutex.Lock()
+			defer r.mutex.Unlock()
+
+			delete(r.pending, id)
+		}()
+		return ctx.Err()
+	case resp, ok := <-c:
+		if !ok {
+			return ErrClosedClient
+		}
+		if resp.Error != nil {
+			return &RPCError{Method: method, Err: resp.Error}
+		}
+		if result == nil {
+			return nil
+		}
+		return json.Unmarshal(resp.Result, result)
+	}
+}
+
+// ListDBs returns the names of the databases served by the server.
+func (r *Client) ListDBs(ctx context.Context) ([]string, error) {
+	var dbs []string
+	if err := r.call(ctx, "list_dbs", []interface{}{}, &dbs); err != nil {
+		return nil, err
+	}
+
+	return dbs, nil
+}
+
+// Transact executes ops atomically on the database whose name is db. An error is returned if any
+// operation fails, and then nothing is committed.
+func (r *Client) Transact(ctx context.Context, db string, ops ...Operation) ([]OperationResult, error) {
+	params := []interface{}{db}
+	for _, v := range ops {
+		params = append(params, v)
+	}
+
+	var results []OperationResult
+	if err := r.call(ctx, "transact", params, &results); err != nil {
+		return nil, err
+	}
+	for i, v := range results {
+		if len(v.Error) == 0 {
+			continue
+		}
+		// There is an additional result if the commit has failed after all operations succeeded.
+		if i >= len(ops) {
+			return nil, fmt.Errorf("OVSDB commit failed: %v (%v)", v.Error, v.Details)
+		}
+		return nil, fmt.Errorf("OVSDB %v operation on %v failed: %v (%v)", ops[i]["op"], ops[i]["table"], v.Error, v.Details)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,192 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package ovsdb
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeServer replies the requests received from conn by reply, and returns the requests through requests.
+func fakeServer(t *testing.T, conn net.Conn, reply func(req map[string]interface{}) interface{}) <-chan map[string]interface{} {
+	requests := make(chan map[string]interface{}, 16)
+	go func() {
+		decoder := json.NewDecoder(conn)
+		encoder := json.NewEncoder(conn)
+		for {
+			req := make(map[string]interface{})
+			if err := decoder.Decode(&req); err != nil {
+				return
+			}
+			requests <- req
+			if _, ok := req["method"]; !ok {
+				// Response for our echo.
+				continue
+			}
+			if err := encoder.Encode(map[string]interface{}{"result": reply(req), "error": nil, "id": req["id"]}); err != nil {
+				return
+			}
+		}
+	}()
+
+	return requests
+}
+
+func TestAddBridge(t *testing.T) {
+	client, server := net.Pipe()
+	requests := fakeServer(t, server, func(req map[string]interface{}) interface{} {
+		return []interface{}{map[string]interface{}{}, map[string]interface{}{"uuid": []interface{}{"uuid", "1"}}}
+	})
+	c := NewClient(client)
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.AddBridge(ctx, "br0"); err != nil {
+		t.Fatal(err)
+	}
+
+	req := <-requests
+	params := req["params"].([]interface{})
+	if req["method"] != "transact" || params[0] != "Open_vSwitch" || len(params) != 6 {
+		t.Fatalf("unexpected request: %v", req)
+	}
+	tables := make([]string, 0)
+	for _, v := range params[1:] {
+		op := v.(map[string]interface{})
+		tables = append(tables, op["op"].(string)+":"+op["table"].(string))
+	}
+	expected := "wait:Bridge insert:Interface insert:Port insert:Bridge mutate:Open_vSwitch"
+	if strings.Join(tables, " ") != expected {
+		t.Fatalf("unexpected operations: expected=%v, got=%v", expected, tables)
+	}
+}
+
+func TestBootstrap(t *testing.T) {
+	client, server := net.Pipe()
+	requests := fakeServer(t, server, func(req map[string]interface{}) interface{} {
+		params := req["params"].([]interface{})
+		// Select of the bridge that does not exist.
+		if len(params) == 2 {
+			return []interface{}{map[string]interface{}{"rows": []interface{}{}}}
+		}
+		results := make([]interface{}, 0)
+		for range params[1:] {
+			results = append(results, map[string]interface{}{"count": 1})
+		}
+		return results
+	})
+	c := NewClient(client)
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	tunnels := []Tunnel{
+		{Name: "vxlan0", Type: "vxlan", RemoteIP: net.ParseIP("10.0.0.2")},
+		{Name: "vxlan1", Type: "vxlan", RemoteIP: net.ParseIP("10.0.0.3")},
+	}
+	if err := c.Bootstrap(ctx, "br0", []string{"tcp:10.0.0.1:6633"}, "secure", tunnels); err != nil {
+		t.Fatal(err)
+	}
+
+	<-requests
+	// The bridge, its controller and the tunnels are made by a single transaction.
+	req := <-requests
+	params := req["params"].([]interface{})
+	ops := make([]string, 0)
+	names := make(map[string]bool)
+	for _, v := range params[1:] {
+		op := v.(map[string]interface{})
+		ops = append(ops, op["op"].(string)+":"+op["table"].(string))
+		if name, ok := op["uuid-name"].(string); ok {
+			if names[name] {
+				t.Fatalf("duplicated uuid-name: %v", name)
+			}
+			names[name] = true
+		}
+	}
+	expected := "wait:Bridge insert:Interface insert:Port insert:Bridge mutate:Open_vSwitch " +
+		"insert:Controller update:Bridge " +
+		"wait:Port insert:Interface insert:Port mutate:Bridge " +
+		"wait:Port insert:Interface insert:Port mutate:Bridge"
+	if strings.Join(ops, " ") != expected {
+		t.Fatalf("unexpected operations: expected=%v, got=%v", expected, ops)
+	}
+
+	// Invalid tunnels are rejected before the transaction.
+	if err := c.Bootstrap(ctx, "br0", nil, "", []Tunnel{{Name: "t0", Type: "ipip"}}); err == nil {
+		t.Fatal("expected an error for an unsupported tunnel type")
+	}
+}
+
+func TestTransactError(t *testing.T) {
+	client, server := net.Pipe()
+	fakeServer(t, server, func(req map[string]interface{}) interface{} {
+		return []interface{}{map[string]interface{}{"error": "timed out", "details": "\"wait\" timed out"}, nil}
+	})
+	c := NewClient(client)
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := c.AddPort(ctx, "br0", "vxlan0", "vxlan", map[string]string{"remote_ip": "10.0.0.2"})
+	if err == nil || !strings.Contains(err.Error(), "wait operation on Port failed") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestEchoAndClose(t *testing.T) {
+	client, server := net.Pipe()
+	c := NewClient(client)
+
+	// The server sends an echo request, and we should reply it with the same params.
+	go json.NewEncoder(server).Encode(map[string]interface{}{"method": "echo", "params": []interface{}{"hello"}, "id": "echo"})
+	var reply map[string]interface{}
+	if err := json.NewDecoder(server).Decode(&reply); err != nil {
+		t.Fatal(err)
+	}
+	if reply["id"] != "echo" || reply["result"].([]interface{})[0] != "hello" {
+		t.Fatalf("unexpected echo reply: %v", reply)
+	}
+
+	// Pending requests are aborted when the connection is closed.
+	done := make(chan error)
+	go func() {
+		_, err := c.ListDBs(context.Background())
+		done <- err
+	}()
+	var req map[string]interface{}
+	if err := json.NewDecoder(server).Decode(&req); err != nil {
+		t.Fatal(err)
+	}
+	server.Close()
+	if err := <-done; err != ErrClosedClient {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := c.ListDBs(context.Background()); err != ErrClosedClient {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,120 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package ovsdb
+
+// Operation is an operation of a transaction (RFC 7047, Section 5.2).
+type Operation map[string]interface{}
+
+// OperationResult is the result of an operation. Only the fields relevant to the operation are set.
+type OperationResult struct {
+	// Number of rows updated, mutated or deleted.
+	Count int `json:"count,omitempty"`
+	// UUID of the inserted row, e.g., ["uuid", "..."].
+	UUID []interface{} `json:"uuid,omitempty"`
+	// Rows returned by select.
+	Rows    []map[string]interface{} `json:"rows,omitempty"`
+	Error   string                   `json:"error,omitempty"`
+	Details string                   `json:"details,omitempty"`
+}
+
+// Condition returns a condition of the where clause, e.g., Condition("name", "==", "br0").
+func Condition(column, function string, value interface{}) []interface{} {
+	return []interface{}{column, function, value}
+}
+
+// Mutation returns a mutation of the mutate operation, e.g., Mutation("ports", "insert", NamedUUID("port")).
+func Mutation(column, mutator string, value interface{}) []interface{} {
+	return []interface{}{column, mutator, value}
+}
+
+// NamedUUID refers a row inserted by the same transaction with name as its uuid-name.
+func NamedUUID(name string) []interface{} {
+	return []interface{}{"named-uuid", name}
+}
+
+// UUID refers an existing row.
+func UUID(id string) []interface{} {
+	return []interface{}{"uuid", id}
+}
+
+// Set returns an OVSDB set of values.
+func Set(values ...interface{}) []interface{} {
+	if values == nil {
+		values = []interface{}{}
+	}
+	return []interface{}{"set", values}
+}
+
+// Map returns an OVSDB map of m.
+func Map(m map[string]string) []interface{} {
+	pairs := make([]interface{}, 0, len(m))
+	for k, v := range m {
+		pairs = append(pairs, []interface{}{k, v})
+	}
+	return []interface{}{"map", pairs}
+}
+
+func where(conditions [][]interface{}) []interface{} {
+	v := make([]interface{}, 0, len(conditions))
+	for _, c := range conditions {
+		v = append(v, c)
+	}
+	return v
+}
+
+// Insert inserts row into table. uuidName can be used by the other operations of the same
+// transaction to refer the new row by NamedUUID. It can be empty.
+func Insert(table string, row map[string]interface{}, uuidName string) Operation {
+	op := Operation{"op": "insert", "table": table, "row": row}
+	if len(uuidName) > 0 {
+		op["uuid-name"] = uuidName
+	}
+	return op
+}
+
+// Select returns the columns of the rows matched with conditions. All columns are returned if columns is nil.
+func Select(table string, conditions [][]interface{}, columns []string) Operation {
+	op := Operation{"op": "select", "table": table, "where": where(conditions)}
+	if columns != nil {
+		op["columns"] = columns
+	}
+	return op
+}
+
+// Update updates the columns in row of the rows matched with conditions.
+func Update(table string, conditions [][]interface{}, row map[string]interface{}) Operation {
+	return Operation{"op": "update", "table": table, "where": where(conditions), "row": row}
+}
+
+// Mutate applies mutations to the rows matched with conditions.
+func Mutate(table string, conditions [][]interface{}, mutations ...[]interface{}) Operation {
+	m := make([]interface{}, 0, len(mutations))
+	for _, v := range mutations {
+		m = append(m, v)
+	}
+	return Operation{"op": "mutate", "table": table, "where": where(conditions), "mutations": m}
+}
+
+// Delete deletes the rows matched with conditions.
+func Delete(table string, conditions [][]interface{}) Operation {
+	return Operation{"op": "delete", "table": table, "where": where(conditions)}
+}
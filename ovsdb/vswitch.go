@@ -0,0 +1,295 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package ovsdb
+
+import (
+	"context"
+	"fmt"
+	"net"
+)
+
+const (
+	// Database of Open vSwitch.
+	vswitchDB = "Open_vSwitch"
+)
+
+// absent returns an operation that makes the transaction fail if table has a row whose name is name.
+func absent(table, name string) Operation {
+	return Operation{
+		"op":      "wait",
+		"table":   table,
+		"where":   where([][]interface{}{Condition("name", "==", name)}),
+		"columns": []string{"name"},
+		"until":   "==",
+		"rows":    []interface{}{},
+		"timeout": 0,
+	}
+}
+
+// present returns an operation that makes the transaction fail if table has no row whose name is name.
+func present(table, name string) Operation {
+	op := absent(table, name)
+	op["until"] = "!="
+
+	return op
+}
+
+// uuidOf returns the UUID of the row whose name is name in table. ok will be false if there is no such row.
+func (r *Client) uuidOf(ctx context.Context, table, name string) (id string, ok bool, err error) {
+	results, err := r.Transact(ctx, vswitchDB, Select(table, [][]interface{}{Condition("name", "==", name)}, []string{"_uuid"}))
+	if err != nil {
+		return "", false, err
+	}
+	if len(results) == 0 || len(results[0].Rows) == 0 {
+		return "", false, nil
+	}
+
+	v, ok := results[0].Rows[0]["_uuid"].([]interface{})
+	if !ok || len(v) != 2 {
+		return "", false, fmt.Errorf("invalid UUID of %v %v: %v", table, name, results[0].Rows[0]["_uuid"])
+	}
+	id, ok = v[1].(string)
+	if !ok {
+		return "", false, fmt.Errorf("invalid UUID of %v %v: %v", table, name, v)
+	}
+
+	return id, true, nil
+}
+
+// checkCount returns an error if the operation at index of results has not changed any row.
+func checkCount(results []OperationResult, index int, what string) error {
+	if len(results) <= index || results[index].Count == 0 {
+		return fmt.Errorf("unknown %v", what)
+	}
+
+	return nil
+}
+
+// HasBridge returns whether there is a bridge whose name is name.
+func (r *Client) HasBridge(ctx context.Context, name string) (bool, error) {
+	_, ok, err := r.uuidOf(ctx, "Bridge", name)
+	return ok, err
+}
+
+// AddBridge creates a bridge whose name is name, with its internal port that has the same name.
+func (r *Client) AddBridge(ctx context.Context, name string) error {
+	_, err := r.Transact(ctx, vswitchDB, addBridge(name)...)
+	return err
+}
+
+func addBridge(name string) []Operation {
+	return []Operation{
+		absent("Bridge", name),
+		Insert("Interface", map[string]interface{}{"name": name, "type": "internal"}, "bridge_iface"),
+		Insert("Port", map[string]interface{}{"name": name, "interfaces": NamedUUID("bridge_iface")}, "bridge_port"),
+		Insert("Bridge", map[string]interface{}{"name": name, "ports": NamedUUID("bridge_port")}, "bridge"),
+		Mutate("Open_vSwitch", nil, Mutation("bridges", "insert", Set(NamedUUID("bridge")))),
+	}
+}
+
+// DeleteBridge removes the bridge whose name is name, and also its ports.
+func (r *Client) DeleteBridge(ctx context.Context, name string) error {
+	id, ok, err := r.uuidOf(ctx, "Bridge", name)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("unknown bridge: %v", name)
+	}
+
+	// The bridge, and then its ports and interfaces, are garbage collected once no row refers it.
+	_, err = r.Transact(ctx, vswitchDB, Mutate("Open_vSwitch", nil, Mutation("bridges", "delete", Set(UUID(id)))))
+
+	return err
+}
+
+// SetController replaces the OpenFlow controllers of bridge with targets, e.g., "tcp:10.0.0.1:6633".
+// failMode is "secure" or "standalone", and it is not changed if failMode is empty.
+func (r *Client) SetController(ctx context.Context, bridge string, targets []string, failMode string) error {
+	ops, err := setController(bridge, targets, failMode)
+	if err != nil {
+		return err
+	}
+
+	results, err := r.Transact(ctx, vswitchDB, ops...)
+	if err != nil {
+		return err
+	}
+
+	return checkCount(results, len(ops)-1, "bridge: "+bridge)
+}
+
+// setController returns the operations of SetController. The last one updates the bridge.
+func setController(bridge string, targets []string, failMode string) ([]Operation, error) {
+	if failMode != "" && failMode != "secure" && failMode != "standalone" {
+		return nil, fmt.Errorf("invalid fail mode: %v", failMode)
+	}
+
+	ops := make([]Operation, 0)
+	controllers := make([]interface{}, 0)
+	for i, v := range targets {
+		name := fmt.Sprintf("controller%v", i)
+		ops = append(ops, Insert("Controller", map[string]interface{}{"target": v}, name))
+		controllers = append(controllers, NamedUUID(name))
+	}
+	row := map[string]interface{}{"controller": Set(controllers...)}
+	if failMode != "" {
+		row["fail_mode"] = failMode
+	}
+	// The previous controllers are garbage collected.
+	ops = append(ops, Update("Bridge", [][]interface{}{Condition("name", "==", bridge)}, row))
+
+	return ops, nil
+}
+
+// AddPort adds a port whose name is name to bridge. ifaceType is the type of its interface, such as
+// "internal" and "vxlan", and an empty type means a system (physical) interface. options are the
+// type specific options, e.g., remote_ip of tunnels. It can be nil.
+func (r *Client) AddPort(ctx context.Context, bridge, name, ifaceType string, options map[string]string) error {
+	ops := addPort(bridge, name, ifaceType, options, 0)
+	results, err := r.Transact(ctx, vswitchDB, ops...)
+	if err != nil {
+		return err
+	}
+
+	return checkCount(results, len(ops)-1, "bridge: "+bridge)
+}
+
+// addPort returns the operations of AddPort. The last one adds the port to the bridge. index makes
+// the names of the new rows unique in a transaction that adds several ports.
+func addPort(bridge, name, ifaceType string, options map[string]string, index int) []Operation {
+	iface := map[string]interface{}{"name": name}
+	if ifaceType != "" {
+		iface["type"] = ifaceType
+	}
+	if len(options) > 0 {
+		iface["options"] = Map(options)
+	}
+	ifaceName := fmt.Sprintf("iface%v", index)
+	portName := fmt.Sprintf("port%v", index)
+
+	return []Operation{
+		absent("Port", name),
+		Insert("Interface", iface, ifaceName),
+		Insert("Port", map[string]interface{}{"name": name, "interfaces": NamedUUID(ifaceName)}, portName),
+		Mutate("Bridge", [][]interface{}{Condition("name", "==", bridge)}, Mutation("ports", "insert", Set(NamedUUID(portName)))),
+	}
+}
+
+// Tunnel is a tunnel port of a bridge.
+type Tunnel struct {
+	Name string
+	// vxlan, gre or geneve
+	Type     string
+	RemoteIP net.IP
+}
+
+func (r Tunnel) validate() error {
+	switch r.Type {
+	case "vxlan", "gre", "geneve":
+	default:
+		return fmt.Errorf("unsupported tunnel type: %v", r.Type)
+	}
+	if r.RemoteIP == nil {
+		return fmt.Errorf("nil remote IP address")
+	}
+
+	return nil
+}
+
+func (r Tunnel) options() map[string]string {
+	return map[string]string{"remote_ip": r.RemoteIP.String()}
+}
+
+// AddTunnelPort adds a tunnel port whose name is name to bridge. tunnelType is "vxlan", "gre" or
+// "geneve", and remoteIP is the address of the other tunnel endpoint.
+func (r *Client) AddTunnelPort(ctx context.Context, bridge, name, tunnelType string, remoteIP net.IP) error {
+	t := Tunnel{Name: name, Type: tunnelType, RemoteIP: remoteIP}
+	if err := t.validate(); err != nil {
+		return err
+	}
+
+	return r.AddPort(ctx, bridge, t.Name, t.Type, t.options())
+}
+
+// Bootstrap creates bridge if it does not exist, replaces its controllers with targets as
+// SetController does, and adds tunnels to it. All of them are done in a single transaction,
+// so nothing is changed if any of them fails.
+func (r *Client) Bootstrap(ctx context.Context, bridge string, targets []string, failMode string, tunnels []Tunnel) error {
+	exists, err := r.HasBridge(ctx, bridge)
+	if err != nil {
+		return err
+	}
+
+	ops := make([]Operation, 0)
+	// The bridge may be created or removed by another client after we have checked it, which
+	// fails the whole transaction by the wait operations.
+	if exists {
+		ops = append(ops, present("Bridge", bridge))
+	} else {
+		ops = append(ops, addBridge(bridge)...)
+	}
+	v, err := setController(bridge, targets, failMode)
+	if err != nil {
+		return err
+	}
+	ops = append(ops, v...)
+	// Indexes of the operations that should change the bridge.
+	checks := []int{len(ops) - 1}
+	for i, t := range tunnels {
+		if err := t.validate(); err != nil {
+			return err
+		}
+		ops = append(ops, addPort(bridge, t.Name, t.Type, t.options(), i)...)
+		checks = append(checks, len(ops)-1)
+	}
+
+	results, err := r.Transact(ctx, vswitchDB, ops...)
+	if err != nil {
+		return err
+	}
+	for _, i := range checks {
+		if err := checkCount(results, i, "bridge: "+bridge); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeletePort removes the port whose name is name from bridge.
+func (r *Client) DeletePort(ctx context.Context, bridge, name string) error {
+	id, ok, err := r.uuidOf(ctx, "Port", name)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("unknown port: %v", name)
+	}
+
+	results, err := r.Transact(ctx, vswitchDB, Mutate("Bridge", [][]interface{}{Condition("name", "==", bridge)}, Mutation("ports", "delete", Set(UUID(id)))))
+	if err != nil {
+		return err
+	}
+
+	return checkCount(results, 0, "bridge: "+bridge)
+}
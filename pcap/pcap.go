@@ -0,0 +1,163 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+// Package pcap writes packets in the libpcap file format, which can be read by tcpdump and Wireshark.
+package pcap
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	// LinkTypeRaw is the link-layer header type of the raw IPv4 and IPv6 packets.
+	LinkTypeRaw = 101
+	// Maximum length of a packet in the file.
+	snapLen = 262144
+)
+
+// Writer writes the packets to an underlying writer in the pcap format.
+type Writer struct {
+	w        io.Writer
+	linkType uint32
+	header   bool
+}
+
+// NewWriter returns a writer that writes the packets of linkType to w. The file header is
+// written together with the first packet.
+func NewWriter(w io.Writer, linkType uint32) *Writer {
+	return &Writer{w: w, linkType: linkType}
+}
+
+func (r *Writer) writeHeader() error {
+	v := make([]byte, 24)
+	binary.LittleEndian.PutUint32(v[0:4], 0xa1b2c3d4) // Magic number with microsecond timestamps
+	binary.LittleEndian.PutUint16(v[4:6], 2)          // Major version
+	binary.LittleEndian.PutUint16(v[6:8], 4)          // Minor version
+	// v[8:16] is the time zone offset and the accuracy of the timestamps, which are zero.
+	binary.LittleEndian.PutUint32(v[16:20], snapLen)
+	binary.LittleEndian.PutUint32(v[20:24], r.linkType)
+	_, err := r.w.Write(v)
+
+	return err
+}
+
+// WritePacket writes packet captured at t.
+func (r *Writer) WritePacket(t time.Time, packet []byte) error {
+	if len(packet) > snapLen {
+		return errors.New("too long packet")
+	}
+	if !r.header {
+		if err := r.writeHeader(); err != nil {
+			return err
+		}
+		r.header = true
+	}
+
+	v := make([]byte, 16+len(packet))
+	binary.LittleEndian.PutUint32(v[0:4], uint32(t.Unix()))
+	binary.LittleEndian.PutUint32(v[4:8], uint32(t.Nanosecond()/1000))
+	binary.LittleEndian.PutUint32(v[8:12], uint32(len(packet)))  // Captured length
+	binary.LittleEndian.PutUint32(v[12:16], uint32(len(packet))) // Original length
+	copy(v[16:], packet)
+	_, err := r.w.Write(v)
+
+	return err
+}
+
+type record struct {
+	time   time.Time
+	packet []byte
+}
+
+// Ring keeps the latest packets in memory, which can be written in the pcap format at any time.
+type Ring struct {
+	mutex    sync.Mutex
+	linkType uint32
+	records  []record
+	// Index of the oldest record if the ring is full.
+	next int
+	full bool
+}
+
+// NewRing returns a ring that keeps size packets of linkType. size should be greater than zero.
+func NewRing(size int, linkType uint32) *Ring {
+	if size <= 0 {
+		panic("invalid ring size")
+	}
+
+	return &Ring{
+		linkType: linkType,
+		records:  make([]record, size),
+	}
+}
+
+// Add keeps packet captured at t, replacing the oldest one if the ring is full. packet
+// should not be modified after it is added.
+func (r *Ring) Add(t time.Time, packet []byte) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.records[r.next] = record{time: t, packet: packet}
+	r.next++
+	if r.next == len(r.records) {
+		r.next = 0
+		r.full = true
+	}
+}
+
+// Len returns the number of the packets in the ring.
+func (r *Ring) Len() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.full {
+		return len(r.records)
+	}
+	return r.next
+}
+
+// Dump writes the packets in the ring, from the oldest one, to w in the pcap format.
+func (r *Ring) Dump(w io.Writer) error {
+	r.mutex.Lock()
+	records := make([]record, 0, len(r.records))
+	if r.full {
+		records = append(records, r.records[r.next:]...)
+	}
+	records = append(records, r.records[:r.next]...)
+	r.mutex.Unlock()
+
+	writer := NewWriter(w, r.linkType)
+	if len(records) == 0 {
+		// Empty file that has only the header.
+		return writer.writeHeader()
+	}
+	for _, v := range records {
+		if err := writer.WritePacket(v.time, v.packet); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package pcap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/superkkt/cherry/protocol"
+)
+
+// readPackets parses the pcap file in data and returns its packets.
+func readPackets(t *testing.T, data []byte) [][]byte {
+	if len(data) < 24 {
+		t.Fatalf("too short file: %v bytes", len(data))
+	}
+	if binary.LittleEndian.Uint32(data[0:4]) != 0xa1b2c3d4 || binary.LittleEndian.Uint32(data[20:24]) != LinkTypeRaw {
+		t.Fatalf("unexpected file header: %v", data[:24])
+	}
+
+	packets := make([][]byte, 0)
+	for data = data[24:]; len(data) > 0; {
+		if len(data) < 16 {
+			t.Fatalf("truncated record header")
+		}
+		n := int(binary.LittleEndian.Uint32(data[8:12]))
+		if len(data) < 16+n {
+			t.Fatalf("truncated record")
+		}
+		packets = append(packets, data[16:16+n])
+		data = data[16+n:]
+	}
+
+	return packets
+}
+
+func TestRing(t *testing.T) {
+	ring := NewRing(2, LinkTypeRaw)
+	buf := new(bytes.Buffer)
+	if err := ring.Dump(buf); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(readPackets(t, buf.Bytes())); n != 0 {
+		t.Fatalf("expected an empty file, got %v packets", n)
+	}
+
+	now := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := byte(1); i <= 3; i++ {
+		ring.Add(now, []byte{i})
+	}
+	if ring.Len() != 2 {
+		t.Fatalf("unexpected length: %v", ring.Len())
+	}
+
+	buf.Reset()
+	if err := ring.Dump(buf); err != nil {
+		t.Fatal(err)
+	}
+	packets := readPackets(t, buf.Bytes())
+	// The oldest packet has been replaced.
+	if len(packets) != 2 || packets[0][0] != 2 || packets[1][0] != 3 {
+		t.Fatalf("unexpected packets: %v", packets)
+	}
+}
+
+func TestTCPStream(t *testing.T) {
+	local := &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 6633}
+	remote := &net.TCPAddr{IP: net.IPv4(10, 0, 0, 2), Port: 40000}
+	stream := NewTCPStream(local, remote)
+
+	check := func(sent bool, data []byte, srcPort, dstPort uint16, seq, ack uint32) {
+		packets, err := stream.Packets(sent, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(packets) != 1 {
+			t.Fatalf("unexpected number of packets: %v", len(packets))
+		}
+		ip := new(protocol.IPv4)
+		if err := ip.UnmarshalBinary(packets[0]); err != nil {
+			t.Fatal(err)
+		}
+		tcp := new(protocol.TCP)
+		if err := tcp.UnmarshalBinary(ip.Payload); err != nil {
+			t.Fatal(err)
+		}
+		if tcp.SrcPort != srcPort || tcp.DstPort != dstPort || tcp.Sequence != seq || tcp.Acknowledgment != ack || !bytes.Equal(tcp.Payload, data) {
+			t.Fatalf("unexpected segment: %+v", tcp)
+		}
+	}
+	check(true, make([]byte, 8), 6633, 40000, 0, 0)
+	check(false, make([]byte, 16), 40000, 6633, 0, 8)
+	check(true, make([]byte, 8), 6633, 40000, 8, 16)
+
+	// Too long data for a segment.
+	packets, err := stream.Packets(true, make([]byte, maxSegmentSize+1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(packets) != 2 {
+		t.Fatalf("expected 2 segments, got %v", len(packets))
+	}
+}
@@ -0,0 +1,103 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package pcap
+
+import (
+	"net"
+
+	"github.com/superkkt/cherry/protocol"
+)
+
+const (
+	// Maximum payload of a TCP segment in an IPv4 packet whose headers have no options.
+	maxSegmentSize = 0xFFFF - 20 - 20
+	tcpFlagPSH     = 1 << 3
+	tcpFlagACK     = 1 << 4
+)
+
+// TCPStream makes the IPv4 packets of LinkTypeRaw that carry the data exchanged over a TCP
+// connection, so that the data can be analyzed by the dissectors of the protocols over TCP.
+// The connection establishment is not included. The addresses that are not TCP over IPv4,
+// such as IPv6 ones, are replaced with 0.0.0.0:0.
+type TCPStream struct {
+	local, remote *net.TCPAddr
+	// Next sequence number of each direction.
+	localSeq, remoteSeq uint32
+}
+
+func NewTCPStream(local, remote net.Addr) *TCPStream {
+	return &TCPStream{
+		local:  toTCPAddr(local),
+		remote: toTCPAddr(remote),
+	}
+}
+
+func toTCPAddr(addr net.Addr) *net.TCPAddr {
+	v, ok := addr.(*net.TCPAddr)
+	if !ok || v.IP.To4() == nil {
+		return &net.TCPAddr{IP: net.IPv4zero}
+	}
+
+	return v
+}
+
+// Packets returns the packets that carry data. sent is true if the data is sent from the local
+// address. Long data is split into multiple segments.
+func (r *TCPStream) Packets(sent bool, data []byte) ([][]byte, error) {
+	src, dst := r.remote, r.local
+	seq, ack := &r.remoteSeq, r.localSeq
+	if sent {
+		src, dst = r.local, r.remote
+		seq, ack = &r.localSeq, r.remoteSeq
+	}
+
+	packets := make([][]byte, 0, 1)
+	for len(data) > 0 {
+		n := len(data)
+		if n > maxSegmentSize {
+			n = maxSegmentSize
+		}
+		tcp := &protocol.TCP{
+			SrcPort:        uint16(src.Port),
+			DstPort:        uint16(dst.Port),
+			Sequence:       *seq,
+			Acknowledgment: ack,
+			Flags:          tcpFlagPSH | tcpFlagACK,
+			WindowSize:     0xFFFF,
+			Payload:        data[:n],
+		}
+		tcp.SetPseudoHeader(src.IP, dst.IP)
+		segment, err := tcp.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		packet, err := protocol.NewIPv4(src.IP, dst.IP, 6, segment).MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		packets = append(packets, packet)
+		*seq += uint32(n)
+		data = data[n:]
+	}
+
+	return packets, nil
+}
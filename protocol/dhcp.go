@@ -0,0 +1,201 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package protocol
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+)
+
+// DHCP message types (RFC 2132).
+const (
+	DHCPDiscover uint8 = 1
+	DHCPOffer    uint8 = 2
+	DHCPRequest  uint8 = 3
+	DHCPDecline  uint8 = 4
+	DHCPAck      uint8 = 5
+	DHCPNak      uint8 = 6
+	DHCPRelease  uint8 = 7
+	DHCPInform   uint8 = 8
+)
+
+// DHCP option codes used by the controller.
+const (
+	DHCPOptionPad         uint8 = 0
+	DHCPOptionLeaseTime   uint8 = 51
+	DHCPOptionMessageType uint8 = 53
+	DHCPOptionServerID    uint8 = 54
+	DHCPOptionEnd         uint8 = 255
+)
+
+const (
+	dhcpHeaderLength = 236
+	dhcpMagicCookie  = 0x63825363
+)
+
+type DHCPOption struct {
+	Code uint8
+	Data []byte
+}
+
+// DHCP is a DHCP (BOOTP) message. Only the options are kept after the fixed header;
+// the sname and file fields are ignored.
+type DHCP struct {
+	Op      uint8
+	HType   uint8
+	HLen    uint8
+	Hops    uint8
+	XID     uint32
+	Secs    uint16
+	Flags   uint16
+	CIAddr  net.IP
+	YIAddr  net.IP
+	SIAddr  net.IP
+	GIAddr  net.IP
+	CHAddr  net.HardwareAddr
+	Options []DHCPOption
+}
+
+// Option returns the data of the first option whose code is code.
+func (r *DHCP) Option(code uint8) (data []byte, ok bool) {
+	for _, v := range r.Options {
+		if v.Code == code {
+			return v.Data, true
+		}
+	}
+
+	return nil, false
+}
+
+// MessageType returns the DHCP message type, or 0 if the message is a plain BOOTP message.
+func (r *DHCP) MessageType() uint8 {
+	v, ok := r.Option(DHCPOptionMessageType)
+	if !ok || len(v) != 1 {
+		return 0
+	}
+
+	return v[0]
+}
+
+func putIPv4(dst []byte, ip net.IP) error {
+	if ip == nil {
+		return nil
+	}
+	v := ip.To4()
+	if v == nil {
+		return errors.New("not an IPv4 address")
+	}
+	copy(dst, v)
+
+	return nil
+}
+
+func (r DHCP) MarshalBinary() ([]byte, error) {
+	if len(r.CHAddr) > 16 {
+		return nil, errors.New("too long client hardware address")
+	}
+
+	length := dhcpHeaderLength + 4
+	for _, v := range r.Options {
+		if len(v.Data) > 255 {
+			return nil, errors.New("too long DHCP option")
+		}
+		length += 2 + len(v.Data)
+	}
+	// End option
+	length++
+
+	v := make([]byte, length)
+	v[0] = r.Op
+	v[1] = r.HType
+	v[2] = r.HLen
+	v[3] = r.Hops
+	binary.BigEndian.PutUint32(v[4:8], r.XID)
+	binary.BigEndian.PutUint16(v[8:10], r.Secs)
+	binary.BigEndian.PutUint16(v[10:12], r.Flags)
+	for i, ip := range []net.IP{r.CIAddr, r.YIAddr, r.SIAddr, r.GIAddr} {
+		if err := putIPv4(v[12+i*4:16+i*4], ip); err != nil {
+			return nil, err
+		}
+	}
+	copy(v[28:44], r.CHAddr)
+	// v[44:236] is sname and file
+	binary.BigEndian.PutUint32(v[236:240], dhcpMagicCookie)
+
+	offset := 240
+	for _, opt := range r.Options {
+		v[offset] = opt.Code
+		v[offset+1] = uint8(len(opt.Data))
+		copy(v[offset+2:], opt.Data)
+		offset += 2 + len(opt.Data)
+	}
+	v[offset] = DHCPOptionEnd
+
+	return v, nil
+}
+
+func (r *DHCP) UnmarshalBinary(data []byte) error {
+	if len(data) < dhcpHeaderLength+4 {
+		return errors.New("invalid DHCP packet length")
+	}
+	if binary.BigEndian.Uint32(data[236:240]) != dhcpMagicCookie {
+		return errors.New("invalid DHCP magic cookie")
+	}
+
+	r.Op = data[0]
+	r.HType = data[1]
+	r.HLen = data[2]
+	r.Hops = data[3]
+	r.XID = binary.BigEndian.Uint32(data[4:8])
+	r.Secs = binary.BigEndian.Uint16(data[8:10])
+	r.Flags = binary.BigEndian.Uint16(data[10:12])
+	r.CIAddr = net.IPv4(data[12], data[13], data[14], data[15])
+	r.YIAddr = net.IPv4(data[16], data[17], data[18], data[19])
+	r.SIAddr = net.IPv4(data[20], data[21], data[22], data[23])
+	r.GIAddr = net.IPv4(data[24], data[25], data[26], data[27])
+	if r.HLen > 16 {
+		return errors.New("invalid DHCP hardware address length")
+	}
+	r.CHAddr = net.HardwareAddr(append([]byte(nil), data[28:28+r.HLen]...))
+
+	r.Options = nil
+	options := data[240:]
+	for len(options) > 0 {
+		code := options[0]
+		if code == DHCPOptionEnd {
+			break
+		}
+		if code == DHCPOptionPad {
+			options = options[1:]
+			continue
+		}
+		if len(options) < 2 || len(options) < 2+int(options[1]) {
+			return errors.New("invalid DHCP option length")
+		}
+		length := int(options[1])
+		r.Options = append(r.Options, DHCPOption{Code: code, Data: options[2 : 2+length]})
+		options = options[2+length:]
+	}
+
+	return nil
+}
@@ -29,8 +29,14 @@ import (
 
 type Ethernet struct {
 	SrcMAC, DstMAC net.HardwareAddr
-	Type           uint16
-	Payload        []byte
+	// Tagged is true if the frame has an IEEE 802.1Q tag, whose priority and
+	// VLAN ID are VLANPriority and VLANID.
+	Tagged       bool
+	VLANPriority uint8
+	VLANID       uint16
+	// Type is the EtherType of the payload, not the 802.1Q TPID, even if the frame is tagged.
+	Type    uint16
+	Payload []byte
 }
 
 func (r Ethernet) MarshalBinary() ([]byte, error) {
@@ -41,12 +47,24 @@ func (r Ethernet) MarshalBinary() ([]byte, error) {
 		return nil, errors.New("nil payload")
 	}
 
-	v := make([]byte, 14+len(r.Payload))
+	headerLen := 14
+	if r.Tagged {
+		if r.VLANID > 0xFFF || r.VLANPriority > 0x7 {
+			return nil, errors.New("invalid 802.1Q tag")
+		}
+		headerLen = 18
+	}
+
+	v := make([]byte, headerLen+len(r.Payload))
 	copy(v[0:6], r.DstMAC)
 	copy(v[6:12], r.SrcMAC)
-	binary.BigEndian.PutUint16(v[12:14], r.Type)
+	if r.Tagged {
+		binary.BigEndian.PutUint16(v[12:14], 0x8100)
+		binary.BigEndian.PutUint16(v[14:16], uint16(r.VLANPriority)<<13|r.VLANID)
+	}
+	binary.BigEndian.PutUint16(v[headerLen-2:headerLen], r.Type)
 	if len(r.Payload) > 0 {
-		copy(v[14:], r.Payload)
+		copy(v[headerLen:], r.Payload)
 	}
 
 	return v, nil
@@ -62,9 +80,19 @@ func (r *Ethernet) UnmarshalBinary(data []byte) error {
 	r.Type = binary.BigEndian.Uint16(data[12:14])
 	// IEEE 802.1Q-tagged frame?
 	if r.Type == 0x8100 {
+		if len(data) < 18 {
+			return errors.New("invalid 802.1Q-tagged ethernet frame length")
+		}
+		tci := binary.BigEndian.Uint16(data[14:16])
+		r.Tagged = true
+		r.VLANPriority = uint8(tci >> 13)
+		r.VLANID = tci & 0xFFF
 		r.Type = binary.BigEndian.Uint16(data[16:18])
 		r.Payload = data[18:]
 	} else {
+		r.Tagged = false
+		r.VLANPriority = 0
+		r.VLANID = 0
 		r.Payload = data[14:]
 	}
 	// FIXME: Add routines for JumboFrame
@@ -0,0 +1,161 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package protocol
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+)
+
+// Types of the neighbor discovery messages of ICMPv6 (RFC 4861).
+const (
+	ICMPv6NeighborSolicitation  = 135
+	ICMPv6NeighborAdvertisement = 136
+)
+
+// Types of the link-layer address options of the neighbor discovery messages.
+const (
+	ndOptionSourceLinkAddr = 1
+	ndOptionTargetLinkAddr = 2
+)
+
+// NeighborDiscovery is a Neighbor Solicitation or Advertisement message of ICMPv6.
+type NeighborDiscovery struct {
+	srcIP net.IP
+	dstIP net.IP
+	ICMP
+	// Flags of the advertisement.
+	Router    bool
+	Solicited bool
+	Override  bool
+	Target    net.IP
+	// LinkAddr is the source link-layer address option of the solicitation, or the target
+	// link-layer address option of the advertisement. It is nil if the option is absent.
+	LinkAddr net.HardwareAddr
+}
+
+// NewNeighborAdvertisement returns a solicited advertisement that answers the solicitation
+// for target whose link-layer address is mac.
+func NewNeighborAdvertisement(target net.IP, mac net.HardwareAddr) *NeighborDiscovery {
+	return &NeighborDiscovery{
+		ICMP: ICMP{
+			Type: ICMPv6NeighborAdvertisement,
+		},
+		Solicited: true,
+		Override:  true,
+		Target:    target,
+		LinkAddr:  mac,
+	}
+}
+
+// ICMPv6 checksum needs a pseudo header that has src and dst IPv6 addresses.
+func (r *NeighborDiscovery) SetPseudoHeader(src, dst net.IP) {
+	r.srcIP = src
+	r.dstIP = dst
+}
+
+func (r NeighborDiscovery) MarshalBinary() ([]byte, error) {
+	if r.Type != ICMPv6NeighborSolicitation && r.Type != ICMPv6NeighborAdvertisement {
+		return nil, errors.New("packet is not a neighbor solicitation or advertisement")
+	}
+	target := r.Target.To16()
+	if target == nil || r.Target.To4() != nil {
+		return nil, errors.New("target address is not an IPv6 address")
+	}
+	if r.LinkAddr != nil && len(r.LinkAddr) != 6 {
+		return nil, errors.New("invalid link-layer address")
+	}
+	if r.srcIP.To16() == nil || r.dstIP.To16() == nil {
+		return nil, errors.New("nil pseudo IP addresses")
+	}
+
+	length := 24
+	if r.LinkAddr != nil {
+		length += 8
+	}
+	v := make([]byte, length)
+	v[0] = r.Type
+	v[1] = r.Code
+	// v[2:4] is checksum
+	if r.Router {
+		v[4] |= 0x80
+	}
+	if r.Solicited {
+		v[4] |= 0x40
+	}
+	if r.Override {
+		v[4] |= 0x20
+	}
+	copy(v[8:24], target)
+	if r.LinkAddr != nil {
+		v[24] = ndOptionSourceLinkAddr
+		if r.Type == ICMPv6NeighborAdvertisement {
+			v[24] = ndOptionTargetLinkAddr
+		}
+		// Length in units of 8 bytes.
+		v[25] = 1
+		copy(v[26:32], r.LinkAddr)
+	}
+
+	pseudo := make([]byte, 40)
+	copy(pseudo[0:16], r.srcIP.To16())
+	copy(pseudo[16:32], r.dstIP.To16())
+	binary.BigEndian.PutUint32(pseudo[32:36], uint32(length))
+	pseudo[39] = 58 // ICMPv6
+	checksum := calculateChecksum(append(pseudo, v...))
+	binary.BigEndian.PutUint16(v[2:4], checksum)
+
+	return v, nil
+}
+
+func (r *NeighborDiscovery) UnmarshalBinary(data []byte) error {
+	if len(data) < 24 {
+		return errors.New("invalid neighbor discovery message length")
+	}
+	if data[0] != ICMPv6NeighborSolicitation && data[0] != ICMPv6NeighborAdvertisement {
+		return errors.New("packet is not a neighbor solicitation or advertisement")
+	}
+
+	r.Type = data[0]
+	r.Code = data[1]
+	r.Checksum = binary.BigEndian.Uint16(data[2:4])
+	r.Router = data[4]&0x80 != 0
+	r.Solicited = data[4]&0x40 != 0
+	r.Override = data[4]&0x20 != 0
+	r.Target = net.IP(data[8:24])
+	r.LinkAddr = nil
+
+	options := data[24:]
+	for len(options) >= 8 {
+		length := int(options[1]) * 8
+		if length == 0 || len(options) < length {
+			return errors.New("invalid neighbor discovery option length")
+		}
+		if (options[0] == ndOptionSourceLinkAddr || options[0] == ndOptionTargetLinkAddr) && length == 8 {
+			r.LinkAddr = net.HardwareAddr(options[2:8])
+		}
+		options = options[length:]
+	}
+
+	return nil
+}
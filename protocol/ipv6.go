@@ -0,0 +1,105 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package protocol
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+)
+
+type IPv6 struct {
+	Version      uint8
+	TrafficClass uint8
+	FlowLabel    uint32
+	// Length of the payload including the extension headers.
+	PayloadLength uint16
+	NextHeader    uint8
+	HopLimit      uint8
+	SrcIP         net.IP
+	DstIP         net.IP
+	// Payload starts with the extension headers if NextHeader is not an upper-layer protocol.
+	Payload []byte
+}
+
+func NewIPv6(src, dst net.IP, nextHeader uint8, payload []byte) *IPv6 {
+	return &IPv6{
+		Version:       6,
+		PayloadLength: uint16(len(payload)),
+		NextHeader:    nextHeader,
+		HopLimit:      64,
+		SrcIP:         src,
+		DstIP:         dst,
+		Payload:       payload,
+	}
+}
+
+func (r IPv6) MarshalBinary() ([]byte, error) {
+	if r.SrcIP == nil || r.DstIP == nil {
+		return nil, errors.New("nil IP address")
+	}
+	if r.FlowLabel > 0xFFFFF {
+		return nil, errors.New("invalid flow label")
+	}
+	srcIP := r.SrcIP.To16()
+	if srcIP == nil || r.SrcIP.To4() != nil {
+		return nil, errors.New("source IP address is not an IPv6 address")
+	}
+	dstIP := r.DstIP.To16()
+	if dstIP == nil || r.DstIP.To4() != nil {
+		return nil, errors.New("destination IP address is not an IPv6 address")
+	}
+
+	v := make([]byte, 40+len(r.Payload))
+	binary.BigEndian.PutUint32(v[0:4], uint32(r.Version&0xF)<<28|uint32(r.TrafficClass)<<20|r.FlowLabel)
+	binary.BigEndian.PutUint16(v[4:6], r.PayloadLength)
+	v[6] = r.NextHeader
+	v[7] = r.HopLimit
+	copy(v[8:24], srcIP)
+	copy(v[24:40], dstIP)
+	copy(v[40:], r.Payload)
+
+	return v, nil
+}
+
+func (r *IPv6) UnmarshalBinary(data []byte) error {
+	if len(data) < 40 {
+		return errors.New("invalid IPv6 packet length")
+	}
+
+	v := binary.BigEndian.Uint32(data[0:4])
+	r.Version = uint8(v >> 28)
+	r.TrafficClass = uint8(v >> 20)
+	r.FlowLabel = v & 0xFFFFF
+	r.PayloadLength = binary.BigEndian.Uint16(data[4:6])
+	r.NextHeader = data[6]
+	r.HopLimit = data[7]
+	r.SrcIP = data[8:24]
+	r.DstIP = data[24:40]
+	r.Payload = data[40:]
+	// Ignore the ethernet padding.
+	if int(r.PayloadLength) < len(r.Payload) {
+		r.Payload = r.Payload[:r.PayloadLength]
+	}
+
+	return nil
+}
@@ -0,0 +1,130 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package protocol
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+)
+
+const (
+	// SlowProtocolsType is the ethernet type of the slow protocols, e.g., LACP.
+	SlowProtocolsType = 0x8809
+	// LACPSubtype is the slow protocols subtype of LACP.
+	LACPSubtype = 0x01
+
+	lacpLength = 110
+)
+
+// LACPInfo is the actor or partner information of a LACPDU.
+type LACPInfo struct {
+	SystemPriority uint16
+	System         net.HardwareAddr
+	Key            uint16
+	PortPriority   uint16
+	Port           uint16
+	State          uint8
+}
+
+func (r LACPInfo) String() string {
+	return fmt.Sprintf("SystemPriority=%v, System=%v, Key=%v, PortPriority=%v, Port=%v, State=%v", r.SystemPriority, r.System, r.Key, r.PortPriority, r.Port, r.State)
+}
+
+func (r LACPInfo) marshal(v []byte, tlvType uint8) {
+	v[0] = tlvType
+	v[1] = 20 // Length
+	binary.BigEndian.PutUint16(v[2:4], r.SystemPriority)
+	copy(v[4:10], r.System)
+	binary.BigEndian.PutUint16(v[10:12], r.Key)
+	binary.BigEndian.PutUint16(v[12:14], r.PortPriority)
+	binary.BigEndian.PutUint16(v[14:16], r.Port)
+	v[16] = r.State
+}
+
+func (r *LACPInfo) unmarshal(data []byte, tlvType uint8) error {
+	if data[0] != tlvType || data[1] != 20 {
+		return fmt.Errorf("invalid LACP information TLV: type=%v, length=%v", data[0], data[1])
+	}
+
+	r.SystemPriority = binary.BigEndian.Uint16(data[2:4])
+	r.System = data[4:10]
+	r.Key = binary.BigEndian.Uint16(data[10:12])
+	r.PortPriority = binary.BigEndian.Uint16(data[12:14])
+	r.Port = binary.BigEndian.Uint16(data[14:16])
+	r.State = data[16]
+
+	return nil
+}
+
+// LACP is a LACPDU (IEEE 802.1AX), which is the payload of a slow protocols frame whose destination
+// is 01:80:C2:00:00:02.
+type LACP struct {
+	Version           uint8
+	Actor             LACPInfo
+	Partner           LACPInfo
+	CollectorMaxDelay uint16
+}
+
+func (r LACP) String() string {
+	return fmt.Sprintf("Version=%v, Actor={%v}, Partner={%v}, CollectorMaxDelay=%v", r.Version, r.Actor, r.Partner, r.CollectorMaxDelay)
+}
+
+func (r LACP) MarshalBinary() ([]byte, error) {
+	if len(r.Actor.System) != 6 || len(r.Partner.System) != 6 {
+		return nil, errors.New("invalid actor or partner system")
+	}
+
+	v := make([]byte, lacpLength)
+	v[0] = LACPSubtype
+	v[1] = r.Version
+	r.Actor.marshal(v[2:22], 1)
+	r.Partner.marshal(v[22:42], 2)
+	// Collector information
+	v[42] = 3
+	v[43] = 16
+	binary.BigEndian.PutUint16(v[44:46], r.CollectorMaxDelay)
+	// The terminator and the reserved octets are zeros.
+
+	return v, nil
+}
+
+func (r *LACP) UnmarshalBinary(data []byte) error {
+	if len(data) < lacpLength {
+		return errors.New("invalid LACP packet length")
+	}
+	if data[0] != LACPSubtype {
+		return fmt.Errorf("not a LACP packet: subtype=%v", data[0])
+	}
+
+	r.Version = data[1]
+	if err := r.Actor.unmarshal(data[2:22], 1); err != nil {
+		return err
+	}
+	if err := r.Partner.unmarshal(data[22:42], 2); err != nil {
+		return err
+	}
+	r.CollectorMaxDelay = binary.BigEndian.Uint16(data[44:46])
+
+	return nil
+}
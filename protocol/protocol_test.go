@@ -0,0 +1,219 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package protocol
+
+import (
+	"bytes"
+	"net"
+	"testing"
+)
+
+func TestEthernetVLAN(t *testing.T) {
+	eth := Ethernet{
+		SrcMAC:       net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55},
+		DstMAC:       net.HardwareAddr{0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		Tagged:       true,
+		VLANPriority: 5,
+		VLANID:       1000,
+		Type:         0x0806,
+		Payload:      []byte{0x01, 0x02},
+	}
+	packet, err := eth.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 802.1Q TPID and TCI (PCP=5, VID=1000), and then the EtherType.
+	if !bytes.Equal(packet[12:18], []byte{0x81, 0x00, 0xa3, 0xe8, 0x08, 0x06}) {
+		t.Fatalf("unexpected 802.1Q header: %x", packet[12:18])
+	}
+
+	decoded := new(Ethernet)
+	if err := decoded.UnmarshalBinary(packet); err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Tagged || decoded.VLANPriority != 5 || decoded.VLANID != 1000 || decoded.Type != 0x0806 || !bytes.Equal(decoded.Payload, eth.Payload) {
+		t.Fatalf("unexpected decoded frame: %+v", decoded)
+	}
+
+	if err := decoded.UnmarshalBinary(packet[:16]); err == nil {
+		t.Fatal("expected an error for a truncated 802.1Q tag")
+	}
+}
+
+func TestIPv6(t *testing.T) {
+	src := net.ParseIP("2001:db8::1")
+	dst := net.ParseIP("ff02::1")
+	ip := NewIPv6(src, dst, 58, []byte{0x80, 0x00, 0x00, 0x00})
+	ip.TrafficClass = 0xb8
+	ip.FlowLabel = 0x12345
+	packet, err := ip.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(packet) != 44 || !bytes.Equal(packet[0:8], []byte{0x6b, 0x81, 0x23, 0x45, 0x00, 0x04, 58, 64}) {
+		t.Fatalf("unexpected IPv6 header: %x", packet)
+	}
+
+	// With the ethernet padding.
+	decoded := new(IPv6)
+	if err := decoded.UnmarshalBinary(append(packet, 0x00, 0x00)); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Version != 6 || decoded.TrafficClass != 0xb8 || decoded.FlowLabel != 0x12345 || decoded.NextHeader != 58 ||
+		!decoded.SrcIP.Equal(src) || !decoded.DstIP.Equal(dst) || len(decoded.Payload) != 4 {
+		t.Fatalf("unexpected decoded packet: %+v", decoded)
+	}
+
+	ip.SrcIP = net.IPv4(10, 0, 0, 1)
+	if _, err := ip.MarshalBinary(); err == nil {
+		t.Fatal("expected an error for an IPv4 source address")
+	}
+}
+
+func TestNeighborDiscovery(t *testing.T) {
+	target := net.ParseIP("2001:db8::2")
+	dst := net.ParseIP("2001:db8::1")
+	mac := net.HardwareAddr{0x0a, 0, 0, 0, 0, 2}
+	na := NewNeighborAdvertisement(target, mac)
+	if _, err := na.MarshalBinary(); err == nil {
+		t.Fatal("expected an error for the missing pseudo header")
+	}
+	na.SetPseudoHeader(target, dst)
+	packet, err := na.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(packet) != 32 || packet[0] != ICMPv6NeighborAdvertisement || packet[4] != 0x60 || packet[24] != 2 || packet[25] != 1 {
+		t.Fatalf("unexpected neighbor advertisement: %x", packet)
+	}
+	// Checksum of the message including the pseudo header is zero.
+	pseudo := make([]byte, 40)
+	copy(pseudo[0:16], target)
+	copy(pseudo[16:32], dst)
+	pseudo[35] = byte(len(packet))
+	pseudo[39] = 58
+	if v := calculateChecksum(append(pseudo, packet...)); v != 0 {
+		t.Fatalf("invalid checksum: %x", packet[2:4])
+	}
+
+	decoded := new(NeighborDiscovery)
+	if err := decoded.UnmarshalBinary(packet); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Router || !decoded.Solicited || !decoded.Override || !decoded.Target.Equal(target) || !bytes.Equal(decoded.LinkAddr, mac) {
+		t.Fatalf("unexpected decoded message: %+v", decoded)
+	}
+
+	// Echo request is not a neighbor discovery message.
+	packet[0] = 128
+	if err := decoded.UnmarshalBinary(packet); err == nil {
+		t.Fatal("expected an error for an echo request")
+	}
+	// Option whose length is zero.
+	packet[0] = ICMPv6NeighborSolicitation
+	packet[25] = 0
+	if err := decoded.UnmarshalBinary(packet); err == nil {
+		t.Fatal("expected an error for the invalid option length")
+	}
+}
+
+func TestDHCP(t *testing.T) {
+	msg := DHCP{
+		Op:     2,
+		HType:  1,
+		HLen:   6,
+		XID:    0xdeadbeef,
+		YIAddr: net.IPv4(10, 0, 0, 10),
+		CHAddr: net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55},
+		Options: []DHCPOption{
+			{Code: DHCPOptionMessageType, Data: []byte{DHCPAck}},
+			{Code: DHCPOptionLeaseTime, Data: []byte{0x00, 0x00, 0x0e, 0x10}},
+		},
+	}
+	packet, err := msg.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// With a pad option before the end option.
+	packet = append(packet[:len(packet)-1], DHCPOptionPad, DHCPOptionEnd)
+	decoded := new(DHCP)
+	if err := decoded.UnmarshalBinary(packet); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.XID != msg.XID || !decoded.YIAddr.Equal(msg.YIAddr) || !bytes.Equal(decoded.CHAddr, msg.CHAddr) || !decoded.CIAddr.IsUnspecified() {
+		t.Fatalf("unexpected decoded message: %+v", decoded)
+	}
+	if decoded.MessageType() != DHCPAck {
+		t.Fatalf("unexpected message type: %v", decoded.MessageType())
+	}
+	if v, ok := decoded.Option(DHCPOptionLeaseTime); !ok || !bytes.Equal(v, []byte{0x00, 0x00, 0x0e, 0x10}) {
+		t.Fatalf("unexpected lease time option: %v", v)
+	}
+
+	// Truncated option.
+	if err := decoded.UnmarshalBinary(packet[:245]); err == nil {
+		t.Fatal("expected an error for a truncated option")
+	}
+}
+
+func TestLACP(t *testing.T) {
+	lacp := LACP{
+		Version: 1,
+		Actor: LACPInfo{
+			SystemPriority: 32768,
+			System:         net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55},
+			Key:            13,
+			PortPriority:   255,
+			Port:           2,
+			State:          0x3D,
+		},
+		Partner: LACPInfo{
+			System: net.HardwareAddr{0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		},
+		CollectorMaxDelay: 10,
+	}
+	packet, err := lacp.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(packet) != 110 || !bytes.Equal(packet[0:4], []byte{0x01, 0x01, 0x01, 0x14}) {
+		t.Fatalf("unexpected LACPDU: %x", packet)
+	}
+
+	decoded := new(LACP)
+	if err := decoded.UnmarshalBinary(packet); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Version != 1 || decoded.Actor.String() != lacp.Actor.String() || decoded.Partner.String() != lacp.Partner.String() || decoded.CollectorMaxDelay != 10 {
+		t.Fatalf("unexpected decoded LACPDU: %v", decoded)
+	}
+
+	// Marker protocol
+	packet[0] = 0x02
+	if err := decoded.UnmarshalBinary(packet); err == nil {
+		t.Fatal("expected an error for a non-LACP subtype")
+	}
+	if err := decoded.UnmarshalBinary(packet[:100]); err == nil {
+		t.Fatal("expected an error for a truncated LACPDU")
+	}
+}
@@ -0,0 +1,152 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+// Package ratelog provides a logger that suppresses repeated log messages to
+// prevent a log storm from hot paths that may fail thousands of times per second.
+package ratelog
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/superkkt/cherry/clock"
+
+	"github.com/hashicorp/golang-lru"
+	"github.com/superkkt/go-logging"
+)
+
+const (
+	// Maximum number of keys that are remembered. The least recently used key is
+	// evicted if there are too many keys, so callers cannot exhaust the memory by
+	// using unbounded keys.
+	maxKeys = 1024
+)
+
+type entry struct {
+	level      logging.Level
+	start      time.Time
+	suppressed uint64
+}
+
+// Logger writes the first message of a key immediately, and then suppresses the
+// following messages of the same key for an interval. The number of suppressed
+// messages is written as a summary when the next message of the key is written
+// after the interval, or when the key is evicted.
+type Logger struct {
+	mutex    sync.Mutex
+	logger   *logging.Logger
+	interval time.Duration
+	keys     *lru.Cache
+	clock    clock.Clock
+}
+
+// New returns a logger for module that writes at most one message per interval for each key.
+func New(module string, interval time.Duration) *Logger {
+	if interval <= 0 {
+		panic("interval should be greater than zero")
+	}
+
+	logger := logging.MustGetLogger(module)
+	// Skip the frames of this package to report the original caller.
+	logger.ExtraCalldepth = 3
+	v := &Logger{
+		logger:   logger,
+		interval: interval,
+		clock:    clock.Real,
+	}
+	keys, err := lru.NewWithEvict(maxKeys, v.onEvicted)
+	if err != nil {
+		panic(fmt.Sprintf("failed to create a LRU cache: %v", err))
+	}
+	v.keys = keys
+
+	return v
+}
+
+func (r *Logger) Errorf(key, format string, args ...interface{}) {
+	r.log(logging.ERROR, key, format, args...)
+}
+
+func (r *Logger) Warningf(key, format string, args ...interface{}) {
+	r.log(logging.WARNING, key, format, args...)
+}
+
+func (r *Logger) Infof(key, format string, args ...interface{}) {
+	r.log(logging.INFO, key, format, args...)
+}
+
+func (r *Logger) log(level logging.Level, key, format string, args ...interface{}) {
+	if !r.logger.IsEnabledFor(level) {
+		return
+	}
+
+	summary, ok := r.check(level, key)
+	if !ok {
+		return
+	}
+	if len(summary) > 0 {
+		r.write(level, summary)
+	}
+	r.write(level, fmt.Sprintf(format, args...))
+}
+
+// check returns whether a message of key should be written. summary is not empty if
+// there are messages of key that have been suppressed during the last interval.
+func (r *Logger) check(level logging.Level, key string) (summary string, ok bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := r.clock.Now()
+	if v, found := r.keys.Get(key); found {
+		e := v.(*entry)
+		if now.Sub(e.start) < r.interval {
+			e.suppressed++
+			return "", false
+		}
+		if e.suppressed > 0 {
+			summary = fmt.Sprintf("message repeated %v times in the last %v: %v", e.suppressed, now.Sub(e.start), key)
+		}
+	}
+	r.keys.Add(key, &entry{level: level, start: now})
+
+	return summary, true
+}
+
+// onEvicted is called by the LRU cache while the mutex is locked.
+func (r *Logger) onEvicted(key interface{}, value interface{}) {
+	e := value.(*entry)
+	if e.suppressed == 0 {
+		return
+	}
+	r.write(e.level, fmt.Sprintf("message repeated %v times since %v: %v", e.suppressed, e.start, key))
+}
+
+func (r *Logger) write(level logging.Level, msg string) {
+	switch level {
+	case logging.ERROR:
+		r.logger.Error(msg)
+	case logging.WARNING:
+		r.logger.Warning(msg)
+	default:
+		r.logger.Info(msg)
+	}
+}
@@ -0,0 +1,110 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package ratelog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/superkkt/cherry/testutil"
+
+	"github.com/superkkt/go-logging"
+)
+
+func newTestLogger(interval time.Duration) (*Logger, *logging.MemoryBackend, *testutil.FakeClock) {
+	backend := logging.NewMemoryBackend(2048)
+	logger := New("test", interval)
+	logger.logger.SetBackend(logging.AddModuleLevel(backend))
+	clock := testutil.NewFakeClock(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))
+	logger.clock = clock
+
+	return logger, backend, clock
+}
+
+func messages(backend *logging.MemoryBackend) []string {
+	v := make([]string, 0)
+	for n := backend.Head(); n != nil; n = n.Next() {
+		v = append(v, n.Record.Message())
+	}
+
+	return v
+}
+
+func TestSuppression(t *testing.T) {
+	logger, backend, clock := newTestLogger(10 * time.Second)
+
+	for i := 0; i < 100; i++ {
+		logger.Errorf("decode", "failed to decode: %v", i)
+	}
+	// Another key is not suppressed by the first one.
+	logger.Errorf("write", "failed to write")
+	if v := messages(backend); len(v) != 2 || v[0] != "failed to decode: 0" || v[1] != "failed to write" {
+		t.Fatalf("unexpected messages: %v", v)
+	}
+
+	clock.Advance(9 * time.Second)
+	logger.Errorf("decode", "failed to decode: %v", 100)
+	if v := messages(backend); len(v) != 2 {
+		t.Fatalf("unexpected messages: %v", v)
+	}
+
+	clock.Advance(1 * time.Second)
+	logger.Errorf("decode", "failed to decode: %v", 101)
+	v := messages(backend)
+	if len(v) != 4 {
+		t.Fatalf("unexpected messages: %v", v)
+	}
+	if expected := "message repeated 100 times in the last 10s: decode"; v[2] != expected {
+		t.Fatalf("unexpected summary: expected=%q, got=%q", expected, v[2])
+	}
+	if v[3] != "failed to decode: 101" {
+		t.Fatalf("unexpected message: %v", v[3])
+	}
+
+	// No summary if nothing has been suppressed.
+	clock.Advance(10 * time.Second)
+	logger.Errorf("decode", "failed to decode: %v", 102)
+	if v := messages(backend); len(v) != 5 || v[4] != "failed to decode: 102" {
+		t.Fatalf("unexpected messages: %v", v)
+	}
+}
+
+func TestBoundedKeys(t *testing.T) {
+	logger, backend, _ := newTestLogger(time.Minute)
+
+	logger.Warningf("key", "first")
+	logger.Warningf("key", "suppressed")
+	for i := 0; i < maxKeys; i++ {
+		logger.Warningf(time.Duration(i).String(), "flood")
+	}
+	if n := logger.keys.Len(); n != maxKeys {
+		t.Fatalf("unexpected number of keys: expected=%v, got=%v", maxKeys, n)
+	}
+	// The summary of the evicted key should be written.
+	v := messages(backend)
+	if len(v) != maxKeys+2 {
+		t.Fatalf("unexpected number of messages: expected=%v, got=%v", maxKeys+2, len(v))
+	}
+	if expected := "message repeated 1 times since 2018-01-01 00:00:00 +0000 UTC: key"; v[maxKeys] != expected {
+		t.Fatalf("unexpected summary: expected=%q, got=%q", expected, v[maxKeys])
+	}
+}
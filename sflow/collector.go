@@ -0,0 +1,69 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package sflow
+
+import (
+	"context"
+	"net"
+
+	"github.com/superkkt/go-logging"
+)
+
+var (
+	logger = logging.MustGetLogger("sflow")
+)
+
+// Maximum size of a UDP datagram.
+const maxDatagramSize = 65535
+
+// Serve reads the datagrams from conn and passes the decoded ones to handler until ctx is done
+// or conn is closed. The datagrams that cannot be decoded are dropped. conn is closed when
+// ctx is done.
+func Serve(ctx context.Context, conn net.PacketConn, handler func(Datagram)) error {
+	if handler == nil {
+		panic("handler is nil")
+	}
+
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	buf := make([]byte, maxDatagramSize)
+	// Infinite loop.
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+
+		v, err := Decode(buf[:n])
+		if err != nil {
+			logger.Debugf("dropped the sFlow datagram from %v: %v", addr, err)
+			continue
+		}
+		handler(v)
+	}
+}
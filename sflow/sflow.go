@@ -0,0 +1,247 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+// Package sflow decodes the flow samples of sFlow version 5 datagrams (https://sflow.org/sflow_version_5.txt)
+// exported by switches, and collects them from a UDP socket. Counter samples and the flow records
+// other than the raw packet headers are skipped.
+package sflow
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+)
+
+const (
+	// Sample formats of the standard enterprise.
+	formatFlowSample         = 1
+	formatExpandedFlowSample = 3
+	// Flow record format of the raw packet header.
+	formatRawPacketHeader = 1
+)
+
+const (
+	// HeaderProtocolEthernet is the header protocol of the Ethernet frames.
+	HeaderProtocolEthernet = 1
+	// UnknownInterface is the interface value of the packets that come from or go to an unknown,
+	// or an internal, interface of the agent.
+	UnknownInterface = 0x3FFFFFFF
+)
+
+var (
+	ErrShortDatagram = errors.New("short sFlow datagram")
+)
+
+// Datagram is a sFlow version 5 datagram.
+type Datagram struct {
+	// IP address of the agent, i.e., the switch, that has sent this datagram.
+	AgentAddress   net.IP
+	SubAgentID     uint32
+	SequenceNumber uint32
+	// Uptime of the agent in milliseconds.
+	Uptime  uint32
+	Samples []FlowSample
+}
+
+// FlowSample is a packet sampled by an agent.
+type FlowSample struct {
+	SequenceNumber uint32
+	// One packet is sampled for every SamplingRate packets.
+	SamplingRate uint32
+	// Total number of packets that could have been sampled.
+	SamplePool uint32
+	// Number of the packets dropped due to lack of resources.
+	Drops uint32
+	// Interface indexes of the input and output ports. UnknownInterface if unknown. Output is also
+	// UnknownInterface if the packet has been discarded or sent to multiple interfaces.
+	Input, Output uint32
+	// Raw packet header. nil if the sample has no raw packet header record.
+	Header *PacketHeader
+}
+
+// PacketHeader is the leading bytes of a sampled packet.
+type PacketHeader struct {
+	Protocol uint32
+	// Length of the original frame, including the FCS.
+	FrameLength uint32
+	// Number of bytes removed from the frame before the header is sampled, e.g., FCS.
+	Stripped uint32
+	Header   []byte
+}
+
+// reader reads XDR-encoded values from a byte slice.
+type reader struct {
+	data []byte
+	err  error
+}
+
+func (r *reader) uint32() uint32 {
+	if r.err != nil {
+		return 0
+	}
+	if len(r.data) < 4 {
+		r.err = ErrShortDatagram
+		return 0
+	}
+	v := binary.BigEndian.Uint32(r.data[0:4])
+	r.data = r.data[4:]
+
+	return v
+}
+
+// bytes returns the next n bytes, skipping the padding to the 4-byte boundary.
+func (r *reader) bytes(n uint32) []byte {
+	if r.err != nil {
+		return nil
+	}
+	padded := (uint64(n) + 3) &^ 3
+	if uint64(len(r.data)) < padded {
+		r.err = ErrShortDatagram
+		return nil
+	}
+	v := r.data[:n]
+	r.data = r.data[padded:]
+
+	return v
+}
+
+// Decode decodes a sFlow version 5 datagram.
+func Decode(data []byte) (Datagram, error) {
+	r := &reader{data: data}
+	if version := r.uint32(); r.err == nil && version != 5 {
+		return Datagram{}, fmt.Errorf("unsupported sFlow version: %v", version)
+	}
+
+	v := Datagram{}
+	switch t := r.uint32(); t {
+	case 1:
+		v.AgentAddress = net.IP(append([]byte(nil), r.bytes(net.IPv4len)...))
+	case 2:
+		v.AgentAddress = net.IP(append([]byte(nil), r.bytes(net.IPv6len)...))
+	default:
+		if r.err == nil {
+			return Datagram{}, fmt.Errorf("unknown sFlow agent address type: %v", t)
+		}
+	}
+	v.SubAgentID = r.uint32()
+	v.SequenceNumber = r.uint32()
+	v.Uptime = r.uint32()
+	n := r.uint32()
+	if r.err != nil {
+		return Datagram{}, r.err
+	}
+
+	for i := uint32(0); i < n; i++ {
+		format := r.uint32()
+		body := r.bytes(r.uint32())
+		if r.err != nil {
+			return Datagram{}, r.err
+		}
+		// Skip the samples of the other enterprises and the counter samples.
+		if format != formatFlowSample && format != formatExpandedFlowSample {
+			continue
+		}
+		sample, err := decodeFlowSample(body, format == formatExpandedFlowSample)
+		if err != nil {
+			return Datagram{}, err
+		}
+		v.Samples = append(v.Samples, sample)
+	}
+
+	return v, nil
+}
+
+func decodeFlowSample(data []byte, expanded bool) (FlowSample, error) {
+	r := &reader{data: data}
+	v := FlowSample{}
+	v.SequenceNumber = r.uint32()
+	// Source ID of the data source, which is one or two words.
+	r.uint32()
+	if expanded {
+		r.uint32()
+	}
+	v.SamplingRate = r.uint32()
+	v.SamplePool = r.uint32()
+	v.Drops = r.uint32()
+	if expanded {
+		v.Input = expandedInterface(r.uint32(), r.uint32())
+		v.Output = expandedInterface(r.uint32(), r.uint32())
+	} else {
+		v.Input = compactInterface(r.uint32())
+		v.Output = compactInterface(r.uint32())
+	}
+	n := r.uint32()
+	if r.err != nil {
+		return FlowSample{}, r.err
+	}
+
+	for i := uint32(0); i < n; i++ {
+		format := r.uint32()
+		body := r.bytes(r.uint32())
+		if r.err != nil {
+			return FlowSample{}, r.err
+		}
+		if format != formatRawPacketHeader {
+			continue
+		}
+		header, err := decodePacketHeader(body)
+		if err != nil {
+			return FlowSample{}, err
+		}
+		v.Header = header
+	}
+
+	return v, nil
+}
+
+// compactInterface returns the interface index of the compact interface format, whose two most
+// significant bits are the format of the remaining bits.
+func compactInterface(v uint32) uint32 {
+	if v>>30 != 0 {
+		// Discarded packet, or multiple interfaces.
+		return UnknownInterface
+	}
+
+	return v
+}
+
+func expandedInterface(format, value uint32) uint32 {
+	if format != 0 {
+		return UnknownInterface
+	}
+
+	return value
+}
+
+func decodePacketHeader(data []byte) (*PacketHeader, error) {
+	r := &reader{data: data}
+	v := &PacketHeader{}
+	v.Protocol = r.uint32()
+	v.FrameLength = r.uint32()
+	v.Stripped = r.uint32()
+	v.Header = append([]byte(nil), r.bytes(r.uint32())...)
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	return v, nil
+}
@@ -0,0 +1,193 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package sflow
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+)
+
+type encoder struct {
+	bytes.Buffer
+}
+
+func (r *encoder) uint32(values ...uint32) {
+	for _, v := range values {
+		binary.Write(&r.Buffer, binary.BigEndian, v)
+	}
+}
+
+// opaque writes the length of data, data, and the padding to the 4-byte boundary.
+func (r *encoder) opaque(data []byte) {
+	r.uint32(uint32(len(data)))
+	r.Write(data)
+	r.Write(make([]byte, (4-len(data)%4)%4))
+}
+
+func newHeaderRecord(frame []byte) []byte {
+	e := new(encoder)
+	// Ethernet, frame length, stripped.
+	e.uint32(HeaderProtocolEthernet, uint32(len(frame)+4), 4)
+	e.opaque(frame)
+
+	return e.Bytes()
+}
+
+func newTestDatagram() []byte {
+	frame := []byte{
+		0x0a, 0, 0, 0, 0, 2, // Destination MAC
+		0x0a, 0, 0, 0, 0, 1, // Source MAC
+		0x08, 0x00, // IPv4
+		0x45, 0x00, 0x00, 0x54, 0x00, // Truncated IPv4 header
+	}
+
+	flow := new(encoder)
+	// Sequence, source ID, sampling rate, sample pool, drops, input, output.
+	flow.uint32(7, 3, 512, 1024, 0, 3, 0x3FFFFFFF)
+	// Two records: an extended switch record that is skipped, and the raw packet header.
+	flow.uint32(2)
+	flow.uint32(1001)
+	flow.opaque(make([]byte, 16))
+	flow.uint32(formatRawPacketHeader)
+	flow.opaque(newHeaderRecord(frame))
+
+	expanded := new(encoder)
+	// Sequence, source ID type and index, sampling rate, sample pool, drops.
+	expanded.uint32(8, 0, 5, 256, 2048, 1)
+	// Input and output in the expanded format.
+	expanded.uint32(0, 5, 0, 6)
+	expanded.uint32(0)
+
+	d := new(encoder)
+	d.uint32(5, 1)
+	d.Write([]byte{192, 168, 0, 1})
+	// Sub-agent ID, sequence, uptime, and the number of samples.
+	d.uint32(0, 100, 60000, 3)
+	d.uint32(formatFlowSample)
+	d.opaque(flow.Bytes())
+	// Counter sample is skipped.
+	d.uint32(2)
+	d.opaque(make([]byte, 8))
+	d.uint32(formatExpandedFlowSample)
+	d.opaque(expanded.Bytes())
+
+	return d.Bytes()
+}
+
+func TestDecode(t *testing.T) {
+	v, err := Decode(newTestDatagram())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !v.AgentAddress.Equal(net.IPv4(192, 168, 0, 1)) {
+		t.Fatalf("unexpected agent address: %v", v.AgentAddress)
+	}
+	if v.SequenceNumber != 100 || v.Uptime != 60000 {
+		t.Fatalf("unexpected datagram: %+v", v)
+	}
+	if len(v.Samples) != 2 {
+		t.Fatalf("expected 2 flow samples, got %v", len(v.Samples))
+	}
+
+	s := v.Samples[0]
+	if s.SamplingRate != 512 || s.Input != 3 || s.Output != UnknownInterface {
+		t.Fatalf("unexpected flow sample: %+v", s)
+	}
+	if s.Header == nil {
+		t.Fatal("no packet header")
+	}
+	if s.Header.Protocol != HeaderProtocolEthernet || s.Header.FrameLength != 23 || len(s.Header.Header) != 19 {
+		t.Fatalf("unexpected packet header: %+v", s.Header)
+	}
+
+	s = v.Samples[1]
+	if s.SamplingRate != 256 || s.Drops != 1 || s.Input != 5 || s.Output != 6 || s.Header != nil {
+		t.Fatalf("unexpected expanded flow sample: %+v", s)
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	data := newTestDatagram()
+	for _, n := range []int{0, 4, 20, 40, len(data) - 1} {
+		if _, err := Decode(data[:n]); err == nil {
+			t.Fatalf("expected an error for %v bytes", n)
+		}
+	}
+
+	invalid := append([]byte(nil), data...)
+	// Version 4.
+	invalid[3] = 4
+	if _, err := Decode(invalid); err == nil {
+		t.Fatal("expected an error for the unsupported version")
+	}
+}
+
+func TestServe(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c := make(chan Datagram, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- Serve(ctx, conn, func(d Datagram) { c <- d })
+	}()
+
+	client, err := net.Dial("udp", conn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	// Invalid datagram is dropped.
+	if _, err := client.Write([]byte{0, 0, 0, 5}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Write(newTestDatagram()); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case d := <-c:
+		if d.SequenceNumber != 100 {
+			t.Fatalf("unexpected datagram: %+v", d)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the datagram")
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve did not return")
+	}
+}
@@ -0,0 +1,180 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+// Package testutil provides helpers for the tests of the other packages.
+package testutil
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/superkkt/cherry/clock"
+)
+
+// FakeClock is a clock.Clock whose time only moves when Advance is called. Timers and
+// tickers fire synchronously inside Advance in the order of their deadlines, so tests
+// do not need to sleep. Like the time package, a tick is dropped if the previous one
+// has not been received yet.
+type FakeClock struct {
+	mutex  sync.Mutex
+	now    time.Time
+	timers map[*fakeTimer]struct{}
+	// changed is closed and replaced whenever a timer is added.
+	changed chan struct{}
+}
+
+// NewFakeClock returns a fake clock whose current time is now.
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{
+		now:     now,
+		timers:  make(map[*fakeTimer]struct{}),
+		changed: make(chan struct{}),
+	}
+}
+
+func (r *FakeClock) Now() time.Time {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.now
+}
+
+func (r *FakeClock) Since(t time.Time) time.Duration {
+	return r.Now().Sub(t)
+}
+
+func (r *FakeClock) After(d time.Duration) <-chan time.Time {
+	return r.NewTimer(d).C()
+}
+
+func (r *FakeClock) NewTimer(d time.Duration) clock.Timer {
+	t := &fakeTimer{clock: r, c: make(chan time.Time, 1)}
+	t.Reset(d)
+
+	return t
+}
+
+func (r *FakeClock) NewTicker(d time.Duration) clock.Ticker {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+
+	t := &fakeTimer{clock: r, c: make(chan time.Time, 1), period: d}
+	t.Reset(d)
+
+	return &fakeTicker{t}
+}
+
+// Advance moves the clock forward by d and fires the timers and tickers whose
+// deadlines have been reached.
+func (r *FakeClock) Advance(d time.Duration) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	target := r.now.Add(d)
+	for {
+		expired := make([]*fakeTimer, 0)
+		for t := range r.timers {
+			if !t.deadline.After(target) {
+				expired = append(expired, t)
+			}
+		}
+		if len(expired) == 0 {
+			break
+		}
+		sort.Slice(expired, func(i, j int) bool { return expired[i].deadline.Before(expired[j].deadline) })
+
+		// Fire the earliest one, which may reschedule itself if it is a ticker.
+		t := expired[0]
+		if t.deadline.After(r.now) {
+			r.now = t.deadline
+		}
+		select {
+		case t.c <- r.now:
+		default:
+		}
+		if t.period > 0 {
+			t.deadline = t.deadline.Add(t.period)
+		} else {
+			delete(r.timers, t)
+		}
+	}
+	r.now = target
+}
+
+// WaitTimers blocks until at least n timers and tickers are active. It helps a test
+// wait for a goroutine to create its timer before advancing the clock.
+func (r *FakeClock) WaitTimers(n int) {
+	for {
+		r.mutex.Lock()
+		count, changed := len(r.timers), r.changed
+		r.mutex.Unlock()
+
+		if count >= n {
+			return
+		}
+		<-changed
+	}
+}
+
+type fakeTimer struct {
+	clock    *FakeClock
+	c        chan time.Time
+	deadline time.Time
+	// Zero period means a single event timer.
+	period time.Duration
+}
+
+func (r *fakeTimer) C() <-chan time.Time {
+	return r.c
+}
+
+func (r *fakeTimer) Reset(d time.Duration) bool {
+	r.clock.mutex.Lock()
+	defer r.clock.mutex.Unlock()
+
+	_, active := r.clock.timers[r]
+	r.deadline = r.clock.now.Add(d)
+	r.clock.timers[r] = struct{}{}
+	close(r.clock.changed)
+	r.clock.changed = make(chan struct{})
+
+	return active
+}
+
+func (r *fakeTimer) Stop() bool {
+	r.clock.mutex.Lock()
+	defer r.clock.mutex.Unlock()
+
+	_, active := r.clock.timers[r]
+	delete(r.clock.timers, r)
+
+	return active
+}
+
+type fakeTicker struct {
+	*fakeTimer
+}
+
+func (r *fakeTicker) Stop() {
+	r.fakeTimer.Stop()
+}
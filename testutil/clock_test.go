@@ -0,0 +1,71 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package testutil
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(start)
+	timer := clock.NewTimer(3 * time.Second)
+	ticker := clock.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+
+	clock.Advance(1 * time.Second)
+	select {
+	case <-timer.C():
+		t.Fatalf("timer fired too early")
+	case <-ticker.C():
+		t.Fatalf("ticker fired too early")
+	default:
+	}
+
+	clock.Advance(2 * time.Second)
+	if v := <-ticker.C(); !v.Equal(start.Add(2 * time.Second)) {
+		t.Fatalf("unexpected tick: %v", v)
+	}
+	if v := <-timer.C(); !v.Equal(start.Add(3 * time.Second)) {
+		t.Fatalf("unexpected timer event: %v", v)
+	}
+	if timer.Stop() {
+		t.Fatalf("fired timer is still active")
+	}
+
+	// Ticks are dropped if nobody receives them.
+	clock.Advance(10 * time.Second)
+	if v := <-ticker.C(); !v.Equal(start.Add(4 * time.Second)) {
+		t.Fatalf("unexpected tick: %v", v)
+	}
+	if !clock.Now().Equal(start.Add(13 * time.Second)) {
+		t.Fatalf("unexpected now: %v", clock.Now())
+	}
+
+	// A stopped timer can be reused.
+	if timer.Reset(time.Second) {
+		t.Fatalf("reset reports an inactive timer as active")
+	}
+	clock.Advance(time.Second)
+	<-timer.C()
+}
@@ -0,0 +1,229 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package ofswitch
+
+import (
+	"encoding/binary"
+	"sort"
+
+	"github.com/superkkt/cherry/openflow"
+	"github.com/superkkt/cherry/openflow/of13"
+)
+
+// parseOXM returns the OXM fields of match, which starts with the ofp_match header, in the
+// sorted order, and the length of match including the padding.
+func parseOXM(match []byte) (fields []string, length int, err error) {
+	if len(match) < 4 {
+		return nil, 0, openflow.ErrInvalidPacketLength
+	}
+	n := int(binary.BigEndian.Uint16(match[2:4]))
+	length = (n + 7) / 8 * 8
+	if n < 4 || len(match) < length {
+		return nil, 0, openflow.ErrInvalidPacketLength
+	}
+
+	fields = make([]string, 0)
+	for oxm := match[4:n]; len(oxm) > 0; {
+		if len(oxm) < 4 || len(oxm) < 4+int(oxm[3]) {
+			return nil, 0, openflow.ErrInvalidPacketLength
+		}
+		fields = append(fields, string(oxm[:4+int(oxm[3])]))
+		oxm = oxm[4+int(oxm[3]):]
+	}
+	sort.Strings(fields)
+
+	return fields, length, nil
+}
+
+// covers returns whether all the fields of filter are also the fields of r, i.e., r is
+// selected by a non-strict FLOW_MOD whose match is filter.
+func (r Flow) covers(filter []string) bool {
+	fields := make(map[string]bool)
+	for _, v := range r.fields {
+		fields[v] = true
+	}
+	for _, v := range filter {
+		if !fields[v] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (r Flow) equals(tableID uint8, priority uint16, fields []string) bool {
+	if r.TableID != tableID || r.Priority != priority || len(r.fields) != len(fields) {
+		return false
+	}
+	for i := range fields {
+		if r.fields[i] != fields[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+type flowMod struct {
+	Flow
+	command    uint8
+	cookieMask uint64
+}
+
+func parseFlowMod(packet []byte) (flowMod, error) {
+	payload := packet[headerLength:]
+	if len(payload) < 40 {
+		return flowMod{}, openflow.ErrInvalidPacketLength
+	}
+	fields, n, err := parseOXM(payload[40:])
+	if err != nil {
+		return flowMod{}, err
+	}
+	rawMatch := append([]byte(nil), payload[40:40+n]...)
+	match := of13.NewMatch()
+	if err := match.UnmarshalBinary(rawMatch); err != nil {
+		return flowMod{}, err
+	}
+
+	return flowMod{
+		Flow: Flow{
+			Cookie:       binary.BigEndian.Uint64(payload[0:8]),
+			TableID:      payload[16],
+			IdleTimeout:  binary.BigEndian.Uint16(payload[18:20]),
+			HardTimeout:  binary.BigEndian.Uint16(payload[20:22]),
+			Priority:     binary.BigEndian.Uint16(payload[22:24]),
+			Flags:        binary.BigEndian.Uint16(payload[36:38]),
+			Match:        match,
+			Instructions: append([]byte(nil), payload[40+n:]...),
+			rawMatch:     rawMatch,
+			fields:       fields,
+		},
+		command:    payload[17],
+		cookieMask: binary.BigEndian.Uint64(payload[8:16]),
+	}, nil
+}
+
+// selects returns whether the modify or delete command r selects flow. The output port and
+// group of the command are ignored.
+func (r flowMod) selects(flow Flow) bool {
+	if r.TableID != tableAll && r.TableID != flow.TableID {
+		return false
+	}
+	if r.Cookie&r.cookieMask != flow.Cookie&r.cookieMask {
+		return false
+	}
+	if r.command == of13.OFPFC_MODIFY_STRICT || r.command == of13.OFPFC_DELETE_STRICT {
+		return flow.equals(flow.TableID, r.Priority, r.fields)
+	}
+
+	return flow.covers(r.fields)
+}
+
+func (r *Switch) handleFlowMod(xid uint32, packet []byte) error {
+	if r.config.DiscardFlows {
+		return nil
+	}
+	mod, err := parseFlowMod(packet)
+	if err != nil {
+		return r.sendError(xid, packet)
+	}
+
+	r.mutex.Lock()
+	removed := make([]Flow, 0)
+	switch mod.command {
+	case of13.OFPFC_ADD:
+		replaced := false
+		for i, v := range r.flows {
+			if v.equals(mod.TableID, mod.Priority, mod.fields) {
+				r.flows[i] = mod.Flow
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			r.flows = append(r.flows, mod.Flow)
+		}
+	case of13.OFPFC_MODIFY, of13.OFPFC_MODIFY_STRICT:
+		for i, v := range r.flows {
+			if mod.selects(v) {
+				r.flows[i].Instructions = mod.Instructions
+			}
+		}
+	case of13.OFPFC_DELETE, of13.OFPFC_DELETE_STRICT:
+		flows := r.flows[:0]
+		for _, v := range r.flows {
+			if mod.selects(v) {
+				removed = append(removed, v)
+				continue
+			}
+			flows = append(flows, v)
+		}
+		r.flows = flows
+	default:
+		r.mutex.Unlock()
+		return r.sendError(xid, packet)
+	}
+	r.mutex.Unlock()
+
+	return r.sendFlowRemoved(removed, openflow.FlowRemovedDelete)
+}
+
+// Expire removes the flows selected by f, and sends FLOW_REMOVEDs of reason, one of the
+// openflow.FlowRemoved* reasons, for the removed flows that have the OFPFF_SEND_FLOW_REM flag.
+func (r *Switch) Expire(f func(Flow) bool, reason uint8) error {
+	r.mutex.Lock()
+	removed := make([]Flow, 0)
+	flows := r.flows[:0]
+	for _, v := range r.flows {
+		if f(v) {
+			removed = append(removed, v)
+			continue
+		}
+		flows = append(flows, v)
+	}
+	r.flows = flows
+	r.mutex.Unlock()
+
+	return r.sendFlowRemoved(removed, reason)
+}
+
+func (r *Switch) sendFlowRemoved(flows []Flow, reason uint8) error {
+	for _, flow := range flows {
+		if flow.Flags&of13.OFPFF_SEND_FLOW_REM == 0 {
+			continue
+		}
+		v := make([]byte, 40, 40+len(flow.rawMatch))
+		binary.BigEndian.PutUint64(v[0:8], flow.Cookie)
+		binary.BigEndian.PutUint16(v[8:10], flow.Priority)
+		v[10] = reason
+		v[11] = flow.TableID
+		// v[12:20] is the duration, and v[24:40] is the packet and byte counters.
+		binary.BigEndian.PutUint16(v[20:22], flow.IdleTimeout)
+		binary.BigEndian.PutUint16(v[22:24], flow.HardTimeout)
+		v = append(v, flow.rawMatch...)
+		if err := r.send(of13.OFPT_FLOW_REMOVED, r.newTransactionID(), v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,117 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package ofswitch
+
+import (
+	"encoding/binary"
+
+	"github.com/superkkt/cherry/openflow/of13"
+)
+
+func (r *Switch) handleMultipart(xid uint32, packet []byte) error {
+	payload := packet[headerLength:]
+	if len(payload) < 8 {
+		return r.sendError(xid, packet)
+	}
+	mpType := binary.BigEndian.Uint16(payload[0:2])
+
+	var body []byte
+	switch mpType {
+	case of13.OFPMP_DESC:
+		body = r.desc()
+	case of13.OFPMP_FLOW:
+		body = r.flowStats()
+	case of13.OFPMP_PORT_STATS:
+		body = r.portStats()
+	case of13.OFPMP_PORT_DESC:
+		body = r.portDesc()
+	default:
+		// Empty reply for the others, such as the table features and the queue statistics.
+	}
+
+	v := make([]byte, 8, 8+len(body))
+	binary.BigEndian.PutUint16(v[0:2], mpType)
+	// v[2:4] is flags, and the reply is never split.
+	v = append(v, body...)
+
+	return r.send(of13.OFPT_MULTIPART_REPLY, xid, v)
+}
+
+func (r *Switch) desc() []byte {
+	v := make([]byte, 1056)
+	copy(v[0:255], r.config.Manufacturer)
+	copy(v[256:511], r.config.Hardware)
+	copy(v[512:767], r.config.Software)
+	copy(v[768:799], r.config.Serial)
+	copy(v[800:1055], r.config.Description)
+
+	return v
+}
+
+func (r *Switch) flowStats() []byte {
+	body := make([]byte, 0)
+	for _, flow := range r.Flows() {
+		length := 48 + len(flow.rawMatch) + len(flow.Instructions)
+		v := make([]byte, 48, length)
+		binary.BigEndian.PutUint16(v[0:2], uint16(length))
+		v[2] = flow.TableID
+		// v[4:12] is the duration.
+		binary.BigEndian.PutUint16(v[12:14], flow.Priority)
+		binary.BigEndian.PutUint16(v[14:16], flow.IdleTimeout)
+		binary.BigEndian.PutUint16(v[16:18], flow.HardTimeout)
+		binary.BigEndian.PutUint16(v[18:20], flow.Flags)
+		binary.BigEndian.PutUint64(v[24:32], flow.Cookie)
+		// v[32:48] is the packet and byte counters.
+		v = append(v, flow.rawMatch...)
+		v = append(v, flow.Instructions...)
+		body = append(body, v...)
+	}
+
+	return body
+}
+
+func (r *Switch) portStats() []byte {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	body := make([]byte, 0)
+	for _, p := range r.ports {
+		// All the counters are zero.
+		v := make([]byte, 112)
+		binary.BigEndian.PutUint32(v[0:4], p.Number)
+		body = append(body, v...)
+	}
+
+	return body
+}
+
+func (r *Switch) portDesc() []byte {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	body := make([]byte, 0)
+	for _, p := range r.ports {
+		body = append(body, encodePort(p)...)
+	}
+
+	return body
+}
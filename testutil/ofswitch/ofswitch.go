@@ -0,0 +1,482 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+// Package ofswitch provides a fake OpenFlow 1.3 switch that runs in the process of a test, so
+// that the device layer and the applications can be tested without Open vSwitch or Mininet.
+// The switch completes the handshake, replies to the echo, barrier, role and multipart requests,
+// keeps the flows added by FLOW_MODs, records PACKET_OUTs, and sends the asynchronous messages,
+// such as PACKET_INs, on demand. It does not forward any packet.
+package ofswitch
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sort"
+	"sync"
+
+	"github.com/superkkt/cherry/openflow"
+	"github.com/superkkt/cherry/openflow/of13"
+)
+
+const (
+	headerLength = 8
+	// Table ID that means all the tables.
+	tableAll = 0xFF
+	// Bad request codes of the ERROR message.
+	badRequestBadType = 1
+	// OXM class and field of the ingress port.
+	oxmClassOpenFlowBasic = 0x8000
+	oxmFieldInPort        = 0
+	// Current speed of the ports in kbps.
+	portSpeed = 1000000
+)
+
+// Port is a port of the switch.
+type Port struct {
+	Number uint32
+	Name   string
+	MAC    net.HardwareAddr
+	// LinkDown is true if no physical link is present.
+	LinkDown bool
+}
+
+type Config struct {
+	DPID uint64
+	// Number of the flow tables. Default is 254.
+	NumTables uint8
+	Ports     []Port
+	// Descriptions of the switch reported by the DESC multipart reply.
+	Manufacturer string
+	Hardware     string
+	Software     string
+	Serial       string
+	Description  string
+	// DiscardFlows makes the switch ignore the FLOW_MODs instead of keeping the flows, which
+	// is useful for the benchmarks that send a huge number of flows.
+	DiscardFlows bool
+	// OnMessage, if it is not nil, is called with the type of each message from the controller
+	// after the message is handled. It is called in the goroutine of Serve, so it should not block.
+	OnMessage func(msgType uint8)
+}
+
+// Flow is a flow entry added by a FLOW_MOD.
+type Flow struct {
+	TableID     uint8
+	Priority    uint16
+	Cookie      uint64
+	IdleTimeout uint16
+	HardTimeout uint16
+	Flags       uint16
+	Match       openflow.Match
+	// Raw instructions of the FLOW_MOD.
+	Instructions []byte
+	// Raw match of the FLOW_MOD, and its OXM fields in the sorted order.
+	rawMatch []byte
+	fields   []string
+}
+
+// PacketOut is a PACKET_OUT received by the switch.
+type PacketOut struct {
+	BufferID uint32
+	InPort   uint32
+	// Raw actions of the PACKET_OUT.
+	Actions []byte
+	Data    []byte
+}
+
+// PacketIn is a PACKET_IN sent to the controller.
+type PacketIn struct {
+	InPort  uint32
+	TableID uint8
+	// One of the OFPR_* reasons.
+	Reason uint8
+	Cookie uint64
+	Data   []byte
+}
+
+type Switch struct {
+	config Config
+
+	// Serializes the messages written to the connection.
+	writeMutex sync.Mutex
+	writer     io.Writer
+
+	mutex        sync.Mutex
+	ports        []Port
+	flows        []Flow
+	packetOuts   []PacketOut
+	missSendLen  uint16
+	role         uint32
+	generationID uint64
+	// Number of the received messages of each OFPT_* type.
+	received map[uint8]int
+	xid      uint32
+}
+
+var (
+	ErrNotConnected = errors.New("switch is not connected")
+	ErrUnknownPort  = errors.New("unknown port")
+)
+
+func New(conf Config) *Switch {
+	if conf.NumTables == 0 {
+		conf.NumTables = 254
+	}
+
+	return &Switch{
+		config:      conf,
+		ports:       append([]Port(nil), conf.Ports...),
+		missSendLen: 128,
+		role:        of13.OFPCR_ROLE_EQUAL,
+		received:    make(map[uint8]int),
+	}
+}
+
+// Dial connects to the controller listening on addr, and then serves the connection until it is
+// closed.
+func (r *Switch) Dial(addr string) error {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return r.Serve(conn)
+}
+
+// Serve sends HELLO to the controller connected by conn, and then handles the messages from
+// the controller until conn is closed. It returns nil if conn is closed by the controller.
+func (r *Switch) Serve(conn io.ReadWriter) error {
+	r.writeMutex.Lock()
+	r.writer = conn
+	r.writeMutex.Unlock()
+	defer func() {
+		r.writeMutex.Lock()
+		r.writer = nil
+		r.writeMutex.Unlock()
+	}()
+
+	if err := r.send(of13.OFPT_HELLO, r.newTransactionID(), nil); err != nil {
+		return err
+	}
+
+	reader := bufio.NewReader(conn)
+	for {
+		packet, err := readMessage(reader)
+		if err != nil {
+			if err == io.EOF || err == io.ErrClosedPipe {
+				return nil
+			}
+			return err
+		}
+		if err := r.handle(packet); err != nil {
+			return err
+		}
+		if r.config.OnMessage != nil {
+			r.config.OnMessage(packet[1])
+		}
+	}
+}
+
+func readMessage(reader io.Reader) ([]byte, error) {
+	header := make([]byte, headerLength)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return nil, err
+	}
+	length := int(binary.BigEndian.Uint16(header[2:4]))
+	if length < headerLength {
+		return nil, openflow.ErrInvalidPacketLength
+	}
+	packet := make([]byte, length)
+	copy(packet, header)
+	if _, err := io.ReadFull(reader, packet[headerLength:]); err != nil {
+		return nil, err
+	}
+
+	return packet, nil
+}
+
+func (r *Switch) newTransactionID() uint32 {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.xid++
+	return r.xid
+}
+
+func (r *Switch) send(msgType uint8, xid uint32, payload []byte) error {
+	v := make([]byte, headerLength+len(payload))
+	v[0] = openflow.OF13_VERSION
+	v[1] = msgType
+	binary.BigEndian.PutUint16(v[2:4], uint16(len(v)))
+	binary.BigEndian.PutUint32(v[4:8], xid)
+	copy(v[headerLength:], payload)
+
+	r.writeMutex.Lock()
+	defer r.writeMutex.Unlock()
+
+	if r.writer == nil {
+		return ErrNotConnected
+	}
+	_, err := r.writer.Write(v)
+
+	return err
+}
+
+func (r *Switch) handle(packet []byte) error {
+	msgType := packet[1]
+	xid := binary.BigEndian.Uint32(packet[4:8])
+	payload := packet[headerLength:]
+
+	r.mutex.Lock()
+	r.received[msgType]++
+	r.mutex.Unlock()
+
+	switch msgType {
+	case of13.OFPT_HELLO, of13.OFPT_ECHO_REPLY, of13.OFPT_ERROR:
+		return nil
+	case of13.OFPT_ECHO_REQUEST:
+		return r.send(of13.OFPT_ECHO_REPLY, xid, payload)
+	case of13.OFPT_FEATURES_REQUEST:
+		return r.send(of13.OFPT_FEATURES_REPLY, xid, r.features())
+	case of13.OFPT_GET_CONFIG_REQUEST:
+		v := make([]byte, 4)
+		r.mutex.Lock()
+		binary.BigEndian.PutUint16(v[2:4], r.missSendLen)
+		r.mutex.Unlock()
+		return r.send(of13.OFPT_GET_CONFIG_REPLY, xid, v)
+	case of13.OFPT_SET_CONFIG:
+		if len(payload) < 4 {
+			return r.sendError(xid, packet)
+		}
+		r.mutex.Lock()
+		r.missSendLen = binary.BigEndian.Uint16(payload[2:4])
+		r.mutex.Unlock()
+		return nil
+	case of13.OFPT_BARRIER_REQUEST:
+		return r.send(of13.OFPT_BARRIER_REPLY, xid, nil)
+	case of13.OFPT_ROLE_REQUEST:
+		if len(payload) < 16 {
+			return r.sendError(xid, packet)
+		}
+		return r.send(of13.OFPT_ROLE_REPLY, xid, r.changeRole(payload))
+	case of13.OFPT_FLOW_MOD:
+		return r.handleFlowMod(xid, packet)
+	case of13.OFPT_PACKET_OUT:
+		return r.handlePacketOut(xid, packet)
+	case of13.OFPT_GROUP_MOD, of13.OFPT_METER_MOD, of13.OFPT_PORT_MOD, of13.OFPT_TABLE_MOD:
+		// Accepted without any effect.
+		return nil
+	case of13.OFPT_MULTIPART_REQUEST:
+		return r.handleMultipart(xid, packet)
+	default:
+		return r.sendError(xid, packet)
+	}
+}
+
+// sendError sends the bad request error for packet.
+func (r *Switch) sendError(xid uint32, packet []byte) error {
+	v := make([]byte, 4)
+	binary.BigEndian.PutUint16(v[0:2], of13.OFPET_BAD_REQUEST)
+	binary.BigEndian.PutUint16(v[2:4], badRequestBadType)
+	// At least 64 bytes of the failed request.
+	if len(packet) > 64 {
+		packet = packet[:64]
+	}
+
+	return r.send(of13.OFPT_ERROR, xid, append(v, packet...))
+}
+
+func (r *Switch) features() []byte {
+	v := make([]byte, 24)
+	binary.BigEndian.PutUint64(v[0:8], r.config.DPID)
+	// v[8:12] is the number of buffers, which is zero.
+	v[12] = r.config.NumTables
+	// v[13] is the auxiliary ID, which is zero.
+	// Flow, table and port statistics.
+	binary.BigEndian.PutUint32(v[16:20], 1<<0|1<<1|1<<2)
+
+	return v
+}
+
+func (r *Switch) changeRole(payload []byte) []byte {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if role := binary.BigEndian.Uint32(payload[0:4]); role != of13.OFPCR_ROLE_NOCHANGE {
+		r.role = role
+		r.generationID = binary.BigEndian.Uint64(payload[8:16])
+	}
+	v := make([]byte, 16)
+	binary.BigEndian.PutUint32(v[0:4], r.role)
+	binary.BigEndian.PutUint64(v[8:16], r.generationID)
+
+	return v
+}
+
+// Received returns the number of the received messages whose type is msgType, one of OFPT_*.
+func (r *Switch) Received(msgType uint8) int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.received[msgType]
+}
+
+// MissSendLength returns the max length of the table-miss packets set by the controller.
+func (r *Switch) MissSendLength() uint16 {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.missSendLen
+}
+
+// Role returns the role of the controller and its generation ID.
+func (r *Switch) Role() (role uint32, generationID uint64) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.role, r.generationID
+}
+
+// Flows returns the flows of the switch sorted by the table ID and the priority in the descending
+// order.
+func (r *Switch) Flows() []Flow {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	flows := append([]Flow(nil), r.flows...)
+	sort.SliceStable(flows, func(i, j int) bool {
+		if flows[i].TableID != flows[j].TableID {
+			return flows[i].TableID < flows[j].TableID
+		}
+		return flows[i].Priority > flows[j].Priority
+	})
+
+	return flows
+}
+
+// PacketOuts returns the PACKET_OUTs received so far.
+func (r *Switch) PacketOuts() []PacketOut {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return append([]PacketOut(nil), r.packetOuts...)
+}
+
+func (r *Switch) handlePacketOut(xid uint32, packet []byte) error {
+	payload := packet[headerLength:]
+	if len(payload) < 16 {
+		return r.sendError(xid, packet)
+	}
+	n := int(binary.BigEndian.Uint16(payload[8:10]))
+	if len(payload) < 16+n {
+		return r.sendError(xid, packet)
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.packetOuts = append(r.packetOuts, PacketOut{
+		BufferID: binary.BigEndian.Uint32(payload[0:4]),
+		InPort:   binary.BigEndian.Uint32(payload[4:8]),
+		Actions:  append([]byte(nil), payload[16:16+n]...),
+		Data:     append([]byte(nil), payload[16+n:]...),
+	})
+
+	return nil
+}
+
+// SendPacketIn sends a PACKET_IN to the controller.
+func (r *Switch) SendPacketIn(p PacketIn) error {
+	match := newInPortMatch(p.InPort)
+	v := make([]byte, 16, 16+len(match)+2+len(p.Data))
+	binary.BigEndian.PutUint32(v[0:4], openflow.NoBuffer)
+	binary.BigEndian.PutUint16(v[4:6], uint16(len(p.Data)))
+	v[6] = p.Reason
+	v[7] = p.TableID
+	binary.BigEndian.PutUint64(v[8:16], p.Cookie)
+	v = append(v, match...)
+	// Two bytes of padding before the data.
+	v = append(v, 0, 0)
+	v = append(v, p.Data...)
+
+	return r.send(of13.OFPT_PACKET_IN, r.newTransactionID(), v)
+}
+
+// newInPortMatch returns the OXM match that has only the ingress port.
+func newInPortMatch(port uint32) []byte {
+	v := make([]byte, 16)
+	binary.BigEndian.PutUint16(v[0:2], of13.OFPMT_OXM)
+	binary.BigEndian.PutUint16(v[2:4], 12)
+	binary.BigEndian.PutUint16(v[4:6], oxmClassOpenFlowBasic)
+	v[6] = oxmFieldInPort << 1
+	v[7] = 4
+	binary.BigEndian.PutUint32(v[8:12], port)
+	// v[12:16] is padding.
+
+	return v
+}
+
+// SetLinkDown changes the link state of the port, and then sends a PORT_STATUS to the controller.
+func (r *Switch) SetLinkDown(number uint32, down bool) error {
+	r.mutex.Lock()
+	var port *Port
+	for i := range r.ports {
+		if r.ports[i].Number == number {
+			port = &r.ports[i]
+			break
+		}
+	}
+	if port == nil {
+		r.mutex.Unlock()
+		return ErrUnknownPort
+	}
+	port.LinkDown = down
+	desc := encodePort(*port)
+	r.mutex.Unlock()
+
+	v := make([]byte, 8, 8+len(desc))
+	v[0] = of13.OFPPR_MODIFY
+	v = append(v, desc...)
+
+	return r.send(of13.OFPT_PORT_STATUS, r.newTransactionID(), v)
+}
+
+func encodePort(p Port) []byte {
+	v := make([]byte, 64)
+	binary.BigEndian.PutUint32(v[0:4], p.Number)
+	copy(v[8:14], p.MAC)
+	copy(v[16:31], p.Name)
+	if p.LinkDown {
+		binary.BigEndian.PutUint32(v[36:40], of13.OFPPS_LINK_DOWN)
+	}
+	// Current, advertised and supported features.
+	binary.BigEndian.PutUint32(v[40:44], of13.OFPPF_1GB_FD)
+	binary.BigEndian.PutUint32(v[44:48], of13.OFPPF_1GB_FD)
+	binary.BigEndian.PutUint32(v[48:52], of13.OFPPF_1GB_FD)
+	binary.BigEndian.PutUint32(v[56:60], portSpeed)
+	binary.BigEndian.PutUint32(v[60:64], portSpeed)
+
+	return v
+}
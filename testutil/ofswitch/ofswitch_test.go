@@ -0,0 +1,172 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package ofswitch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/superkkt/cherry/openflow"
+	"github.com/superkkt/cherry/openflow/of13"
+)
+
+type controller struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func (r *controller) write(msg encoding.BinaryMarshaler) {
+	packet, err := msg.MarshalBinary()
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	if _, err := r.conn.Write(packet); err != nil {
+		r.t.Fatal(err)
+	}
+}
+
+// read returns the next message whose type is msgType, skipping the others.
+func (r *controller) read(msgType uint8) []byte {
+	r.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		packet, err := readMessage(r.reader)
+		if err != nil {
+			r.t.Fatal(err)
+		}
+		if packet[1] == msgType {
+			return packet
+		}
+	}
+}
+
+func newTestSwitch(t *testing.T) (*Switch, *controller) {
+	sw := New(Config{
+		DPID: 0x1234,
+		Ports: []Port{
+			{Number: 1, Name: "eth1", MAC: net.HardwareAddr{0x0a, 0, 0, 0, 0, 1}},
+			{Number: 2, Name: "eth2", MAC: net.HardwareAddr{0x0a, 0, 0, 0, 0, 2}},
+		},
+	})
+	local, remote := net.Pipe()
+	go sw.Serve(remote)
+	t.Cleanup(func() { local.Close() })
+
+	c := &controller{t: t, conn: local, reader: bufio.NewReader(local)}
+	c.read(of13.OFPT_HELLO)
+
+	return sw, c
+}
+
+func newInPort(port uint32) openflow.InPort {
+	v := openflow.NewInPort()
+	v.SetValue(port)
+
+	return v
+}
+
+func TestHandshake(t *testing.T) {
+	_, c := newTestSwitch(t)
+
+	c.write(of13.NewFeaturesRequest(1))
+	reply := new(of13.FeaturesReply)
+	if err := reply.UnmarshalBinary(c.read(of13.OFPT_FEATURES_REPLY)); err != nil {
+		t.Fatal(err)
+	}
+	if reply.DPID() != 0x1234 || reply.NumTables() != 254 {
+		t.Fatalf("unexpected features reply: DPID=%v, tables=%v", reply.DPID(), reply.NumTables())
+	}
+
+	c.write(of13.NewEchoRequest(2))
+	c.read(of13.OFPT_ECHO_REPLY)
+}
+
+func TestFlows(t *testing.T) {
+	sw, c := newTestSwitch(t)
+
+	match := of13.NewMatch()
+	match.SetInPort(newInPort(1))
+	match.SetEtherType(0x0800)
+	flow := of13.NewFlowMod(1, of13.OFPFC_ADD)
+	flow.SetTableID(0)
+	flow.SetPriority(100)
+	flow.SetCookie(0xABCD)
+	flow.SetFlowMatch(match)
+	c.write(flow)
+	c.write(of13.NewBarrierRequest(2))
+	c.read(of13.OFPT_BARRIER_REPLY)
+
+	flows := sw.Flows()
+	if len(flows) != 1 || flows[0].Priority != 100 || flows[0].Cookie != 0xABCD {
+		t.Fatalf("unexpected flows: %+v", flows)
+	}
+
+	req := of13.NewFlowStatsRequest(3)
+	req.SetTableID(0xFF)
+	req.SetMatch(of13.NewMatch())
+	c.write(req)
+	stats := new(of13.FlowStatsReply)
+	if err := stats.UnmarshalBinary(c.read(of13.OFPT_MULTIPART_REPLY)); err != nil {
+		t.Fatal(err)
+	}
+	if len(stats.FlowStats()) != 1 || stats.FlowStats()[0].Cookie != 0xABCD {
+		t.Fatalf("unexpected flow stats: %+v", stats.FlowStats())
+	}
+
+	// Non-strict delete matching the in_port only.
+	filter := of13.NewMatch()
+	filter.SetInPort(newInPort(1))
+	del := of13.NewFlowMod(4, of13.OFPFC_DELETE)
+	del.SetTableID(0xFF)
+	del.SetFlowMatch(filter)
+	c.write(del)
+
+	removed := new(of13.FlowRemoved)
+	if err := removed.UnmarshalBinary(c.read(of13.OFPT_FLOW_REMOVED)); err != nil {
+		t.Fatal(err)
+	}
+	if removed.Cookie() != 0xABCD || removed.Reason() != openflow.FlowRemovedDelete {
+		t.Fatalf("unexpected flow removed: cookie=%v, reason=%v", removed.Cookie(), removed.Reason())
+	}
+	if len(sw.Flows()) != 0 {
+		t.Fatalf("expected no flows, got %+v", sw.Flows())
+	}
+}
+
+func TestPacketIn(t *testing.T) {
+	sw, c := newTestSwitch(t)
+
+	data := []byte{1, 2, 3, 4}
+	go sw.SendPacketIn(PacketIn{InPort: 2, Data: data})
+
+	p := new(of13.PacketIn)
+	if err := p.UnmarshalBinary(c.read(of13.OFPT_PACKET_IN)); err != nil {
+		t.Fatal(err)
+	}
+	if p.InPort() != 2 || !bytes.Equal(p.Data(), data) {
+		t.Fatalf("unexpected packet in: port=%v, data=%v", p.InPort(), p.Data())
+	}
+}
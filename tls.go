@@ -0,0 +1,97 @@
+/*
+ * Cherry - An OpenFlow Controller
+ *
+ * Copyright (C) 2015 Samjung Data Service, Inc. All rights reserved.
+ * Kitae Kim <superkkt@sds.co.kr>
+ *
+ * This program is free software; you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation; either version 2 of the License, or
+ * any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License along
+ * with this program; if not, write to the Free Software Foundation, Inc.,
+ * 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+ */
+
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/superkkt/viper"
+)
+
+// newTLSConfig returns the TLS configuration for the OpenFlow connections from
+// the switches. It returns nil if TLS is disabled.
+func newTLSConfig() (*tls.Config, error) {
+	if viper.GetBool("default.tls.enable") == false {
+		return nil, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(viper.GetString("default.tls.cert_file"), viper.GetString("default.tls.key_file"))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load the server certificate")
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	// Verify the client certificates of the switches if the CA is specified.
+	if caFile := viper.GetString("default.tls.ca_file"); len(caFile) > 0 {
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read the CA file")
+		}
+		pool := x509.NewCertPool()
+		if pool.AppendCertsFromPEM(pem) == false {
+			return nil, fmt.Errorf("no valid certificate in the CA file: %v", caFile)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	suites, err := parseCipherSuites(viper.GetString("default.tls.cipher_suites"))
+	if err != nil {
+		return nil, err
+	}
+	config.CipherSuites = suites
+
+	return config, nil
+}
+
+// parseCipherSuites parses the cipher suite names separated by comma. It returns
+// nil, which means the default cipher suites, if names is empty.
+func parseCipherSuites(names string) ([]uint16, error) {
+	names = strings.Replace(names, " ", "", -1)
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	supported := make(map[string]uint16)
+	for _, v := range tls.CipherSuites() {
+		supported[v.Name] = v.ID
+	}
+
+	var suites []uint16
+	for _, name := range strings.Split(names, ",") {
+		id, ok := supported[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown or insecure cipher suite: %v", name)
+		}
+		suites = append(suites, id)
+	}
+
+	return suites, nil
+}